@@ -0,0 +1,53 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+
+	"github.com/sirupsen/logrus"
+)
+
+var errInvalidConfig = errors.New("invalid configuration")
+
+// configReport collects the problems of the configuration with --check-config. Otherwise, the first problem
+// is fatal. A nil report is never in check mode.
+type configReport struct {
+	check    bool
+	problems []error
+}
+
+// fail reports a problem of the configuration, err may be nil
+func (r *configReport) fail(err error, msg string, fields logrus.Fields) {
+	if r == nil || !r.check {
+		entry := log.WithFields(fields)
+		if err != nil {
+			entry = entry.WithError(err)
+		}
+		entry.Fatal(msg)
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(fields)) {
+		msg += fmt.Sprintf(" %s=%v", key, fields[key])
+	}
+	if err != nil {
+		r.problems = append(r.problems, fmt.Errorf("%s: %w", msg, err))
+	} else {
+		r.problems = append(r.problems, fmt.Errorf("%w: %s", errInvalidConfig, msg))
+	}
+}
+
+// print writes the problems found to w, and returns an error if there are any
+func (r *configReport) print(w io.Writer) error {
+	if len(r.problems) == 0 {
+		fmt.Fprintln(w, "configuration OK")
+		return nil
+	}
+	fmt.Fprintf(w, "found %d configuration problems:\n", len(r.problems))
+	for _, problem := range r.problems {
+		fmt.Fprintf(w, "  - %s\n", problem)
+	}
+	return fmt.Errorf("%w: %d problems", errInvalidConfig, len(r.problems))
+}
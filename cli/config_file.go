@@ -0,0 +1,337 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/url"
+	"os"
+	"os/signal"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/flashbots/mev-boost/server"
+	"github.com/flashbots/mev-boost/server/types"
+	"github.com/urfave/cli/v3"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	errInvalidConfigFile = errors.New("invalid config file")
+
+	// yamlErrorPrefix is the prefix of YAML decoding errors, which repeats the line of the key
+	yamlErrorPrefix = regexp.MustCompile(`^yaml: unmarshal errors:\n\s*line \d+: `)
+)
+
+// optionSource returns the values of the options by flag name, like the command
+type optionSource interface {
+	IsSet(name string) bool
+	String(name string) string
+	Bool(name string) bool
+	Int(name string) int64
+	Uint(name string) uint64
+	Float(name string) float64
+	StringSlice(name string) []string
+}
+
+// configFile is the content of the --config file. Options are set by their flag name, for example:
+//
+//	mainnet: true
+//	request-timeout-getheader: 950
+//	bid-tie-break: reliability
+//	relays:
+//	  - url: https://0xa15b...@relay.example.com
+//	    label: example
+//	    priority: 10
+//	    weight: 2
+//	    min-bid: 0.01
+//	    headers:
+//	      Authorization: Bearer ...
+//	    pubkeys: [0xb27c...]
+//	  - https://0x8a1d...@other-relay.example.com
+type configFile struct {
+	Relays  []configFileRelay    `yaml:"relays"`
+	Options map[string]yaml.Node `yaml:",inline"`
+}
+
+// configFileRelay is a relay of the config file, with the attributes which are query parameters of relay URLs
+type configFileRelay struct {
+	URL             string            `yaml:"url"`
+	Pubkeys         []string          `yaml:"pubkeys"`
+	Label           string            `yaml:"label"`
+	Priority        int               `yaml:"priority"`
+	Stream          bool              `yaml:"stream"`
+	SniffGzip       bool              `yaml:"sniff-gzip"`
+	MinOverLocalPct *float64          `yaml:"min-over-local-pct"`
+	Forks           []string          `yaml:"forks"`
+	Weight          int               `yaml:"weight"`
+	MinBid          *float64          `yaml:"min-bid"`
+	Headers         map[string]string `yaml:"headers"`
+}
+
+// configFileRelayKeys are the attributes of a relay in the config file
+var configFileRelayKeys = []string{"url", "pubkeys", "label", "priority", "stream", "sniff-gzip", "min-over-local-pct", "forks", "weight", "min-bid", "headers"}
+
+// UnmarshalYAML decodes a relay given as a mapping of its attributes, or as a relay URL
+func (r *configFileRelay) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		return node.Decode(&r.URL)
+	}
+	// The decoder does not reject unknown fields of custom unmarshalers
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if key := node.Content[i]; !slices.Contains(configFileRelayKeys, key.Value) {
+			return fmt.Errorf("line %d: unknown relay attribute %s", key.Line, key.Value)
+		}
+	}
+	type plain configFileRelay
+	return node.Decode((*plain)(r))
+}
+
+// relayURL returns the URL of the relay with its attributes as query parameters, as parsed by types.NewRelayEntry
+func (r *configFileRelay) relayURL() string {
+	query := url.Values{}
+	for _, pubkey := range r.Pubkeys {
+		query.Add("pubkey", pubkey)
+	}
+	if r.Label != "" {
+		query.Set("label", r.Label)
+	}
+	if r.Priority != 0 {
+		query.Set("priority", strconv.Itoa(r.Priority))
+	}
+	if r.Stream {
+		query.Set("stream", "true")
+	}
+	if r.SniffGzip {
+		query.Set("sniff-gzip", "true")
+	}
+	if r.MinOverLocalPct != nil {
+		query.Set("min-over-local-pct", strconv.FormatFloat(*r.MinOverLocalPct, 'f', -1, 64))
+	}
+	for _, fork := range r.Forks {
+		query.Add("fork", fork)
+	}
+	if r.Weight != 0 {
+		query.Set("weight", strconv.Itoa(r.Weight))
+	}
+	if r.MinBid != nil {
+		query.Set("min-bid", strconv.FormatFloat(*r.MinBid, 'f', -1, 64))
+	}
+	for _, name := range slices.Sorted(maps.Keys(r.Headers)) {
+		query.Add("header", name+":"+r.Headers[name])
+	}
+	if len(query) == 0 {
+		return r.URL
+	}
+	if strings.Contains(r.URL, "?") {
+		return r.URL + "&" + query.Encode()
+	}
+	return r.URL + "?" + query.Encode()
+}
+
+// fileOptions returns the options set by flags or environment variables of the command, then the options of the
+// config file, and then the flag defaults
+type fileOptions struct {
+	*cli.Command
+	path   string
+	values map[string]any
+}
+
+// newFileOptions returns the options of the command and of its config file, if any. On error, the options of
+// the command are returned.
+func newFileOptions(cmd *cli.Command) (*fileOptions, error) {
+	options := &fileOptions{Command: cmd, path: cmd.String(configFileFlag.Name)}
+	if options.path == "" {
+		return options, nil
+	}
+	values, err := loadConfigFile(options.path, flags)
+	if err != nil {
+		return options, err
+	}
+	options.values = values
+	return options, nil
+}
+
+// loadConfigFile reads a config file, and returns its values by flag name with the type of the flag
+func loadConfigFile(path string, flags []cli.Flag) (map[string]any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errInvalidConfigFile, err)
+	}
+	var file configFile
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&file); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%w %s: %w", errInvalidConfigFile, path, err)
+	}
+
+	// Check the options in the order of the file, so that the first problem is reported
+	keys := slices.SortedFunc(maps.Keys(file.Options), func(a, b string) int {
+		return file.Options[a].Line - file.Options[b].Line
+	})
+	values := make(map[string]any)
+	for _, key := range keys {
+		node := file.Options[key]
+		fail := func(msg string, args ...any) error {
+			return fmt.Errorf("%w %s: line %d: key %s: %s", errInvalidConfigFile, path, node.Line, key, fmt.Sprintf(msg, args...))
+		}
+		i := slices.IndexFunc(flags, func(f cli.Flag) bool { return slices.Contains(f.Names(), key) })
+		if i < 0 {
+			return nil, fail("unknown option")
+		}
+		flag := flags[i]
+		name := flag.Names()[0]
+		if name == configFileFlag.Name || name == checkConfigFlag.Name || name == versionFlag.Name {
+			return nil, fail("can only be set on the command line")
+		}
+		if _, ok := values[name]; ok {
+			return nil, fail("option is set twice")
+		}
+
+		var value any
+		switch flag.(type) {
+		case *cli.StringFlag:
+			var v string
+			err = node.Decode(&v)
+			value = v
+		case *cli.BoolFlag:
+			var v bool
+			err = node.Decode(&v)
+			value = v
+		case *cli.IntFlag:
+			var v int64
+			err = node.Decode(&v)
+			value = v
+		case *cli.UintFlag:
+			var v uint64
+			err = node.Decode(&v)
+			value = v
+		case *cli.FloatFlag:
+			var v float64
+			err = node.Decode(&v)
+			value = v
+		case *cli.StringSliceFlag:
+			// A single entry or a comma-separated list is also accepted, like on the command line
+			var v []string
+			if node.Kind == yaml.ScalarNode {
+				v = []string{""}
+				err = node.Decode(&v[0])
+			} else {
+				err = node.Decode(&v)
+			}
+			value = v
+		default:
+			return nil, fail("not supported in the config file")
+		}
+		if err != nil {
+			return nil, fail("%s", yamlErrorPrefix.ReplaceAllString(err.Error(), ""))
+		}
+		values[name] = value
+	}
+
+	if len(file.Relays) > 0 {
+		if _, ok := values[relaysFlag.Name]; ok {
+			return nil, fmt.Errorf("%w %s: relays are set twice, with the relays and the %s keys", errInvalidConfigFile, path, relaysFlag.Name)
+		}
+		urls := make([]string, 0, len(file.Relays))
+		for i, relay := range file.Relays {
+			relayURL := relay.relayURL()
+			if _, err := types.NewRelayEntry(relayURL); err != nil {
+				return nil, fmt.Errorf("%w %s: key relays[%d]: %w", errInvalidConfigFile, path, i, err)
+			}
+			urls = append(urls, relayURL)
+		}
+		values[relaysFlag.Name] = urls
+	}
+	return values, nil
+}
+
+// fromFile returns the value of the option in the config file, unless a flag or environment variable sets it
+func (o *fileOptions) fromFile(name string) (any, bool) {
+	if o.Command.IsSet(name) {
+		return nil, false
+	}
+	value, ok := o.values[name]
+	return value, ok
+}
+
+func (o *fileOptions) IsSet(name string) bool {
+	_, ok := o.values[name]
+	return ok || o.Command.IsSet(name)
+}
+
+func (o *fileOptions) String(name string) string {
+	if value, ok := o.fromFile(name); ok {
+		return value.(string) //nolint:forcetypeassert // decoded with the type of the flag
+	}
+	return o.Command.String(name)
+}
+
+func (o *fileOptions) Bool(name string) bool {
+	if value, ok := o.fromFile(name); ok {
+		return value.(bool) //nolint:forcetypeassert // decoded with the type of the flag
+	}
+	return o.Command.Bool(name)
+}
+
+func (o *fileOptions) Int(name string) int64 {
+	if value, ok := o.fromFile(name); ok {
+		return value.(int64) //nolint:forcetypeassert // decoded with the type of the flag
+	}
+	return o.Command.Int(name)
+}
+
+func (o *fileOptions) Uint(name string) uint64 {
+	if value, ok := o.fromFile(name); ok {
+		return value.(uint64) //nolint:forcetypeassert // decoded with the type of the flag
+	}
+	return o.Command.Uint(name)
+}
+
+func (o *fileOptions) Float(name string) float64 {
+	if value, ok := o.fromFile(name); ok {
+		return value.(float64) //nolint:forcetypeassert // decoded with the type of the flag
+	}
+	return o.Command.Float(name)
+}
+
+func (o *fileOptions) StringSlice(name string) []string {
+	if value, ok := o.fromFile(name); ok {
+		return value.([]string) //nolint:forcetypeassert // decoded with the type of the flag
+	}
+	return o.Command.StringSlice(name)
+}
+
+// reloadConfigFileOnSighup reloads the relays and policies of the config file on SIGHUP. Flags and environment
+// variables still take precedence over the file, and changes of other options need a restart.
+func reloadConfigFileOnSighup(cmd *cli.Command, service *server.BoostService) {
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+	for range sighup {
+		if err := reloadConfigFile(cmd, service); err != nil {
+			log.WithError(err).Error("could not reload the config file, keeping the previous relays and policies")
+			continue
+		}
+		log.WithField("path", cmd.String(configFileFlag.Name)).Info("reloaded the config file")
+	}
+}
+
+// reloadConfigFile reads the config file again, and applies its relays and policies together if the configuration
+// is valid
+func reloadConfigFile(cmd *cli.Command, service *server.BoostService) error {
+	options, err := newFileOptions(cmd)
+	if err != nil {
+		return err
+	}
+	report := &configReport{check: true}
+	opts := buildServiceOpts(options, report)
+	if len(report.problems) > 0 {
+		return errors.Join(report.problems...)
+	}
+	return service.Reload(opts)
+}
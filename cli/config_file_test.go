@@ -0,0 +1,221 @@
+package cli
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/attestantio/go-eth2-client/spec"
+	"github.com/flashbots/mev-boost/server"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli/v3"
+)
+
+const testRelayPubkey = "0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249"
+
+// writeConfigFile writes a config file in a temporary directory, and returns its path
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "mev-boost.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+// runCommand runs the command with copies of the flags, which keep their values after a run, and returns its options
+func runCommand(t *testing.T, args ...string) (*cli.Command, *fileOptions, error) {
+	t.Helper()
+	copies := make([]cli.Flag, 0, len(flags))
+	for _, flag := range flags {
+		switch f := flag.(type) {
+		case *cli.StringFlag:
+			c := *f
+			copies = append(copies, &c)
+		case *cli.BoolFlag:
+			c := *f
+			copies = append(copies, &c)
+		case *cli.IntFlag:
+			c := *f
+			copies = append(copies, &c)
+		case *cli.UintFlag:
+			c := *f
+			copies = append(copies, &c)
+		case *cli.FloatFlag:
+			c := *f
+			copies = append(copies, &c)
+		case *cli.StringSliceFlag:
+			c := *f
+			copies = append(copies, &c)
+		default:
+			t.Fatalf("unexpected flag type %T", flag)
+		}
+	}
+
+	var options *fileOptions
+	var optionsErr error
+	cmd := &cli.Command{
+		Name:  "mev-boost",
+		Flags: copies,
+		Action: func(_ context.Context, cmd *cli.Command) error {
+			options, optionsErr = newFileOptions(cmd)
+			return nil
+		},
+	}
+	require.NoError(t, cmd.Run(context.Background(), append([]string{"mev-boost"}, args...)))
+	return cmd, options, optionsErr
+}
+
+func TestLoadConfigFile(t *testing.T) {
+	testCases := []struct {
+		name    string
+		content string
+		err     string
+	}{
+		{name: "Empty file"},
+		{name: "Unknown option", content: "mainnet: true\nbid-tie-brak: random\n", err: "line 2: key bid-tie-brak: unknown option"},
+		{name: "Invalid value", content: "request-timeout-getheader: soon\n", err: "line 1: key request-timeout-getheader: cannot unmarshal !!str `soon` into int64"},
+		{name: "Command line option", content: "check-config: true\n", err: "line 1: key check-config: can only be set on the command line"},
+		{name: "Option set twice by alias", content: "relay-monitors: a\nrelay-monitor: b\n", err: "line 2: key relay-monitor: option is set twice"},
+		{name: "Invalid relay", content: "relays:\n  - url: https://relay.example.com\n", err: "key relays[0]: missing relay public key"},
+		{name: "Unknown relay attribute", content: "relays:\n  - url: https://" + testRelayPubkey + "@relay.example.com\n    tier: 1\n", err: "line 3: unknown relay attribute tier"},
+		{name: "Relays set twice", content: "relay: https://" + testRelayPubkey + "@relay.example.com\nrelays:\n  - https://" + testRelayPubkey + "@relay.example.com\n", err: "relays are set twice"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := loadConfigFile(writeConfigFile(t, tc.content), flags)
+			if tc.err == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, errInvalidConfigFile)
+			require.ErrorContains(t, err, tc.err)
+		})
+	}
+
+	t.Run("Values have the type of the flag", func(t *testing.T) {
+		values, err := loadConfigFile(writeConfigFile(t, `
+mainnet: true
+request-timeout-getheader: 800
+genesis-timestamp: 1606824023
+min-bid: 0.05
+bid-tie-break: reliability
+builder-denylist: 0xaa, 0xbb
+cors-allowed-origins: [https://a.example.com, https://b.example.com]
+relays:
+  - url: https://`+testRelayPubkey+`@relay-a.example.com?id=1
+    label: a
+    priority: 10
+    stream: true
+    min-over-local-pct: 2.5
+  - https://`+testRelayPubkey+`@relay-b.example.com
+`), flags)
+		require.NoError(t, err)
+		require.Equal(t, map[string]any{
+			"mainnet":                   true,
+			"request-timeout-getheader": int64(800),
+			"genesis-timestamp":         uint64(1606824023),
+			"min-bid":                   0.05,
+			"bid-tie-break":             "reliability",
+			"builder-denylist":          []string{"0xaa, 0xbb"},
+			"cors-allowed-origins":      []string{"https://a.example.com", "https://b.example.com"},
+			"relay": []string{
+				"https://" + testRelayPubkey + "@relay-a.example.com?id=1&label=a&min-over-local-pct=2.5&priority=10&stream=true",
+				"https://" + testRelayPubkey + "@relay-b.example.com",
+			},
+		}, values)
+	})
+}
+
+func TestConfigFilePrecedence(t *testing.T) {
+	path := writeConfigFile(t, `
+request-timeout-getheader: 800
+request-timeout-getpayload: 3000
+bid-tie-break: reliability
+min-bid: 0.05
+`)
+	t.Setenv("BID_TIE_BREAK", "random")
+	t.Setenv("RELAY_TIMEOUT_MS_GETPAYLOAD", "3500")
+
+	_, options, err := runCommand(t, "--config", path, "--request-timeout-getheader", "700")
+	require.NoError(t, err)
+
+	// Flags, then environment variables, then the file, then the defaults
+	require.Equal(t, int64(700), options.Int(timeoutGetHeaderFlag.Name))
+	require.Equal(t, int64(3500), options.Int(timeoutGetPayloadFlag.Name))
+	require.Equal(t, "random", options.String(bidTieBreakFlag.Name))
+	require.InDelta(t, 0.05, options.Float(minBidFlag.Name), 0)
+	require.True(t, options.IsSet(minBidFlag.Name))
+	require.Equal(t, server.ConflictingBidsLowest, options.String(conflictingBidsFlag.Name))
+	require.False(t, options.IsSet(conflictingBidsFlag.Name))
+}
+
+func TestConfigFileRoundTrip(t *testing.T) {
+	path := writeConfigFile(t, `
+mainnet: true
+relays:
+  - url: https://`+testRelayPubkey+`@relay-a.example.com
+    label: a
+    priority: 10
+    sniff-gzip: true
+  - url: https://`+testRelayPubkey+`@relay-b.example.com
+    min-over-local-pct: 2.5
+    forks: [deneb, electra]
+    weight: 3
+    min-bid: 0.01
+    headers:
+      Authorization: Bearer token
+      X-Api-Key: abc
+relay-check: true
+min-bid: 0.05
+bid-tie-break: reliability
+conflicting-bids: drop
+cors-allowed-origins: [https://a.example.com, https://b.example.com]
+request-timeout-getheader: 800
+`)
+	_, fromFile, err := runCommand(t, "--config", path)
+	require.NoError(t, err)
+	_, fromFlags, err := runCommand(t,
+		"--mainnet",
+		"--relay", "https://"+testRelayPubkey+"@relay-a.example.com?label=a&priority=10&sniff-gzip=true",
+		"--relay", "https://"+testRelayPubkey+"@relay-b.example.com?min-over-local-pct=2.5&fork=deneb&fork=electra&weight=3&min-bid=0.01&header=Authorization:Bearer%20token&header=X-Api-Key:abc",
+		"--relay-check",
+		"--min-bid", "0.05",
+		"--bid-tie-break", "reliability",
+		"--conflicting-bids", "drop",
+		"--cors-allowed-origins", "https://a.example.com,https://b.example.com",
+		"--request-timeout-getheader", "800",
+	)
+	require.NoError(t, err)
+
+	fileReport := &configReport{check: true}
+	flagsReport := &configReport{check: true}
+	opts := buildServiceOpts(fromFile, fileReport)
+	require.Empty(t, fileReport.problems)
+	require.Equal(t, buildServiceOpts(fromFlags, flagsReport), opts)
+	require.Empty(t, flagsReport.problems)
+	require.Len(t, opts.Relays, 2)
+	require.Equal(t, "a", opts.Relays[0].Label)
+	require.Equal(t, 10, opts.Relays[0].Priority)
+	require.Equal(t, []spec.DataVersion{spec.DataVersionDeneb, spec.DataVersionElectra}, opts.Relays[1].Forks)
+	require.Equal(t, 3, opts.Relays[1].Weight)
+	require.Equal(t, "10000000000000000", opts.Relays[1].MinBid.String())
+	require.Equal(t, map[string]string{"Authorization": "Bearer token", "X-Api-Key": "abc"}, opts.Relays[1].Headers)
+	require.Empty(t, opts.Relays[1].URL.RawQuery)
+}
+
+func TestReloadConfigFile(t *testing.T) {
+	path := writeConfigFile(t, "mainnet: true\nrelays:\n  - https://"+testRelayPubkey+"@relay-a.example.com\n")
+	cmd, options, err := runCommand(t, "--config", path)
+	require.NoError(t, err)
+	service, err := server.NewBoostService(buildServiceOpts(options, &configReport{check: true}))
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(path, []byte("mainnet: true\nbid-tie-break: random\nrelays:\n  - https://"+testRelayPubkey+"@relay-b.example.com\n"), 0o600))
+	require.NoError(t, reloadConfigFile(cmd, service))
+
+	// Invalid files are not applied
+	require.NoError(t, os.WriteFile(path, []byte("mainnet: true\nbid-tie-break: never\nrelays:\n  - https://"+testRelayPubkey+"@relay-b.example.com\n"), 0o600))
+	require.Error(t, reloadConfigFile(cmd, service))
+	require.NoError(t, os.WriteFile(path, []byte("mainnet: true\nrelays: []\n"), 0o600))
+	require.Error(t, reloadConfigFile(cmd, service))
+}
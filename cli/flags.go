@@ -1,6 +1,9 @@
 package cli
 
-import "github.com/urfave/cli/v3"
+import (
+	"github.com/flashbots/mev-boost/server"
+	"github.com/urfave/cli/v3"
+)
 
 const (
 	LoggingCategory = "LOGGING AND DEBUGGING"
@@ -13,15 +16,59 @@ var flags = []cli.Flag{
 	// general
 	addrFlag,
 	versionFlag,
+	checkConfigFlag,
+	configFileFlag,
+	compatShimsFlag,
+	consensusVersionShadowFlag,
+	inferConsensusVersionFlag,
+	selfMonitorIntervalFlag,
+	resourceSoftLimitsFlag,
+	resourceHardLimitsFlag,
+	metricsFlag,
+	metricsAddrFlag,
+	statsdAddrFlag,
+	statsdPrefixFlag,
+	statsdDialectFlag,
+	alertWebhookURLFlag,
+	alertCommandFlag,
+	alertMinIntervalFlag,
+	alertUnhealthyChecksFlag,
+	alertNoBidSlotsFlag,
+	tenantsFileFlag,
+	payloadOutcomesFileFlag,
+	bidMetadataFileFlag,
+	payloadArtifactsDirFlag,
+	chaosConfigFlag,
+	chaosAllowMainnetFlag,
+	chaosDelayFlag,
+	chaosDelayEnableFlag,
+	apiAuthTokenFlag,
+	apiAuthTokenFileFlag,
+	allowedClientCIDRsFlag,
+	trustedProxiesFlag,
 	// logging
 	jsonFlag,
 	debugFlag,
 	logLevelFlag,
 	logServiceFlag,
 	logNoVersionFlag,
+	timingHeaderFlag,
+	debugEndpointsFlag,
+	adminEndpointsFlag,
+	adminTokenFlag,
+	corsAllowedOriginsFlag,
+	corsAllowedHeadersFlag,
+	corsMaxAgeFlag,
+	slowRelayThresholdFlag,
+	sessionSummaryFileFlag,
 	// genesis
 	customGenesisForkFlag,
 	customGenesisTimeFlag,
+	secondsPerSlotFlag,
+	nextForkVersionFlag,
+	nextForkEpochFlag,
+	forkEpochsFlag,
+	signingForkVersionsFlag,
 	mainnetFlag,
 	sepoliaFlag,
 	holeskyFlag,
@@ -29,11 +76,60 @@ var flags = []cli.Flag{
 	relaysFlag,
 	relayMonitorFlag,
 	minBidFlag,
+	relayPriorityToleranceFlag,
+	minBidOverLocalFlag,
+	relayOrderHeaderFlag,
+	forwardHeadersFlag,
+	bidTieBreakFlag,
+	conflictingBidsFlag,
+	builderAllowlistFlag,
+	builderDenylistFlag,
 	relayCheckFlag,
+	relayCheckReadinessFlag,
+	relayCheckStartupTimeoutFlag,
+	prewarmRelayConnectionsFlag,
+	strictRelaySchemaFlag,
+	failedDeliveryPolicyFlag,
+	withholdingPenaltyFlag,
+	withholdingPenaltyPolicyFlag,
+	minActiveRelaysFlag,
+	minBiddingRelaysFlag,
+	minBiddingRelaysPolicyFlag,
+	withholdingEventsRetentionFlag,
+	relayFailurePolicyFlag,
+	strictPubkeyCheckFlag,
+	checkBidTimestampFlag,
+	requestSigningKeyFlag,
+	requestSigningSchemeFlag,
+	serveCachedBidFlag,
+	cachedBidMaxAgeFlag,
+	validationLevelFlag,
+	relayContentTypeCheckFlag,
+	executionRPCFlag,
 	timeoutGetHeaderFlag,
+	timeoutGetHeaderForkFlag,
+	getHeaderSlotDeadlineFlag,
+	getHeaderRetryTimeoutFractionFlag,
 	timeoutGetPayloadFlag,
 	timeoutRegValFlag,
+	timeoutRelayMonitorFlag,
+	timeoutDialFlag,
+	timeoutTLSHandshakeFlag,
+	relayTLSMinVersionFlag,
 	maxRetriesFlag,
+	retryOnFlag,
+	maxRegistrationBatchSizeFlag,
+	registrationJitterFlag,
+	feeRecipientAuditFlag,
+	approvedFeeRecipientsFlag,
+	verifyRegistrationSignaturesFlag,
+	maxPayloadResponseSizeFlag,
+	maxPayloadRequestSizeFlag,
+	relayDNSCacheTTLFlag,
+	relayLocalAddrFlag,
+	followRelayRedirectsSameHostFlag,
+	maxCachedSlotsFlag,
+	blockNumberSpreadFlag,
 }
 
 var (
@@ -50,6 +146,193 @@ var (
 		Usage:    "print version",
 		Category: GeneralCategory,
 	}
+	checkConfigFlag = &cli.BoolFlag{
+		Name:     "check-config",
+		Usage:    "check the configuration, report all problems found and exit without serving",
+		Category: GeneralCategory,
+	}
+	configFileFlag = &cli.StringFlag{
+		Name:     "config",
+		Sources:  cli.EnvVars("CONFIG_FILE"),
+		Usage:    "YAML file with options by flag name and the relays as structured entries, used for options not set by flags or environment variables. Relays and policies are reloaded on SIGHUP",
+		Category: GeneralCategory,
+	}
+	compatShimsFlag = &cli.BoolFlag{
+		Name:     "compat-shims",
+		Sources:  cli.EnvVars("COMPAT_SHIMS"),
+		Usage:    "enables the workarounds for known quirks of specific beacon clients, keyed on the User-Agent",
+		Category: GeneralCategory,
+	}
+	consensusVersionShadowFlag = &cli.BoolFlag{
+		Name:     "consensus-version-shadow",
+		Sources:  cli.EnvVars("CONSENSUS_VERSION_SHADOW"),
+		Usage:    "log and count getPayload requests whose Eth-Consensus-Version header does not match the fork of the body",
+		Category: GeneralCategory,
+	}
+	inferConsensusVersionFlag = &cli.BoolFlag{
+		Name:     "infer-consensus-version",
+		Sources:  cli.EnvVars("INFER_CONSENSUS_VERSION"),
+		Usage:    "decode relay responses without a version nor an Eth-Consensus-Version header as the fork of the slot, from the fork epochs of the network",
+		Value:    true,
+		Category: GeneralCategory,
+	}
+	selfMonitorIntervalFlag = &cli.IntFlag{
+		Name:     "self-monitor-interval",
+		Sources:  cli.EnvVars("SELF_MONITOR_INTERVAL_MS"),
+		Usage:    "interval of the samples of goroutines, open file descriptors and heap usage, 0 to disable [ms]",
+		Value:    server.DefaultSelfMonitorInterval.Milliseconds(),
+		Category: GeneralCategory,
+	}
+	resourceSoftLimitsFlag = &cli.StringSliceFlag{
+		Name:     "resource-soft-limits",
+		Sources:  cli.EnvVars("RESOURCE_SOFT_LIMITS"),
+		Usage:    "resource usage above which warnings are logged, as goroutines=<n>, fds=<n> and heap_mb=<n>",
+		Category: GeneralCategory,
+	}
+	resourceHardLimitsFlag = &cli.StringSliceFlag{
+		Name:     "resource-hard-limits",
+		Sources:  cli.EnvVars("RESOURCE_HARD_LIMITS"),
+		Usage:    "resource usage above which idle relay connections are closed and registerValidator and debug requests are rejected, as goroutines=<n>, fds=<n> and heap_mb=<n>",
+		Category: GeneralCategory,
+	}
+	metricsFlag = &cli.BoolFlag{
+		Name:     "metrics",
+		Sources:  cli.EnvVars("METRICS_ENABLED"),
+		Usage:    "enables a metrics server",
+		Category: GeneralCategory,
+	}
+	metricsAddrFlag = &cli.StringFlag{
+		Name:     "metrics-addr",
+		Sources:  cli.EnvVars("METRICS_ADDR"),
+		Value:    "localhost:18551",
+		Usage:    "listening address for the metrics server",
+		Category: GeneralCategory,
+	}
+	statsdAddrFlag = &cli.StringFlag{
+		Name:     "statsd-addr",
+		Sources:  cli.EnvVars("STATSD_ADDR"),
+		Usage:    "also send the key metrics to this StatsD server (host:port, UDP)",
+		Category: GeneralCategory,
+	}
+	statsdPrefixFlag = &cli.StringFlag{
+		Name:     "statsd-prefix",
+		Sources:  cli.EnvVars("STATSD_PREFIX"),
+		Value:    "mev_boost",
+		Usage:    "prefix of the StatsD metric names",
+		Category: GeneralCategory,
+	}
+	statsdDialectFlag = &cli.StringFlag{
+		Name:     "statsd-dialect",
+		Sources:  cli.EnvVars("STATSD_DIALECT"),
+		Value:    server.StatsdDialectStatsd,
+		Usage:    "StatsD dialect: statsd, or dogstatsd which adds tags",
+		Category: GeneralCategory,
+	}
+	alertWebhookURLFlag = &cli.StringFlag{
+		Name:     "alert-webhook-url",
+		Sources:  cli.EnvVars("ALERT_WEBHOOK_URL"),
+		Usage:    "POST a JSON alert to this URL when a payload is withheld, all relays are unhealthy, no relay bids or bids do not verify",
+		Category: GeneralCategory,
+	}
+	alertCommandFlag = &cli.StringFlag{
+		Name:     "alert-command",
+		Sources:  cli.EnvVars("ALERT_COMMAND"),
+		Usage:    "run this command, without a shell and with the JSON alert on stdin, on the same conditions as --alert-webhook-url",
+		Category: GeneralCategory,
+	}
+	alertMinIntervalFlag = &cli.IntFlag{
+		Name:     "alert-min-interval",
+		Sources:  cli.EnvVars("ALERT_MIN_INTERVAL_SEC"),
+		Value:    600,
+		Usage:    "minimum time between two alerts of the same condition [s]",
+		Category: GeneralCategory,
+	}
+	alertUnhealthyChecksFlag = &cli.IntFlag{
+		Name:     "alert-unhealthy-checks",
+		Sources:  cli.EnvVars("ALERT_UNHEALTHY_CHECKS"),
+		Value:    server.DefaultAlertUnhealthyChecks,
+		Usage:    "alert when no relay passes this many consecutive relay checks",
+		Category: GeneralCategory,
+	}
+	alertNoBidSlotsFlag = &cli.IntFlag{
+		Name:     "alert-no-bid-slots",
+		Sources:  cli.EnvVars("ALERT_NO_BID_SLOTS"),
+		Value:    server.DefaultAlertNoBidSlots,
+		Usage:    "alert when no relay bids in this many consecutive proposer slots",
+		Category: GeneralCategory,
+	}
+	allowedClientCIDRsFlag = &cli.StringSliceFlag{
+		Name:     "allowed-client-cidrs",
+		Sources:  cli.EnvVars("ALLOWED_CLIENT_CIDRS"),
+		Usage:    "only allow clients in these CIDRs or IP addresses to call registerValidator, getHeader and getPayload - single entry or comma-separated list",
+		Category: GeneralCategory,
+	}
+	trustedProxiesFlag = &cli.StringSliceFlag{
+		Name:     "trusted-proxies",
+		Sources:  cli.EnvVars("TRUSTED_PROXIES"),
+		Usage:    "take the client address of requests from these CIDRs or IP addresses from the X-Forwarded-For header - single entry or comma-separated list",
+		Category: GeneralCategory,
+	}
+	apiAuthTokenFlag = &cli.StringFlag{
+		Name:     "api-auth-token",
+		Sources:  cli.EnvVars("API_AUTH_TOKEN"),
+		Usage:    "require requests to authenticate with this token, as a bearer token or HMAC-SHA256 (all endpoints except /healthz)",
+		Category: GeneralCategory,
+	}
+	apiAuthTokenFileFlag = &cli.StringFlag{
+		Name:     "api-auth-token-file",
+		Sources:  cli.EnvVars("API_AUTH_TOKEN_FILE"),
+		Usage:    "like api-auth-token, with the token read from this file, which is read again on SIGHUP",
+		Category: GeneralCategory,
+	}
+	chaosConfigFlag = &cli.StringFlag{
+		Name:     "chaos-config",
+		Sources:  cli.EnvVars("CHAOS_CONFIG"),
+		Usage:    "inject the faults configured in this JSON file into relay requests, for failure testing in staging",
+		Category: GeneralCategory,
+	}
+	chaosAllowMainnetFlag = &cli.BoolFlag{
+		Name:     "chaos-i-know-what-im-doing",
+		Sources:  cli.EnvVars("CHAOS_I_KNOW_WHAT_IM_DOING"),
+		Usage:    "allow chaos-config and chaos-delay-ms on mainnet",
+		Category: GeneralCategory,
+	}
+	chaosDelayFlag = &cli.IntFlag{
+		Name:     "chaos-delay-ms",
+		Sources:  cli.EnvVars("CHAOS_DELAY_MS"),
+		Usage:    "delay getHeader and getPayload responses to the beacon node by this many milliseconds, for testing its fallback timing in staging (requires chaos-delay-enable)",
+		Category: GeneralCategory,
+	}
+	chaosDelayEnableFlag = &cli.BoolFlag{
+		Name:     "chaos-delay-enable",
+		Sources:  cli.EnvVars("CHAOS_DELAY_ENABLE"),
+		Usage:    "enable the response delay of chaos-delay-ms",
+		Category: GeneralCategory,
+	}
+	payloadOutcomesFileFlag = &cli.StringFlag{
+		Name:     "payload-outcomes-file",
+		Sources:  cli.EnvVars("PAYLOAD_OUTCOMES_FILE"),
+		Usage:    "persist the outcomes of recent getPayload requests to this file, to recognize blocks submitted again after a restart",
+		Category: GeneralCategory,
+	}
+	bidMetadataFileFlag = &cli.StringFlag{
+		Name:     "bid-metadata-file",
+		Sources:  cli.EnvVars("BID_METADATA_FILE"),
+		Usage:    "persist the relays of recently served bids to this file, to request the payload of a block submitted after a restart from the relays of its bid first",
+		Category: GeneralCategory,
+	}
+	payloadArtifactsDirFlag = &cli.StringFlag{
+		Name:     "payload-artifacts-dir",
+		Sources:  cli.EnvVars("PAYLOAD_ARTIFACTS_DIR"),
+		Usage:    "write the exact getPayload responses sent to the beacon node to this directory, named by slot and block hash",
+		Category: GeneralCategory,
+	}
+	tenantsFileFlag = &cli.StringFlag{
+		Name:     "tenants-file",
+		Sources:  cli.EnvVars("TENANTS_FILE"),
+		Usage:    "JSON file mapping validator pubkeys or pubkey prefixes to tenant labels for the per tenant metrics, reloaded on change",
+		Category: GeneralCategory,
+	}
 	// Logging and debugging
 	jsonFlag = &cli.BoolFlag{
 		Name:     "json",
@@ -83,6 +366,60 @@ var (
 		Usage:    "disables adding the version to every log entry",
 		Category: LoggingCategory,
 	}
+	timingHeaderFlag = &cli.BoolFlag{
+		Name:     "timing-header",
+		Sources:  cli.EnvVars("TIMING_HEADER"),
+		Usage:    "add a header with the per-stage timing breakdown [ms] to getHeader and getPayload responses",
+		Category: LoggingCategory,
+	}
+	debugEndpointsFlag = &cli.BoolFlag{
+		Name:     "debug-endpoints",
+		Sources:  cli.EnvVars("DEBUG_ENDPOINTS"),
+		Usage:    "serve internal state on the /debug/ endpoints",
+		Category: LoggingCategory,
+	}
+	adminEndpointsFlag = &cli.BoolFlag{
+		Name:     "admin-endpoints",
+		Sources:  cli.EnvVars("ADMIN_ENDPOINTS"),
+		Usage:    "serve the admin endpoints, which change the builder denylist, flush the bid cache and drain before a shutdown",
+		Category: GeneralCategory,
+	}
+	adminTokenFlag = &cli.StringFlag{
+		Name:     "admin-token",
+		Sources:  cli.EnvVars("ADMIN_TOKEN"),
+		Usage:    "require this token in the X-MEVBoost-Admin-Token header for the admin and debug endpoints",
+		Category: GeneralCategory,
+	}
+	corsAllowedOriginsFlag = &cli.StringSliceFlag{
+		Name:     "cors-allowed-origins",
+		Sources:  cli.EnvVars("CORS_ALLOWED_ORIGINS"),
+		Usage:    "allow browsers on these origins to call the status, health and debug endpoints, exact or like https://*.example.com - single entry or comma-separated list",
+		Category: GeneralCategory,
+	}
+	corsAllowedHeadersFlag = &cli.StringSliceFlag{
+		Name:     "cors-allowed-headers",
+		Sources:  cli.EnvVars("CORS_ALLOWED_HEADERS"),
+		Usage:    "request headers allowed in cross-origin requests, like Authorization - single entry or comma-separated list",
+		Category: GeneralCategory,
+	}
+	corsMaxAgeFlag = &cli.IntFlag{
+		Name:     "cors-max-age",
+		Sources:  cli.EnvVars("CORS_MAX_AGE_SEC"),
+		Usage:    "how long browsers may cache preflight responses, 0 leaves it to the browser [s]",
+		Category: GeneralCategory,
+	}
+	sessionSummaryFileFlag = &cli.StringFlag{
+		Name:     "session-summary-file",
+		Sources:  cli.EnvVars("SESSION_SUMMARY_FILE"),
+		Usage:    "also write the session summary logged on shutdown to this file as JSON",
+		Category: LoggingCategory,
+	}
+	slowRelayThresholdFlag = &cli.IntFlag{
+		Name:     "slow-relay-threshold",
+		Sources:  cli.EnvVars("SLOW_RELAY_THRESHOLD_MS"),
+		Usage:    "only log getHeader relay responses slower than this value, and errors. 0 logs all responses [ms]",
+		Category: LoggingCategory,
+	}
 	// Genesis Flags
 	customGenesisForkFlag = &cli.StringFlag{
 		Name:     "genesis-fork-version",
@@ -90,12 +427,43 @@ var (
 		Usage:    "use a custom genesis fork version",
 		Category: GenesisCategory,
 	}
+	nextForkVersionFlag = &cli.StringFlag{
+		Name:     "next-fork-version",
+		Sources:  cli.EnvVars("NEXT_FORK_VERSION"),
+		Usage:    "also accept relay bids signed under the builder domain of this fork version, preferred from next-fork-epoch",
+		Category: GenesisCategory,
+	}
+	nextForkEpochFlag = &cli.UintFlag{
+		Name:     "next-fork-epoch",
+		Sources:  cli.EnvVars("NEXT_FORK_EPOCH"),
+		Usage:    "epoch at which next-fork-version activates",
+		Category: GenesisCategory,
+	}
+	forkEpochsFlag = &cli.StringSliceFlag{
+		Name:     "fork-epochs",
+		Sources:  cli.EnvVars("FORK_EPOCHS"),
+		Usage:    "activation epochs of the forks, which are known for mainnet, sepolia and holesky, as <fork>=<epoch> - single entry or comma-separated list",
+		Category: GenesisCategory,
+	}
+	signingForkVersionsFlag = &cli.StringSliceFlag{
+		Name:     "signing-fork-versions",
+		Sources:  cli.EnvVars("SIGNING_FORK_VERSIONS"),
+		Usage:    "also accept relay bids signed under the builder domain of these fork versions - single entry or comma-separated list",
+		Category: GenesisCategory,
+	}
 	customGenesisTimeFlag = &cli.UintFlag{
 		Name:     "genesis-timestamp",
 		Sources:  cli.EnvVars("GENESIS_TIMESTAMP"),
 		Usage:    "use a custom genesis timestamp (unix seconds)",
 		Category: GenesisCategory,
 	}
+	secondsPerSlotFlag = &cli.UintFlag{
+		Name:     "seconds-per-slot",
+		Sources:  cli.EnvVars("SECONDS_PER_SLOT"),
+		Value:    12,
+		Usage:    "slot time of the network, for devnets with a custom slot time [s]",
+		Category: GenesisCategory,
+	}
 	mainnetFlag = &cli.BoolFlag{
 		Name:     "mainnet",
 		Sources:  cli.EnvVars("MAINNET"),
@@ -120,20 +488,182 @@ var (
 		Name:     "relay",
 		Aliases:  []string{"relays"},
 		Sources:  cli.EnvVars("RELAYS"),
-		Usage:    "relay urls - single entry or comma-separated list (scheme://pubkey@host)",
+		Usage:    "relay urls - single entry or comma-separated list (scheme://pubkey@host), add ?label=name to name a relay in logs and metrics, ?stream=true to consume its top bid stream, ?pubkey=0x... to also accept bids signed by another relay key, e.g. during a key rotation, ?sniff-gzip=true to decompress gzip responses of a relay which omits the Content-Encoding header, ?fork=electra (repeatable) to only ask a relay for bids in the slots of these forks, ?weight=N to select a relay N times as often in the random bid tie-break, ?min-bid=N to override the minimum bid for a relay [eth], and ?header=Name:Value (repeatable) to send a header with every request to a relay",
 		Category: RelayCategory,
 	}
 	relayMonitorFlag = &cli.StringSliceFlag{
 		Name:     "relay-monitors",
 		Aliases:  []string{"relay-monitor"},
 		Sources:  cli.EnvVars("RELAY_MONITORS"),
-		Usage:    "relay monitor urls - single entry or comma-separated list (scheme://host)",
+		Usage:    "relay monitor urls - single entry or comma-separated list (scheme://host), ?path-prefix=/path replaces the /eth/v1/builder path prefix",
 		Category: RelayCategory,
 	}
 	minBidFlag = &cli.FloatFlag{
 		Name:     "min-bid",
 		Sources:  cli.EnvVars("MIN_BID_ETH"),
-		Usage:    "minimum bid to accept from a relay [eth]",
+		Usage:    "minimum bid to accept from a relay, unless the relay url sets ?min-bid=N [eth]",
+		Category: RelayCategory,
+	}
+	relayPriorityToleranceFlag = &cli.FloatFlag{
+		Name:     "relay-priority-tolerance",
+		Sources:  cli.EnvVars("RELAY_PRIORITY_TOLERANCE_PCT"),
+		Usage:    "bids from relays with a higher priority (?priority=N in the relay url) win if within this percentage of the best bid [%]",
+		Category: RelayCategory,
+	}
+	minBidOverLocalFlag = &cli.FloatFlag{
+		Name:     "min-bid-over-local",
+		Sources:  cli.EnvVars("MIN_BID_OVER_LOCAL_PCT"),
+		Usage:    "bids must exceed the local block value sent by the beacon node in the X-MEVBoost-Local-Block-Value header by this percentage, unless the relay url sets ?min-over-local-pct=N [%]",
+		Category: RelayCategory,
+	}
+	relayOrderHeaderFlag = &cli.BoolFlag{
+		Name:     "relay-order-header",
+		Sources:  cli.EnvVars("RELAY_ORDER_HEADER"),
+		Usage:    "let getHeader requests prioritise relays with the X-MEVBoost-Relay-Order header, a comma-separated list of relay hostnames",
+		Category: RelayCategory,
+	}
+	forwardHeadersFlag = &cli.StringSliceFlag{
+		Name:     "forward-headers",
+		Sources:  cli.EnvVars("FORWARD_HEADERS"),
+		Usage:    "names of beacon node request headers to copy into the getHeader and getPayload requests to relays, like a correlation ID",
+		Category: RelayCategory,
+	}
+	failedDeliveryPolicyFlag = &cli.StringFlag{
+		Name:     "failed-delivery-policy",
+		Sources:  cli.EnvVars("FAILED_DELIVERY_POLICY"),
+		Value:    server.FailedDeliveryPolicyDeprioritize,
+		Usage:    "what to do with bids for a block hash the same relay previously failed to deliver: deprioritize or reject",
+		Category: RelayCategory,
+	}
+	withholdingPenaltyFlag = &cli.IntFlag{
+		Name:     "withholding-penalty",
+		Sources:  cli.EnvVars("WITHHOLDING_PENALTY_SEC"),
+		Usage:    "cooldown of a relay after it withheld a payload, 0 disables it [s]",
+		Category: RelayCategory,
+	}
+	withholdingPenaltyPolicyFlag = &cli.StringFlag{
+		Name:     "withholding-penalty-policy",
+		Sources:  cli.EnvVars("WITHHOLDING_PENALTY_POLICY"),
+		Value:    server.WithholdingPenaltyDeprioritize,
+		Usage:    "what to do with bids of a relay in withholding cooldown: deprioritize or exclude",
+		Category: RelayCategory,
+	}
+	minActiveRelaysFlag = &cli.IntFlag{
+		Name:     "min-active-relays",
+		Sources:  cli.EnvVars("MIN_ACTIVE_RELAYS"),
+		Value:    1,
+		Usage:    "refuse getHeader and getPayload requests with 503 and report unhealthy on /status while fewer relays are active, relays whose bids are excluded by the withholding penalty are inactive. 0 disables it",
+		Category: RelayCategory,
+	}
+	minBiddingRelaysFlag = &cli.IntFlag{
+		Name:     "min-bidding-relays",
+		Sources:  cli.EnvVars("MIN_BIDDING_RELAYS"),
+		Usage:    "minimum number of relays with an acceptable bid in an auction, identical bids of mirrored relays count once per relay. 0 disables it",
+		Category: RelayCategory,
+	}
+	minBiddingRelaysPolicyFlag = &cli.StringFlag{
+		Name:     "min-bidding-relays-policy",
+		Sources:  cli.EnvVars("MIN_BIDDING_RELAYS_POLICY"),
+		Value:    server.MinBiddingRelaysWarn,
+		Usage:    "what to do with auctions with fewer bidding relays than the minimum: warn (serve the bid as low confidence) or strict (respond without a bid)",
+		Category: RelayCategory,
+	}
+	withholdingEventsRetentionFlag = &cli.IntFlag{
+		Name:     "withholding-events-retention",
+		Sources:  cli.EnvVars("WITHHOLDING_EVENTS_RETENTION_SEC"),
+		Value:    86400,
+		Usage:    "how long withholding events are listed at /debug/withholding [s]",
+		Category: RelayCategory,
+	}
+	relayFailurePolicyFlag = &cli.StringFlag{
+		Name:     "relay-failure-policy",
+		Sources:  cli.EnvVars("RELAY_FAILURE_POLICY"),
+		Value:    server.RelayFailurePolicyNoBid,
+		Usage:    "what to do when every relay fails in getHeader early in the slot: no-bid, retry (retry the auction once within the timeout) or retry-after (502 with a Retry-After header)",
+		Category: RelayCategory,
+	}
+	checkBidTimestampFlag = &cli.BoolFlag{
+		Name:     "check-bid-timestamp",
+		Sources:  cli.EnvVars("CHECK_BID_TIMESTAMP"),
+		Usage:    "reject bids whose payload timestamp is not the start of the requested slot, e.g. bids cached by the relay for an earlier slot",
+		Value:    true,
+		Category: RelayCategory,
+	}
+	strictPubkeyCheckFlag = &cli.BoolFlag{
+		Name:     "strict-pubkey-check",
+		Sources:  cli.EnvVars("STRICT_PUBKEY_CHECK"),
+		Usage:    "reject getHeader requests for pubkeys which are not valid BLS public keys",
+		Category: RelayCategory,
+	}
+	requestSigningKeyFlag = &cli.StringFlag{
+		Name:     "request-signing-key",
+		Sources:  cli.EnvVars("REQUEST_SIGNING_KEY"),
+		Usage:    "sign getHeader and registerValidator requests to relays with this hex encoded operator key",
+		Category: RelayCategory,
+	}
+	requestSigningSchemeFlag = &cli.StringFlag{
+		Name:     "request-signing-scheme",
+		Sources:  cli.EnvVars("REQUEST_SIGNING_SCHEME"),
+		Value:    server.RequestSigningSchemeBLS,
+		Usage:    "scheme of the request signing key: bls or ed25519 (32 byte seed)",
+		Category: RelayCategory,
+	}
+	builderAllowlistFlag = &cli.StringSliceFlag{
+		Name:     "builder-allowlist",
+		Sources:  cli.EnvVars("BUILDER_ALLOWLIST"),
+		Usage:    "only accept bids signed by these builder pubkeys - single entry or comma-separated list",
+		Category: RelayCategory,
+	}
+	builderDenylistFlag = &cli.StringSliceFlag{
+		Name:     "builder-denylist",
+		Sources:  cli.EnvVars("BUILDER_DENYLIST"),
+		Usage:    "ignore bids signed by these builder pubkeys, also if they are on the allowlist - single entry or comma-separated list",
+		Category: RelayCategory,
+	}
+	bidTieBreakFlag = &cli.StringFlag{
+		Name:     "bid-tie-break",
+		Sources:  cli.EnvVars("BID_TIE_BREAK"),
+		Value:    server.BidTieBreakRelayPosition,
+		Usage:    "which relay's copy of the same bid to use when several relays deliver it: relay-position, reliability (fewest recent failed deliveries) or random",
+		Category: RelayCategory,
+	}
+	conflictingBidsFlag = &cli.StringFlag{
+		Name:     "conflicting-bids",
+		Sources:  cli.EnvVars("CONFLICTING_BIDS"),
+		Value:    server.ConflictingBidsLowest,
+		Usage:    "what to do when relays bid different values or builder pubkeys for the same block hash: lowest (keep the bids with the lowest value) or drop (ignore all of them)",
+		Category: RelayCategory,
+	}
+	serveCachedBidFlag = &cli.BoolFlag{
+		Name:     "serve-cached-bid",
+		Sources:  cli.EnvVars("SERVE_CACHED_BID"),
+		Usage:    "when all relays fail in getHeader, serve the most recent bid already served for the same slot, parent hash and proposer",
+		Category: RelayCategory,
+	}
+	cachedBidMaxAgeFlag = &cli.IntFlag{
+		Name:     "cached-bid-max-age",
+		Sources:  cli.EnvVars("CACHED_BID_MAX_AGE_MS"),
+		Usage:    "maximum age of a cached bid served when all relays fail, 0 allows bids until the end of their slot [ms]",
+		Category: RelayCategory,
+	}
+	validationLevelFlag = &cli.StringFlag{
+		Name:     "validation-level",
+		Sources:  cli.EnvVars("VALIDATION_LEVEL"),
+		Value:    string(server.ValidationLevelStrict),
+		Usage:    "how much of the relay bids and payloads is verified: none, basic (signatures, block hashes and KZG commitments, as in earlier releases) or strict (also transactions roots, so payloads accepted at basic can be rejected, and logs signed execution requests which differ from the bid)",
+		Category: RelayCategory,
+	}
+	relayContentTypeCheckFlag = &cli.StringFlag{
+		Name:     "relay-content-type-check",
+		Sources:  cli.EnvVars("RELAY_CONTENT_TYPE_CHECK"),
+		Value:    server.ContentTypeCheckLenient,
+		Usage:    "relay responses which are neither JSON nor SSZ: strict (rejected), lenient (reported if they cannot be decoded) or off",
+		Category: RelayCategory,
+	}
+	executionRPCFlag = &cli.StringFlag{
+		Name:     "execution-rpc",
+		Sources:  cli.EnvVars("EXECUTION_RPC_URL"),
+		Usage:    "execution client JSON-RPC url, used to audit the payment the proposer received for delivered payloads",
 		Category: RelayCategory,
 	}
 	relayCheckFlag = &cli.BoolFlag{
@@ -142,6 +672,31 @@ var (
 		Usage:    "check relay status on startup and on the status API call",
 		Category: RelayCategory,
 	}
+	relayCheckReadinessFlag = &cli.BoolFlag{
+		Name:     "relay-check-readiness",
+		Sources:  cli.EnvVars("RELAY_CHECK_READINESS"),
+		Usage:    "report unavailable on the status API call until the initial relay check has finished",
+		Category: RelayCategory,
+	}
+	relayCheckStartupTimeoutFlag = &cli.IntFlag{
+		Name:     "relay-check-startup-timeout",
+		Sources:  cli.EnvVars("RELAY_CHECK_STARTUP_TIMEOUT_MS"),
+		Usage:    "maximum time to wait for the initial relay check [ms]",
+		Value:    5000,
+		Category: RelayCategory,
+	}
+	prewarmRelayConnectionsFlag = &cli.BoolFlag{
+		Name:     "prewarm-relay-connections",
+		Sources:  cli.EnvVars("PREWARM_RELAY_CONNECTIONS"),
+		Usage:    "open a connection to each relay at startup and keep it warm, so that the first getHeader request does not wait for a TLS handshake",
+		Category: RelayCategory,
+	}
+	strictRelaySchemaFlag = &cli.BoolFlag{
+		Name:     "strict-relay-schema",
+		Sources:  cli.EnvVars("STRICT_RELAY_SCHEMA"),
+		Usage:    "reject relay bids which violate the builder spec, instead of only logging the violations",
+		Category: RelayCategory,
+	}
 	// mev-boost relay request timeouts (see also https://github.com/flashbots/mev-boost/issues/287)
 	timeoutGetHeaderFlag = &cli.IntFlag{
 		Name:     "request-timeout-getheader",
@@ -150,6 +705,24 @@ var (
 		Value:    950,
 		Category: RelayCategory,
 	}
+	timeoutGetHeaderForkFlag = &cli.StringSliceFlag{
+		Name:     "request-timeout-getheader-fork",
+		Sources:  cli.EnvVars("RELAY_TIMEOUT_MS_GETHEADER_FORK"),
+		Usage:    "timeout for getHeader requests to the relay in the slots of a fork, as <fork>=<ms> - single entry or comma-separated list",
+		Category: RelayCategory,
+	}
+	getHeaderSlotDeadlineFlag = &cli.IntFlag{
+		Name:     "getheader-slot-deadline",
+		Sources:  cli.EnvVars("GETHEADER_SLOT_DEADLINE_MS"),
+		Usage:    "time into the slot after which getHeader stops waiting for relays, 0 only applies the getHeader timeout [ms]",
+		Category: RelayCategory,
+	}
+	getHeaderRetryTimeoutFractionFlag = &cli.FloatFlag{
+		Name:     "getheader-retry-timeout-fraction",
+		Sources:  cli.EnvVars("GETHEADER_RETRY_TIMEOUT_FRACTION"),
+		Usage:    "fraction of the getHeader budget for retries of the same slot, parent hash and validator, which are also served the earlier bid if it is more valuable, 0 uses the full budget",
+		Category: RelayCategory,
+	}
 	timeoutGetPayloadFlag = &cli.IntFlag{
 		Name:     "request-timeout-getpayload",
 		Sources:  cli.EnvVars("RELAY_TIMEOUT_MS_GETPAYLOAD"),
@@ -157,6 +730,25 @@ var (
 		Value:    4000,
 		Category: RelayCategory,
 	}
+	timeoutDialFlag = &cli.IntFlag{
+		Name:     "request-timeout-dial",
+		Sources:  cli.EnvVars("RELAY_TIMEOUT_MS_DIAL"),
+		Usage:    "timeout for connecting to a relay, 0 uses the default of 30s [ms]",
+		Category: RelayCategory,
+	}
+	timeoutTLSHandshakeFlag = &cli.IntFlag{
+		Name:     "request-timeout-tls-handshake",
+		Sources:  cli.EnvVars("RELAY_TIMEOUT_MS_TLS_HANDSHAKE"),
+		Usage:    "timeout for the TLS handshake with a relay, 0 uses the default of 10s [ms]",
+		Category: RelayCategory,
+	}
+	relayTLSMinVersionFlag = &cli.StringFlag{
+		Name:     "relay-tls-min-version",
+		Sources:  cli.EnvVars("RELAY_TLS_MIN_VERSION"),
+		Usage:    "minimum TLS version of connections to relays: 1.2 or 1.3",
+		Value:    server.RelayTLSVersion12,
+		Category: RelayCategory,
+	}
 	timeoutRegValFlag = &cli.IntFlag{
 		Name:     "request-timeout-regval",
 		Sources:  cli.EnvVars("RELAY_TIMEOUT_MS_REGVAL"),
@@ -164,6 +756,88 @@ var (
 		Value:    3000,
 		Category: RelayCategory,
 	}
+	timeoutRelayMonitorFlag = &cli.IntFlag{
+		Name:     "request-timeout-relay-monitor",
+		Sources:  cli.EnvVars("RELAY_TIMEOUT_MS_RELAY_MONITOR"),
+		Usage:    "timeout for registerValidator requests to relay monitors [ms], 0 for the registerValidator timeout",
+		Category: RelayCategory,
+	}
+	maxRegistrationBatchSizeFlag = &cli.IntFlag{
+		Name:     "max-registration-batch-size",
+		Sources:  cli.EnvVars("MAX_REGISTRATION_BATCH_SIZE"),
+		Value:    server.DefaultMaxRegistrationBatchSize,
+		Usage:    "maximum number of validator registrations accepted in a single request",
+		Category: RelayCategory,
+	}
+	registrationJitterFlag = &cli.IntFlag{
+		Name:     "registration-jitter",
+		Sources:  cli.EnvVars("REGISTRATION_JITTER_MS"),
+		Usage:    "maximum random delay of each relay registerValidator request, below half of the registerValidator timeout [ms]",
+		Category: RelayCategory,
+	}
+	feeRecipientAuditFlag = &cli.BoolFlag{
+		Name:     "fee-recipient-audit",
+		Sources:  cli.EnvVars("FEE_RECIPIENT_AUDIT"),
+		Usage:    "keep the recent fee recipients of each validator, served on /debug/fee-recipients, and warn when a validator registers a different fee recipient",
+		Category: RelayCategory,
+	}
+	approvedFeeRecipientsFlag = &cli.StringSliceFlag{
+		Name:     "approved-fee-recipients",
+		Sources:  cli.EnvVars("APPROVED_FEE_RECIPIENTS"),
+		Usage:    "only forward validator registrations with these fee recipients - single entry or comma-separated list",
+		Category: RelayCategory,
+	}
+	verifyRegistrationSignaturesFlag = &cli.BoolFlag{
+		Name:     "verify-registration-signatures",
+		Sources:  cli.EnvVars("VERIFY_REGISTRATION_SIGNATURES"),
+		Usage:    "verify the signature of each validator registration before forwarding it, and drop the invalid ones (CPU intensive)",
+		Category: RelayCategory,
+	}
+	maxPayloadResponseSizeFlag = &cli.IntFlag{
+		Name:     "max-payload-response-size",
+		Sources:  cli.EnvVars("MAX_PAYLOAD_RESPONSE_MB"),
+		Value:    server.DefaultMaxPayloadResponseSize >> 20,
+		Usage:    "maximum size of a relay getPayload response, larger responses are ignored [MB]",
+		Category: RelayCategory,
+	}
+	maxPayloadRequestSizeFlag = &cli.IntFlag{
+		Name:     "max-payload-request-size",
+		Sources:  cli.EnvVars("MAX_PAYLOAD_REQUEST_MB"),
+		Value:    server.DefaultMaxPayloadRequestSize >> 20,
+		Usage:    "maximum size of a getPayload request body of the beacon node, larger requests are rejected with 413 [MB]",
+		Category: RelayCategory,
+	}
+	maxCachedSlotsFlag = &cli.IntFlag{
+		Name:     "max-cached-slots",
+		Sources:  cli.EnvVars("MAX_CACHED_SLOTS"),
+		Usage:    "maximum number of distinct slots of which bids are cached, evicting the oldest slots first. 0 only evicts bids by age",
+		Category: RelayCategory,
+	}
+	blockNumberSpreadFlag = &cli.UintFlag{
+		Name:     "blocknumber-spread",
+		Sources:  cli.EnvVars("BLOCK_NUMBER_SPREAD"),
+		Value:    server.DefaultBlockNumberSpread,
+		Usage:    "spread of the block numbers of the bids of an auction above which relays bidding on another block number than most relays are reported",
+		Category: RelayCategory,
+	}
+	relayDNSCacheTTLFlag = &cli.IntFlag{
+		Name:     "relay-dns-cache-ttl",
+		Sources:  cli.EnvVars("RELAY_DNS_CACHE_TTL_SEC"),
+		Usage:    "reuse the resolved addresses of relay hosts for this long, 0 resolves them for every new connection [s]",
+		Category: RelayCategory,
+	}
+	relayLocalAddrFlag = &cli.StringFlag{
+		Name:     "relay-local-addr",
+		Sources:  cli.EnvVars("RELAY_LOCAL_ADDR"),
+		Usage:    "source IP address or network interface of connections to relays, falls back to the default route if it cannot be bound",
+		Category: RelayCategory,
+	}
+	followRelayRedirectsSameHostFlag = &cli.BoolFlag{
+		Name:     "follow-relay-redirects-same-host",
+		Sources:  cli.EnvVars("FOLLOW_RELAY_REDIRECTS_SAME_HOST"),
+		Usage:    "follow a single redirect of a relay to the same host and scheme, other redirects are never followed",
+		Category: RelayCategory,
+	}
 	maxRetriesFlag = &cli.IntFlag{
 		Name:     "request-max-retries",
 		Sources:  cli.EnvVars("REQUEST_MAX_RETRIES"),
@@ -171,4 +845,11 @@ var (
 		Value:    5,
 		Category: RelayCategory,
 	}
+	retryOnFlag = &cli.StringSliceFlag{
+		Name:     "request-retry-on",
+		Sources:  cli.EnvVars("REQUEST_RETRY_ON"),
+		Usage:    "conditions of failed relay get payload requests which are retried: connection, timeout, 5xx, 4xx and invalid_response, optionally with a lower maximum number of retries like 5xx=2",
+		Value:    server.DefaultRetryConditions,
+		Category: RelayCategory,
+	}
 )
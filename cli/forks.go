@@ -0,0 +1,101 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/attestantio/go-eth2-client/spec"
+	"github.com/flashbots/mev-boost/server"
+)
+
+var errInvalidForkEntry = errors.New("invalid fork entry, expected <fork>=<value>")
+
+// Activation epochs of the forks with a builder API, by network
+var (
+	forkEpochsMainnet = map[spec.DataVersion]uint64{
+		spec.DataVersionBellatrix: 144896,
+		spec.DataVersionCapella:   194048,
+		spec.DataVersionDeneb:     269568,
+		spec.DataVersionElectra:   364032,
+	}
+	forkEpochsSepolia = map[spec.DataVersion]uint64{
+		spec.DataVersionBellatrix: 100,
+		spec.DataVersionCapella:   56832,
+		spec.DataVersionDeneb:     132608,
+		spec.DataVersionElectra:   222464,
+	}
+	forkEpochsHolesky = map[spec.DataVersion]uint64{
+		spec.DataVersionBellatrix: 0,
+		spec.DataVersionCapella:   256,
+		spec.DataVersionDeneb:     29696,
+		spec.DataVersionElectra:   115968,
+	}
+)
+
+// parseForkValues parses entries of <fork>=<value>, like deneb=1200
+func parseForkValues(entries []string) (map[spec.DataVersion]uint64, error) {
+	values := make(map[spec.DataVersion]uint64)
+	for _, entry := range entries {
+		if entry == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", errInvalidForkEntry, entry)
+		}
+		fork, err := server.ParseFork(strings.TrimSpace(name))
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := values[fork]; ok {
+			return nil, fmt.Errorf("%w: %s", errDuplicateEntry, fork)
+		}
+		values[fork], err = strconv.ParseUint(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %w", errInvalidForkEntry, entry, err)
+		}
+	}
+	return values, nil
+}
+
+// setupForkEpochs returns the fork activation epochs of the network, with the epochs of the fork-epochs flag
+func setupForkEpochs(cmd optionSource, report *configReport) map[spec.DataVersion]uint64 {
+	epochs := make(map[spec.DataVersion]uint64)
+	if !cmd.IsSet(customGenesisForkFlag.Name) {
+		switch {
+		case cmd.Bool(sepoliaFlag.Name):
+			epochs = maps.Clone(forkEpochsSepolia)
+		case cmd.Bool(holeskyFlag.Name):
+			epochs = maps.Clone(forkEpochsHolesky)
+		case cmd.Bool(mainnetFlag.Name):
+			epochs = maps.Clone(forkEpochsMainnet)
+		}
+	}
+
+	overrides, err := parseForkValues(parseList(cmd, forkEpochsFlag.Name))
+	if err != nil {
+		report.fail(err, "invalid fork epochs", nil)
+	}
+	maps.Copy(epochs, overrides)
+	return epochs
+}
+
+// setupGetHeaderForkTimeouts returns the getHeader timeouts of the request-timeout-getheader-fork flag
+func setupGetHeaderForkTimeouts(cmd optionSource, report *configReport) map[spec.DataVersion]time.Duration {
+	values, err := parseForkValues(parseList(cmd, timeoutGetHeaderForkFlag.Name))
+	if err != nil {
+		report.fail(err, "invalid per-fork getHeader timeouts", nil)
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	timeouts := make(map[spec.DataVersion]time.Duration, len(values))
+	for fork, ms := range values {
+		timeouts[fork] = time.Duration(ms) * time.Millisecond
+	}
+	return timeouts
+}
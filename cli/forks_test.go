@@ -0,0 +1,45 @@
+package cli
+
+import (
+	"testing"
+	"time"
+
+	"github.com/attestantio/go-eth2-client/spec"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseForkValues(t *testing.T) {
+	values, err := parseForkValues([]string{"deneb=1200", " Electra = 1500", ""})
+	require.NoError(t, err)
+	require.Equal(t, map[spec.DataVersion]uint64{spec.DataVersionDeneb: 1200, spec.DataVersionElectra: 1500}, values)
+
+	_, err = parseForkValues([]string{"deneb"})
+	require.ErrorIs(t, err, errInvalidForkEntry)
+	_, err = parseForkValues([]string{"deneb=soon"})
+	require.ErrorIs(t, err, errInvalidForkEntry)
+	_, err = parseForkValues([]string{"deneb=1", "deneb=2"})
+	require.ErrorIs(t, err, errDuplicateEntry)
+	_, err = parseForkValues([]string{"altair=1"})
+	require.Error(t, err)
+}
+
+func TestSetupForkEpochs(t *testing.T) {
+	_, options, err := runCommand(t, "--mainnet", "--fork-epochs", "electra=400000",
+		"--request-timeout-getheader-fork", "electra=1200")
+	require.NoError(t, err)
+	report := &configReport{check: true}
+	opts := buildServiceOpts(options, report)
+	require.Empty(t, report.problems)
+
+	// The network schedule, with the epochs of the flag
+	require.Equal(t, forkEpochsMainnet[spec.DataVersionDeneb], opts.ForkEpochs[spec.DataVersionDeneb])
+	require.Equal(t, uint64(400000), opts.ForkEpochs[spec.DataVersionElectra])
+	require.Equal(t, uint64(364032), forkEpochsMainnet[spec.DataVersionElectra])
+	require.Equal(t, map[spec.DataVersion]time.Duration{spec.DataVersionElectra: 1200 * time.Millisecond}, opts.GetHeaderTimeoutByFork)
+
+	// Custom networks only have the epochs of the flag
+	_, options, err = runCommand(t, "--genesis-fork-version", "0x01020304", "--fork-epochs", "deneb=5")
+	require.NoError(t, err)
+	opts = buildServiceOpts(options, &configReport{check: true})
+	require.Equal(t, map[spec.DataVersion]uint64{spec.DataVersionDeneb: 5}, opts.ForkEpochs)
+}
@@ -6,10 +6,15 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 	"time"
 
+	"github.com/attestantio/go-eth2-client/spec/bellatrix"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
 	"github.com/flashbots/go-boost-utils/types"
+	"github.com/flashbots/go-boost-utils/utils"
 	"github.com/flashbots/mev-boost/common"
 	"github.com/flashbots/mev-boost/config"
 	"github.com/flashbots/mev-boost/server"
@@ -23,6 +28,10 @@ const (
 	genesisForkVersionGoerli  = "0x00001020"
 	genesisForkVersionHolesky = "0x01017000"
 
+	// shutdownTimeout is how long shutdown waits for in-flight and expected getPayload requests, the HTTP servers then
+	// get their own budget to finish their requests
+	shutdownTimeout = 10 * time.Second
+
 	genesisTimeMainnet = 1606824023
 	genesisTimeSepolia = 1655733600
 	genesisTimeGoerli  = 1614588812
@@ -44,6 +53,9 @@ func Main() {
 		Usage:  "mev-boost implementation, see help for more info",
 		Action: start,
 		Flags:  flags,
+		Commands: []*cli.Command{
+			probeRelaysCommand,
+		},
 	}
 
 	if err := cmd.Run(context.Background(), os.Args); err != nil {
@@ -59,67 +71,203 @@ func start(_ context.Context, cmd *cli.Command) error {
 		return nil
 	}
 
-	if err := setupLogging(cmd); err != nil {
+	// Options which are not set by flags or environment variables are read from the config file
+	options, configFileErr := newFileOptions(cmd)
+	if err := setupLogging(options); err != nil {
 		flag.Usage()
 		log.WithError(err).Fatal("failed setting up logging")
 	}
 
-	var (
-		genesisForkVersion, genesisTime      = setupGenesis(cmd)
-		relays, monitors, minBid, relayCheck = setupRelays(cmd)
-		listenAddr                           = cmd.String(addrFlag.Name)
-	)
+	// With --check-config, all problems of the configuration are reported instead of starting
+	report := &configReport{check: cmd.Bool(checkConfigFlag.Name)}
+	if configFileErr != nil {
+		report.fail(configFileErr, "Failed loading the config file", nil)
+	}
 
-	opts := server.BoostServiceOpts{
-		Log:                      log,
-		ListenAddr:               listenAddr,
-		Relays:                   relays,
-		RelayMonitors:            monitors,
-		GenesisForkVersionHex:    genesisForkVersion,
-		GenesisTime:              genesisTime,
-		RelayCheck:               relayCheck,
-		RelayMinBid:              minBid,
-		RequestTimeoutGetHeader:  time.Duration(cmd.Int(timeoutGetHeaderFlag.Name)) * time.Millisecond,
-		RequestTimeoutGetPayload: time.Duration(cmd.Int(timeoutGetPayloadFlag.Name)) * time.Millisecond,
-		RequestTimeoutRegVal:     time.Duration(cmd.Int(timeoutRegValFlag.Name)) * time.Millisecond,
-		RequestMaxRetries:        int(cmd.Int(maxRetriesFlag.Name)),
+	opts := buildServiceOpts(options, report)
+	if report.check {
+		report.problems = append(report.problems, server.ValidateConfig(opts)...)
+		return report.print(cmd.Writer)
 	}
+
 	service, err := server.NewBoostService(opts)
 	if err != nil {
 		log.WithError(err).Fatal("failed creating the server")
 	}
 
-	if relayCheck && service.CheckRelays() == 0 {
+	// With the readiness gate, the initial relay check runs in the background once the server starts
+	if opts.RelayCheck && !opts.RelayCheckReadiness && service.CheckRelays() == 0 {
 		log.Error("no relay passed the health-check!")
 	}
 
-	log.Infof("Listening on %v", listenAddr)
-	return service.StartHTTPServer()
+	if opts.MetricsAddr != "" {
+		go func() {
+			log.Infof("metrics server listening on %v", opts.MetricsAddr)
+			if err := service.StartMetricsServer(); err != nil {
+				log.WithError(err).Error("metrics server failed")
+			}
+		}()
+	}
+	if options.path != "" {
+		go reloadConfigFileOnSighup(cmd, service)
+	}
+
+	log.Infof("Listening on %v", opts.ListenAddr)
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- service.StartHTTPServer()
+	}()
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+	select {
+	case err := <-serverErr:
+		return err
+	case sig := <-stop:
+		log.WithField("signal", sig.String()).Info("shutting down")
+	case <-service.Drained():
+		log.Info("drained, shutting down")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	return service.Stop(ctx)
 }
 
-func setupRelays(cmd *cli.Command) (relayList, relayMonitorList, types.U256Str, bool) {
-	// For backwards compatibility with the -relays flag.
+// buildServiceOpts returns the options of the service, and reports the problems of the configuration
+func buildServiceOpts(cmd optionSource, report *configReport) server.BoostServiceOpts {
 	var (
-		relays   relayList
-		monitors relayMonitorList
+		genesisForkVersion, genesisTime      = setupGenesis(cmd, report)
+		relays, monitors, minBid, relayCheck = setupRelays(cmd, report)
+		listenAddr                           = cmd.String(addrFlag.Name)
+		metricsAddr                          string
 	)
-	if cmd.IsSet(relaysFlag.Name) {
-		relayURLs := cmd.StringSlice(relaysFlag.Name)
-		for _, urls := range relayURLs {
-			for _, url := range strings.Split(urls, ",") {
-				if err := relays.Set(strings.TrimSpace(url)); err != nil {
-					log.WithError(err).WithField("relay", url).Fatal("Invalid relay URL")
-				}
-			}
-		}
+	if cmd.Bool(metricsFlag.Name) {
+		metricsAddr = cmd.String(metricsAddrFlag.Name)
 	}
 
-	if len(relays) == 0 {
+	return server.BoostServiceOpts{
+		Log:                          log,
+		ListenAddr:                   listenAddr,
+		MetricsAddr:                  metricsAddr,
+		StatsdAddr:                   cmd.String(statsdAddrFlag.Name),
+		StatsdPrefix:                 cmd.String(statsdPrefixFlag.Name),
+		StatsdDialect:                cmd.String(statsdDialectFlag.Name),
+		AlertWebhookURL:              cmd.String(alertWebhookURLFlag.Name),
+		AlertCommand:                 cmd.String(alertCommandFlag.Name),
+		AlertMinInterval:             time.Duration(cmd.Int(alertMinIntervalFlag.Name)) * time.Second,
+		AlertUnhealthyChecks:         int(cmd.Int(alertUnhealthyChecksFlag.Name)),
+		AlertNoBidSlots:              int(cmd.Int(alertNoBidSlotsFlag.Name)),
+		Relays:                       relays,
+		RelayMonitors:                monitors,
+		GenesisForkVersionHex:        genesisForkVersion,
+		GenesisTime:                  genesisTime,
+		SecondsPerSlot:               cmd.Uint(secondsPerSlotFlag.Name),
+		CheckBidTimestamp:            cmd.Bool(checkBidTimestampFlag.Name),
+		NextForkVersionHex:           cmd.String(nextForkVersionFlag.Name),
+		NextForkEpoch:                cmd.Uint(nextForkEpochFlag.Name),
+		ExtraSigningForkVersions:     parseList(cmd, signingForkVersionsFlag.Name),
+		ForkEpochs:                   setupForkEpochs(cmd, report),
+		RelayCheck:                   relayCheck,
+		RelayMinBid:                  minBid,
+		RelayPriorityTolerancePct:    cmd.Float(relayPriorityToleranceFlag.Name),
+		MinBidOverLocalPct:           cmd.Float(minBidOverLocalFlag.Name),
+		RelayOrderHeader:             cmd.Bool(relayOrderHeaderFlag.Name),
+		ForwardHeaders:               parseList(cmd, forwardHeadersFlag.Name),
+		TimingHeader:                 cmd.Bool(timingHeaderFlag.Name),
+		CompatShims:                  cmd.Bool(compatShimsFlag.Name),
+		ConsensusVersionShadow:       cmd.Bool(consensusVersionShadowFlag.Name),
+		InferConsensusVersion:        cmd.Bool(inferConsensusVersionFlag.Name),
+		SlowRelayThreshold:           time.Duration(cmd.Int(slowRelayThresholdFlag.Name)) * time.Millisecond,
+		StrictRelaySchema:            cmd.Bool(strictRelaySchemaFlag.Name),
+		DebugEndpoints:               cmd.Bool(debugEndpointsFlag.Name),
+		FailedDeliveryPolicy:         cmd.String(failedDeliveryPolicyFlag.Name),
+		WithholdingPenalty:           time.Duration(cmd.Int(withholdingPenaltyFlag.Name)) * time.Second,
+		WithholdingEventsRetention:   time.Duration(cmd.Int(withholdingEventsRetentionFlag.Name)) * time.Second,
+		WithholdingPenaltyPolicy:     cmd.String(withholdingPenaltyPolicyFlag.Name),
+		MinActiveRelays:              int(cmd.Int(minActiveRelaysFlag.Name)),
+		MinBiddingRelays:             int(cmd.Int(minBiddingRelaysFlag.Name)),
+		MinBiddingRelaysPolicy:       cmd.String(minBiddingRelaysPolicyFlag.Name),
+		RelayFailurePolicy:           cmd.String(relayFailurePolicyFlag.Name),
+		StrictPubkeyCheck:            cmd.Bool(strictPubkeyCheckFlag.Name),
+		RequestSigningKey:            cmd.String(requestSigningKeyFlag.Name),
+		RequestSigningScheme:         cmd.String(requestSigningSchemeFlag.Name),
+		ServeCachedBid:               cmd.Bool(serveCachedBidFlag.Name),
+		CachedBidMaxAge:              time.Duration(cmd.Int(cachedBidMaxAgeFlag.Name)) * time.Millisecond,
+		BidTieBreak:                  cmd.String(bidTieBreakFlag.Name),
+		ConflictingBids:              cmd.String(conflictingBidsFlag.Name),
+		BuilderAllowlist:             parseBuilderPubkeys(cmd, builderAllowlistFlag.Name, report),
+		BuilderDenylist:              parseBuilderPubkeys(cmd, builderDenylistFlag.Name, report),
+		AdminEndpoints:               cmd.Bool(adminEndpointsFlag.Name),
+		AdminToken:                   cmd.String(adminTokenFlag.Name),
+		CORSAllowedOrigins:           parseList(cmd, corsAllowedOriginsFlag.Name),
+		CORSAllowedHeaders:           parseList(cmd, corsAllowedHeadersFlag.Name),
+		CORSMaxAge:                   time.Duration(cmd.Int(corsMaxAgeFlag.Name)) * time.Second,
+		ValidationLevel:              server.ValidationLevel(cmd.String(validationLevelFlag.Name)),
+		RelayContentTypeCheck:        cmd.String(relayContentTypeCheckFlag.Name),
+		ExecutionRPCURL:              cmd.String(executionRPCFlag.Name),
+		TenantsFile:                  cmd.String(tenantsFileFlag.Name),
+		PayloadOutcomesFile:          cmd.String(payloadOutcomesFileFlag.Name),
+		BidMetadataFile:              cmd.String(bidMetadataFileFlag.Name),
+		PayloadArtifactsDir:          cmd.String(payloadArtifactsDirFlag.Name),
+		ChaosConfig:                  cmd.String(chaosConfigFlag.Name),
+		ChaosAllowMainnet:            cmd.Bool(chaosAllowMainnetFlag.Name),
+		ChaosDelay:                   time.Duration(cmd.Int(chaosDelayFlag.Name)) * time.Millisecond,
+		ChaosDelayEnabled:            cmd.Bool(chaosDelayEnableFlag.Name),
+		APIAuthToken:                 cmd.String(apiAuthTokenFlag.Name),
+		APIAuthTokenFile:             cmd.String(apiAuthTokenFileFlag.Name),
+		AllowedClientCIDRs:           parseList(cmd, allowedClientCIDRsFlag.Name),
+		TrustedProxies:               parseList(cmd, trustedProxiesFlag.Name),
+		RelayCheckReadiness:          cmd.Bool(relayCheckReadinessFlag.Name),
+		RelayCheckStartupTimeout:     time.Duration(cmd.Int(relayCheckStartupTimeoutFlag.Name)) * time.Millisecond,
+		PrewarmRelayConnections:      cmd.Bool(prewarmRelayConnectionsFlag.Name),
+		SelfMonitorInterval:          time.Duration(cmd.Int(selfMonitorIntervalFlag.Name)) * time.Millisecond,
+		ResourceSoftLimits:           parseResourceLimits(cmd, resourceSoftLimitsFlag.Name, report),
+		ResourceHardLimits:           parseResourceLimits(cmd, resourceHardLimitsFlag.Name, report),
+		RequestTimeoutGetHeader:      time.Duration(cmd.Int(timeoutGetHeaderFlag.Name)) * time.Millisecond,
+		GetHeaderTimeoutByFork:       setupGetHeaderForkTimeouts(cmd, report),
+		GetHeaderSlotDeadline:        time.Duration(cmd.Int(getHeaderSlotDeadlineFlag.Name)) * time.Millisecond,
+		RetryTimeoutFraction:         cmd.Float(getHeaderRetryTimeoutFractionFlag.Name),
+		RequestTimeoutGetPayload:     time.Duration(cmd.Int(timeoutGetPayloadFlag.Name)) * time.Millisecond,
+		RequestTimeoutRegVal:         time.Duration(cmd.Int(timeoutRegValFlag.Name)) * time.Millisecond,
+		RequestTimeoutRelayMonitor:   time.Duration(cmd.Int(timeoutRelayMonitorFlag.Name)) * time.Millisecond,
+		RegistrationJitter:           time.Duration(cmd.Int(registrationJitterFlag.Name)) * time.Millisecond,
+		RelayDialTimeout:             time.Duration(cmd.Int(timeoutDialFlag.Name)) * time.Millisecond,
+		RelayTLSHandshakeTimeout:     time.Duration(cmd.Int(timeoutTLSHandshakeFlag.Name)) * time.Millisecond,
+		RelayTLSMinVersion:           cmd.String(relayTLSMinVersionFlag.Name),
+		RequestMaxRetries:            int(cmd.Int(maxRetriesFlag.Name)),
+		RequestRetryOn:               parseList(cmd, retryOnFlag.Name),
+		MaxRegistrationBatchSize:     int(cmd.Int(maxRegistrationBatchSizeFlag.Name)),
+		FeeRecipientAudit:            cmd.Bool(feeRecipientAuditFlag.Name),
+		ApprovedFeeRecipients:        parseFeeRecipients(cmd, approvedFeeRecipientsFlag.Name, report),
+		VerifyRegistrationSignatures: cmd.Bool(verifyRegistrationSignaturesFlag.Name),
+		MaxPayloadResponseSize:       cmd.Int(maxPayloadResponseSizeFlag.Name) << 20,
+		MaxPayloadRequestSize:        cmd.Int(maxPayloadRequestSizeFlag.Name) << 20,
+		RelayDNSCacheTTL:             time.Duration(cmd.Int(relayDNSCacheTTLFlag.Name)) * time.Second,
+		RelayLocalAddr:               cmd.String(relayLocalAddrFlag.Name),
+		FollowRelayRedirectsSameHost: cmd.Bool(followRelayRedirectsSameHostFlag.Name),
+		MaxCachedSlots:               int(cmd.Int(maxCachedSlotsFlag.Name)),
+		BlockNumberSpread:            cmd.Uint(blockNumberSpreadFlag.Name),
+		SessionSummaryFile:           cmd.String(sessionSummaryFileFlag.Name),
+	}
+}
+
+func setupRelays(cmd optionSource, report *configReport) (relayList, relayMonitorList, types.U256Str, bool) {
+	var monitors relayMonitorList
+	relays := parseRelays(cmd, report)
+	if len(relays) == 0 && !report.check {
 		log.Fatal("no relays specified")
 	}
 	log.Infof("using %d relays", len(relays))
 	for index, relay := range relays {
-		log.Infof("relay #%d: %s", index+1, relay.String())
+		if len(relay.AdditionalPublicKeys) > 0 {
+			log.Infof("relay #%d: %s also accepts bids signed by %d additional keys", index+1, relay.Name(), len(relay.AdditionalPublicKeys))
+		}
+		if relay.Priority != 0 {
+			log.Infof("relay #%d: %s = %s (priority %d)", index+1, relay.Name(), relay.String(), relay.Priority)
+		} else {
+			log.Infof("relay #%d: %s = %s", index+1, relay.Name(), relay.String())
+		}
 	}
 
 	// For backwards compatibility with the -relay-monitors flag.
@@ -128,7 +276,7 @@ func setupRelays(cmd *cli.Command) (relayList, relayMonitorList, types.U256Str,
 		for _, urls := range monitorURLs {
 			for _, url := range strings.Split(urls, ",") {
 				if err := monitors.Set(strings.TrimSpace(url)); err != nil {
-					log.WithError(err).WithField("relayMonitor", url).Fatal("Invalid relay monitor URL")
+					report.fail(err, "Invalid relay monitor URL", logrus.Fields{"relayMonitor": url})
 				}
 			}
 		}
@@ -143,7 +291,8 @@ func setupRelays(cmd *cli.Command) (relayList, relayMonitorList, types.U256Str,
 
 	relayMinBidWei, err := sanitizeMinBid(cmd.Float(minBidFlag.Name))
 	if err != nil {
-		log.WithError(err).Fatal("Failed sanitizing min bid")
+		report.fail(err, "Failed sanitizing min bid", nil)
+		relayMinBidWei = new(types.U256Str)
 	}
 	if relayMinBidWei.BigInt().Sign() > 0 {
 		log.Infof("Min bid set to %v eth (%v wei)", cmd.Float(minBidFlag.Name), relayMinBidWei)
@@ -151,7 +300,82 @@ func setupRelays(cmd *cli.Command) (relayList, relayMonitorList, types.U256Str,
 	return relays, monitors, *relayMinBidWei, cmd.Bool(relayCheckFlag.Name)
 }
 
-func setupGenesis(cmd *cli.Command) (string, uint64) {
+// parseRelays returns the relays of the relay flag
+func parseRelays(cmd optionSource, report *configReport) relayList {
+	// For backwards compatibility with the -relays flag.
+	var relays relayList
+	if cmd.IsSet(relaysFlag.Name) {
+		relayURLs := cmd.StringSlice(relaysFlag.Name)
+		for _, urls := range relayURLs {
+			for _, url := range strings.Split(urls, ",") {
+				if err := relays.Set(strings.TrimSpace(url)); err != nil {
+					report.fail(err, "Invalid relay URL", logrus.Fields{"relay": url})
+				}
+			}
+		}
+	}
+	return relays
+}
+
+// parseBuilderPubkeys returns the builder pubkeys of the builder allowlist or denylist flag
+func parseBuilderPubkeys(cmd optionSource, name string, report *configReport) []phase0.BLSPubKey {
+	var pubkeys []phase0.BLSPubKey
+	for _, entries := range cmd.StringSlice(name) {
+		for _, entry := range strings.Split(entries, ",") {
+			pubkey, err := utils.HexToPubkey(strings.TrimSpace(entry))
+			if err != nil {
+				report.fail(err, "Invalid builder pubkey", logrus.Fields{"builderPubkey": entry})
+				continue
+			}
+			pubkeys = append(pubkeys, pubkey)
+		}
+	}
+	if len(pubkeys) > 0 {
+		log.Infof("using %d builder pubkeys of %s", len(pubkeys), name)
+	}
+	return pubkeys
+}
+
+func parseFeeRecipients(cmd optionSource, name string, report *configReport) []bellatrix.ExecutionAddress {
+	var feeRecipients []bellatrix.ExecutionAddress
+	for _, entry := range parseList(cmd, name) {
+		if entry == "" {
+			continue
+		}
+		feeRecipient, err := utils.HexToAddress(entry)
+		if err != nil {
+			report.fail(err, "Invalid fee recipient", logrus.Fields{"feeRecipient": entry})
+			continue
+		}
+		feeRecipients = append(feeRecipients, feeRecipient)
+	}
+	if len(feeRecipients) > 0 {
+		log.Infof("using %d fee recipients of %s", len(feeRecipients), name)
+	}
+	return feeRecipients
+}
+
+// parseResourceLimits returns the resource limits of the soft or hard limits flag
+func parseResourceLimits(cmd optionSource, name string, report *configReport) server.ResourceLimits {
+	limits, err := server.ParseResourceLimits(parseList(cmd, name))
+	if err != nil {
+		report.fail(err, "Invalid resource limit", logrus.Fields{"flag": name})
+	}
+	return limits
+}
+
+// parseList returns the entries of a string slice flag, which may also be comma-separated
+func parseList(cmd optionSource, name string) []string {
+	var list []string
+	for _, entries := range cmd.StringSlice(name) {
+		for _, entry := range strings.Split(entries, ",") {
+			list = append(list, strings.TrimSpace(entry))
+		}
+	}
+	return list
+}
+
+func setupGenesis(cmd optionSource, report *configReport) (string, uint64) {
 	var (
 		genesisForkVersion string
 		genesisTime        uint64
@@ -170,8 +394,10 @@ func setupGenesis(cmd *cli.Command) (string, uint64) {
 		genesisForkVersion = genesisForkVersionMainnet
 		genesisTime = genesisTimeMainnet
 	default:
-		flag.Usage()
-		log.Fatal("please specify a genesis fork version (eg. -mainnet / -sepolia / -goerli / -holesky / -genesis-fork-version flags)")
+		if !report.check {
+			flag.Usage()
+		}
+		report.fail(nil, "please specify a genesis fork version (eg. -mainnet / -sepolia / -goerli / -holesky / -genesis-fork-version flags)", nil)
 	}
 
 	if cmd.IsSet(customGenesisTimeFlag.Name) {
@@ -181,7 +407,7 @@ func setupGenesis(cmd *cli.Command) (string, uint64) {
 	return genesisForkVersion, genesisTime
 }
 
-func setupLogging(cmd *cli.Command) error {
+func setupLogging(cmd optionSource) error {
 	// setup logging
 	log.Logger.SetOutput(os.Stdout)
 	if cmd.IsSet(jsonFlag.Name) {
@@ -2,10 +2,13 @@ package cli
 
 import (
 	"math/big"
+	"strings"
 	"testing"
 
 	"github.com/flashbots/go-boost-utils/types"
 	"github.com/flashbots/mev-boost/common"
+	"github.com/flashbots/mev-boost/server"
+	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/require"
 )
 
@@ -35,3 +38,52 @@ func TestFloatEthTo256Wei(t *testing.T) {
 
 	require.Equal(t, *referenceWeiU256, *weiU256)
 }
+
+func TestRelayListLabels(t *testing.T) {
+	const pubkey = "0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249"
+
+	var relays relayList
+	require.NoError(t, relays.Set("https://"+pubkey+"@relay-a.example.com?label=a"))
+	require.NoError(t, relays.Set("https://"+pubkey+"@relay-b.example.com"))
+
+	// an explicit label may not repeat another label
+	require.ErrorIs(t, relays.Set("https://"+pubkey+"@relay-c.example.com?label=a"), errDuplicateRelayLabel)
+	// nor the hostname of a relay without label
+	require.ErrorIs(t, relays.Set("https://"+pubkey+"@relay-c.example.com?label=relay-b.example.com"), errDuplicateRelayLabel)
+
+	require.NoError(t, relays.Set("https://"+pubkey+"@relay-c.example.com?label=c"))
+	require.Len(t, relays, 3)
+}
+
+func TestConfigReport(t *testing.T) {
+	report := &configReport{check: true}
+	var out strings.Builder
+	require.NoError(t, report.print(&out))
+	require.Equal(t, "configuration OK\n", out.String())
+
+	report.fail(errDuplicateEntry, "Invalid relay URL", logrus.Fields{"relay": "https://relay.example.com"})
+	report.fail(nil, "please specify a genesis fork version", nil)
+	out.Reset()
+	err := report.print(&out)
+	require.ErrorIs(t, err, errInvalidConfig)
+	require.Equal(t, "found 2 configuration problems:\n"+
+		"  - Invalid relay URL relay=https://relay.example.com: duplicate entry\n"+
+		"  - invalid configuration: please specify a genesis fork version\n", out.String())
+}
+
+func TestParseResourceLimits(t *testing.T) {
+	_, options, err := runCommand(t, "--resource-hard-limits", "goroutines=10000,fds=4096", "--resource-soft-limits", "heap_mb=512")
+	require.NoError(t, err)
+	report := &configReport{check: true}
+	opts := buildServiceOpts(options, report)
+	require.Empty(t, report.problems)
+	require.Equal(t, server.ResourceLimits{Goroutines: 10000, OpenFDs: 4096}, opts.ResourceHardLimits)
+	require.Equal(t, server.ResourceLimits{HeapMB: 512}, opts.ResourceSoftLimits)
+	require.Equal(t, server.DefaultSelfMonitorInterval, opts.SelfMonitorInterval)
+
+	_, options, err = runCommand(t, "--resource-hard-limits", "threads=10")
+	require.NoError(t, err)
+	report = &configReport{check: true}
+	buildServiceOpts(options, report)
+	require.Len(t, report.problems, 1)
+}
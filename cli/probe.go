@@ -0,0 +1,103 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/flashbots/mev-boost/server"
+	"github.com/urfave/cli/v3"
+)
+
+var errProbeFailed = errors.New("relay probe failed")
+
+var probeRelaysCommand = &cli.Command{
+	Name:   "probe-relays",
+	Usage:  "measure latencies of the relays from this machine, without taking part in real auctions",
+	Action: probeRelays,
+	Flags: []cli.Flag{
+		probeRequestsFlag,
+		probeTimeoutFlag,
+		probeMaxP95Flag,
+		probeMaxTLSHandshakeFlag,
+	},
+}
+
+var (
+	probeRequestsFlag = &cli.IntFlag{
+		Name:  "probe-requests",
+		Value: 10,
+		Usage: "number of status and getHeader requests sent to each relay",
+	}
+	probeTimeoutFlag = &cli.IntFlag{
+		Name:  "probe-timeout",
+		Value: 2000,
+		Usage: "timeout for each probe request [ms]",
+	}
+	probeMaxP95Flag = &cli.IntFlag{
+		Name:  "probe-max-p95",
+		Usage: "fail relays with a status or getHeader p95 latency above this value, 0 to disable [ms]",
+	}
+	probeMaxTLSHandshakeFlag = &cli.IntFlag{
+		Name:  "probe-max-tls-handshake",
+		Usage: "fail relays with a TLS handshake slower than this value, 0 to disable [ms]",
+	}
+)
+
+// probeRelays measures the relays and prints a report, returning an error if any relay failed
+func probeRelays(ctx context.Context, cmd *cli.Command) error {
+	if err := setupLogging(cmd); err != nil {
+		return err
+	}
+	_, genesisTime := setupGenesis(cmd, nil)
+	relays := parseRelays(cmd, nil)
+	if len(relays) == 0 {
+		log.Fatal("no relays specified")
+	}
+
+	thresholds := server.RelayProbeThresholds{
+		MaxP95:          time.Duration(cmd.Int(probeMaxP95Flag.Name)) * time.Millisecond,
+		MaxTLSHandshake: time.Duration(cmd.Int(probeMaxTLSHandshakeFlag.Name)) * time.Millisecond,
+	}
+	results, err := server.ProbeRelays(ctx, server.RelayProbeOpts{
+		Relays:         relays,
+		GenesisTime:    genesisTime,
+		SecondsPerSlot: cmd.Uint(secondsPerSlotFlag.Name),
+		Requests:       int(cmd.Int(probeRequestsFlag.Name)),
+		Timeout:        time.Duration(cmd.Int(probeTimeoutFlag.Name)) * time.Millisecond,
+
+		RelayDialTimeout:             time.Duration(cmd.Int(timeoutDialFlag.Name)) * time.Millisecond,
+		RelayTLSHandshakeTimeout:     time.Duration(cmd.Int(timeoutTLSHandshakeFlag.Name)) * time.Millisecond,
+		RelayTLSMinVersion:           cmd.String(relayTLSMinVersionFlag.Name),
+		RelayDNSCacheTTL:             time.Duration(cmd.Int(relayDNSCacheTTLFlag.Name)) * time.Second,
+		RelayLocalAddr:               cmd.String(relayLocalAddrFlag.Name),
+		FollowRelayRedirectsSameHost: cmd.Bool(followRelayRedirectsSameHostFlag.Name),
+		Log:                          log,
+	})
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, result := range results {
+		fmt.Fprintf(cmd.Writer, "%s\n", result.Relay.String())
+		fmt.Fprintf(cmd.Writer, "  http version:  %s\n", result.HTTPVersion)
+		fmt.Fprintf(cmd.Writer, "  tls handshake: %v\n", result.TLSHandshake.Round(time.Millisecond))
+		fmt.Fprintf(cmd.Writer, "  status:        p50 %v, p95 %v\n", result.StatusP50.Round(time.Millisecond), result.StatusP95.Round(time.Millisecond))
+		fmt.Fprintf(cmd.Writer, "  getHeader:     p50 %v, p95 %v\n", result.GetHeaderP50.Round(time.Millisecond), result.GetHeaderP95.Round(time.Millisecond))
+		failures := result.Failures(thresholds)
+		if len(failures) == 0 {
+			fmt.Fprintf(cmd.Writer, "  result:        ok\n")
+			continue
+		}
+		failed++
+		fmt.Fprintf(cmd.Writer, "  result:        FAILED\n    - %s\n", strings.Join(failures, "\n    - "))
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%w: %d of %d relays", errProbeFailed, failed, len(results))
+	}
+	return nil
+}
@@ -2,13 +2,17 @@ package cli
 
 import (
 	"errors"
+	"fmt"
 	"net/url"
 	"strings"
 
 	"github.com/flashbots/mev-boost/server/types"
 )
 
-var errDuplicateEntry = errors.New("duplicate entry")
+var (
+	errDuplicateEntry      = errors.New("duplicate entry")
+	errDuplicateRelayLabel = errors.New("duplicate relay label")
+)
 
 type relayList []types.RelayEntry
 
@@ -33,6 +37,12 @@ func (r *relayList) Set(value string) error {
 	if r.Contains(relay) {
 		return errDuplicateEntry
 	}
+	// Labels identify relays in logs and metrics, so they must not be the same as the name of another relay
+	for _, entry := range *r {
+		if (relay.Label != "" || entry.Label != "") && relay.Name() == entry.Name() {
+			return fmt.Errorf("%w: %s", errDuplicateRelayLabel, relay.Name())
+		}
+	}
 	*r = append(*r, relay)
 	return nil
 }
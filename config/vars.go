@@ -10,6 +10,9 @@ var (
 	// Version is set at build time (must be a var, not a const!)
 	Version = "v1.8.2-dev"
 
+	// Commit is the git commit of the build, set at build time
+	Commit = "unknown"
+
 	// RFC3339Milli is a time format string based on time.RFC3339 but with millisecond precision
 	RFC3339Milli = "2006-01-02T15:04:05.999Z07:00"
 
@@ -28,6 +31,9 @@ var (
 	// ServerMaxHeaderBytes defines the max header byte size for requests (for dos prevention)
 	ServerMaxHeaderBytes = common.GetEnvInt("MAX_HEADER_BYTES", 4000)
 
+	// MaxLoggedBodySize is the maximum number of bytes of a request or response body included in logs
+	MaxLoggedBodySize = common.GetEnvInt("MEV_BOOST_MAX_LOGGED_BODY_SIZE", 2048)
+
 	// SkipRelaySignatureCheck can be used to disable relay signature check
 	SkipRelaySignatureCheck = os.Getenv("SKIP_RELAY_SIGNATURE_CHECK") == "1"
 
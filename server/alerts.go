@@ -0,0 +1,276 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/flashbots/mev-boost/config"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+// Alert triggers
+const (
+	// AlertPayloadWithheld is sent when no relay delivered the payload of a signed block
+	AlertPayloadWithheld = "payload_withheld"
+	// AlertRelaysUnhealthy is sent when no relay passed a number of consecutive relay checks
+	AlertRelaysUnhealthy = "relays_unhealthy"
+	// AlertNoBids is sent when no relay bid in a number of consecutive proposer slots
+	AlertNoBids = "no_bids"
+	// AlertConfigError is sent when relay bids do not verify, which usually means a wrong network or relay pubkey
+	AlertConfigError = "config_error"
+	// AlertConflictingBids is sent when relays bid different values or builder pubkeys for the same block hash
+	AlertConflictingBids = "conflicting_bids"
+)
+
+const (
+	// DefaultAlertMinInterval is the default minimum time between two alerts of the same trigger
+	DefaultAlertMinInterval = 10 * time.Minute
+	// DefaultAlertUnhealthyChecks is the default number of consecutive failed relay checks which trigger an alert
+	DefaultAlertUnhealthyChecks = 3
+	// DefaultAlertNoBidSlots is the default number of consecutive proposer slots without bids which trigger an alert
+	DefaultAlertNoBidSlots = 5
+
+	// alertBufferSize is the number of alerts waiting to be sent, further alerts are dropped
+	alertBufferSize = 64
+	// alertTimeout bounds the webhook request and the run time of the alert command
+	alertTimeout = 10 * time.Second
+	// maxAlertCommandOutput is the number of bytes of the output of a failed alert command which are logged
+	maxAlertCommandOutput = 1024
+)
+
+var (
+	errInvalidAlertWebhook = errors.New("alert webhook must be an http or https URL")
+	errEmptyAlertCommand   = errors.New("alert command is empty")
+
+	alertsSent = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "alerts_total",
+		Help: "Number of alerts by trigger and result (sent, failed, rate_limited or dropped)",
+	}, []string{"trigger", "result"})
+)
+
+// alertEvent is the JSON body POSTed to the webhook and written to the stdin of the alert command
+type alertEvent struct {
+	Trigger string         `json:"trigger"`
+	Message string         `json:"message"`
+	Time    time.Time      `json:"time"`
+	Version string         `json:"version"`
+	Fields  map[string]any `json:"fields,omitempty"`
+}
+
+// alerter sends alerts to a webhook and/or a local command. Alerts are queued and sent in the background, so
+// request handlers never wait for them, and each trigger sends at most one alert per minInterval. All methods
+// are no-ops on a nil value.
+type alerter struct {
+	webhookURL      string
+	command         []string
+	minInterval     time.Duration
+	unhealthyChecks int
+	noBidSlots      int
+	client          http.Client
+	log             *logrus.Entry
+	events          chan alertEvent
+
+	mu               sync.Mutex
+	lastSent         map[string]time.Time
+	unhealthyCount   int
+	noBidCount       int
+	lastNoBidSlot    phase0.Slot
+	noBidSlotCounted bool
+}
+
+// parseAlertTargets checks the webhook URL, and returns the arguments of the command split on whitespace
+func parseAlertTargets(webhookURL, command string) ([]string, error) {
+	if webhookURL != "" {
+		u, err := url.Parse(webhookURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, errInvalidAlertWebhook
+		}
+	}
+	if command == "" {
+		return nil, nil
+	}
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return nil, errEmptyAlertCommand
+	}
+	return args, nil
+}
+
+// newAlerter starts sending alerts to the webhook and the command, either of which may be empty. The command is
+// run without a shell.
+func newAlerter(webhookURL, command string, minInterval time.Duration, unhealthyChecks, noBidSlots int, log *logrus.Entry) (*alerter, error) {
+	args, err := parseAlertTargets(webhookURL, command)
+	if err != nil {
+		return nil, err
+	}
+	if minInterval <= 0 {
+		minInterval = DefaultAlertMinInterval
+	}
+	if unhealthyChecks <= 0 {
+		unhealthyChecks = DefaultAlertUnhealthyChecks
+	}
+	if noBidSlots <= 0 {
+		noBidSlots = DefaultAlertNoBidSlots
+	}
+	a := &alerter{
+		webhookURL:      webhookURL,
+		command:         args,
+		minInterval:     minInterval,
+		unhealthyChecks: unhealthyChecks,
+		noBidSlots:      noBidSlots,
+		client:          http.Client{Timeout: alertTimeout},
+		log:             log.WithField("module", "alerts"),
+		events:          make(chan alertEvent, alertBufferSize),
+		lastSent:        make(map[string]time.Time),
+	}
+	go a.run()
+	return a, nil
+}
+
+// alert queues an alert of the trigger, unless the trigger sent an alert within the minimum interval
+func (a *alerter) alert(trigger, message string, fields logrus.Fields) {
+	if a == nil {
+		return
+	}
+	now := time.Now()
+	a.mu.Lock()
+	if last, ok := a.lastSent[trigger]; ok && now.Sub(last) < a.minInterval {
+		a.mu.Unlock()
+		alertsSent.WithLabelValues(trigger, "rate_limited").Inc()
+		return
+	}
+	a.lastSent[trigger] = now
+	a.mu.Unlock()
+
+	event := alertEvent{Trigger: trigger, Message: message, Time: now.UTC(), Version: config.Version, Fields: fields}
+	select {
+	case a.events <- event:
+	default:
+		alertsSent.WithLabelValues(trigger, "dropped").Inc()
+	}
+}
+
+// relayCheck alerts if no relay passed the last unhealthyChecks relay checks
+func (a *alerter) relayCheck(healthyRelays, totalRelays int) {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	if healthyRelays > 0 {
+		a.unhealthyCount = 0
+		a.mu.Unlock()
+		return
+	}
+	a.unhealthyCount++
+	count := a.unhealthyCount
+	a.mu.Unlock()
+
+	if count >= a.unhealthyChecks {
+		a.alert(AlertRelaysUnhealthy, "no relay passed the relay checks", logrus.Fields{
+			"consecutiveChecks": count,
+			"totalRelays":       totalRelays,
+		})
+	}
+}
+
+// auction alerts if no relay bid in the last noBidSlots proposer slots. Several auctions of the same slot count once.
+func (a *alerter) auction(slot phase0.Slot, bid bool) {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	if bid {
+		a.noBidCount = 0
+		a.mu.Unlock()
+		return
+	}
+	if !a.noBidSlotCounted || slot != a.lastNoBidSlot {
+		a.noBidCount++
+		a.lastNoBidSlot = slot
+		a.noBidSlotCounted = true
+	}
+	count := a.noBidCount
+	a.mu.Unlock()
+
+	if count >= a.noBidSlots {
+		a.alert(AlertNoBids, "no relay bid in consecutive proposer slots", logrus.Fields{
+			"consecutiveSlots": count,
+			"slot":             slot,
+		})
+	}
+}
+
+// run sends the queued alerts
+func (a *alerter) run() {
+	for event := range a.events {
+		body, err := json.Marshal(event)
+		if err != nil {
+			a.log.WithError(err).Error("could not encode alert")
+			continue
+		}
+		log := a.log.WithField("trigger", event.Trigger)
+		result := "sent"
+		if a.webhookURL != "" {
+			if err := a.post(body); err != nil {
+				log.WithError(err).Error("could not send alert to the webhook")
+				result = "failed"
+			}
+		}
+		if len(a.command) > 0 {
+			if output, err := a.exec(body); err != nil {
+				log.WithError(err).WithField("output", output).Error("alert command failed")
+				result = "failed"
+			}
+		}
+		alertsSent.WithLabelValues(event.Trigger, result).Inc()
+	}
+}
+
+// post sends the alert to the webhook
+func (a *alerter) post(body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, a.webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%w: %d", errHTTPErrorResponse, resp.StatusCode)
+	}
+	return nil
+}
+
+// exec runs the alert command with the alert on stdin. The command runs without a shell, with an empty
+// environment in the temporary directory, and is killed after alertTimeout.
+func (a *alerter) exec(body []byte) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), alertTimeout)
+	defer cancel()
+
+	//nolint:gosec // the command is configured by the operator
+	cmd := exec.CommandContext(ctx, a.command[0], a.command[1:]...)
+	cmd.Env = []string{}
+	cmd.Dir = os.TempDir()
+	cmd.Stdin = bytes.NewReader(body)
+	cmd.WaitDelay = time.Second
+	output, err := cmd.CombinedOutput()
+	if len(output) > maxAlertCommandOutput {
+		output = output[:maxAlertCommandOutput]
+	}
+	return string(output), err
+}
@@ -0,0 +1,175 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/flashbots/mev-boost/server/mock"
+	"github.com/flashbots/mev-boost/server/params"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// newFakeWebhook returns a webhook server, and a channel receiving the alerts POSTed to it
+func newFakeWebhook(t *testing.T, status int) (*httptest.Server, chan alertEvent) {
+	t.Helper()
+	events := make(chan alertEvent, alertBufferSize)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event alertEvent
+		if err := json.NewDecoder(r.Body).Decode(&event); err == nil {
+			events <- event
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server, events
+}
+
+// receiveAlert returns the next alert of the channel, or fails after a second
+func receiveAlert(t *testing.T, events chan alertEvent) alertEvent {
+	t.Helper()
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("no alert received")
+		return alertEvent{}
+	}
+}
+
+func TestAlerter(t *testing.T) {
+	t.Run("Invalid targets", func(t *testing.T) {
+		_, err := newAlerter("ftp://example.com", "", 0, 0, 0, mock.TestLog)
+		require.ErrorIs(t, err, errInvalidAlertWebhook)
+		_, err = newAlerter("", "   ", 0, 0, 0, mock.TestLog)
+		require.ErrorIs(t, err, errEmptyAlertCommand)
+	})
+
+	t.Run("Alerts of a trigger are rate limited", func(t *testing.T) {
+		server, events := newFakeWebhook(t, http.StatusOK)
+		a, err := newAlerter(server.URL, "", time.Hour, 0, 0, mock.TestLog)
+		require.NoError(t, err)
+		before := testutil.ToFloat64(alertsSent.WithLabelValues(AlertConfigError, "rate_limited"))
+
+		a.alert(AlertConfigError, "first", nil)
+		a.alert(AlertConfigError, "second", nil)
+		a.alert(AlertPayloadWithheld, "other trigger", nil)
+
+		event := receiveAlert(t, events)
+		require.Equal(t, AlertConfigError, event.Trigger)
+		require.Equal(t, "first", event.Message)
+		require.Equal(t, AlertPayloadWithheld, receiveAlert(t, events).Trigger)
+		require.InDelta(t, 1, testutil.ToFloat64(alertsSent.WithLabelValues(AlertConfigError, "rate_limited"))-before, 0)
+	})
+
+	t.Run("Failed webhook requests are counted", func(t *testing.T) {
+		server, events := newFakeWebhook(t, http.StatusInternalServerError)
+		a, err := newAlerter(server.URL, "", time.Hour, 0, 0, mock.TestLog)
+		require.NoError(t, err)
+		before := testutil.ToFloat64(alertsSent.WithLabelValues(AlertNoBids, "failed"))
+
+		a.alert(AlertNoBids, "no bids", nil)
+		receiveAlert(t, events)
+		require.Eventually(t, func() bool {
+			return testutil.ToFloat64(alertsSent.WithLabelValues(AlertNoBids, "failed"))-before == 1
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("Relay checks", func(t *testing.T) {
+		server, events := newFakeWebhook(t, http.StatusOK)
+		a, err := newAlerter(server.URL, "", time.Hour, 2, 0, mock.TestLog)
+		require.NoError(t, err)
+
+		// A healthy relay resets the count
+		a.relayCheck(0, 2)
+		a.relayCheck(1, 2)
+		a.relayCheck(0, 2)
+		require.Empty(t, events)
+
+		a.relayCheck(0, 2)
+		event := receiveAlert(t, events)
+		require.Equal(t, AlertRelaysUnhealthy, event.Trigger)
+		require.InDelta(t, 2, event.Fields["consecutiveChecks"], 0)
+	})
+
+	t.Run("Proposer slots without bids", func(t *testing.T) {
+		server, events := newFakeWebhook(t, http.StatusOK)
+		a, err := newAlerter(server.URL, "", time.Hour, 0, 2, mock.TestLog)
+		require.NoError(t, err)
+
+		// Several auctions of the same slot count once, and a bid resets the count
+		a.auction(1, false)
+		a.auction(1, false)
+		a.auction(2, true)
+		a.auction(3, false)
+		require.Empty(t, events)
+
+		a.auction(4, false)
+		event := receiveAlert(t, events)
+		require.Equal(t, AlertNoBids, event.Trigger)
+		require.InDelta(t, 2, event.Fields["consecutiveSlots"], 0)
+	})
+
+	t.Run("Command receives the alert on stdin", func(t *testing.T) {
+		dir := t.TempDir()
+		script := filepath.Join(dir, "alert.sh")
+		output := filepath.Join(dir, "alert.json")
+		require.NoError(t, os.WriteFile(script, []byte("#!/bin/sh\ncat > \"$1\"\n"), 0o700))
+		a, err := newAlerter("", script+" "+output, time.Hour, 0, 0, mock.TestLog)
+		require.NoError(t, err)
+
+		a.alert(AlertConfigError, "relay signature does not verify", map[string]any{"relay": "relay-1"})
+		var event alertEvent
+		require.Eventually(t, func() bool {
+			data, err := os.ReadFile(output)
+			return err == nil && json.Unmarshal(data, &event) == nil
+		}, time.Second, 10*time.Millisecond)
+		require.Equal(t, AlertConfigError, event.Trigger)
+		require.Equal(t, "relay-1", event.Fields["relay"])
+	})
+
+	t.Run("Alerting does not wait for a slow command", func(t *testing.T) {
+		dir := t.TempDir()
+		script := filepath.Join(dir, "slow.sh")
+		require.NoError(t, os.WriteFile(script, []byte("#!/bin/sh\nsleep 5\n"), 0o700))
+		a, err := newAlerter("", script, time.Nanosecond, 0, 0, mock.TestLog)
+		require.NoError(t, err)
+
+		start := time.Now()
+		for range alertBufferSize + 2 {
+			a.alert(AlertNoBids, "no bids", nil)
+			time.Sleep(time.Microsecond)
+		}
+		require.Less(t, time.Since(start), time.Second)
+	})
+
+	t.Run("Nil alerter", func(t *testing.T) {
+		var a *alerter
+		a.alert(AlertConfigError, "ignored", nil)
+		a.relayCheck(0, 1)
+		a.auction(1, false)
+	})
+}
+
+func TestWithheldPayloadAlert(t *testing.T) {
+	server, events := newFakeWebhook(t, http.StatusOK)
+	block, _ := loadDenebBlock(t)
+	backend := newTestBackend(t, 1, time.Second)
+	var err error
+	backend.boost.alerts, err = newAlerter(server.URL, "", time.Hour, 0, 0, mock.TestLog)
+	require.NoError(t, err)
+	backend.relays[0].OverrideHandleGetPayload(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	rr := backend.request(t, http.MethodPost, params.PathGetPayload, block)
+	require.Equal(t, http.StatusBadGateway, rr.Code, rr.Body.String())
+	event := receiveAlert(t, events)
+	require.Equal(t, AlertPayloadWithheld, event.Trigger)
+	require.Equal(t, blockHash(block).String(), event.Fields["blockHash"])
+}
@@ -0,0 +1,166 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/flashbots/mev-boost/server/params"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	errMissingAuthorization = errors.New("missing authorization")
+	errInvalidAuthorization = errors.New("invalid authorization")
+	errEmptyAuthToken       = errors.New("empty API auth token")
+	errInvalidAdminToken    = errors.New("invalid admin token")
+)
+
+// hmacMaxClockSkew is the maximum difference between the timestamp of an HMAC authorization and the local time
+const hmacMaxClockSkew = 30 * time.Second
+
+// apiAuth authenticates requests to the builder API with a shared token, either sent as a bearer token:
+//
+//	Authorization: Bearer <token>
+//
+// or as an HMAC-SHA256 keyed with the token over the timestamp, method and path of the request:
+//
+//	Authorization: HMAC-SHA256 <unix timestamp>:<hex hmac of "timestamp\nmethod\npath">
+type apiAuth struct {
+	token     atomic.Pointer[[]byte]
+	tokenFile string
+}
+
+// newAPIAuth returns the authentication for the token, or for the token read from the token file
+func newAPIAuth(token, tokenFile string) (*apiAuth, error) {
+	a := &apiAuth{tokenFile: tokenFile}
+	if tokenFile != "" {
+		return a, a.reload()
+	}
+	if token == "" {
+		return nil, errEmptyAuthToken
+	}
+	b := []byte(token)
+	a.token.Store(&b)
+	return a, nil
+}
+
+// reload reads the token file again. Requests which are already authenticated are not affected.
+func (a *apiAuth) reload() error {
+	data, err := os.ReadFile(a.tokenFile)
+	if err != nil {
+		return err
+	}
+	token := []byte(strings.TrimSpace(string(data)))
+	if len(token) == 0 {
+		return errEmptyAuthToken
+	}
+	a.token.Store(&token)
+	return nil
+}
+
+// authenticate checks the authorization header of the request
+func (a *apiAuth) authenticate(req *http.Request) error {
+	scheme, credentials, _ := strings.Cut(req.Header.Get("Authorization"), " ")
+	token := *a.token.Load()
+	switch {
+	case scheme == "":
+		return errMissingAuthorization
+	case strings.EqualFold(scheme, "Bearer"):
+		if subtle.ConstantTimeCompare([]byte(credentials), token) == 1 {
+			return nil
+		}
+	case strings.EqualFold(scheme, "HMAC-SHA256"):
+		timestamp, mac, _ := strings.Cut(credentials, ":")
+		unix, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil || time.Since(time.Unix(unix, 0)).Abs() > hmacMaxClockSkew {
+			return errInvalidAuthorization
+		}
+		received, err := hex.DecodeString(mac)
+		if err != nil {
+			return errInvalidAuthorization
+		}
+		if hmac.Equal(received, requestHMAC(token, timestamp, req)) {
+			return nil
+		}
+	}
+	return errInvalidAuthorization
+}
+
+// requestHMAC returns the HMAC of a request for the HMAC-SHA256 authorization scheme
+func requestHMAC(token []byte, timestamp string, req *http.Request) []byte {
+	h := hmac.New(sha256.New, token)
+	h.Write([]byte(timestamp + "\n" + req.Method + "\n" + req.URL.Path))
+	return h.Sum(nil)
+}
+
+// authMiddleware rejects unauthenticated requests, except for the health check
+func (m *BoostService) authMiddleware(next http.Handler) http.Handler {
+	if m.apiAuth == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == params.PathHealthz {
+			next.ServeHTTP(w, req)
+			return
+		}
+		if err := m.apiAuth.authenticate(req); err != nil {
+			apiAuthFailures.WithLabelValues(clientLabel(req.Header.Get("User-Agent"))).Inc()
+			m.log.WithError(err).WithFields(logrus.Fields{
+				"remoteAddr": req.RemoteAddr,
+				"ua":         req.Header.Get("User-Agent"),
+				"path":       req.URL.Path,
+			}).Warn("rejecting unauthenticated request")
+			m.respondError(w, http.StatusUnauthorized, err.Error())
+			return
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+// adminAuth requires the admin token in the admin token header for debug and admin endpoints, if one is configured
+func (m *BoostService) adminAuth(next http.HandlerFunc) http.HandlerFunc {
+	if m.adminToken == "" {
+		return next
+	}
+	return func(w http.ResponseWriter, req *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(req.Header.Get(HeaderKeyAdminToken)), []byte(m.adminToken)) != 1 {
+			m.log.WithFields(logrus.Fields{
+				"remoteAddr": req.RemoteAddr,
+				"ua":         req.Header.Get("User-Agent"),
+				"path":       req.URL.Path,
+			}).Warn("rejecting admin request with an invalid admin token")
+			m.respondError(w, http.StatusUnauthorized, errInvalidAdminToken.Error())
+			return
+		}
+		next(w, req)
+	}
+}
+
+// watchAuthTokenFile reloads the token file on SIGHUP
+func (m *BoostService) watchAuthTokenFile() {
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+	for range sighup {
+		if err := m.apiAuth.reload(); err != nil {
+			m.log.WithError(err).Error("could not reload API auth token file, keeping the previous token")
+			continue
+		}
+		m.log.Info("reloaded API auth token file")
+	}
+}
+
+// handleHealthz reports that the server is running, it is not authenticated
+func (m *BoostService) handleHealthz(w http.ResponseWriter, _ *http.Request) {
+	m.respondOK(w, nilResponse)
+}
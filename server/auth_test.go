@@ -0,0 +1,95 @@
+package server
+
+import (
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/flashbots/mev-boost/server/params"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// authRequest sends a status request with the authorization header to the backend
+func authRequest(t *testing.T, backend *testBackend, path, authorization string) int {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, path, nil)
+	require.NoError(t, err)
+	req.Header.Set("User-Agent", "Lighthouse/v5.3.0")
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	rr := httptest.NewRecorder()
+	backend.boost.getRouter().ServeHTTP(rr, req)
+	return rr.Code
+}
+
+// hmacAuthorization returns an HMAC-SHA256 authorization header for a GET request of the path
+func hmacAuthorization(token string, ts time.Time, path string) string {
+	timestamp := strconv.FormatInt(ts.Unix(), 10)
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	return "HMAC-SHA256 " + timestamp + ":" + hex.EncodeToString(requestHMAC([]byte(token), timestamp, req))
+}
+
+func TestAPIAuth(t *testing.T) {
+	backend := newTestBackend(t, 1, time.Second)
+	auth, err := newAPIAuth("secret", "")
+	require.NoError(t, err)
+	backend.boost.apiAuth = auth
+
+	testCases := []struct {
+		name          string
+		path          string
+		authorization string
+		code          int
+	}{
+		{name: "Missing authorization", path: params.PathStatus, code: http.StatusUnauthorized},
+		{name: "Bearer token", path: params.PathStatus, authorization: "Bearer secret", code: http.StatusOK},
+		{name: "Wrong bearer token", path: params.PathStatus, authorization: "Bearer secrets", code: http.StatusUnauthorized},
+		{name: "Unknown scheme", path: params.PathStatus, authorization: "Basic secret", code: http.StatusUnauthorized},
+		{name: "HMAC", path: params.PathStatus, authorization: hmacAuthorization("secret", time.Now(), params.PathStatus), code: http.StatusOK},
+		{name: "HMAC with wrong token", path: params.PathStatus, authorization: hmacAuthorization("wrong", time.Now(), params.PathStatus), code: http.StatusUnauthorized},
+		{name: "HMAC for another path", path: params.PathStatus, authorization: hmacAuthorization("secret", time.Now(), params.PathGetPayload), code: http.StatusUnauthorized},
+		{name: "Expired HMAC", path: params.PathStatus, authorization: hmacAuthorization("secret", time.Now().Add(-time.Minute), params.PathStatus), code: http.StatusUnauthorized},
+		{name: "Healthz without authorization", path: params.PathHealthz, code: http.StatusOK},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			before := testutil.ToFloat64(apiAuthFailures.WithLabelValues("lighthouse"))
+			require.Equal(t, tt.code, authRequest(t, backend, tt.path, tt.authorization))
+
+			failures := testutil.ToFloat64(apiAuthFailures.WithLabelValues("lighthouse")) - before
+			if tt.code == http.StatusUnauthorized {
+				require.InDelta(t, 1, failures, 0)
+			} else {
+				require.InDelta(t, 0, failures, 0)
+			}
+		})
+	}
+}
+
+func TestAPIAuthTokenFileReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(path, []byte("first\n"), 0o600))
+
+	backend := newTestBackend(t, 1, time.Second)
+	auth, err := newAPIAuth("", path)
+	require.NoError(t, err)
+	backend.boost.apiAuth = auth
+	require.Equal(t, http.StatusOK, authRequest(t, backend, params.PathStatus, "Bearer first"))
+
+	require.NoError(t, os.WriteFile(path, []byte("second\n"), 0o600))
+	require.NoError(t, auth.reload())
+	require.Equal(t, http.StatusUnauthorized, authRequest(t, backend, params.PathStatus, "Bearer first"))
+	require.Equal(t, http.StatusOK, authRequest(t, backend, params.PathStatus, "Bearer second"))
+
+	// An empty token file keeps the previous token
+	require.NoError(t, os.WriteFile(path, nil, 0o600))
+	require.ErrorIs(t, auth.reload(), errEmptyAuthToken)
+	require.Equal(t, http.StatusOK, authRequest(t, backend, params.PathStatus, "Bearer second"))
+}
@@ -0,0 +1,132 @@
+package server
+
+import (
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/flashbots/mev-boost/server/types"
+	"github.com/holiman/uint256"
+)
+
+// maxBidArrivalSlots is the number of most recent slots of which bid arrivals are kept
+const maxBidArrivalSlots = 64
+
+// bidArrival is a bid a relay delivered in the getHeader fan-out
+type bidArrival struct {
+	Relay      string        `json:"relay"`
+	ArrivedAt  time.Time     `json:"arrived_at"`
+	MsIntoSlot int64         `json:"ms_into_slot"`
+	LatencyMs  int64         `json:"latency_ms"`
+	BlockHash  phase0.Hash32 `json:"block_hash"`
+	Value      string        `json:"value"`
+
+	// BlockNumber is the block number of the bid, BlockNumberOutlier is set if most relays bid on another one
+	BlockNumber        uint64 `json:"block_number,string"`
+	BlockNumberOutlier bool   `json:"block_number_outlier,omitempty"`
+}
+
+// slotBidArrivals are the bid arrivals of a slot, in the order the bids arrived
+type slotBidArrivals struct {
+	Slot     phase0.Slot  `json:"slot,string"`
+	Arrivals []bidArrival `json:"arrivals"`
+}
+
+// bidArrivals keeps the order in which relays delivered bids in the most recent slots
+type bidArrivals struct {
+	mu    sync.Mutex
+	slots map[phase0.Slot][]bidArrival
+}
+
+func newBidArrivals() *bidArrivals {
+	return &bidArrivals{
+		slots: make(map[phase0.Slot][]bidArrival),
+	}
+}
+
+// record adds the arrival of a bid of the relay in the slot starting at slotStart, and forgets the arrivals of
+// slots older than the maxBidArrivalSlots most recent ones
+func (b *bidArrivals) record(slotStart time.Time, slot phase0.Slot, relay types.RelayEntry, blockHash phase0.Hash32, blockNumber uint64, value *uint256.Int, requestStart, arrivedAt time.Time) {
+	arrival := bidArrival{
+		Relay:       relayLabel(relay),
+		ArrivedAt:   arrivedAt,
+		MsIntoSlot:  arrivedAt.Sub(slotStart).Milliseconds(),
+		LatencyMs:   arrivedAt.Sub(requestStart).Milliseconds(),
+		BlockHash:   blockHash,
+		Value:       value.Dec(),
+		BlockNumber: blockNumber,
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.slots[slot] = append(b.slots[slot], arrival)
+	for len(b.slots) > maxBidArrivalSlots {
+		delete(b.slots, slices.Min(slices.Collect(maps.Keys(b.slots))))
+	}
+}
+
+// flagBlockNumberOutliers flags the bid arrivals of the relays in the slot as block number outliers
+func (b *bidArrivals) flagBlockNumberOutliers(slot phase0.Slot, relays []string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i, arrival := range b.slots[slot] {
+		if slices.Contains(relays, arrival.Relay) {
+			b.slots[slot][i].BlockNumberOutlier = true
+		}
+	}
+}
+
+// get returns the bid arrivals of the slot in arrival order
+func (b *bidArrivals) get(slot phase0.Slot) (slotBidArrivals, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	arrivals, ok := b.slots[slot]
+	if !ok {
+		return slotBidArrivals{}, false
+	}
+	arrivals = slices.Clone(arrivals)
+	slices.SortStableFunc(arrivals, func(a, b bidArrival) int {
+		return a.ArrivedAt.Compare(b.ArrivedAt)
+	})
+	return slotBidArrivals{Slot: slot, Arrivals: arrivals}, true
+}
+
+// list returns the bid arrivals of the recent slots, most recent slot first
+func (b *bidArrivals) list() []slotBidArrivals {
+	b.mu.Lock()
+	slots := slices.Sorted(maps.Keys(b.slots))
+	b.mu.Unlock()
+
+	slices.Reverse(slots)
+	list := make([]slotBidArrivals, 0, len(slots))
+	for _, slot := range slots {
+		if arrivals, ok := b.get(slot); ok {
+			list = append(list, arrivals)
+		}
+	}
+	return list
+}
+
+// handleDebugBidArrivals returns the order in which relays delivered bids in the slot of the slot query parameter,
+// or in each of the recent slots without it
+func (m *BoostService) handleDebugBidArrivals(w http.ResponseWriter, req *http.Request) {
+	slotParam := req.URL.Query().Get("slot")
+	if slotParam == "" {
+		m.respondOK(w, m.bidArrivals.list())
+		return
+	}
+	slot, err := strconv.ParseUint(slotParam, 10, 64)
+	if err != nil {
+		m.respondError(w, http.StatusBadRequest, errInvalidSlot.Error())
+		return
+	}
+	arrivals, ok := m.bidArrivals.get(phase0.Slot(slot))
+	if !ok {
+		arrivals = slotBidArrivals{Slot: phase0.Slot(slot), Arrivals: []bidArrival{}}
+	}
+	m.respondOK(w, arrivals)
+}
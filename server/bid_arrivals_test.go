@@ -0,0 +1,73 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/attestantio/go-eth2-client/spec"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/flashbots/mev-boost/server/mock"
+	"github.com/flashbots/mev-boost/server/params"
+	"github.com/flashbots/mev-boost/server/types"
+	"github.com/holiman/uint256"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBidArrivals(t *testing.T) {
+	relays := []types.RelayEntry{mock.NewRelay(t).RelayEntry, mock.NewRelay(t).RelayEntry}
+	start := time.Unix(24, 0) // slot 2 starts at genesis 0
+	b := newBidArrivals()
+
+	// Bids are ordered by arrival, not by the time they were recorded
+	b.record(start, 2, relays[0], phase0.Hash32{0x01}, 100, uint256.NewInt(1), start, start.Add(300*time.Millisecond))
+	b.record(start, 2, relays[1], phase0.Hash32{0x02}, 100, uint256.NewInt(2), start, start.Add(100*time.Millisecond))
+	arrivals, ok := b.get(2)
+	require.True(t, ok)
+	require.Len(t, arrivals.Arrivals, 2)
+	require.Equal(t, relayLabel(relays[1]), arrivals.Arrivals[0].Relay)
+	require.Equal(t, int64(100), arrivals.Arrivals[0].MsIntoSlot)
+	require.Equal(t, int64(100), arrivals.Arrivals[0].LatencyMs)
+	require.Equal(t, "2", arrivals.Arrivals[0].Value)
+	require.Equal(t, relayLabel(relays[0]), arrivals.Arrivals[1].Relay)
+
+	// Only the most recent slots are kept
+	for slot := range phase0.Slot(maxBidArrivalSlots) {
+		b.record(start, 3+slot, relays[0], phase0.Hash32{0x03}, 100, uint256.NewInt(1), start, start)
+	}
+	_, ok = b.get(2)
+	require.False(t, ok)
+	list := b.list()
+	require.Len(t, list, maxBidArrivalSlots)
+	require.Equal(t, phase0.Slot(2+maxBidArrivalSlots), list[0].Slot)
+}
+
+func TestHandleDebugBidArrivals(t *testing.T) {
+	parentHash := mock.HexToHash("0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7")
+	backend := newTestBackend(t, 2, time.Second)
+	backend.boost.debugEndpoints = true
+	for i, relay := range backend.relays {
+		relay.GetHeaderResponse = relay.MakeGetHeaderResponse(12345+uint64(i), parentHash.String(), parentHash.String(), feeRecipientTestPubkey, spec.DataVersionDeneb)
+	}
+	// The first relay in the list is the slower one
+	backend.relays[0].ResponseDelay = 100 * time.Millisecond
+
+	rr := backend.request(t, http.MethodGet, getHeaderPath(1, parentHash, mock.HexToPubkey(feeRecipientTestPubkey)), nil)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	rr = httptest.NewRecorder()
+	backend.boost.getRouter().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, params.PathDebugBidArrivals+"?slot=1", nil))
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var arrivals slotBidArrivals
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &arrivals))
+	require.Len(t, arrivals.Arrivals, 2)
+	require.Equal(t, relayLabel(backend.relays[1].RelayEntry), arrivals.Arrivals[0].Relay)
+	require.Equal(t, relayLabel(backend.relays[0].RelayEntry), arrivals.Arrivals[1].Relay)
+	require.GreaterOrEqual(t, arrivals.Arrivals[1].LatencyMs, int64(100))
+
+	rr = httptest.NewRecorder()
+	backend.boost.getRouter().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, params.PathDebugBidArrivals+"?slot=abc", nil))
+	require.Equal(t, http.StatusBadRequest, rr.Code, rr.Body.String())
+}
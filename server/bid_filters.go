@@ -0,0 +1,229 @@
+package server
+
+import (
+	"time"
+
+	builderSpec "github.com/attestantio/go-builder-client/spec"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/flashbots/mev-boost/server/types"
+	"github.com/holiman/uint256"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+// emptyListTxRoot is the transactions root of a block without transactions
+const emptyListTxRoot = "0x7ffe241ea60187fdb0187bfa22de35d1f9bed7ab061d9401fd47e34a54fbede1"
+
+var (
+	bidFilterRejections = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "bid_filter_rejections_total",
+		Help: "Number of relay bids rejected by each bid filter",
+	}, []string{"filter"})
+
+	staleBids = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "stale_bid_total",
+		Help: "Number of relay bids rejected because their timestamp is not the one of the requested slot",
+	}, []string{"relay"})
+)
+
+// BidVerdict is the decision of a bid filter
+type BidVerdict int
+
+const (
+	// BidAccept lets the bid continue to the next filter
+	BidAccept BidVerdict = iota
+	// BidDeprioritize lets the bid continue, but it only wins the auction if there are no other bids
+	BidDeprioritize
+	// BidReject removes the bid from the auction, the remaining filters are skipped
+	BidReject
+)
+
+// BidFilterResult is the verdict of a bid filter, and the reason for it
+type BidFilterResult struct {
+	Verdict BidVerdict
+	Reason  string
+}
+
+// CandidateBid is a relay bid for a getHeader request which was decoded and verified, before filtering
+type CandidateBid struct {
+	Relay          types.RelayEntry
+	Slot           phase0.Slot
+	ParentHashHex  string       // parent hash of the getHeader request
+	ProposerPubkey string       // validator pubkey of the getHeader request
+	LocalValue     *uint256.Int // local block value supplied by the beacon node, nil if unknown
+	Bid            *builderSpec.VersionedSignedBuilderBid
+
+	BlockHash     phase0.Hash32
+	ParentHash    phase0.Hash32 // parent hash of the bid
+	BuilderPubkey phase0.BLSPubKey
+	BlockNumber   uint64
+	TxRoot        phase0.Root
+	Value         *uint256.Int
+}
+
+// BidFilter decides whether a bid takes part in the auction. Filters run in order for every bid in getHeader,
+// concurrently for the bids of different relays. Embedders can add filters with BoostServiceOpts.BidFilters,
+// which run after the built-in filters.
+type BidFilter interface {
+	// Name identifies the filter in logs and the bid_filter_rejections_total metric
+	Name() string
+	// Filter returns the verdict for the bid. The log entry has the relay and bid fields set.
+	Filter(log *logrus.Entry, bid *CandidateBid) BidFilterResult
+}
+
+// bidFilterFunc is a BidFilter implemented by a function
+type bidFilterFunc struct {
+	name   string
+	filter func(log *logrus.Entry, bid *CandidateBid) BidFilterResult
+}
+
+func (f bidFilterFunc) Name() string { return f.name }
+
+func (f bidFilterFunc) Filter(log *logrus.Entry, bid *CandidateBid) BidFilterResult {
+	return f.filter(log, bid)
+}
+
+// NewBidFilter returns a BidFilter with the name which calls the function
+func NewBidFilter(name string, filter func(log *logrus.Entry, bid *CandidateBid) BidFilterResult) BidFilter {
+	return bidFilterFunc{name: name, filter: filter}
+}
+
+// defaultBidFilters returns the built-in bid filters, in the order they run
+func (m *BoostService) defaultBidFilters() []BidFilter {
+	return []BidFilter{
+		NewBidFilter("builder_denylist", m.filterBuilderDenylist),
+		NewBidFilter("builder_allowlist", m.filterBuilderAllowlist),
+		NewBidFilter("parent_hash", m.filterParentHash),
+		NewBidFilter("bid_timestamp", m.filterBidTimestamp),
+		NewBidFilter("zero_value", filterZeroValue),
+		NewBidFilter("min_bid", m.filterMinBid),
+		NewBidFilter("min_over_local", m.filterMinOverLocal),
+		NewBidFilter("failed_delivery", m.filterFailedDelivery),
+		NewBidFilter("withholding_penalty", m.filterWithholdingPenalty),
+	}
+}
+
+// filterBid runs the bid through the filter chain, until a filter rejects it. It returns whether the bid
+// was accepted, and whether a filter deprioritized it.
+func (m *BoostService) filterBid(log *logrus.Entry, bid *CandidateBid, quiet bool) (accepted, deprioritized bool) {
+	for _, filter := range m.bidFilters {
+		result := filter.Filter(log, bid)
+		switch result.Verdict {
+		case BidReject:
+			bidFilterRejections.WithLabelValues(filter.Name()).Inc()
+			if !quiet {
+				log.WithFields(logrus.Fields{"filter": filter.Name(), "reason": result.Reason}).Debug("bid rejected by filter")
+			}
+			return false, deprioritized
+		case BidDeprioritize:
+			if !quiet {
+				log.WithFields(logrus.Fields{"filter": filter.Name(), "reason": result.Reason}).Debug("bid deprioritized by filter")
+			}
+			deprioritized = true
+		case BidAccept:
+		}
+	}
+	return true, deprioritized
+}
+
+// Only accept bids of allowed builders. The denylist takes precedence over the allowlist.
+func (m *BoostService) filterBuilderDenylist(log *logrus.Entry, bid *CandidateBid) BidFilterResult {
+	if !m.builderDenylist.denied(bid.BuilderPubkey) {
+		return BidFilterResult{Verdict: BidAccept}
+	}
+	relayBidsRejected.WithLabelValues(relayLabel(bid.Relay), "builder_denied").Inc()
+	log.WithField("builderPubkey", bid.BuilderPubkey.String()).Warn("ignoring bid from a builder on the denylist")
+	return BidFilterResult{Verdict: BidReject, Reason: "builder on the denylist"}
+}
+
+func (m *BoostService) filterBuilderAllowlist(log *logrus.Entry, bid *CandidateBid) BidFilterResult {
+	if m.builderAllowlist == nil || m.builderAllowlist[bid.BuilderPubkey] {
+		return BidFilterResult{Verdict: BidAccept}
+	}
+	relayBidsRejected.WithLabelValues(relayLabel(bid.Relay), "builder_not_allowed").Inc()
+	log.WithField("builderPubkey", bid.BuilderPubkey.String()).Warn("ignoring bid from a builder which is not on the allowlist")
+	return BidFilterResult{Verdict: BidReject, Reason: "builder not on the allowlist"}
+}
+
+// Verify response coherence with proposer's input data
+func (m *BoostService) filterParentHash(log *logrus.Entry, bid *CandidateBid) BidFilterResult {
+	if m.validationLevel == ValidationLevelNone || bid.ParentHash.String() == bid.ParentHashHex {
+		return BidFilterResult{Verdict: BidAccept}
+	}
+	log.WithFields(logrus.Fields{
+		"originalParentHash": bid.ParentHashHex,
+		"responseParentHash": bid.ParentHash.String(),
+	}).Error("proposer and relay parent hashes are not the same")
+	return BidFilterResult{Verdict: BidReject, Reason: "parent hash mismatch"}
+}
+
+// The payload of a bid for the slot has the timestamp of the start of the slot, other timestamps are bids cached
+// by the relay for an earlier slot with the same parent
+func (m *BoostService) filterBidTimestamp(log *logrus.Entry, bid *CandidateBid) BidFilterResult {
+	if !m.checkBidTimestamp || m.validationLevel == ValidationLevelNone {
+		return BidFilterResult{Verdict: BidAccept}
+	}
+	timestamp, err := bid.Bid.Timestamp()
+	expected := m.slotTimestamp(bid.Slot)
+	if err == nil && timestamp == expected {
+		return BidFilterResult{Verdict: BidAccept}
+	}
+	staleBids.WithLabelValues(relayLabel(bid.Relay)).Inc()
+	if err != nil {
+		log = log.WithError(err)
+	}
+	log.WithFields(logrus.Fields{
+		"timestamp":         timestamp,
+		"expectedTimestamp": expected,
+		"secondsPerSlot":    m.secondsPerSlot,
+	}).Warn("ignoring bid with a timestamp which is not the one of the slot")
+	return BidFilterResult{Verdict: BidReject, Reason: "stale timestamp"}
+}
+
+// Ignore bids with 0 value
+func filterZeroValue(log *logrus.Entry, bid *CandidateBid) BidFilterResult {
+	if !bid.Value.IsZero() && bid.TxRoot.String() != emptyListTxRoot {
+		return BidFilterResult{Verdict: BidAccept}
+	}
+	log.Warn("ignoring bid with 0 value")
+	return BidFilterResult{Verdict: BidReject, Reason: "zero value"}
+}
+
+// Skip if value is lower than the minimum bid, which a relay can override
+func (m *BoostService) filterMinBid(_ *logrus.Entry, bid *CandidateBid) BidFilterResult {
+	minBid := m.relayMinBid
+	if bid.Relay.MinBid != nil {
+		minBid = *bid.Relay.MinBid
+	}
+	if bid.Value.CmpBig(minBid.BigInt()) == -1 {
+		return BidFilterResult{Verdict: BidReject, Reason: "below min-bid value"}
+	}
+	return BidFilterResult{Verdict: BidAccept}
+}
+
+// Bids for a block hash which this relay previously failed to deliver are suspect
+func (m *BoostService) filterFailedDelivery(log *logrus.Entry, bid *CandidateBid) BidFilterResult {
+	if !m.failedDeliveries.failed(bid.Relay, bid.BlockHash) {
+		return BidFilterResult{Verdict: BidAccept}
+	}
+	if m.failedDeliveryPolicy == FailedDeliveryPolicyReject {
+		log.Warn("ignoring bid for a block hash this relay previously failed to deliver")
+		return BidFilterResult{Verdict: BidReject, Reason: "failed delivery of this block hash"}
+	}
+	log.Warn("deprioritizing bid for a block hash this relay previously failed to deliver")
+	return BidFilterResult{Verdict: BidDeprioritize, Reason: "failed delivery of this block hash"}
+}
+
+// Bids of relays which recently withheld a payload are excluded or deprioritized until the cooldown ends
+func (m *BoostService) filterWithholdingPenalty(log *logrus.Entry, bid *CandidateBid) BidFilterResult {
+	if _, penalized := m.withholdingPenalties.penalized(bid.Relay, time.Now()); !penalized {
+		return BidFilterResult{Verdict: BidAccept}
+	}
+	if m.withholdingPenaltyPolicy == WithholdingPenaltyExclude {
+		relayBidsRejected.WithLabelValues(relayLabel(bid.Relay), "withholding_penalty").Inc()
+		log.Warn("ignoring bid from a relay which recently withheld a payload")
+		return BidFilterResult{Verdict: BidReject, Reason: "withholding penalty"}
+	}
+	log.Warn("deprioritizing bid from a relay which recently withheld a payload")
+	return BidFilterResult{Verdict: BidDeprioritize, Reason: "withholding penalty"}
+}
@@ -0,0 +1,202 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/attestantio/go-eth2-client/spec"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/flashbots/mev-boost/server/mock"
+	"github.com/flashbots/mev-boost/server/types"
+	"github.com/holiman/uint256"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBidFilterChainOrder(t *testing.T) {
+	custom := NewBidFilter("custom", func(*logrus.Entry, *CandidateBid) BidFilterResult {
+		return BidFilterResult{Verdict: BidAccept}
+	})
+	service, err := NewBoostService(BoostServiceOpts{
+		Log:                   mock.TestLog,
+		Relays:                []types.RelayEntry{mock.NewRelay(t).RelayEntry},
+		GenesisForkVersionHex: "0x00000000",
+		BidFilters:            []BidFilter{custom},
+	})
+	require.NoError(t, err)
+
+	names := make([]string, 0, len(service.bidFilters))
+	for _, filter := range service.bidFilters {
+		names = append(names, filter.Name())
+	}
+	require.Equal(t, []string{
+		"builder_denylist", "builder_allowlist", "parent_hash", "bid_timestamp", "zero_value", "min_bid",
+		"min_over_local", "failed_delivery", "withholding_penalty", "custom",
+	}, names)
+}
+
+func TestFilterBid(t *testing.T) {
+	var calls []string
+	filter := func(name string, verdict BidVerdict) BidFilter {
+		return NewBidFilter(name, func(*logrus.Entry, *CandidateBid) BidFilterResult {
+			calls = append(calls, name)
+			return BidFilterResult{Verdict: verdict, Reason: name}
+		})
+	}
+
+	tests := []struct {
+		name          string
+		filters       []BidFilter
+		calls         []string
+		accepted      bool
+		deprioritized bool
+	}{
+		{
+			name:     "accepted by all filters",
+			filters:  []BidFilter{filter("a", BidAccept), filter("b", BidAccept)},
+			calls:    []string{"a", "b"},
+			accepted: true,
+		},
+		{
+			name:          "deprioritized bids continue through the chain",
+			filters:       []BidFilter{filter("a", BidDeprioritize), filter("b", BidAccept)},
+			calls:         []string{"a", "b"},
+			accepted:      true,
+			deprioritized: true,
+		},
+		{
+			name:    "the first rejection stops the chain",
+			filters: []BidFilter{filter("a", BidAccept), filter("b", BidReject), filter("c", BidAccept)},
+			calls:   []string{"a", "b"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls = nil
+			m := &BoostService{bidFilters: tt.filters}
+			accepted, deprioritized := m.filterBid(mock.TestLog, &CandidateBid{}, false)
+			require.Equal(t, tt.accepted, accepted)
+			require.Equal(t, tt.deprioritized, deprioritized)
+			require.Equal(t, tt.calls, calls)
+		})
+	}
+}
+
+func TestGetHeaderBidFilters(t *testing.T) {
+	const (
+		pubkey = "0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249"
+		hashA  = "0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7"
+		hashB  = "0xcf8e0d4e9587369b2301d0790347320302cc0943d5a1884560367e8208d920f2"
+	)
+	parentHash := phase0.Hash32{0x01}
+	path := getHeaderPath(1, parentHash, mock.HexToPubkey(pubkey))
+
+	newBackend := func(t *testing.T, verdict BidVerdict) *testBackend {
+		t.Helper()
+		backend := newTestBackend(t, 2, time.Second)
+		backend.relays[0].GetHeaderResponse = backend.relays[0].MakeGetHeaderResponse(
+			20000, hashA, parentHash.String(), pubkey, spec.DataVersionDeneb)
+		backend.relays[1].GetHeaderResponse = backend.relays[1].MakeGetHeaderResponse(
+			15000, hashB, parentHash.String(), pubkey, spec.DataVersionDeneb)
+
+		// The filter decides on the most profitable bid, after the built-in filters accepted it
+		backend.boost.bidFilters = append(backend.boost.bidFilters, NewBidFilter("test_"+t.Name(), func(_ *logrus.Entry, bid *CandidateBid) BidFilterResult {
+			if bid.Slot == 1 && bid.ProposerPubkey == pubkey && bid.BlockHash.String() == hashA {
+				return BidFilterResult{Verdict: verdict, Reason: "test"}
+			}
+			return BidFilterResult{Verdict: BidAccept}
+		}))
+		return backend
+	}
+
+	t.Run("rejected bids are not selected", func(t *testing.T) {
+		backend := newBackend(t, BidReject)
+		rr := backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		require.Contains(t, rr.Body.String(), hashB)
+		require.InDelta(t, 1, testutil.ToFloat64(bidFilterRejections.WithLabelValues("test_"+t.Name())), 0)
+	})
+
+	t.Run("deprioritized bids lose to other bids", func(t *testing.T) {
+		backend := newBackend(t, BidDeprioritize)
+		rr := backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		require.Contains(t, rr.Body.String(), hashB)
+		require.Zero(t, testutil.ToFloat64(bidFilterRejections.WithLabelValues("test_"+t.Name())))
+	})
+
+	t.Run("built-in filters still apply", func(t *testing.T) {
+		backend := newBackend(t, BidAccept)
+		backend.boost.relayMinBid = types.IntToU256(17000)
+		rr := backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		require.Contains(t, rr.Body.String(), hashA)
+	})
+}
+
+func TestFilterMinBid(t *testing.T) {
+	relay := mock.NewRelay(t).RelayEntry
+	relayMinBid := types.IntToU256(500)
+	m := &BoostService{relayMinBid: types.IntToU256(1000)}
+	bid := func(relay types.RelayEntry, value uint64) *CandidateBid {
+		return &CandidateBid{Relay: relay, Value: uint256.NewInt(value)}
+	}
+
+	require.Equal(t, BidReject, m.filterMinBid(mock.TestLog, bid(relay, 999)).Verdict)
+	require.Equal(t, BidAccept, m.filterMinBid(mock.TestLog, bid(relay, 1000)).Verdict)
+
+	// The minimum bid of the relay replaces the global one, also when it is higher
+	relay.MinBid = &relayMinBid
+	require.Equal(t, BidAccept, m.filterMinBid(mock.TestLog, bid(relay, 500)).Verdict)
+	require.Equal(t, BidReject, m.filterMinBid(mock.TestLog, bid(relay, 499)).Verdict)
+	relayMinBid = types.IntToU256(2000)
+	require.Equal(t, BidReject, m.filterMinBid(mock.TestLog, bid(relay, 1500)).Verdict)
+}
+
+func TestFilterBidTimestamp(t *testing.T) {
+	relay := mock.NewRelay(t)
+	bidWithTimestamp := func(timestamp uint64) *CandidateBid {
+		bid := relay.MakeGetHeaderResponse(12345, phase0.Hash32{0x01}.String(), phase0.Hash32{0x02}.String(), feeRecipientTestPubkey, spec.DataVersionDeneb)
+		bid.Deneb.Message.Header.Timestamp = timestamp
+		return &CandidateBid{Relay: relay.RelayEntry, Slot: 10, Bid: bid}
+	}
+
+	testCases := []struct {
+		name           string
+		secondsPerSlot uint64
+		timestamp      uint64
+		verdict        BidVerdict
+	}{
+		{name: "Mainnet slot time", secondsPerSlot: 12, timestamp: 1000 + 10*12, verdict: BidAccept},
+		{name: "Bid of the previous slot", secondsPerSlot: 12, timestamp: 1000 + 9*12, verdict: BidReject},
+		{name: "Custom slot time", secondsPerSlot: 2, timestamp: 1000 + 10*2, verdict: BidAccept},
+		{name: "Mainnet timestamp with a custom slot time", secondsPerSlot: 2, timestamp: 1000 + 10*12, verdict: BidReject},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &BoostService{genesisTime: 1000, secondsPerSlot: tt.secondsPerSlot, checkBidTimestamp: true, validationLevel: ValidationLevelStrict}
+			before := testutil.ToFloat64(staleBids.WithLabelValues(relayLabel(relay.RelayEntry)))
+			require.Equal(t, tt.verdict, m.filterBidTimestamp(mock.TestLog, bidWithTimestamp(tt.timestamp)).Verdict)
+			stale := testutil.ToFloat64(staleBids.WithLabelValues(relayLabel(relay.RelayEntry))) - before
+			if tt.verdict == BidReject {
+				require.InDelta(t, 1, stale, 0)
+			} else {
+				require.InDelta(t, 0, stale, 0)
+			}
+		})
+	}
+
+	t.Run("Disabled without a genesis time", func(t *testing.T) {
+		service, err := NewBoostService(BoostServiceOpts{
+			Log:                   mock.TestLog,
+			Relays:                []types.RelayEntry{relay.RelayEntry},
+			GenesisForkVersionHex: "0x00000000",
+			CheckBidTimestamp:     true,
+		})
+		require.NoError(t, err)
+		require.False(t, service.checkBidTimestamp)
+		require.Equal(t, BidAccept, service.filterBidTimestamp(mock.TestLog, bidWithTimestamp(0)).Verdict)
+	})
+}
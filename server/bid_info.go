@@ -0,0 +1,52 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+
+	builderSpec "github.com/attestantio/go-builder-client/spec"
+	"github.com/attestantio/go-eth2-client/spec"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/holiman/uint256"
+)
+
+var (
+	errNoBidInfoExtractor = errors.New("no bid info extractor for the bid version")
+	errIncompleteBid      = errors.New("bid has no message, header or value")
+)
+
+// bidInfo is used to store bid response fields for logging and validation
+type bidInfo struct {
+	blockHash   phase0.Hash32
+	parentHash  phase0.Hash32
+	pubkey      phase0.BLSPubKey // builder pubkey of the bid, which signed it
+	blockNumber uint64
+	txRoot      phase0.Root
+	value       *uint256.Int
+}
+
+// BidInfoExtractor reads the bid info of the bids of one fork. Each fork has its extractor in a bid_info_<fork>.go
+// file, which registers it with registerBidInfoExtractor.
+type BidInfoExtractor interface {
+	// bidInfo returns the bid info, or errIncompleteBid if the bid has no data of the fork or misses fields
+	bidInfo(bid *builderSpec.VersionedSignedBuilderBid) (bidInfo, error)
+}
+
+// bidInfoExtractors are the extractors of the forks, set up before main runs
+var bidInfoExtractors = map[spec.DataVersion]BidInfoExtractor{}
+
+func registerBidInfoExtractor(version spec.DataVersion, extractor BidInfoExtractor) {
+	if _, ok := bidInfoExtractors[version]; ok {
+		panic(fmt.Sprintf("bid info extractor of %s registered twice", version))
+	}
+	bidInfoExtractors[version] = extractor
+}
+
+// parseBidInfo returns the bid info with the extractor of the version of the bid
+func parseBidInfo(bid *builderSpec.VersionedSignedBuilderBid) (bidInfo, error) {
+	extractor, ok := bidInfoExtractors[bid.Version]
+	if !ok {
+		return bidInfo{}, fmt.Errorf("%w: %s", errNoBidInfoExtractor, bid.Version)
+	}
+	return extractor.bidInfo(bid)
+}
@@ -0,0 +1,28 @@
+package server
+
+import (
+	builderSpec "github.com/attestantio/go-builder-client/spec"
+	"github.com/attestantio/go-eth2-client/spec"
+)
+
+func init() {
+	registerBidInfoExtractor(spec.DataVersionBellatrix, bellatrixBidInfo{})
+}
+
+// bellatrixBidInfo reads the bid info of bellatrix bids
+type bellatrixBidInfo struct{}
+
+func (bellatrixBidInfo) bidInfo(bid *builderSpec.VersionedSignedBuilderBid) (bidInfo, error) {
+	if bid.Bellatrix == nil || bid.Bellatrix.Message == nil || bid.Bellatrix.Message.Header == nil || bid.Bellatrix.Message.Value == nil {
+		return bidInfo{}, errIncompleteBid
+	}
+	message := bid.Bellatrix.Message
+	return bidInfo{
+		blockHash:   message.Header.BlockHash,
+		parentHash:  message.Header.ParentHash,
+		pubkey:      message.Pubkey,
+		blockNumber: message.Header.BlockNumber,
+		txRoot:      message.Header.TransactionsRoot,
+		value:       message.Value,
+	}, nil
+}
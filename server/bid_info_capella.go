@@ -0,0 +1,28 @@
+package server
+
+import (
+	builderSpec "github.com/attestantio/go-builder-client/spec"
+	"github.com/attestantio/go-eth2-client/spec"
+)
+
+func init() {
+	registerBidInfoExtractor(spec.DataVersionCapella, capellaBidInfo{})
+}
+
+// capellaBidInfo reads the bid info of capella bids
+type capellaBidInfo struct{}
+
+func (capellaBidInfo) bidInfo(bid *builderSpec.VersionedSignedBuilderBid) (bidInfo, error) {
+	if bid.Capella == nil || bid.Capella.Message == nil || bid.Capella.Message.Header == nil || bid.Capella.Message.Value == nil {
+		return bidInfo{}, errIncompleteBid
+	}
+	message := bid.Capella.Message
+	return bidInfo{
+		blockHash:   message.Header.BlockHash,
+		parentHash:  message.Header.ParentHash,
+		pubkey:      message.Pubkey,
+		blockNumber: message.Header.BlockNumber,
+		txRoot:      message.Header.TransactionsRoot,
+		value:       message.Value,
+	}, nil
+}
@@ -0,0 +1,28 @@
+package server
+
+import (
+	builderSpec "github.com/attestantio/go-builder-client/spec"
+	"github.com/attestantio/go-eth2-client/spec"
+)
+
+func init() {
+	registerBidInfoExtractor(spec.DataVersionDeneb, denebBidInfo{})
+}
+
+// denebBidInfo reads the bid info of deneb bids
+type denebBidInfo struct{}
+
+func (denebBidInfo) bidInfo(bid *builderSpec.VersionedSignedBuilderBid) (bidInfo, error) {
+	if bid.Deneb == nil || bid.Deneb.Message == nil || bid.Deneb.Message.Header == nil || bid.Deneb.Message.Value == nil {
+		return bidInfo{}, errIncompleteBid
+	}
+	message := bid.Deneb.Message
+	return bidInfo{
+		blockHash:   message.Header.BlockHash,
+		parentHash:  message.Header.ParentHash,
+		pubkey:      message.Pubkey,
+		blockNumber: message.Header.BlockNumber,
+		txRoot:      message.Header.TransactionsRoot,
+		value:       message.Value,
+	}, nil
+}
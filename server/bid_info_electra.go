@@ -0,0 +1,28 @@
+package server
+
+import (
+	builderSpec "github.com/attestantio/go-builder-client/spec"
+	"github.com/attestantio/go-eth2-client/spec"
+)
+
+func init() {
+	registerBidInfoExtractor(spec.DataVersionElectra, electraBidInfo{})
+}
+
+// electraBidInfo reads the bid info of electra bids
+type electraBidInfo struct{}
+
+func (electraBidInfo) bidInfo(bid *builderSpec.VersionedSignedBuilderBid) (bidInfo, error) {
+	if bid.Electra == nil || bid.Electra.Message == nil || bid.Electra.Message.Header == nil || bid.Electra.Message.Value == nil {
+		return bidInfo{}, errIncompleteBid
+	}
+	message := bid.Electra.Message
+	return bidInfo{
+		blockHash:   message.Header.BlockHash,
+		parentHash:  message.Header.ParentHash,
+		pubkey:      message.Pubkey,
+		blockNumber: message.Header.BlockNumber,
+		txRoot:      message.Header.TransactionsRoot,
+		value:       message.Value,
+	}, nil
+}
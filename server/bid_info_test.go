@@ -0,0 +1,74 @@
+package server
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	builderApiBellatrix "github.com/attestantio/go-builder-client/api/bellatrix"
+	builderSpec "github.com/attestantio/go-builder-client/spec"
+	"github.com/attestantio/go-eth2-client/spec"
+	"github.com/attestantio/go-eth2-client/spec/bellatrix"
+	"github.com/flashbots/mev-boost/server/mock"
+	"github.com/holiman/uint256"
+	"github.com/stretchr/testify/require"
+)
+
+// TestBidInfoExtractorsExhaustive fails when go-builder-client adds the bid of a fork without an extractor
+func TestBidInfoExtractorsExhaustive(t *testing.T) {
+	bidType := reflect.TypeOf(builderSpec.VersionedSignedBuilderBid{})
+	forks := 0
+	for i := range bidType.NumField() {
+		name := bidType.Field(i).Name
+		if name == "Version" {
+			continue
+		}
+		forks++
+		var version spec.DataVersion
+		require.NoError(t, version.UnmarshalJSON([]byte(strconv.Quote(strings.ToLower(name)))), name)
+		require.Contains(t, bidInfoExtractors, version, "no bid info extractor for %s bids", name)
+	}
+	require.Len(t, bidInfoExtractors, forks)
+}
+
+func TestParseBidInfo(t *testing.T) {
+	blockHash := "0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7"
+	parentHash := "0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab8"
+	relay := mock.NewRelay(t)
+
+	bids := map[spec.DataVersion]*builderSpec.VersionedSignedBuilderBid{
+		spec.DataVersionBellatrix: {
+			Version: spec.DataVersionBellatrix,
+			Bellatrix: &builderApiBellatrix.SignedBuilderBid{
+				Message: &builderApiBellatrix.BuilderBid{
+					Header: &bellatrix.ExecutionPayloadHeader{
+						BlockHash:  mock.HexToHash(blockHash),
+						ParentHash: mock.HexToHash(parentHash),
+					},
+					Value:  uint256.NewInt(12345),
+					Pubkey: mock.HexToPubkey(feeRecipientTestPubkey),
+				},
+			},
+		},
+	}
+	for _, version := range []spec.DataVersion{spec.DataVersionCapella, spec.DataVersionDeneb, spec.DataVersionElectra} {
+		bids[version] = relay.MakeGetHeaderResponse(12345, blockHash, parentHash, feeRecipientTestPubkey, version)
+	}
+	require.Len(t, bids, len(bidInfoExtractors))
+
+	for version, bid := range bids {
+		info, err := parseBidInfo(bid)
+		require.NoError(t, err, version.String())
+		require.Equal(t, blockHash, info.blockHash.String(), version.String())
+		require.Equal(t, parentHash, info.parentHash.String(), version.String())
+		require.Equal(t, feeRecipientTestPubkey, info.pubkey.String(), version.String())
+		require.Equal(t, uint64(12345), info.value.Uint64(), version.String())
+	}
+
+	// Bids without the data of their version, or of an unknown version, have no bid info
+	_, err := parseBidInfo(&builderSpec.VersionedSignedBuilderBid{Version: spec.DataVersionDeneb, Electra: bids[spec.DataVersionElectra].Electra})
+	require.ErrorIs(t, err, errIncompleteBid)
+	_, err = parseBidInfo(&builderSpec.VersionedSignedBuilderBid{Version: spec.DataVersionAltair})
+	require.ErrorIs(t, err, errNoBidInfoExtractor)
+}
@@ -0,0 +1,76 @@
+package server
+
+import (
+	"crypto/sha256"
+	"sync"
+
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/flashbots/mev-boost/server/types"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// bidMemoMaxPerRelay is the maximum number of distinct bids remembered per relay and slot
+const bidMemoMaxPerRelay = 16
+
+var relayBidMemoHits = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "relay_bid_memo_hits_total",
+	Help: "Number of relay bids which were identical to a verified bid of the same relay and slot, so the signature was not verified again",
+}, []string{"relay"})
+
+// bidMemo remembers the bids of the current slot of which the relay signature was verified, by relay and
+// response body, so an identical bid from the same relay is not verified again when the relay is queried
+// again within the slot
+type bidMemo struct {
+	mu      sync.Mutex
+	slot    phase0.Slot
+	entries map[string]map[[sha256.Size]byte]struct{} // relay -> body hash
+}
+
+func newBidMemo() *bidMemo {
+	return &bidMemo{
+		entries: make(map[string]map[[sha256.Size]byte]struct{}),
+	}
+}
+
+// advance forgets all bids if slot is later than the current slot, and returns false for earlier slots.
+// The caller holds the lock.
+func (b *bidMemo) advance(slot phase0.Slot) bool {
+	if slot < b.slot {
+		return false
+	}
+	if slot > b.slot {
+		b.slot = slot
+		clear(b.entries)
+	}
+	return true
+}
+
+// verified returns true if the same relay already sent this bid in this slot, and it was verified
+func (b *bidMemo) verified(relay types.RelayEntry, slot phase0.Slot, body []byte) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.advance(slot) {
+		return false
+	}
+	_, ok := b.entries[relay.String()][sha256.Sum256(body)]
+	return ok
+}
+
+// record remembers a bid of which the relay signature was verified
+func (b *bidMemo) record(relay types.RelayEntry, slot phase0.Slot, body []byte) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.advance(slot) {
+		return
+	}
+	key := relay.String()
+	if b.entries[key] == nil {
+		b.entries[key] = make(map[[sha256.Size]byte]struct{})
+	}
+	if len(b.entries[key]) >= bidMemoMaxPerRelay {
+		return
+	}
+	b.entries[key][sha256.Sum256(body)] = struct{}{}
+}
@@ -0,0 +1,68 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/flashbots/mev-boost/server/mock"
+	"github.com/flashbots/mev-boost/server/types"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBidMemo(t *testing.T) {
+	const pubkey = "0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249"
+	relayA, err := types.NewRelayEntry("https://" + pubkey + "@a.com")
+	require.NoError(t, err)
+	relayB, err := types.NewRelayEntry("https://" + pubkey + "@b.com")
+	require.NoError(t, err)
+	bid := []byte(`{"version":"deneb"}`)
+
+	memo := newBidMemo()
+	require.False(t, memo.verified(relayA, 10, bid))
+	memo.record(relayA, 10, bid)
+	require.True(t, memo.verified(relayA, 10, bid))
+
+	// Only identical bids of the same relay
+	require.False(t, memo.verified(relayA, 10, []byte(`{"version":"electra"}`)))
+	require.False(t, memo.verified(relayB, 10, bid))
+
+	// Bids of earlier slots are neither remembered nor recognized
+	memo.record(relayB, 9, bid)
+	require.False(t, memo.verified(relayB, 9, bid))
+	require.True(t, memo.verified(relayA, 10, bid))
+
+	// A new slot invalidates all bids
+	require.False(t, memo.verified(relayA, 11, bid))
+	require.False(t, memo.verified(relayA, 10, bid))
+
+	// The number of bids per relay is bounded
+	for i := range bidMemoMaxPerRelay + 1 {
+		memo.record(relayA, 11, []byte{byte(i)})
+	}
+	require.True(t, memo.verified(relayA, 11, []byte{byte(bidMemoMaxPerRelay - 1)}))
+	require.False(t, memo.verified(relayA, 11, []byte{byte(bidMemoMaxPerRelay)}))
+}
+
+func TestGetHeaderBidMemo(t *testing.T) {
+	hash := mock.HexToHash("0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7")
+	pubkey := mock.HexToPubkey(
+		"0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249")
+
+	backend := newTestBackend(t, 1, time.Second)
+	hits := relayBidMemoHits.WithLabelValues(relayLabel(backend.relays[0].RelayEntry))
+	before := testutil.ToFloat64(hits)
+
+	// The relay sends the same bid again within the slot
+	for range 2 {
+		rr := backend.request(t, http.MethodGet, getHeaderPath(1, hash, pubkey), nil)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	}
+	require.InDelta(t, 1, testutil.ToFloat64(hits)-before, 0)
+
+	// The bid is verified again in the next slot
+	rr := backend.request(t, http.MethodGet, getHeaderPath(2, hash, pubkey), nil)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.InDelta(t, 1, testutil.ToFloat64(hits)-before, 0)
+}
@@ -0,0 +1,124 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"slices"
+	"sync"
+
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/flashbots/mev-boost/server/types"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// bidMetadataMaxSlotAge is the number of slots after which the metadata of a served bid is forgotten
+const bidMetadataMaxSlotAge = 2
+
+// How a getPayload request for a block without a known bid, e.g. after a restart of mev-boost, was handled
+const (
+	// coldStartPersisted asked the relays of the persisted bid metadata first
+	coldStartPersisted = "persisted"
+	// coldStartFanOut asked all relays, as no relay of the bid is known
+	coldStartFanOut = "fan_out"
+)
+
+var coldStartRecoveries = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "getpayload_cold_start_recoveries_total",
+	Help: "Number of getPayload requests for a block without a known bid, by recovery (persisted or fan_out) and whether the payload was delivered",
+}, []string{"recovery", "delivered"})
+
+// bidMetadata is the minimal information about a served bid needed to request its payload after a restart
+type bidMetadata struct {
+	Slot      phase0.Slot   `json:"slot,string"`
+	BlockHash phase0.Hash32 `json:"block_hash"`
+	Relays    []string      `json:"relays"`
+}
+
+// bidMetadataStore persists the relays of recently served bids, so that a beacon node submitting a block after a
+// restart of mev-boost is sent to the relays of its bid. All methods are no-ops on a nil value.
+type bidMetadataStore struct {
+	mu   sync.Mutex
+	path string
+	bids []bidMetadata
+}
+
+// newBidMetadataStore loads the bid metadata from the file, which does not need to exist yet
+func newBidMetadataStore(path string) (*bidMetadataStore, error) {
+	s := &bidMetadataStore{path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &s.bids); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// record stores the relays of the bid, forgets bids which are too old, and writes the bids to the file
+func (s *bidMetadataStore) record(slot phase0.Slot, blockHash phase0.Hash32, relays []types.RelayEntry) error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	bids := make([]bidMetadata, 0, len(s.bids)+1)
+	for _, bid := range s.bids {
+		if bid.Slot+bidMetadataMaxSlotAge < slot || (bid.Slot == slot && bid.BlockHash == blockHash) {
+			continue
+		}
+		bids = append(bids, bid)
+	}
+	bid := bidMetadata{Slot: slot, BlockHash: blockHash, Relays: make([]string, 0, len(relays))}
+	for _, relay := range relays {
+		bid.Relays = append(bid.Relays, relay.String())
+	}
+	s.bids = append(bids, bid)
+
+	data, err := json.Marshal(s.bids)
+	if err != nil {
+		return err
+	}
+	// Write to a temporary file first, so that a crash does not leave a truncated file behind
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// lookup returns the URLs of the relays of the bid, if the bid is known
+func (s *bidMetadataStore) lookup(slot phase0.Slot, blockHash phase0.Hash32) ([]string, bool) {
+	if s == nil {
+		return nil, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, bid := range s.bids {
+		if bid.Slot == slot && bid.BlockHash == blockHash {
+			return bid.Relays, true
+		}
+	}
+	return nil, false
+}
+
+// recoverBidRelays returns the configured relays of the persisted bid for the block, and all relays with those first
+func (m *BoostService) recoverBidRelays(slot phase0.Slot, blockHash phase0.Hash32) (recovered, ordered []types.RelayEntry) {
+	urls, _ := m.bidMetadata.lookup(slot, blockHash)
+	for _, relay := range m.relays {
+		if slices.Contains(urls, relay.String()) {
+			recovered = append(recovered, relay)
+		}
+	}
+	ordered = append(ordered, recovered...)
+	for _, relay := range m.relays {
+		if !slices.Contains(urls, relay.String()) {
+			ordered = append(ordered, relay)
+		}
+	}
+	return recovered, ordered
+}
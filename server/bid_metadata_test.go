@@ -0,0 +1,120 @@
+package server
+
+import (
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/attestantio/go-eth2-client/spec"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/flashbots/mev-boost/server/mock"
+	"github.com/flashbots/mev-boost/server/params"
+	"github.com/flashbots/mev-boost/server/types"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+	logrusTest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBidMetadataStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bids.json")
+	store, err := newBidMetadataStore(path)
+	require.NoError(t, err)
+	relays := []types.RelayEntry{mock.NewRelay(t).RelayEntry, mock.NewRelay(t).RelayEntry}
+
+	require.NoError(t, store.record(1, phase0.Hash32{0x01}, relays[:1]))
+	require.NoError(t, store.record(1, phase0.Hash32{0x01}, relays))
+	require.NoError(t, store.record(2, phase0.Hash32{0x02}, relays[1:]))
+
+	reloaded, err := newBidMetadataStore(path)
+	require.NoError(t, err)
+	urls, ok := reloaded.lookup(1, phase0.Hash32{0x01})
+	require.True(t, ok)
+	require.Equal(t, []string{relays[0].String(), relays[1].String()}, urls)
+	_, ok = reloaded.lookup(2, phase0.Hash32{0x01})
+	require.False(t, ok)
+
+	// Old bids are pruned
+	require.NoError(t, reloaded.record(2+bidMetadataMaxSlotAge, phase0.Hash32{0x03}, relays))
+	_, ok = reloaded.lookup(1, phase0.Hash32{0x01})
+	require.False(t, ok)
+	_, ok = reloaded.lookup(2, phase0.Hash32{0x02})
+	require.True(t, ok)
+}
+
+func TestGetHeaderPersistsBidMetadata(t *testing.T) {
+	parentHash := mock.HexToHash("0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7")
+	blockHash := mock.HexToHash("0xcf8e0d4e9587369b2301d0790347320302cc0943d5a1884560367e8208d920f2")
+	store, err := newBidMetadataStore(filepath.Join(t.TempDir(), "bids.json"))
+	require.NoError(t, err)
+
+	backend := newTestBackend(t, 2, time.Second)
+	backend.boost.bidMetadata = store
+	backend.relays[1].GetHeaderResponse = backend.relays[1].MakeGetHeaderResponse(
+		12345, blockHash.String(), parentHash.String(), feeRecipientTestPubkey, spec.DataVersionDeneb)
+	rr := backend.request(t, http.MethodGet, getHeaderPath(1, parentHash, mock.HexToPubkey(feeRecipientTestPubkey)), nil)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	require.Eventually(t, func() bool {
+		_, ok := store.lookup(1, blockHash)
+		return ok
+	}, time.Second, 10*time.Millisecond)
+	urls, _ := store.lookup(1, blockHash)
+	require.Equal(t, []string{backend.relays[1].RelayEntry.String()}, urls)
+}
+
+func TestGetPayloadColdStart(t *testing.T) {
+	t.Run("Blind fan-out without a known bid", func(t *testing.T) {
+		block, response := loadDenebBlock(t)
+		backend := newTestBackend(t, 2, time.Second)
+		backend.relays[1].GetPayloadResponse = response
+		before := testutil.ToFloat64(coldStartRecoveries.WithLabelValues(coldStartFanOut, "true"))
+
+		rr := backend.request(t, http.MethodPost, params.PathGetPayload, block)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		// The response does not wait for the relay without the payload
+		require.Eventually(t, func() bool {
+			return backend.relays[0].GetRequestCount(params.PathGetPayload) == 1
+		}, time.Second, 10*time.Millisecond)
+		require.Equal(t, 1, backend.relays[1].GetRequestCount(params.PathGetPayload))
+		require.InDelta(t, 1, testutil.ToFloat64(coldStartRecoveries.WithLabelValues(coldStartFanOut, "true"))-before, 0)
+	})
+
+	t.Run("Relays of the persisted bid are recovered", func(t *testing.T) {
+		block, _ := loadDenebBlock(t)
+		store, err := newBidMetadataStore(filepath.Join(t.TempDir(), "bids.json"))
+		require.NoError(t, err)
+		logger, hook := logrusTest.NewNullLogger()
+		backend := newTestBackend(t, 2, 100*time.Millisecond)
+		backend.boost.log = logrus.NewEntry(logger)
+		backend.boost.bidMetadata = store
+		require.NoError(t, store.record(slot(block), blockHash(block), []types.RelayEntry{backend.relays[1].RelayEntry}))
+
+		recovered, ordered := backend.boost.recoverBidRelays(slot(block), blockHash(block))
+		require.Equal(t, []types.RelayEntry{backend.relays[1].RelayEntry}, recovered)
+		require.Equal(t, []types.RelayEntry{backend.relays[1].RelayEntry, backend.relays[0].RelayEntry}, ordered)
+
+		// Neither relay delivers, and the relay of the bid is reported as withholding
+		failing := func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		backend.relays[0].OverrideHandleGetPayload(failing)
+		backend.relays[1].OverrideHandleGetPayload(failing)
+		before := testutil.ToFloat64(coldStartRecoveries.WithLabelValues(coldStartPersisted, "false"))
+		rr := backend.request(t, http.MethodPost, params.PathGetPayload, block)
+		require.Equal(t, http.StatusBadGateway, rr.Code, rr.Body.String())
+		require.Positive(t, backend.relays[0].GetRequestCount(params.PathGetPayload), "all relays are asked")
+		require.InDelta(t, 1, testutil.ToFloat64(coldStartRecoveries.WithLabelValues(coldStartPersisted, "false"))-before, 0)
+
+		var withholding *logrus.Entry
+		for _, entry := range hook.AllEntries() {
+			if entry.Message == "no payload received from relay!" {
+				withholding = entry
+			}
+		}
+		require.NotNil(t, withholding)
+		require.Equal(t, relayLabel(backend.relays[1].RelayEntry), withholding.Data["relaysWithBid"])
+		require.Equal(t, coldStartPersisted, withholding.Data["coldStartRecovery"])
+	})
+}
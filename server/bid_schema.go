@@ -0,0 +1,345 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+
+	builderSpec "github.com/attestantio/go-builder-client/spec"
+	"github.com/attestantio/go-eth2-client/spec"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/flashbots/mev-boost/server/types"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	errBidDecode          = errors.New("could not decode bid")
+	errBidSchemaViolation = errors.New("bid violates the builder spec")
+)
+
+// Kinds of schema violations, used as metric labels
+const (
+	schemaViolationInvalidJSON     = "invalid_json"
+	schemaViolationMissingField    = "missing_field"
+	schemaViolationInvalidType     = "invalid_type"
+	schemaViolationInvalidHex      = "invalid_hex"
+	schemaViolationInvalidLength   = "invalid_length"
+	schemaViolationInvalidNumber   = "invalid_number"
+	schemaViolationUnknownVersion  = "unknown_version"
+	schemaViolationVersionMismatch = "version_mismatch"
+	schemaViolationZeroValue       = "zero_value"
+)
+
+// maxSchemaSnippetLen is the maximum length of an offending value included in a diagnostic
+const maxSchemaSnippetLen = 24
+
+// bidSchemaViolation describes how a getHeader response from a relay deviates from the builder spec
+type bidSchemaViolation struct {
+	kind  string
+	field string
+	value string
+}
+
+func (v bidSchemaViolation) String() string {
+	if v.value == "" {
+		return fmt.Sprintf("%s: %s", v.field, v.kind)
+	}
+	return fmt.Sprintf("%s: %s (%s)", v.field, v.kind, v.value)
+}
+
+// decodeBid decodes a getHeader response, logging and counting every violation of the builder spec.
+// Bids with violations are rejected if strict relay schema checks are enabled, and accepted otherwise.
+func (m *BoostService) decodeBid(log *logrus.Entry, relay types.RelayEntry, body []byte) (*builderSpec.VersionedSignedBuilderBid, error) {
+	bid := new(builderSpec.VersionedSignedBuilderBid)
+	if err := json.Unmarshal(body, bid); err != nil {
+		// The decoder errors are hard to act on, find out exactly which fields are wrong
+		violations := diagnoseBidJSON(body)
+		m.reportBidSchemaViolations(log, relay, violations)
+		if len(violations) == 0 {
+			log.WithError(err).Warn("could not decode bid")
+		}
+		return nil, fmt.Errorf("%w: %w", errBidDecode, err)
+	}
+
+	violations := validateBidSchema(bid)
+	m.reportBidSchemaViolations(log, relay, violations)
+	if len(violations) > 0 && m.strictRelaySchema {
+		return nil, errBidSchemaViolation
+	}
+	return bid, nil
+}
+
+func (m *BoostService) reportBidSchemaViolations(log *logrus.Entry, relay types.RelayEntry, violations []bidSchemaViolation) {
+	for _, violation := range violations {
+		log.WithFields(logrus.Fields{
+			"field":  violation.field,
+			"kind":   violation.kind,
+			"value":  violation.value,
+			"strict": m.strictRelaySchema,
+		}).Warnf("relay bid violates the builder spec: %s", violation.String())
+		relayBidSchemaViolations.WithLabelValues(relayLabel(relay), violation.kind).Inc()
+	}
+}
+
+// validateBidSchema checks a decoded bid for consistency and for header fields which must never be zero
+func validateBidSchema(bid *builderSpec.VersionedSignedBuilderBid) []bidSchemaViolation {
+	violations := []bidSchemaViolation{}
+
+	// Exactly the data of the announced version must be present
+	present := []struct {
+		version spec.DataVersion
+		ok      bool
+	}{
+		{spec.DataVersionBellatrix, bid.Bellatrix != nil},
+		{spec.DataVersionCapella, bid.Capella != nil},
+		{spec.DataVersionDeneb, bid.Deneb != nil},
+		{spec.DataVersionElectra, bid.Electra != nil},
+	}
+	announcedPresent := false
+	for _, data := range present {
+		switch {
+		case data.ok && data.version == bid.Version:
+			announcedPresent = true
+		case data.ok:
+			violations = append(violations, bidSchemaViolation{schemaViolationVersionMismatch, "data", fmt.Sprintf("%s data in %s bid", data.version, bid.Version)})
+		}
+	}
+	if !announcedPresent {
+		return append(violations, bidSchemaViolation{schemaViolationVersionMismatch, "data", fmt.Sprintf("no %s data", bid.Version)})
+	}
+
+	checkZero := func(field string, isZero bool, err error) {
+		if err != nil {
+			violations = append(violations, bidSchemaViolation{schemaViolationMissingField, field, ""})
+		} else if isZero {
+			violations = append(violations, bidSchemaViolation{schemaViolationZeroValue, field, ""})
+		}
+	}
+
+	pubkey, err := bid.Builder()
+	checkZero("data.message.pubkey", pubkey == phase0.BLSPubKey{}, err)
+	signature, err := bid.Signature()
+	checkZero("data.signature", signature == phase0.BLSSignature{}, err)
+	blockHash, err := bid.BlockHash()
+	checkZero("data.message.header.block_hash", blockHash == phase0.Hash32{}, err)
+	parentHash, err := bid.ParentHash()
+	checkZero("data.message.header.parent_hash", parentHash == phase0.Hash32{}, err)
+	stateRoot, err := bid.StateRoot()
+	checkZero("data.message.header.state_root", stateRoot == phase0.Root{}, err)
+	feeRecipient, err := bid.FeeRecipient()
+	checkZero("data.message.header.fee_recipient", feeRecipient.IsZero(), err)
+	blockNumber, err := bid.BlockNumber()
+	checkZero("data.message.header.block_number", blockNumber == 0, err)
+	timestamp, err := bid.Timestamp()
+	checkZero("data.message.header.timestamp", timestamp == 0, err)
+	return violations
+}
+
+// bidHexField is a fixed-length hex field of the builder bid, with its length in bytes
+type bidHexField struct {
+	name   string
+	length int
+}
+
+var (
+	bidHeaderHexFields = []bidHexField{
+		{"parent_hash", 32},
+		{"fee_recipient", 20},
+		{"state_root", 32},
+		{"receipts_root", 32},
+		{"logs_bloom", 256},
+		{"prev_randao", 32},
+		{"block_hash", 32},
+		{"transactions_root", 32},
+	}
+	bidHeaderHexFieldsCapella  = []bidHexField{{"withdrawals_root", 32}}
+	bidHeaderNumberFields      = []string{"block_number", "gas_limit", "gas_used", "timestamp", "base_fee_per_gas"}
+	bidHeaderNumberFieldsDeneb = []string{"blob_gas_used", "excess_blob_gas"}
+
+	bidVersionsSupported       = []string{"bellatrix", "capella", "deneb", "electra"}
+	bidVersionsWithWithdrawals = []string{"capella", "deneb", "electra"}
+	bidVersionsWithBlobs       = []string{"deneb", "electra"}
+)
+
+const (
+	bidKZGCommitmentLength = 48
+	bidPubkeyLength        = 48
+	bidSignatureLength     = 96
+	bidExtraDataMaxLength  = 32
+)
+
+// diagnoseBidJSON walks the raw JSON of a getHeader response to find the fields violating the builder spec
+func diagnoseBidJSON(body []byte) []bidSchemaViolation {
+	d := &bidDiagnosis{violations: []bidSchemaViolation{}}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var root any
+	if err := decoder.Decode(&root); err != nil {
+		d.add(schemaViolationInvalidJSON, "response", snippet(string(body)))
+		return d.violations
+	}
+
+	rootObj, ok := d.object("response", root)
+	if !ok {
+		return d.violations
+	}
+
+	version, ok := d.str("version", rootObj["version"])
+	if !ok {
+		return d.violations
+	}
+	if !slices.Contains(bidVersionsSupported, version) {
+		d.add(schemaViolationUnknownVersion, "version", version)
+		return d.violations
+	}
+
+	data, ok := d.object("data", rootObj["data"])
+	if !ok {
+		return d.violations
+	}
+	d.hex("data.signature", data["signature"], bidSignatureLength)
+
+	message, ok := d.object("data.message", data["message"])
+	if !ok {
+		return d.violations
+	}
+	d.hex("data.message.pubkey", message["pubkey"], bidPubkeyLength)
+	d.number("data.message.value", message["value"])
+
+	if slices.Contains(bidVersionsWithBlobs, version) {
+		if commitments, ok := d.array("data.message.blob_kzg_commitments", message["blob_kzg_commitments"]); ok {
+			for i, commitment := range commitments {
+				d.hex(fmt.Sprintf("data.message.blob_kzg_commitments[%d]", i), commitment, bidKZGCommitmentLength)
+			}
+		}
+	}
+
+	header, ok := d.object("data.message.header", message["header"])
+	if !ok {
+		return d.violations
+	}
+	for _, field := range bidHeaderHexFields {
+		d.hex("data.message.header."+field.name, header[field.name], field.length)
+	}
+	if slices.Contains(bidVersionsWithWithdrawals, version) {
+		for _, field := range bidHeaderHexFieldsCapella {
+			d.hex("data.message.header."+field.name, header[field.name], field.length)
+		}
+	}
+	for _, field := range bidHeaderNumberFields {
+		d.number("data.message.header."+field, header[field])
+	}
+	if slices.Contains(bidVersionsWithBlobs, version) {
+		for _, field := range bidHeaderNumberFieldsDeneb {
+			d.number("data.message.header."+field, header[field])
+		}
+	}
+	d.hex("data.message.header.extra_data", header["extra_data"], -bidExtraDataMaxLength)
+	return d.violations
+}
+
+// bidDiagnosis collects schema violations while walking a JSON document
+type bidDiagnosis struct {
+	violations []bidSchemaViolation
+}
+
+func (d *bidDiagnosis) add(kind, field, value string) {
+	d.violations = append(d.violations, bidSchemaViolation{kind: kind, field: field, value: value})
+}
+
+func (d *bidDiagnosis) object(field string, value any) (map[string]any, bool) {
+	if value == nil {
+		d.add(schemaViolationMissingField, field, "")
+		return nil, false
+	}
+	obj, ok := value.(map[string]any)
+	if !ok {
+		d.add(schemaViolationInvalidType, field, fmt.Sprintf("expected object, got %s", jsonTypeName(value)))
+	}
+	return obj, ok
+}
+
+func (d *bidDiagnosis) array(field string, value any) ([]any, bool) {
+	if value == nil {
+		d.add(schemaViolationMissingField, field, "")
+		return nil, false
+	}
+	arr, ok := value.([]any)
+	if !ok {
+		d.add(schemaViolationInvalidType, field, fmt.Sprintf("expected array, got %s", jsonTypeName(value)))
+	}
+	return arr, ok
+}
+
+func (d *bidDiagnosis) str(field string, value any) (string, bool) {
+	if value == nil {
+		d.add(schemaViolationMissingField, field, "")
+		return "", false
+	}
+	s, ok := value.(string)
+	if !ok {
+		d.add(schemaViolationInvalidType, field, fmt.Sprintf("expected string, got %s %s", jsonTypeName(value), snippet(fmt.Sprint(value))))
+	}
+	return s, ok
+}
+
+// hex checks a hex string of the given length in bytes, a negative length is used as maximum length
+func (d *bidDiagnosis) hex(field string, value any, length int) {
+	s, ok := d.str(field, value)
+	if !ok {
+		return
+	}
+	b, err := hexutil.Decode(s)
+	if err != nil {
+		d.add(schemaViolationInvalidHex, field, snippet(s))
+		return
+	}
+	if (length >= 0 && len(b) != length) || (length < 0 && len(b) > -length) {
+		d.add(schemaViolationInvalidLength, field, fmt.Sprintf("%d bytes: %s", len(b), snippet(s)))
+	}
+}
+
+// number checks a uint256 encoded as a decimal string
+func (d *bidDiagnosis) number(field string, value any) {
+	if n, isNumber := value.(json.Number); isNumber {
+		d.add(schemaViolationInvalidType, field, fmt.Sprintf("expected decimal string, got number %s", snippet(n.String())))
+		return
+	}
+	s, ok := d.str(field, value)
+	if !ok {
+		return
+	}
+	n, ok := new(big.Int).SetString(s, 10)
+	if !ok || n.Sign() < 0 || n.BitLen() > 256 {
+		d.add(schemaViolationInvalidNumber, field, snippet(s))
+	}
+}
+
+func jsonTypeName(value any) string {
+	switch value.(type) {
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case json.Number:
+		return "number"
+	case bool:
+		return "bool"
+	}
+	return "null"
+}
+
+// snippet shortens a value for inclusion in a log message
+func snippet(s string) string {
+	if len(s) <= maxSchemaSnippetLen {
+		return s
+	}
+	return s[:maxSchemaSnippetLen] + "..."
+}
@@ -0,0 +1,195 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	builderApiDeneb "github.com/attestantio/go-builder-client/api/deneb"
+	builderSpec "github.com/attestantio/go-builder-client/spec"
+	"github.com/attestantio/go-eth2-client/spec"
+	"github.com/attestantio/go-eth2-client/spec/deneb"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/flashbots/mev-boost/server/mock"
+	"github.com/holiman/uint256"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func validDenebBid() *builderSpec.VersionedSignedBuilderBid {
+	return &builderSpec.VersionedSignedBuilderBid{
+		Version: spec.DataVersionDeneb,
+		Deneb: &builderApiDeneb.SignedBuilderBid{
+			Message: &builderApiDeneb.BuilderBid{
+				Header: &deneb.ExecutionPayloadHeader{
+					ParentHash:    mock.HexToHash("0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7"),
+					FeeRecipient:  mock.HexToAddress("0xdb65fEd33dc262Fe09D9a2Ba8F80b329BA25f941"),
+					StateRoot:     phase0.Root{0x01},
+					BlockNumber:   12345,
+					Timestamp:     1700000000,
+					BlockHash:     mock.HexToHash("0x534809bd2b6832edff8d8ce4cb0e50068804fd1ef432c8362ad708a74fdc0e46"),
+					BaseFeePerGas: uint256.NewInt(7),
+				},
+				BlobKZGCommitments: []deneb.KZGCommitment{},
+				Value:              uint256.NewInt(12345),
+				Pubkey:             mock.HexToPubkey("0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249"),
+			},
+			Signature: phase0.BLSSignature{0x02},
+		},
+	}
+}
+
+func TestValidateBidSchema(t *testing.T) {
+	t.Run("Valid bid", func(t *testing.T) {
+		require.Empty(t, validateBidSchema(validDenebBid()))
+	})
+
+	t.Run("Zero header fields", func(t *testing.T) {
+		bid := validDenebBid()
+		bid.Deneb.Message.Header.FeeRecipient = [20]byte{}
+		bid.Deneb.Message.Header.Timestamp = 0
+		violations := validateBidSchema(bid)
+		require.Equal(t, []bidSchemaViolation{
+			{schemaViolationZeroValue, "data.message.header.fee_recipient", ""},
+			{schemaViolationZeroValue, "data.message.header.timestamp", ""},
+		}, violations)
+	})
+
+	t.Run("Version mismatch", func(t *testing.T) {
+		bid := validDenebBid()
+		bid.Version = spec.DataVersionElectra
+		violations := validateBidSchema(bid)
+		require.Len(t, violations, 2)
+		require.Equal(t, schemaViolationVersionMismatch, violations[0].kind)
+		require.Equal(t, schemaViolationVersionMismatch, violations[1].kind)
+	})
+}
+
+func TestDiagnoseBidJSON(t *testing.T) {
+	validJSON, err := json.Marshal(validDenebBid())
+	require.NoError(t, err)
+
+	// modify returns the valid bid JSON, after applying f to the decoded message
+	modify := func(t *testing.T, f func(root, message, header map[string]any)) []byte {
+		t.Helper()
+		var root map[string]any
+		require.NoError(t, json.Unmarshal(validJSON, &root))
+		message := root["data"].(map[string]any)["message"].(map[string]any) //nolint:forcetypeassert
+		header := message["header"].(map[string]any)                         //nolint:forcetypeassert
+		f(root, message, header)
+		body, err := json.Marshal(root)
+		require.NoError(t, err)
+		return body
+	}
+
+	testCases := []struct {
+		name     string
+		body     []byte
+		expected []bidSchemaViolation
+	}{
+		{
+			name:     "Valid bid",
+			body:     validJSON,
+			expected: []bidSchemaViolation{},
+		},
+		{
+			name:     "Invalid JSON",
+			body:     []byte(`{"version":`),
+			expected: []bidSchemaViolation{{schemaViolationInvalidJSON, "response", `{"version":`}},
+		},
+		{
+			name:     "Unknown version",
+			body:     []byte(`{"version":"fulu","data":{}}`),
+			expected: []bidSchemaViolation{{schemaViolationUnknownVersion, "version", "fulu"}},
+		},
+		{
+			name: "Missing pubkey",
+			body: modify(t, func(_, message, _ map[string]any) {
+				delete(message, "pubkey")
+			}),
+			expected: []bidSchemaViolation{{schemaViolationMissingField, "data.message.pubkey", ""}},
+		},
+		{
+			name: "Value as a number",
+			body: modify(t, func(_, message, _ map[string]any) {
+				message["value"] = 12345
+			}),
+			expected: []bidSchemaViolation{{schemaViolationInvalidType, "data.message.value", "expected decimal string, got number 12345"}},
+		},
+		{
+			name: "Wrong length block hash",
+			body: modify(t, func(_, _, header map[string]any) {
+				header["block_hash"] = "0x5348"
+			}),
+			expected: []bidSchemaViolation{{schemaViolationInvalidLength, "data.message.header.block_hash", "2 bytes: 0x5348"}},
+		},
+		{
+			name: "Invalid hex and header as wrong type",
+			body: modify(t, func(root, message, _ map[string]any) {
+				root["data"].(map[string]any)["signature"] = "0xzz" //nolint:forcetypeassert
+				message["header"] = []any{}
+			}),
+			expected: []bidSchemaViolation{
+				{schemaViolationInvalidHex, "data.signature", "0xzz"},
+				{schemaViolationInvalidType, "data.message.header", "expected object, got array"},
+			},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, diagnoseBidJSON(tt.body))
+		})
+	}
+
+	t.Run("Long values are shortened", func(t *testing.T) {
+		body := modify(t, func(_, _, header map[string]any) {
+			header["parent_hash"] = "0x" + strings.Repeat("ab", 40)
+		})
+		violations := diagnoseBidJSON(body)
+		require.Len(t, violations, 1)
+		require.Equal(t, "40 bytes: 0xababababababababababab...", violations[0].value)
+	})
+}
+
+func TestGetHeaderStrictRelaySchema(t *testing.T) {
+	hash := mock.HexToHash("0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7")
+	pubkey := mock.HexToPubkey(
+		"0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249")
+	path := getHeaderPath(1, hash, pubkey)
+
+	// The default mock bid has no fee recipient, state root, block number or timestamp
+	t.Run("Violations are counted but accepted by default", func(t *testing.T) {
+		backend := newTestBackend(t, 1, time.Second)
+		label := relayLabel(backend.relays[0].RelayEntry)
+		before := testutil.ToFloat64(relayBidSchemaViolations.WithLabelValues(label, schemaViolationZeroValue))
+
+		rr := backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		require.InDelta(t, before+4, testutil.ToFloat64(relayBidSchemaViolations.WithLabelValues(label, schemaViolationZeroValue)), 0)
+	})
+
+	t.Run("Violations are rejected in strict mode", func(t *testing.T) {
+		backend := newTestBackend(t, 1, time.Second)
+		backend.boost.strictRelaySchema = true
+		rr := backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, http.StatusNoContent, rr.Code, rr.Body.String())
+	})
+
+	t.Run("Undecodable bids are diagnosed", func(t *testing.T) {
+		backend := newTestBackend(t, 1, time.Second)
+		label := relayLabel(backend.relays[0].RelayEntry)
+		before := testutil.ToFloat64(relayBidSchemaViolations.WithLabelValues(label, schemaViolationInvalidType))
+
+		backend.relays[0].OverrideHandleGetHeader(func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			_, err := w.Write([]byte(`{"version":"deneb","data":{"message":{"value":1}}}`))
+			require.NoError(t, err) //nolint:testifylint
+		})
+		rr := backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, http.StatusNoContent, rr.Code, rr.Body.String())
+		require.InDelta(t, before+1, testutil.ToFloat64(relayBidSchemaViolations.WithLabelValues(label, schemaViolationInvalidType)), 0)
+	})
+}
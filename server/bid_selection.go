@@ -0,0 +1,255 @@
+package server
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"time"
+
+	builderSpec "github.com/attestantio/go-builder-client/spec"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/flashbots/mev-boost/server/types"
+	"github.com/holiman/uint256"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+// Tie-breaks between identical bids (same value and block hash) delivered by several relays
+const (
+	BidTieBreakRelayPosition = "relay-position"
+	BidTieBreakReliability   = "reliability"
+	BidTieBreakRandom        = "random"
+)
+
+// Handling of bids for the same block hash with different values or builder pubkeys
+const (
+	ConflictingBidsLowest = "lowest"
+	ConflictingBidsDrop   = "drop"
+)
+
+var conflictingBids = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "conflicting_bids_total",
+	Help: "Number of bids of a relay for a block hash for which another relay bid a different value or builder pubkey",
+}, []string{"relay"})
+
+// bidCandidate is a bid which passed all checks during getHeader, and can be selected as the best bid
+type bidCandidate struct {
+	relay    types.RelayEntry
+	response builderSpec.VersionedSignedBuilderBid
+	bidInfo  bidInfo
+
+	// deprioritized is set if a bid filter deprioritized the bid, e.g. after a failed delivery of this block hash
+	deprioritized bool
+
+	// tieBreak describes how the candidate was selected among bids of the same value, set by selectBestBid
+	tieBreak string
+}
+
+// isMoreProfitable returns true if the candidate has a higher value than the other one, using the block hash as tiebreaker
+func (c *bidCandidate) isMoreProfitable(other *bidCandidate) bool {
+	valueDiff := c.bidInfo.value.Cmp(other.bidInfo.value)
+	if valueDiff != 0 {
+		return valueDiff == 1
+	}
+	return c.bidInfo.blockHash.String() < other.bidInfo.blockHash.String()
+}
+
+// selectBestBid returns the most profitable bid. Bids within the priority tolerance of the most profitable
+// bid win over it if they were delivered by a relay with a higher priority. Bids deprioritized by a bid filter
+// are only selected if there are no other bids. Bids of the same value are decided by the lowest block hash,
+// and the same bid from several relays by the configured tie-break.
+func (m *BoostService) selectBestBid(log *logrus.Entry, candidates []bidCandidate) (bidCandidate, bool) {
+	if len(candidates) == 0 {
+		return bidCandidate{}, false
+	}
+	deprioritized := func(c bidCandidate) bool { return c.deprioritized }
+	if slices.ContainsFunc(candidates, func(c bidCandidate) bool { return !deprioritized(c) }) {
+		candidates = slices.DeleteFunc(slices.Clone(candidates), deprioritized)
+	}
+
+	// Find the most profitable bid
+	mostProfitable := &candidates[0]
+	for i := range candidates {
+		if candidates[i].isMoreProfitable(mostProfitable) {
+			mostProfitable = &candidates[i]
+		}
+	}
+
+	// Find the bid from the relay with the highest priority, within the tolerance band
+	threshold := priorityToleranceThreshold(mostProfitable.bidInfo.value, m.relayPriorityToleranceBps)
+	best := mostProfitable
+	for i := range candidates {
+		candidate := &candidates[i]
+		if candidate.bidInfo.value.Lt(threshold) {
+			continue
+		}
+		if candidate.relay.Priority > best.relay.Priority ||
+			(candidate.relay.Priority == best.relay.Priority && candidate.isMoreProfitable(best)) {
+			best = candidate
+		}
+	}
+
+	if best != mostProfitable {
+		log.WithFields(logrus.Fields{
+			"selectedRelay":          relayLabel(best.relay),
+			"selectedRelayPriority":  best.relay.Priority,
+			"selectedValue":          best.bidInfo.value.Dec(),
+			"mostProfitableRelay":    relayLabel(mostProfitable.relay),
+			"mostProfitablePriority": mostProfitable.relay.Priority,
+			"mostProfitableValue":    mostProfitable.bidInfo.value.Dec(),
+			"toleranceBps":           m.relayPriorityToleranceBps,
+		}).Info("relay priority overrides bid value")
+	}
+
+	// The same builder often submits the same block to several relays
+	tied := []bidCandidate{}
+	sameValue := false
+	for _, candidate := range candidates {
+		if candidate.relay.Priority != best.relay.Priority || !candidate.bidInfo.value.Eq(best.bidInfo.value) {
+			continue
+		}
+		if candidate.bidInfo.blockHash != best.bidInfo.blockHash {
+			sameValue = true
+			continue
+		}
+		tied = append(tied, candidate)
+	}
+	selected := *best
+	switch {
+	case len(tied) > 1:
+		selected, selected.tieBreak = m.breakTie(tied, uint64(time.Now().UnixNano()))
+	case sameValue:
+		selected.tieBreak = "block-hash"
+	default:
+		selected.tieBreak = "none"
+	}
+	return selected, true
+}
+
+// resolveConflictingBids handles bids of several relays for the same block hash which do not agree on the value
+// or the builder pubkey. The block content determines both, so a conflict points to a relay bug or manipulation.
+// Conflicts are logged, counted and alerted. With the lowest policy, only the bids with the lowest value are kept,
+// and among them the bids with the builder pubkey of the relay first in the relay list. With the drop policy, no
+// bid for the block hash is kept. The order of the remaining candidates is unchanged.
+func (m *BoostService) resolveConflictingBids(log *logrus.Entry, slot phase0.Slot, candidates []bidCandidate) []bidCandidate {
+	byBlockHash := make(map[phase0.Hash32][]bidCandidate)
+	for _, candidate := range candidates {
+		byBlockHash[candidate.bidInfo.blockHash] = append(byBlockHash[candidate.bidInfo.blockHash], candidate)
+	}
+
+	// kept is the bid of each conflicting block hash whose value and builder pubkey are kept, or nil to drop all
+	kept := make(map[phase0.Hash32]*bidCandidate)
+	for blockHash, bids := range byBlockHash {
+		conflicting := slices.ContainsFunc(bids[1:], func(c bidCandidate) bool {
+			return !c.bidInfo.value.Eq(bids[0].bidInfo.value) || c.bidInfo.pubkey != bids[0].bidInfo.pubkey
+		})
+		if !conflicting {
+			continue
+		}
+
+		bids = slices.Clone(bids)
+		slices.SortFunc(bids, func(a, b bidCandidate) int {
+			if diff := a.bidInfo.value.Cmp(b.bidInfo.value); diff != 0 {
+				return diff
+			}
+			if diff := m.relayPosition(a.relay) - m.relayPosition(b.relay); diff != 0 {
+				return diff
+			}
+			return strings.Compare(a.relay.String(), b.relay.String())
+		})
+		reported := make([]string, 0, len(bids))
+		for _, bid := range bids {
+			conflictingBids.WithLabelValues(relayLabel(bid.relay)).Inc()
+			reported = append(reported, fmt.Sprintf("%s: value %s builder %s", relayLabel(bid.relay), bid.bidInfo.value.Dec(), bid.bidInfo.pubkey.String()))
+		}
+		fields := logrus.Fields{
+			"blockHash": blockHash.String(),
+			"bids":      strings.Join(reported, ", "),
+			"policy":    m.conflictingBids,
+		}
+		if m.conflictingBids == ConflictingBidsDrop {
+			kept[blockHash] = nil
+		} else {
+			kept[blockHash] = &bids[0]
+			fields["keptRelay"] = relayLabel(bids[0].relay)
+			fields["keptValue"] = bids[0].bidInfo.value.Dec()
+		}
+		log.WithFields(fields).Error("relays sent conflicting bids for the same block hash")
+		m.alerts.alert(AlertConflictingBids, "relays sent different values or builder pubkeys for the same block hash", logrus.Fields{
+			"slot":      uint64(slot),
+			"blockHash": blockHash.String(),
+			"bids":      reported,
+		})
+	}
+	if len(kept) == 0 {
+		return candidates
+	}
+
+	return slices.DeleteFunc(slices.Clone(candidates), func(c bidCandidate) bool {
+		keep, conflicting := kept[c.bidInfo.blockHash]
+		if !conflicting {
+			return false
+		}
+		return keep == nil || !c.bidInfo.value.Eq(keep.bidInfo.value) || c.bidInfo.pubkey != keep.bidInfo.pubkey
+	})
+}
+
+// breakTie selects one of several relays which delivered the same bid, and describes how it was selected.
+// The result only depends on the candidates, the relay list, the recent failed deliveries and the seed.
+func (m *BoostService) breakTie(tied []bidCandidate, seed uint64) (bidCandidate, string) {
+	byPosition := func(a, b bidCandidate) int {
+		if diff := m.relayPosition(a.relay) - m.relayPosition(b.relay); diff != 0 {
+			return diff
+		}
+		return strings.Compare(a.relay.String(), b.relay.String())
+	}
+	tied = slices.Clone(tied)
+	slices.SortFunc(tied, byPosition)
+
+	switch m.bidTieBreak {
+	case BidTieBreakReliability:
+		slices.SortStableFunc(tied, func(a, b bidCandidate) int {
+			return m.failedDeliveries.count(a.relay) - m.failedDeliveries.count(b.relay)
+		})
+		return tied[0], BidTieBreakReliability
+	case BidTieBreakRandom:
+		// Relays are selected in proportion to their weight
+		total := 0
+		for _, candidate := range tied {
+			total += candidate.relay.TieBreakWeight()
+		}
+		rng := rand.New(rand.NewPCG(seed, 0)) //nolint:gosec // not used for security
+		pick := rng.IntN(total)
+		selected := tied[len(tied)-1]
+		for _, candidate := range tied {
+			if pick < candidate.relay.TieBreakWeight() {
+				selected = candidate
+				break
+			}
+			pick -= candidate.relay.TieBreakWeight()
+		}
+		return selected, fmt.Sprintf("%s (seed %d)", BidTieBreakRandom, seed)
+	default:
+		return tied[0], BidTieBreakRelayPosition
+	}
+}
+
+// relayPosition returns the index of the relay in the relay list, or the length of the list for unknown relays
+func (m *BoostService) relayPosition(relay types.RelayEntry) int {
+	for i, r := range m.relays {
+		if r.String() == relay.String() {
+			return i
+		}
+	}
+	return len(m.relays)
+}
+
+// priorityToleranceThreshold returns the lowest value which is within the tolerance (in basis points) of the given value
+func priorityToleranceThreshold(value *uint256.Int, toleranceBps uint64) *uint256.Int {
+	if toleranceBps >= 10000 {
+		return uint256.NewInt(0)
+	}
+	tolerance, _ := new(uint256.Int).MulDivOverflow(value, uint256.NewInt(toleranceBps), uint256.NewInt(10000))
+	return new(uint256.Int).Sub(value, tolerance)
+}
@@ -0,0 +1,262 @@
+package server
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/flashbots/mev-boost/server/mock"
+	"github.com/flashbots/mev-boost/server/types"
+	"github.com/holiman/uint256"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestCandidate(t *testing.T, relayURL string, priority int, value uint64, blockHash phase0.Hash32) bidCandidate {
+	t.Helper()
+	relay, err := types.NewRelayEntry(relayURL)
+	require.NoError(t, err)
+	relay.Priority = priority
+	return bidCandidate{
+		relay: relay,
+		bidInfo: bidInfo{
+			blockHash: blockHash,
+			value:     uint256.NewInt(value),
+		},
+	}
+}
+
+func TestPriorityToleranceThreshold(t *testing.T) {
+	require.Equal(t, uint256.NewInt(1000), priorityToleranceThreshold(uint256.NewInt(1000), 0))
+	require.Equal(t, uint256.NewInt(990), priorityToleranceThreshold(uint256.NewInt(1000), 100))
+	require.Equal(t, uint256.NewInt(0), priorityToleranceThreshold(uint256.NewInt(1000), 10000))
+}
+
+func TestSelectBestBid(t *testing.T) {
+	const pubkey = "0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249"
+	hashA := mock.HexToHash("0xa18385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7")
+	hashB := mock.HexToHash("0xb28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7")
+
+	t.Run("No candidates", func(t *testing.T) {
+		backend := newTestBackend(t, 1, time.Second)
+		_, ok := backend.boost.selectBestBid(mock.TestLog, nil)
+		require.False(t, ok)
+	})
+
+	t.Run("Highest value wins without priorities", func(t *testing.T) {
+		backend := newTestBackend(t, 1, time.Second)
+		backend.boost.relayPriorityToleranceBps = 100
+		candidates := []bidCandidate{
+			newTestCandidate(t, "http://"+pubkey+"@primary.com", 0, 995, hashA),
+			newTestCandidate(t, "http://"+pubkey+"@backup.com", 0, 1000, hashB),
+		}
+		best, ok := backend.boost.selectBestBid(mock.TestLog, candidates)
+		require.True(t, ok)
+		require.Equal(t, "backup.com", best.relay.URL.Host)
+	})
+
+	t.Run("Priority wins within the tolerance band", func(t *testing.T) {
+		backend := newTestBackend(t, 1, time.Second)
+		backend.boost.relayPriorityToleranceBps = 100 // 1%
+		candidates := []bidCandidate{
+			newTestCandidate(t, "http://"+pubkey+"@backup.com", 0, 1000, hashB),
+			newTestCandidate(t, "http://"+pubkey+"@primary.com", 10, 991, hashA),
+		}
+		best, ok := backend.boost.selectBestBid(mock.TestLog, candidates)
+		require.True(t, ok)
+		require.Equal(t, "primary.com", best.relay.URL.Host)
+	})
+
+	t.Run("Highest value wins outside the tolerance band", func(t *testing.T) {
+		backend := newTestBackend(t, 1, time.Second)
+		backend.boost.relayPriorityToleranceBps = 100 // 1%
+		candidates := []bidCandidate{
+			newTestCandidate(t, "http://"+pubkey+"@backup.com", 0, 1000, hashB),
+			newTestCandidate(t, "http://"+pubkey+"@primary.com", 10, 989, hashA),
+		}
+		best, ok := backend.boost.selectBestBid(mock.TestLog, candidates)
+		require.True(t, ok)
+		require.Equal(t, "backup.com", best.relay.URL.Host)
+	})
+
+	t.Run("Priority breaks exact ties without tolerance", func(t *testing.T) {
+		backend := newTestBackend(t, 1, time.Second)
+		candidates := []bidCandidate{
+			newTestCandidate(t, "http://"+pubkey+"@backup.com", 0, 1000, hashA),
+			newTestCandidate(t, "http://"+pubkey+"@primary.com", 1, 1000, hashB),
+		}
+		best, ok := backend.boost.selectBestBid(mock.TestLog, candidates)
+		require.True(t, ok)
+		require.Equal(t, "primary.com", best.relay.URL.Host)
+	})
+}
+
+func TestBidTieBreak(t *testing.T) {
+	const pubkey = "0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249"
+	hash := mock.HexToHash("0xa18385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7")
+
+	// newBackend returns a backend with three relays, and candidates with the same bid from each of them
+	newBackend := func(t *testing.T, tieBreak string) (*testBackend, []bidCandidate) {
+		t.Helper()
+		backend := newTestBackend(t, 1, time.Second)
+		backend.boost.bidTieBreak = tieBreak
+		candidates := []bidCandidate{
+			newTestCandidate(t, "http://"+pubkey+"@relay-a.com", 0, 1000, hash),
+			newTestCandidate(t, "http://"+pubkey+"@relay-b.com", 0, 1000, hash),
+			newTestCandidate(t, "http://"+pubkey+"@relay-c.com", 0, 1000, hash),
+		}
+		backend.boost.relays = []types.RelayEntry{candidates[0].relay, candidates[1].relay, candidates[2].relay}
+		return backend, candidates
+	}
+
+	// selectRepeatedly selects the best bid from shuffled candidates, and requires the same result every time
+	selectRepeatedly := func(t *testing.T, selectBest func([]bidCandidate) bidCandidate, candidates []bidCandidate) bidCandidate {
+		t.Helper()
+		first := selectBest(candidates)
+		for i := 0; i < 20; i++ {
+			shuffled := slices.Clone(candidates)
+			rand.Shuffle(len(shuffled), func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
+			selected := selectBest(shuffled)
+			require.Equal(t, first.relay.String(), selected.relay.String())
+			require.Equal(t, first.tieBreak, selected.tieBreak)
+		}
+		return first
+	}
+
+	t.Run("Relay position", func(t *testing.T) {
+		backend, candidates := newBackend(t, BidTieBreakRelayPosition)
+		best := selectRepeatedly(t, func(c []bidCandidate) bidCandidate {
+			best, ok := backend.boost.selectBestBid(mock.TestLog, c)
+			require.True(t, ok)
+			return best
+		}, candidates)
+		require.Equal(t, "relay-a.com", best.relay.URL.Host)
+		require.Equal(t, BidTieBreakRelayPosition, best.tieBreak)
+	})
+
+	t.Run("Reliability", func(t *testing.T) {
+		backend, candidates := newBackend(t, BidTieBreakReliability)
+		backend.boost.failedDeliveries.record(1, phase0.Hash32{0x01}, []types.RelayEntry{candidates[0].relay})
+		best := selectRepeatedly(t, func(c []bidCandidate) bidCandidate {
+			best, ok := backend.boost.selectBestBid(mock.TestLog, c)
+			require.True(t, ok)
+			return best
+		}, candidates)
+		require.Equal(t, "relay-b.com", best.relay.URL.Host)
+		require.Equal(t, BidTieBreakReliability, best.tieBreak)
+	})
+
+	t.Run("Random with the same seed", func(t *testing.T) {
+		backend, candidates := newBackend(t, BidTieBreakRandom)
+		for _, seed := range []uint64{1, 2, 3, 12345} {
+			best := selectRepeatedly(t, func(c []bidCandidate) bidCandidate {
+				best, tieBreak := backend.boost.breakTie(c, seed)
+				best.tieBreak = tieBreak
+				return best
+			}, candidates)
+			require.Equal(t, fmt.Sprintf("random (seed %d)", seed), best.tieBreak)
+		}
+
+		best, ok := backend.boost.selectBestBid(mock.TestLog, candidates)
+		require.True(t, ok)
+		require.True(t, strings.HasPrefix(best.tieBreak, "random (seed "))
+	})
+
+	t.Run("Random by weight", func(t *testing.T) {
+		backend, candidates := newBackend(t, BidTieBreakRandom)
+		candidates[1].relay.Weight = 1000
+		selected := map[string]int{}
+		for seed := range uint64(50) {
+			best, _ := backend.boost.breakTie(candidates, seed)
+			selected[best.relay.URL.Host]++
+		}
+		require.Greater(t, selected["relay-b.com"], 45)
+	})
+
+	t.Run("Different blocks are decided by block hash", func(t *testing.T) {
+		backend, _ := newBackend(t, BidTieBreakRelayPosition)
+		candidates := []bidCandidate{
+			newTestCandidate(t, "http://"+pubkey+"@relay-a.com", 0, 1000, mock.HexToHash("0xb28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7")),
+			newTestCandidate(t, "http://"+pubkey+"@relay-b.com", 0, 1000, hash),
+		}
+		best, ok := backend.boost.selectBestBid(mock.TestLog, candidates)
+		require.True(t, ok)
+		require.Equal(t, "relay-b.com", best.relay.URL.Host)
+		require.Equal(t, "block-hash", best.tieBreak)
+	})
+
+	t.Run("No tie", func(t *testing.T) {
+		backend, candidates := newBackend(t, BidTieBreakRelayPosition)
+		candidates[2].bidInfo.value = uint256.NewInt(1001)
+		best, ok := backend.boost.selectBestBid(mock.TestLog, candidates)
+		require.True(t, ok)
+		require.Equal(t, "relay-c.com", best.relay.URL.Host)
+		require.Equal(t, "none", best.tieBreak)
+	})
+}
+
+func TestResolveConflictingBids(t *testing.T) {
+	const pubkey = "0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249"
+	hashA := mock.HexToHash("0xa18385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7")
+	hashB := mock.HexToHash("0xb28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7")
+
+	// newBackend returns a backend with three relays, the first two bidding different values for the same block hash
+	newBackend := func(t *testing.T, policy string) (*testBackend, []bidCandidate) {
+		t.Helper()
+		backend := newTestBackend(t, 1, time.Second)
+		backend.boost.conflictingBids = policy
+		candidates := []bidCandidate{
+			newTestCandidate(t, "http://"+pubkey+"@relay-a.com", 0, 2000, hashA),
+			newTestCandidate(t, "http://"+pubkey+"@relay-b.com", 0, 1000, hashA),
+			newTestCandidate(t, "http://"+pubkey+"@relay-c.com", 0, 1500, hashB),
+		}
+		backend.boost.relays = []types.RelayEntry{candidates[0].relay, candidates[1].relay, candidates[2].relay}
+		return backend, candidates
+	}
+	hosts := func(candidates []bidCandidate) []string {
+		var hosts []string
+		for _, c := range candidates {
+			hosts = append(hosts, c.relay.URL.Host)
+		}
+		return hosts
+	}
+
+	t.Run("Bids which agree are kept", func(t *testing.T) {
+		backend, candidates := newBackend(t, ConflictingBidsLowest)
+		candidates[1].bidInfo.value = uint256.NewInt(2000)
+		require.Equal(t, []string{"relay-a.com", "relay-b.com", "relay-c.com"}, hosts(backend.boost.resolveConflictingBids(mock.TestLog, 1, candidates)))
+	})
+
+	t.Run("The lowest value is kept", func(t *testing.T) {
+		backend, candidates := newBackend(t, ConflictingBidsLowest)
+		label := relayLabel(candidates[0].relay)
+		before := testutil.ToFloat64(conflictingBids.WithLabelValues(label))
+		resolved := backend.boost.resolveConflictingBids(mock.TestLog, 1, candidates)
+		require.Equal(t, []string{"relay-b.com", "relay-c.com"}, hosts(resolved))
+		require.InDelta(t, 1, testutil.ToFloat64(conflictingBids.WithLabelValues(label))-before, 0)
+
+		// The higher conflicting bid does not win, although it is the most profitable one
+		best, ok := backend.boost.selectBestBid(mock.TestLog, resolved)
+		require.True(t, ok)
+		require.Equal(t, "relay-c.com", best.relay.URL.Host)
+	})
+
+	t.Run("The builder pubkey of the relay first in the relay list is kept", func(t *testing.T) {
+		backend, candidates := newBackend(t, ConflictingBidsLowest)
+		candidates[0].bidInfo.value = uint256.NewInt(1000)
+		candidates[0].bidInfo.pubkey = phase0.BLSPubKey{0x01}
+		candidates = append(candidates, newTestCandidate(t, "http://"+pubkey+"@relay-d.com", 0, 1000, hashA))
+		candidates[3].bidInfo.pubkey = phase0.BLSPubKey{0x01}
+		slices.Reverse(candidates)
+		require.Equal(t, []string{"relay-d.com", "relay-c.com", "relay-a.com"}, hosts(backend.boost.resolveConflictingBids(mock.TestLog, 1, candidates)))
+	})
+
+	t.Run("All bids for the block hash are dropped", func(t *testing.T) {
+		backend, candidates := newBackend(t, ConflictingBidsDrop)
+		require.Equal(t, []string{"relay-c.com"}, hosts(backend.boost.resolveConflictingBids(mock.TestLog, 1, candidates)))
+	})
+}
@@ -0,0 +1,150 @@
+package server
+
+import (
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// bidStoreShards is the number of shards of the bid store. Bids are sharded by slot, so that the getHeader and
+// getPayload requests of a slot do not wait for the requests of other slots, nor for a whole cleanup sweep.
+const bidStoreShards = 16
+
+var (
+	bidStoreLockWait = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "bid_store_lock_wait_seconds",
+		Help:    "Time waited for a lock of the bid store, by operation (get, put, slot, sweep)",
+		Buckets: prometheus.ExponentialBuckets(0.000001, 4, 10),
+	}, []string{"operation"})
+	bidCacheEntries = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "bid_cache_entries",
+		Help: "Number of bids in the bid cache",
+	})
+	bidCacheEvictions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "bid_cache_evictions_total",
+		Help: "Number of bids removed from the bid cache, by reason (expired, max_slots or flush)",
+	}, []string{"reason"})
+)
+
+// bidStore keeps the bids returned by getHeader, by slot and block hash
+type bidStore interface {
+	// get returns the bid for the block hash in the slot
+	get(slot phase0.Slot, blockHash phase0.Hash32) (bidResp, bool)
+	// put stores the bid for the block hash in the slot, replacing a previous bid
+	put(slot phase0.Slot, blockHash phase0.Hash32, bid bidResp)
+	// slotBids returns the bids of the slot
+	slotBids(slot phase0.Slot) []bidResp
+	// slots returns the slots of the stored bids
+	slots() []phase0.Slot
+	// deleteFunc removes the bids for which del returns true, and returns the number of removed bids
+	deleteFunc(del func(bidResp) bool) int
+	// len returns the number of stored bids
+	len() int
+}
+
+// bidStoreShard is a shard of the bid store, with the bids of a subset of the slots
+type bidStoreShard struct {
+	mu   sync.Mutex
+	bids map[phase0.Slot]map[phase0.Hash32]bidResp
+}
+
+// lock takes the lock of the shard, and measures how long it waited for it
+func (s *bidStoreShard) lock(operation string) {
+	start := time.Now()
+	s.mu.Lock()
+	bidStoreLockWait.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+}
+
+// shardedBidStore is a bidStore with a lock per shard of slots
+type shardedBidStore struct {
+	shards [bidStoreShards]bidStoreShard
+}
+
+func newShardedBidStore() *shardedBidStore {
+	s := &shardedBidStore{}
+	for i := range s.shards {
+		s.shards[i].bids = make(map[phase0.Slot]map[phase0.Hash32]bidResp)
+	}
+	return s
+}
+
+func (s *shardedBidStore) shard(slot phase0.Slot) *bidStoreShard {
+	return &s.shards[slot%bidStoreShards]
+}
+
+func (s *shardedBidStore) get(slot phase0.Slot, blockHash phase0.Hash32) (bidResp, bool) {
+	shard := s.shard(slot)
+	shard.lock("get")
+	defer shard.mu.Unlock()
+	bid, ok := shard.bids[slot][blockHash]
+	return bid, ok
+}
+
+func (s *shardedBidStore) put(slot phase0.Slot, blockHash phase0.Hash32, bid bidResp) {
+	shard := s.shard(slot)
+	shard.lock("put")
+	defer shard.mu.Unlock()
+	if shard.bids[slot] == nil {
+		shard.bids[slot] = make(map[phase0.Hash32]bidResp)
+	}
+	shard.bids[slot][blockHash] = bid
+}
+
+func (s *shardedBidStore) slotBids(slot phase0.Slot) []bidResp {
+	shard := s.shard(slot)
+	shard.lock("slot")
+	defer shard.mu.Unlock()
+	return slices.Collect(maps.Values(shard.bids[slot]))
+}
+
+func (s *shardedBidStore) slots() []phase0.Slot {
+	var slots []phase0.Slot
+	for i := range s.shards {
+		shard := &s.shards[i]
+		shard.lock("sweep")
+		for slot := range shard.bids {
+			slots = append(slots, slot)
+		}
+		shard.mu.Unlock()
+	}
+	return slots
+}
+
+// deleteFunc locks one shard at a time, so a sweep only holds back the requests of the slots of one shard
+func (s *shardedBidStore) deleteFunc(del func(bidResp) bool) int {
+	removed := 0
+	for i := range s.shards {
+		shard := &s.shards[i]
+		shard.lock("sweep")
+		for slot, bids := range shard.bids {
+			for blockHash, bid := range bids {
+				if del(bid) {
+					delete(bids, blockHash)
+					removed++
+				}
+			}
+			if len(bids) == 0 {
+				delete(shard.bids, slot)
+			}
+		}
+		shard.mu.Unlock()
+	}
+	return removed
+}
+
+func (s *shardedBidStore) len() int {
+	n := 0
+	for i := range s.shards {
+		shard := &s.shards[i]
+		shard.lock("sweep")
+		for _, bids := range shard.bids {
+			n += len(bids)
+		}
+		shard.mu.Unlock()
+	}
+	return n
+}
@@ -0,0 +1,170 @@
+package server
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/stretchr/testify/require"
+)
+
+// mutexBidStore is a bidStore with a single lock, like the bid map before the sharded store, to compare with
+type mutexBidStore struct {
+	mu   sync.Mutex
+	bids map[string]bidResp
+}
+
+func (s *mutexBidStore) get(slot phase0.Slot, blockHash phase0.Hash32) (bidResp, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	bid, ok := s.bids[bidKey(slot, blockHash)]
+	return bid, ok
+}
+
+func (s *mutexBidStore) put(slot phase0.Slot, blockHash phase0.Hash32, bid bidResp) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.bids[bidKey(slot, blockHash)] = bid
+}
+
+func (s *mutexBidStore) slotBids(slot phase0.Slot) []bidResp {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var bids []bidResp
+	for _, bid := range s.bids {
+		if bid.slot == slot {
+			bids = append(bids, bid)
+		}
+	}
+	return bids
+}
+
+func (s *mutexBidStore) slots() []phase0.Slot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	seen := make(map[phase0.Slot]bool)
+	var slots []phase0.Slot
+	for _, bid := range s.bids {
+		if !seen[bid.slot] {
+			seen[bid.slot] = true
+			slots = append(slots, bid.slot)
+		}
+	}
+	return slots
+}
+
+func (s *mutexBidStore) deleteFunc(del func(bidResp) bool) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	removed := 0
+	for k, bid := range s.bids {
+		if del(bid) {
+			delete(s.bids, k)
+			removed++
+		}
+	}
+	return removed
+}
+
+func (s *mutexBidStore) len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.bids)
+}
+
+func TestShardedBidStore(t *testing.T) {
+	store := newShardedBidStore()
+	_, ok := store.get(1, phase0.Hash32{0x01})
+	require.False(t, ok)
+
+	store.put(1, phase0.Hash32{0x01}, bidResp{slot: 1, tenant: "a"})
+	store.put(1, phase0.Hash32{0x02}, bidResp{slot: 1})
+	store.put(1+bidStoreShards, phase0.Hash32{0x01}, bidResp{slot: 1 + bidStoreShards})
+	store.put(2, phase0.Hash32{0x03}, bidResp{slot: 2})
+	require.Equal(t, 4, store.len())
+
+	bid, ok := store.get(1, phase0.Hash32{0x01})
+	require.True(t, ok)
+	require.Equal(t, "a", bid.tenant)
+
+	// A bid replaces the bid of the same slot and block hash
+	store.put(1, phase0.Hash32{0x01}, bidResp{slot: 1, tenant: "b"})
+	bid, _ = store.get(1, phase0.Hash32{0x01})
+	require.Equal(t, "b", bid.tenant)
+	require.Equal(t, 4, store.len())
+
+	// Slots of the same shard are kept apart
+	require.Len(t, store.slotBids(1), 2)
+	require.Len(t, store.slotBids(1+bidStoreShards), 1)
+	require.Empty(t, store.slotBids(3))
+	require.ElementsMatch(t, []phase0.Slot{1, 2, 1 + bidStoreShards}, store.slots())
+
+	removed := store.deleteFunc(func(bid bidResp) bool { return bid.slot == 1 })
+	require.Equal(t, 2, removed)
+	require.ElementsMatch(t, []phase0.Slot{2, 1 + bidStoreShards}, store.slots())
+	require.Equal(t, 2, store.deleteFunc(func(bidResp) bool { return true }))
+	require.Zero(t, store.len())
+}
+
+// BenchmarkBidStore runs getHeader, getPayload and cached bid lookups of recent slots in parallel, with cleanup
+// sweeps of the whole store, against the single lock store and the sharded store
+func BenchmarkBidStore(b *testing.B) {
+	stores := map[string]func() bidStore{
+		"mutex":   func() bidStore { return &mutexBidStore{bids: make(map[string]bidResp)} },
+		"sharded": func() bidStore { return newShardedBidStore() },
+	}
+	for _, name := range []string{"mutex", "sharded"} {
+		b.Run(name, func(b *testing.B) {
+			store := stores[name]()
+			// Bids of the last 64 slots, like a cache kept for a few minutes
+			for slot := phase0.Slot(0); slot < 64; slot++ {
+				for i := range 20 {
+					store.put(slot, phase0.Hash32{byte(i)}, bidResp{slot: slot, t: time.Now()})
+				}
+			}
+
+			done := make(chan struct{})
+			var sweeps sync.WaitGroup
+			sweeps.Add(1)
+			go func() {
+				defer sweeps.Done()
+				for {
+					select {
+					case <-done:
+						return
+					default:
+						store.deleteFunc(func(bid bidResp) bool { return bid.slot > 1<<40 })
+						time.Sleep(100 * time.Microsecond)
+					}
+				}
+			}()
+
+			var worker atomic.Uint64
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				slot := phase0.Slot(worker.Add(1) % 64)
+				i := 0
+				for pb.Next() {
+					blockHash := phase0.Hash32{byte(i % 20)}
+					switch i % 4 {
+					case 0:
+						store.put(slot, blockHash, bidResp{slot: slot, t: time.Now()})
+					case 1:
+						store.slotBids(slot)
+					default:
+						if _, ok := store.get(slot, blockHash); !ok {
+							panic(fmt.Sprintf("missing bid of slot %d", slot))
+						}
+					}
+					i++
+				}
+			})
+			b.StopTimer()
+			close(done)
+			sweeps.Wait()
+		})
+	}
+}
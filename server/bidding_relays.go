@@ -0,0 +1,55 @@
+package server
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+// Policies for auctions with fewer bidding relays than the minimum
+const (
+	MinBiddingRelaysStrict = "strict" // respond without a bid
+	MinBiddingRelaysWarn   = "warn"   // serve the best bid, flagged as low confidence
+)
+
+var (
+	errInvalidMinBiddingRelays       = errors.New("minimum bidding relays must be between 0 and the number of relays")
+	errInvalidMinBiddingRelaysPolicy = errors.New("minimum bidding relays policy must be strict or warn")
+
+	auctionBiddingRelays = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "auction_bidding_relays_total",
+		Help: "Number of auctions by the number of relays with an acceptable bid",
+	}, []string{"bidding_relays"})
+	lowConfidenceAuctions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "low_confidence_auctions_total",
+		Help: "Number of auctions with a bid but fewer bidding relays than the minimum, by policy (strict or warn)",
+	}, []string{"policy"})
+)
+
+// checkBiddingRelays counts the auction by its number of bidding relays, and returns false if its bid must not be
+// served because fewer relays than the minimum bid. With the warn policy the bid is served, and the returned log
+// flags the auction as low confidence. Bids served from the cache were checked when they were first served.
+func (m *BoostService) checkBiddingRelays(log *logrus.Entry, result bidResp) (*logrus.Entry, bool) {
+	if result.servedFromCache {
+		return log, true
+	}
+	auctionBiddingRelays.WithLabelValues(strconv.Itoa(result.biddingRelays)).Inc()
+	if result.response.IsEmpty() || result.biddingRelays >= m.minBiddingRelays {
+		return log, true
+	}
+
+	lowConfidenceAuctions.WithLabelValues(m.minBiddingRelaysPolicy).Inc()
+	log = log.WithFields(logrus.Fields{
+		"biddingRelays":    result.biddingRelays,
+		"minBiddingRelays": m.minBiddingRelays,
+	})
+	if m.minBiddingRelaysPolicy == MinBiddingRelaysStrict {
+		log.Warn("fewer relays bid than the minimum, not serving the bid")
+		return log, false
+	}
+	log = log.WithField("lowConfidence", true)
+	log.Warn("fewer relays bid than the minimum, serving the bid as low confidence")
+	return log, true
+}
@@ -0,0 +1,80 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/flashbots/mev-boost/server/mock"
+	"github.com/flashbots/mev-boost/server/types"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMinBiddingRelays(t *testing.T) {
+	parentHash := mock.HexToHash("0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7")
+	path := getHeaderPath(1, parentHash, mock.HexToPubkey(feeRecipientTestPubkey))
+
+	// newBackend returns three mirrored relays sending the same bid, of which the last one does not bid if silent
+	newBackend := func(t *testing.T, policy string, silent bool) *testBackend {
+		t.Helper()
+		backend := newTestBackend(t, 3, time.Second)
+		backend.boost.minBiddingRelays = 3
+		backend.boost.minBiddingRelaysPolicy = policy
+		if silent {
+			backend.relays[2].OverrideHandleGetHeader(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusNoContent)
+			})
+		}
+		return backend
+	}
+
+	t.Run("Mirrored relays count as several sources", func(t *testing.T) {
+		backend := newBackend(t, MinBiddingRelaysStrict, false)
+		before := testutil.ToFloat64(auctionBiddingRelays.WithLabelValues("3"))
+		rr := backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		require.InDelta(t, 1, testutil.ToFloat64(auctionBiddingRelays.WithLabelValues("3"))-before, 0)
+	})
+
+	t.Run("Warn serves the bid", func(t *testing.T) {
+		backend := newBackend(t, MinBiddingRelaysWarn, true)
+		before := testutil.ToFloat64(lowConfidenceAuctions.WithLabelValues(MinBiddingRelaysWarn))
+		beforeCount := testutil.ToFloat64(auctionBiddingRelays.WithLabelValues("2"))
+		rr := backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		require.InDelta(t, 1, testutil.ToFloat64(lowConfidenceAuctions.WithLabelValues(MinBiddingRelaysWarn))-before, 0)
+		require.InDelta(t, 1, testutil.ToFloat64(auctionBiddingRelays.WithLabelValues("2"))-beforeCount, 0)
+	})
+
+	t.Run("Strict responds without a bid", func(t *testing.T) {
+		backend := newBackend(t, MinBiddingRelaysStrict, true)
+		before := testutil.ToFloat64(lowConfidenceAuctions.WithLabelValues(MinBiddingRelaysStrict))
+		rr := backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, http.StatusNoContent, rr.Code, rr.Body.String())
+		require.InDelta(t, 1, testutil.ToFloat64(lowConfidenceAuctions.WithLabelValues(MinBiddingRelaysStrict))-before, 0)
+		// The bid is not remembered for getPayload
+		require.Empty(t, backend.boost.bids.slotBids(1))
+	})
+}
+
+func TestMinBiddingRelaysConfig(t *testing.T) {
+	opts := BoostServiceOpts{
+		Log:                   mock.TestLog,
+		ListenAddr:            "localhost:12345",
+		Relays:                []types.RelayEntry{mock.NewRelay(t).RelayEntry},
+		GenesisForkVersionHex: "0x00000000",
+	}
+	opts.MinBiddingRelays = 1
+	_, err := NewBoostService(opts)
+	require.NoError(t, err)
+
+	opts.MinBiddingRelays = 2
+	_, err = NewBoostService(opts)
+	require.ErrorIs(t, err, errInvalidMinBiddingRelays)
+
+	opts.MinBiddingRelays = 1
+	opts.MinBiddingRelaysPolicy = "reject"
+	_, err = NewBoostService(opts)
+	require.ErrorIs(t, err, errInvalidMinBiddingRelaysPolicy)
+}
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"cmp"
+	"slices"
+
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultBlockNumberSpread is the default spread of the block numbers of the bids of an auction above which
+// relays disagree
+const DefaultBlockNumberSpread = 1
+
+var relayBlockNumberDisagreements = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "relay_blocknumber_disagreement_total",
+	Help: "Number of auctions in which the bids of the relay had another block number than most relays",
+}, []string{"relay"})
+
+// relayBlockNumber is the block number of the bid of a relay in an auction
+type relayBlockNumber struct {
+	relay       string
+	blockNumber uint64
+}
+
+// blockNumberOutliers returns the block number of most bids, and the bids with another block number if the
+// spread of the block numbers is above the maximum. The median block number wins ties.
+func blockNumberOutliers(bids []relayBlockNumber, maxSpread uint64) (uint64, []relayBlockNumber) {
+	if len(bids) < 2 {
+		return 0, nil
+	}
+	numbers := make([]uint64, 0, len(bids))
+	for _, bid := range bids {
+		numbers = append(numbers, bid.blockNumber)
+	}
+	slices.Sort(numbers)
+	if numbers[len(numbers)-1]-numbers[0] <= maxSpread {
+		return 0, nil
+	}
+
+	reference := numbers[(len(numbers)-1)/2]
+	count := 0
+	for _, n := range numbers {
+		if n == reference {
+			count++
+		}
+	}
+	for i := 0; i < len(numbers); {
+		j := i
+		for j < len(numbers) && numbers[j] == numbers[i] {
+			j++
+		}
+		if j-i > count {
+			reference, count = numbers[i], j-i
+		}
+		i = j
+	}
+
+	var outliers []relayBlockNumber
+	for _, bid := range bids {
+		if bid.blockNumber != reference {
+			outliers = append(outliers, bid)
+		}
+	}
+	slices.SortFunc(outliers, func(a, b relayBlockNumber) int {
+		return cmp.Compare(a.relay, b.relay)
+	})
+	return reference, outliers
+}
+
+// checkBlockNumberAgreement warns about the relays whose bids were built on another block number than the bids of
+// most relays, which hints at a relay out of sync or at a fork, and flags them in the bid arrivals of the slot
+func (m *BoostService) checkBlockNumberAgreement(log *logrus.Entry, slot phase0.Slot, bids []relayBlockNumber) {
+	maxSpread := m.blockNumberSpread
+	if maxSpread == 0 {
+		maxSpread = DefaultBlockNumberSpread
+	}
+	reference, outliers := blockNumberOutliers(bids, maxSpread)
+	if len(outliers) == 0 {
+		return
+	}
+
+	blockNumbers := make(logrus.Fields, len(bids))
+	for _, bid := range bids {
+		blockNumbers[bid.relay] = bid.blockNumber
+	}
+	labels := make([]string, 0, len(outliers))
+	for _, outlier := range outliers {
+		relayBlockNumberDisagreements.WithLabelValues(outlier.relay).Inc()
+		labels = append(labels, outlier.relay)
+	}
+	m.bidArrivals.flagBlockNumberOutliers(slot, labels)
+	log.WithFields(logrus.Fields{
+		"blockNumber":       reference,
+		"relayBlockNumbers": blockNumbers,
+		"outlierRelays":     labels,
+	}).Warn("relays disagree on the block number of their bids, an outlier relay may be out of sync or on a fork")
+}
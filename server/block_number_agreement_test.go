@@ -0,0 +1,105 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/attestantio/go-eth2-client/spec"
+	"github.com/flashbots/mev-boost/server/mock"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlockNumberOutliers(t *testing.T) {
+	bids := func(numbers ...uint64) []relayBlockNumber {
+		bids := make([]relayBlockNumber, 0, len(numbers))
+		for i, n := range numbers {
+			bids = append(bids, relayBlockNumber{relay: string(rune('a' + i)), blockNumber: n})
+		}
+		return bids
+	}
+	testCases := []struct {
+		name      string
+		bids      []relayBlockNumber
+		reference uint64
+		outliers  []string
+	}{
+		{name: "single bid", bids: bids(100)},
+		{name: "consistent", bids: bids(100, 100, 100)},
+		{name: "within spread", bids: bids(100, 101, 100)},
+		{name: "one outlier", bids: bids(100, 95, 100), reference: 100, outliers: []string{"b"}},
+		{name: "tie takes the lower median", bids: bids(103, 100), reference: 100, outliers: []string{"a"}},
+		{name: "no majority takes the median", bids: bids(102, 100, 101), reference: 101, outliers: []string{"a", "b"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			reference, outliers := blockNumberOutliers(tc.bids, DefaultBlockNumberSpread)
+			require.Equal(t, tc.reference, reference)
+			var relays []string
+			for _, outlier := range outliers {
+				relays = append(relays, outlier.relay)
+			}
+			require.Equal(t, tc.outliers, relays)
+		})
+	}
+}
+
+func TestGetHeaderBlockNumberDisagreement(t *testing.T) {
+	parentHash := mock.HexToHash("0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7")
+	path := getHeaderPath(1, parentHash, mock.HexToPubkey(feeRecipientTestPubkey))
+
+	newBackend := func(t *testing.T, blockNumbers ...uint64) *testBackend {
+		t.Helper()
+		backend := newTestBackend(t, len(blockNumbers), time.Second)
+		// The block numbers are changed after the bids are signed
+		backend.boost.validationLevel = ValidationLevelNone
+		for i, relay := range backend.relays {
+			resp := relay.MakeGetHeaderResponse(12345+uint64(i), parentHash.String(), parentHash.String(), feeRecipientTestPubkey, spec.DataVersionDeneb)
+			resp.Deneb.Message.Header.BlockNumber = blockNumbers[i]
+			relay.GetHeaderResponse = resp
+		}
+		return backend
+	}
+
+	t.Run("Consistent", func(t *testing.T) {
+		backend := newBackend(t, 100, 100, 101)
+		before := make([]float64, len(backend.relays))
+		for i, relay := range backend.relays {
+			before[i] = testutil.ToFloat64(relayBlockNumberDisagreements.WithLabelValues(relayLabel(relay.RelayEntry)))
+		}
+
+		rr := backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+		for i, relay := range backend.relays {
+			require.InDelta(t, before[i], testutil.ToFloat64(relayBlockNumberDisagreements.WithLabelValues(relayLabel(relay.RelayEntry))), 0)
+		}
+		arrivals, ok := backend.boost.bidArrivals.get(1)
+		require.True(t, ok)
+		for _, arrival := range arrivals.Arrivals {
+			require.False(t, arrival.BlockNumberOutlier, arrival.Relay)
+		}
+	})
+
+	t.Run("Outlier", func(t *testing.T) {
+		backend := newBackend(t, 100, 100, 90)
+		outlier := relayLabel(backend.relays[2].RelayEntry)
+		before := testutil.ToFloat64(relayBlockNumberDisagreements.WithLabelValues(outlier))
+
+		rr := backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+		// The bid of the outlier is still a candidate, it is only reported
+		require.InDelta(t, 1, testutil.ToFloat64(relayBlockNumberDisagreements.WithLabelValues(outlier))-before, 0)
+		arrivals, ok := backend.boost.bidArrivals.get(1)
+		require.True(t, ok)
+		require.Len(t, arrivals.Arrivals, 3)
+		for _, arrival := range arrivals.Arrivals {
+			require.Equal(t, arrival.Relay == outlier, arrival.BlockNumberOutlier, arrival.Relay)
+			if arrival.Relay == outlier {
+				require.Equal(t, uint64(90), arrival.BlockNumber)
+			}
+		}
+	})
+}
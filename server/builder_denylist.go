@@ -0,0 +1,83 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+)
+
+// builderDenylist is the set of builder pubkeys whose bids are ignored, it can be changed at runtime
+// with the admin endpoint
+type builderDenylist struct {
+	mu      sync.RWMutex
+	pubkeys map[phase0.BLSPubKey]bool
+}
+
+func newBuilderDenylist(pubkeys []phase0.BLSPubKey) *builderDenylist {
+	d := &builderDenylist{pubkeys: make(map[phase0.BLSPubKey]bool, len(pubkeys))}
+	d.add(pubkeys)
+	return d
+}
+
+// denied returns whether bids of the builder are ignored
+func (d *builderDenylist) denied(pubkey phase0.BLSPubKey) bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.pubkeys[pubkey]
+}
+
+func (d *builderDenylist) add(pubkeys []phase0.BLSPubKey) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, pubkey := range pubkeys {
+		d.pubkeys[pubkey] = true
+	}
+}
+
+func (d *builderDenylist) remove(pubkeys []phase0.BLSPubKey) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, pubkey := range pubkeys {
+		delete(d.pubkeys, pubkey)
+	}
+}
+
+// list returns the denied builder pubkeys, sorted
+func (d *builderDenylist) list() []phase0.BLSPubKey {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	pubkeys := make([]phase0.BLSPubKey, 0, len(d.pubkeys))
+	for pubkey := range d.pubkeys {
+		pubkeys = append(pubkeys, pubkey)
+	}
+	slices.SortFunc(pubkeys, func(a, b phase0.BLSPubKey) int {
+		return strings.Compare(a.String(), b.String())
+	})
+	return pubkeys
+}
+
+// handleAdminBuilderDenylist returns the builder denylist. POST adds the pubkeys of the JSON list in the request body
+// to the denylist, DELETE removes them.
+func (m *BoostService) handleAdminBuilderDenylist(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		var pubkeys []phase0.BLSPubKey
+		if err := json.NewDecoder(req.Body).Decode(&pubkeys); err != nil {
+			m.respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		log := m.log.WithField("builderPubkeys", pubkeys)
+		if req.Method == http.MethodPost {
+			m.builderDenylist.add(pubkeys)
+			log.Warn("added builders to the denylist")
+		} else {
+			m.builderDenylist.remove(pubkeys)
+			log.Warn("removed builders from the denylist")
+		}
+	}
+	m.respondOK(w, m.builderDenylist.list())
+}
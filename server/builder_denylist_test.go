@@ -0,0 +1,82 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/flashbots/mev-boost/server/mock"
+	"github.com/flashbots/mev-boost/server/params"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetHeaderBuilderDenylist(t *testing.T) {
+	hash := mock.HexToHash("0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7")
+	pubkey := mock.HexToPubkey(
+		"0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249")
+	path := getHeaderPath(1, hash, pubkey)
+
+	t.Run("Bid of another builder", func(t *testing.T) {
+		backend := newTestBackend(t, 1, time.Second)
+		backend.boost.builderDenylist = newBuilderDenylist([]phase0.BLSPubKey{{0xb1, 0x9c, 0x3f}})
+		rr := backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	})
+
+	t.Run("Bid of a denied builder", func(t *testing.T) {
+		backend := newTestBackend(t, 1, time.Second)
+		backend.boost.builderDenylist = newBuilderDenylist([]phase0.BLSPubKey{backend.relays[0].RelayEntry.PublicKey})
+		label := relayLabel(backend.relays[0].RelayEntry)
+		rr := backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, http.StatusNoContent, rr.Code, rr.Body.String())
+		require.InDelta(t, 1, testutil.ToFloat64(relayBidsRejected.WithLabelValues(label, "builder_denied")), 0)
+	})
+
+	t.Run("Denylist takes precedence over the allowlist", func(t *testing.T) {
+		backend := newTestBackend(t, 1, time.Second)
+		builder := backend.relays[0].RelayEntry.PublicKey
+		backend.boost.builderAllowlist = map[phase0.BLSPubKey]bool{builder: true}
+		backend.boost.builderDenylist = newBuilderDenylist([]phase0.BLSPubKey{builder})
+		rr := backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, http.StatusNoContent, rr.Code, rr.Body.String())
+	})
+}
+
+func TestAdminBuilderDenylist(t *testing.T) {
+	hash := mock.HexToHash("0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7")
+	pubkey := mock.HexToPubkey(
+		"0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249")
+	path := getHeaderPath(1, hash, pubkey)
+
+	backend := newTestBackend(t, 1, time.Second)
+	builder := backend.relays[0].RelayEntry.PublicKey
+
+	rr := backend.request(t, http.MethodGet, params.PathAdminBuilderDenylist, nil)
+	require.Equal(t, http.StatusNotFound, rr.Code, "admin endpoints are disabled by default")
+
+	backend.boost.adminEndpoints = true
+	denylist := func(rr *httptest.ResponseRecorder) []phase0.BLSPubKey {
+		t.Helper()
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		var pubkeys []phase0.BLSPubKey
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &pubkeys))
+		return pubkeys
+	}
+
+	rr = backend.request(t, http.MethodPost, params.PathAdminBuilderDenylist, []phase0.BLSPubKey{builder})
+	require.Equal(t, []phase0.BLSPubKey{builder}, denylist(rr))
+	rr = backend.request(t, http.MethodGet, path, nil)
+	require.Equal(t, http.StatusNoContent, rr.Code)
+
+	rr = backend.request(t, http.MethodDelete, params.PathAdminBuilderDenylist, []phase0.BLSPubKey{builder})
+	require.Empty(t, denylist(rr))
+	rr = backend.request(t, http.MethodGet, path, nil)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	rr = backend.request(t, http.MethodPost, params.PathAdminBuilderDenylist, json.RawMessage(`["0x1234"]`))
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+}
@@ -0,0 +1,274 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/flashbots/mev-boost/server/params"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	errChaosOnMainnet     = errors.New("refusing to inject chaos on mainnet")
+	errChaosInjected      = errors.New("chaos: injected relay error")
+	errInvalidChaosConfig = errors.New("invalid chaos config")
+	errChaosDelayDisabled = errors.New("chaos response delay is configured, but not enabled")
+	errInvalidChaosDelay  = errors.New("chaos response delay cannot be negative")
+)
+
+// mainnetGenesisForkVersion is the genesis fork version of mainnet, on which chaos is refused by default
+const mainnetGenesisForkVersion = "0x00000000"
+
+// chaosReloadInterval is how often the chaos config file is checked for changes
+const chaosReloadInterval = 5 * time.Second
+
+// chaosAllRelays is the relay key of faults for relays without their own entry
+const chaosAllRelays = "*"
+
+var chaosFaultsInjected = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "chaos_faults_injected_total",
+	Help: "Number of faults injected into relay requests by the chaos config",
+}, []string{"relay", "fault"})
+
+// chaosFaults are the faults injected into the requests to a relay
+type chaosFaults struct {
+	// LatencyMs delays each request
+	LatencyMs int `json:"latency_ms"`
+	// ErrorRate is the share of requests, between 0 and 1, which fail without reaching the relay
+	ErrorRate float64 `json:"error_rate"`
+	// BidValuePct changes the value of getHeader bids by this percentage. The relay signature does not cover
+	// the changed value, so this only results in usable bids with relay signature checks disabled.
+	BidValuePct float64 `json:"bid_value_pct"`
+	// WithholdPayload makes getPayload requests fail as if the relay withheld the payload
+	WithholdPayload bool `json:"withhold_payload"`
+}
+
+// chaosConfig holds the faults to inject per relay host, as configured in a JSON file:
+//
+//	{"relays": {"relay.example.com": {"latency_ms": 200, "error_rate": 0.1}, "*": {"withhold_payload": true}}}
+//
+// The file is reloaded when it changes.
+type chaosConfig struct {
+	path string
+
+	mu      sync.RWMutex
+	modTime time.Time
+	relays  map[string]chaosFaults
+}
+
+// newChaosConfig loads the chaos config file
+func newChaosConfig(path string) (*chaosConfig, error) {
+	c := &chaosConfig{path: path}
+	if _, err := c.reload(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// reload reads the chaos config file again if it was modified, and returns whether it was reloaded.
+// On error the previous config is kept.
+func (c *chaosConfig) reload() (bool, error) {
+	info, err := os.Stat(c.path)
+	if err != nil {
+		return false, err
+	}
+	c.mu.RLock()
+	unchanged := info.ModTime().Equal(c.modTime)
+	c.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		return false, err
+	}
+	var file struct {
+		Relays map[string]chaosFaults `json:"relays"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return false, fmt.Errorf("could not parse chaos config: %w", err)
+	}
+	for relay, faults := range file.Relays {
+		if faults.ErrorRate < 0 || faults.ErrorRate > 1 || faults.LatencyMs < 0 || faults.BidValuePct <= -100 {
+			return false, fmt.Errorf("%w: relay %s", errInvalidChaosConfig, relay)
+		}
+	}
+
+	c.mu.Lock()
+	c.modTime = info.ModTime()
+	c.relays = file.Relays
+	c.mu.Unlock()
+	return true, nil
+}
+
+// faults returns the faults to inject into requests to the relay host
+func (c *chaosConfig) faults(host string) (chaosFaults, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if faults, ok := c.relays[host]; ok {
+		return faults, true
+	}
+	faults, ok := c.relays[chaosAllRelays]
+	return faults, ok
+}
+
+// watchChaosConfig reloads the chaos config file when it changes
+func (m *BoostService) watchChaosConfig() {
+	for {
+		time.Sleep(chaosReloadInterval)
+		reloaded, err := m.chaos.reload()
+		if err != nil {
+			m.log.WithError(err).Error("CHAOS: could not reload chaos config, keeping the previous config")
+		} else if reloaded {
+			m.log.WithField("path", m.chaos.path).Warn("CHAOS: reloaded chaos config")
+		}
+	}
+}
+
+// chaosTransport injects the faults of the chaos config into relay requests
+type chaosTransport struct {
+	next  http.RoundTripper
+	chaos *chaosConfig
+	log   *logrus.Entry
+}
+
+// CloseIdleConnections closes the idle connections of the wrapped transport
+func (t *chaosTransport) CloseIdleConnections() {
+	if closer, ok := t.next.(interface{ CloseIdleConnections() }); ok {
+		closer.CloseIdleConnections()
+	}
+}
+
+func (t *chaosTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	faults, ok := t.chaos.faults(req.URL.Host)
+	if !ok {
+		return t.next.RoundTrip(req)
+	}
+	relay := req.URL.Host
+	log := t.log.WithFields(logrus.Fields{"relay": relay, "path": req.URL.Path})
+	inject := func(fault string) {
+		chaosFaultsInjected.WithLabelValues(relay, fault).Inc()
+		log.Warnf("CHAOS: injecting %s", fault)
+	}
+
+	if faults.LatencyMs > 0 {
+		inject("latency")
+		select {
+		case <-time.After(time.Duration(faults.LatencyMs) * time.Millisecond):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+
+	if faults.ErrorRate > 0 && rand.Float64() < faults.ErrorRate {
+		inject("error")
+		return nil, errChaosInjected
+	}
+
+	if faults.WithholdPayload && req.Method == http.MethodPost && strings.HasSuffix(req.URL.Path, params.PathGetPayload) {
+		inject("withholding")
+		body := `{"code":500,"message":"chaos: payload withheld"}`
+		return &http.Response{
+			StatusCode:    http.StatusInternalServerError,
+			Header:        http.Header{"Content-Type": []string{"application/json"}},
+			Body:          io.NopCloser(strings.NewReader(body)),
+			ContentLength: int64(len(body)),
+			Request:       req,
+		}, nil
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil || faults.BidValuePct == 0 || resp.StatusCode != http.StatusOK || !strings.Contains(req.URL.Path, params.PathPrefixGetHeader) {
+		return resp, err
+	}
+	inject("bid value change")
+	return changeBidValue(resp, faults.BidValuePct)
+}
+
+// changeBidValue changes the value of the getHeader bid in the response by the percentage
+func changeBidValue(resp *http.Response, pct float64) (*http.Response, error) {
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	var bid struct {
+		Version string `json:"version"`
+		Data    struct {
+			Message   map[string]json.RawMessage `json:"message"`
+			Signature json.RawMessage            `json:"signature"`
+		} `json:"data"`
+	}
+	var value string
+	if json.Unmarshal(body, &bid) == nil && json.Unmarshal(bid.Data.Message["value"], &value) == nil {
+		if wei, ok := new(big.Int).SetString(value, 10); ok {
+			changed, _ := new(big.Float).Mul(new(big.Float).SetInt(wei), big.NewFloat(1+pct/100)).Int(nil)
+			bid.Data.Message["value"], _ = json.Marshal(changed.String())
+			if changedBody, err := json.Marshal(bid); err == nil {
+				body = changedBody
+			}
+		}
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	resp.ContentLength = int64(len(body))
+	return resp, nil
+}
+
+// chaosDelay delays the response of the handler to the beacon node by the chaos delay, to test the fallback
+// timing of beacon nodes. The handler runs without delay, the response is held back when it is written.
+func (m *BoostService) chaosDelay(next http.HandlerFunc) http.HandlerFunc {
+	if m.chaosResponseDelay <= 0 {
+		return next
+	}
+	return func(w http.ResponseWriter, req *http.Request) {
+		next(&chaosDelayWriter{ResponseWriter: w, req: req, delay: m.chaosResponseDelay, log: m.log}, req)
+	}
+}
+
+// chaosDelayWriter sleeps before the response is written
+type chaosDelayWriter struct {
+	http.ResponseWriter
+	req   *http.Request
+	delay time.Duration
+	log   *logrus.Entry
+	once  sync.Once
+}
+
+func (w *chaosDelayWriter) wait() {
+	w.once.Do(func() {
+		w.log.WithFields(logrus.Fields{"path": w.req.URL.Path, "delay": w.delay.String()}).Warn("CHAOS: delaying response")
+		select {
+		case <-time.After(w.delay):
+		case <-w.req.Context().Done():
+		}
+	})
+}
+
+func (w *chaosDelayWriter) WriteHeader(code int) {
+	w.wait()
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *chaosDelayWriter) Write(b []byte) (int, error) {
+	w.wait()
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (w *chaosDelayWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
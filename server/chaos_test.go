@@ -0,0 +1,146 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	builderSpec "github.com/attestantio/go-builder-client/spec"
+	"github.com/flashbots/mev-boost/server/mock"
+	"github.com/flashbots/mev-boost/server/params"
+	"github.com/flashbots/mev-boost/server/types"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// writeChaosConfig writes the faults for the relay to a chaos config file, and returns its path
+func writeChaosConfig(t *testing.T, path, relay string, faults chaosFaults) string {
+	t.Helper()
+	if path == "" {
+		path = filepath.Join(t.TempDir(), "chaos.json")
+	}
+	data, err := json.Marshal(map[string]any{"relays": map[string]chaosFaults{relay: faults}})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+	return path
+}
+
+// newChaosBackend returns a test backend injecting the faults into the requests to its relay
+func newChaosBackend(t *testing.T, faults chaosFaults) (*testBackend, string) {
+	t.Helper()
+	backend := newTestBackend(t, 1, 500*time.Millisecond)
+	relay := relayLabel(backend.relays[0].RelayEntry)
+	chaos, err := newChaosConfig(writeChaosConfig(t, "", relay, faults))
+	require.NoError(t, err)
+	backend.boost.chaos = chaos
+	transport := &chaosTransport{next: http.DefaultTransport, chaos: chaos, log: mock.TestLog}
+	backend.boost.httpClientGetHeader.Transport = transport
+	backend.boost.httpClientGetPayload.Transport = transport
+	return backend, relay
+}
+
+func TestChaos(t *testing.T) {
+	hash := mock.HexToHash("0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7")
+	pubkey := mock.HexToPubkey(
+		"0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249")
+	path := getHeaderPath(1, hash, pubkey)
+
+	t.Run("Refused on mainnet", func(t *testing.T) {
+		opts := BoostServiceOpts{
+			Log:                   mock.TestLog,
+			Relays:                []types.RelayEntry{mock.NewRelay(t).RelayEntry},
+			GenesisForkVersionHex: mainnetGenesisForkVersion,
+			ChaosConfig:           writeChaosConfig(t, "", chaosAllRelays, chaosFaults{LatencyMs: 10}),
+		}
+		_, err := NewBoostService(opts)
+		require.ErrorIs(t, err, errChaosOnMainnet)
+
+		opts.ChaosAllowMainnet = true
+		_, err = NewBoostService(opts)
+		require.NoError(t, err)
+	})
+
+	t.Run("Latency", func(t *testing.T) {
+		backend, relay := newChaosBackend(t, chaosFaults{LatencyMs: 100})
+		start := time.Now()
+		rr := backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		require.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+		require.InDelta(t, 1, testutil.ToFloat64(chaosFaultsInjected.WithLabelValues(relay, "latency")), 0)
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		backend, relay := newChaosBackend(t, chaosFaults{ErrorRate: 1})
+		rr := backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, http.StatusNoContent, rr.Code, rr.Body.String())
+		require.Equal(t, 0, backend.relays[0].GetRequestCount(path))
+		require.InDelta(t, 1, testutil.ToFloat64(chaosFaultsInjected.WithLabelValues(relay, "error")), 0)
+	})
+
+	t.Run("Bid value change", func(t *testing.T) {
+		backend, _ := newChaosBackend(t, chaosFaults{BidValuePct: 50})
+		backend.boost.validationLevel = ValidationLevelNone
+		rr := backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+		bid := new(builderSpec.VersionedSignedBuilderBid)
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), bid))
+		value, err := bid.Value()
+		require.NoError(t, err)
+		require.Equal(t, uint64(18517), value.Uint64(), "1.5 times the mock relay bid value 12345")
+	})
+
+	t.Run("Payload withholding", func(t *testing.T) {
+		block, response := loadDenebBlock(t)
+		backend, relay := newChaosBackend(t, chaosFaults{WithholdPayload: true})
+		backend.relays[0].GetPayloadResponse = response
+		rr := backend.request(t, http.MethodPost, params.PathGetPayload, block)
+		require.Equal(t, http.StatusBadGateway, rr.Code, rr.Body.String())
+		require.Positive(t, testutil.ToFloat64(chaosFaultsInjected.WithLabelValues(relay, "withholding")))
+	})
+
+	t.Run("Reload", func(t *testing.T) {
+		backend, relay := newChaosBackend(t, chaosFaults{ErrorRate: 1})
+		writeChaosConfig(t, backend.boost.chaos.path, relay, chaosFaults{})
+		// Make sure the modification time changes on file systems with a coarse resolution
+		require.NoError(t, os.Chtimes(backend.boost.chaos.path, time.Now(), time.Now().Add(time.Second)))
+		reloaded, err := backend.boost.chaos.reload()
+		require.NoError(t, err)
+		require.True(t, reloaded)
+
+		rr := backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	})
+
+	t.Run("Response delay", func(t *testing.T) {
+		opts := BoostServiceOpts{
+			Log:                   mock.TestLog,
+			Relays:                []types.RelayEntry{mock.NewRelay(t).RelayEntry},
+			GenesisForkVersionHex: "0x01017000",
+			ChaosDelay:            100 * time.Millisecond,
+		}
+		_, err := NewBoostService(opts)
+		require.ErrorIs(t, err, errChaosDelayDisabled)
+
+		opts.ChaosDelayEnabled = true
+		opts.GenesisForkVersionHex = mainnetGenesisForkVersion
+		_, err = NewBoostService(opts)
+		require.ErrorIs(t, err, errChaosOnMainnet)
+
+		backend := newTestBackend(t, 1, time.Second)
+		backend.boost.chaosResponseDelay = 100 * time.Millisecond
+		start := time.Now()
+		rr := backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		require.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+		require.Equal(t, 1, backend.relays[0].GetRequestCount(path))
+	})
+
+	t.Run("Invalid config", func(t *testing.T) {
+		_, err := newChaosConfig(writeChaosConfig(t, "", chaosAllRelays, chaosFaults{ErrorRate: 2}))
+		require.ErrorIs(t, err, errInvalidChaosConfig)
+	})
+}
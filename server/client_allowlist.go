@@ -0,0 +1,154 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	errInvalidClientCIDR = errors.New("invalid client CIDR or IP address")
+	errClientNotAllowed  = errors.New("client not allowed")
+
+	clientRequestsRejected = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "client_requests_rejected_total",
+		Help: "Number of registerValidator, getHeader and getPayload requests rejected because the client address is not allowed",
+	}, []string{"method"})
+)
+
+// parseCIDRs returns the prefixes of a list of CIDRs and IP addresses, ignoring empty entries. An IP address is
+// the prefix of this single address.
+func parseCIDRs(entries []string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			addr, err := netip.ParseAddr(entry)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s", errInvalidClientCIDR, entry)
+			}
+			addr = addr.Unmap()
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", errInvalidClientCIDR, entry)
+		}
+		if prefix.Addr().Is4In6() {
+			// ::ffff:10.0.0.0/104 is 10.0.0.0/8, as client addresses are unmapped too
+			prefix = netip.PrefixFrom(prefix.Addr().Unmap(), max(prefix.Bits()-96, 0))
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// containsAddr returns whether one of the prefixes contains the address
+func containsAddr(prefixes []netip.Prefix, addr netip.Addr) bool {
+	return slices.ContainsFunc(prefixes, func(prefix netip.Prefix) bool {
+		return prefix.Contains(addr)
+	})
+}
+
+// clientAllowlist restricts the proposer endpoints to clients in the allowed prefixes. The client of a request
+// from a trusted proxy is taken from the X-Forwarded-For header. All methods work on a nil value, which allows
+// every client and ignores X-Forwarded-For.
+type clientAllowlist struct {
+	allowed        []netip.Prefix
+	trustedProxies []netip.Prefix
+}
+
+// newClientAllowlist returns the allowlist of the allowed clients, which allows every client if empty
+func newClientAllowlist(allowedCIDRs, trustedProxies []string) (*clientAllowlist, error) {
+	allowed, err := parseCIDRs(allowedCIDRs)
+	if err != nil {
+		return nil, err
+	}
+	proxies, err := parseCIDRs(trustedProxies)
+	if err != nil {
+		return nil, err
+	}
+	return &clientAllowlist{allowed: allowed, trustedProxies: proxies}, nil
+}
+
+// clientAddr returns the address of the client of the request. Behind trusted proxies, it is the rightmost address
+// of the X-Forwarded-For header which is not a trusted proxy, as the addresses on its left may be spoofed by the
+// client. It returns false if the address cannot be determined.
+func (c *clientAllowlist) clientAddr(req *http.Request) (netip.Addr, bool) {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		host = req.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	addr = addr.Unmap().WithZone("")
+	if c == nil || !containsAddr(c.trustedProxies, addr) {
+		return addr, true
+	}
+
+	var hops []string
+	for _, header := range req.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			return netip.Addr{}, false
+		}
+		addr = hop.Unmap().WithZone("")
+		if !containsAddr(c.trustedProxies, addr) {
+			return addr, true
+		}
+	}
+	// Every hop is a trusted proxy, the leftmost one is the client
+	return addr, true
+}
+
+// allows returns whether the address is allowed to call the proposer endpoints
+func (c *clientAllowlist) allows(addr netip.Addr) bool {
+	return c == nil || len(c.allowed) == 0 || containsAddr(c.allowed, addr)
+}
+
+// clientLogField returns the address of the client of the request for logs
+func (c *clientAllowlist) clientLogField(req *http.Request) string {
+	if addr, ok := c.clientAddr(req); ok {
+		return addr.String()
+	}
+	return req.RemoteAddr
+}
+
+// restrictClients rejects requests of clients which are not in the allowed CIDRs with 403
+func (m *BoostService) restrictClients(method string, next http.HandlerFunc) http.HandlerFunc {
+	if m.clientAllowlist == nil || len(m.clientAllowlist.allowed) == 0 {
+		return next
+	}
+	return func(w http.ResponseWriter, req *http.Request) {
+		addr, ok := m.clientAllowlist.clientAddr(req)
+		if !ok || !m.clientAllowlist.allows(addr) {
+			clientRequestsRejected.WithLabelValues(method).Inc()
+			m.log.WithFields(logrus.Fields{
+				"method":        method,
+				"clientIP":      m.clientAllowlist.clientLogField(req),
+				"remoteAddr":    req.RemoteAddr,
+				"xForwardedFor": strings.Join(req.Header.Values("X-Forwarded-For"), ", "),
+				"ua":            req.Header.Get("User-Agent"),
+			}).Warn("rejecting request of a client which is not allowed")
+			m.respondError(w, http.StatusForbidden, errClientNotAllowed.Error())
+			return
+		}
+		next(w, req)
+	}
+}
@@ -0,0 +1,136 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/flashbots/mev-boost/server/params"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCIDRs(t *testing.T) {
+	testCases := []struct {
+		entries  []string
+		expected []string
+		err      bool
+	}{
+		{entries: nil, expected: nil},
+		{entries: []string{"", " "}, expected: nil},
+		{entries: []string{"10.0.0.0/8", " 192.168.1.7 "}, expected: []string{"10.0.0.0/8", "192.168.1.7/32"}},
+		{entries: []string{"10.1.2.3/8"}, expected: []string{"10.0.0.0/8"}},
+		{entries: []string{"::1", "fd00::/8"}, expected: []string{"::1/128", "fd00::/8"}},
+		{entries: []string{"::ffff:10.0.0.1", "::ffff:10.0.0.0/104"}, expected: []string{"10.0.0.1/32", "10.0.0.0/8"}},
+		{entries: []string{"10.0.0.0/33"}, err: true},
+		{entries: []string{"localhost"}, err: true},
+		{entries: []string{"10.0.0.0/8", "10.0.0"}, err: true},
+	}
+	for _, tc := range testCases {
+		prefixes, err := parseCIDRs(tc.entries)
+		if tc.err {
+			require.ErrorIs(t, err, errInvalidClientCIDR, tc.entries)
+			continue
+		}
+		require.NoError(t, err, tc.entries)
+		var actual []string
+		for _, prefix := range prefixes {
+			actual = append(actual, prefix.String())
+		}
+		require.Equal(t, tc.expected, actual, tc.entries)
+	}
+}
+
+func TestClientAddr(t *testing.T) {
+	clients, err := newClientAllowlist(nil, []string{"127.0.0.1", "10.0.0.0/8"})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name          string
+		remoteAddr    string
+		xForwardedFor []string
+		expected      string
+	}{
+		{name: "Direct client", remoteAddr: "192.168.1.7:4000", expected: "192.168.1.7"},
+		{name: "IPv4-mapped client", remoteAddr: "[::ffff:192.168.1.7]:4000", expected: "192.168.1.7"},
+		{name: "Untrusted peer spoofing X-Forwarded-For", remoteAddr: "192.168.1.7:4000", xForwardedFor: []string{"10.0.0.1"}, expected: "192.168.1.7"},
+		{name: "Trusted proxy", remoteAddr: "127.0.0.1:4000", xForwardedFor: []string{"192.168.1.7"}, expected: "192.168.1.7"},
+		{name: "Trusted proxy without X-Forwarded-For", remoteAddr: "127.0.0.1:4000", expected: "127.0.0.1"},
+		{name: "Chain of trusted proxies", remoteAddr: "127.0.0.1:4000", xForwardedFor: []string{"192.168.1.7, 10.0.0.2"}, expected: "192.168.1.7"},
+		{name: "Client spoofing the leftmost hop", remoteAddr: "127.0.0.1:4000", xForwardedFor: []string{"10.0.0.9, 192.168.1.7"}, expected: "192.168.1.7"},
+		{name: "Several X-Forwarded-For headers", remoteAddr: "127.0.0.1:4000", xForwardedFor: []string{"172.16.0.1", "192.168.1.7"}, expected: "192.168.1.7"},
+		{name: "Only trusted hops", remoteAddr: "127.0.0.1:4000", xForwardedFor: []string{"10.0.0.2, 10.0.0.3"}, expected: "10.0.0.2"},
+		{name: "Invalid hop", remoteAddr: "127.0.0.1:4000", xForwardedFor: []string{"192.168.1.7, unknown"}, expected: ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, params.PathStatus, nil)
+			req.RemoteAddr = tc.remoteAddr
+			for _, value := range tc.xForwardedFor {
+				req.Header.Add("X-Forwarded-For", value)
+			}
+			addr, ok := clients.clientAddr(req)
+			if tc.expected == "" {
+				require.False(t, ok)
+				return
+			}
+			require.True(t, ok)
+			require.Equal(t, netip.MustParseAddr(tc.expected), addr)
+		})
+	}
+
+	t.Run("X-Forwarded-For is ignored without trusted proxies", func(t *testing.T) {
+		var clients *clientAllowlist
+		req := httptest.NewRequest(http.MethodGet, params.PathStatus, nil)
+		req.RemoteAddr = "127.0.0.1:4000"
+		req.Header.Set("X-Forwarded-For", "192.168.1.7")
+		addr, ok := clients.clientAddr(req)
+		require.True(t, ok)
+		require.Equal(t, netip.MustParseAddr("127.0.0.1"), addr)
+	})
+}
+
+func TestRestrictClients(t *testing.T) {
+	backend := newTestBackend(t, 1, time.Second)
+	var err error
+	backend.boost.clientAllowlist, err = newClientAllowlist([]string{"192.168.1.0/24"}, []string{"127.0.0.1"})
+	require.NoError(t, err)
+	router := backend.boost.getRouter()
+
+	request := func(method, path, remoteAddr, xForwardedFor string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.RemoteAddr = remoteAddr
+		if xForwardedFor != "" {
+			req.Header.Set("X-Forwarded-For", xForwardedFor)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	before := testutil.ToFloat64(clientRequestsRejected.WithLabelValues("registerValidator"))
+	rr := request(http.MethodPost, params.PathRegisterValidator, "172.16.0.1:4000", "")
+	require.Equal(t, http.StatusForbidden, rr.Code)
+	require.Contains(t, rr.Body.String(), errClientNotAllowed.Error())
+	require.InDelta(t, 1, testutil.ToFloat64(clientRequestsRejected.WithLabelValues("registerValidator"))-before, 0)
+
+	// X-Forwarded-For of an untrusted peer is ignored
+	rr = request(http.MethodPost, params.PathGetPayload, "172.16.0.1:4000", "192.168.1.7")
+	require.Equal(t, http.StatusForbidden, rr.Code)
+
+	// The client behind the trusted proxy is allowed, the request fails later because it has no body
+	rr = request(http.MethodPost, params.PathGetPayload, "127.0.0.1:4000", "192.168.1.7")
+	require.NotEqual(t, http.StatusForbidden, rr.Code)
+	rr = request(http.MethodPost, params.PathGetPayload, "127.0.0.1:4000", "172.16.0.1")
+	require.Equal(t, http.StatusForbidden, rr.Code)
+	rr = request(http.MethodGet, getHeaderPath(1, [32]byte{}, [48]byte{}), "172.16.0.1:4000", "")
+	require.Equal(t, http.StatusForbidden, rr.Code)
+
+	// The status and health endpoints stay open
+	rr = request(http.MethodGet, params.PathStatus, "172.16.0.1:4000", "")
+	require.Equal(t, http.StatusOK, rr.Code)
+	rr = request(http.MethodGet, params.PathHealthz, "172.16.0.1:4000", "")
+	require.Equal(t, http.StatusOK, rr.Code)
+}
@@ -0,0 +1,96 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// compatShim works around a quirk of a specific beacon client. Shims run before routing, and may
+// modify the request, or wrap the response writer.
+type compatShim struct {
+	name        string
+	client      string // client name as returned by clientFromUserAgent
+	issue       string // link to the upstream issue of the quirk
+	description string
+	apply       func(w http.ResponseWriter, req *http.Request) (http.ResponseWriter, bool)
+}
+
+// compatShims are the known beacon client workarounds. Every shim must link the upstream issue of
+// the client quirk it works around, none is known to be needed by a released client at the moment.
+var compatShims []compatShim
+
+// clientFromUserAgent returns the lowercase client name from a user agent, eg. "lighthouse" for "Lighthouse/v5.1.0-a1b2c3d"
+func clientFromUserAgent(ua string) string {
+	product, _, _ := strings.Cut(strings.TrimSpace(ua), " ")
+	name, _, _ := strings.Cut(product, "/")
+	return strings.ToLower(name)
+}
+
+// knownBeaconClients are the clients which get their own metric label
+var knownBeaconClients = map[string]bool{
+	"lighthouse": true,
+	"prysm":      true,
+	"teku":       true,
+	"lodestar":   true,
+	"nimbus":     true,
+	"grandine":   true,
+}
+
+// clientLabel returns the client name of a user agent for metric labels, "other" for unknown clients
+func clientLabel(ua string) string {
+	client := clientFromUserAgent(ua)
+	if !knownBeaconClients[client] {
+		return "other"
+	}
+	return client
+}
+
+// compatShimLog remembers which shims were already logged for which client
+type compatShimLog struct {
+	mu     sync.Mutex
+	logged map[string]bool
+}
+
+// firstUse returns true the first time it is called for a shim and client
+func (l *compatShimLog) firstUse(shim, client string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.logged == nil {
+		l.logged = make(map[string]bool)
+	}
+	key := shim + "/" + client
+	if l.logged[key] {
+		return false
+	}
+	l.logged[key] = true
+	return true
+}
+
+// compatMiddleware applies the shims matching the beacon client which sent the request, when enabled
+func (m *BoostService) compatMiddleware(next http.Handler) http.Handler {
+	if !m.compatShimsEnabled {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		client := clientFromUserAgent(req.Header.Get("User-Agent"))
+		for _, shim := range compatShims {
+			if shim.client != client {
+				continue
+			}
+			var applied bool
+			w, applied = shim.apply(w, req)
+			if applied && m.compatShimLog.firstUse(shim.name, client) {
+				m.log.WithFields(logrus.Fields{
+					"shim":   shim.name,
+					"client": client,
+					"issue":  shim.issue,
+					"ua":     req.Header.Get("User-Agent"),
+				}).Infof("applying beacon client compatibility shim: %s", shim.description)
+			}
+		}
+		next.ServeHTTP(w, req)
+	})
+}
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/flashbots/mev-boost/server/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientFromUserAgent(t *testing.T) {
+	testCases := map[string]string{
+		"Lighthouse/v5.1.0-a1b2c3d":     "lighthouse",
+		"Prysm/v5.0.3 (linux amd64)":    "prysm",
+		"teku/v24.2.0":                  "teku",
+		"Lodestar/v1.17.0/80c248b":      "lodestar",
+		"nimbus":                        "nimbus",
+		"":                              "",
+		"  Grandine/0.4.0 extra tokens": "grandine",
+	}
+	for ua, expected := range testCases {
+		require.Equal(t, expected, clientFromUserAgent(ua), ua)
+	}
+}
+
+func TestCompatShims(t *testing.T) {
+	hash := mock.HexToHash("0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7")
+	pubkey := mock.HexToPubkey(
+		"0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249")
+	path := getHeaderPath(1, hash, pubkey)
+
+	// A shim for a made up client, which tolerates a trailing slash on the getHeader path
+	shims := compatShims
+	compatShims = []compatShim{{
+		name:        "getheader-trailing-slash",
+		client:      "testclient",
+		issue:       "https://example.com/testclient/issues/1",
+		description: "tolerate a trailing slash on the getHeader path",
+		apply: func(w http.ResponseWriter, req *http.Request) (http.ResponseWriter, bool) {
+			if !strings.HasSuffix(req.URL.Path, "/") {
+				return w, false
+			}
+			req.URL.Path = strings.TrimRight(req.URL.Path, "/")
+			return w, true
+		},
+	}}
+	t.Cleanup(func() { compatShims = shims })
+
+	// request sends a request with the given user agent through the router
+	request := func(t *testing.T, backend *testBackend, ua, path string) *httptest.ResponseRecorder {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodGet, path, nil)
+		require.NoError(t, err)
+		req.Header.Set("User-Agent", ua)
+		rr := httptest.NewRecorder()
+		backend.boost.getRouter().ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("shims apply to their client", func(t *testing.T) {
+		backend := newTestBackend(t, 1, time.Second)
+		backend.boost.compatShimsEnabled = true
+		rr := request(t, backend, "TestClient/v1.0.0", path+"/")
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+		rr = request(t, backend, "Lighthouse/v5.1.0", path+"/")
+		require.Equal(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("shims are disabled by default", func(t *testing.T) {
+		backend := newTestBackend(t, 1, time.Second)
+		rr := request(t, backend, "TestClient/v1.0.0", path+"/")
+		require.Equal(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("shims are logged once per client", func(t *testing.T) {
+		var shimLog compatShimLog
+		require.True(t, shimLog.firstUse("getheader-trailing-slash", "lodestar"))
+		require.False(t, shimLog.firstUse("getheader-trailing-slash", "lodestar"))
+		require.True(t, shimLog.firstUse("json-content-type", "lodestar"))
+		require.True(t, shimLog.firstUse("getheader-trailing-slash", "teku"))
+	})
+
+	t.Run("shims link the issue of the quirk", func(t *testing.T) {
+		for _, shim := range shims {
+			require.NotEmpty(t, shim.issue, shim.name)
+		}
+	})
+}
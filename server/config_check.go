@@ -0,0 +1,198 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"strings"
+)
+
+var errListenAddrCollision = errors.New("listen address collides with another listener")
+
+// ValidateConfig checks the options the same way as NewBoostService, and also checks that the relay local
+// address and the listen addresses can be bound. It returns every problem found instead of only the first
+// one, and nil if there are none.
+func ValidateConfig(opts BoostServiceOpts) []error {
+	return validateConfig(opts, true)
+}
+
+// validateConfig returns the problems of the options. With bind, it also binds the relay local address
+// and the listen addresses, which NewBoostService leaves to the relay transport and the servers.
+func validateConfig(opts BoostServiceOpts, bind bool) []error {
+	var errs []error
+	check := func(err error) {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(opts.Relays) == 0 {
+		check(errNoRelays)
+	}
+	if opts.RelayPriorityTolerancePct < 0 || opts.RelayPriorityTolerancePct > 100 {
+		check(errInvalidPriorityTolerance)
+	}
+	if opts.MinBidOverLocalPct < 0 || math.IsNaN(opts.MinBidOverLocalPct) {
+		check(errInvalidMinBidOverLocal)
+	}
+	if f := opts.RetryTimeoutFraction; f < 0 || f > 1 || math.IsNaN(f) {
+		check(errInvalidRetryTimeoutFraction)
+	}
+	if p := opts.FailedDeliveryPolicy; p != "" && p != FailedDeliveryPolicyDeprioritize && p != FailedDeliveryPolicyReject {
+		check(errInvalidFailedDeliveryPolicy)
+	}
+	if p := opts.RelayFailurePolicy; p != "" && p != RelayFailurePolicyNoBid && p != RelayFailurePolicyRetry && p != RelayFailurePolicyRetryAfter {
+		check(errInvalidRelayFailurePolicy)
+	}
+	if p := opts.WithholdingPenaltyPolicy; p != "" && p != WithholdingPenaltyDeprioritize && p != WithholdingPenaltyExclude {
+		check(errInvalidWithholdingPenalty)
+	}
+	if opts.MinActiveRelays < 0 || (opts.MinActiveRelays > len(opts.Relays) && len(opts.Relays) > 0) {
+		check(errInvalidMinActiveRelays)
+	}
+	if opts.MinBiddingRelays < 0 || (opts.MinBiddingRelays > len(opts.Relays) && len(opts.Relays) > 0) {
+		check(errInvalidMinBiddingRelays)
+	}
+	if p := opts.MinBiddingRelaysPolicy; p != "" && p != MinBiddingRelaysStrict && p != MinBiddingRelaysWarn {
+		check(errInvalidMinBiddingRelaysPolicy)
+	}
+	check(validateResourceLimits(opts.ResourceSoftLimits, opts.ResourceHardLimits))
+	if opts.SelfMonitorInterval <= 0 && (!opts.ResourceSoftLimits.isZero() || !opts.ResourceHardLimits.isZero()) {
+		check(errResourceLimitsNeedSample)
+	}
+	if _, err := parseRetryConditions(opts.RequestRetryOn); err != nil {
+		check(err)
+	}
+	if j := opts.RegistrationJitter; j < 0 || (opts.RequestTimeoutRegVal > 0 && j >= opts.RequestTimeoutRegVal/2) {
+		check(errInvalidRegistrationJitter)
+	}
+	if t := opts.BidTieBreak; t != "" && t != BidTieBreakRelayPosition && t != BidTieBreakReliability && t != BidTieBreakRandom {
+		check(errInvalidBidTieBreak)
+	}
+	if p := opts.ConflictingBids; p != "" && p != ConflictingBidsLowest && p != ConflictingBidsDrop {
+		check(errInvalidConflictingBids)
+	}
+	if l := opts.ValidationLevel; l != "" && l != ValidationLevelNone && l != ValidationLevelBasic && l != ValidationLevelStrict {
+		check(errInvalidValidationLevel)
+	}
+	if c := opts.RelayContentTypeCheck; c != "" && c != ContentTypeCheckStrict && c != ContentTypeCheckLenient && c != ContentTypeCheckOff {
+		check(errInvalidContentTypeCheck)
+	}
+
+	_, err := parseForwardHeaders(opts.ForwardHeaders)
+	check(err)
+	_, err = relayMonitorEntries(opts.RelayMonitors)
+	check(err)
+	_, err = parseRelayTLSMinVersion(opts.RelayTLSMinVersion)
+	check(err)
+	check(validateForkTimeouts(opts.GetHeaderTimeoutByFork, opts.ForkEpochs))
+	check(validateRelayForks(opts.Relays, opts.ForkEpochs))
+	_, err = newSigningDomains(opts.GenesisTime, opts.SecondsPerSlot, opts.GenesisForkVersionHex, opts.NextForkVersionHex, opts.NextForkEpoch, opts.ExtraSigningForkVersions)
+	check(err)
+
+	if opts.APIAuthToken != "" || opts.APIAuthTokenFile != "" {
+		_, err := newAPIAuth(opts.APIAuthToken, opts.APIAuthTokenFile)
+		check(err)
+	}
+	if len(opts.AllowedClientCIDRs) > 0 || len(opts.TrustedProxies) > 0 {
+		_, err := newClientAllowlist(opts.AllowedClientCIDRs, opts.TrustedProxies)
+		check(err)
+	}
+	if opts.RequestSigningKey != "" {
+		scheme := opts.RequestSigningScheme
+		if scheme == "" {
+			scheme = RequestSigningSchemeBLS
+		}
+		_, err := newRequestSigner(scheme, opts.RequestSigningKey)
+		check(err)
+	}
+	if len(opts.CORSAllowedOrigins) > 0 {
+		_, err := newCORSPolicy(opts.CORSAllowedOrigins, opts.CORSAllowedHeaders, opts.CORSMaxAge)
+		check(err)
+	}
+	if opts.PayloadOutcomesFile != "" {
+		_, err := newPayloadOutcomes(opts.PayloadOutcomesFile)
+		check(err)
+	}
+	if opts.PayloadArtifactsDir != "" {
+		check(checkPayloadArtifactsDir(opts.PayloadArtifactsDir))
+	}
+	if opts.AlertWebhookURL != "" || opts.AlertCommand != "" {
+		_, err := parseAlertTargets(opts.AlertWebhookURL, opts.AlertCommand)
+		check(err)
+	}
+	if opts.StatsdAddr != "" {
+		if d := opts.StatsdDialect; d != "" && d != StatsdDialectStatsd && d != StatsdDialectDogstatsd {
+			check(fmt.Errorf("%w: %s", errInvalidStatsdDialect, d))
+		}
+		_, err := net.ResolveUDPAddr("udp", opts.StatsdAddr)
+		check(err)
+	}
+	if opts.ChaosConfig != "" {
+		if strings.EqualFold(opts.GenesisForkVersionHex, mainnetGenesisForkVersion) && !opts.ChaosAllowMainnet {
+			check(errChaosOnMainnet)
+		}
+		_, err := newChaosConfig(opts.ChaosConfig)
+		check(err)
+	}
+	if opts.ChaosDelay < 0 {
+		check(errInvalidChaosDelay)
+	} else if opts.ChaosDelay > 0 {
+		if !opts.ChaosDelayEnabled {
+			check(errChaosDelayDisabled)
+		}
+		if strings.EqualFold(opts.GenesisForkVersionHex, mainnetGenesisForkVersion) && !opts.ChaosAllowMainnet {
+			check(errChaosOnMainnet)
+		}
+	}
+	if opts.TenantsFile != "" {
+		_, err := newTenantMap(opts.TenantsFile)
+		check(err)
+	}
+
+	if opts.ListenAddr != "" && opts.MetricsAddr != "" && listenAddrsCollide(opts.ListenAddr, opts.MetricsAddr) {
+		check(fmt.Errorf("%w: %s and metrics %s", errListenAddrCollision, opts.ListenAddr, opts.MetricsAddr))
+	}
+
+	if bind {
+		if opts.RelayLocalAddr != "" {
+			_, err := resolveRelayLocalAddr(opts.RelayLocalAddr, opts.Log)
+			check(err)
+		}
+		for _, addr := range []string{opts.ListenAddr, opts.MetricsAddr} {
+			if addr == "" {
+				continue
+			}
+			listener, err := net.Listen("tcp", addr)
+			if err != nil {
+				check(err)
+				continue
+			}
+			listener.Close()
+		}
+	}
+	return errs
+}
+
+// listenAddrsCollide returns true if two listen addresses use the same port on an overlapping interface
+func listenAddrsCollide(a, b string) bool {
+	hostA, portA, errA := net.SplitHostPort(a)
+	hostB, portB, errB := net.SplitHostPort(b)
+	if errA != nil || errB != nil || portA != portB || portA == "0" {
+		return false
+	}
+	return hostA == hostB || isWildcardHost(hostA) || isWildcardHost(hostB) || (isLoopbackHost(hostA) && isLoopbackHost(hostB))
+}
+
+// isWildcardHost returns true if listening on the host listens on all interfaces
+func isWildcardHost(host string) bool {
+	ip := net.ParseIP(host)
+	return host == "" || (ip != nil && ip.IsUnspecified())
+}
+
+// isLoopbackHost returns true for localhost and loopback addresses
+func isLoopbackHost(host string) bool {
+	ip := net.ParseIP(host)
+	return strings.EqualFold(host, "localhost") || (ip != nil && ip.IsLoopback())
+}
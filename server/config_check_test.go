@@ -0,0 +1,144 @@
+package server
+
+import (
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/flashbots/mev-boost/server/mock"
+	"github.com/flashbots/mev-boost/server/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateConfig(t *testing.T) {
+	validOpts := func() BoostServiceOpts {
+		return BoostServiceOpts{
+			Log:                   mock.TestLog,
+			ListenAddr:            "127.0.0.1:0",
+			Relays:                []types.RelayEntry{mock.NewRelay(t).RelayEntry},
+			GenesisForkVersionHex: "0x00000000",
+		}
+	}
+
+	t.Run("Valid configuration", func(t *testing.T) {
+		require.Empty(t, ValidateConfig(validOpts()))
+	})
+
+	t.Run("Relay monitor path prefix must be absolute", func(t *testing.T) {
+		monitor, err := url.Parse("http://monitor.example.com?path-prefix=api")
+		require.NoError(t, err)
+		opts := validOpts()
+		opts.RelayMonitors = []*url.URL{monitor}
+		errs := ValidateConfig(opts)
+		require.Len(t, errs, 1)
+		require.ErrorIs(t, errs[0], types.ErrInvalidRelayMonitorPathPrefix)
+	})
+
+	t.Run("Registration jitter must stay within the timeout", func(t *testing.T) {
+		opts := validOpts()
+		opts.RequestTimeoutRegVal = time.Second
+		opts.RegistrationJitter = 400 * time.Millisecond
+		require.Empty(t, ValidateConfig(opts))
+
+		opts.RegistrationJitter = 500 * time.Millisecond
+		errs := ValidateConfig(opts)
+		require.Len(t, errs, 1)
+		require.ErrorIs(t, errs[0], errInvalidRegistrationJitter)
+
+		opts.RegistrationJitter = -time.Millisecond
+		require.ErrorIs(t, ValidateConfig(opts)[0], errInvalidRegistrationJitter)
+	})
+
+	t.Run("Retry conditions must be known", func(t *testing.T) {
+		opts := validOpts()
+		opts.RequestRetryOn = []string{"5xx=2", "timeout"}
+		require.Empty(t, ValidateConfig(opts))
+
+		opts.RequestRetryOn = []string{"5xx", "reset"}
+		require.ErrorIs(t, ValidateConfig(opts)[0], errUnknownRetryCondition)
+	})
+
+	t.Run("Resource limits need the self monitor", func(t *testing.T) {
+		opts := validOpts()
+		opts.ResourceHardLimits = ResourceLimits{Goroutines: 10000}
+		require.ErrorIs(t, ValidateConfig(opts)[0], errResourceLimitsNeedSample)
+
+		opts.SelfMonitorInterval = time.Second
+		require.Empty(t, ValidateConfig(opts))
+		opts.ResourceSoftLimits = ResourceLimits{Goroutines: 20000}
+		require.ErrorIs(t, ValidateConfig(opts)[0], errHardLimitBelowSoftLimit)
+	})
+
+	t.Run("All problems are reported together", func(t *testing.T) {
+		// The listen address is already in use
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer listener.Close()
+
+		tenantsFile := filepath.Join(t.TempDir(), "tenants.json")
+		require.NoError(t, os.WriteFile(tenantsFile, []byte(`{"0x8a1d": "not a valid label"}`), 0o600))
+
+		opts := validOpts()
+		opts.ListenAddr = listener.Addr().String()
+		opts.Relays = nil
+		opts.RelayPriorityTolerancePct = 150
+		opts.FailedDeliveryPolicy = "ignore"
+		opts.ValidationLevel = "paranoid"
+		opts.RelayContentTypeCheck = "html"
+		opts.GenesisForkVersionHex = "0x0000"
+		opts.TenantsFile = tenantsFile
+		opts.StatsdAddr = "localhost:8125"
+		opts.StatsdDialect = "graphite"
+
+		errs := ValidateConfig(opts)
+		require.Len(t, errs, 9, errs)
+		require.ErrorIs(t, errs[0], errNoRelays)
+		require.ErrorIs(t, errs[1], errInvalidPriorityTolerance)
+		require.ErrorIs(t, errs[2], errInvalidFailedDeliveryPolicy)
+		require.ErrorIs(t, errs[3], errInvalidValidationLevel)
+		require.ErrorIs(t, errs[4], errInvalidContentTypeCheck)
+		require.ErrorIs(t, errs[5], errInvalidForkVersion)
+		require.ErrorIs(t, errs[6], errInvalidStatsdDialect)
+		require.ErrorIs(t, errs[7], errInvalidTenantLabel)
+		require.ErrorContains(t, errs[8], "address already in use")
+
+		// NewBoostService reports the same problems, but does not bind the listen address
+		_, err = NewBoostService(opts)
+		require.ErrorIs(t, err, errNoRelays)
+		require.ErrorIs(t, err, errInvalidTenantLabel)
+		require.NotContains(t, err.Error(), "address already in use")
+	})
+}
+
+func TestListenAddrsCollide(t *testing.T) {
+	testCases := []struct {
+		a, b    string
+		collide bool
+	}{
+		{"localhost:18550", "localhost:18551", false},
+		{"localhost:18550", "localhost:18550", true},
+		{"localhost:18550", "127.0.0.1:18550", true},
+		{"0.0.0.0:18550", "127.0.0.1:18550", true},
+		{":18550", "10.0.0.1:18550", true},
+		{"[::]:18550", "localhost:18550", true},
+		{"10.0.0.1:18550", "10.0.0.2:18550", false},
+		{"10.0.0.1:18550", "localhost:18550", false},
+		{"localhost:0", "localhost:0", false},
+	}
+	for _, tc := range testCases {
+		require.Equal(t, tc.collide, listenAddrsCollide(tc.a, tc.b), "%s and %s", tc.a, tc.b)
+	}
+
+	opts := BoostServiceOpts{
+		Log:                   mock.TestLog,
+		ListenAddr:            "localhost:18550",
+		MetricsAddr:           "0.0.0.0:18550",
+		Relays:                []types.RelayEntry{mock.NewRelay(t).RelayEntry},
+		GenesisForkVersionHex: "0x00000000",
+	}
+	_, err := NewBoostService(opts)
+	require.ErrorIs(t, err, errListenAddrCollision)
+}
@@ -0,0 +1,120 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/attestantio/go-eth2-client/spec"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/flashbots/mev-boost/server/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+// Outcomes of comparing the Eth-Consensus-Version header of a getPayload request with the decoded fork
+const (
+	consensusVersionMatch    = "match"
+	consensusVersionMismatch = "mismatch"
+	consensusVersionMissing  = "missing"
+	consensusVersionUnknown  = "unknown"
+)
+
+var consensusVersionChecks = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "getpayload_consensus_version_checks_total",
+	Help: "Number of getPayload requests by whether the Eth-Consensus-Version header matches the fork the body decodes as",
+}, []string{"client", "outcome"})
+
+// compareConsensusVersion compares the fork of the Eth-Consensus-Version header with the fork the request body
+// was decoded as, to find out if the header can be relied on for decoding. The decoded fork is still used.
+func (m *BoostService) compareConsensusVersion(log *logrus.Entry, req *http.Request, decodedFork string) {
+	header := req.Header.Get(HeaderEthConsensusVersion)
+	outcome := consensusVersionMatch
+	if header == "" {
+		outcome = consensusVersionMissing
+	} else {
+		var version spec.DataVersion
+		if err := version.UnmarshalJSON([]byte(strconv.Quote(header))); err != nil {
+			outcome = consensusVersionUnknown
+		} else if version.String() != decodedFork {
+			outcome = consensusVersionMismatch
+		}
+	}
+
+	ua := req.Header.Get("User-Agent")
+	consensusVersionChecks.WithLabelValues(clientLabel(ua), outcome).Inc()
+	if outcome != consensusVersionMatch {
+		log.WithFields(logrus.Fields{
+			"headerVersion":  header,
+			"decodedVersion": decodedFork,
+			"outcome":        outcome,
+		}).Warn("Eth-Consensus-Version header does not match the decoded getPayload request")
+	}
+}
+
+var relayConsensusVersionInferred = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "relay_consensus_version_inferred_total",
+	Help: "Number of relay responses without a version and without an Eth-Consensus-Version header, whose fork was inferred from the slot",
+}, []string{"relay", "method"})
+
+// consensusVersionKey is the context key of the relay response whose version SendHTTPRequest fills in if the body
+// has none
+type consensusVersionKey struct{}
+
+type consensusVersionFallback struct {
+	relay  string
+	method string
+	fork   spec.DataVersion // fork of the slot, unknown if it must not be inferred
+	log    *logrus.Entry
+}
+
+// withConsensusVersionFallback returns a context in which SendHTTPRequest fills in the version of a response body
+// without one, from the Eth-Consensus-Version header or else from the fork of the slot if inferring it is enabled
+func (m *BoostService) withConsensusVersionFallback(ctx context.Context, log *logrus.Entry, relay types.RelayEntry, method string, slot phase0.Slot) context.Context {
+	fallback := consensusVersionFallback{relay: relayLabel(relay), method: method, log: log}
+	if m.inferConsensusVersion {
+		fallback.fork = m.forkSchedule.forkAt(slot)
+	}
+	return context.WithValue(ctx, consensusVersionKey{}, fallback)
+}
+
+// fillConsensusVersion returns the body with the version of the response if the context has a fallback and the body
+// has no version. Bodies which are not JSON objects, or whose version is unknown, are returned as is.
+func fillConsensusVersion(ctx context.Context, header http.Header, body []byte) []byte {
+	fallback, ok := ctx.Value(consensusVersionKey{}).(consensusVersionFallback)
+	if !ok || !bytes.HasPrefix(bytes.TrimSpace(body), []byte("{")) {
+		return body
+	}
+	var probe struct {
+		Version json.RawMessage `json:"version"`
+	}
+	if err := json.Unmarshal(body, &probe); err != nil || len(probe.Version) > 0 {
+		return body
+	}
+
+	version := header.Get(HeaderEthConsensusVersion)
+	if version == "" {
+		if fallback.fork == spec.DataVersionUnknown {
+			return body
+		}
+		version = fallback.fork.String()
+		relayConsensusVersionInferred.WithLabelValues(fallback.relay, fallback.method).Inc()
+		fallback.log.WithField("version", version).Debug("relay response has no version nor Eth-Consensus-Version header, using the fork of the slot")
+	}
+	return withVersionField(body, version)
+}
+
+// withVersionField adds a version field to a JSON object
+func withVersionField(body []byte, version string) []byte {
+	field, _ := json.Marshal(version)
+	rest := bytes.TrimSpace(bytes.TrimSpace(body)[1:])
+	out := make([]byte, 0, len(body)+len(field)+12)
+	out = append(out, `{"version":`...)
+	out = append(out, field...)
+	if len(rest) > 0 && rest[0] != '}' {
+		out = append(out, ',')
+	}
+	return append(out, rest...)
+}
@@ -0,0 +1,114 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/attestantio/go-eth2-client/spec"
+	"github.com/flashbots/mev-boost/server/mock"
+	"github.com/flashbots/mev-boost/server/params"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConsensusVersionShadow(t *testing.T) {
+	testCases := []struct {
+		name    string
+		header  string
+		outcome string
+	}{
+		{name: "Matching header", header: "deneb", outcome: consensusVersionMatch},
+		{name: "Matching header in another case", header: "Deneb", outcome: consensusVersionMatch},
+		{name: "Header of another fork", header: "electra", outcome: consensusVersionMismatch},
+		{name: "Missing header", outcome: consensusVersionMissing},
+		{name: "Unknown fork", header: "fulu", outcome: consensusVersionUnknown},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			block, response := loadDenebBlock(t)
+			backend := newTestBackend(t, 1, time.Second)
+			backend.boost.consensusVersionShadow = true
+			backend.relays[0].GetPayloadResponse = response
+
+			body, err := json.Marshal(block)
+			require.NoError(t, err)
+			req := httptest.NewRequest(http.MethodPost, params.PathGetPayload, bytes.NewReader(body))
+			req.Header.Set("User-Agent", "Teku/v24.10.0")
+			if tt.header != "" {
+				req.Header.Set(HeaderEthConsensusVersion, tt.header)
+			}
+			before := testutil.ToFloat64(consensusVersionChecks.WithLabelValues("teku", tt.outcome))
+
+			rr := httptest.NewRecorder()
+			backend.boost.getRouter().ServeHTTP(rr, req)
+
+			// The decoded fork is used regardless of the header
+			require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+			require.InDelta(t, 1, testutil.ToFloat64(consensusVersionChecks.WithLabelValues("teku", tt.outcome))-before, 0)
+		})
+	}
+}
+
+func TestFillConsensusVersion(t *testing.T) {
+	relay := mock.NewRelay(t).RelayEntry
+	backend := newTestBackend(t, 1, time.Second)
+	backend.boost.forkSchedule = newForkSchedule(map[spec.DataVersion]uint64{spec.DataVersionDeneb: 0})
+	withHeader := http.Header{}
+	withHeader.Set(HeaderEthConsensusVersion, "electra")
+	body := []byte(` {"data": {}} `)
+
+	// Without the fallback in the context, or with a version in the body, the body is kept
+	require.Equal(t, body, fillConsensusVersion(context.Background(), http.Header{}, body))
+	backend.boost.inferConsensusVersion = true
+	ctx := backend.boost.withConsensusVersionFallback(context.Background(), mock.TestLog, relay, "getHeader", 1)
+	versioned := []byte(`{"version":"deneb","data":{}}`)
+	require.Equal(t, versioned, fillConsensusVersion(ctx, withHeader, versioned))
+	require.Equal(t, []byte("null"), fillConsensusVersion(ctx, http.Header{}, []byte("null")))
+
+	// The header is used before the slot
+	require.JSONEq(t, `{"version":"electra","data":{}}`, string(fillConsensusVersion(ctx, withHeader, body)))
+	require.JSONEq(t, `{"version":"deneb"}`, string(fillConsensusVersion(ctx, http.Header{}, []byte("{ }"))))
+
+	before := testutil.ToFloat64(relayConsensusVersionInferred.WithLabelValues(relayLabel(relay), "getHeader"))
+	require.JSONEq(t, `{"version":"deneb","data":{}}`, string(fillConsensusVersion(ctx, http.Header{}, body)))
+	require.InDelta(t, 1, testutil.ToFloat64(relayConsensusVersionInferred.WithLabelValues(relayLabel(relay), "getHeader"))-before, 0)
+
+	// Without inference, the body is kept for the decoder to reject
+	backend.boost.inferConsensusVersion = false
+	ctx = backend.boost.withConsensusVersionFallback(context.Background(), mock.TestLog, relay, "getHeader", 1)
+	require.Equal(t, body, fillConsensusVersion(ctx, http.Header{}, body))
+}
+
+func TestGetHeaderWithoutConsensusVersion(t *testing.T) {
+	parentHash := mock.HexToHash("0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7")
+	path := getHeaderPath(1, parentHash, mock.HexToPubkey(feeRecipientTestPubkey))
+
+	for _, infer := range []bool{true, false} {
+		backend := newTestBackend(t, 1, time.Second)
+		backend.boost.forkSchedule = newForkSchedule(map[spec.DataVersion]uint64{spec.DataVersionDeneb: 0})
+		backend.boost.inferConsensusVersion = infer
+		relay := backend.relays[0]
+		bid := relay.MakeGetHeaderResponse(12345, parentHash.String(), parentHash.String(), feeRecipientTestPubkey, spec.DataVersionDeneb)
+		relay.OverrideHandleGetHeader(func(w http.ResponseWriter, _ *http.Request) {
+			// The bid of a relay which sets neither the version nor the Eth-Consensus-Version header
+			encoded, err := json.Marshal(bid)
+			require.NoError(t, err)
+			var fields map[string]json.RawMessage
+			require.NoError(t, json.Unmarshal(encoded, &fields))
+			delete(fields, "version")
+			require.NoError(t, json.NewEncoder(w).Encode(fields))
+		})
+
+		rr := backend.request(t, http.MethodGet, path, nil)
+		if infer {
+			require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		} else {
+			require.Equal(t, http.StatusNoContent, rr.Code, rr.Body.String())
+		}
+	}
+}
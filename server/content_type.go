@@ -0,0 +1,87 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/flashbots/mev-boost/server/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+// Policies for successful relay responses with a content type which is neither JSON nor SSZ, like the HTML error
+// page of a proxy
+const (
+	// ContentTypeCheckStrict rejects the response without decoding it
+	ContentTypeCheckStrict = "strict"
+	// ContentTypeCheckLenient decodes the response, and reports the content type if decoding fails
+	ContentTypeCheckLenient = "lenient"
+	// ContentTypeCheckOff does not check the content type
+	ContentTypeCheckOff = "off"
+)
+
+var (
+	errUnexpectedContentType   = errors.New("unexpected content type")
+	errInvalidContentTypeCheck = errors.New("relay content type check must be strict, lenient or off")
+
+	relayUnexpectedContentTypes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "relay_unexpected_content_type_total",
+		Help: "Number of successful relay responses which were not used because of a content type which is neither JSON nor SSZ, by relay and method",
+	}, []string{"relay", "method"})
+)
+
+type contentTypeCheckKey struct{}
+
+// contentTypeCheck checks the content type of the responses of a relay request
+type contentTypeCheck struct {
+	relay  string
+	method string
+	policy string
+	log    *logrus.Entry
+}
+
+// withContentTypeCheck returns a context which makes SendHTTPRequest check the content type of the response,
+// unless the check is off
+func (m *BoostService) withContentTypeCheck(ctx context.Context, log *logrus.Entry, relay types.RelayEntry, method string) context.Context {
+	if m.contentTypeCheck == "" || m.contentTypeCheck == ContentTypeCheckOff {
+		return ctx
+	}
+	return context.WithValue(ctx, contentTypeCheckKey{}, contentTypeCheck{relay: relayLabel(relay), method: method, policy: m.contentTypeCheck, log: log})
+}
+
+// expectedContentType returns true for JSON and SSZ responses, and for responses without a content type
+func expectedContentType(contentType string) bool {
+	if strings.TrimSpace(contentType) == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json") || mediaType == "application/octet-stream"
+}
+
+// unexpectedContentType returns an error identifying the relay and the content type if the context checks the
+// content type and the response has an unexpected one. It is called before decoding the response without a
+// decode error, when only the strict policy rejects the response, and again with the error if decoding failed.
+func unexpectedContentType(ctx context.Context, header http.Header, body []byte, decodeErr error) error {
+	check, ok := ctx.Value(contentTypeCheckKey{}).(contentTypeCheck)
+	contentType := header.Get("Content-Type")
+	if !ok || expectedContentType(contentType) || (decodeErr == nil && check.policy != ContentTypeCheckStrict) {
+		return nil
+	}
+	relayUnexpectedContentTypes.WithLabelValues(check.relay, check.method).Inc()
+	check.log.WithFields(logrus.Fields{
+		"contentType": contentType,
+		"body":        redactBody(body),
+	}).Warn("relay response has an unexpected content type, the relay or a proxy in front of it may be misbehaving")
+	err := fmt.Errorf("%w %q from relay %s, expected JSON or SSZ", errUnexpectedContentType, contentType, check.relay)
+	if decodeErr != nil {
+		return fmt.Errorf("%w: %w", err, decodeErr)
+	}
+	return err
+}
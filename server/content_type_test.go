@@ -0,0 +1,107 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/attestantio/go-eth2-client/spec"
+	"github.com/flashbots/mev-boost/server/mock"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+	logrusTest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpectedContentType(t *testing.T) {
+	for contentType, expected := range map[string]bool{
+		"":                                true,
+		"application/json":                true,
+		"application/json; charset=utf-8": true,
+		"application/problem+json":        true,
+		"application/octet-stream":        true,
+		"text/html; charset=utf-8":        false,
+		"text/plain":                      false,
+		"not a media type;;":              false,
+	} {
+		require.Equal(t, expected, expectedContentType(contentType), contentType)
+	}
+}
+
+func TestGetHeaderUnexpectedContentType(t *testing.T) {
+	parentHash := "0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7"
+	path := getHeaderPath(1, mock.HexToHash(parentHash), mock.HexToPubkey(feeRecipientTestPubkey))
+
+	// respond sends the body with the content type from every relay
+	respond := func(t *testing.T, policy, contentType string, body func(relay *mock.Relay) []byte) (*testBackend, int, *logrusTest.Hook) {
+		t.Helper()
+		backend := newTestBackend(t, 1, time.Second)
+		backend.boost.contentTypeCheck = policy
+		logger, hook := logrusTest.NewNullLogger()
+		backend.boost.log = logrus.NewEntry(logger)
+		relay := backend.relays[0]
+		relay.OverrideHandleGetHeader(func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("Content-Type", contentType)
+			_, _ = w.Write(body(relay))
+		})
+		return backend, backend.request(t, http.MethodGet, path, nil).Code, hook
+	}
+	html := func(*mock.Relay) []byte { return []byte("<html><body>502 Bad Gateway</body></html>") }
+	bid := func(relay *mock.Relay) []byte {
+		body, err := json.Marshal(relay.MakeGetHeaderResponse(12345, parentHash, parentHash, feeRecipientTestPubkey, spec.DataVersionDeneb))
+		require.NoError(t, err)
+		return body
+	}
+	unexpected := func(backend *testBackend) float64 {
+		return testutil.ToFloat64(relayUnexpectedContentTypes.WithLabelValues(relayLabel(backend.relays[0].RelayEntry), "getHeader"))
+	}
+	loggedError := func(hook *logrusTest.Hook) error {
+		for _, entry := range hook.AllEntries() {
+			if err, ok := entry.Data[logrus.ErrorKey].(error); ok {
+				return err
+			}
+		}
+		return nil
+	}
+
+	t.Run("Proxy error page is reported with its content type", func(t *testing.T) {
+		backend, code, hook := respond(t, ContentTypeCheckLenient, "text/html; charset=utf-8", html)
+		require.Equal(t, http.StatusNoContent, code)
+		require.InDelta(t, 1, unexpected(backend), 0)
+		err := loggedError(hook)
+		require.ErrorIs(t, err, errUnexpectedContentType)
+		require.Contains(t, err.Error(), `"text/html; charset=utf-8" from relay `+relayLabel(backend.relays[0].RelayEntry))
+
+		var snippet any
+		for _, entry := range hook.AllEntries() {
+			if entry.Data["contentType"] != nil {
+				snippet = entry.Data["body"]
+			}
+		}
+		require.Equal(t, string(html(nil)), snippet)
+	})
+
+	t.Run("Lenient policy decodes JSON with another content type", func(t *testing.T) {
+		backend, code, _ := respond(t, ContentTypeCheckLenient, "text/plain", bid)
+		require.Equal(t, http.StatusOK, code)
+		require.Zero(t, unexpected(backend))
+	})
+
+	t.Run("Strict policy rejects JSON with another content type", func(t *testing.T) {
+		backend, code, hook := respond(t, ContentTypeCheckStrict, "text/plain", bid)
+		require.Equal(t, http.StatusNoContent, code)
+		require.InDelta(t, 1, unexpected(backend), 0)
+		require.ErrorIs(t, loggedError(hook), errUnexpectedContentType)
+
+		_, code, _ = respond(t, ContentTypeCheckStrict, "application/json", bid)
+		require.Equal(t, http.StatusOK, code)
+	})
+
+	t.Run("Check can be turned off", func(t *testing.T) {
+		backend, code, hook := respond(t, ContentTypeCheckOff, "text/html", html)
+		require.Equal(t, http.StatusNoContent, code)
+		require.Zero(t, unexpected(backend))
+		require.NotErrorIs(t, loggedError(hook), errUnexpectedContentType)
+	})
+}
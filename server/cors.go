@@ -0,0 +1,120 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/flashbots/mev-boost/server/params"
+)
+
+var errInvalidCORSOrigin = errors.New("invalid CORS origin")
+
+// corsPolicy allows browsers on other origins to call the read-only endpoints. Origins are either matched exactly,
+// like https://dashboard.example.com, or as a wildcard subdomain pattern, like https://*.example.com.
+type corsPolicy struct {
+	origins   map[string]bool
+	wildcards []corsWildcard
+	headers   string
+	maxAge    string
+}
+
+// corsWildcard matches the origins with the scheme and any subdomain of the suffix
+type corsWildcard struct {
+	scheme string // including "://"
+	suffix string // including the leading dot and the port, if any
+}
+
+func newCORSPolicy(origins, headers []string, maxAge time.Duration) (*corsPolicy, error) {
+	p := &corsPolicy{
+		origins: make(map[string]bool, len(origins)),
+		headers: strings.Join(headers, ", "),
+	}
+	if maxAge > 0 {
+		p.maxAge = strconv.Itoa(int(maxAge.Seconds()))
+	}
+	for _, origin := range origins {
+		origin = strings.ToLower(origin)
+		scheme, host, ok := strings.Cut(origin, "://")
+		if !ok || (scheme != "http" && scheme != "https") || host == "" || strings.ContainsAny(host, "/?#@") {
+			return nil, fmt.Errorf("%w: %s", errInvalidCORSOrigin, origin)
+		}
+		suffix, wildcard := strings.CutPrefix(host, "*.")
+		if strings.Contains(suffix, "*") || suffix == "" {
+			return nil, fmt.Errorf("%w: %s", errInvalidCORSOrigin, origin)
+		}
+		if wildcard {
+			p.wildcards = append(p.wildcards, corsWildcard{scheme: scheme + "://", suffix: "." + suffix})
+		} else {
+			p.origins[origin] = true
+		}
+	}
+	return p, nil
+}
+
+// allowed returns whether the origin matches one of the allowed origins
+func (p *corsPolicy) allowed(origin string) bool {
+	origin = strings.ToLower(origin)
+	if p.origins[origin] {
+		return true
+	}
+	for _, w := range p.wildcards {
+		subdomain, ok := strings.CutPrefix(origin, w.scheme)
+		if !ok {
+			continue
+		}
+		subdomain, ok = strings.CutSuffix(subdomain, w.suffix)
+		if ok && subdomain != "" && !strings.ContainsAny(subdomain, "/:@") {
+			return true
+		}
+	}
+	return false
+}
+
+// corsReadOnlyPath returns whether cross-origin requests may be allowed for the path. The proposer endpoints
+// and the admin endpoints are never included.
+func corsReadOnlyPath(path string) bool {
+	return path == params.PathStatus || path == params.PathHealthz || strings.HasPrefix(path, params.PathPrefixDebug)
+}
+
+// corsMiddleware answers preflight requests and adds the CORS headers to GET requests of the read-only
+// endpoints from allowed origins. Other requests are passed on without CORS headers, so browsers block them.
+func (m *BoostService) corsMiddleware(next http.Handler) http.Handler {
+	if m.cors == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		origin := req.Header.Get("Origin")
+		if origin == "" || !corsReadOnlyPath(req.URL.Path) {
+			next.ServeHTTP(w, req)
+			return
+		}
+		w.Header().Add("Vary", "Origin")
+		if !m.cors.allowed(origin) {
+			next.ServeHTTP(w, req)
+			return
+		}
+
+		if req.Method == http.MethodOptions && req.Header.Get("Access-Control-Request-Method") != "" {
+			w.Header().Add("Vary", "Access-Control-Request-Method")
+			w.Header().Add("Vary", "Access-Control-Request-Headers")
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Allow-Methods", "GET, HEAD")
+			if m.cors.headers != "" {
+				w.Header().Set("Access-Control-Allow-Headers", m.cors.headers)
+			}
+			if m.cors.maxAge != "" {
+				w.Header().Set("Access-Control-Max-Age", m.cors.maxAge)
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		if req.Method == http.MethodGet || req.Method == http.MethodHead {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+		}
+		next.ServeHTTP(w, req)
+	})
+}
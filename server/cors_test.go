@@ -0,0 +1,137 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/flashbots/mev-boost/server/params"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewCORSPolicy(t *testing.T) {
+	testCases := []struct {
+		origin string
+		valid  bool
+	}{
+		{origin: "https://dashboard.example.com", valid: true},
+		{origin: "http://localhost:3000", valid: true},
+		{origin: "https://*.example.com", valid: true},
+		{origin: "*", valid: false},
+		{origin: "https://*", valid: false},
+		{origin: "https://dash*.example.com", valid: false},
+		{origin: "https://*.*.example.com", valid: false},
+		{origin: "ftp://example.com", valid: false},
+		{origin: "example.com", valid: false},
+		{origin: "https://example.com/", valid: false},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.origin, func(t *testing.T) {
+			_, err := newCORSPolicy([]string{tt.origin}, nil, 0)
+			if tt.valid {
+				require.NoError(t, err)
+			} else {
+				require.ErrorIs(t, err, errInvalidCORSOrigin)
+			}
+		})
+	}
+}
+
+func TestCORSPolicyAllowed(t *testing.T) {
+	policy, err := newCORSPolicy([]string{"https://dashboard.example.com", "https://*.monitoring.io"}, nil, 0)
+	require.NoError(t, err)
+
+	require.True(t, policy.allowed("https://dashboard.example.com"))
+	require.True(t, policy.allowed("https://Dashboard.Example.com"))
+	require.True(t, policy.allowed("https://grafana.monitoring.io"))
+	require.True(t, policy.allowed("https://a.b.monitoring.io"))
+	require.False(t, policy.allowed("http://dashboard.example.com"))
+	require.False(t, policy.allowed("https://other.example.com"))
+	require.False(t, policy.allowed("https://monitoring.io"))
+	require.False(t, policy.allowed("https://evilmonitoring.io"))
+	require.False(t, policy.allowed("http://grafana.monitoring.io"))
+	require.False(t, policy.allowed("https://grafana.monitoring.io:8443"))
+}
+
+func TestCORS(t *testing.T) {
+	backend := newTestBackend(t, 1, time.Second)
+	backend.boost.debugEndpoints = true
+	policy, err := newCORSPolicy([]string{"https://*.example.com"}, []string{"Authorization", HeaderKeyAdminToken}, 10*time.Minute)
+	require.NoError(t, err)
+	backend.boost.cors = policy
+
+	corsRequest := func(method, path, origin string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Origin", origin)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		rr := httptest.NewRecorder()
+		backend.boost.getRouter().ServeHTTP(rr, req)
+		return rr
+	}
+	preflight := func(method string) http.Header {
+		return http.Header{"Access-Control-Request-Method": []string{method}}
+	}
+
+	t.Run("Preflight for status", func(t *testing.T) {
+		rr := corsRequest(http.MethodOptions, params.PathStatus, "https://dashboard.example.com", preflight(http.MethodGet))
+		require.Equal(t, http.StatusNoContent, rr.Code)
+		require.Equal(t, "https://dashboard.example.com", rr.Header().Get("Access-Control-Allow-Origin"))
+		require.Equal(t, "GET, HEAD", rr.Header().Get("Access-Control-Allow-Methods"))
+		require.Equal(t, "Authorization, X-MEVBoost-Admin-Token", rr.Header().Get("Access-Control-Allow-Headers"))
+		require.Equal(t, "600", rr.Header().Get("Access-Control-Max-Age"))
+	})
+
+	t.Run("Cross-origin GET of status", func(t *testing.T) {
+		rr := corsRequest(http.MethodGet, params.PathStatus, "https://dashboard.example.com", nil)
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Equal(t, "https://dashboard.example.com", rr.Header().Get("Access-Control-Allow-Origin"))
+		require.Contains(t, rr.Header().Values("Vary"), "Origin")
+	})
+
+	t.Run("Cross-origin GET of a debug endpoint", func(t *testing.T) {
+		rr := corsRequest(http.MethodGet, params.PathDebugFailedDeliveries, "https://dashboard.example.com", nil)
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Equal(t, "https://dashboard.example.com", rr.Header().Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("Origin not allowed", func(t *testing.T) {
+		rr := corsRequest(http.MethodGet, params.PathStatus, "https://dashboard.example.org", nil)
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Empty(t, rr.Header().Get("Access-Control-Allow-Origin"))
+
+		rr = corsRequest(http.MethodOptions, params.PathStatus, "https://dashboard.example.org", preflight(http.MethodGet))
+		require.NotEqual(t, http.StatusNoContent, rr.Code)
+		require.Empty(t, rr.Header().Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("Proposer endpoints", func(t *testing.T) {
+		rr := corsRequest(http.MethodOptions, params.PathGetPayload, "https://dashboard.example.com", preflight(http.MethodPost))
+		require.NotEqual(t, http.StatusNoContent, rr.Code)
+		require.Empty(t, rr.Header().Get("Access-Control-Allow-Origin"))
+
+		rr = corsRequest(http.MethodPost, params.PathRegisterValidator, "https://dashboard.example.com", nil)
+		require.Empty(t, rr.Header().Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("Cross-origin POST of a debug endpoint", func(t *testing.T) {
+		rr := corsRequest(http.MethodPost, params.PathDebugBidsFlush, "https://dashboard.example.com", nil)
+		require.Empty(t, rr.Header().Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("Preflight is not authenticated", func(t *testing.T) {
+		auth, err := newAPIAuth("secret", "")
+		require.NoError(t, err)
+		backend.boost.apiAuth = auth
+		defer func() { backend.boost.apiAuth = nil }()
+
+		rr := corsRequest(http.MethodOptions, params.PathStatus, "https://dashboard.example.com", preflight(http.MethodGet))
+		require.Equal(t, http.StatusNoContent, rr.Code)
+
+		rr = corsRequest(http.MethodGet, params.PathStatus, "https://dashboard.example.com", nil)
+		require.Equal(t, http.StatusUnauthorized, rr.Code)
+		require.Equal(t, "https://dashboard.example.com", rr.Header().Get("Access-Control-Allow-Origin"))
+	})
+}
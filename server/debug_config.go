@@ -0,0 +1,290 @@
+package server
+
+import (
+	"maps"
+	"net/http"
+	"slices"
+)
+
+// configDump is the effective configuration of the running service, returned by the config debug endpoint.
+// Secrets are redacted.
+type configDump struct {
+	ListenAddr    string             `json:"listen_addr"`
+	MetricsAddr   string             `json:"metrics_addr"`
+	Relays        []relayConfigDump  `json:"relays"`
+	RelayMonitors []string           `json:"relay_monitors"`
+	Timeouts      timeoutsConfigDump `json:"timeouts"`
+	Selection     selectionDump      `json:"selection"`
+	Features      map[string]bool    `json:"features"`
+	Forks         forksConfigDump    `json:"forks"`
+	Files         filesConfigDump    `json:"files"`
+	Secrets       secretsConfigDump  `json:"secrets"`
+	Builders      buildersConfigDump `json:"builders"`
+}
+
+type relayConfigDump struct {
+	Name                 string   `json:"name"`
+	URL                  string   `json:"url"`
+	PublicKey            string   `json:"pubkey"`
+	AdditionalPublicKeys []string `json:"additional_pubkeys,omitempty"`
+	Label                string   `json:"label,omitempty"`
+	Priority             int      `json:"priority"`
+	Stream               bool     `json:"stream"`
+	SniffGzip            bool     `json:"sniff_gzip,omitempty"`
+	MinOverLocalPct      *float64 `json:"min_over_local_pct,omitempty"`
+	Forks                []string `json:"forks,omitempty"`
+	Weight               int      `json:"weight"`
+	MinBid               string   `json:"min_bid,omitempty"`
+	Headers              []string `json:"headers,omitempty"` // names only, the values may be credentials
+}
+
+type timeoutsConfigDump struct {
+	GetHeader             string `json:"get_header"`
+	GetHeaderSlotDeadline string `json:"get_header_slot_deadline"`
+	GetPayload            string `json:"get_payload"`
+	RegisterValidator     string `json:"register_validator"`
+	RelayMonitor          string `json:"relay_monitor"`
+	RegistrationJitter    string `json:"registration_jitter"`
+	RelayCheckStartup     string `json:"relay_check_startup"`
+	SlowRelayThreshold    string `json:"slow_relay_threshold"`
+	ChaosResponseDelay    string `json:"chaos_response_delay"`
+	RequestMaxRetries     int    `json:"request_max_retries"`
+
+	// RequestRetryOn are the maximum retries of the retried conditions, 0 for RequestMaxRetries
+	RequestRetryOn map[string]int `json:"request_retry_on"`
+
+	// GetHeaderByFork are the getHeader timeouts by fork name, which override GetHeader
+	GetHeaderByFork map[string]string `json:"get_header_by_fork,omitempty"`
+}
+
+type selectionDump struct {
+	MinBid                   string   `json:"min_bid"`
+	MinBidOverLocalPct       float64  `json:"min_bid_over_local_pct"`
+	PriorityToleranceBps     uint64   `json:"priority_tolerance_bps"`
+	TieBreak                 string   `json:"tie_break"`
+	ConflictingBids          string   `json:"conflicting_bids"`
+	RetryTimeoutFraction     float64  `json:"retry_timeout_fraction"`
+	CachedBidMaxAge          string   `json:"cached_bid_max_age"`
+	MaxCachedSlots           int      `json:"max_cached_slots"`
+	ValidationLevel          string   `json:"validation_level"`
+	RelayContentTypeCheck    string   `json:"relay_content_type_check"`
+	FailedDeliveryPolicy     string   `json:"failed_delivery_policy"`
+	RelayFailurePolicy       string   `json:"relay_failure_policy"`
+	WithholdingPenalty       string   `json:"withholding_penalty"`
+	WithholdingPenaltyPolicy string   `json:"withholding_penalty_policy"`
+	MinActiveRelays          int      `json:"min_active_relays"`
+	MinBiddingRelays         int      `json:"min_bidding_relays"`
+	MinBiddingRelaysPolicy   string   `json:"min_bidding_relays_policy"`
+	BidFilters               []string `json:"bid_filters"`
+}
+
+type forksConfigDump struct {
+	GenesisTime        uint64   `json:"genesis_time,string"`
+	SecondsPerSlot     uint64   `json:"seconds_per_slot,string"`
+	CurrentForkVersion string   `json:"current_fork_version"`
+	NextForkVersion    string   `json:"next_fork_version,omitempty"`
+	NextForkEpoch      uint64   `json:"next_fork_epoch,string,omitempty"`
+	ExtraForkVersions  []string `json:"extra_fork_versions,omitempty"`
+
+	// ForkEpochs are the activation epochs by fork name
+	ForkEpochs map[string]uint64 `json:"fork_epochs,omitempty"`
+}
+
+type filesConfigDump struct {
+	APIAuthTokenFile    string `json:"api_auth_token_file,omitempty"`
+	ChaosConfig         string `json:"chaos_config,omitempty"`
+	TenantsFile         string `json:"tenants_file,omitempty"`
+	PayloadOutcomesFile string `json:"payload_outcomes_file,omitempty"`
+	BidMetadataFile     string `json:"bid_metadata_file,omitempty"`
+	PayloadArtifactsDir string `json:"payload_artifacts_dir,omitempty"`
+	SessionSummaryFile  string `json:"session_summary_file,omitempty"`
+}
+
+type secretsConfigDump struct {
+	APIAuthToken      string `json:"api_auth_token,omitempty"`
+	AdminToken        string `json:"admin_token,omitempty"`
+	RequestSigningKey string `json:"request_signing_key,omitempty"`
+	ExecutionRPCURL   string `json:"execution_rpc_url,omitempty"`
+}
+
+type buildersConfigDump struct {
+	Allowlist int `json:"allowlist"`
+	Denylist  int `json:"denylist"`
+}
+
+// configDump returns the effective configuration. It is read from the running service, so it includes changes
+// made at runtime, such as reloaded files and the admin builder denylist. Relay public keys are truncated
+// unless fullPubkeys is set.
+func (m *BoostService) configDump(fullPubkeys bool) configDump {
+	pubkey := func(pubkey string) string {
+		if fullPubkeys {
+			return pubkey
+		}
+		return truncatePubkey(pubkey)
+	}
+
+	dump := configDump{
+		ListenAddr:    m.listenAddr,
+		MetricsAddr:   m.metricsAddr,
+		Relays:        make([]relayConfigDump, 0, len(m.relays)),
+		RelayMonitors: make([]string, 0, len(m.relayMonitors)),
+		Timeouts: timeoutsConfigDump{
+			GetHeader:             m.httpClientGetHeader.Timeout.String(),
+			GetHeaderSlotDeadline: m.getHeaderSlotDeadline.String(),
+			GetPayload:            m.httpClientGetPayload.Timeout.String(),
+			RegisterValidator:     m.httpClientRegVal.Timeout.String(),
+			RelayMonitor:          m.httpClientMonitor.Timeout.String(),
+			RegistrationJitter:    m.registrationJitter.String(),
+			RelayCheckStartup:     m.relayCheckStartupTimeout.String(),
+			SlowRelayThreshold:    m.slowRelayThreshold.String(),
+			ChaosResponseDelay:    m.chaosResponseDelay.String(),
+			RequestMaxRetries:     m.requestMaxRetries,
+			RequestRetryOn:        m.requestRetryOn,
+		},
+		Selection: selectionDump{
+			MinBid:                   m.relayMinBid.String(),
+			MinBidOverLocalPct:       m.minBidOverLocalPct,
+			PriorityToleranceBps:     m.relayPriorityToleranceBps,
+			TieBreak:                 m.bidTieBreak,
+			ConflictingBids:          m.conflictingBids,
+			RetryTimeoutFraction:     m.retryTimeoutFraction,
+			CachedBidMaxAge:          m.cachedBidMaxAge.String(),
+			MaxCachedSlots:           m.maxCachedSlots,
+			ValidationLevel:          string(m.validationLevel),
+			RelayContentTypeCheck:    m.contentTypeCheck,
+			FailedDeliveryPolicy:     m.failedDeliveryPolicy,
+			RelayFailurePolicy:       m.relayFailurePolicy,
+			WithholdingPenalty:       m.withholdingPenalties.duration.String(),
+			WithholdingPenaltyPolicy: m.withholdingPenaltyPolicy,
+			MinActiveRelays:          m.minActiveRelays,
+			MinBiddingRelays:         m.minBiddingRelays,
+			MinBiddingRelaysPolicy:   m.minBiddingRelaysPolicy,
+			BidFilters:               make([]string, 0, len(m.bidFilters)),
+		},
+		Features: map[string]bool{
+			"relay_check":              m.relayCheck,
+			"relay_check_readiness":    m.relayCheckReadiness,
+			"prewarm_connections":      m.relayConnectionPrewarm,
+			"timing_header":            m.timingHeader,
+			"compat_shims":             m.compatShimsEnabled,
+			"consensus_version_shadow": m.consensusVersionShadow,
+			"infer_consensus_version":  m.inferConsensusVersion,
+			"strict_relay_schema":      m.strictRelaySchema,
+			"strict_pubkey_check":      m.strictPubkeyCheck,
+			"relay_order_header":       m.relayOrderHeader,
+			"serve_cached_bid":         m.serveCachedBid,
+			"debug_endpoints":          m.debugEndpoints,
+			"admin_endpoints":          m.adminEndpoints,
+			"api_auth":                 m.apiAuth != nil,
+			"client_allowlist":         m.clientAllowlist != nil && len(m.clientAllowlist.allowed) > 0,
+			"request_signing":          m.requestSigner != nil,
+			"cors":                     m.cors != nil,
+			"payment_audit":            m.paymentAuditor != nil,
+			"statsd":                   m.statsd != nil,
+			"chaos":                    m.chaos != nil || m.chaosResponseDelay > 0,
+			"fee_recipient_audit":      m.feeRecipients.audit,
+			"registration_signatures":  m.registrationDomain != nil,
+			"bid_timestamp_check":      m.checkBidTimestamp,
+			"alerting":                 m.alerts != nil,
+			"self_monitor":             m.selfMonitor != nil,
+		},
+		Files: filesConfigDump{
+			PayloadArtifactsDir: m.payloadArtifactsDir,
+			SessionSummaryFile:  m.sessionSummaryFile,
+		},
+		Secrets: secretsConfigDump{
+			AdminToken: redactSecret(m.adminToken),
+		},
+		Builders: buildersConfigDump{
+			Allowlist: len(m.builderAllowlist),
+			Denylist:  len(m.builderDenylist.list()),
+		},
+	}
+
+	for _, relay := range m.relays {
+		// The public key is reported separately, and the relay options are reported as fields
+		relayURL := *relay.URL
+		relayURL.User = nil
+		relayURL.RawQuery = ""
+		relayDump := relayConfigDump{
+			Name:            relay.Name(),
+			URL:             relayURL.String(),
+			PublicKey:       pubkey(relay.PublicKey.String()),
+			Label:           relay.Label,
+			Priority:        relay.Priority,
+			Stream:          relay.Stream,
+			SniffGzip:       relay.SniffGzip,
+			MinOverLocalPct: relay.MinOverLocalPct,
+			Weight:          relay.TieBreakWeight(),
+			Headers:         slices.Sorted(maps.Keys(relay.Headers)),
+		}
+		if relay.MinBid != nil {
+			relayDump.MinBid = relay.MinBid.String()
+		}
+		for _, additional := range relay.AdditionalPublicKeys {
+			relayDump.AdditionalPublicKeys = append(relayDump.AdditionalPublicKeys, pubkey(additional.String()))
+		}
+		for _, fork := range relay.Forks {
+			relayDump.Forks = append(relayDump.Forks, fork.String())
+		}
+		dump.Relays = append(dump.Relays, relayDump)
+	}
+	for _, monitor := range m.relayMonitors {
+		dump.RelayMonitors = append(dump.RelayMonitors, redactURL(monitor.URL))
+	}
+	for _, filter := range m.bidFilters {
+		dump.Selection.BidFilters = append(dump.Selection.BidFilters, filter.Name())
+	}
+
+	for fork, timeout := range m.getHeaderForkTimeouts {
+		if dump.Timeouts.GetHeaderByFork == nil {
+			dump.Timeouts.GetHeaderByFork = make(map[string]string)
+		}
+		dump.Timeouts.GetHeaderByFork[fork.String()] = timeout.String()
+	}
+
+	dump.Forks.GenesisTime = m.genesisTime
+	for _, activation := range m.forkSchedule {
+		if dump.Forks.ForkEpochs == nil {
+			dump.Forks.ForkEpochs = make(map[string]uint64)
+		}
+		dump.Forks.ForkEpochs[activation.fork.String()] = activation.epoch
+	}
+	dump.Forks.SecondsPerSlot = m.secondsPerSlot
+	if m.signingDomains != nil {
+		dump.Forks.CurrentForkVersion, dump.Forks.NextForkVersion, dump.Forks.ExtraForkVersions = m.signingDomains.forkVersions()
+		if dump.Forks.NextForkVersion != "" {
+			dump.Forks.NextForkEpoch = m.signingDomains.nextForkEpoch
+		}
+	}
+
+	if m.apiAuth != nil {
+		dump.Files.APIAuthTokenFile = m.apiAuth.tokenFile
+		dump.Secrets.APIAuthToken = redactedValue
+	}
+	if m.requestSigner != nil {
+		dump.Secrets.RequestSigningKey = redactedValue
+	}
+	if m.paymentAuditor != nil {
+		dump.Secrets.ExecutionRPCURL = redactURLString(m.paymentAuditor.rpcURL)
+	}
+	if m.chaos != nil {
+		dump.Files.ChaosConfig = m.chaos.path
+	}
+	if m.tenants != nil {
+		dump.Files.TenantsFile = m.tenants.path
+	}
+	if m.payloadOutcomes != nil {
+		dump.Files.PayloadOutcomesFile = m.payloadOutcomes.path
+	}
+	if m.bidMetadata != nil {
+		dump.Files.BidMetadataFile = m.bidMetadata.path
+	}
+	return dump
+}
+
+// handleDebugConfig returns the effective configuration with secrets redacted. Relay public keys are truncated,
+// unless requested in full with ?pubkeys=full.
+func (m *BoostService) handleDebugConfig(w http.ResponseWriter, req *http.Request) {
+	m.respondOK(w, m.configDump(req.URL.Query().Get("pubkeys") == "full"))
+}
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/flashbots/mev-boost/server/mock"
+	"github.com/flashbots/mev-boost/server/params"
+	"github.com/flashbots/mev-boost/server/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigDumpRedactsSecrets(t *testing.T) {
+	const (
+		apiToken    = "api-token-secret"
+		adminToken  = "admin-token-secret"
+		signingKey  = "0x4242424242424242424242424242424242424242424242424242424242424242"
+		rpcPassword = "rpc-password-secret"
+		rpcAPIKey   = "rpc-path-key-secret"
+		monitorPass = "monitor-password-secret"
+	)
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte(apiToken), 0o600))
+	relay := mock.NewRelay(t)
+	monitor, err := url.Parse("https://user:" + monitorPass + "@monitor.example.com/" + rpcAPIKey)
+	require.NoError(t, err)
+
+	m, err := NewBoostService(BoostServiceOpts{
+		Log:                   mock.TestLog,
+		ListenAddr:            "localhost:12345",
+		Relays:                []types.RelayEntry{relay.RelayEntry},
+		RelayMonitors:         []*url.URL{monitor},
+		GenesisForkVersionHex: "0x00000000",
+		RelayMinBid:           types.IntToU256(12345),
+		APIAuthTokenFile:      tokenFile,
+		AdminToken:            adminToken,
+		RequestSigningKey:     signingKey,
+		RequestSigningScheme:  RequestSigningSchemeEd25519,
+		ExecutionRPCURL:       "https://user:" + rpcPassword + "@rpc.example.com/v3/" + rpcAPIKey,
+	})
+	require.NoError(t, err)
+
+	body, err := json.Marshal(m.configDump(false))
+	require.NoError(t, err)
+	for _, secret := range []string{apiToken, adminToken, signingKey, strings.TrimPrefix(signingKey, "0x"), rpcPassword, rpcAPIKey, monitorPass} {
+		require.NotContains(t, string(body), secret)
+	}
+	require.NotContains(t, string(body), relay.RelayEntry.PublicKey.String(), "relay public keys are truncated")
+
+	dump := m.configDump(false)
+	require.Equal(t, redactedValue, dump.Secrets.APIAuthToken)
+	require.Equal(t, tokenFile, dump.Files.APIAuthTokenFile)
+	require.Equal(t, redactedValue, dump.Secrets.AdminToken)
+	require.Equal(t, redactedValue, dump.Secrets.RequestSigningKey)
+	require.Equal(t, "https://rpc.example.com/"+redactedValue, dump.Secrets.ExecutionRPCURL)
+	require.Equal(t, []string{"https://monitor.example.com/" + redactedValue}, dump.RelayMonitors)
+	require.Equal(t, relay.Server.URL, dump.Relays[0].URL)
+
+	dump = m.configDump(true)
+	require.Equal(t, relay.RelayEntry.PublicKey.String(), dump.Relays[0].PublicKey)
+}
+
+func TestHandleDebugConfig(t *testing.T) {
+	backend := newTestBackend(t, 2, time.Second)
+	rr := backend.request(t, http.MethodGet, params.PathDebugConfig, nil)
+	require.Equal(t, http.StatusNotFound, rr.Code, "debug endpoints are disabled by default")
+
+	backend.boost.debugEndpoints = true
+	dump := func(t *testing.T) configDump {
+		t.Helper()
+		rr := httptest.NewRecorder()
+		backend.boost.getRouter().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, params.PathDebugConfig, nil))
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		var dump configDump
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &dump))
+		return dump
+	}
+
+	d := dump(t)
+	require.Equal(t, "localhost:12345", d.ListenAddr)
+	require.Len(t, d.Relays, 2)
+	require.Equal(t, "12345", d.Selection.MinBid)
+	require.Equal(t, "1s", d.Timeouts.GetHeader)
+	require.Equal(t, "0x00000000", d.Forks.CurrentForkVersion)
+	require.True(t, d.Features["relay_check"])
+	require.Equal(t, 0, d.Builders.Denylist)
+
+	// Changes made at runtime are reflected
+	backend.boost.builderDenylist.add([]phase0.BLSPubKey{{0x01}})
+	require.Equal(t, 1, dump(t).Builders.Denylist)
+}
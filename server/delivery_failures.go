@@ -0,0 +1,112 @@
+package server
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/flashbots/mev-boost/server/types"
+)
+
+// Policies for bids with a block hash for which the same relay previously failed to deliver the payload
+const (
+	FailedDeliveryPolicyDeprioritize = "deprioritize"
+	FailedDeliveryPolicyReject       = "reject"
+)
+
+// failedDeliveryMaxSlotAge is the number of slots after which a failed delivery is forgotten
+const failedDeliveryMaxSlotAge = 64
+
+// failedDelivery is a block hash for which a relay did not deliver the payload
+type failedDelivery struct {
+	Relay     string        `json:"relay"`
+	Slot      phase0.Slot   `json:"slot,string"`
+	BlockHash phase0.Hash32 `json:"block_hash"`
+}
+
+// failedDeliveries keeps track of failed payload deliveries per relay
+type failedDeliveries struct {
+	mu      sync.Mutex
+	entries map[string]map[phase0.Hash32]phase0.Slot // relay -> block hash -> slot
+	labels  map[string]string                        // relay -> relay label
+}
+
+func newFailedDeliveries() *failedDeliveries {
+	return &failedDeliveries{
+		entries: make(map[string]map[phase0.Hash32]phase0.Slot),
+		labels:  make(map[string]string),
+	}
+}
+
+// record remembers that the relays failed to deliver the payload for the block hash, and forgets
+// failed deliveries which are too old
+func (f *failedDeliveries) record(slot phase0.Slot, blockHash phase0.Hash32, relays []types.RelayEntry) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, relay := range relays {
+		key := relay.String()
+		if f.entries[key] == nil {
+			f.entries[key] = make(map[phase0.Hash32]phase0.Slot)
+			f.labels[key] = relayLabel(relay)
+		}
+		f.entries[key][blockHash] = slot
+	}
+
+	for key, hashes := range f.entries {
+		for hash, failedSlot := range hashes {
+			if failedSlot+failedDeliveryMaxSlotAge < slot {
+				delete(hashes, hash)
+			}
+		}
+		if len(hashes) == 0 {
+			delete(f.entries, key)
+			delete(f.labels, key)
+		}
+	}
+}
+
+// failed returns true if the relay failed to deliver the payload for the block hash
+func (f *failedDeliveries) failed(relay types.RelayEntry, blockHash phase0.Hash32) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, ok := f.entries[relay.String()][blockHash]
+	return ok
+}
+
+// count returns the number of recent failed deliveries of the relay
+func (f *failedDeliveries) count(relay types.RelayEntry) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.entries[relay.String()])
+}
+
+// list returns all remembered failed deliveries by relay label, sorted by slot and relay
+func (f *failedDeliveries) list() []failedDelivery {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	deliveries := []failedDelivery{}
+	for relay, hashes := range f.entries {
+		for hash, slot := range hashes {
+			deliveries = append(deliveries, failedDelivery{Relay: f.labels[relay], Slot: slot, BlockHash: hash})
+		}
+	}
+	slices.SortFunc(deliveries, func(a, b failedDelivery) int {
+		if a.Slot != b.Slot {
+			return int(a.Slot) - int(b.Slot)
+		}
+		if a.Relay != b.Relay {
+			return strings.Compare(a.Relay, b.Relay)
+		}
+		return strings.Compare(a.BlockHash.String(), b.BlockHash.String())
+	})
+	return deliveries
+}
+
+// handleDebugFailedDeliveries returns the block hashes for which relays recently failed to deliver the payload
+func (m *BoostService) handleDebugFailedDeliveries(w http.ResponseWriter, _ *http.Request) {
+	m.respondOK(w, m.failedDeliveries.list())
+}
@@ -0,0 +1,118 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	eth2ApiV1Deneb "github.com/attestantio/go-eth2-client/api/v1/deneb"
+	"github.com/attestantio/go-eth2-client/spec"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/flashbots/mev-boost/server/mock"
+	"github.com/flashbots/mev-boost/server/params"
+	"github.com/flashbots/mev-boost/server/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFailedDeliveries(t *testing.T) {
+	relayA, err := types.NewRelayEntry("http://0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249@a.com")
+	require.NoError(t, err)
+	relayB, err := types.NewRelayEntry("http://0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249@b.com?label=relay-b")
+	require.NoError(t, err)
+	hash1 := phase0.Hash32{0x01}
+	hash2 := phase0.Hash32{0x02}
+
+	f := newFailedDeliveries()
+	f.record(100, hash1, []types.RelayEntry{relayA})
+	require.True(t, f.failed(relayA, hash1))
+	require.False(t, f.failed(relayB, hash1))
+	require.False(t, f.failed(relayA, hash2))
+
+	// Old failed deliveries are forgotten
+	f.record(100+failedDeliveryMaxSlotAge, hash2, []types.RelayEntry{relayB})
+	require.True(t, f.failed(relayA, hash1))
+	f.record(101+failedDeliveryMaxSlotAge, hash2, []types.RelayEntry{relayB})
+	require.False(t, f.failed(relayA, hash1))
+	require.Equal(t, []failedDelivery{{Relay: "relay-b", Slot: 101 + failedDeliveryMaxSlotAge, BlockHash: hash2}}, f.list())
+}
+
+func TestGetHeaderAfterFailedDelivery(t *testing.T) {
+	const pubkey = "0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249"
+
+	jsonFile, err := os.Open("../testdata/signed-blinded-beacon-block-deneb.json")
+	require.NoError(t, err)
+	defer jsonFile.Close()
+	block := new(eth2ApiV1Deneb.SignedBlindedBeaconBlock)
+	require.NoError(t, DecodeJSON(jsonFile, block))
+	header := block.Message.Body.ExecutionPayloadHeader
+	path := getHeaderPath(uint64(block.Message.Slot), header.ParentHash, mock.HexToPubkey(pubkey))
+
+	// bidAndWithhold makes relay 0 bid for the block, and withhold the payload
+	bidAndWithhold := func(t *testing.T, backend *testBackend) {
+		t.Helper()
+		backend.relays[0].GetHeaderResponse = backend.relays[0].MakeGetHeaderResponse(
+			20000, header.BlockHash.String(), header.ParentHash.String(), pubkey, spec.DataVersionDeneb)
+		backend.relays[0].OverrideHandleGetPayload(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		})
+		for _, relay := range backend.relays[1:] {
+			relay.GetHeaderResponse = relay.MakeGetHeaderResponse(
+				15000, "0xcf8e0d4e9587369b2301d0790347320302cc0943d5a1884560367e8208d920f2", header.ParentHash.String(), pubkey, spec.DataVersionDeneb)
+		}
+
+		rr := backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		require.Contains(t, rr.Body.String(), header.BlockHash.String())
+
+		rr = backend.request(t, http.MethodPost, params.PathGetPayload, block)
+		require.Equal(t, http.StatusBadGateway, rr.Code, rr.Body.String())
+	}
+
+	t.Run("Same block hash is rejected", func(t *testing.T) {
+		backend := newTestBackend(t, 1, 250*time.Millisecond)
+		backend.boost.failedDeliveryPolicy = FailedDeliveryPolicyReject
+		bidAndWithhold(t, backend)
+
+		rr := backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, http.StatusNoContent, rr.Code, rr.Body.String())
+	})
+
+	t.Run("Same block hash is deprioritized", func(t *testing.T) {
+		backend := newTestBackend(t, 2, 250*time.Millisecond)
+		bidAndWithhold(t, backend)
+
+		rr := backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		require.Contains(t, rr.Body.String(), "0xcf8e0d4e9587369b2301d0790347320302cc0943d5a1884560367e8208d920f2")
+
+		// Without other bids, the deprioritized bid is still used
+		backend.relays[1].GetHeaderResponse = nil
+		backend.relays[1].OverrideHandleGetHeader(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		})
+		rr = backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		require.Contains(t, rr.Body.String(), header.BlockHash.String())
+	})
+
+	t.Run("Failed deliveries are shown on the debug endpoint", func(t *testing.T) {
+		backend := newTestBackend(t, 1, 250*time.Millisecond)
+		bidAndWithhold(t, backend)
+
+		rr := backend.request(t, http.MethodGet, params.PathDebugFailedDeliveries, nil)
+		require.Equal(t, http.StatusNotFound, rr.Code)
+
+		backend.boost.debugEndpoints = true
+		rr = backend.request(t, http.MethodGet, params.PathDebugFailedDeliveries, nil)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		var deliveries []failedDelivery
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &deliveries))
+		require.Equal(t, []failedDelivery{{
+			Relay:     backend.relays[0].RelayEntry.Name(),
+			Slot:      block.Message.Slot,
+			BlockHash: header.BlockHash,
+		}}, deliveries)
+	})
+}
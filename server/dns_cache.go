@@ -0,0 +1,93 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+var errNoRelayAddresses = errors.New("no addresses for relay host")
+
+// hostResolver resolves a hostname, it is satisfied by net.Resolver
+type hostResolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+// dnsCache reuses the resolved addresses of relay hosts for the TTL. Hosts are resolved again after the TTL,
+// so relays which migrate are followed. If resolving again fails, the expired addresses are used until the
+// next attempt succeeds.
+type dnsCache struct {
+	ttl      time.Duration
+	resolver hostResolver
+	dialer   *net.Dialer
+	log      *logrus.Entry
+
+	mu      sync.Mutex
+	entries map[string]dnsCacheEntry
+}
+
+type dnsCacheEntry struct {
+	addrs   []string
+	expires time.Time
+}
+
+func newDNSCache(ttl time.Duration, dialer *net.Dialer, log *logrus.Entry) *dnsCache {
+	return &dnsCache{
+		ttl:      ttl,
+		resolver: net.DefaultResolver,
+		dialer:   dialer,
+		log:      log,
+		entries:  make(map[string]dnsCacheEntry),
+	}
+}
+
+// lookup returns the addresses of the host, resolving it only if the cached addresses expired
+func (c *dnsCache) lookup(ctx context.Context, host string, now time.Time) ([]string, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[host]
+	c.mu.Unlock()
+	if ok && now.Before(entry.expires) {
+		return entry.addrs, nil
+	}
+
+	addrs, err := c.resolver.LookupHost(ctx, host)
+	if err == nil && len(addrs) == 0 {
+		err = errNoRelayAddresses
+	}
+	if err != nil {
+		if ok {
+			c.log.WithError(err).WithField("host", host).Warn("could not resolve relay host, using the expired addresses")
+			return entry.addrs, nil
+		}
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.entries[host] = dnsCacheEntry{addrs: addrs, expires: now.Add(c.ttl)}
+	c.mu.Unlock()
+	return addrs, nil
+}
+
+// DialContext dials the cached addresses of the host in order until one connects
+func (c *dnsCache) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil || net.ParseIP(host) != nil {
+		return c.dialer.DialContext(ctx, network, address)
+	}
+	addrs, err := c.lookup(ctx, host, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	for _, addr := range addrs {
+		var conn net.Conn
+		conn, err = c.dialer.DialContext(ctx, network, net.JoinHostPort(addr, port))
+		if err == nil {
+			return conn, nil
+		}
+	}
+	return nil, err
+}
@@ -0,0 +1,105 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/flashbots/mev-boost/server/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var errTestDNS = errors.New("dns server unavailable")
+
+// fakeResolver resolves hosts with the addresses of the map and counts the lookups
+type fakeResolver struct {
+	addrs   map[string][]string
+	err     error
+	lookups int
+}
+
+func (r *fakeResolver) LookupHost(_ context.Context, host string) ([]string, error) {
+	r.lookups++
+	if r.err != nil {
+		return nil, r.err
+	}
+	return r.addrs[host], nil
+}
+
+func TestDNSCacheLookup(t *testing.T) {
+	resolver := &fakeResolver{addrs: map[string][]string{"relay.test": {"10.0.0.1"}}}
+	cache := newDNSCache(time.Minute, &net.Dialer{}, mock.TestLog)
+	cache.resolver = resolver
+	now := time.Now()
+	ctx := context.Background()
+
+	addrs, err := cache.lookup(ctx, "relay.test", now)
+	require.NoError(t, err)
+	require.Equal(t, []string{"10.0.0.1"}, addrs)
+
+	// Within the TTL, the cached addresses are used
+	addrs, err = cache.lookup(ctx, "relay.test", now.Add(59*time.Second))
+	require.NoError(t, err)
+	require.Equal(t, []string{"10.0.0.1"}, addrs)
+	require.Equal(t, 1, resolver.lookups)
+
+	// After the TTL, the relay migrated
+	resolver.addrs["relay.test"] = []string{"10.0.0.2"}
+	addrs, err = cache.lookup(ctx, "relay.test", now.Add(time.Minute))
+	require.NoError(t, err)
+	require.Equal(t, []string{"10.0.0.2"}, addrs)
+	require.Equal(t, 2, resolver.lookups)
+
+	// If resolving again fails, the expired addresses are used
+	resolver.err = errTestDNS
+	addrs, err = cache.lookup(ctx, "relay.test", now.Add(3*time.Minute))
+	require.NoError(t, err)
+	require.Equal(t, []string{"10.0.0.2"}, addrs)
+	require.Equal(t, 3, resolver.lookups)
+
+	// Hosts which were never resolved fail
+	_, err = cache.lookup(ctx, "other.test", now)
+	require.ErrorIs(t, err, errTestDNS)
+
+	resolver.err = nil
+	_, err = cache.lookup(ctx, "other.test", now)
+	require.ErrorIs(t, err, errNoRelayAddresses)
+}
+
+func TestDNSCacheTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	_, port, err := net.SplitHostPort(serverURL.Host)
+	require.NoError(t, err)
+
+	resolver := &fakeResolver{addrs: map[string][]string{"relay.test": {"127.0.0.1"}}}
+	cache := newDNSCache(time.Minute, &net.Dialer{}, mock.TestLog)
+	cache.resolver = resolver
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = cache.DialContext
+	transport.DisableKeepAlives = true
+	client := http.Client{Transport: transport}
+
+	for range 3 {
+		resp, err := client.Get("http://" + net.JoinHostPort("relay.test", port))
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+	require.Equal(t, 1, resolver.lookups)
+
+	// IP addresses are dialed directly
+	resp, err := client.Get(server.URL)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, 1, resolver.lookups)
+}
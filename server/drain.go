@@ -0,0 +1,176 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/sirupsen/logrus"
+)
+
+var errDraining = errors.New("mev-boost is draining before shutdown, no new bids are served")
+
+// payloadDrain stops new auctions before a shutdown, and tracks the in-flight getPayload requests and the slots of
+// the served bids so the proposals which already have a header are still served
+type payloadDrain struct {
+	draining atomic.Bool
+
+	mu       sync.Mutex
+	inFlight int
+	pending  map[phase0.Slot]time.Time // end of the slots of served bids without a getPayload request yet
+	idle     chan struct{}             // closed once draining without in-flight getPayload requests or pending slots
+	closed   bool
+}
+
+// start starts draining and returns the number of in-flight getPayload requests, and of slots with a served bid
+// which are still waiting for their getPayload request
+func (d *payloadDrain) start() (inFlight, pendingSlots int) {
+	d.draining.Store(true)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, end := range d.pending {
+		d.expireLocked(end)
+	}
+	d.closeIdleLocked()
+	return d.inFlight, len(d.pending)
+}
+
+func (d *payloadDrain) begin() {
+	d.mu.Lock()
+	d.inFlight++
+	d.mu.Unlock()
+}
+
+func (d *payloadDrain) end() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.inFlight--
+	d.closeIdleLocked()
+}
+
+// served records that a bid of the slot is served, after which its getPayload request is expected until the slot
+// ends. It returns false without recording the slot once draining started, as the drain may be done already.
+func (d *payloadDrain) served(slot phase0.Slot, end time.Time) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.draining.Load() {
+		return false
+	}
+	d.pruneLocked(time.Now())
+	if d.pending == nil {
+		d.pending = make(map[phase0.Slot]time.Time)
+	}
+	d.pending[slot] = end
+	return true
+}
+
+// payloadArrived records that the getPayload request of the slot arrived, which is then tracked as in-flight
+func (d *payloadDrain) payloadArrived(slot phase0.Slot) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.pending, slot)
+	d.closeIdleLocked()
+}
+
+// expireLocked checks again whether the drain is done once the slot ends, d.mu must be held
+func (d *payloadDrain) expireLocked(end time.Time) {
+	time.AfterFunc(time.Until(end), func() {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		d.closeIdleLocked()
+	})
+}
+
+// pruneLocked forgets the slots which ended, d.mu must be held
+func (d *payloadDrain) pruneLocked(now time.Time) {
+	for slot, end := range d.pending {
+		if !now.Before(end) {
+			delete(d.pending, slot)
+		}
+	}
+}
+
+// closeIdleLocked closes the idle channel while draining without in-flight requests or pending slots, d.mu must be held
+func (d *payloadDrain) closeIdleLocked() {
+	if d.closed || d.inFlight > 0 || !d.draining.Load() {
+		return
+	}
+	d.pruneLocked(time.Now())
+	if len(d.pending) > 0 {
+		return
+	}
+	if d.idle == nil {
+		d.idle = make(chan struct{})
+	}
+	close(d.idle)
+	d.closed = true
+}
+
+// drained returns a channel which is closed once draining without in-flight getPayload requests or pending slots
+func (d *payloadDrain) drained() <-chan struct{} {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.idle == nil {
+		d.idle = make(chan struct{})
+	}
+	return d.idle
+}
+
+// wait waits until the in-flight getPayload requests are done and the pending slots got their getPayload request or
+// ended, or the context is done
+func (d *payloadDrain) wait(ctx context.Context) error {
+	select {
+	case <-d.drained():
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Drained returns a channel which is closed once the service drains, has no in-flight getPayload requests, and
+// every served bid got its getPayload request or its slot ended, after which it can be stopped without missing
+// a proposal
+func (m *BoostService) Drained() <-chan struct{} {
+	return m.drain.drained()
+}
+
+// refuseWhileDraining rejects the requests of the handler while the service drains
+func (m *BoostService) refuseWhileDraining(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if m.drain.draining.Load() {
+			m.respondDraining(w)
+			return
+		}
+		next(w, req)
+	}
+}
+
+// respondDraining rejects a request because the service drains
+func (m *BoostService) respondDraining(w http.ResponseWriter) {
+	w.Header().Set("Retry-After", "1")
+	m.respondError(w, http.StatusServiceUnavailable, errDraining.Error())
+}
+
+// trackPayload tracks the requests of the handler as in-flight getPayload requests
+func (m *BoostService) trackPayload(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		m.drain.begin()
+		defer m.drain.end()
+		next(w, req)
+	}
+}
+
+// handleAdminDrain starts draining: new getHeader requests are rejected, and Drained is closed once the
+// in-flight getPayload requests are done and the slots of the served bids got their getPayload request or ended
+func (m *BoostService) handleAdminDrain(w http.ResponseWriter, _ *http.Request) {
+	inFlight, pendingSlots := m.drain.start()
+	m.log.WithFields(logrus.Fields{
+		"inFlightPayloads": inFlight,
+		"pendingSlots":     pendingSlots,
+	}).Warn("draining, no new getHeader requests are served")
+	m.respondOK(w, map[string]any{"draining": true, "inflight_payloads": inFlight, "pending_slots": pendingSlots})
+}
@@ -0,0 +1,200 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/flashbots/mev-boost/server/mock"
+	"github.com/flashbots/mev-boost/server/params"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDrain(t *testing.T) {
+	block, response := loadDenebBlock(t)
+	backend := newTestBackend(t, 1, time.Second)
+	backend.relays[0].GetPayloadResponse = response
+	backend.relays[0].ResponseDelay = 200 * time.Millisecond
+	headerPath := getHeaderPath(1, mock.HexToHash("0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7"), mock.HexToPubkey(feeRecipientTestPubkey))
+
+	rr := backend.request(t, http.MethodPost, params.PathAdminDrain, nil)
+	require.Equal(t, http.StatusNotFound, rr.Code, "admin endpoints are disabled by default")
+	backend.boost.adminEndpoints = true
+
+	// A proposal which got its header before the drain is still served
+	payloadDone := make(chan int, 1)
+	go func() {
+		payloadDone <- backend.request(t, http.MethodPost, params.PathGetPayload, block).Code
+	}()
+	require.Eventually(t, func() bool {
+		return backend.relays[0].GetRequestCount(params.PathGetPayload) == 1
+	}, time.Second, 5*time.Millisecond)
+
+	rr = backend.request(t, http.MethodPost, params.PathAdminDrain, nil)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var resp struct {
+		Draining         bool `json:"draining"`
+		InFlightPayloads int  `json:"inflight_payloads"`
+	}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	require.True(t, resp.Draining)
+	require.Equal(t, 1, resp.InFlightPayloads)
+
+	rr = backend.request(t, http.MethodGet, headerPath, nil)
+	require.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	require.Contains(t, rr.Body.String(), errDraining.Error())
+	select {
+	case <-backend.boost.Drained():
+		t.Fatal("drained with an in-flight getPayload request")
+	default:
+	}
+
+	require.Equal(t, http.StatusOK, <-payloadDone)
+	select {
+	case <-backend.boost.Drained():
+	case <-time.After(time.Second):
+		t.Fatal("not drained after the getPayload request is done")
+	}
+}
+
+func TestDrainWaitsForServedBids(t *testing.T) {
+	backend := newTestBackend(t, 1, time.Second)
+	backend.boost.adminEndpoints = true
+	// Slot 1 ends in two slots
+	backend.boost.genesisTime = uint64(time.Now().Unix())
+	headerPath := getHeaderPath(1, mock.HexToHash("0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7"), mock.HexToPubkey(feeRecipientTestPubkey))
+	rr := backend.request(t, http.MethodGet, headerPath, nil)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	// The proposer which got the header sends its getPayload request after the drain started
+	rr = backend.request(t, http.MethodPost, params.PathAdminDrain, nil)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var resp struct {
+		PendingSlots int `json:"pending_slots"`
+	}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	require.Equal(t, 1, resp.PendingSlots)
+	select {
+	case <-backend.boost.Drained():
+		t.Fatal("drained before the getPayload request of a served bid")
+	default:
+	}
+
+	backend.boost.drain.payloadArrived(1)
+	select {
+	case <-backend.boost.Drained():
+	case <-time.After(time.Second):
+		t.Fatal("not drained after the getPayload request of the served bid")
+	}
+
+	t.Run("until the slot ends", func(t *testing.T) {
+		var drain payloadDrain
+		require.True(t, drain.served(1, time.Now().Add(100*time.Millisecond)))
+		_, pendingSlots := drain.start()
+		require.Equal(t, 1, pendingSlots)
+		select {
+		case <-drain.drained():
+			t.Fatal("drained before the slot of the served bid ended")
+		default:
+		}
+		select {
+		case <-drain.drained():
+		case <-time.After(time.Second):
+			t.Fatal("not drained after the slot of the served bid ended")
+		}
+	})
+
+	t.Run("ended slots are not waited for", func(t *testing.T) {
+		var drain payloadDrain
+		require.True(t, drain.served(1, time.Now().Add(-time.Second)))
+		_, pendingSlots := drain.start()
+		require.Zero(t, pendingSlots)
+		select {
+		case <-drain.drained():
+		default:
+			t.Fatal("not drained without pending slots")
+		}
+	})
+
+	t.Run("bids of auctions during which draining started are refused", func(t *testing.T) {
+		backend := newTestBackend(t, 1, time.Second)
+		backend.boost.adminEndpoints = true
+		backend.boost.genesisTime = uint64(time.Now().Unix())
+		backend.relays[0].ResponseDelay = 200 * time.Millisecond
+		headerDone := make(chan int, 1)
+		go func() {
+			headerDone <- backend.request(t, http.MethodGet, headerPath, nil).Code
+		}()
+		require.Eventually(t, func() bool {
+			return backend.relays[0].GetRequestCount(headerPath) == 1
+		}, time.Second, 5*time.Millisecond)
+
+		rr := backend.request(t, http.MethodPost, params.PathAdminDrain, nil)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		<-backend.boost.Drained()
+		require.Equal(t, http.StatusServiceUnavailable, <-headerDone)
+	})
+}
+
+func TestStopWaitsForInFlightPayloads(t *testing.T) {
+	backend := newTestBackend(t, 1, time.Second)
+	backend.boost.drain.begin()
+	released := make(chan struct{})
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		close(released)
+		backend.boost.drain.end()
+	}()
+
+	require.NoError(t, backend.boost.Stop(context.Background()))
+	select {
+	case <-released:
+	default:
+		t.Fatal("stopped before the in-flight getPayload request was done")
+	}
+	require.Equal(t, http.StatusServiceUnavailable, backend.request(t, http.MethodGet, "/eth/v1/builder/header/1/0x00/0x00", nil).Code)
+
+	t.Run("until the context is done", func(t *testing.T) {
+		backend := newTestBackend(t, 1, time.Second)
+		backend.boost.drain.begin()
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		require.NoError(t, backend.boost.Stop(ctx))
+	})
+
+	t.Run("server requests finish after a drain timeout", func(t *testing.T) {
+		backend := newTestBackend(t, 1, time.Second)
+		started := make(chan struct{})
+		srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) { //nolint:gosec
+			close(started)
+			time.Sleep(100 * time.Millisecond)
+			w.WriteHeader(http.StatusOK)
+		})}
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		go srv.Serve(ln) //nolint:errcheck
+		backend.boost.srv = srv
+
+		responded := make(chan int, 1)
+		go func() {
+			resp, err := http.Get("http://" + ln.Addr().String()) //nolint:noctx
+			if err != nil {
+				responded <- 0
+				return
+			}
+			resp.Body.Close()
+			responded <- resp.StatusCode
+		}()
+		<-started
+
+		// The drain wait uses up its context, the server still gets to finish the request
+		backend.boost.drain.begin()
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		require.NoError(t, backend.boost.Stop(ctx))
+		require.Equal(t, http.StatusOK, <-responded)
+	})
+}
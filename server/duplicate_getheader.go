@@ -0,0 +1,51 @@
+package server
+
+import (
+	"slices"
+	"sync"
+
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+)
+
+// getHeaderCallersMaxSlotAge is the number of slots after which the callers of getHeader are forgotten
+const getHeaderCallersMaxSlotAge = 2
+
+// getHeaderCallerKey identifies the getHeader requests of a validator for a slot
+type getHeaderCallerKey struct {
+	slot   phase0.Slot
+	pubkey string
+}
+
+// getHeaderCallers remembers the user agents which requested a header for each slot and validator. Several beacon
+// nodes requesting a header for the same validator hint at a redundant setup which could propose twice.
+type getHeaderCallers struct {
+	mu         sync.Mutex
+	userAgents map[getHeaderCallerKey][]string
+}
+
+func newGetHeaderCallers() *getHeaderCallers {
+	return &getHeaderCallers{
+		userAgents: make(map[getHeaderCallerKey][]string),
+	}
+}
+
+// record remembers the user agent, and returns the other user agents which requested a header for the same slot
+// and validator before. Nothing is returned for a user agent which was already recorded.
+func (c *getHeaderCallers) record(slot phase0.Slot, pubkey, userAgent string) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key := range c.userAgents {
+		if key.slot+getHeaderCallersMaxSlotAge < slot {
+			delete(c.userAgents, key)
+		}
+	}
+
+	key := getHeaderCallerKey{slot: slot, pubkey: pubkey}
+	others := c.userAgents[key]
+	if slices.Contains(others, userAgent) {
+		return nil
+	}
+	c.userAgents[key] = append(others, userAgent)
+	return slices.Clone(others)
+}
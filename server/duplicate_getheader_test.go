@@ -0,0 +1,48 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/flashbots/mev-boost/server/mock"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetHeaderCallers(t *testing.T) {
+	callers := newGetHeaderCallers()
+	require.Empty(t, callers.record(1, "0x01", "Lighthouse/v5.3.0"))
+	require.Empty(t, callers.record(1, "0x01", "Lighthouse/v5.3.0"), "the same client requesting again is not a duplicate")
+	require.Empty(t, callers.record(1, "0x02", "Teku/v24.10.0"), "another validator is not a duplicate")
+	require.Equal(t, []string{"Lighthouse/v5.3.0"}, callers.record(1, "0x01", "Teku/v24.10.0"))
+	require.Equal(t, []string{"Lighthouse/v5.3.0", "Teku/v24.10.0"}, callers.record(1, "0x01", "Prysm/v5.1.0"))
+
+	// Old slots are forgotten
+	callers.record(1+getHeaderCallersMaxSlotAge+1, "0x03", "Lighthouse/v5.3.0")
+	require.Empty(t, callers.record(1, "0x01", "Nimbus/v24.10.0"))
+}
+
+func TestGetHeaderFromSeveralClients(t *testing.T) {
+	hash := mock.HexToHash("0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7")
+	pubkey := mock.HexToPubkey(
+		"0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249")
+	backend := newTestBackend(t, 1, time.Second)
+
+	// getHeader requests the header as the client with the user agent, and returns the number of detected duplicates
+	getHeader := func(ua string) float64 {
+		t.Helper()
+		before := testutil.ToFloat64(duplicateGetHeaderRequests)
+		req := httptest.NewRequest(http.MethodGet, getHeaderPath(1, hash, pubkey), nil)
+		req.Header.Set("User-Agent", ua)
+		rr := httptest.NewRecorder()
+		backend.boost.getRouter().ServeHTTP(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		return testutil.ToFloat64(duplicateGetHeaderRequests) - before
+	}
+
+	require.InDelta(t, 0, getHeader("Lighthouse/v5.3.0"), 0)
+	require.InDelta(t, 0, getHeader("Lighthouse/v5.3.0"), 0)
+	require.InDelta(t, 1, getHeader("Teku/v24.10.0"), 0)
+}
@@ -0,0 +1,234 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	builderApiV1 "github.com/attestantio/go-builder-client/api/v1"
+	"github.com/attestantio/go-eth2-client/spec/bellatrix"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/flashbots/go-boost-utils/utils"
+	"github.com/flashbots/mev-boost/server/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+// feeRecipientHistorySize is the number of fee recipients remembered per validator in audit mode
+const feeRecipientHistorySize = 10
+
+var (
+	errFeeRecipientNotApproved = errors.New("fee recipient of the registrations is not approved")
+
+	bidFeeRecipientMismatches = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "bid_fee_recipient_mismatches_total",
+		Help: "Number of served bids whose fee recipient differs from the one the validator registered most recently",
+	}, []string{"relay"})
+	feeRecipientChanges = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "fee_recipient_changes_total",
+		Help: "Number of registrations which changed the fee recipient of a validator, counted in fee recipient audit mode",
+	})
+	feeRecipientRejections = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "fee_recipient_registrations_rejected_total",
+		Help: "Number of registrations which were not forwarded to relays because their fee recipient is not approved",
+	})
+)
+
+// feeRecipientChange is an entry of the fee recipient history of a validator
+type feeRecipientChange struct {
+	FeeRecipient string    `json:"fee_recipient"`
+	Timestamp    time.Time `json:"timestamp"` // of the registration message
+	ReceivedAt   time.Time `json:"received_at"`
+}
+
+// registeredFeeRecipient is the fee recipient of the most recent registration of a validator
+type registeredFeeRecipient struct {
+	feeRecipient bellatrix.ExecutionAddress
+	timestamp    time.Time // of the registration message
+	receivedAt   time.Time
+
+	// history are the fee recipients of the validator in audit mode, oldest first
+	history []feeRecipientChange
+}
+
+// feeRecipientUpdate is a change of the fee recipient of a validator
+type feeRecipientUpdate struct {
+	pubkey   phase0.BLSPubKey
+	previous bellatrix.ExecutionAddress
+	current  bellatrix.ExecutionAddress
+}
+
+// registeredFeeRecipients remembers the fee recipient each validator registered most recently, and in audit mode
+// the last feeRecipientHistorySize fee recipients of each validator
+type registeredFeeRecipients struct {
+	mu      sync.Mutex
+	audit   bool
+	entries map[phase0.BLSPubKey]registeredFeeRecipient
+}
+
+func newRegisteredFeeRecipients(audit bool) *registeredFeeRecipients {
+	return &registeredFeeRecipients{
+		audit:   audit,
+		entries: make(map[phase0.BLSPubKey]registeredFeeRecipient),
+	}
+}
+
+// record remembers the fee recipients of the registrations, unless a more recent registration of the validator
+// is known, and forgets validators which were not registered again within registrationCoverageMaxAge. It returns
+// the changes of fee recipients.
+func (r *registeredFeeRecipients) record(registrations []builderApiV1.SignedValidatorRegistration, now time.Time) []feeRecipientUpdate {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var updates []feeRecipientUpdate
+	for _, registration := range registrations {
+		if registration.Message == nil {
+			continue
+		}
+		pubkey := registration.Message.Pubkey
+		previous, ok := r.entries[pubkey]
+		if ok && previous.timestamp.After(registration.Message.Timestamp) {
+			continue
+		}
+		entry := registeredFeeRecipient{
+			feeRecipient: registration.Message.FeeRecipient,
+			timestamp:    registration.Message.Timestamp,
+			receivedAt:   now,
+			history:      previous.history,
+		}
+		changed := ok && previous.feeRecipient != entry.feeRecipient
+		if changed {
+			updates = append(updates, feeRecipientUpdate{pubkey, previous.feeRecipient, entry.feeRecipient})
+		}
+		if r.audit && (!ok || changed) {
+			entry.history = append(entry.history, feeRecipientChange{
+				FeeRecipient: entry.feeRecipient.String(),
+				Timestamp:    entry.timestamp,
+				ReceivedAt:   now,
+			})
+			if len(entry.history) > feeRecipientHistorySize {
+				entry.history = entry.history[len(entry.history)-feeRecipientHistorySize:]
+			}
+		}
+		r.entries[pubkey] = entry
+	}
+	for pubkey, entry := range r.entries {
+		if now.Sub(entry.receivedAt) > registrationCoverageMaxAge {
+			delete(r.entries, pubkey)
+		}
+	}
+	return updates
+}
+
+// lookup returns the fee recipient the validator registered most recently
+func (r *registeredFeeRecipients) lookup(pubkey phase0.BLSPubKey) (bellatrix.ExecutionAddress, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	entry, ok := r.entries[pubkey]
+	return entry.feeRecipient, ok
+}
+
+// lookupHistory returns the fee recipient history of the validator, oldest first
+func (r *registeredFeeRecipients) lookupHistory(pubkey phase0.BLSPubKey) []feeRecipientChange {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	history := r.entries[pubkey].history
+	return append(make([]feeRecipientChange, 0, len(history)), history...)
+}
+
+// mapping returns the current fee recipient of every validator
+func (r *registeredFeeRecipients) mapping() map[string]string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	mapping := make(map[string]string, len(r.entries))
+	for pubkey, entry := range r.entries {
+		mapping[pubkey.String()] = entry.feeRecipient.String()
+	}
+	return mapping
+}
+
+// recordFeeRecipients remembers the fee recipients of the registrations, and in audit mode warns about and counts
+// validators whose fee recipient changed
+func (m *BoostService) recordFeeRecipients(log *logrus.Entry, registrations []builderApiV1.SignedValidatorRegistration) {
+	updates := m.feeRecipients.record(registrations, time.Now())
+	if !m.feeRecipients.audit {
+		return
+	}
+	for _, update := range updates {
+		feeRecipientChanges.Inc()
+		log.WithFields(logrus.Fields{
+			"pubkey":               update.pubkey.String(),
+			"previousFeeRecipient": update.previous.String(),
+			"feeRecipient":         update.current.String(),
+		}).Warn("fee recipient of the validator changed")
+	}
+}
+
+// filterApprovedFeeRecipients returns the registrations whose fee recipient is approved, and logs the others as
+// rejected. All registrations are approved without a list of approved fee recipients.
+func (m *BoostService) filterApprovedFeeRecipients(log *logrus.Entry, registrations []builderApiV1.SignedValidatorRegistration) []builderApiV1.SignedValidatorRegistration {
+	if m.approvedFeeRecipients == nil {
+		return registrations
+	}
+	approved := make([]builderApiV1.SignedValidatorRegistration, 0, len(registrations))
+	for _, registration := range registrations {
+		if registration.Message != nil && !m.approvedFeeRecipients[registration.Message.FeeRecipient] {
+			feeRecipientRejections.Inc()
+			log.WithFields(logrus.Fields{
+				"pubkey":       registration.Message.Pubkey.String(),
+				"feeRecipient": registration.Message.FeeRecipient.String(),
+			}).Error("rejecting registration with a fee recipient which is not approved")
+			continue
+		}
+		approved = append(approved, registration)
+	}
+	return approved
+}
+
+// handleDebugFeeRecipients returns the fee recipient history of the validator of the pubkey query parameter, or
+// the current fee recipient of every validator without it
+func (m *BoostService) handleDebugFeeRecipients(w http.ResponseWriter, req *http.Request) {
+	pubkeyHex := req.URL.Query().Get("pubkey")
+	if pubkeyHex == "" {
+		m.respondOK(w, m.feeRecipients.mapping())
+		return
+	}
+	pubkey, err := utils.HexToPubkey(pubkeyHex)
+	if err != nil {
+		m.respondError(w, http.StatusBadRequest, errInvalidPubkey.Error())
+		return
+	}
+	feeRecipient, ok := m.feeRecipients.lookup(pubkey)
+	response := struct {
+		Pubkey       phase0.BLSPubKey     `json:"pubkey"`
+		FeeRecipient string               `json:"fee_recipient,omitempty"`
+		History      []feeRecipientChange `json:"history"`
+	}{Pubkey: pubkey, History: m.feeRecipients.lookupHistory(pubkey)}
+	if ok {
+		response.FeeRecipient = feeRecipient.String()
+	}
+	m.respondOK(w, response)
+}
+
+// checkFeeRecipient warns if the fee recipient of the served bid is not the one the validator registered most
+// recently. The bid is served regardless, this only makes the drift visible to the operator.
+func (m *BoostService) checkFeeRecipient(log *logrus.Entry, pubkey phase0.BLSPubKey, bid bidResp) {
+	registered, ok := m.feeRecipients.lookup(pubkey)
+	if !ok {
+		return
+	}
+	feeRecipient, err := bid.response.FeeRecipient()
+	if err != nil || feeRecipient == registered {
+		return
+	}
+	for _, relay := range bid.relays {
+		bidFeeRecipientMismatches.WithLabelValues(relayLabel(relay)).Inc()
+	}
+	log.WithFields(logrus.Fields{
+		"registeredFeeRecipient": registered.String(),
+		"bidFeeRecipient":        feeRecipient.String(),
+		"relays":                 strings.Join(types.RelayEntriesToNames(bid.relays), ", "),
+	}).Warn("fee recipient of the bid does not match the most recent registration of the validator")
+}
@@ -0,0 +1,228 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	builderApiV1 "github.com/attestantio/go-builder-client/api/v1"
+	"github.com/attestantio/go-eth2-client/spec"
+	"github.com/attestantio/go-eth2-client/spec/bellatrix"
+	"github.com/flashbots/mev-boost/server/mock"
+	"github.com/flashbots/mev-boost/server/params"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+	logrusTest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/require"
+)
+
+const feeRecipientTestPubkey = "0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249"
+
+func feeRecipientRegistration(feeRecipient string, timestamp int64) builderApiV1.SignedValidatorRegistration {
+	return builderApiV1.SignedValidatorRegistration{
+		Message: &builderApiV1.ValidatorRegistration{
+			FeeRecipient: mock.HexToAddress(feeRecipient),
+			Timestamp:    time.Unix(timestamp, 0),
+			Pubkey:       mock.HexToPubkey(feeRecipientTestPubkey),
+		},
+	}
+}
+
+func TestRegisteredFeeRecipients(t *testing.T) {
+	const (
+		first  = "0xdb65fEd33dc262Fe09D9a2Ba8F80b329BA25f941"
+		second = "0x0000000000000000000000000000000000000001"
+	)
+	pubkey := mock.HexToPubkey(feeRecipientTestPubkey)
+	now := time.Now()
+	r := newRegisteredFeeRecipients(false)
+	_, ok := r.lookup(pubkey)
+	require.False(t, ok)
+
+	r.record([]builderApiV1.SignedValidatorRegistration{feeRecipientRegistration(first, 1000)}, now)
+	feeRecipient, ok := r.lookup(pubkey)
+	require.True(t, ok)
+	require.Equal(t, mock.HexToAddress(first), feeRecipient)
+
+	r.record([]builderApiV1.SignedValidatorRegistration{feeRecipientRegistration(second, 2000)}, now)
+	feeRecipient, _ = r.lookup(pubkey)
+	require.Equal(t, mock.HexToAddress(second), feeRecipient)
+
+	// An older registration does not replace the most recent one
+	r.record([]builderApiV1.SignedValidatorRegistration{feeRecipientRegistration(first, 1500)}, now)
+	feeRecipient, _ = r.lookup(pubkey)
+	require.Equal(t, mock.HexToAddress(second), feeRecipient)
+
+	// Validators which are not registered again are forgotten
+	r.record(nil, now.Add(registrationCoverageMaxAge+time.Second))
+	_, ok = r.lookup(pubkey)
+	require.False(t, ok)
+}
+
+func TestRegisteredFeeRecipientsAudit(t *testing.T) {
+	const (
+		first  = "0xdb65fEd33dc262Fe09D9a2Ba8F80b329BA25f941"
+		second = "0x0000000000000000000000000000000000000001"
+	)
+	pubkey := mock.HexToPubkey(feeRecipientTestPubkey)
+	now := time.Now()
+	r := newRegisteredFeeRecipients(true)
+
+	require.Empty(t, r.record([]builderApiV1.SignedValidatorRegistration{feeRecipientRegistration(first, 1000)}, now))
+	require.Empty(t, r.record([]builderApiV1.SignedValidatorRegistration{feeRecipientRegistration(first, 1001)}, now), "re-registering the same fee recipient is no change")
+	updates := r.record([]builderApiV1.SignedValidatorRegistration{feeRecipientRegistration(second, 1002)}, now)
+	require.Equal(t, []feeRecipientUpdate{{pubkey, mock.HexToAddress(first), mock.HexToAddress(second)}}, updates)
+
+	history := r.lookupHistory(pubkey)
+	require.Len(t, history, 2)
+	require.Equal(t, mock.HexToAddress(first).String(), history[0].FeeRecipient)
+	require.Equal(t, time.Unix(1000, 0), history[0].Timestamp)
+	require.Equal(t, mock.HexToAddress(second).String(), history[1].FeeRecipient)
+
+	// The history only keeps the most recent changes
+	for i := range 2 * feeRecipientHistorySize {
+		feeRecipient := first
+		if i%2 == 0 {
+			feeRecipient = second
+		}
+		r.record([]builderApiV1.SignedValidatorRegistration{feeRecipientRegistration(feeRecipient, int64(1003+i))}, now)
+	}
+	history = r.lookupHistory(pubkey)
+	require.Len(t, history, feeRecipientHistorySize)
+	require.Equal(t, time.Unix(int64(1002+2*feeRecipientHistorySize), 0), history[len(history)-1].Timestamp)
+	require.Equal(t, map[string]string{pubkey.String(): mock.HexToAddress(first).String()}, r.mapping())
+}
+
+func TestRegisterValidatorFeeRecipientAudit(t *testing.T) {
+	const (
+		first  = "0xdb65fEd33dc262Fe09D9a2Ba8F80b329BA25f941"
+		second = "0x0000000000000000000000000000000000000001"
+	)
+	logger, hook := logrusTest.NewNullLogger()
+	backend := newTestBackend(t, 1, time.Second)
+	backend.boost.log = logrus.NewEntry(logger)
+	backend.boost.feeRecipients = newRegisteredFeeRecipients(true)
+	backend.boost.debugEndpoints = true
+
+	before := testutil.ToFloat64(feeRecipientChanges)
+	for i, feeRecipient := range []string{first, second} {
+		registrations := []builderApiV1.SignedValidatorRegistration{feeRecipientRegistration(feeRecipient, int64(1000+i))}
+		rr := backend.request(t, http.MethodPost, params.PathRegisterValidator, registrations)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	}
+	require.InDelta(t, 1, testutil.ToFloat64(feeRecipientChanges)-before, 0)
+	var warnings []*logrus.Entry
+	for _, entry := range hook.AllEntries() {
+		if _, ok := entry.Data["previousFeeRecipient"]; ok {
+			warnings = append(warnings, entry)
+		}
+	}
+	require.Len(t, warnings, 1)
+	require.Equal(t, logrus.WarnLevel, warnings[0].Level)
+	require.Equal(t, mock.HexToAddress(first).String(), warnings[0].Data["previousFeeRecipient"])
+	require.Equal(t, mock.HexToAddress(second).String(), warnings[0].Data["feeRecipient"])
+
+	rr := httptest.NewRecorder()
+	backend.boost.getRouter().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, params.PathDebugFeeRecipients+"?pubkey="+feeRecipientTestPubkey, nil))
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var response struct {
+		FeeRecipient string               `json:"fee_recipient"`
+		History      []feeRecipientChange `json:"history"`
+	}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	require.Equal(t, mock.HexToAddress(second).String(), response.FeeRecipient)
+	require.Len(t, response.History, 2)
+
+	rr = httptest.NewRecorder()
+	backend.boost.getRouter().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, params.PathDebugFeeRecipients, nil))
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.JSONEq(t, `{"`+feeRecipientTestPubkey+`":"`+mock.HexToAddress(second).String()+`"}`, rr.Body.String())
+}
+
+func TestRegisterValidatorApprovedFeeRecipients(t *testing.T) {
+	const (
+		approved   = "0xdb65fEd33dc262Fe09D9a2Ba8F80b329BA25f941"
+		unapproved = "0x0000000000000000000000000000000000000001"
+	)
+	backend := newTestBackend(t, 1, time.Second)
+	backend.boost.approvedFeeRecipients = map[bellatrix.ExecutionAddress]bool{mock.HexToAddress(approved): true}
+
+	rr := backend.request(t, http.MethodPost, params.PathRegisterValidator, []builderApiV1.SignedValidatorRegistration{feeRecipientRegistration(approved, 1000)})
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.Equal(t, 1, backend.relays[0].GetRequestCount(params.PathRegisterValidator))
+
+	// The registration is not forwarded, and the approved fee recipient stays registered
+	before := testutil.ToFloat64(feeRecipientRejections)
+	rr = backend.request(t, http.MethodPost, params.PathRegisterValidator, []builderApiV1.SignedValidatorRegistration{feeRecipientRegistration(unapproved, 1001)})
+	require.Equal(t, http.StatusBadRequest, rr.Code, rr.Body.String())
+	require.Equal(t, 1, backend.relays[0].GetRequestCount(params.PathRegisterValidator))
+	require.InDelta(t, 1, testutil.ToFloat64(feeRecipientRejections)-before, 0)
+	feeRecipient, _ := backend.boost.feeRecipients.lookup(mock.HexToPubkey(feeRecipientTestPubkey))
+	require.Equal(t, mock.HexToAddress(approved), feeRecipient)
+}
+
+func TestRegisterValidatorApprovedFeeRecipientsEmptyBatch(t *testing.T) {
+	// Empty batches are forwarded with or without an approved list, encoded as [] and null
+	for _, approvedFeeRecipients := range []map[bellatrix.ExecutionAddress]bool{nil, {mock.HexToAddress("0xdb65fEd33dc262Fe09D9a2Ba8F80b329BA25f941"): true}} {
+		backend := newTestBackend(t, 1, time.Second)
+		backend.boost.approvedFeeRecipients = approvedFeeRecipients
+		for _, batch := range [][]builderApiV1.SignedValidatorRegistration{{}, nil} {
+			rr := backend.request(t, http.MethodPost, params.PathRegisterValidator, batch)
+			require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		}
+		require.Equal(t, 2, backend.relays[0].GetRequestCount(params.PathRegisterValidator))
+	}
+}
+
+func TestGetHeaderFeeRecipientMismatch(t *testing.T) {
+	parentHash := mock.HexToHash("0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7")
+	path := getHeaderPath(1, parentHash, mock.HexToPubkey(feeRecipientTestPubkey))
+
+	// getHeader registers the fee recipient, and returns the fee recipient warnings of the following getHeader request. The
+	// bids of the mock relay have the zero fee recipient.
+	getHeader := func(t *testing.T, feeRecipient string) []*logrus.Entry {
+		t.Helper()
+		logger, hook := logrusTest.NewNullLogger()
+		backend := newTestBackend(t, 1, time.Second)
+		backend.boost.log = logrus.NewEntry(logger)
+		backend.relays[0].GetHeaderResponse = backend.relays[0].MakeGetHeaderResponse(
+			12345, parentHash.String(), parentHash.String(), feeRecipientTestPubkey, spec.DataVersionDeneb)
+		if feeRecipient != "" {
+			registrations := []builderApiV1.SignedValidatorRegistration{feeRecipientRegistration(feeRecipient, 1000)}
+			rr := backend.request(t, http.MethodPost, params.PathRegisterValidator, registrations)
+			require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		}
+		hook.Reset()
+		rr := backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+		var warnings []*logrus.Entry
+		for _, entry := range hook.AllEntries() {
+			if _, ok := entry.Data["registeredFeeRecipient"]; ok {
+				warnings = append(warnings, entry)
+			}
+		}
+		return warnings
+	}
+
+	t.Run("Mismatch is warned about", func(t *testing.T) {
+		const registered = "0xdb65fEd33dc262Fe09D9a2Ba8F80b329BA25f941"
+		warnings := getHeader(t, registered)
+		require.Len(t, warnings, 1)
+		require.Equal(t, mock.HexToAddress(registered).String(), warnings[0].Data["registeredFeeRecipient"])
+		require.Equal(t, bellatrix.ExecutionAddress{}.String(), warnings[0].Data["bidFeeRecipient"])
+		relay, ok := warnings[0].Data["relays"].(string)
+		require.True(t, ok)
+		require.InDelta(t, 1, testutil.ToFloat64(bidFeeRecipientMismatches.WithLabelValues(relay)), 0)
+	})
+
+	t.Run("Matching fee recipient", func(t *testing.T) {
+		require.Empty(t, getHeader(t, "0x0000000000000000000000000000000000000000"))
+	})
+
+	t.Run("Unregistered validator", func(t *testing.T) {
+		require.Empty(t, getHeader(t, ""))
+	})
+}
@@ -0,0 +1,132 @@
+package server
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/attestantio/go-eth2-client/spec"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/flashbots/mev-boost/server/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	errUnknownFork             = errors.New("unknown fork")
+	errInvalidForkTimeout      = errors.New("per-fork getHeader timeouts must be positive")
+	errForkTimeoutWithoutEpoch = errors.New("per-fork getHeader timeout of a fork without an activation epoch")
+	errRelayForkWithoutEpoch   = errors.New("relay fork allowlist has a fork without an activation epoch")
+
+	relayForkSkips = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "relay_getheader_fork_skips_total",
+		Help: "Number of getHeader requests not sent to a relay because the fork of the slot is not on its fork allowlist",
+	}, []string{"relay", "fork"})
+)
+
+// builderForks are the forks with a builder API, oldest first
+var builderForks = []spec.DataVersion{
+	spec.DataVersionBellatrix,
+	spec.DataVersionCapella,
+	spec.DataVersionDeneb,
+	spec.DataVersionElectra,
+}
+
+// ParseFork returns the builder API fork of a name, like "deneb"
+func ParseFork(name string) (spec.DataVersion, error) {
+	for _, fork := range builderForks {
+		if strings.EqualFold(name, fork.String()) {
+			return fork, nil
+		}
+	}
+	return spec.DataVersionUnknown, fmt.Errorf("%w: %s", errUnknownFork, name)
+}
+
+// forkActivation is the epoch a fork activates at
+type forkActivation struct {
+	fork  spec.DataVersion
+	epoch uint64
+}
+
+// forkSchedule returns the fork of a slot from the activation epochs of the forks, latest activation first
+type forkSchedule []forkActivation
+
+func newForkSchedule(epochs map[spec.DataVersion]uint64) forkSchedule {
+	schedule := make(forkSchedule, 0, len(epochs))
+	for fork, epoch := range epochs {
+		schedule = append(schedule, forkActivation{fork: fork, epoch: epoch})
+	}
+	slices.SortFunc(schedule, func(a, b forkActivation) int {
+		return cmp.Or(cmp.Compare(b.epoch, a.epoch), cmp.Compare(b.fork, a.fork))
+	})
+	return schedule
+}
+
+// forkAt returns the fork of the slot, or spec.DataVersionUnknown if it is before every known activation
+func (s forkSchedule) forkAt(slot phase0.Slot) spec.DataVersion {
+	epoch := uint64(slot) / slotsPerEpoch
+	for _, activation := range s {
+		if epoch >= activation.epoch {
+			return activation.fork
+		}
+	}
+	return spec.DataVersionUnknown
+}
+
+// validateForkTimeouts checks that the per-fork getHeader timeouts are positive, and that their forks have an
+// activation epoch, as they would never apply otherwise
+func validateForkTimeouts(timeouts map[spec.DataVersion]time.Duration, epochs map[spec.DataVersion]uint64) error {
+	for fork, timeout := range timeouts {
+		if timeout <= 0 {
+			return fmt.Errorf("%w: %s", errInvalidForkTimeout, fork)
+		}
+		if _, ok := epochs[fork]; !ok {
+			return fmt.Errorf("%w: %s", errForkTimeoutWithoutEpoch, fork)
+		}
+	}
+	return nil
+}
+
+// validateRelayForks checks that the forks of the relay fork allowlists have an activation epoch, as the relays
+// would never be asked for bids in their slots otherwise
+func validateRelayForks(relays []types.RelayEntry, epochs map[spec.DataVersion]uint64) error {
+	for _, relay := range relays {
+		for _, fork := range relay.Forks {
+			if _, ok := epochs[fork]; !ok {
+				return fmt.Errorf("%w: %s %s", errRelayForkWithoutEpoch, relay.Name(), fork)
+			}
+		}
+	}
+	return nil
+}
+
+// forkRelays returns the relays whose fork allowlist has the fork of the slot. No relay is skipped if the fork of
+// the slot is unknown.
+func (m *BoostService) forkRelays(log *logrus.Entry, relays []types.RelayEntry, slot phase0.Slot) []types.RelayEntry {
+	fork := m.forkSchedule.forkAt(slot)
+	if fork == spec.DataVersionUnknown {
+		return relays
+	}
+	supported := make([]types.RelayEntry, 0, len(relays))
+	for _, relay := range relays {
+		if !relay.SupportsFork(fork) {
+			relayForkSkips.WithLabelValues(relayLabel(relay), fork.String()).Inc()
+			log.WithFields(logrus.Fields{"relay": relayLabel(relay), "fork": fork.String()}).Debug("skipping relay which does not support the fork of the slot")
+			continue
+		}
+		supported = append(supported, relay)
+	}
+	return supported
+}
+
+// getHeaderTimeout returns the getHeader timeout of the fork of the slot, which is RequestTimeoutGetHeader
+// unless the fork has its own timeout
+func (m *BoostService) getHeaderTimeout(slot phase0.Slot) time.Duration {
+	if timeout, ok := m.getHeaderForkTimeouts[m.forkSchedule.forkAt(slot)]; ok {
+		return timeout
+	}
+	return m.httpClientGetHeader.Timeout
+}
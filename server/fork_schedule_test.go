@@ -0,0 +1,110 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/attestantio/go-eth2-client/spec"
+	"github.com/flashbots/mev-boost/server/mock"
+	"github.com/flashbots/mev-boost/server/types"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFork(t *testing.T) {
+	fork, err := ParseFork("Deneb")
+	require.NoError(t, err)
+	require.Equal(t, spec.DataVersionDeneb, fork)
+
+	_, err = ParseFork("altair")
+	require.ErrorIs(t, err, errUnknownFork)
+}
+
+func TestForkSchedule(t *testing.T) {
+	schedule := newForkSchedule(map[spec.DataVersion]uint64{
+		spec.DataVersionCapella: 10,
+		spec.DataVersionElectra: 30,
+		spec.DataVersionDeneb:   20,
+	})
+	require.Equal(t, spec.DataVersionUnknown, schedule.forkAt(10*slotsPerEpoch-1))
+	require.Equal(t, spec.DataVersionCapella, schedule.forkAt(10*slotsPerEpoch))
+	require.Equal(t, spec.DataVersionDeneb, schedule.forkAt(30*slotsPerEpoch-1))
+	require.Equal(t, spec.DataVersionElectra, schedule.forkAt(30*slotsPerEpoch))
+
+	require.Equal(t, spec.DataVersionUnknown, newForkSchedule(nil).forkAt(1))
+}
+
+func TestValidateForkTimeouts(t *testing.T) {
+	epochs := map[spec.DataVersion]uint64{spec.DataVersionElectra: 30}
+	require.NoError(t, validateForkTimeouts(map[spec.DataVersion]time.Duration{spec.DataVersionElectra: time.Second}, epochs))
+	require.ErrorIs(t, validateForkTimeouts(map[spec.DataVersion]time.Duration{spec.DataVersionElectra: 0}, epochs), errInvalidForkTimeout)
+	require.ErrorIs(t, validateForkTimeouts(map[spec.DataVersion]time.Duration{spec.DataVersionDeneb: time.Second}, epochs), errForkTimeoutWithoutEpoch)
+}
+
+func TestGetHeaderForkTimeout(t *testing.T) {
+	hash := mock.HexToHash("0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7")
+	pubkey := mock.HexToPubkey(
+		"0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249")
+
+	backend := newTestBackend(t, 1, 100*time.Millisecond)
+	backend.boost.forkSchedule = newForkSchedule(map[spec.DataVersion]uint64{
+		spec.DataVersionDeneb:   0,
+		spec.DataVersionElectra: 10,
+	})
+	backend.boost.getHeaderForkTimeouts = map[spec.DataVersion]time.Duration{spec.DataVersionElectra: time.Second}
+	backend.relays[0].ResponseDelay = 300 * time.Millisecond
+
+	require.Equal(t, 100*time.Millisecond, backend.boost.getHeaderTimeout(1))
+	require.Equal(t, time.Second, backend.boost.getHeaderTimeout(10*slotsPerEpoch))
+
+	// The slow relay times out in a deneb slot, with the default timeout
+	rr := backend.request(t, http.MethodGet, getHeaderPath(1, hash, pubkey), nil)
+	require.Equal(t, http.StatusNoContent, rr.Code, rr.Body.String())
+
+	// The electra timeout leaves time for the slow relay
+	rr = backend.request(t, http.MethodGet, getHeaderPath(10*slotsPerEpoch, hash, pubkey), nil)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+}
+
+func TestValidateRelayForks(t *testing.T) {
+	relay := mock.NewRelay(t).RelayEntry
+	relay.Forks = []spec.DataVersion{spec.DataVersionElectra}
+	require.NoError(t, validateRelayForks([]types.RelayEntry{relay}, map[spec.DataVersion]uint64{spec.DataVersionElectra: 30}))
+	require.ErrorIs(t, validateRelayForks([]types.RelayEntry{relay}, map[spec.DataVersion]uint64{spec.DataVersionDeneb: 20}), errRelayForkWithoutEpoch)
+}
+
+func TestGetHeaderRelayForks(t *testing.T) {
+	hash := mock.HexToHash("0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7")
+	pubkey := mock.HexToPubkey(
+		"0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249")
+
+	// The second relay is only ready for electra
+	backend := newTestBackend(t, 2, time.Second)
+	backend.boost.forkSchedule = newForkSchedule(map[spec.DataVersion]uint64{
+		spec.DataVersionDeneb:   0,
+		spec.DataVersionElectra: 10,
+	})
+	backend.boost.relays[1].Forks = []spec.DataVersion{spec.DataVersionElectra}
+	electraRelay := relayLabel(backend.boost.relays[1])
+	before := testutil.ToFloat64(relayForkSkips.WithLabelValues(electraRelay, "deneb"))
+
+	denebPath := getHeaderPath(1, hash, pubkey)
+	rr := backend.request(t, http.MethodGet, denebPath, nil)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.Equal(t, 1, backend.relays[0].GetRequestCount(denebPath))
+	require.Zero(t, backend.relays[1].GetRequestCount(denebPath))
+	require.InDelta(t, 1, testutil.ToFloat64(relayForkSkips.WithLabelValues(electraRelay, "deneb"))-before, 0)
+
+	electraPath := getHeaderPath(10*slotsPerEpoch, hash, pubkey)
+	rr = backend.request(t, http.MethodGet, electraPath, nil)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.Equal(t, 1, backend.relays[0].GetRequestCount(electraPath))
+	require.Equal(t, 1, backend.relays[1].GetRequestCount(electraPath))
+
+	// Without activation epochs the fork of the slot is unknown, and every relay is asked
+	backend.boost.forkSchedule = newForkSchedule(nil)
+	rr = backend.request(t, http.MethodGet, denebPath, nil)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.Equal(t, 1, backend.relays[1].GetRequestCount(denebPath))
+}
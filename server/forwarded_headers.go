@@ -0,0 +1,76 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/flashbots/mev-boost/server/types"
+)
+
+var errInvalidForwardHeader = errors.New("header cannot be forwarded to relays")
+
+// headerNameRegex matches the token characters allowed in header names
+var headerNameRegex = regexp.MustCompile("^[A-Za-z0-9!#$%&'*+.^_`|~-]+$")
+
+// unforwardableHeaders are set by mev-boost or the HTTP client, or carry credentials of the beacon node
+var unforwardableHeaders = map[string]bool{
+	"Authorization":     true,
+	"Connection":        true,
+	"Content-Length":    true,
+	"Content-Type":      true,
+	"Cookie":            true,
+	"Host":              true,
+	"Transfer-Encoding": true,
+	"User-Agent":        true,
+}
+
+// parseForwardHeaders returns the canonical names of the headers to forward from the beacon node to relays
+func parseForwardHeaders(names []string) ([]string, error) {
+	canonical := make([]string, 0, len(names))
+	for _, name := range names {
+		key := http.CanonicalHeaderKey(strings.TrimSpace(name))
+		if !headerNameRegex.MatchString(key) || unforwardableHeaders[key] || strings.HasPrefix(key, "X-Mevboost-") {
+			return nil, fmt.Errorf("%w: %q", errInvalidForwardHeader, name)
+		}
+		canonical = append(canonical, key)
+	}
+	return canonical, nil
+}
+
+// forwardedHeaders returns the headers of the beacon node request which are forwarded to relays
+func (m *BoostService) forwardedHeaders(req *http.Request) map[string]string {
+	var forwarded map[string]string
+	for _, key := range m.forwardHeaders {
+		if value := req.Header.Get(key); value != "" {
+			if forwarded == nil {
+				forwarded = make(map[string]string, len(m.forwardHeaders))
+			}
+			forwarded[key] = value
+		}
+	}
+	return forwarded
+}
+
+// withRelayHeaders returns the headers of a request to the relay with the headers configured for the relay, which do
+// not replace the headers of the request
+func withRelayHeaders(headers map[string]string, relay types.RelayEntry) map[string]string {
+	if len(relay.Headers) == 0 {
+		return headers
+	}
+	merged := maps.Clone(relay.Headers)
+	maps.Copy(merged, headers)
+	return merged
+}
+
+// addForwardedHeaders adds the forwarded headers to the headers of a relay request, without replacing them
+func addForwardedHeaders(headers, forwarded map[string]string) {
+	for key, value := range forwarded {
+		if _, ok := headers[key]; !ok {
+			headers[key] = value
+		}
+	}
+}
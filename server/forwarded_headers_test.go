@@ -0,0 +1,108 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/attestantio/go-eth2-client/spec"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/flashbots/mev-boost/server/mock"
+	"github.com/flashbots/mev-boost/server/params"
+	"github.com/flashbots/mev-boost/server/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseForwardHeaders(t *testing.T) {
+	testCases := []struct {
+		names    []string
+		expected []string
+		valid    bool
+	}{
+		{names: nil, expected: []string{}, valid: true},
+		{names: []string{"x-correlation-id", " X-Request-ID "}, expected: []string{"X-Correlation-Id", "X-Request-Id"}, valid: true},
+		{names: []string{"Authorization"}},
+		{names: []string{"content-type"}},
+		{names: []string{"X-MEVBoost-SlotID"}},
+		{names: []string{"X Correlation"}},
+		{names: []string{""}},
+	}
+	for _, tc := range testCases {
+		headers, err := parseForwardHeaders(tc.names)
+		if !tc.valid {
+			require.ErrorIs(t, err, errInvalidForwardHeader, tc.names)
+			continue
+		}
+		require.NoError(t, err)
+		require.Equal(t, tc.expected, headers)
+	}
+}
+
+func TestRelayHeaders(t *testing.T) {
+	backend := newTestBackend(t, 1, time.Second)
+	received := make(chan http.Header, 1)
+	backend.relays[0].OverrideHandleGetHeader(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Clone()
+		w.WriteHeader(http.StatusNoContent)
+	})
+	// The headers of the relay do not replace the headers of the request
+	backend.boost.relays[0].Headers = map[string]string{"Authorization": "Bearer abc", HeaderStartTimeUnixMS: "0"}
+
+	rr := backend.request(t, http.MethodGet, getHeaderPath(1, phase0.Hash32{0x01}, mock.HexToPubkey(feeRecipientTestPubkey)), nil)
+	require.Equal(t, http.StatusNoContent, rr.Code, rr.Body.String())
+	headers := <-received
+	require.Equal(t, "Bearer abc", headers.Get("Authorization"))
+	require.NotEqual(t, "0", headers.Get(HeaderStartTimeUnixMS))
+
+	require.Equal(t, map[string]string{"A": "1"}, withRelayHeaders(map[string]string{"A": "1"}, types.RelayEntry{}))
+}
+
+func TestForwardHeaders(t *testing.T) {
+	const pubkey = "0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249"
+
+	// send makes a request with the correlation header and another header which is not forwarded
+	send := func(t *testing.T, backend *testBackend, method, path string, payload any) {
+		t.Helper()
+		body, err := json.Marshal(payload)
+		require.NoError(t, err)
+		req, err := http.NewRequest(method, path, bytes.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("X-Correlation-Id", "abc123")
+		req.Header.Set("X-Other", "secret")
+		rr := httptest.NewRecorder()
+		backend.boost.getRouter().ServeHTTP(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	block, response := loadDenebBlock(t)
+	header := block.Message.Body.ExecutionPayloadHeader
+	backend := newTestBackend(t, 1, time.Second)
+	backend.boost.forwardHeaders = []string{"X-Correlation-Id"}
+	relay := backend.relays[0]
+
+	received := make(chan http.Header, 2)
+	relay.OverrideHandleGetHeader(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Clone()
+		resp := relay.MakeGetHeaderResponse(12345, header.BlockHash.String(), header.ParentHash.String(), pubkey, spec.DataVersionDeneb)
+		w.Header().Set("Content-Type", "application/json")
+		require.NoError(t, json.NewEncoder(w).Encode(resp))
+	})
+	relay.OverrideHandleGetPayload(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Clone()
+		w.Header().Set("Content-Type", "application/json")
+		require.NoError(t, json.NewEncoder(w).Encode(response))
+	})
+
+	send(t, backend, http.MethodGet, getHeaderPath(uint64(block.Message.Slot), header.ParentHash, mock.HexToPubkey(pubkey)), nil)
+	send(t, backend, http.MethodPost, params.PathGetPayload, block)
+
+	for _, method := range []string{"getHeader", "getPayload"} {
+		headers := <-received
+		require.Equal(t, "abc123", headers.Get("X-Correlation-Id"), method)
+		require.Empty(t, headers.Get("X-Other"), method)
+		require.NotEmpty(t, headers.Get(HeaderStartTimeUnixMS), method)
+	}
+}
@@ -2,16 +2,20 @@ package server
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"maps"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	builderApi "github.com/attestantio/go-builder-client/api"
 	denebApi "github.com/attestantio/go-builder-client/api/deneb"
-	builderSpec "github.com/attestantio/go-builder-client/spec"
 	eth2ApiV1Bellatrix "github.com/attestantio/go-eth2-client/api/v1/bellatrix"
 	eth2ApiV1Capella "github.com/attestantio/go-eth2-client/api/v1/capella"
 	eth2ApiV1Deneb "github.com/attestantio/go-eth2-client/api/v1/deneb"
@@ -19,10 +23,12 @@ import (
 	"github.com/attestantio/go-eth2-client/spec"
 	"github.com/attestantio/go-eth2-client/spec/deneb"
 	"github.com/attestantio/go-eth2-client/spec/phase0"
+	utilbellatrix "github.com/attestantio/go-eth2-client/util/bellatrix"
 	"github.com/flashbots/mev-boost/config"
 	"github.com/flashbots/mev-boost/server/params"
 	"github.com/flashbots/mev-boost/server/types"
 	"github.com/google/uuid"
+	"github.com/holiman/uint256"
 	"github.com/sirupsen/logrus"
 )
 
@@ -39,14 +45,34 @@ var (
 	errInvalidBlockhash = errors.New("invalid blockhash")
 	errInvalidKZGLength = errors.New("invalid KZG commitments length")
 	errInvalidKZG       = errors.New("invalid KZG commitment")
+	errInvalidTxRoot    = errors.New("invalid transactions root")
+
+	errMissingExecutionRequests = errors.New("missing execution requests")
+	errInvalidExecutionRequests = errors.New("execution requests do not match the bid")
+)
+
+// ValidationLevel controls how much of the relay bids and payloads is verified
+type ValidationLevel string
+
+const (
+	// ValidationLevelNone trusts the relays, only checking that responses are usable
+	ValidationLevelNone ValidationLevel = "none"
+	// ValidationLevelBasic verifies relay signatures, parent hashes, payload block hashes and KZG commitments
+	ValidationLevelBasic ValidationLevel = "basic"
+	// ValidationLevelStrict additionally verifies payload transactions roots, and logs signed execution requests which
+	// differ from the ones of the bid
+	ValidationLevelStrict ValidationLevel = "strict"
 )
 
-// processPayload requests the payload (execution payload, blobs bundle, etc) from the relays
-func processPayload[P Payload](m *BoostService, log *logrus.Entry, ua UserAgent, blindedBlock P) (*builderApi.VersionedSubmitBlindedBlockResponse, bidResp) {
+// processPayload requests the payload (execution payload, blobs bundle, etc) from the relays. It also returns the
+// recorded outcome of a previous submission of the same block, and does not request a payload which was already delivered.
+// A payload delivered in the current slot is returned again.
+func processPayload[P Payload](m *BoostService, log *logrus.Entry, timer *requestTimer, ua UserAgent, forwarded map[string]string, blindedBlock P) (*relayPayload, bidResp, *payloadOutcome) {
 	var (
 		slot      = slot(blindedBlock)
 		blockHash = blockHash(blindedBlock)
 	)
+	m.drain.payloadArrived(slot)
 
 	// Get the currentSlotUID for this slot
 	currentSlotUID := ""
@@ -62,22 +88,59 @@ func processPayload[P Payload](m *BoostService, log *logrus.Entry, ua UserAgent,
 	log = prepareLogger(log, blindedBlock, ua, currentSlotUID)
 
 	// Log how late into the slot the request starts
-	slotStartTimestamp := m.genesisTime + uint64(slot)*config.SlotTimeSec
-	msIntoSlot := uint64(time.Now().UTC().UnixMilli()) - slotStartTimestamp*1000
+	msIntoSlot := m.msIntoSlot(slot, time.Now())
 	log.WithFields(logrus.Fields{
 		"genesisTime": m.genesisTime,
-		"slotTimeSec": config.SlotTimeSec,
+		"slotTimeSec": m.secondsPerSlot,
 		"msIntoSlot":  msIntoSlot,
 	}).Infof("submitBlindedBlock request start - %d milliseconds into slot %d", msIntoSlot, slot)
 
 	// Get the bid!
-	m.bidsLock.Lock()
-	originalBid := m.bids[bidKey(slot, blockHash)]
-	m.bidsLock.Unlock()
+	originalBid, _ := m.bids.get(slot, blockHash)
+
+	// The beacon node may submit the same block again when its request timed out, the payload delivered
+	// before is returned without requesting it from the relays again
+	if cached, ok := m.deliveredPayloads.get(slot, blockHash, time.Now()); ok {
+		return cached, originalBid, &payloadOutcome{Slot: slot, BlockHash: blockHash, Delivered: true}
+	}
+
+	// The beacon node may submit the same block again, e.g. when retrying after a restart of mev-boost
+	var previous *payloadOutcome
+	if outcome, ok := m.payloadOutcomes.lookup(slot, blockHash); ok {
+		previous = &outcome
+		log = log.WithField("previouslyDelivered", outcome.Delivered)
+		if outcome.Delivered {
+			return nil, originalBid, previous
+		}
+		log.Warn("duplicate submission of a block for which no payload was delivered before")
+	}
+
+	// Without a known bid, e.g. right after a restart, the relays of the persisted bid metadata are asked first,
+	// along with all other relays
+	relays := m.relays
 	if originalBid.response.IsEmpty() {
-		log.Error("no bid for this getPayload payload found, was getHeader called before?")
+		originalBid.relays, relays = m.recoverBidRelays(slot, blockHash)
+		originalBid.coldStartRecovery = coldStartFanOut
+		if len(originalBid.relays) > 0 {
+			originalBid.coldStartRecovery = coldStartPersisted
+		}
+		log = log.WithFields(logrus.Fields{
+			"coldStartRecovery": originalBid.coldStartRecovery,
+			"relaysWithBid":     strings.Join(types.RelayEntriesToNames(originalBid.relays), ", "),
+		})
+		log.Error("no bid for this getPayload payload found, was getHeader called before? Requesting the payload from all relays")
 	} else if len(originalBid.relays) == 0 {
 		log.Warn("bid found but no associated relays")
+	} else {
+		relays = m.bidRelaysFirst(log, originalBid.relays)
+	}
+
+	// Make sure the proposer signed the execution requests of the bid. The relay is still asked for
+	// the payload, as it has the final say on whether the block can be published.
+	if block, ok := any(blindedBlock).(*eth2ApiV1Electra.SignedBlindedBeaconBlock); ok && m.validationLevel == ValidationLevelStrict {
+		if err := verifyExecutionRequests(block, originalBid); err != nil {
+			log.WithError(err).Error("invalid execution requests in signed blinded block")
+		}
 	}
 
 	// Add request headers
@@ -85,9 +148,10 @@ func processPayload[P Payload](m *BoostService, log *logrus.Entry, ua UserAgent,
 		HeaderKeySlotUID:      currentSlotUID,
 		HeaderStartTimeUnixMS: fmt.Sprintf("%d", time.Now().UTC().UnixMilli()),
 	}
+	addForwardedHeaders(headers, forwarded)
 
 	// Prepare for requests
-	resultCh := make(chan *builderApi.VersionedSubmitBlindedBlockResponse, len(m.relays))
+	resultCh := make(chan *relayPayload, len(relays))
 	var received atomic.Bool
 	go func() {
 		// Make sure we receive a response within the timeout
@@ -99,15 +163,26 @@ func processPayload[P Payload](m *BoostService, log *logrus.Entry, ua UserAgent,
 	requestCtx, requestCtxCancel := context.WithCancel(context.Background())
 	defer requestCtxCancel()
 
-	for _, relay := range m.relays {
+	timer.mark(timingStageFanout)
+	for _, relay := range relays {
 		go func(relay types.RelayEntry) {
 			url := relay.GetURI(params.PathGetPayload)
-			log := log.WithField("url", url)
+			log := log.WithFields(logrus.Fields{"relay": relayLabel(relay), "url": url})
 			log.Debug("calling getPayload")
 
-			responsePayload := new(builderApi.VersionedSubmitBlindedBlockResponse)
-			_, err := SendHTTPRequestWithRetries(requestCtx, m.httpClientGetPayload, http.MethodPost, url, ua, headers, blindedBlock, responsePayload, m.requestMaxRetries, log)
+			delivered := &relayPayload{response: new(builderApi.VersionedSubmitBlindedBlockResponse)}
+			ctx := withGzipSniffing(withMaxResponseSize(withRetryConditions(requestCtx, m.requestRetryOn), m.maxPayloadResponseSize), relay, log)
+			ctx = m.withConsensusVersionFallback(m.relayAdvisories.observing(ctx, relay), log, relay, "getPayload", slot)
+			ctx = m.withContentTypeCheck(ctx, log, relay, "getPayload")
+			requestStart := time.Now()
+			_, err := SendHTTPRequestWithRetries(ctx, m.httpClientGetPayload, http.MethodPost, url, ua, withRelayHeaders(headers, relay), blindedBlock, delivered, m.requestMaxRetries, log)
+			if errors.Is(err, errResponseTooLarge) {
+				relayPayloadRejections.WithLabelValues(relayLabel(relay), "response_too_large").Inc()
+				log.WithError(err).WithField("maxResponseSize", m.maxPayloadResponseSize).Error("relay sent a getPayload response which is too large, ignoring it")
+				return
+			}
 			if err != nil {
+				log := log.WithFields(m.session.recordRequestError(relay, "getPayload", err))
 				if errors.Is(requestCtx.Err(), context.Canceled) {
 					// This is expected if the payload has already been received by another relay
 					log.Info("request was cancelled")
@@ -116,15 +191,26 @@ func processPayload[P Payload](m *BoostService, log *logrus.Entry, ua UserAgent,
 				}
 				return
 			}
+			m.session.recordLatency(relay, "getPayload", time.Since(requestStart))
+
+			responsePayload := delivered.response
+
+			// Check the number of blobs before verifying them
+			if err := checkBlobCount(responsePayload); err != nil {
+				relayPayloadRejections.WithLabelValues(relayLabel(relay), "too_many_blobs").Inc()
+				log.WithError(err).Error("relay sent a getPayload response with too many blobs, ignoring it")
+				return
+			}
 
-			if err := verifyPayload(blindedBlock, log, responsePayload); err != nil {
+			if err := verifyPayload(blindedBlock, log, responsePayload, m.validationLevel); err != nil {
 				return
 			}
 
 			requestCtxCancel()
 			if received.CompareAndSwap(false, true) {
-				resultCh <- responsePayload
-				log.Info("received payload from relay")
+				timer.mark(timingStagePayload)
+				resultCh <- delivered
+				comparePayloadValue(log, relay, originalBid.bidInfo.value, responsePayload).Info("received payload from relay")
 			} else {
 				log.Trace("Discarding response, already received a correct response")
 			}
@@ -134,11 +220,39 @@ func processPayload[P Payload](m *BoostService, log *logrus.Entry, ua UserAgent,
 	// Wait for the first request to complete
 	result := <-resultCh
 
-	return result, originalBid
+	// Remember the relays which did not deliver the payload, in case they bid the same block hash again
+	if result == nil && len(originalBid.relays) > 0 && previous == nil {
+		m.failedDeliveries.record(slot, blockHash, originalBid.relays)
+	}
+	if result != nil {
+		m.deliveredPayloads.add(slot, blockHash, result, m.slotEnd(slot), time.Now())
+	}
+	if err := m.payloadOutcomes.record(slot, blockHash, result != nil); err != nil {
+		log.WithError(err).Error("could not persist the getPayload outcome")
+	}
+
+	return result, originalBid, previous
+}
+
+// bidRelaysFirst returns the relays of the bid, followed by the other configured relays. The relays of the bid
+// are the copies stored with it, so a relay removed by a reload since the bid was served is still asked.
+func (m *BoostService) bidRelaysFirst(log *logrus.Entry, bidRelays []types.RelayEntry) []types.RelayEntry {
+	relays := slices.Clone(bidRelays)
+	for _, bidRelay := range bidRelays {
+		if !slices.ContainsFunc(m.relays, func(relay types.RelayEntry) bool { return relay.String() == bidRelay.String() }) {
+			log.WithField("relay", relayLabel(bidRelay)).Info("relay of the bid was removed by a reload, still requesting the payload from it")
+		}
+	}
+	for _, relay := range m.relays {
+		if !slices.ContainsFunc(bidRelays, func(bidRelay types.RelayEntry) bool { return bidRelay.String() == relay.String() }) {
+			relays = append(relays, relay)
+		}
+	}
+	return relays
 }
 
 // verifyPayload checks that the payload is valid
-func verifyPayload[P Payload](payload P, log *logrus.Entry, response *builderApi.VersionedSubmitBlindedBlockResponse) error {
+func verifyPayload[P Payload](payload P, log *logrus.Entry, response *builderApi.VersionedSubmitBlindedBlockResponse, level ValidationLevel) error {
 	// Verify version
 	switch any(payload).(type) {
 	case *eth2ApiV1Bellatrix.SignedBlindedBeaconBlock:
@@ -177,6 +291,10 @@ func verifyPayload[P Payload](payload P, log *logrus.Entry, response *builderApi
 		return errEmptyPayload
 	}
 
+	if level == ValidationLevelNone {
+		return nil
+	}
+
 	// Verify post-conditions
 	switch block := any(payload).(type) {
 	case *eth2ApiV1Bellatrix.SignedBlindedBeaconBlock:
@@ -202,6 +320,38 @@ func verifyPayload[P Payload](payload P, log *logrus.Entry, response *builderApi
 			return err
 		}
 	}
+
+	if level == ValidationLevelStrict {
+		return verifyTransactionsRoot(log, payload, response)
+	}
+	return nil
+}
+
+// verifyExecutionRequests checks that the execution requests of an electra block are the ones of the original bid
+func verifyExecutionRequests(block *eth2ApiV1Electra.SignedBlindedBeaconBlock, originalBid bidResp) error {
+	requests := block.Message.Body.ExecutionRequests
+	if requests == nil {
+		return errMissingExecutionRequests
+	}
+	if originalBid.response.Version != spec.DataVersionElectra || originalBid.response.Electra == nil {
+		return nil
+	}
+
+	bidRequests := originalBid.response.Electra.Message.ExecutionRequests
+	if bidRequests == nil {
+		return nil
+	}
+	bidRoot, err := bidRequests.HashTreeRoot()
+	if err != nil {
+		return err
+	}
+	blockRoot, err := requests.HashTreeRoot()
+	if err != nil {
+		return err
+	}
+	if bidRoot != blockRoot {
+		return fmt.Errorf("%w: bid root %#x, block root %#x", errInvalidExecutionRequests, bidRoot, blockRoot)
+	}
 	return nil
 }
 
@@ -216,6 +366,26 @@ func verifyBlockHash[P Payload](log *logrus.Entry, payload P, executionPayloadHa
 	return nil
 }
 
+// verifyTransactionsRoot checks that the transactions of the payload match the transactions root of the header
+func verifyTransactionsRoot[P Payload](log *logrus.Entry, payload P, response *builderApi.VersionedSubmitBlindedBlockResponse) error {
+	delivered, err := getDeliveredPayload(response)
+	if err != nil {
+		return err
+	}
+	root, err := (&utilbellatrix.ExecutionPayloadTransactions{Transactions: delivered.transactions}).HashTreeRoot()
+	if err != nil {
+		return err
+	}
+	if phase0.Root(root) != transactionsRoot(payload) {
+		log.WithFields(logrus.Fields{
+			"requestTxRoot":  transactionsRoot(payload).String(),
+			"responseTxRoot": phase0.Root(root).String(),
+		}).Error("requestTxRoot does not equal responseTxRoot")
+		return errInvalidTxRoot
+	}
+	return nil
+}
+
 // verifyKZGCommitments checks that blobs bundle is valid
 func verifyKZGCommitments(log *logrus.Entry, blobs *denebApi.BlobsBundle, commitments []deneb.KZGCommitment) error {
 	// Ensure that blobs are valid and matches the request
@@ -311,13 +481,66 @@ func blockHash[P Payload](payload P) phase0.Hash32 {
 	return nilHash
 }
 
+// blindedBlockID returns the slot and block hash of a decoded signed blinded beacon block of any fork
+func blindedBlockID(payload any) (phase0.Slot, phase0.Hash32) {
+	switch block := payload.(type) {
+	case *eth2ApiV1Bellatrix.SignedBlindedBeaconBlock:
+		return slot(block), blockHash(block)
+	case *eth2ApiV1Capella.SignedBlindedBeaconBlock:
+		return slot(block), blockHash(block)
+	case *eth2ApiV1Deneb.SignedBlindedBeaconBlock:
+		return slot(block), blockHash(block)
+	case *eth2ApiV1Electra.SignedBlindedBeaconBlock:
+		return slot(block), blockHash(block)
+	}
+	return 0, phase0.Hash32{}
+}
+
+// transactionsRoot returns the transactions root of the block's execution payload header
+func transactionsRoot[P Payload](payload P) phase0.Root {
+	switch block := any(payload).(type) {
+	case *eth2ApiV1Bellatrix.SignedBlindedBeaconBlock:
+		return block.Message.Body.ExecutionPayloadHeader.TransactionsRoot
+	case *eth2ApiV1Capella.SignedBlindedBeaconBlock:
+		return block.Message.Body.ExecutionPayloadHeader.TransactionsRoot
+	case *eth2ApiV1Deneb.SignedBlindedBeaconBlock:
+		return block.Message.Body.ExecutionPayloadHeader.TransactionsRoot
+	case *eth2ApiV1Electra.SignedBlindedBeaconBlock:
+		return block.Message.Body.ExecutionPayloadHeader.TransactionsRoot
+	}
+	return phase0.Root{}
+}
+
 // bidKey makes a map key for a specific bid
 func bidKey(slot phase0.Slot, blockHash phase0.Hash32) string {
 	return fmt.Sprintf("%v%v", slot, blockHash)
 }
 
-// getHeader requests a bid from each relay and returns the most profitable one
-func (m *BoostService) getHeader(log *logrus.Entry, ua UserAgent, slot phase0.Slot, pubkey, parentHashHex string) (bidResp, error) {
+// getHeaderDeadline returns the absolute deadline for all relay requests of a getHeader call starting at now: the
+// getHeader timeout of the fork of the slot, but no later than the slot deadline if one is configured
+func (m *BoostService) getHeaderDeadline(slot phase0.Slot, now time.Time) time.Time {
+	deadline := now.Add(m.getHeaderTimeout(slot))
+	if m.getHeaderSlotDeadline <= 0 {
+		return deadline
+	}
+	if slotDeadline := m.slotStart(slot).Add(m.getHeaderSlotDeadline); slotDeadline.Before(deadline) {
+		return slotDeadline
+	}
+	return deadline
+}
+
+// withBudget returns a copy of the headers with the milliseconds left until the deadline at now. Unlike the
+// cutoff timestamp, the budget does not depend on the clock of the relay agreeing with the local clock.
+func withBudget(headers map[string]string, deadline, now time.Time) map[string]string {
+	budgeted := maps.Clone(headers)
+	budgeted[HeaderKeyBudgetMS] = strconv.FormatInt(max(deadline.Sub(now).Milliseconds(), 0), 10)
+	return budgeted
+}
+
+// getHeader requests a bid from each of the relays and returns the most profitable one
+// All relay requests share the deadline, so stragglers cannot delay the response beyond it.
+// If every relay fails, errAllRelaysFailed is returned.
+func (m *BoostService) getHeader(log *logrus.Entry, timer *requestTimer, ua UserAgent, forwarded map[string]string, relays []types.RelayEntry, slot phase0.Slot, pubkey, parentHashHex string, localValue *uint256.Int, deadline time.Time) (bidResp, error) {
 	// Ensure arguments are valid
 	if len(pubkey) != 98 {
 		return bidResp{}, errInvalidPubkey
@@ -337,19 +560,28 @@ func (m *BoostService) getHeader(log *logrus.Entry, ua UserAgent, slot phase0.Sl
 	log = log.WithField("slotUID", slotUID)
 
 	// Log how late into the slot the request starts
-	slotStartTimestamp := m.genesisTime + uint64(slot)*config.SlotTimeSec
-	msIntoSlot := uint64(time.Now().UTC().UnixMilli()) - slotStartTimestamp*1000
+	msIntoSlot := m.msIntoSlot(slot, time.Now())
 	log.WithFields(logrus.Fields{
 		"genesisTime": m.genesisTime,
-		"slotTimeSec": config.SlotTimeSec,
+		"slotTimeSec": m.secondsPerSlot,
 		"msIntoSlot":  msIntoSlot,
 	}).Infof("getHeader request start - %d milliseconds into slot %d", msIntoSlot, slot)
 
+	requestCtx, cancel := context.WithDeadline(withRequestSigner(context.Background(), m.requestSigner), deadline)
+	defer cancel()
+	if budget := time.Until(deadline); budget <= 0 {
+		log.WithField("slotDeadlineMs", m.getHeaderSlotDeadline.Milliseconds()).Warn("getHeader request is past the slot deadline")
+	} else {
+		log = log.WithField("budgetMs", budget.Milliseconds())
+	}
+
 	// Add request headers
 	headers := map[string]string{
 		HeaderKeySlotUID:      slotUID.String(),
 		HeaderStartTimeUnixMS: fmt.Sprintf("%d", time.Now().UTC().UnixMilli()),
+		HeaderKeyCutoffUnixMS: strconv.FormatInt(deadline.UnixMilli(), 10),
 	}
+	addForwardedHeaders(headers, forwarded)
 
 	var (
 		mu sync.Mutex
@@ -358,29 +590,96 @@ func (m *BoostService) getHeader(log *logrus.Entry, ua UserAgent, slot phase0.Sl
 		// The final response, containing the highest bid (if any)
 		result = bidResp{}
 
-		// Relays that sent the bid for a specific blockHash
-		relays = make(map[BlockHashHex][]types.RelayEntry)
+		// All bids which passed validation
+		candidates = []bidCandidate{}
+
+		// Block numbers of the bids with a valid signature, to find relays out of sync
+		blockNumbers []relayBlockNumber
+
+		// Number of relays which responded at all, including errors and no-content responses
+		numRelayResponses atomic.Int32
 	)
 
+	// Relays which asked to retry later are skipped until then, and relays are only asked in the slots of their forks
+	relays = m.relayAdvisories.available(log, relays, time.Now())
+	relays = m.forkRelays(log, relays, slot)
+
+	// The client timeout follows the getHeader timeout of the fork, which may be longer than the default
+	client := m.httpClientGetHeader
+	client.Timeout = m.getHeaderTimeout(slot)
+
 	// Request a bid from each relay
-	for _, relay := range m.relays {
+	timer.mark(timingStageFanout)
+	for _, relay := range relays {
 		wg.Add(1)
 		go func(relay types.RelayEntry) {
 			defer wg.Done()
 
 			// Build the request URL
 			url := relay.GetURI(fmt.Sprintf("/eth/v1/builder/header/%d/%s/%s", slot, parentHashHex, pubkey))
-			log := log.WithField("url", url)
-
-			// Send the get bid request to the relay
-			bid := new(builderSpec.VersionedSignedBuilderBid)
-			code, err := SendHTTPRequest(context.Background(), m.httpClientGetHeader, http.MethodGet, url, ua, headers, nil, bid)
+			log := log.WithFields(logrus.Fields{"relay": relayLabel(relay), "url": url})
+
+			// Use the relay's top bid from its stream if connected, or send the get bid request to the relay
+			var body json.RawMessage
+			var code int
+			var err error
+			requestStart := time.Now()
+			streamed, fromStream := m.topBidStreams[relay.String()].bid(slot, parentHashHex, pubkey)
+			if fromStream {
+				relayTopBidStreamBids.WithLabelValues(relayLabel(relay), "stream").Inc()
+				log = log.WithField("source", "stream")
+				code, body = http.StatusOK, streamed
+			} else {
+				if relay.Stream {
+					relayTopBidStreamBids.WithLabelValues(relayLabel(relay), "request").Inc()
+				}
+				ctx := m.relayAdvisories.observing(withGzipSniffing(requestCtx, relay, log), relay)
+				ctx = m.withConsensusVersionFallback(ctx, log, relay, "getHeader", slot)
+				ctx = m.withContentTypeCheck(ctx, log, relay, "getHeader")
+				code, err = SendHTTPRequest(ctx, client, http.MethodGet, url, ua, withRelayHeaders(withBudget(headers, deadline, time.Now()), relay), nil, &body)
+				m.statsd.timing("relay.latency", time.Since(requestStart), statsdTags{"relay": relayLabel(relay), "method": "getHeader"})
+			}
+			latency := time.Since(requestStart)
+			log = log.WithField("latencyMs", latency.Milliseconds())
+			if errors.Is(err, errEmptyResponseBody) {
+				err = nil
+			}
 			if err != nil {
-				log.WithError(err).Warn("error making request to relay")
+				log.WithFields(m.session.recordRequestError(relay, "getHeader", err)).WithError(err).Warn("error making request to relay")
 				return
 			}
+			numRelayResponses.Add(1)
+			if !fromStream {
+				m.session.recordLatency(relay, "getHeader", latency)
+			}
+
+			// With a slow relay threshold, only responses of slow relays and errors are logged
+			quiet := false
+			if m.slowRelayThreshold > 0 {
+				quiet = latency < m.slowRelayThreshold
+				if !quiet {
+					log.WithField("code", code).Warn("slow relay response")
+				}
+			}
+
 			if code == http.StatusNoContent {
-				log.Debug("no-content response")
+				if !quiet {
+					log.Debug("no-content response")
+				}
+				return
+			}
+			if isNoBidBody(body) {
+				if !quiet {
+					log.WithField("code", code).Debug("empty bid response, no bid")
+				}
+				return
+			}
+			timer.mark(timingStageFirstBid)
+
+			// Decode the bid, checking it against the builder spec
+			bid, err := m.decodeBid(log, relay, body)
+			if err != nil {
+				log.WithError(err).Warn("ignoring bid")
 				return
 			}
 
@@ -411,82 +710,127 @@ func (m *BoostService) getHeader(log *logrus.Entry, ua UserAgent, slot phase0.Sl
 				"value":       valueEth.Text('f', 18),
 			})
 
-			// Ensure the bid uses the correct public key
-			if relay.PublicKey.String() != bidInfo.pubkey.String() {
+			// Ensure the bid uses one of the public keys of the relay
+			if m.validationLevel != ValidationLevelNone && !relay.HasPublicKey(bidInfo.pubkey) {
 				log.Errorf("bid pubkey mismatch. expected: %s - got: %s", relay.PublicKey.String(), bidInfo.pubkey.String())
+				m.alerts.alert(AlertConfigError, "bid pubkey does not match the relay pubkey", logrus.Fields{
+					"relay":         relayLabel(relay),
+					"builderPubkey": bidInfo.pubkey.String(),
+				})
 				return
 			}
 
-			// Verify the relay signature in the relay response
-			if !config.SkipRelaySignatureCheck {
-				ok, err := checkRelaySignature(bid, m.builderSigningDomain, relay.PublicKey)
-				if err != nil {
-					log.WithError(err).Error("error verifying relay signature")
-					return
-				}
-				if !ok {
-					log.Error("failed to verify relay signature")
-					return
+			// Verify the relay signature in the relay response, unless the relay already sent the same bid in this slot
+			if !config.SkipRelaySignatureCheck && m.validationLevel != ValidationLevelNone {
+				if m.bidMemo.verified(relay, slot, body) {
+					relayBidMemoHits.WithLabelValues(relayLabel(relay)).Inc()
+				} else {
+					ok, err := m.verifyRelaySignature(bid, relay, bidInfo.pubkey)
+					if err != nil {
+						log.WithError(err).Error("error verifying relay signature")
+						return
+					}
+					if !ok {
+						log.Error("failed to verify relay signature")
+						m.alerts.alert(AlertConfigError, "relay signature does not verify, check the network of mev-boost and the relay", logrus.Fields{
+							"relay": relayLabel(relay),
+						})
+						return
+					}
+					m.bidMemo.record(relay, slot, body)
 				}
 			}
 
-			// Verify response coherence with proposer's input data
-			if bidInfo.parentHash.String() != parentHashHex {
-				log.WithFields(logrus.Fields{
-					"originalParentHash": parentHashHex,
-					"responseParentHash": bidInfo.parentHash.String(),
-				}).Error("proposer and relay parent hashes are not the same")
-				return
-			}
-
-			// Ignore bids with 0 value
-			isZeroValue := bidInfo.value.IsZero()
-			isEmptyListTxRoot := bidInfo.txRoot.String() == "0x7ffe241ea60187fdb0187bfa22de35d1f9bed7ab061d9401fd47e34a54fbede1"
-			if isZeroValue || isEmptyListTxRoot {
-				log.Warn("ignoring bid with 0 value")
-				return
+			m.session.recordBid(relay)
+			m.bidArrivals.record(m.slotStart(slot), slot, relay, bidInfo.blockHash, bidInfo.blockNumber, bidInfo.value, requestStart, requestStart.Add(latency))
+			mu.Lock()
+			blockNumbers = append(blockNumbers, relayBlockNumber{relay: relayLabel(relay), blockNumber: bidInfo.blockNumber})
+			mu.Unlock()
+			if !quiet {
+				log.Debug("bid received")
 			}
 
-			log.Debug("bid received")
-
-			// Skip if value is lower than the minimum bid
-			if bidInfo.value.CmpBig(m.relayMinBid.BigInt()) == -1 {
-				log.Debug("ignoring bid below min-bid value")
+			// Run the bid through the filter chain, now that the signature authenticated the builder pubkey
+			accepted, deprioritized := m.filterBid(log, &CandidateBid{
+				Relay:          relay,
+				Slot:           slot,
+				ParentHashHex:  parentHashHex,
+				ProposerPubkey: pubkey,
+				LocalValue:     localValue,
+				Bid:            bid,
+				BlockHash:      bidInfo.blockHash,
+				ParentHash:     bidInfo.parentHash,
+				BuilderPubkey:  bidInfo.pubkey,
+				BlockNumber:    bidInfo.blockNumber,
+				TxRoot:         bidInfo.txRoot,
+				Value:          bidInfo.value,
+			}, quiet)
+			if !accepted {
 				return
 			}
 
 			mu.Lock()
 			defer mu.Unlock()
 
-			// Remember which relays delivered which bids (multiple relays might deliver the top bid)
-			relays[BlockHashHex(bidInfo.blockHash.String())] = append(relays[BlockHashHex(bidInfo.blockHash.String())], relay)
-
-			// Compare the bid with already known top bid (if any)
-			if !result.response.IsEmpty() {
-				valueDiff := bidInfo.value.Cmp(result.bidInfo.value)
-				if valueDiff == -1 {
-					// The current bid is less profitable than already known one
-					return
-				} else if valueDiff == 0 {
-					// The current bid is equally profitable as already known one
-					// Use hash as tiebreaker
-					previousBidBlockHash := result.bidInfo.blockHash
-					if bidInfo.blockHash.String() >= previousBidBlockHash.String() {
-						return
-					}
-				}
-			}
-
-			// Use this relay's response as mev-boost response because it's most profitable
-			log.Debug("new best bid")
-			result.response = *bid
-			result.bidInfo = bidInfo
-			result.t = time.Now()
+			candidates = append(candidates, bidCandidate{relay: relay, response: *bid, bidInfo: bidInfo, deprioritized: deprioritized})
 		}(relay)
 	}
 	wg.Wait()
 
-	// Set the winning relays before returning
-	result.relays = relays[BlockHashHex(result.bidInfo.blockHash.String())]
+	// Relays bidding on other block numbers than most relays may be out of sync
+	m.checkBlockNumberAgreement(log, slot, blockNumbers)
+
+	// Resolve bids of several relays for the same block hash which do not agree on the value or builder
+	candidates = m.resolveConflictingBids(log, slot, candidates)
+
+	// Each relay has at most one candidate, so mirrored relays sending the same bid count as several sources
+	result.biddingRelays = len(candidates)
+
+	// Select the winning bid
+	if best, ok := m.selectBestBid(log, candidates); ok {
+		result.response = best.response
+		result.bidInfo = best.bidInfo
+		result.tieBreak = best.tieBreak
+		result.t = time.Now()
+	}
+	timer.mark(timingStageSelected)
+
+	// Set the winning relays before returning, in the order of the relay list (multiple relays might deliver the top bid).
+	// They are copies, so getPayload still reaches them if the relay list is reloaded in the meantime.
+	for _, candidate := range candidates {
+		if !result.response.IsEmpty() && candidate.bidInfo.blockHash == result.bidInfo.blockHash {
+			result.relays = append(result.relays, candidate.relay.Clone())
+		}
+	}
+	slices.SortFunc(result.relays, func(a, b types.RelayEntry) int {
+		return m.relayPosition(a) - m.relayPosition(b)
+	})
+
+	if result.response.IsEmpty() && numRelayResponses.Load() == 0 {
+		return result, errAllRelaysFailed
+	}
 	return result, nil
 }
+
+// cachedBid returns the most recent bid served for the slot, parent hash and proposer, if the slot has not
+// ended yet and the bid is not older than the maximum age
+func (m *BoostService) cachedBid(slot phase0.Slot, parentHashHex, pubkey string) (bidResp, bool) {
+	now := time.Now()
+	if !now.Before(m.slotEnd(slot)) {
+		return bidResp{}, false
+	}
+
+	var cached bidResp
+	for _, bid := range m.bids.slotBids(slot) {
+		if bid.slot != slot || bid.bidInfo.parentHash.String() != parentHashHex || bid.proposerPubkey != pubkey {
+			continue
+		}
+		if m.cachedBidMaxAge > 0 && now.Sub(bid.t) > m.cachedBidMaxAge {
+			continue
+		}
+		if cached.response.IsEmpty() || bid.t.After(cached.t) {
+			cached = bid
+		}
+	}
+	return cached, !cached.response.IsEmpty()
+}
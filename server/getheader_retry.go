@@ -0,0 +1,75 @@
+package server
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/sirupsen/logrus"
+)
+
+var errInvalidRetryTimeoutFraction = errors.New("getHeader retry timeout fraction must be between 0 and 1")
+
+// auctionedHeadersMaxSlotAge is the number of slots after which auctions are forgotten
+const auctionedHeadersMaxSlotAge = 2
+
+// auctionKey identifies the auction of a getHeader request
+type auctionKey struct {
+	slot       phase0.Slot
+	parentHash string
+	pubkey     string
+}
+
+// auctionedHeaders remembers which slot, parent hash and proposer were auctioned, to recognize getHeader retries
+type auctionedHeaders struct {
+	mu       sync.Mutex
+	auctions map[auctionKey]bool
+}
+
+func newAuctionedHeaders() *auctionedHeaders {
+	return &auctionedHeaders{
+		auctions: make(map[auctionKey]bool),
+	}
+}
+
+// record remembers the auction, and returns true if it was already auctioned before
+func (a *auctionedHeaders) record(slot phase0.Slot, parentHashHex, pubkey string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for key := range a.auctions {
+		if key.slot+auctionedHeadersMaxSlotAge < slot {
+			delete(a.auctions, key)
+		}
+	}
+
+	key := auctionKey{slot: slot, parentHash: parentHashHex, pubkey: pubkey}
+	retry := a.auctions[key]
+	a.auctions[key] = true
+	return retry
+}
+
+// retryDeadline shortens the deadline of a getHeader retry to the retry fraction of the remaining budget
+func (m *BoostService) retryDeadline(deadline, now time.Time) time.Time {
+	if !now.Before(deadline) {
+		return deadline
+	}
+	return now.Add(time.Duration(float64(deadline.Sub(now)) * m.retryTimeoutFraction))
+}
+
+// preferCachedBid returns the bid served before for the same auction if it is more valuable than the bid of
+// the retry, or if no relay responded to the retry
+func (m *BoostService) preferCachedBid(log *logrus.Entry, fresh bidResp, err error, slot phase0.Slot, parentHashHex, pubkey string) (bidResp, error) {
+	if err != nil && !errors.Is(err, errAllRelaysFailed) {
+		return fresh, err
+	}
+	cached, ok := m.cachedBid(slot, parentHashHex, pubkey)
+	if ok && (fresh.response.IsEmpty() || cached.bidInfo.value.Gt(fresh.bidInfo.value)) {
+		log.WithField("retryBid", "cached").Info("getHeader retry serves the bid of the first auction")
+		cached.servedFromCache = true
+		return cached, nil
+	}
+	log.WithField("retryBid", "fresh").Info("getHeader retry serves a fresh bid")
+	return fresh, err
+}
@@ -0,0 +1,98 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	builderSpec "github.com/attestantio/go-builder-client/spec"
+	"github.com/attestantio/go-eth2-client/spec"
+	"github.com/flashbots/mev-boost/config"
+	"github.com/flashbots/mev-boost/server/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuctionedHeaders(t *testing.T) {
+	a := newAuctionedHeaders()
+	require.False(t, a.record(1, "0x01", "0xaa"))
+	require.True(t, a.record(1, "0x01", "0xaa"))
+	require.False(t, a.record(1, "0x02", "0xaa"), "another parent hash is another auction")
+	require.False(t, a.record(1, "0x01", "0xbb"), "another proposer is another auction")
+
+	// Old auctions are forgotten
+	require.False(t, a.record(2+auctionedHeadersMaxSlotAge, "0x01", "0xaa"))
+	require.Len(t, a.auctions, 1)
+}
+
+func TestRetryDeadline(t *testing.T) {
+	now := time.Now()
+	m := &BoostService{retryTimeoutFraction: 0.25}
+	require.Equal(t, now.Add(250*time.Millisecond), m.retryDeadline(now.Add(time.Second), now))
+	require.Equal(t, now.Add(-time.Second), m.retryDeadline(now.Add(-time.Second), now), "a deadline in the past is kept")
+}
+
+func TestGetHeaderRetry(t *testing.T) {
+	const (
+		pubkey    = "0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249"
+		firstHash = "0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7"
+		retryHash = "0xcf8e0d4e9587369b2301d0790347320302cc0943d5a1884560367e8208d920f2"
+	)
+	parentHash := mock.HexToHash("0x0000000000000000000000000000000000000000000000000000000000000001")
+	path := getHeaderPath(1, parentHash, mock.HexToPubkey(pubkey))
+
+	// auction runs the first auction of slot 1, after which the relay bids the value for another block
+	// with the delay
+	auction := func(t *testing.T, retryValue uint64, delay time.Duration) *testBackend {
+		t.Helper()
+		backend := newTestBackend(t, 1, time.Second)
+		backend.boost.genesisTime = uint64(time.Now().Unix()) - config.SlotTimeSec
+		backend.boost.retryTimeoutFraction = 0.2
+		backend.relays[0].GetHeaderResponse = backend.relays[0].MakeGetHeaderResponse(
+			20000, firstHash, parentHash.String(), pubkey, spec.DataVersionDeneb)
+		rr := backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+		backend.relays[0].GetHeaderResponse = backend.relays[0].MakeGetHeaderResponse(
+			retryValue, retryHash, parentHash.String(), pubkey, spec.DataVersionDeneb)
+		backend.relays[0].ResponseDelay = delay
+		return backend
+	}
+	servedBlockHash := func(t *testing.T, backend *testBackend) string {
+		t.Helper()
+		rr := backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		bid := new(builderSpec.VersionedSignedBuilderBid)
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), bid))
+		blockHash, err := bid.BlockHash()
+		require.NoError(t, err)
+		return blockHash.String()
+	}
+
+	t.Run("Retry is cut off at the reduced budget and served the first bid", func(t *testing.T) {
+		backend := auction(t, 30000, 500*time.Millisecond)
+		start := time.Now()
+		require.Equal(t, firstHash, servedBlockHash(t, backend))
+		require.Less(t, time.Since(start), 450*time.Millisecond)
+	})
+
+	t.Run("Retry is served a more valuable fresh bid", func(t *testing.T) {
+		backend := auction(t, 30000, 0)
+		require.Equal(t, retryHash, servedBlockHash(t, backend))
+	})
+
+	t.Run("Retry is served the first bid if it is more valuable", func(t *testing.T) {
+		backend := auction(t, 15000, 0)
+		require.Equal(t, firstHash, servedBlockHash(t, backend))
+	})
+
+	t.Run("Another slot is a first auction with the full budget", func(t *testing.T) {
+		backend := auction(t, 30000, 500*time.Millisecond)
+		path := getHeaderPath(2, parentHash, mock.HexToPubkey(pubkey))
+		start := time.Now()
+		rr := backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		require.Contains(t, rr.Body.String(), retryHash)
+		require.GreaterOrEqual(t, time.Since(start), 500*time.Millisecond)
+	})
+}
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/flashbots/mev-boost/server/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+// gzipMagic are the first bytes of every gzip stream
+var gzipMagic = []byte{0x1f, 0x8b}
+
+var relayGzipSniffed = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "relay_gzip_sniffed_total",
+	Help: "Number of relay responses which were gzip compressed without a Content-Encoding header and were decompressed",
+}, []string{"relay"})
+
+// gzipSniffKey is the context key of the relay whose responses SendHTTPRequest decompresses if they are gzip
+// compressed without a Content-Encoding header
+type gzipSniffKey struct{}
+
+type gzipSniffing struct {
+	relay string
+	log   *logrus.Entry
+}
+
+// withGzipSniffing returns a context in which SendHTTPRequest decompresses gzip response bodies without a
+// Content-Encoding header, if the relay has the sniff-gzip option
+func withGzipSniffing(ctx context.Context, relay types.RelayEntry, log *logrus.Entry) context.Context {
+	if !relay.SniffGzip {
+		return ctx
+	}
+	return context.WithValue(ctx, gzipSniffKey{}, gzipSniffing{relay: relayLabel(relay), log: log})
+}
+
+// sniffGzip returns the decompressed body if sniffing is enabled in the context, and the body starts with the gzip
+// magic bytes although the response has no Content-Encoding header. Otherwise it returns the body as is.
+func sniffGzip(ctx context.Context, header http.Header, body []byte, maxSize int64) ([]byte, error) {
+	sniffing, ok := ctx.Value(gzipSniffKey{}).(gzipSniffing)
+	if !ok || header.Get("Content-Encoding") != "" || !bytes.HasPrefix(body, gzipMagic) {
+		return body, nil
+	}
+
+	reader, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("could not decompress gzip response body: %w", err)
+	}
+	defer reader.Close()
+	if maxSize <= 0 {
+		maxSize = DefaultMaxPayloadResponseSize
+	}
+	decompressed, err := io.ReadAll(io.LimitReader(reader, maxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("could not decompress gzip response body: %w", err)
+	}
+	if int64(len(decompressed)) > maxSize {
+		return nil, fmt.Errorf("%w: more than %d bytes decompressed", errResponseTooLarge, maxSize)
+	}
+
+	relayGzipSniffed.WithLabelValues(sniffing.relay).Inc()
+	sniffing.log.WithFields(logrus.Fields{
+		"compressedSize":   len(body),
+		"decompressedSize": len(decompressed),
+	}).Info("decompressed gzip response of the relay without a Content-Encoding header")
+	return decompressed, nil
+}
@@ -0,0 +1,101 @@
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/attestantio/go-eth2-client/spec"
+	"github.com/flashbots/mev-boost/server/mock"
+	"github.com/flashbots/mev-boost/server/params"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func gzipBody(t *testing.T, body []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, err := w.Write(body)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func TestSendHTTPRequestSniffGzip(t *testing.T) {
+	value := strings.Repeat("a", 1000)
+	compressed := gzipBody(t, []byte(`{"foo":"`+value+`"}`))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(compressed)
+	}))
+	defer server.Close()
+	relay := mock.NewRelay(t).RelayEntry
+
+	var dst map[string]string
+	_, err := SendHTTPRequest(context.Background(), *http.DefaultClient, http.MethodGet, server.URL, "test", nil, nil, &dst)
+	require.Error(t, err, "sniffing is off by default")
+
+	ctx := withGzipSniffing(context.Background(), relay, mock.TestLog)
+	require.Equal(t, context.Background(), ctx, "the relay does not have the sniff-gzip option")
+
+	relay.SniffGzip = true
+	before := testutil.ToFloat64(relayGzipSniffed.WithLabelValues(relayLabel(relay)))
+	ctx = withGzipSniffing(context.Background(), relay, mock.TestLog)
+	_, err = SendHTTPRequest(ctx, *http.DefaultClient, http.MethodGet, server.URL, "test", nil, nil, &dst)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"foo": value}, dst)
+	require.InDelta(t, 1, testutil.ToFloat64(relayGzipSniffed.WithLabelValues(relayLabel(relay)))-before, 0)
+
+	// The decompressed body is subject to the maximum response size
+	_, err = SendHTTPRequest(withMaxResponseSize(ctx, int64(len(compressed))), *http.DefaultClient, http.MethodGet, server.URL, "test", nil, nil, &dst)
+	require.ErrorIs(t, err, errResponseTooLarge)
+}
+
+func TestGetHeaderSniffGzip(t *testing.T) {
+	parentHash := mock.HexToHash("0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7")
+	pubkey := "0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249"
+	path := getHeaderPath(1, parentHash, mock.HexToPubkey(pubkey))
+
+	backend := newTestBackend(t, 1, time.Second)
+	response := backend.relays[0].MakeGetHeaderResponse(12345, parentHash.String(), parentHash.String(), pubkey, spec.DataVersionDeneb)
+	body, err := json.Marshal(response)
+	require.NoError(t, err)
+	compressed := gzipBody(t, body)
+	backend.relays[0].OverrideHandleGetHeader(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(compressed)
+	})
+
+	rr := backend.request(t, http.MethodGet, path, nil)
+	require.Equal(t, http.StatusNoContent, rr.Code, rr.Body.String())
+
+	backend.boost.relays[0].SniffGzip = true
+	rr = backend.request(t, http.MethodGet, path, nil)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.Contains(t, rr.Body.String(), parentHash.String())
+}
+
+func TestGetPayloadSniffGzip(t *testing.T) {
+	block, response := loadDenebBlock(t)
+	body, err := json.Marshal(response)
+	require.NoError(t, err)
+	compressed := gzipBody(t, body)
+
+	backend := newTestBackend(t, 1, time.Second)
+	backend.boost.relays[0].SniffGzip = true
+	backend.relays[0].OverrideHandleGetPayload(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(compressed)
+	})
+
+	rr := backend.request(t, http.MethodPost, params.PathGetPayload, block)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.Equal(t, string(body), rr.Body.String(), "the decompressed bytes are passed through")
+}
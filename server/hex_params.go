@@ -0,0 +1,21 @@
+package server
+
+import (
+	"encoding/hex"
+	"strings"
+)
+
+// normalizeHexParam returns the lowercase, 0x prefixed form of a hex path parameter of the given length in
+// bytes and the decoded bytes. The prefix is optional in the parameter, since some clients omit it.
+func normalizeHexParam(value string, length int) (string, []byte, bool) {
+	digits := strings.ToLower(value)
+	digits = strings.TrimPrefix(digits, "0x")
+	if len(digits) != 2*length {
+		return "", nil, false
+	}
+	b, err := hex.DecodeString(digits)
+	if err != nil {
+		return "", nil, false
+	}
+	return "0x" + digits, b, true
+}
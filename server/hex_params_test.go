@@ -0,0 +1,107 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testParentHashHex = "0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7"
+	testPubkeyHex     = "0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249"
+)
+
+func TestNormalizeHexParam(t *testing.T) {
+	testCases := []struct {
+		name     string
+		value    string
+		length   int
+		expected string
+	}{
+		{name: "Normalized", value: testParentHashHex, length: 32, expected: testParentHashHex},
+		{name: "Mixed case", value: "0xE28385E7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3AB7", length: 32, expected: testParentHashHex},
+		{name: "Uppercase prefix", value: "0XE28385E7BD68DF656CD0042B74B69C3104B5356ED1F20EB69F1F925DF47A3AB7", length: 32, expected: testParentHashHex},
+		{name: "Missing prefix", value: strings.TrimPrefix(testPubkeyHex, "0x"), length: 48, expected: testPubkeyHex},
+		{name: "Empty", value: "", length: 32},
+		{name: "Only prefix", value: "0x", length: 32},
+		{name: "Too short", value: testParentHashHex[:64], length: 32},
+		{name: "Too long", value: testParentHashHex + "00", length: 32},
+		{name: "Odd length", value: testParentHashHex + "0", length: 32},
+		{name: "Invalid hex", value: "0xg28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7", length: 32},
+		{name: "Hash as pubkey", value: testParentHashHex, length: 48},
+		{name: "Double prefix", value: "0x0x" + testParentHashHex[6:], length: 32},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			normalized, b, ok := normalizeHexParam(tt.value, tt.length)
+			require.Equal(t, tt.expected != "", ok)
+			require.Equal(t, tt.expected, normalized)
+			if ok {
+				require.Len(t, b, tt.length)
+			}
+		})
+	}
+}
+
+func TestGetHeaderHexParams(t *testing.T) {
+	// A valid length pubkey which is not a point on the curve
+	invalidPoint := "0x" + strings.Repeat("ff", 48)
+
+	testCases := []struct {
+		name       string
+		parentHash string
+		pubkey     string
+		strict     bool
+		code       int
+		message    string
+	}{
+		{name: "Normalized", parentHash: testParentHashHex, pubkey: testPubkeyHex, code: http.StatusOK},
+		{name: "Mixed case and missing prefixes", parentHash: strings.ToUpper(testParentHashHex[2:]), pubkey: "0X" + strings.ToUpper(testPubkeyHex[2:]), code: http.StatusOK},
+		{name: "Invalid hex hash", parentHash: "0xzz" + testParentHashHex[4:], pubkey: testPubkeyHex, code: http.StatusBadRequest, message: "invalid hash: 0xzz" + testParentHashHex[4:]},
+		{name: "Short hash", parentHash: "0x1234", pubkey: testPubkeyHex, code: http.StatusBadRequest, message: "invalid hash: 0x1234"},
+		{name: "Invalid hex pubkey", parentHash: testParentHashHex, pubkey: testPubkeyHex[:96] + "zz", code: http.StatusBadRequest, message: "invalid pubkey: " + testPubkeyHex[:96] + "zz"},
+		{name: "Short pubkey", parentHash: testParentHashHex, pubkey: "8a1d", code: http.StatusBadRequest, message: "invalid pubkey: 8a1d"},
+		{name: "Pubkey not on the curve", parentHash: testParentHashHex, pubkey: invalidPoint, code: http.StatusNoContent},
+		{name: "Pubkey not on the curve, strict", parentHash: testParentHashHex, pubkey: invalidPoint, strict: true, code: http.StatusBadRequest, message: "invalid pubkey: " + invalidPoint},
+		{name: "Valid pubkey, strict", parentHash: testParentHashHex, pubkey: testPubkeyHex, strict: true, code: http.StatusOK},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := newTestBackend(t, 1, time.Second)
+			backend.boost.strictPubkeyCheck = tt.strict
+			var relayPath string
+			if tt.code != http.StatusOK {
+				backend.relays[0].OverrideHandleGetHeader(func(w http.ResponseWriter, req *http.Request) {
+					relayPath = req.URL.Path
+					w.WriteHeader(http.StatusNoContent)
+				})
+			}
+
+			rr := backend.request(t, http.MethodGet, fmt.Sprintf("/eth/v1/builder/header/1/%s/%s", tt.parentHash, tt.pubkey), nil)
+			require.Equal(t, tt.code, rr.Code, rr.Body.String())
+			if tt.message != "" {
+				require.JSONEq(t, fmt.Sprintf(`{"code":400,"message":%q}`, tt.message), rr.Body.String())
+				require.Empty(t, relayPath)
+			}
+			if tt.code == http.StatusNoContent {
+				require.Equal(t, fmt.Sprintf("/eth/v1/builder/header/1/%s/%s", testParentHashHex, tt.pubkey), relayPath)
+			}
+		})
+	}
+
+	t.Run("Relays receive the normalized form", func(t *testing.T) {
+		backend := newTestBackend(t, 1, time.Second)
+		var relayPath string
+		backend.relays[0].OverrideHandleGetHeader(func(w http.ResponseWriter, req *http.Request) {
+			relayPath = req.URL.Path
+			w.WriteHeader(http.StatusNoContent)
+		})
+		rr := backend.request(t, http.MethodGet, fmt.Sprintf("/eth/v1/builder/header/1/%s/%s", strings.ToUpper(testParentHashHex[2:]), strings.ToUpper(testPubkeyHex[2:])), nil)
+		require.Equal(t, http.StatusNoContent, rr.Code, rr.Body.String())
+		require.Equal(t, fmt.Sprintf("/eth/v1/builder/header/1/%s/%s", testParentHashHex, testPubkeyHex), relayPath)
+	})
+}
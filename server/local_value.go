@@ -0,0 +1,68 @@
+package server
+
+import (
+	"errors"
+	"math"
+
+	"github.com/flashbots/mev-boost/server/types"
+	"github.com/holiman/uint256"
+	"github.com/sirupsen/logrus"
+)
+
+var errInvalidMinBidOverLocal = errors.New("min bid over local percentage cannot be negative")
+
+// Sources of the percentage a bid must exceed the local block value by
+const (
+	localThresholdSourceGlobal = "global"
+	localThresholdSourceRelay  = "relay"
+)
+
+// localThreshold is the value a bid must reach to win over the local block
+type localThreshold struct {
+	localValue *uint256.Int
+	pct        float64
+	source     string
+	value      *uint256.Int
+}
+
+// logFields returns the fields which describe the threshold in logs
+func (t localThreshold) logFields() logrus.Fields {
+	return logrus.Fields{
+		"localValue":           t.localValue.Dec(),
+		"localThreshold":       t.value.Dec(),
+		"localThresholdPct":    t.pct,
+		"localThresholdSource": t.source,
+	}
+}
+
+// localBlockThreshold returns the value bids of the relay must reach to win over the local block value. The
+// percentage over the local value is the one of the relay (?min-over-local-pct=N), otherwise the global one.
+func (m *BoostService) localBlockThreshold(relay types.RelayEntry, localValue *uint256.Int) localThreshold {
+	t := localThreshold{localValue: localValue, pct: m.minBidOverLocalPct, source: localThresholdSourceGlobal}
+	if relay.MinOverLocalPct != nil {
+		t.pct = *relay.MinOverLocalPct
+		t.source = localThresholdSourceRelay
+	}
+	bps := uint256.NewInt(uint64(math.Round(t.pct * 100)))
+	margin, mulOverflow := new(uint256.Int).MulDivOverflow(localValue, bps, uint256.NewInt(10000))
+	value, addOverflow := new(uint256.Int).AddOverflow(localValue, margin)
+	t.value = value
+	if mulOverflow || addOverflow {
+		t.value = new(uint256.Int).SetAllOne()
+	}
+	return t
+}
+
+// Bids must exceed the local block value supplied by the beacon node by the configured percentage
+func (m *BoostService) filterMinOverLocal(log *logrus.Entry, bid *CandidateBid) BidFilterResult {
+	if bid.LocalValue == nil {
+		return BidFilterResult{Verdict: BidAccept}
+	}
+	threshold := m.localBlockThreshold(bid.Relay, bid.LocalValue)
+	if bid.Value.Lt(threshold.value) {
+		relayBidsRejected.WithLabelValues(relayLabel(bid.Relay), "below_local_threshold").Inc()
+		log.WithFields(threshold.logFields()).Info("ignoring bid which does not beat the local block value by enough")
+		return BidFilterResult{Verdict: BidReject, Reason: "below the local block value threshold"}
+	}
+	return BidFilterResult{Verdict: BidAccept}
+}
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/attestantio/go-eth2-client/spec"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/flashbots/mev-boost/server/mock"
+	"github.com/flashbots/mev-boost/server/types"
+	"github.com/holiman/uint256"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalBlockThreshold(t *testing.T) {
+	relayPct := 10.0
+	m := &BoostService{minBidOverLocalPct: 5}
+	tests := []struct {
+		name      string
+		relay     types.RelayEntry
+		local     *uint256.Int
+		threshold uint64
+		source    string
+	}{
+		{name: "global percentage", local: uint256.NewInt(1000), threshold: 1050, source: localThresholdSourceGlobal},
+		{name: "relay percentage", relay: types.RelayEntry{MinOverLocalPct: &relayPct}, local: uint256.NewInt(1000), threshold: 1100, source: localThresholdSourceRelay},
+		{name: "zero local value", local: uint256.NewInt(0), threshold: 0, source: localThresholdSourceGlobal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			threshold := m.localBlockThreshold(tt.relay, tt.local)
+			require.Equal(t, tt.threshold, threshold.value.Uint64())
+			require.Equal(t, tt.source, threshold.source)
+		})
+	}
+
+	// The threshold saturates instead of overflowing
+	threshold := m.localBlockThreshold(types.RelayEntry{}, new(uint256.Int).SetAllOne())
+	require.Equal(t, new(uint256.Int).SetAllOne(), threshold.value)
+}
+
+func TestGetHeaderMinOverLocal(t *testing.T) {
+	const (
+		pubkey = "0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249"
+		hashA  = "0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7"
+		hashB  = "0xcf8e0d4e9587369b2301d0790347320302cc0943d5a1884560367e8208d920f2"
+	)
+	parentHash := phase0.Hash32{0x01}
+	path := getHeaderPath(1, parentHash, mock.HexToPubkey(pubkey))
+
+	// Relay 0 bids 20000 and must beat the local value by 50%, relay 1 bids 15000 with the global 10%
+	newBackend := func(t *testing.T) *testBackend {
+		t.Helper()
+		backend := newTestBackend(t, 2, time.Second)
+		backend.boost.minBidOverLocalPct = 10
+		pct := 50.0
+		backend.boost.relays[0].MinOverLocalPct = &pct
+		backend.relays[0].GetHeaderResponse = backend.relays[0].MakeGetHeaderResponse(
+			20000, hashA, parentHash.String(), pubkey, spec.DataVersionDeneb)
+		backend.relays[1].GetHeaderResponse = backend.relays[1].MakeGetHeaderResponse(
+			15000, hashB, parentHash.String(), pubkey, spec.DataVersionDeneb)
+		return backend
+	}
+	request := func(t *testing.T, backend *testBackend, localValue string) *httptest.ResponseRecorder {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodGet, path, nil)
+		require.NoError(t, err)
+		if localValue != "" {
+			req.Header.Set(HeaderKeyLocalBlockValue, localValue)
+		}
+		rr := httptest.NewRecorder()
+		backend.boost.getRouter().ServeHTTP(rr, req)
+		return rr
+	}
+
+	tests := []struct {
+		name       string
+		localValue string
+		code       int
+		blockHash  string
+	}{
+		{name: "no local value", code: http.StatusOK, blockHash: hashA},
+		{name: "both bids beat the local value", localValue: "13000", code: http.StatusOK, blockHash: hashA},
+		{name: "relay threshold rejects the best bid", localValue: "13500", code: http.StatusOK, blockHash: hashB},
+		{name: "no bid beats the local value", localValue: "14000", code: http.StatusNoContent},
+		{name: "invalid local value is ignored", localValue: "0x10", code: http.StatusOK, blockHash: hashA},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := request(t, newBackend(t), tt.localValue)
+			require.Equal(t, tt.code, rr.Code, rr.Body.String())
+			if tt.blockHash != "" {
+				require.Contains(t, rr.Body.String(), tt.blockHash)
+			}
+		})
+	}
+}
@@ -0,0 +1,221 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"runtime"
+	"time"
+
+	"github.com/flashbots/mev-boost/config"
+	"github.com/flashbots/mev-boost/server/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sirupsen/logrus"
+)
+
+var errMetricsServerAlreadyRunning = errors.New("metrics server already running")
+
+// prometheusRegistry holds all mev-boost metrics, it is served by the metrics server
+var prometheusRegistry = prometheus.NewRegistry()
+
+var relayBidSchemaViolations = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "relay_bid_schema_violations_total",
+	Help: "Number of builder spec violations found in relay getHeader responses",
+}, []string{"relay", "kind"})
+
+var relayBidsRejected = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "relay_bids_rejected_total",
+	Help: "Number of valid relay bids rejected by the mev-boost configuration",
+}, []string{"relay", "reason"})
+
+var relayPaymentDiscrepancies = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "relay_payment_discrepancies_total",
+	Help: "Number of delivered payloads in which the proposer received less than the bid value, by relay and builder pubkey",
+}, []string{"relay", "builder"})
+
+var relayRequestErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "relay_request_errors_total",
+	Help: "Number of requests to relays which failed, excluding requests aborted by mev-boost",
+}, []string{"relay", "method"})
+
+var relayRequestsAborted = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "relay_requests_aborted_total",
+	Help: "Number of requests to relays which were cancelled or exceeded their deadline",
+}, []string{"relay", "method", "reason"})
+
+var builderBidsWon = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "builder_bids_won_total",
+	Help: "Number of getHeader requests won by a bid of the builder, across relays",
+}, []string{"builder"})
+
+var duplicateGetHeaderRequests = prometheus.NewCounter(prometheus.CounterOpts{
+	Name: "duplicate_getheader_requests_total",
+	Help: "Number of getHeader requests for a slot and validator which another client already requested a header for",
+})
+
+var apiAuthFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "api_auth_failures_total",
+	Help: "Number of requests rejected because of missing or invalid authentication, by beacon client",
+}, []string{"client"})
+
+// Per tenant metrics, the tenant is resolved from the validator pubkey of each request
+var (
+	tenantAuctions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "tenant_auctions_total",
+		Help: "Number of getHeader requests",
+	}, []string{"tenant"})
+
+	tenantBidsWon = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "tenant_bids_won_total",
+		Help: "Number of getHeader requests for which a bid was returned",
+	}, []string{"tenant"})
+
+	tenantPayloadsDelivered = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "tenant_payloads_delivered_total",
+		Help: "Number of payloads delivered to the proposer",
+	}, []string{"tenant"})
+
+	tenantRegistrationsForwarded = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "tenant_registrations_forwarded_total",
+		Help: "Number of validator registrations accepted by at least one relay",
+	}, []string{"tenant"})
+)
+
+// buildInfo is always 1, and identifies the running build by its labels
+var buildInfo = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "build_info",
+	Help: "Build information of the running mev-boost binary",
+}, []string{"version", "commit", "go_version"})
+
+func init() {
+	prometheusRegistry.MustRegister(
+		buildInfo,
+		relayBidSchemaViolations,
+		relayBidsRejected,
+		staleBids,
+		conflictingBids,
+		relayBlockNumberDisagreements,
+		bidStoreLockWait,
+		bidCacheEntries,
+		bidCacheEvictions,
+		relayForkSkips,
+		configReloads,
+		bidFilterRejections,
+		relayRedirectBlocked,
+		relayBidMemoHits,
+		relayBidPubkeys,
+		relayPaymentDiscrepancies,
+		relayPayloadValueShortfall,
+		relayPayloadRejections,
+		relayGzipSniffed,
+		relayUnexpectedContentTypes,
+		relayRequestErrors,
+		relayTLSErrors,
+		relayDeprecationWarnings,
+		relayRetryAfterBackoffs,
+		relayConnectionPrewarms,
+		relayRequestsAborted,
+		relayTopBidStreamConnected,
+		relayTopBidStreamDisconnects,
+		relayTopBidStreamBids,
+		builderBidsWon,
+		consensusVersionChecks,
+		relayConsensusVersionInferred,
+		relayRequestRetries,
+		selfMonitorGoroutines,
+		selfMonitorOpenFDs,
+		selfMonitorHeapBytes,
+		selfMonitorLimitsExceeded,
+		selfMonitorActions,
+		duplicateGetHeaderRequests,
+		getHeaderRelayFailureResponses,
+		chaosFaultsInjected,
+		relayBidSigningDomains,
+		relayRegisteredValidators,
+		bidFeeRecipientMismatches,
+		feeRecipientChanges,
+		feeRecipientRejections,
+		auctionBiddingRelays,
+		lowConfidenceAuctions,
+		registrationSignatureRejections,
+		activeRelaysGauge,
+		relayFloorRefusals,
+		coldStartRecoveries,
+		alertsSent,
+		payloadResponseWriteDuration,
+		payloadResponseWriteDeadlineExceeded,
+		apiAuthFailures,
+		clientRequestsRejected,
+		statsdMetricsDropped,
+		tenantAuctions,
+		tenantBidsWon,
+		tenantPayloadsDelivered,
+		tenantRegistrationsForwarded,
+		auctionsTotal,
+		auctionBidsServed,
+		registrationBatchesForwarded,
+		registrationsForwarded,
+		payloadsTotal,
+		relayBidsReceived,
+		relayBidsWon,
+		relayPayloads,
+		relayRequestDuration,
+	)
+	buildInfo.WithLabelValues(config.Version, config.Commit, runtime.Version()).Set(1)
+}
+
+// relayLabel returns the identifier of a relay used in logs and metric labels, its label or host
+func relayLabel(relay types.RelayEntry) string {
+	return relay.Name()
+}
+
+// countRelayRequestError counts a failed relay request, separating requests which were cancelled or timed out
+// on the mev-boost side from relay errors. It returns the log fields detailing TLS errors.
+func countRelayRequestError(relay types.RelayEntry, method string, err error) logrus.Fields {
+	switch {
+	case errors.Is(err, context.Canceled):
+		relayRequestsAborted.WithLabelValues(relayLabel(relay), method, "canceled").Inc()
+	case relayRequestAborted(err):
+		relayRequestsAborted.WithLabelValues(relayLabel(relay), method, "deadline_exceeded").Inc()
+	default:
+		relayRequestErrors.WithLabelValues(relayLabel(relay), method).Inc()
+		return countRelayTLSError(relay, method, err)
+	}
+	return logrus.Fields{}
+}
+
+// relayRequestAborted returns true if the relay request was cancelled or timed out on the mev-boost side
+func relayRequestAborted(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || os.IsTimeout(err)
+}
+
+// StartMetricsServer starts the HTTP server exposing prometheus metrics
+func (m *BoostService) StartMetricsServer() error {
+	m.srvLock.Lock()
+	if m.metricsSrv != nil {
+		m.srvLock.Unlock()
+		return errMetricsServerAlreadyRunning
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(prometheusRegistry, promhttp.HandlerOpts{}))
+
+	srv := &http.Server{
+		Addr:    m.metricsAddr,
+		Handler: mux,
+
+		ReadTimeout:       time.Duration(config.ServerReadTimeoutMs) * time.Millisecond,
+		ReadHeaderTimeout: time.Duration(config.ServerReadHeaderTimeoutMs) * time.Millisecond,
+		IdleTimeout:       time.Duration(config.ServerIdleTimeoutMs) * time.Millisecond,
+	}
+	m.metricsSrv = srv
+	m.srvLock.Unlock()
+
+	err := srv.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
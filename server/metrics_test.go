@@ -0,0 +1,69 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/flashbots/mev-boost/config"
+	"github.com/flashbots/mev-boost/server/mock"
+	"github.com/flashbots/mev-boost/server/params"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildInfo(t *testing.T) {
+	require.Equal(t, 1, testutil.CollectAndCount(buildInfo))
+	require.InDelta(t, 1, testutil.ToFloat64(buildInfo.WithLabelValues(config.Version, config.Commit, runtime.Version())), 0)
+}
+
+func TestCountRelayRequestError(t *testing.T) {
+	testCases := []struct {
+		name    string
+		timeout time.Duration
+		delay   time.Duration
+		code    int
+		reason  string
+	}{
+		{name: "Relay error response", timeout: time.Second, code: http.StatusInternalServerError},
+		{name: "Client timeout", timeout: 10 * time.Millisecond, delay: 50 * time.Millisecond, code: http.StatusOK, reason: "deadline_exceeded"},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			relay := mock.NewRelay(t)
+			relay.ResponseDelay = tt.delay
+			relay.OverrideHandleGetHeader(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(tt.code)
+			})
+			label := relayLabel(relay.RelayEntry)
+
+			client := http.Client{Timeout: tt.timeout}
+			_, err := SendHTTPRequest(context.Background(), client, http.MethodGet, relay.RelayEntry.GetURI(getHeaderPath(1, phase0.Hash32{}, phase0.BLSPubKey{})), "", nil, nil, nil)
+			require.Error(t, err)
+			countRelayRequestError(relay.RelayEntry, "getHeader", err)
+
+			if tt.reason == "" {
+				require.InDelta(t, 1, testutil.ToFloat64(relayRequestErrors.WithLabelValues(label, "getHeader")), 0)
+			} else {
+				require.InDelta(t, 0, testutil.ToFloat64(relayRequestErrors.WithLabelValues(label, "getHeader")), 0)
+				require.InDelta(t, 1, testutil.ToFloat64(relayRequestsAborted.WithLabelValues(label, "getHeader", tt.reason)), 0)
+			}
+		})
+	}
+
+	t.Run("Cancelled request", func(t *testing.T) {
+		relay := mock.NewRelay(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := SendHTTPRequest(ctx, http.Client{}, http.MethodGet, relay.RelayEntry.GetURI(params.PathStatus), "", nil, nil, nil)
+		require.Error(t, err)
+		countRelayRequestError(relay.RelayEntry, "status", err)
+
+		label := relayLabel(relay.RelayEntry)
+		require.InDelta(t, 0, testutil.ToFloat64(relayRequestErrors.WithLabelValues(label, "status")), 0)
+		require.InDelta(t, 1, testutil.ToFloat64(relayRequestsAborted.WithLabelValues(label, "status", "canceled")), 0)
+	})
+}
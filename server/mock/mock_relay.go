@@ -359,3 +359,10 @@ func (m *Relay) OverrideHandleGetPayload(method func(w http.ResponseWriter, req
 
 	m.handlerOverrideGetPayload = method
 }
+
+func (m *Relay) OverrideHandleGetHeader(method func(w http.ResponseWriter, req *http.Request)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.handlerOverrideGetHeader = method
+}
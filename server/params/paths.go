@@ -4,6 +4,35 @@ const (
 	// Router paths
 	PathStatus            = "/eth/v1/builder/status"
 	PathRegisterValidator = "/eth/v1/builder/validators"
-	PathGetHeader         = "/eth/v1/builder/header/{slot:[0-9]+}/{parent_hash:0x[a-fA-F0-9]+}/{pubkey:0x[a-fA-F0-9]+}"
+	PathGetHeader         = "/eth/v1/builder/header/{slot:[0-9]+}/{parent_hash}/{pubkey}"
 	PathGetPayload        = "/eth/v1/builder/blinded_blocks"
+
+	// PathHealthz reports that mev-boost is running, it does not require authentication
+	PathHealthz = "/healthz"
+
+	// Debug paths, only served with debug endpoints enabled
+	PathDebugFailedDeliveries = "/debug/failed-deliveries"
+	PathDebugRegistrations    = "/debug/registrations"
+	PathDebugRelays           = "/debug/relays"
+	PathDebugWithholding      = "/debug/withholding"
+	PathDebugConfig           = "/debug/config"
+	PathDebugFeeRecipients    = "/debug/fee-recipients"
+	PathDebugBidArrivals      = "/debug/bid-arrivals"
+
+	// PathPrefixDebug is the common prefix of the debug paths
+	PathPrefixDebug = "/debug/"
+
+	// Admin paths, only served with admin endpoints enabled
+	PathAdminBuilderDenylist = "/admin/builder-denylist"
+	PathDebugBidsFlush       = "/debug/bids/flush"
+
+	// PathAdminDrain stops serving new getHeader requests, and shuts down once the in-flight getPayload
+	// requests are done
+	PathAdminDrain = "/drain"
+
+	// PathTopBidStream is the server-sent events stream of top bids, served by relays which support it
+	PathTopBidStream = "/relay/v1/builder/top_bids"
+
+	// PathPrefixGetHeader is the static part of PathGetHeader
+	PathPrefixGetHeader = "/eth/v1/builder/header/"
 )
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"sync"
+	"time"
+
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+)
+
+// cachedPayload is a payload delivered to the beacon node, kept until the end of its slot
+type cachedPayload struct {
+	payload *relayPayload
+	expiry  time.Time
+}
+
+// deliveredPayloads caches the payloads delivered in the current slot, so that a beacon node retrying getPayload
+// receives the same payload again without requesting it from the relays another time
+type deliveredPayloads struct {
+	mu       sync.Mutex
+	payloads map[string]cachedPayload // bidKey -> payload
+}
+
+func newDeliveredPayloads() *deliveredPayloads {
+	return &deliveredPayloads{
+		payloads: make(map[string]cachedPayload),
+	}
+}
+
+// add caches the payload for the block until the slot ends at expiry, and forgets payloads of slots which ended
+func (d *deliveredPayloads) add(slot phase0.Slot, blockHash phase0.Hash32, payload *relayPayload, expiry, now time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for key, payload := range d.payloads {
+		if !now.Before(payload.expiry) {
+			delete(d.payloads, key)
+		}
+	}
+	if now.Before(expiry) {
+		d.payloads[bidKey(slot, blockHash)] = cachedPayload{payload: payload, expiry: expiry}
+	}
+}
+
+// get returns the payload delivered for the block, if its slot has not ended yet
+func (d *deliveredPayloads) get(slot phase0.Slot, blockHash phase0.Hash32, now time.Time) (*relayPayload, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	payload, ok := d.payloads[bidKey(slot, blockHash)]
+	if !ok || !now.Before(payload.expiry) {
+		return nil, false
+	}
+	return payload.payload, true
+}
+
+// len returns the number of cached payloads, including payloads of ended slots which were not forgotten yet
+func (d *deliveredPayloads) len() int {
+	if d == nil {
+		return 0
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.payloads)
+}
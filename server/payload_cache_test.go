@@ -0,0 +1,70 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	builderApi "github.com/attestantio/go-builder-client/api"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/flashbots/mev-boost/config"
+	"github.com/flashbots/mev-boost/server/params"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeliveredPayloads(t *testing.T) {
+	now := time.Now()
+	response := &relayPayload{response: &builderApi.VersionedSubmitBlindedBlockResponse{}}
+	hash := phase0.Hash32{0x01}
+
+	d := newDeliveredPayloads()
+	d.add(100, hash, response, now.Add(time.Second), now)
+	cached, ok := d.get(100, hash, now)
+	require.True(t, ok)
+	require.Same(t, response, cached)
+	_, ok = d.get(100, phase0.Hash32{0x02}, now)
+	require.False(t, ok)
+
+	// Payloads are only returned until the end of their slot
+	_, ok = d.get(100, hash, now.Add(time.Second))
+	require.False(t, ok)
+
+	// Payloads of slots which ended are not cached, and forgotten when adding another payload
+	d.add(99, hash, response, now, now)
+	_, ok = d.get(99, hash, now.Add(-time.Second))
+	require.False(t, ok)
+	require.Len(t, d.payloads, 1)
+	d.add(101, hash, response, now.Add(2*time.Second), now.Add(time.Second))
+	require.Len(t, d.payloads, 1)
+}
+
+func TestGetPayloadRepeated(t *testing.T) {
+	t.Run("Payload is returned again within the slot", func(t *testing.T) {
+		block, response := loadDenebBlock(t)
+		backend := newTestBackend(t, 1, time.Second)
+		backend.boost.genesisTime = uint64(time.Now().Unix()) - uint64(block.Message.Slot)*config.SlotTimeSec
+		backend.relays[0].GetPayloadResponse = response
+
+		rr := backend.request(t, http.MethodPost, params.PathGetPayload, block)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		first := rr.Body.String()
+
+		rr = backend.request(t, http.MethodPost, params.PathGetPayload, block)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		require.Equal(t, first, rr.Body.String())
+		require.Equal(t, "deneb", rr.Header().Get(HeaderEthConsensusVersion))
+		require.Equal(t, 1, backend.relays[0].GetRequestCount(params.PathGetPayload))
+	})
+
+	t.Run("Payload is requested again after the slot", func(t *testing.T) {
+		block, response := loadDenebBlock(t)
+		backend := newTestBackend(t, 1, time.Second)
+		backend.relays[0].GetPayloadResponse = response
+
+		rr := backend.request(t, http.MethodPost, params.PathGetPayload, block)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		rr = backend.request(t, http.MethodPost, params.PathGetPayload, block)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		require.Equal(t, 2, backend.relays[0].GetRequestCount(params.PathGetPayload))
+	})
+}
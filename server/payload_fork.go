@@ -0,0 +1,42 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+var errUnknownBlindedBlockFork = errors.New("could not determine the fork of the signed blinded beacon block")
+
+// blindedBlockForkFields are the block body fields introduced by each fork, newest fork first. Blocks
+// without any of them are bellatrix blocks.
+var blindedBlockForkFields = []struct {
+	fork  string
+	field string
+}{
+	{fork: "electra", field: "execution_requests"},
+	{fork: "deneb", field: "blob_kzg_commitments"},
+	{fork: "capella", field: "bls_to_execution_changes"},
+}
+
+// detectBlindedBlockFork returns the fork of a JSON signed blinded beacon block from the fields of its body,
+// so it can be decoded once into the type of that fork
+func detectBlindedBlockFork(body []byte) (string, error) {
+	var block struct {
+		Message struct {
+			Body map[string]json.RawMessage `json:"body"`
+		} `json:"message"`
+	}
+	if err := json.Unmarshal(body, &block); err != nil {
+		return "", err
+	}
+	fields := block.Message.Body
+	for _, f := range blindedBlockForkFields {
+		if _, ok := fields[f.field]; ok {
+			return f.fork, nil
+		}
+	}
+	if _, ok := fields["execution_payload_header"]; ok {
+		return "bellatrix", nil
+	}
+	return "", errUnknownBlindedBlockFork
+}
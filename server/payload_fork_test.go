@@ -0,0 +1,35 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectBlindedBlockFork(t *testing.T) {
+	testCases := []struct {
+		name string
+		body string
+		fork string
+		err  bool
+	}{
+		{name: "Electra", body: `{"message":{"body":{"execution_payload_header":{},"bls_to_execution_changes":[],"blob_kzg_commitments":[],"execution_requests":{}}}}`, fork: "electra"},
+		{name: "Deneb", body: `{"message":{"body":{"execution_payload_header":{},"bls_to_execution_changes":[],"blob_kzg_commitments":[]}}}`, fork: "deneb"},
+		{name: "Capella", body: `{"message":{"body":{"execution_payload_header":{},"bls_to_execution_changes":[]}}}`, fork: "capella"},
+		{name: "Bellatrix", body: `{"message":{"body":{"execution_payload_header":{}}}}`, fork: "bellatrix"},
+		{name: "No execution payload header", body: `{"message":{"body":{}}}`, err: true},
+		{name: "Not a block", body: `{"slot":"1"}`, err: true},
+		{name: "Invalid JSON", body: `{"message":`, err: true},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			fork, err := detectBlindedBlockFork([]byte(tt.body))
+			if tt.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.fork, fork)
+		})
+	}
+}
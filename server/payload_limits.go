@@ -0,0 +1,74 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	builderApi "github.com/attestantio/go-builder-client/api"
+	denebApi "github.com/attestantio/go-builder-client/api/deneb"
+	"github.com/attestantio/go-eth2-client/spec"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	errResponseTooLarge = errors.New("response body too large")
+	errTooManyBlobs     = errors.New("too many blobs")
+
+	errPayloadRequestTooLarge = errors.New("getPayload request body too large")
+
+	relayPayloadRejections = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "relay_payload_rejections_total",
+		Help: "Number of getPayload responses rejected before processing them, by reason (response_too_large or too_many_blobs)",
+	}, []string{"relay", "reason"})
+)
+
+// maxBlobsPerBlock is the maximum number of blobs in a block of each fork with blobs
+var maxBlobsPerBlock = map[spec.DataVersion]int{
+	spec.DataVersionDeneb:   6,
+	spec.DataVersionElectra: 9,
+}
+
+// maxResponseSizeKey is the context key of the maximum response body size read by SendHTTPRequest
+type maxResponseSizeKey struct{}
+
+// withMaxResponseSize returns a context in which SendHTTPRequest fails with errResponseTooLarge instead of
+// reading response bodies larger than size bytes
+func withMaxResponseSize(ctx context.Context, size int64) context.Context {
+	if size <= 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, maxResponseSizeKey{}, size)
+}
+
+// readLimitedBody reads a request body, and returns errPayloadRequestTooLarge if it is larger than maxSize bytes
+func readLimitedBody(body io.Reader, maxSize int64) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(body, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > maxSize {
+		return nil, errPayloadRequestTooLarge
+	}
+	return data, nil
+}
+
+// checkBlobCount returns errTooManyBlobs if the blobs bundle of the payload exceeds the maximum of its fork
+func checkBlobCount(response *builderApi.VersionedSubmitBlindedBlockResponse) error {
+	var bundle *denebApi.BlobsBundle
+	switch {
+	case response.Version == spec.DataVersionDeneb && response.Deneb != nil:
+		bundle = response.Deneb.BlobsBundle
+	case response.Version == spec.DataVersionElectra && response.Electra != nil:
+		bundle = response.Electra.BlobsBundle
+	}
+	if bundle == nil {
+		return nil
+	}
+	count := max(len(bundle.Blobs), len(bundle.Commitments), len(bundle.Proofs))
+	if limit := maxBlobsPerBlock[response.Version]; count > limit {
+		return fmt.Errorf("%w: %d blobs, the maximum of %s is %d", errTooManyBlobs, count, response.Version, limit)
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	builderApi "github.com/attestantio/go-builder-client/api"
+	denebApi "github.com/attestantio/go-builder-client/api/deneb"
+	"github.com/attestantio/go-eth2-client/spec"
+	"github.com/attestantio/go-eth2-client/spec/deneb"
+	"github.com/flashbots/mev-boost/server/params"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func testBlobsBundle(n int) *denebApi.BlobsBundle {
+	return &denebApi.BlobsBundle{
+		Blobs:       make([]deneb.Blob, n),
+		Commitments: make([]deneb.KZGCommitment, n),
+		Proofs:      make([]deneb.KZGProof, n),
+	}
+}
+
+func TestCheckBlobCount(t *testing.T) {
+	testCases := []struct {
+		name     string
+		response *builderApi.VersionedSubmitBlindedBlockResponse
+		err      error
+	}{
+		{
+			name:     "Deneb maximum",
+			response: &builderApi.VersionedSubmitBlindedBlockResponse{Version: spec.DataVersionDeneb, Deneb: &denebApi.ExecutionPayloadAndBlobsBundle{BlobsBundle: testBlobsBundle(6)}},
+		},
+		{
+			name:     "Deneb too many",
+			response: &builderApi.VersionedSubmitBlindedBlockResponse{Version: spec.DataVersionDeneb, Deneb: &denebApi.ExecutionPayloadAndBlobsBundle{BlobsBundle: testBlobsBundle(7)}},
+			err:      errTooManyBlobs,
+		},
+		{
+			name: "Deneb too many proofs",
+			response: &builderApi.VersionedSubmitBlindedBlockResponse{Version: spec.DataVersionDeneb, Deneb: &denebApi.ExecutionPayloadAndBlobsBundle{BlobsBundle: &denebApi.BlobsBundle{
+				Proofs: make([]deneb.KZGProof, 100),
+			}}},
+			err: errTooManyBlobs,
+		},
+		{
+			name:     "Deneb without bundle",
+			response: &builderApi.VersionedSubmitBlindedBlockResponse{Version: spec.DataVersionDeneb, Deneb: &denebApi.ExecutionPayloadAndBlobsBundle{}},
+		},
+		{
+			name:     "Capella has no blobs",
+			response: &builderApi.VersionedSubmitBlindedBlockResponse{Version: spec.DataVersionCapella},
+		},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			require.ErrorIs(t, checkBlobCount(tt.response), tt.err)
+		})
+	}
+}
+
+func TestReadLimitedBody(t *testing.T) {
+	body, err := readLimitedBody(strings.NewReader("abcd"), 4)
+	require.NoError(t, err)
+	require.Equal(t, []byte("abcd"), body)
+
+	_, err = readLimitedBody(strings.NewReader("abcde"), 4)
+	require.ErrorIs(t, err, errPayloadRequestTooLarge)
+}
+
+func TestSendHTTPRequestMaxResponseSize(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		size, _ := strconv.Atoi(req.URL.Query().Get("size"))
+		body := `"` + strings.Repeat("a", size-2) + `"`
+		if req.URL.Query().Get("chunked") == "" {
+			w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		}
+		_, _ = w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	testCases := []struct {
+		query string
+		err   error
+	}{
+		{query: "size=1000"},
+		{query: "size=1000&chunked=1"},
+		{query: "size=1001", err: errResponseTooLarge},
+		{query: "size=1001&chunked=1", err: errResponseTooLarge},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.query, func(t *testing.T) {
+			var dst string
+			ctx := withMaxResponseSize(context.Background(), 1000)
+			_, err := SendHTTPRequest(ctx, *http.DefaultClient, http.MethodGet, server.URL+"?"+tt.query, "", nil, nil, &dst)
+			require.ErrorIs(t, err, tt.err)
+		})
+	}
+}
+
+func TestGetPayloadLimits(t *testing.T) {
+	t.Run("Oversized response fails over to another relay", func(t *testing.T) {
+		backend := newTestBackend(t, 2, 5*time.Second)
+		backend.boost.maxPayloadResponseSize = 1 << 20
+		block, response := loadDenebBlock(t)
+		backend.relays[1].GetPayloadResponse = response
+
+		// The first relay sends an endless response, slower than the second relay sends a valid one
+		backend.relays[0].OverrideHandleGetPayload(func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"version":"deneb","data":"`))
+			chunk := []byte(strings.Repeat("a", 64<<10))
+			for {
+				if _, err := w.Write(chunk); err != nil {
+					return
+				}
+			}
+		})
+		backend.relays[1].OverrideHandleGetPayload(func(w http.ResponseWriter, _ *http.Request) {
+			time.Sleep(200 * time.Millisecond)
+			backend.relays[1].DefaultHandleGetPayload(w)
+		})
+
+		rejections := relayPayloadRejections.WithLabelValues(relayLabel(backend.relays[0].RelayEntry), "response_too_large")
+		before := testutil.ToFloat64(rejections)
+		start := time.Now()
+		rr := backend.request(t, http.MethodPost, params.PathGetPayload, block)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		require.Less(t, time.Since(start), 2*time.Second)
+		require.Eventually(t, func() bool {
+			return testutil.ToFloat64(rejections) == before+1
+		}, time.Second, 10*time.Millisecond)
+		require.Equal(t, 1, backend.relays[0].GetRequestCount(params.PathGetPayload))
+	})
+
+	t.Run("Oversized request is rejected", func(t *testing.T) {
+		backend := newTestBackend(t, 1, time.Second)
+		backend.boost.maxPayloadRequestSize = 1 << 10
+		router := backend.boost.getRouter()
+		body := strings.Repeat("a", 1<<10+1)
+
+		// With and without a Content-Length header
+		for _, contentLength := range []int64{int64(len(body)), -1} {
+			req := httptest.NewRequest(http.MethodPost, params.PathGetPayload, strings.NewReader(body))
+			req.ContentLength = contentLength
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+			require.Equal(t, http.StatusRequestEntityTooLarge, rr.Code, contentLength)
+			require.Contains(t, rr.Body.String(), errPayloadRequestTooLarge.Error())
+		}
+		require.Equal(t, 0, backend.relays[0].GetRequestCount(params.PathGetPayload))
+
+		// A body of the maximum size is read, and rejected as invalid
+		req := httptest.NewRequest(http.MethodPost, params.PathGetPayload, strings.NewReader(body[1:]))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("Too many blobs", func(t *testing.T) {
+		backend := newTestBackend(t, 2, 5*time.Second)
+		block, response := loadDenebBlock(t)
+		tooManyBlobs := *response
+		tooManyBlobs.Deneb = &denebApi.ExecutionPayloadAndBlobsBundle{
+			ExecutionPayload: response.Deneb.ExecutionPayload,
+			BlobsBundle:      testBlobsBundle(7),
+		}
+		backend.relays[0].GetPayloadResponse = &tooManyBlobs
+		backend.relays[1].GetPayloadResponse = response
+
+		rejections := relayPayloadRejections.WithLabelValues(relayLabel(backend.relays[0].RelayEntry), "too_many_blobs")
+		before := testutil.ToFloat64(rejections)
+		// The valid payload is only sent once the response of the first relay was rejected, as the first
+		// successful response cancels the other requests
+		backend.relays[1].OverrideHandleGetPayload(func(w http.ResponseWriter, req *http.Request) {
+			for testutil.ToFloat64(rejections) == before {
+				select {
+				case <-req.Context().Done():
+					return
+				case <-time.After(10 * time.Millisecond):
+				}
+			}
+			backend.relays[1].DefaultHandleGetPayload(w)
+		})
+
+		rr := backend.request(t, http.MethodPost, params.PathGetPayload, block)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		require.InDelta(t, before+1, testutil.ToFloat64(rejections), 0)
+	})
+}
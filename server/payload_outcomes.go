@@ -0,0 +1,111 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+)
+
+var errPayloadAlreadyDelivered = errors.New("payload for this block was already delivered")
+
+// payloadOutcomeMaxSlotAge is the number of slots after which a getPayload outcome is forgotten
+const payloadOutcomeMaxSlotAge = 32
+
+// payloadOutcome is the result of a getPayload request for a block
+type payloadOutcome struct {
+	Slot      phase0.Slot   `json:"slot,string"`
+	BlockHash phase0.Hash32 `json:"block_hash"`
+	Delivered bool          `json:"delivered"`
+	// Response identifies the bytes of the payload response written to the beacon node
+	Response *writtenResponse `json:"response,omitempty"`
+}
+
+// payloadOutcomes persists the outcomes of recent getPayload requests, so that a beacon node retrying getPayload
+// after a restart of mev-boost is recognized as a duplicate submission. All methods are no-ops on a nil value.
+type payloadOutcomes struct {
+	mu       sync.Mutex
+	path     string
+	outcomes []payloadOutcome
+}
+
+// newPayloadOutcomes loads the outcomes from the file, which does not need to exist yet
+func newPayloadOutcomes(path string) (*payloadOutcomes, error) {
+	p := &payloadOutcomes{path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return p, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &p.outcomes); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// lookup returns the recorded outcome for the block, if any
+func (p *payloadOutcomes) lookup(slot phase0.Slot, blockHash phase0.Hash32) (payloadOutcome, bool) {
+	if p == nil {
+		return payloadOutcome{}, false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, outcome := range p.outcomes {
+		if outcome.Slot == slot && outcome.BlockHash == blockHash {
+			return outcome, true
+		}
+	}
+	return payloadOutcome{}, false
+}
+
+// record stores the outcome for the block, forgets outcomes which are too old, and writes the outcomes to the file
+func (p *payloadOutcomes) record(slot phase0.Slot, blockHash phase0.Hash32, delivered bool) error {
+	if p == nil {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	outcomes := make([]payloadOutcome, 0, len(p.outcomes)+1)
+	for _, outcome := range p.outcomes {
+		if outcome.Slot+payloadOutcomeMaxSlotAge < slot || (outcome.Slot == slot && outcome.BlockHash == blockHash) {
+			continue
+		}
+		outcomes = append(outcomes, outcome)
+	}
+	p.outcomes = append(outcomes, payloadOutcome{Slot: slot, BlockHash: blockHash, Delivered: delivered})
+	return p.save()
+}
+
+// recordResponse stores the response written to the beacon node with the outcome for the block
+func (p *payloadOutcomes) recordResponse(slot phase0.Slot, blockHash phase0.Hash32, response writtenResponse) error {
+	if p == nil {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i := range p.outcomes {
+		if p.outcomes[i].Slot == slot && p.outcomes[i].BlockHash == blockHash {
+			p.outcomes[i].Response = &response
+			return p.save()
+		}
+	}
+	return nil
+}
+
+// save writes the outcomes to the file, the lock must be held
+func (p *payloadOutcomes) save() error {
+	data, err := json.Marshal(p.outcomes)
+	if err != nil {
+		return err
+	}
+	// Write to a temporary file first, so that a crash does not leave a truncated file behind
+	tmp := p.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, p.path)
+}
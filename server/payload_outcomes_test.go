@@ -0,0 +1,102 @@
+package server
+
+import (
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/flashbots/mev-boost/server/params"
+	"github.com/sirupsen/logrus"
+	logrusTest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPayloadOutcomes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outcomes.json")
+	outcomes, err := newPayloadOutcomes(path)
+	require.NoError(t, err)
+
+	require.NoError(t, outcomes.record(1, phase0.Hash32{0x01}, false))
+	require.NoError(t, outcomes.record(2, phase0.Hash32{0x02}, true))
+	require.NoError(t, outcomes.record(1, phase0.Hash32{0x01}, true))
+
+	reloaded, err := newPayloadOutcomes(path)
+	require.NoError(t, err)
+	outcome, ok := reloaded.lookup(1, phase0.Hash32{0x01})
+	require.True(t, ok)
+	require.True(t, outcome.Delivered)
+	_, ok = reloaded.lookup(1, phase0.Hash32{0x02})
+	require.False(t, ok)
+
+	// Old outcomes are pruned
+	require.NoError(t, reloaded.record(2+payloadOutcomeMaxSlotAge, phase0.Hash32{0x03}, true))
+	_, ok = reloaded.lookup(1, phase0.Hash32{0x01})
+	require.False(t, ok)
+	_, ok = reloaded.lookup(2, phase0.Hash32{0x02})
+	require.True(t, ok)
+}
+
+func TestGetPayloadAfterRestart(t *testing.T) {
+	// withholdingLevel returns the level of the log entry reporting that no payload was received
+	withholdingLevel := func(t *testing.T, hook *logrusTest.Hook) logrus.Level {
+		t.Helper()
+		for _, entry := range hook.AllEntries() {
+			if strings.HasPrefix(entry.Message, "no payload received from relay") {
+				return entry.Level
+			}
+		}
+		require.Fail(t, "withholding was not logged")
+		return 0
+	}
+
+	// restart returns a new backend with the outcomes persisted by the previous one
+	restart := func(t *testing.T, path string) (*testBackend, *logrusTest.Hook) {
+		t.Helper()
+		outcomes, err := newPayloadOutcomes(path)
+		require.NoError(t, err)
+		logger, hook := logrusTest.NewNullLogger()
+		backend := newTestBackend(t, 1, time.Second)
+		backend.boost.log = logrus.NewEntry(logger)
+		backend.boost.payloadOutcomes = outcomes
+		return backend, hook
+	}
+
+	t.Run("Delivered payload is not requested again", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "outcomes.json")
+		block, response := loadDenebBlock(t)
+
+		backend, _ := restart(t, path)
+		backend.relays[0].GetPayloadResponse = response
+		rr := backend.request(t, http.MethodPost, params.PathGetPayload, block)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+		backend, _ = restart(t, path)
+		rr = backend.request(t, http.MethodPost, params.PathGetPayload, block)
+		require.Equal(t, http.StatusConflict, rr.Code, rr.Body.String())
+		require.Equal(t, 0, backend.relays[0].GetRequestCount(params.PathGetPayload))
+	})
+
+	t.Run("Failed delivery is retried without reporting it again", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "outcomes.json")
+		block, _ := loadDenebBlock(t)
+		failing := func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+
+		backend, hook := restart(t, path)
+		backend.relays[0].OverrideHandleGetPayload(failing)
+		rr := backend.request(t, http.MethodPost, params.PathGetPayload, block)
+		require.Equal(t, http.StatusBadGateway, rr.Code, rr.Body.String())
+		require.Equal(t, logrus.ErrorLevel, withholdingLevel(t, hook))
+
+		backend, hook = restart(t, path)
+		backend.relays[0].OverrideHandleGetPayload(failing)
+		rr = backend.request(t, http.MethodPost, params.PathGetPayload, block)
+		require.Equal(t, http.StatusBadGateway, rr.Code, rr.Body.String())
+		require.Positive(t, backend.relays[0].GetRequestCount(params.PathGetPayload))
+		require.Equal(t, logrus.WarnLevel, withholdingLevel(t, hook))
+	})
+}
@@ -0,0 +1,47 @@
+package server
+
+import (
+	"math/big"
+
+	builderApi "github.com/attestantio/go-builder-client/api"
+	"github.com/flashbots/mev-boost/server/types"
+	"github.com/holiman/uint256"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+var relayPayloadValueShortfall = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "relay_payload_value_shortfall_ratio",
+	Help:    "Shortfall of the proposer payment in delivered payloads relative to the value declared in the bid, 0 if the payment was at least the declared value",
+	Buckets: []float64{0, 0.001, 0.01, 0.05, 0.1, 0.25, 0.5, 1},
+}, []string{"relay"})
+
+// comparePayloadValue adds the value declared in the bid and the proposer payment in the payload delivered by the
+// relay to the log. The payment is only known if the builder paid the proposer with the last transaction of the
+// block, it does not include priority fees, so the comparison is approximate.
+func comparePayloadValue(log *logrus.Entry, relay types.RelayEntry, declared *uint256.Int, response *builderApi.VersionedSubmitBlindedBlockResponse) *logrus.Entry {
+	if declared == nil {
+		return log
+	}
+	log = log.WithField("declaredValue", declared.Dec())
+	payload, err := getDeliveredPayload(response)
+	if err != nil {
+		return log
+	}
+	tx := proposerPaymentTx(payload)
+	if tx == nil {
+		return log.WithField("deliveredValue", "unknown")
+	}
+
+	delivered := tx.Value()
+	diff := new(big.Int).Sub(declared.ToBig(), delivered)
+	shortfall := 0.0
+	if diff.Sign() > 0 && !declared.IsZero() {
+		shortfall, _ = new(big.Rat).SetFrac(diff, declared.ToBig()).Float64()
+	}
+	relayPayloadValueShortfall.WithLabelValues(relayLabel(relay)).Observe(shortfall)
+	return log.WithFields(logrus.Fields{
+		"deliveredValue": delivered.String(),
+		"valueDiff":      diff.String(),
+	})
+}
@@ -0,0 +1,77 @@
+package server
+
+import (
+	"testing"
+
+	builderApi "github.com/attestantio/go-builder-client/api"
+	"github.com/attestantio/go-eth2-client/spec"
+	"github.com/attestantio/go-eth2-client/spec/bellatrix"
+	"github.com/attestantio/go-eth2-client/spec/capella"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/flashbots/mev-boost/server/types"
+	"github.com/holiman/uint256"
+	"github.com/sirupsen/logrus"
+	logrusTest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComparePayloadValue(t *testing.T) {
+	proposer := common.HexToAddress("0xdb65fEd33dc262Fe09D9a2Ba8F80b329BA25f941")
+	paymentTx, builder := signedPaymentTx(t, proposer, 1000)
+	relay, err := types.NewRelayEntry("http://0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249@value.relay.com")
+	require.NoError(t, err)
+
+	response := func(feeRecipient common.Address, transactions ...bellatrix.Transaction) *builderApi.VersionedSubmitBlindedBlockResponse {
+		return &builderApi.VersionedSubmitBlindedBlockResponse{
+			Version: spec.DataVersionCapella,
+			Capella: &capella.ExecutionPayload{
+				FeeRecipient: bellatrix.ExecutionAddress(feeRecipient),
+				Transactions: transactions,
+			},
+		}
+	}
+
+	testCases := []struct {
+		name     string
+		declared *uint256.Int
+		response *builderApi.VersionedSubmitBlindedBlockResponse
+		expected logrus.Fields
+	}{
+		{
+			name:     "Payment matches the declared value",
+			declared: uint256.NewInt(1000),
+			response: response(builder, paymentTx),
+			expected: logrus.Fields{"declaredValue": "1000", "deliveredValue": "1000", "valueDiff": "0"},
+		},
+		{
+			name:     "Relay over-reported the value",
+			declared: uint256.NewInt(1500),
+			response: response(builder, bellatrix.Transaction{0x01}, paymentTx),
+			expected: logrus.Fields{"declaredValue": "1500", "deliveredValue": "1000", "valueDiff": "500"},
+		},
+		{
+			name:     "No payment transaction",
+			declared: uint256.NewInt(1000),
+			response: response(proposer, paymentTx),
+			expected: logrus.Fields{"declaredValue": "1000", "deliveredValue": "unknown"},
+		},
+		{
+			name:     "Unknown payload version",
+			declared: uint256.NewInt(1000),
+			response: &builderApi.VersionedSubmitBlindedBlockResponse{},
+			expected: logrus.Fields{"declaredValue": "1000"},
+		},
+		{
+			name:     "No bid",
+			response: response(builder, paymentTx),
+			expected: logrus.Fields{},
+		},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, hook := logrusTest.NewNullLogger()
+			comparePayloadValue(logrus.NewEntry(logger), relay, tt.declared, tt.response).Info("received payload from relay")
+			require.Equal(t, tt.expected, hook.LastEntry().Data)
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	builderApi "github.com/attestantio/go-builder-client/api"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+// minPayloadWriteBudget is the time a getPayload response may take to be written, even if its slot ends sooner
+const minPayloadWriteBudget = time.Second
+
+// How the getPayload response is written to the beacon node
+const (
+	payloadWritePassthrough = "passthrough"
+	payloadWriteEncoded     = "encoded"
+)
+
+var (
+	payloadResponseWriteDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "getpayload_response_write_seconds",
+		Help:    "Time to serialize and write the getPayload response to the beacon node, by whether the relay's bytes were passed through or the payload was encoded again",
+		Buckets: []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5},
+	}, []string{"mode"})
+	payloadResponseWriteDeadlineExceeded = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "getpayload_response_write_deadline_exceeded_total",
+		Help: "Number of getPayload responses which could not be written to the beacon node before the end of their slot",
+	})
+)
+
+// relayPayload is the payload delivered by a relay, and the JSON it was decoded from, which is written to the
+// beacon node as is once the payload is verified
+type relayPayload struct {
+	response *builderApi.VersionedSubmitBlindedBlockResponse
+	raw      []byte
+}
+
+// UnmarshalJSON decodes the response and keeps a copy of its bytes
+func (p *relayPayload) UnmarshalJSON(b []byte) error {
+	if p.response == nil {
+		p.response = new(builderApi.VersionedSubmitBlindedBlockResponse)
+	}
+	if err := json.Unmarshal(b, p.response); err != nil {
+		p.raw = nil
+		return err
+	}
+	p.raw = bytes.Clone(b)
+	return nil
+}
+
+// writeTo writes the relay's bytes if they are known, otherwise it encodes the response, and returns which was done
+func (p *relayPayload) writeTo(w io.Writer) (string, error) {
+	if len(p.raw) > 0 {
+		_, err := w.Write(p.raw)
+		return payloadWritePassthrough, err
+	}
+	return payloadWriteEncoded, json.NewEncoder(w).Encode(p.response)
+}
+
+// payloadWriteDeadline returns the time by which the getPayload response for the slot must be written: the end of
+// the slot, as the payload is of no use afterwards, but at least minPayloadWriteBudget from now
+func (m *BoostService) payloadWriteDeadline(slot phase0.Slot, now time.Time) time.Time {
+	deadline := m.slotEnd(slot)
+	if floor := now.Add(minPayloadWriteBudget); deadline.Before(floor) {
+		return floor
+	}
+	return deadline
+}
+
+// writePayload writes the payload as the body of the response before the write deadline of its slot, so that a slow
+// connection does not hold the payload in memory after the slot ended. body wraps w to capture the written bytes.
+func (m *BoostService) writePayload(w http.ResponseWriter, body io.Writer, log *logrus.Entry, payload *relayPayload, slot phase0.Slot) {
+	start := time.Now()
+	deadline := m.payloadWriteDeadline(slot, start)
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(deadline); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		log.WithError(err).Warn("could not set the write deadline of the getPayload response")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	mode, err := payload.writeTo(body)
+	payloadResponseWriteDuration.WithLabelValues(mode).Observe(time.Since(start).Seconds())
+	log = log.WithFields(logrus.Fields{
+		"writeMode":       mode,
+		"writeDurationMs": time.Since(start).Milliseconds(),
+	})
+	switch {
+	case err == nil:
+		log.Debug("getPayload response written")
+	case errors.Is(err, os.ErrDeadlineExceeded) || !time.Now().Before(deadline):
+		payloadResponseWriteDeadlineExceeded.Inc()
+		log.WithError(err).WithField("writeDeadline", deadline).Error("getPayload response could not be written to the beacon node before the deadline")
+	default:
+		log.WithField("response", redactJSON(payload.response)).WithError(err).Error("could not write the getPayload response to the beacon node")
+	}
+}
@@ -0,0 +1,153 @@
+package server
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	builderApi "github.com/attestantio/go-builder-client/api"
+	builderApiDeneb "github.com/attestantio/go-builder-client/api/deneb"
+	"github.com/attestantio/go-eth2-client/spec"
+	"github.com/attestantio/go-eth2-client/spec/deneb"
+	"github.com/flashbots/mev-boost/config"
+	"github.com/flashbots/mev-boost/server/mock"
+	"github.com/flashbots/mev-boost/server/params"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetPayloadPassthrough(t *testing.T) {
+	block, response := loadDenebBlock(t)
+	relayBody, err := json.MarshalIndent(response, "", "  ")
+	require.NoError(t, err)
+
+	backend := newTestBackend(t, 1, time.Second)
+	backend.relays[0].OverrideHandleGetPayload(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(relayBody)
+	})
+
+	// The beacon node receives the bytes of the relay, not a new encoding of the payload
+	rr := backend.request(t, http.MethodPost, params.PathGetPayload, block)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.Equal(t, string(relayBody), rr.Body.String())
+	require.Equal(t, "deneb", rr.Header().Get(HeaderEthConsensusVersion))
+}
+
+func TestRelayPayloadWriteTo(t *testing.T) {
+	_, response := loadDenebBlock(t)
+	raw, err := json.Marshal(response)
+	require.NoError(t, err)
+
+	payload := new(relayPayload)
+	require.NoError(t, json.Unmarshal(raw, payload))
+	require.Equal(t, raw, payload.raw)
+	require.Equal(t, response, payload.response)
+
+	// Without the relay's bytes, the payload is encoded again
+	payload.raw = nil
+	w := new(countingWriter)
+	mode, err := payload.writeTo(w)
+	require.NoError(t, err)
+	require.Equal(t, payloadWriteEncoded, mode)
+	require.Equal(t, int64(len(raw)+1), w.n)
+
+	require.Error(t, json.Unmarshal([]byte(`{"version":"deneb","data":1}`), payload))
+	require.Nil(t, payload.raw)
+}
+
+func TestPayloadWriteDeadline(t *testing.T) {
+	now := time.Now()
+	m := &BoostService{genesisTime: uint64(now.Unix()), secondsPerSlot: config.SlotTimeSec}
+	require.Equal(t, m.slotEnd(0), m.payloadWriteDeadline(0, now))
+	require.Equal(t, m.slotEnd(5), m.payloadWriteDeadline(5, now))
+
+	// A slot which ended, or is about to, still gets the minimum budget
+	m.genesisTime = uint64(now.Unix()) - 10*config.SlotTimeSec
+	require.Equal(t, now.Add(minPayloadWriteBudget), m.payloadWriteDeadline(0, now))
+}
+
+// deadlineWriter records the write deadline, and fails writes as if it had passed
+type deadlineWriter struct {
+	*httptest.ResponseRecorder
+	deadline time.Time
+}
+
+func (w *deadlineWriter) SetWriteDeadline(deadline time.Time) error {
+	w.deadline = deadline
+	return nil
+}
+
+func (w *deadlineWriter) Write([]byte) (int, error) {
+	return 0, fmt.Errorf("write tcp: %w", os.ErrDeadlineExceeded)
+}
+
+func TestWritePayloadDeadlineExceeded(t *testing.T) {
+	_, response := loadDenebBlock(t)
+	backend := newTestBackend(t, 1, time.Second)
+	backend.boost.genesisTime = uint64(time.Now().Unix())
+
+	w := &deadlineWriter{ResponseRecorder: httptest.NewRecorder()}
+	before := testutil.ToFloat64(payloadResponseWriteDeadlineExceeded)
+	backend.boost.writePayload(w, w, mock.TestLog, &relayPayload{response: response}, 1)
+	require.Equal(t, backend.boost.slotEnd(1), w.deadline)
+	require.InDelta(t, 1, testutil.ToFloat64(payloadResponseWriteDeadlineExceeded)-before, 0)
+}
+
+// blobPayload returns a Deneb payload with the number of random blobs
+func blobPayload(b *testing.B, numBlobs int) *builderApi.VersionedSubmitBlindedBlockResponse {
+	b.Helper()
+	response := (&mock.Relay{}).MakeGetPayloadResponse(
+		"0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7",
+		"0xcf8e0d4e9587369b2301d0790347320302cc0943d5a1884560367e8208d920f2",
+		"0xdb65fEd33dc262Fe09D9a2Ba8F80b329BA25f941", 12345, spec.DataVersionDeneb)
+	bundle := &builderApiDeneb.BlobsBundle{}
+	for range numBlobs {
+		var blob deneb.Blob
+		_, err := rand.Read(blob[:])
+		require.NoError(b, err)
+		bundle.Blobs = append(bundle.Blobs, blob)
+		bundle.Commitments = append(bundle.Commitments, deneb.KZGCommitment{0x01})
+		bundle.Proofs = append(bundle.Proofs, deneb.KZGProof{0x01})
+	}
+	response.Deneb.BlobsBundle = bundle
+	return response
+}
+
+// BenchmarkPayloadResponseWrite compares encoding the payload for the beacon node with passing the bytes of the
+// relay through, for a payload with 6 blobs
+func BenchmarkPayloadResponseWrite(b *testing.B) {
+	response := blobPayload(b, 6)
+	raw, err := json.Marshal(response)
+	require.NoError(b, err)
+	payload := new(relayPayload)
+	require.NoError(b, json.Unmarshal(raw, payload))
+	var buf bytes.Buffer
+
+	b.Run("encode", func(b *testing.B) {
+		b.SetBytes(int64(len(raw)))
+		b.ReportAllocs()
+		for range b.N {
+			buf.Reset()
+			if err := json.NewEncoder(&buf).Encode(payload.response); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("passthrough", func(b *testing.B) {
+		b.SetBytes(int64(len(raw)))
+		b.ReportAllocs()
+		for range b.N {
+			buf.Reset()
+			if _, err := payload.writeTo(&buf); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,176 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"time"
+
+	builderApi "github.com/attestantio/go-builder-client/api"
+	"github.com/attestantio/go-eth2-client/spec"
+	"github.com/attestantio/go-eth2-client/spec/bellatrix"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	errUnknownPayloadVersion = errors.New("unknown payload version")
+	errRPCErrorResponse      = errors.New("RPC error response")
+)
+
+// paymentAuditor compares the value a bid claimed with the payment the proposer received, as seen by an
+// execution client. It only logs and counts discrepancies, and never affects the response to the proposer.
+type paymentAuditor struct {
+	rpcURL string
+	client http.Client
+
+	// The block might not be imported by the execution client right after getPayload
+	retryInterval time.Duration
+	maxWait       time.Duration
+}
+
+func newPaymentAuditor(rpcURL string, secondsPerSlot uint64) *paymentAuditor {
+	return &paymentAuditor{
+		rpcURL:        rpcURL,
+		client:        http.Client{Timeout: 5 * time.Second},
+		retryInterval: time.Second,
+		maxWait:       time.Duration(3*secondsPerSlot) * time.Second,
+	}
+}
+
+// deliveredPayload are the execution payload fields needed for the payment audit
+type deliveredPayload struct {
+	blockNumber  uint64
+	feeRecipient bellatrix.ExecutionAddress
+	transactions []bellatrix.Transaction
+}
+
+// getDeliveredPayload returns the fields of the execution payload needed for the payment audit
+func getDeliveredPayload(response *builderApi.VersionedSubmitBlindedBlockResponse) (deliveredPayload, error) {
+	switch response.Version {
+	case spec.DataVersionBellatrix:
+		p := response.Bellatrix
+		return deliveredPayload{p.BlockNumber, p.FeeRecipient, p.Transactions}, nil
+	case spec.DataVersionCapella:
+		p := response.Capella
+		return deliveredPayload{p.BlockNumber, p.FeeRecipient, p.Transactions}, nil
+	case spec.DataVersionDeneb:
+		p := response.Deneb.ExecutionPayload
+		return deliveredPayload{p.BlockNumber, p.FeeRecipient, p.Transactions}, nil
+	case spec.DataVersionElectra:
+		p := response.Electra.ExecutionPayload
+		return deliveredPayload{p.BlockNumber, p.FeeRecipient, p.Transactions}, nil
+	case spec.DataVersionUnknown, spec.DataVersionPhase0, spec.DataVersionAltair:
+	}
+	return deliveredPayload{}, fmt.Errorf("%w: %s", errUnknownPayloadVersion, response.Version)
+}
+
+// proposerPaymentTx returns the transaction with which the builder paid the proposer, if any. Builders usually
+// pay the proposer with the last transaction of the block, sent from the block's fee recipient.
+func proposerPaymentTx(payload deliveredPayload) *ethTypes.Transaction {
+	if len(payload.transactions) == 0 {
+		return nil
+	}
+
+	tx := new(ethTypes.Transaction)
+	if err := tx.UnmarshalBinary(payload.transactions[len(payload.transactions)-1]); err != nil || tx.To() == nil {
+		return nil
+	}
+	sender, err := ethTypes.Sender(ethTypes.LatestSignerForChainID(tx.ChainId()), tx)
+	if err != nil || sender != common.Address(payload.feeRecipient) {
+		return nil
+	}
+	return tx
+}
+
+// proposerPaymentRecipient returns the address which received the proposer payment: the recipient of the
+// payment transaction, or otherwise the block's fee recipient
+func proposerPaymentRecipient(payload deliveredPayload) common.Address {
+	if tx := proposerPaymentTx(payload); tx != nil {
+		return *tx.To()
+	}
+	return common.Address(payload.feeRecipient)
+}
+
+// audit compares the value of the bid with the balance difference of the proposer payment recipient across the block
+func (a *paymentAuditor) audit(log *logrus.Entry, response *builderApi.VersionedSubmitBlindedBlockResponse, bid bidResp) {
+	payload, err := getDeliveredPayload(response)
+	if err != nil {
+		log.WithError(err).Warn("payment audit: could not read payload")
+		return
+	}
+	recipient := proposerPaymentRecipient(payload)
+	log = log.WithFields(logrus.Fields{
+		"blockNumber":      payload.blockNumber,
+		"paymentRecipient": recipient.String(),
+		"bidValue":         bid.bidInfo.value.Dec(),
+		"builderPubkey":    bid.bidInfo.pubkey.String(),
+	})
+	if payload.blockNumber == 0 {
+		log.Warn("payment audit: payload has no block number")
+		return
+	}
+
+	// Wait for the execution client to import the block
+	deadline := time.Now().Add(a.maxWait)
+	var after *big.Int
+	for {
+		after, err = a.getBalance(recipient, payload.blockNumber)
+		if err == nil || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(a.retryInterval)
+	}
+	if err != nil {
+		log.WithError(err).Warn("payment audit: could not get balance after the block")
+		return
+	}
+	before, err := a.getBalance(recipient, payload.blockNumber-1)
+	if err != nil {
+		log.WithError(err).Warn("payment audit: could not get balance before the block")
+		return
+	}
+
+	delivered := new(big.Int).Sub(after, before)
+	log = log.WithField("deliveredValue", delivered.String())
+	if delivered.Cmp(bid.bidInfo.value.ToBig()) >= 0 {
+		log.Info("payment audit: proposer received the bid value")
+		return
+	}
+
+	log.Warn("payment audit: proposer received less than the bid value")
+	for _, relay := range bid.relays {
+		relayPaymentDiscrepancies.WithLabelValues(relayLabel(relay), bid.bidInfo.pubkey.String()).Inc()
+	}
+}
+
+// getBalance returns the balance of the address at the end of the block
+func (a *paymentAuditor) getBalance(address common.Address, blockNumber uint64) (*big.Int, error) {
+	payload := map[string]any{
+		"id":      1,
+		"jsonrpc": "2.0",
+		"method":  "eth_getBalance",
+		"params":  []any{address.String(), hexutil.EncodeUint64(blockNumber)},
+	}
+	var resp struct {
+		Result *hexutil.Big `json:"result"`
+		Error  *struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if _, err := SendHTTPRequest(context.Background(), a.client, http.MethodPost, a.rpcURL, "", nil, payload, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Error != nil {
+		return nil, fmt.Errorf("%w: %d / %s", errRPCErrorResponse, resp.Error.Code, resp.Error.Message)
+	}
+	if resp.Result == nil {
+		return nil, fmt.Errorf("%w: missing result", errRPCErrorResponse)
+	}
+	return resp.Result.ToInt(), nil
+}
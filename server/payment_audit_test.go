@@ -0,0 +1,137 @@
+package server
+
+import (
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	builderApi "github.com/attestantio/go-builder-client/api"
+	"github.com/attestantio/go-eth2-client/spec"
+	"github.com/attestantio/go-eth2-client/spec/bellatrix"
+	"github.com/attestantio/go-eth2-client/spec/capella"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/flashbots/mev-boost/server/mock"
+	"github.com/flashbots/mev-boost/server/types"
+	"github.com/holiman/uint256"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// signedPaymentTx returns a signed transaction transferring value to the given address
+func signedPaymentTx(t *testing.T, to common.Address, value int64) (bellatrix.Transaction, common.Address) {
+	t.Helper()
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	signer := ethTypes.LatestSignerForChainID(big.NewInt(1))
+	tx, err := ethTypes.SignNewTx(key, signer, &ethTypes.DynamicFeeTx{
+		ChainID:   big.NewInt(1),
+		Nonce:     7,
+		GasTipCap: big.NewInt(0),
+		GasFeeCap: big.NewInt(10_000_000_000),
+		Gas:       21000,
+		To:        &to,
+		Value:     big.NewInt(value),
+	})
+	require.NoError(t, err)
+	raw, err := tx.MarshalBinary()
+	require.NoError(t, err)
+	return raw, crypto.PubkeyToAddress(key.PublicKey)
+}
+
+func TestProposerPaymentRecipient(t *testing.T) {
+	proposer := common.HexToAddress("0xdb65fEd33dc262Fe09D9a2Ba8F80b329BA25f941")
+	paymentTx, builder := signedPaymentTx(t, proposer, 1000)
+
+	t.Run("Payment transaction from the fee recipient", func(t *testing.T) {
+		payload := deliveredPayload{feeRecipient: bellatrix.ExecutionAddress(builder), transactions: []bellatrix.Transaction{{0x01}, paymentTx}}
+		require.Equal(t, proposer, proposerPaymentRecipient(payload))
+	})
+
+	t.Run("Last transaction from another sender", func(t *testing.T) {
+		feeRecipient := common.HexToAddress("0x1111111111111111111111111111111111111111")
+		payload := deliveredPayload{feeRecipient: bellatrix.ExecutionAddress(feeRecipient), transactions: []bellatrix.Transaction{paymentTx}}
+		require.Equal(t, feeRecipient, proposerPaymentRecipient(payload))
+	})
+
+	t.Run("No transactions", func(t *testing.T) {
+		payload := deliveredPayload{feeRecipient: bellatrix.ExecutionAddress(proposer)}
+		require.Equal(t, proposer, proposerPaymentRecipient(payload))
+	})
+}
+
+func TestPaymentAudit(t *testing.T) {
+	proposer := common.HexToAddress("0xdb65fEd33dc262Fe09D9a2Ba8F80b329BA25f941")
+	paymentTx, builder := signedPaymentTx(t, proposer, 1000)
+	relay, err := types.NewRelayEntry("http://0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249@audit.relay.com")
+	require.NoError(t, err)
+	builderPubkey := mock.HexToPubkey("0xa1dead01e65f0a0eee7b5170223f20c8f0cbf122eac3324d61afbdb33a8885ff8cab2ef514ac2c7698ae0d6289ef27fc")
+
+	response := &builderApi.VersionedSubmitBlindedBlockResponse{
+		Version: spec.DataVersionCapella,
+		Capella: &capella.ExecutionPayload{
+			BlockNumber:  100,
+			FeeRecipient: bellatrix.ExecutionAddress(builder),
+			Transactions: []bellatrix.Transaction{paymentTx},
+		},
+	}
+
+	// newRPC returns an execution client which reports the proposer balance before and after block 100,
+	// and which has not imported block 100 on the first request
+	newRPC := func(t *testing.T, delivered int64) *httptest.Server {
+		t.Helper()
+		var requests atomic.Int32
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			var rpcReq struct {
+				Method string `json:"method"`
+				Params []string
+			}
+			require.NoError(t, json.NewDecoder(req.Body).Decode(&rpcReq))
+			require.Equal(t, "eth_getBalance", rpcReq.Method)
+			require.Equal(t, proposer.String(), rpcReq.Params[0])
+
+			w.Header().Set("Content-Type", "application/json")
+			if requests.Add(1) == 1 {
+				_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"error":{"code":-32000,"message":"header not found"}}`))
+				return
+			}
+			balance := big.NewInt(5000)
+			if rpcReq.Params[1] == hexutil.EncodeUint64(100) {
+				balance.Add(balance, big.NewInt(delivered))
+			}
+			_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"` + hexutil.EncodeBig(balance) + `"}`))
+		}))
+	}
+
+	testCases := []struct {
+		name          string
+		delivered     int64
+		discrepancies float64
+	}{
+		{name: "Bid value delivered", delivered: 1000, discrepancies: 0},
+		{name: "Less than bid value delivered", delivered: 400, discrepancies: 1},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			rpc := newRPC(t, tt.delivered)
+			defer rpc.Close()
+			auditor := newPaymentAuditor(rpc.URL, 12)
+			auditor.retryInterval = time.Millisecond
+
+			bid := bidResp{
+				bidInfo: bidInfo{value: uint256.NewInt(1000), pubkey: builderPubkey},
+				relays:  []types.RelayEntry{relay},
+			}
+			discrepancies := relayPaymentDiscrepancies.WithLabelValues(relayLabel(relay), builderPubkey.String())
+			before := testutil.ToFloat64(discrepancies)
+			auditor.audit(mock.TestLog, response, bid)
+			require.InDelta(t, before+tt.discrepancies, testutil.ToFloat64(discrepancies), 0)
+		})
+	}
+}
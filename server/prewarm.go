@@ -0,0 +1,84 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/flashbots/mev-boost/server/params"
+	"github.com/flashbots/mev-boost/server/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// defaultIdleConnTimeout is the idle connection timeout of http.DefaultTransport
+	defaultIdleConnTimeout = 90 * time.Second
+	// prewarmTimeout bounds a prewarm request, which is not retried
+	prewarmTimeout = 5 * time.Second
+)
+
+var relayConnectionPrewarms = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "relay_connection_prewarms_total",
+	Help: "Number of status requests sent to relays to establish or keep their pooled connections warm, by result (ok or error)",
+}, []string{"relay", "result"})
+
+// connectionPrewarmInterval returns the interval of prewarm requests, half the idle timeout of pooled connections of
+// the transport, so that a connection to each relay stays open
+func connectionPrewarmInterval(transport http.RoundTripper) time.Duration {
+	idleConnTimeout := defaultIdleConnTimeout
+	switch t := transport.(type) {
+	case *http.Transport:
+		idleConnTimeout = t.IdleConnTimeout
+	case *relayTLSVersionTransport:
+		idleConnTimeout = t.next.IdleConnTimeout
+	}
+	if idleConnTimeout <= 0 {
+		// Idle connections are never closed, keep the connections warm like with the default transport
+		idleConnTimeout = defaultIdleConnTimeout
+	}
+	return idleConnTimeout / 2
+}
+
+// prewarmRelayConnections sends a status request to each relay, which leaves a pooled connection to the relay
+// with a finished TLS handshake for the next getHeader request
+func (m *BoostService) prewarmRelayConnections(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, prewarmTimeout)
+	defer cancel()
+	m.reloadLock.RLock()
+	defer m.reloadLock.RUnlock()
+
+	var wg sync.WaitGroup
+	for _, relay := range m.relays {
+		wg.Add(1)
+		go func(relay types.RelayEntry) {
+			defer wg.Done()
+			url := relay.GetURI(params.PathStatus)
+			_, err := SendHTTPRequest(ctx, m.httpClientGetHeader, http.MethodGet, url, "", withRelayHeaders(nil, relay), nil, nil)
+			if err != nil {
+				relayConnectionPrewarms.WithLabelValues(relayLabel(relay), "error").Inc()
+				m.log.WithError(err).WithFields(logrus.Fields{"relay": relayLabel(relay), "url": url}).Debug("could not prewarm the connection to the relay")
+				return
+			}
+			relayConnectionPrewarms.WithLabelValues(relayLabel(relay), "ok").Inc()
+		}(relay)
+	}
+	wg.Wait()
+}
+
+// runConnectionPrewarm prewarms the relay connections at startup, and then every prewarm interval until the
+// context is done
+func (m *BoostService) runConnectionPrewarm(ctx context.Context) {
+	m.log.WithField("interval", m.connectionPrewarmInterval.String()).Info("prewarming relay connections")
+	ticker := time.NewTicker(m.connectionPrewarmInterval)
+	defer ticker.Stop()
+	for {
+		m.prewarmRelayConnections(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package server
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/flashbots/mev-boost/server/params"
+	"github.com/flashbots/mev-boost/server/types"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConnectionPrewarmInterval(t *testing.T) {
+	require.Equal(t, defaultIdleConnTimeout/2, connectionPrewarmInterval(nil))
+	require.Equal(t, 30*time.Second, connectionPrewarmInterval(&http.Transport{IdleConnTimeout: time.Minute}))
+	require.Equal(t, 30*time.Second, connectionPrewarmInterval(&relayTLSVersionTransport{next: &http.Transport{IdleConnTimeout: time.Minute}}))
+	require.Equal(t, defaultIdleConnTimeout/2, connectionPrewarmInterval(&http.Transport{}))
+}
+
+func TestPrewarmRelayConnections(t *testing.T) {
+	t.Run("The getHeader request reuses the prewarmed connection", func(t *testing.T) {
+		var connections atomic.Int32
+		server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+		server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+			if state == http.StateNew {
+				connections.Add(1)
+			}
+		}
+		server.Start()
+		defer server.Close()
+		u, err := url.Parse(server.URL)
+		require.NoError(t, err)
+		relay := types.RelayEntry{URL: u}
+
+		backend := newTestBackend(t, 1, time.Second)
+		backend.boost.relays = []types.RelayEntry{relay}
+		backend.boost.httpClientGetHeader.Transport = &http.Transport{}
+		before := testutil.ToFloat64(relayConnectionPrewarms.WithLabelValues(relayLabel(relay), "ok"))
+
+		backend.boost.prewarmRelayConnections(context.Background())
+		require.InDelta(t, 1, testutil.ToFloat64(relayConnectionPrewarms.WithLabelValues(relayLabel(relay), "ok"))-before, 0)
+		require.Equal(t, int32(1), connections.Load())
+
+		_, err = SendHTTPRequest(context.Background(), backend.boost.httpClientGetHeader, http.MethodGet, relay.GetURI(getHeaderPath(1, [32]byte{}, [48]byte{})), "", nil, nil, nil)
+		require.NoError(t, err)
+		require.Equal(t, int32(1), connections.Load())
+	})
+
+	t.Run("Connections are prewarmed until stopped", func(t *testing.T) {
+		backend := newTestBackend(t, 2, time.Second)
+		backend.boost.connectionPrewarmInterval = 10 * time.Millisecond
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			backend.boost.runConnectionPrewarm(ctx)
+			close(done)
+		}()
+
+		require.Eventually(t, func() bool {
+			return backend.relays[0].GetRequestCount(params.PathStatus) >= 2 && backend.relays[1].GetRequestCount(params.PathStatus) >= 2
+		}, time.Second, 5*time.Millisecond)
+		cancel()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("prewarming did not stop")
+		}
+	})
+}
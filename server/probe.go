@@ -0,0 +1,213 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/http/httptrace"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/flashbots/mev-boost/server/params"
+	"github.com/flashbots/mev-boost/server/types"
+	"github.com/sirupsen/logrus"
+)
+
+// probePubkey is the proposer pubkey used for the synthetic getHeader requests of the relay probe
+const probePubkey = "0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249"
+
+// probeSlotsInPast is how far before the current slot the synthetic getHeader requests are, so that
+// no real auction is affected
+const probeSlotsInPast = 32
+
+// RelayProbeOpts configures ProbeRelays
+type RelayProbeOpts struct {
+	Relays      []types.RelayEntry
+	GenesisTime uint64
+	// SecondsPerSlot is the slot time of the network, the SLOT_SEC environment variable or 12 if zero
+	SecondsPerSlot uint64
+	Requests       int // number of status and getHeader requests per relay
+	Timeout        time.Duration
+
+	// The relay connection settings, as in BoostServiceOpts, so relays are probed over the connections the
+	// service makes
+	RelayDialTimeout             time.Duration
+	RelayTLSHandshakeTimeout     time.Duration
+	RelayTLSMinVersion           string
+	RelayDNSCacheTTL             time.Duration
+	RelayLocalAddr               string
+	FollowRelayRedirectsSameHost bool
+	Log                          *logrus.Entry
+}
+
+// RelayProbeThresholds are the limits a relay has to stay within to pass the probe, zero disables a limit
+type RelayProbeThresholds struct {
+	MaxP95          time.Duration
+	MaxTLSHandshake time.Duration
+}
+
+// RelayProbeResult contains the latencies and connection details measured for a relay
+type RelayProbeResult struct {
+	Relay        types.RelayEntry
+	HTTPVersion  string
+	TLSHandshake time.Duration
+	StatusP50    time.Duration
+	StatusP95    time.Duration
+	GetHeaderP50 time.Duration
+	GetHeaderP95 time.Duration
+	Errors       []error
+}
+
+// Failures returns the reasons why the relay did not pass the probe
+func (r *RelayProbeResult) Failures(thresholds RelayProbeThresholds) []string {
+	failures := []string{}
+	for _, err := range r.Errors {
+		failures = append(failures, err.Error())
+	}
+	if thresholds.MaxP95 > 0 && r.StatusP95 > thresholds.MaxP95 {
+		failures = append(failures, fmt.Sprintf("status p95 %v exceeds %v", r.StatusP95, thresholds.MaxP95))
+	}
+	if thresholds.MaxP95 > 0 && r.GetHeaderP95 > thresholds.MaxP95 {
+		failures = append(failures, fmt.Sprintf("getHeader p95 %v exceeds %v", r.GetHeaderP95, thresholds.MaxP95))
+	}
+	if thresholds.MaxTLSHandshake > 0 && r.TLSHandshake > thresholds.MaxTLSHandshake {
+		failures = append(failures, fmt.Sprintf("TLS handshake %v exceeds %v", r.TLSHandshake, thresholds.MaxTLSHandshake))
+	}
+	return failures
+}
+
+// ProbeRelays sends timed status and synthetic getHeader requests to all relays in parallel, with the relay
+// transport of the service
+func ProbeRelays(ctx context.Context, opts RelayProbeOpts) ([]RelayProbeResult, error) {
+	if opts.Log == nil {
+		opts.Log = logrus.NewEntry(logrus.New())
+	}
+	transport, err := newConfiguredRelayTransport(opts.RelayDialTimeout, opts.RelayTLSHandshakeTimeout, opts.RelayDNSCacheTTL, opts.RelayLocalAddr, opts.RelayTLSMinVersion, opts.Log)
+	if err != nil {
+		return nil, err
+	}
+	checkRedirect := newRelayRedirectPolicy(opts.Relays, opts.FollowRelayRedirectsSameHost, opts.Log)
+
+	results := make([]RelayProbeResult, len(opts.Relays))
+	opts.Requests = max(opts.Requests, 1)
+
+	// Use a slot from the past, so no real auction is affected
+	slot := phase0.Slot(0)
+	if now := uint64(time.Now().Unix()); now > opts.GenesisTime {
+		currentSlot := (now - opts.GenesisTime) / secondsPerSlotOrDefault(opts.SecondsPerSlot)
+		if currentSlot > probeSlotsInPast {
+			slot = phase0.Slot(currentSlot - probeSlotsInPast)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for i, relay := range opts.Relays {
+		wg.Add(1)
+		go func(i int, relay types.RelayEntry) {
+			defer wg.Done()
+			client := http.Client{Timeout: opts.Timeout, CheckRedirect: checkRedirect, Transport: transport}
+			results[i] = probeRelay(ctx, client, relay, slot, opts)
+		}(i, relay)
+	}
+	wg.Wait()
+	return results, nil
+}
+
+// probeRelay measures the latencies of a single relay
+func probeRelay(ctx context.Context, client http.Client, relay types.RelayEntry, slot phase0.Slot, opts RelayProbeOpts) RelayProbeResult {
+	result := RelayProbeResult{Relay: relay, HTTPVersion: "HTTP/1.1"}
+
+	// Measure the TLS handshake of the first connection, and the negotiated protocol
+	var (
+		mu             sync.Mutex
+		handshakeStart time.Time
+		handshakeDone  bool
+	)
+	trace := &httptrace.ClientTrace{
+		TLSHandshakeStart: func() {
+			mu.Lock()
+			defer mu.Unlock()
+			if !handshakeDone {
+				handshakeStart = time.Now()
+			}
+		},
+		TLSHandshakeDone: func(state tls.ConnectionState, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			if handshakeDone || err != nil {
+				return
+			}
+			handshakeDone = true
+			result.TLSHandshake = time.Since(handshakeStart)
+			if state.NegotiatedProtocol == "h2" {
+				result.HTTPVersion = "HTTP/2"
+			}
+		},
+	}
+	ctx = httptrace.WithClientTrace(ctx, trace)
+
+	getHeaderURL := relay.GetURI(fmt.Sprintf("/eth/v1/builder/header/%d/%s/%s", slot, phase0.Hash32{}.String(), probePubkey))
+	requests := []struct {
+		name      string
+		url       string
+		latencies []time.Duration
+		// accept decides whether a response counts as successful
+		accept func(code int, err error) bool
+	}{
+		{
+			name: "status",
+			url:  relay.GetURI(params.PathStatus),
+			accept: func(_ int, err error) bool {
+				return err == nil
+			},
+		},
+		{
+			// The slot is in the past, so an error response from the relay is as good as a bid
+			name: "getHeader",
+			url:  getHeaderURL,
+			accept: func(code int, _ error) bool {
+				return code > 0 && code < http.StatusInternalServerError
+			},
+		},
+	}
+
+	for i := range requests {
+		request := &requests[i]
+		var lastErr error
+		for range opts.Requests {
+			start := time.Now()
+			code, err := SendHTTPRequest(ctx, client, http.MethodGet, request.url, "", withRelayHeaders(nil, relay), nil, nil)
+			if !request.accept(code, err) {
+				lastErr = err
+				if err == nil {
+					lastErr = fmt.Errorf("%w: %d", errHTTPErrorResponse, code)
+				}
+				continue
+			}
+			request.latencies = append(request.latencies, time.Since(start))
+		}
+		if len(request.latencies) < opts.Requests {
+			result.Errors = append(result.Errors, fmt.Errorf("%s: %d of %d requests failed: %w", request.name, opts.Requests-len(request.latencies), opts.Requests, lastErr))
+		}
+	}
+
+	result.StatusP50 = percentile(requests[0].latencies, 50)
+	result.StatusP95 = percentile(requests[0].latencies, 95)
+	result.GetHeaderP50 = percentile(requests[1].latencies, 50)
+	result.GetHeaderP95 = percentile(requests[1].latencies, 95)
+	return result
+}
+
+// percentile returns the nearest-rank percentile of the durations
+func percentile(durations []time.Duration, p int) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+	sorted := slices.Clone(durations)
+	slices.Sort(sorted)
+	rank := (p*len(sorted) + 99) / 100
+	return sorted[max(rank-1, 0)]
+}
@@ -0,0 +1,102 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/flashbots/mev-boost/server/mock"
+	"github.com/flashbots/mev-boost/server/params"
+	"github.com/flashbots/mev-boost/server/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPercentile(t *testing.T) {
+	durations := []time.Duration{5, 1, 4, 2, 3, 10, 9, 8, 7, 6}
+	require.Equal(t, time.Duration(5), percentile(durations, 50))
+	require.Equal(t, time.Duration(10), percentile(durations, 95))
+	require.Equal(t, time.Duration(1), percentile(durations, 0))
+	require.Equal(t, time.Duration(0), percentile(nil, 50))
+}
+
+func TestProbeRelays(t *testing.T) {
+	t.Run("Healthy relay", func(t *testing.T) {
+		relay := mock.NewRelay(t)
+		relay.OverrideHandleGetHeader(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		})
+		results, err := ProbeRelays(context.Background(), RelayProbeOpts{
+			Relays:   []types.RelayEntry{relay.RelayEntry},
+			Requests: 3,
+			Timeout:  time.Second,
+		})
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		require.Empty(t, results[0].Errors)
+		require.Equal(t, "HTTP/1.1", results[0].HTTPVersion)
+		require.Positive(t, results[0].StatusP95)
+		require.Positive(t, results[0].GetHeaderP95)
+		require.Equal(t, 3, relay.GetRequestCount(params.PathStatus))
+		require.Empty(t, results[0].Failures(RelayProbeThresholds{MaxP95: time.Second}))
+	})
+
+	t.Run("Slow relay fails the threshold", func(t *testing.T) {
+		relay := mock.NewRelay(t)
+		relay.ResponseDelay = 20 * time.Millisecond
+		results, err := ProbeRelays(context.Background(), RelayProbeOpts{
+			Relays:   []types.RelayEntry{relay.RelayEntry},
+			Requests: 2,
+			Timeout:  time.Second,
+		})
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		require.Empty(t, results[0].Errors)
+		require.Len(t, results[0].Failures(RelayProbeThresholds{MaxP95: 10 * time.Millisecond}), 2)
+	})
+
+	t.Run("Unavailable relay", func(t *testing.T) {
+		relay := mock.NewRelay(t)
+		relay.Server.Close()
+		results, err := ProbeRelays(context.Background(), RelayProbeOpts{
+			Relays:   []types.RelayEntry{relay.RelayEntry},
+			Requests: 2,
+			Timeout:  time.Second,
+		})
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		require.Len(t, results[0].Errors, 2)
+		require.Len(t, results[0].Failures(RelayProbeThresholds{}), 2)
+	})
+
+	t.Run("Relay transport of the service", func(t *testing.T) {
+		// The relay only supports TLS 1.2, below the minimum TLS version of relay connections
+		relayServer := httptest.NewUnstartedServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+		relayServer.TLS = &tls.Config{MaxVersion: tls.VersionTLS12}
+		relayServer.StartTLS()
+		defer relayServer.Close()
+		relay, err := types.NewRelayEntry(strings.Replace(relayServer.URL, "https://", "https://"+probePubkey+"@", 1))
+		require.NoError(t, err)
+
+		results, err := ProbeRelays(context.Background(), RelayProbeOpts{
+			Relays:             []types.RelayEntry{relay},
+			Requests:           1,
+			Timeout:            time.Second,
+			RelayTLSMinVersion: RelayTLSVersion13,
+			Log:                mock.TestLog,
+		})
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		require.Len(t, results[0].Errors, 2)
+		require.ErrorIs(t, results[0].Errors[0], errRelayTLSVersion)
+
+		_, err = ProbeRelays(context.Background(), RelayProbeOpts{
+			Relays:             []types.RelayEntry{relay},
+			RelayTLSMinVersion: "1.1",
+		})
+		require.ErrorIs(t, err, errInvalidRelayTLSMinVersion)
+	})
+}
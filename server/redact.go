@@ -0,0 +1,86 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+
+	"github.com/flashbots/mev-boost/config"
+)
+
+// maxLoggedValueLen is the length above which hex and base64 string values are elided from logged bodies,
+// which removes blobs, and keeps hashes, signatures and KZG commitments
+const maxLoggedValueLen = 256
+
+// largeValueRegex matches JSON string values which are long hex or base64 strings
+var largeValueRegex = regexp.MustCompile(fmt.Sprintf(`"(0x[0-9a-fA-F]{%d,}|[A-Za-z0-9+/]{%d,}={0,2})"`, maxLoggedValueLen, maxLoggedValueLen))
+
+// redactBody returns a body which is safe to log: long hex and base64 values are elided, and the result is truncated
+// to config.MaxLoggedBodySize. A hash of the full body is appended, so the log can be matched to a stored body.
+func redactBody(body []byte) string {
+	elided := largeValueRegex.ReplaceAllFunc(body, func(value []byte) []byte {
+		return []byte(fmt.Sprintf(`"<elided %d chars>"`, len(value)-2))
+	})
+	if len(elided) == len(body) && len(body) <= config.MaxLoggedBodySize {
+		return string(body)
+	}
+	if len(elided) > config.MaxLoggedBodySize {
+		elided = elided[:config.MaxLoggedBodySize]
+	}
+	return fmt.Sprintf("%s... [%d bytes, sha256 %x]", elided, len(body), sha256.Sum256(body))
+}
+
+// redactJSON returns the JSON encoding of v for logging, see redactBody
+func redactJSON(v any) string {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("<could not encode: %v>", err)
+	}
+	return redactBody(body)
+}
+
+// redactedValue replaces secrets in responses
+const redactedValue = "<redacted>"
+
+// redactSecret returns redactedValue for a configured secret, and an empty string if none is configured
+func redactSecret(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return redactedValue
+}
+
+// redactURL returns the scheme and host of the URL. Credentials, the path and the query are replaced, as they
+// may hold API keys.
+func redactURL(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+	redacted := u.Scheme + "://" + u.Host
+	if u.User != nil || (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" {
+		redacted += "/" + redactedValue
+	}
+	return redacted
+}
+
+// redactURLString is redactURL for an unparsed URL, which is entirely redacted if it cannot be parsed
+func redactURLString(rawURL string) string {
+	if rawURL == "" {
+		return ""
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return redactedValue
+	}
+	return redactURL(u)
+}
+
+// truncatePubkey shortens a hex public key to its first and last four bytes
+func truncatePubkey(pubkey string) string {
+	if len(pubkey) <= 20 {
+		return pubkey
+	}
+	return pubkey[:10] + "..." + pubkey[len(pubkey)-8:]
+}
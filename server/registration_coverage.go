@@ -0,0 +1,115 @@
+package server
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	builderApiV1 "github.com/attestantio/go-builder-client/api/v1"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/flashbots/go-boost-utils/utils"
+	"github.com/flashbots/mev-boost/server/types"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// registrationCoverageMaxAge is the time after which a validator which was not registered with a relay again
+// is forgotten for that relay. Beacon nodes send the registrations of their validators every epoch.
+const registrationCoverageMaxAge = time.Hour
+
+var relayRegisteredValidators = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "relay_registered_validators",
+	Help: "Number of validators successfully registered with the relay within the last hour",
+}, []string{"relay"})
+
+// relayRegistration is the last time a validator was successfully registered with a relay
+type relayRegistration struct {
+	Relay     string    `json:"relay"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// registrationCoverage keeps track of which validators were successfully registered with which relays
+type registrationCoverage struct {
+	mu      sync.Mutex
+	entries map[string]map[phase0.BLSPubKey]time.Time // relay label -> validator pubkey -> last registration
+}
+
+func newRegistrationCoverage() *registrationCoverage {
+	return &registrationCoverage{
+		entries: make(map[string]map[phase0.BLSPubKey]time.Time),
+	}
+}
+
+// record remembers that the registrations were forwarded to the relay, and forgets validators of the relay
+// which were not registered again within the max age
+func (c *registrationCoverage) record(relay types.RelayEntry, registrations []builderApiV1.SignedValidatorRegistration, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := relayLabel(relay)
+	pubkeys := c.entries[key]
+	if pubkeys == nil {
+		pubkeys = make(map[phase0.BLSPubKey]time.Time, len(registrations))
+		c.entries[key] = pubkeys
+	}
+	for _, registration := range registrations {
+		if registration.Message != nil {
+			pubkeys[registration.Message.Pubkey] = now
+		}
+	}
+	for pubkey, t := range pubkeys {
+		if now.Sub(t) > registrationCoverageMaxAge {
+			delete(pubkeys, pubkey)
+		}
+	}
+	relayRegisteredValidators.WithLabelValues(relayLabel(relay)).Set(float64(len(pubkeys)))
+}
+
+// lookup returns the relays the validator was registered with, sorted by relay
+func (c *registrationCoverage) lookup(pubkey phase0.BLSPubKey) []relayRegistration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	registrations := []relayRegistration{}
+	for relay, pubkeys := range c.entries {
+		if t, ok := pubkeys[pubkey]; ok {
+			registrations = append(registrations, relayRegistration{Relay: relay, Timestamp: t})
+		}
+	}
+	slices.SortFunc(registrations, func(a, b relayRegistration) int {
+		return strings.Compare(a.Relay, b.Relay)
+	})
+	return registrations
+}
+
+// counts returns the number of validators registered with each relay
+func (c *registrationCoverage) counts() map[string]int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	counts := make(map[string]int, len(c.entries))
+	for relay, pubkeys := range c.entries {
+		counts[relay] = len(pubkeys)
+	}
+	return counts
+}
+
+// handleDebugRegistrations returns the relays the validator of the pubkey query parameter was registered
+// with, or the number of registered validators per relay without it
+func (m *BoostService) handleDebugRegistrations(w http.ResponseWriter, req *http.Request) {
+	pubkeyHex := req.URL.Query().Get("pubkey")
+	if pubkeyHex == "" {
+		m.respondOK(w, m.registrationCoverage.counts())
+		return
+	}
+	pubkey, err := utils.HexToPubkey(pubkeyHex)
+	if err != nil {
+		m.respondError(w, http.StatusBadRequest, errInvalidPubkey.Error())
+		return
+	}
+	m.respondOK(w, struct {
+		Pubkey phase0.BLSPubKey    `json:"pubkey"`
+		Relays []relayRegistration `json:"relays"`
+	}{pubkey, m.registrationCoverage.lookup(pubkey)})
+}
@@ -0,0 +1,91 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	builderApiV1 "github.com/attestantio/go-builder-client/api/v1"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/flashbots/mev-boost/server/mock"
+	"github.com/flashbots/mev-boost/server/params"
+	"github.com/flashbots/mev-boost/server/types"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// testRegistration returns a validator registration of the pubkey, the signature is not verified by mev-boost
+func testRegistration(pubkey phase0.BLSPubKey) builderApiV1.SignedValidatorRegistration {
+	return builderApiV1.SignedValidatorRegistration{
+		Message: &builderApiV1.ValidatorRegistration{
+			FeeRecipient: mock.HexToAddress("0xdb65fEd33dc262Fe09D9a2Ba8F80b329BA25f941"),
+			Timestamp:    time.Unix(1234356, 0),
+			Pubkey:       pubkey,
+		},
+		Signature: mock.HexToSignature(
+			"0x81510b571e22f89d1697545aac01c9ad0c1e7a3e778b3078bef524efae14990e58a6e960a152abd49de2e18d7fd3081c15d5c25867ccfad3d47beef6b39ac24b6b9fbf2cfa91c88f67aff750438a6841ec9e4a06a94ae41410c4f97b75ab284c"),
+	}
+}
+
+func TestRegistrationCoverage(t *testing.T) {
+	relayA, err := types.NewRelayEntry("https://0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249@relay-a.example.com?label=relay-a")
+	require.NoError(t, err)
+	relayB, err := types.NewRelayEntry("https://0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249@relay-b.example.com")
+	require.NoError(t, err)
+	validator1 := phase0.BLSPubKey{0x01}
+	validator2 := phase0.BLSPubKey{0x02}
+
+	coverage := newRegistrationCoverage()
+	now := time.Now()
+	coverage.record(relayA, []builderApiV1.SignedValidatorRegistration{testRegistration(validator1), testRegistration(validator2)}, now)
+	coverage.record(relayB, []builderApiV1.SignedValidatorRegistration{testRegistration(validator1)}, now.Add(time.Second))
+
+	require.Equal(t, []relayRegistration{
+		{Relay: "relay-a", Timestamp: now},
+		{Relay: "relay-b.example.com", Timestamp: now.Add(time.Second)},
+	}, coverage.lookup(validator1))
+	require.Equal(t, []relayRegistration{{Relay: "relay-a", Timestamp: now}}, coverage.lookup(validator2))
+	require.Empty(t, coverage.lookup(phase0.BLSPubKey{0x03}))
+	require.Equal(t, map[string]int{"relay-a": 2, "relay-b.example.com": 1}, coverage.counts())
+	require.InDelta(t, 2, testutil.ToFloat64(relayRegisteredValidators.WithLabelValues("relay-a")), 0)
+
+	// Validators which are not registered again are forgotten after the max age
+	coverage.record(relayA, []builderApiV1.SignedValidatorRegistration{testRegistration(validator1)}, now.Add(registrationCoverageMaxAge+time.Second))
+	require.Empty(t, coverage.lookup(validator2))
+	require.InDelta(t, 1, testutil.ToFloat64(relayRegisteredValidators.WithLabelValues("relay-a")), 0)
+}
+
+func TestDebugRegistrations(t *testing.T) {
+	backend := newTestBackend(t, 2, time.Second)
+	backend.boost.debugEndpoints = true
+	backend.relays[1].OverrideHandleRegisterValidator(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	})
+	validator := mock.HexToPubkey(
+		"0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249")
+
+	rr := backend.request(t, http.MethodPost, params.PathRegisterValidator, []builderApiV1.SignedValidatorRegistration{testRegistration(validator)})
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Eventually(t, func() bool {
+		return backend.relays[1].GetRequestCount(params.PathRegisterValidator) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	rr = backend.request(t, http.MethodGet, params.PathDebugRegistrations+"?pubkey="+validator.String(), nil)
+	require.Equal(t, http.StatusOK, rr.Code)
+	var resp struct {
+		Pubkey phase0.BLSPubKey    `json:"pubkey"`
+		Relays []relayRegistration `json:"relays"`
+	}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	require.Equal(t, validator, resp.Pubkey)
+	require.Len(t, resp.Relays, 1)
+	require.Equal(t, backend.relays[0].RelayEntry.Name(), resp.Relays[0].Relay)
+
+	rr = backend.request(t, http.MethodGet, params.PathDebugRegistrations, nil)
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.JSONEq(t, `{"`+backend.relays[0].RelayEntry.Name()+`":1}`, rr.Body.String())
+
+	rr = backend.request(t, http.MethodGet, params.PathDebugRegistrations+"?pubkey=0x1234", nil)
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+}
@@ -0,0 +1,67 @@
+package server
+
+import (
+	"errors"
+
+	builderApiV1 "github.com/attestantio/go-builder-client/api/v1"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/flashbots/go-boost-utils/ssz"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	errInvalidRegistrationSignatures = errors.New("no validator registration has a valid signature")
+
+	registrationSignatureRejections = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "registration_signatures_rejected_total",
+		Help: "Number of registrations which were not forwarded to relays because their signature is not valid",
+	})
+)
+
+// newRegistrationDomain returns the builder domain validator registrations are signed under, which is always
+// computed from the genesis fork version, or nil if registration signatures are not verified
+func newRegistrationDomain(verify bool, genesisForkVersionHex string) (*phase0.Domain, error) {
+	if !verify {
+		return nil, nil //nolint:nilnil
+	}
+	domain, err := ComputeDomain(ssz.DomainTypeAppBuilder, genesisForkVersionHex, phase0.Root{}.String())
+	if err != nil {
+		return nil, err
+	}
+	return &domain, nil
+}
+
+// verifyRegistrationSignature checks the BLS signature of the registration by its validator
+func verifyRegistrationSignature(registration builderApiV1.SignedValidatorRegistration, domain phase0.Domain) (bool, error) {
+	if registration.Message == nil {
+		return false, nil
+	}
+	return ssz.VerifySignature(registration.Message, domain, registration.Message.Pubkey[:], registration.Signature[:])
+}
+
+// filterValidRegistrationSignatures returns the registrations with a valid signature, and logs the others as
+// rejected. All registrations are returned if registration signatures are not verified.
+func (m *BoostService) filterValidRegistrationSignatures(log *logrus.Entry, registrations []builderApiV1.SignedValidatorRegistration) []builderApiV1.SignedValidatorRegistration {
+	if m.registrationDomain == nil {
+		return registrations
+	}
+	valid := make([]builderApiV1.SignedValidatorRegistration, 0, len(registrations))
+	for _, registration := range registrations {
+		ok, err := verifyRegistrationSignature(registration, *m.registrationDomain)
+		if !ok {
+			registrationSignatureRejections.Inc()
+			log := log
+			if registration.Message != nil {
+				log = log.WithField("pubkey", registration.Message.Pubkey.String())
+			}
+			if err != nil {
+				log = log.WithError(err)
+			}
+			log.Warn("dropping registration with an invalid signature")
+			continue
+		}
+		valid = append(valid, registration)
+	}
+	return valid
+}
@@ -0,0 +1,90 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	builderApiV1 "github.com/attestantio/go-builder-client/api/v1"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/flashbots/go-boost-utils/bls"
+	"github.com/flashbots/go-boost-utils/ssz"
+	"github.com/flashbots/mev-boost/server/params"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// signedRegistration returns a registration signed by a new validator key under the domain
+func signedRegistration(t *testing.T, domain phase0.Domain) builderApiV1.SignedValidatorRegistration {
+	t.Helper()
+	sk, pk, err := bls.GenerateNewKeypair()
+	require.NoError(t, err)
+	registration := testRegistration(phase0.BLSPubKey(bls.PublicKeyToBytes(pk)))
+	registration.Signature, err = ssz.SignMessage(registration.Message, domain, sk)
+	require.NoError(t, err)
+	return registration
+}
+
+func TestRegisterValidatorSignatures(t *testing.T) {
+	domain, err := newRegistrationDomain(true, "0x00000000")
+	require.NoError(t, err)
+	valid := signedRegistration(t, *domain)
+	// Signed under the domain of another fork version
+	otherDomain, err := newRegistrationDomain(true, "0x01017000")
+	require.NoError(t, err)
+	wrongDomain := signedRegistration(t, *otherDomain)
+	tampered := signedRegistration(t, *domain)
+	tampered.Message.GasLimit++
+
+	newBackend := func(t *testing.T, verify bool) (*testBackend, *[]builderApiV1.SignedValidatorRegistration) {
+		t.Helper()
+		backend := newTestBackend(t, 1, time.Second)
+		registrationDomain, err := newRegistrationDomain(verify, "0x00000000")
+		require.NoError(t, err)
+		backend.boost.registrationDomain = registrationDomain
+		forwarded := new([]builderApiV1.SignedValidatorRegistration)
+		backend.relays[0].OverrideHandleRegisterValidator(func(w http.ResponseWriter, req *http.Request) {
+			require.NoError(t, DecodeJSON(req.Body, forwarded))
+			w.WriteHeader(http.StatusOK)
+		})
+		return backend, forwarded
+	}
+
+	t.Run("Disabled", func(t *testing.T) {
+		backend, forwarded := newBackend(t, false)
+		rr := backend.request(t, http.MethodPost, params.PathRegisterValidator, []builderApiV1.SignedValidatorRegistration{valid, tampered})
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		require.Len(t, *forwarded, 2)
+	})
+
+	t.Run("Empty batch", func(t *testing.T) {
+		for _, verify := range []bool{false, true} {
+			backend, forwarded := newBackend(t, verify)
+			// Encoded as [] and null
+			for _, batch := range [][]builderApiV1.SignedValidatorRegistration{{}, nil} {
+				rr := backend.request(t, http.MethodPost, params.PathRegisterValidator, batch)
+				require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+				require.Empty(t, *forwarded)
+			}
+			require.Equal(t, 2, backend.relays[0].GetRequestCount(params.PathRegisterValidator))
+		}
+	})
+
+	t.Run("InvalidSignaturesDropped", func(t *testing.T) {
+		backend, forwarded := newBackend(t, true)
+		before := testutil.ToFloat64(registrationSignatureRejections)
+		rr := backend.request(t, http.MethodPost, params.PathRegisterValidator, []builderApiV1.SignedValidatorRegistration{tampered, valid, wrongDomain})
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		require.Len(t, *forwarded, 1)
+		require.Equal(t, valid.Message.Pubkey, (*forwarded)[0].Message.Pubkey)
+		require.InDelta(t, 2, testutil.ToFloat64(registrationSignatureRejections)-before, 0)
+	})
+
+	t.Run("NoValidSignature", func(t *testing.T) {
+		backend, _ := newBackend(t, true)
+		rr := backend.request(t, http.MethodPost, params.PathRegisterValidator, []builderApiV1.SignedValidatorRegistration{tampered})
+		require.Equal(t, http.StatusBadRequest, rr.Code, rr.Body.String())
+		require.Contains(t, rr.Body.String(), errInvalidRegistrationSignatures.Error())
+		require.Equal(t, 0, backend.relays[0].GetRequestCount(params.PathRegisterValidator))
+	})
+}
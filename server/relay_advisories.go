@@ -0,0 +1,223 @@
+package server
+
+import (
+	"cmp"
+	"context"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/flashbots/mev-boost/server/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// relayWarningTTL is how long a relay warning stays active after the relay last sent it, it is logged again
+	// if the relay sends it after that
+	relayWarningTTL = time.Hour
+	// maxRelayRetryAfter caps the backoff a relay requests with Retry-After
+	maxRelayRetryAfter = 5 * time.Minute
+)
+
+// relayWarningHeaders are the response headers relays announce deprecations and required upgrades with
+var relayWarningHeaders = []string{"Warning", "Deprecation", "Sunset"}
+
+var (
+	relayDeprecationWarnings = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "relay_deprecation_warnings_total",
+		Help: "Number of relay responses with a deprecation or upgrade warning, by response header (Warning, Deprecation or Sunset)",
+	}, []string{"relay", "header"})
+	relayRetryAfterBackoffs = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "relay_retry_after_backoffs_total",
+		Help: "Number of 429 and 503 relay responses with a Retry-After header, after which getHeader and registerValidator skip the relay",
+	}, []string{"relay"})
+)
+
+// relayWarning is a warning announced by a relay in a response header
+type relayWarning struct {
+	Header    string    `json:"header"`
+	Message   string    `json:"message"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// relayAdvisories keeps track of the warnings of the relays, and of the relays backing off after a Retry-After
+type relayAdvisories struct {
+	mu       sync.Mutex
+	log      *logrus.Entry
+	warnings map[string]map[string]*relayWarning // relay -> header and message -> warning
+	backoffs map[string]time.Time                // relay -> end of the backoff
+}
+
+func newRelayAdvisories(log *logrus.Entry) *relayAdvisories {
+	return &relayAdvisories{
+		log:      log,
+		warnings: make(map[string]map[string]*relayWarning),
+		backoffs: make(map[string]time.Time),
+	}
+}
+
+// relayAdvisoryKey is the context key of the relay whose responses SendHTTPRequest passes to the advisories
+type relayAdvisoryKey struct{}
+
+type relayAdvisoryObserver struct {
+	advisories *relayAdvisories
+	relay      types.RelayEntry
+}
+
+// observing returns a context in which SendHTTPRequest records the warnings and the Retry-After of the responses
+// of the relay
+func (a *relayAdvisories) observing(ctx context.Context, relay types.RelayEntry) context.Context {
+	if a == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, relayAdvisoryKey{}, relayAdvisoryObserver{advisories: a, relay: relay})
+}
+
+// observeRelayResponse records the response if the context observes the responses of a relay
+func observeRelayResponse(ctx context.Context, resp *http.Response) {
+	if observer, ok := ctx.Value(relayAdvisoryKey{}).(relayAdvisoryObserver); ok {
+		observer.advisories.observe(observer.relay, resp, time.Now())
+	}
+}
+
+// warningMessage returns the text of a Warning header value like `299 - "deprecated"`, other values are returned as is
+func warningMessage(header, value string) string {
+	value = strings.TrimSpace(value)
+	if header != "Warning" {
+		return value
+	}
+	start := strings.IndexByte(value, '"')
+	end := strings.LastIndexByte(value, '"')
+	if start < 0 || end <= start {
+		return value
+	}
+	return value[start+1 : end]
+}
+
+// parseRetryAfter returns the delay of a Retry-After value, which is either seconds or an HTTP date
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if seconds, err := strconv.ParseUint(value, 10, 32); err == nil {
+		return time.Duration(seconds) * time.Second, seconds > 0
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return date.Sub(now), date.After(now)
+	}
+	return 0, false
+}
+
+// observe records the warnings of the response, which are logged once per TTL, and starts a backoff of the relay
+// if the response is a 429 or 503 with a Retry-After header
+func (a *relayAdvisories) observe(relay types.RelayEntry, resp *http.Response, now time.Time) {
+	label := relayLabel(relay)
+	for _, header := range relayWarningHeaders {
+		for _, value := range resp.Header.Values(header) {
+			relayDeprecationWarnings.WithLabelValues(label, header).Inc()
+			a.recordWarning(relay, header, warningMessage(header, value), now)
+		}
+	}
+
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+		return
+	}
+	delay, ok := parseRetryAfter(resp.Header.Get("Retry-After"), now)
+	if !ok {
+		return
+	}
+	delay = min(delay, maxRelayRetryAfter)
+	relayRetryAfterBackoffs.WithLabelValues(label).Inc()
+	a.mu.Lock()
+	a.backoffs[relay.String()] = now.Add(delay)
+	a.mu.Unlock()
+	a.log.WithFields(logrus.Fields{
+		"relay":      label,
+		"statusCode": resp.StatusCode,
+		"backoffMs":  delay.Milliseconds(),
+	}).Warn("relay asked to retry later, skipping it for getHeader and registerValidator until then")
+}
+
+func (a *relayAdvisories) recordWarning(relay types.RelayEntry, header, message string, now time.Time) {
+	a.mu.Lock()
+	warnings := a.warnings[relay.String()]
+	if warnings == nil {
+		warnings = make(map[string]*relayWarning)
+		a.warnings[relay.String()] = warnings
+	}
+	key := header + ": " + message
+	warning, ok := warnings[key]
+	isNew := !ok || now.Sub(warning.LastSeen) > relayWarningTTL
+	if isNew {
+		warning = &relayWarning{Header: header, Message: message, FirstSeen: now}
+		warnings[key] = warning
+	}
+	warning.LastSeen = now
+	a.mu.Unlock()
+
+	if isNew {
+		a.log.WithFields(logrus.Fields{
+			"relay":   relayLabel(relay),
+			"header":  header,
+			"message": message,
+		}).Warn("relay announced a deprecation or required upgrade")
+	}
+}
+
+// activeWarnings returns the warnings the relay sent within the TTL, oldest first
+func (a *relayAdvisories) activeWarnings(relay types.RelayEntry, now time.Time) []relayWarning {
+	if a == nil {
+		return nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var active []relayWarning
+	for key, warning := range a.warnings[relay.String()] {
+		if now.Sub(warning.LastSeen) > relayWarningTTL {
+			delete(a.warnings[relay.String()], key)
+			continue
+		}
+		active = append(active, *warning)
+	}
+	slices.SortFunc(active, func(x, y relayWarning) int {
+		return cmp.Or(x.FirstSeen.Compare(y.FirstSeen), cmp.Compare(x.Header+x.Message, y.Header+y.Message))
+	})
+	return active
+}
+
+// backingOff returns the end of the backoff of the relay, and whether it is still backing off
+func (a *relayAdvisories) backingOff(relay types.RelayEntry, now time.Time) (time.Time, bool) {
+	if a == nil {
+		return time.Time{}, false
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	until, ok := a.backoffs[relay.String()]
+	if !ok {
+		return time.Time{}, false
+	}
+	if !now.Before(until) {
+		delete(a.backoffs, relay.String())
+		return time.Time{}, false
+	}
+	return until, true
+}
+
+// available returns the relays which are not backing off
+func (a *relayAdvisories) available(log *logrus.Entry, relays []types.RelayEntry, now time.Time) []types.RelayEntry {
+	available := make([]types.RelayEntry, 0, len(relays))
+	for _, relay := range relays {
+		if until, ok := a.backingOff(relay, now); ok {
+			log.WithFields(logrus.Fields{
+				"relay":        relayLabel(relay),
+				"backoffUntil": until.UTC().Format(time.RFC3339),
+			}).Debug("skipping relay which asked to retry later")
+			continue
+		}
+		available = append(available, relay)
+	}
+	return available
+}
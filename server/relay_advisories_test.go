@@ -0,0 +1,128 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	builderApiV1 "github.com/attestantio/go-builder-client/api/v1"
+	"github.com/flashbots/mev-boost/server/mock"
+	"github.com/flashbots/mev-boost/server/params"
+	"github.com/flashbots/mev-boost/server/types"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		value string
+		delay time.Duration
+		ok    bool
+	}{
+		{value: "30", delay: 30 * time.Second, ok: true},
+		{value: " 5 ", delay: 5 * time.Second, ok: true},
+		{value: "0"},
+		{value: "-1"},
+		{value: "soon"},
+		{value: ""},
+		{value: "Mon, 01 Jan 2024 00:02:00 GMT", delay: 2 * time.Minute, ok: true},
+		{value: "Sun, 31 Dec 2023 23:59:00 GMT"},
+	}
+	for _, tc := range testCases {
+		delay, ok := parseRetryAfter(tc.value, now)
+		require.Equal(t, tc.ok, ok, tc.value)
+		if tc.ok {
+			require.Equal(t, tc.delay, delay, tc.value)
+		}
+	}
+}
+
+func TestWarningMessage(t *testing.T) {
+	require.Equal(t, "please upgrade mev-boost", warningMessage("Warning", `299 relay.example.com "please upgrade mev-boost"`))
+	require.Equal(t, "deprecated", warningMessage("Warning", "deprecated"))
+	require.Equal(t, "@1735689600", warningMessage("Deprecation", " @1735689600 "))
+}
+
+func TestRelayAdvisories(t *testing.T) {
+	hash := mock.HexToHash("0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7")
+	pubkey := mock.HexToPubkey(
+		"0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249")
+
+	backend := newTestBackend(t, 2, time.Second)
+	backend.boost.debugEndpoints = true
+	warned, limited := backend.relays[0], backend.relays[1]
+	warned.OverrideHandleGetHeader(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Add("Warning", `299 - "getHeader v1 is deprecated, please upgrade mev-boost"`)
+		w.Header().Set("Sunset", "Wed, 01 Jan 2025 00:00:00 GMT")
+		w.WriteHeader(http.StatusNoContent)
+	})
+	limited.OverrideHandleGetHeader(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	label := relayLabel(warned.RelayEntry)
+	warningsBefore := testutil.ToFloat64(relayDeprecationWarnings.WithLabelValues(label, "Warning"))
+	backoffsBefore := testutil.ToFloat64(relayRetryAfterBackoffs.WithLabelValues(relayLabel(limited.RelayEntry)))
+
+	path := getHeaderPath(1, hash, pubkey)
+	for range 2 {
+		rr := backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, http.StatusNoContent, rr.Code, rr.Body.String())
+	}
+
+	// Every response with a warning is counted, and the backed off relay is skipped by the second getHeader
+	require.InDelta(t, 2, testutil.ToFloat64(relayDeprecationWarnings.WithLabelValues(label, "Warning"))-warningsBefore, 0)
+	require.InDelta(t, 1, testutil.ToFloat64(relayRetryAfterBackoffs.WithLabelValues(relayLabel(limited.RelayEntry)))-backoffsBefore, 0)
+	require.Equal(t, 2, warned.GetRequestCount(path))
+	require.Equal(t, 1, limited.GetRequestCount(path))
+
+	// Registrations are not sent to the backed off relay either
+	rr := backend.request(t, http.MethodPost, params.PathRegisterValidator, []builderApiV1.SignedValidatorRegistration{testRegistration(pubkey)})
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.Equal(t, 1, warned.GetRequestCount(params.PathRegisterValidator))
+	require.Zero(t, limited.GetRequestCount(params.PathRegisterValidator))
+
+	// The warnings are listed once, and the backoff is shown on the debug endpoint
+	rr = backend.request(t, http.MethodGet, params.PathDebugRelays, nil)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var relays []relayState
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &relays))
+	require.Len(t, relays[0].Warnings, 2)
+	require.Equal(t, "Sunset", relays[0].Warnings[0].Header)
+	require.Equal(t, "Wed, 01 Jan 2025 00:00:00 GMT", relays[0].Warnings[0].Message)
+	require.Equal(t, "getHeader v1 is deprecated, please upgrade mev-boost", relays[0].Warnings[1].Message)
+	require.Nil(t, relays[0].BackoffUntil)
+	require.Empty(t, relays[1].Warnings)
+	require.NotNil(t, relays[1].BackoffUntil)
+	require.WithinDuration(t, time.Now().Add(time.Minute), *relays[1].BackoffUntil, 5*time.Second)
+}
+
+func TestRelayAdvisoriesExpiry(t *testing.T) {
+	advisories := newRelayAdvisories(mock.TestLog)
+	relay := mock.NewRelay(t).RelayEntry
+	now := time.Now()
+
+	resp := &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{}}
+	resp.Header.Set("Retry-After", "3600")
+	resp.Header.Set("Deprecation", "true")
+	advisories.observe(relay, resp, now)
+
+	// The backoff is capped, and ends with the warning after their durations
+	until, ok := advisories.backingOff(relay, now)
+	require.True(t, ok)
+	require.Equal(t, now.Add(maxRelayRetryAfter), until)
+	require.Len(t, advisories.activeWarnings(relay, now), 1)
+	require.Empty(t, advisories.available(mock.TestLog, []types.RelayEntry{relay}, now))
+
+	_, ok = advisories.backingOff(relay, now.Add(maxRelayRetryAfter))
+	require.False(t, ok)
+	require.Empty(t, advisories.activeWarnings(relay, now.Add(relayWarningTTL+time.Second)))
+
+	// A nil tracker observes nothing, and never backs off
+	var none *relayAdvisories
+	_, ok = none.backingOff(relay, now)
+	require.False(t, ok)
+	require.Nil(t, none.activeWarnings(relay, now))
+}
@@ -0,0 +1,89 @@
+package server
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/flashbots/mev-boost/server/types"
+	"github.com/holiman/uint256"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+// Policies for getHeader requests for which every relay failed
+const (
+	RelayFailurePolicyNoBid      = "no-bid"
+	RelayFailurePolicyRetry      = "retry"
+	RelayFailurePolicyRetryAfter = "retry-after"
+)
+
+const (
+	// relayFailureSlotWindow is the part of the slot in which a getHeader request for which every relay failed
+	// is retried, or the beacon node is asked to retry
+	relayFailureSlotWindow = 2 * time.Second
+
+	// relayFailureRetryDelay is the backoff before retrying the auction after every relay failed
+	relayFailureRetryDelay = 200 * time.Millisecond
+
+	// relayFailureRetryAfter is the Retry-After hint for the beacon node, the smallest one the header supports
+	relayFailureRetryAfter = time.Second
+)
+
+var (
+	errAllRelaysFailed             = errors.New("all relays failed")
+	errInvalidRelayFailurePolicy   = errors.New("relay failure policy must be no-bid, retry or retry-after")
+	getHeaderRelayFailureResponses = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "getheader_relay_failure_responses_total",
+		Help: "Number of getHeader requests for which every relay failed, by how mev-boost responded",
+	}, []string{"action"})
+)
+
+// afterAllRelaysFailed applies the relay failure policy to a getHeader request for which every relay failed.
+// It returns the bid of a retried auction, or how long the beacon node should wait before retrying itself.
+// Without either, there is no bid.
+func (m *BoostService) afterAllRelaysFailed(log *logrus.Entry, timer *requestTimer, ua UserAgent, forwarded map[string]string, relays []types.RelayEntry, slot phase0.Slot, pubkey, parentHashHex string, localValue *uint256.Int, deadline time.Time) (bidResp, time.Duration) {
+	remaining := relayFailureSlotWindow - time.Since(m.slotStart(slot))
+	log = log.WithFields(logrus.Fields{
+		"relayFailurePolicy": m.relayFailurePolicy,
+		"remainingWindowMs":  remaining.Milliseconds(),
+	})
+
+	// A bid which was already served for this slot is better than no bid
+	if m.serveCachedBid {
+		if cached, ok := m.cachedBid(slot, parentHashHex, pubkey); ok {
+			getHeaderRelayFailureResponses.WithLabelValues("served_from_cache").Inc()
+			log.WithFields(logrus.Fields{
+				"blockHash": cached.bidInfo.blockHash.String(),
+				"bidAgeMs":  time.Since(cached.t).Milliseconds(),
+				"relays":    strings.Join(types.RelayEntriesToNames(cached.relays), ", "),
+			}).Warn("all relays failed, serving cached bid")
+			cached.servedFromCache = true
+			return cached, 0
+		}
+	}
+
+	switch {
+	case m.relayFailurePolicy == RelayFailurePolicyRetry && remaining > 0 && time.Until(deadline) > relayFailureRetryDelay:
+		time.Sleep(relayFailureRetryDelay)
+		result, err := m.getHeader(log, timer, ua, forwarded, relays, slot, pubkey, parentHashHex, localValue, deadline)
+		if err != nil {
+			getHeaderRelayFailureResponses.WithLabelValues("retry_failed").Inc()
+			log.WithError(err).Warn("all relays failed, and failed again in the retried auction")
+			return bidResp{}, 0
+		}
+		getHeaderRelayFailureResponses.WithLabelValues("retry_succeeded").Inc()
+		log.Info("all relays failed, the retried auction succeeded")
+		return result, 0
+
+	case m.relayFailurePolicy == RelayFailurePolicyRetryAfter && remaining > relayFailureRetryAfter:
+		getHeaderRelayFailureResponses.WithLabelValues("retry_after").Inc()
+		log.Warn("all relays failed, asking the beacon node to retry")
+		return bidResp{}, relayFailureRetryAfter
+	}
+
+	getHeaderRelayFailureResponses.WithLabelValues("no_bid").Inc()
+	log.Warn("all relays failed")
+	return bidResp{}, 0
+}
@@ -0,0 +1,86 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/attestantio/go-eth2-client/spec"
+	"github.com/flashbots/mev-boost/config"
+	"github.com/flashbots/mev-boost/server/mock"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetHeaderRelayFailurePolicy(t *testing.T) {
+	hash := mock.HexToHash("0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7")
+	pubkey := mock.HexToPubkey(
+		"0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249")
+	const slot = 10
+
+	// newFlappingBackend returns a backend whose relay fails the first getHeader request, starting the slot now
+	newFlappingBackend := func(t *testing.T, policy string) *testBackend {
+		t.Helper()
+		backend := newTestBackend(t, 1, time.Second)
+		backend.boost.relayFailurePolicy = policy
+		backend.boost.genesisTime = uint64(time.Now().Unix()) - slot*config.SlotTimeSec
+
+		relay := backend.relays[0]
+		response := relay.MakeGetHeaderResponse(12345, hash.String(), hash.String(), pubkey.String(), spec.DataVersionDeneb)
+		requests := 0
+		relay.OverrideHandleGetHeader(func(w http.ResponseWriter, _ *http.Request) {
+			requests++
+			if requests == 1 {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			require.NoError(t, json.NewEncoder(w).Encode(response))
+		})
+		return backend
+	}
+
+	testCases := []struct {
+		name       string
+		policy     string
+		code       int
+		retryAfter string
+		action     string
+		requests   int
+	}{
+		{name: "No bid", policy: RelayFailurePolicyNoBid, code: http.StatusNoContent, action: "no_bid", requests: 1},
+		{name: "Retry succeeds after a flap", policy: RelayFailurePolicyRetry, code: http.StatusOK, action: "retry_succeeded", requests: 2},
+		{name: "Retry-After", policy: RelayFailurePolicyRetryAfter, code: http.StatusBadGateway, retryAfter: "1", action: "retry_after", requests: 1},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := newFlappingBackend(t, tt.policy)
+			before := testutil.ToFloat64(getHeaderRelayFailureResponses.WithLabelValues(tt.action))
+
+			rr := backend.request(t, http.MethodGet, getHeaderPath(slot, hash, pubkey), nil)
+			require.Equal(t, tt.code, rr.Code, rr.Body.String())
+			require.Equal(t, tt.retryAfter, rr.Header().Get("Retry-After"))
+			require.Equal(t, tt.requests, backend.relays[0].GetRequestCount(getHeaderPath(slot, hash, pubkey)))
+			require.InDelta(t, 1, testutil.ToFloat64(getHeaderRelayFailureResponses.WithLabelValues(tt.action))-before, 0)
+		})
+	}
+
+	t.Run("Not retried late in the slot", func(t *testing.T) {
+		backend := newFlappingBackend(t, RelayFailurePolicyRetry)
+		backend.boost.genesisTime -= 3 * config.SlotTimeSec / 4
+
+		rr := backend.request(t, http.MethodGet, getHeaderPath(slot, hash, pubkey), nil)
+		require.Equal(t, http.StatusNoContent, rr.Code, rr.Body.String())
+		require.Equal(t, 1, backend.relays[0].GetRequestCount(getHeaderPath(slot, hash, pubkey)))
+	})
+
+	t.Run("Not retried without budget", func(t *testing.T) {
+		backend := newFlappingBackend(t, RelayFailurePolicyRetry)
+		backend.boost.httpClientGetHeader.Timeout = 100 * time.Millisecond
+
+		rr := backend.request(t, http.MethodGet, getHeaderPath(slot, hash, pubkey), nil)
+		require.Equal(t, http.StatusNoContent, rr.Code, rr.Body.String())
+		require.Equal(t, 1, backend.relays[0].GetRequestCount(getHeaderPath(slot, hash, pubkey)))
+	})
+}
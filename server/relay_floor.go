@@ -0,0 +1,60 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	errTooFewActiveRelays = errors.New("too few active relays")
+
+	activeRelaysGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "active_relays",
+		Help: "Number of relays whose bids are used, which excludes relays in cooldown with the exclude withholding penalty policy",
+	})
+	relayFloorRefusals = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "relay_floor_refusals_total",
+		Help: "Number of requests refused because fewer relays than the minimum were active",
+	}, []string{"method"})
+)
+
+// activeRelays returns the number of relays whose bids are used at the time
+func (m *BoostService) activeRelays(now time.Time) int {
+	active := 0
+	for _, relay := range m.relays {
+		if _, penalized := m.withholdingPenalties.penalized(relay, now); penalized && m.withholdingPenaltyPolicy == WithholdingPenaltyExclude {
+			continue
+		}
+		active++
+	}
+	return active
+}
+
+// checkMinActiveRelays returns errTooFewActiveRelays if fewer than the minimum number of relays are active. A
+// breach of the floor and the recovery from it are logged once each.
+func (m *BoostService) checkMinActiveRelays(log *logrus.Entry, method string) error {
+	if m.minActiveRelays <= 0 {
+		return nil
+	}
+	active := m.activeRelays(time.Now())
+	activeRelaysGauge.Set(float64(active))
+	log = log.WithFields(logrus.Fields{
+		"activeRelays":    active,
+		"minActiveRelays": m.minActiveRelays,
+	})
+	if active >= m.minActiveRelays {
+		if m.relayFloorBreached.CompareAndSwap(true, false) {
+			log.Info("enough relays are active again, serving requests")
+		}
+		return nil
+	}
+	if !m.relayFloorBreached.Swap(true) {
+		log.Error("fewer relays are active than the minimum, refusing getHeader and getPayload requests until enough relays are active")
+	}
+	relayFloorRefusals.WithLabelValues(method).Inc()
+	return fmt.Errorf("%w: %d of at least %d", errTooFewActiveRelays, active, m.minActiveRelays)
+}
@@ -0,0 +1,84 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/flashbots/mev-boost/server/mock"
+	"github.com/flashbots/mev-boost/server/params"
+	"github.com/flashbots/mev-boost/server/types"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+	logrusTest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMinActiveRelays(t *testing.T) {
+	parentHash := mock.HexToHash("0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7")
+	path := getHeaderPath(1, parentHash, mock.HexToPubkey(feeRecipientTestPubkey))
+
+	logger, hook := logrusTest.NewNullLogger()
+	backend := newTestBackend(t, 2, time.Second)
+	backend.boost.log = logrus.NewEntry(logger)
+	backend.boost.minActiveRelays = 2
+	backend.boost.withholdingPenalties = newWithholdingPenalties(time.Minute)
+	backend.boost.withholdingPenaltyPolicy = WithholdingPenaltyExclude
+
+	rr := backend.request(t, http.MethodGet, path, nil)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	// A relay in cooldown with the exclude policy is not active
+	backend.boost.withholdingPenalties.penalize([]types.RelayEntry{backend.relays[0].RelayEntry}, time.Now())
+	require.Equal(t, 1, backend.boost.activeRelays(time.Now()))
+	before := testutil.ToFloat64(relayFloorRefusals.WithLabelValues("getHeader"))
+	for range 2 {
+		rr = backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, http.StatusServiceUnavailable, rr.Code, rr.Body.String())
+	}
+	require.Contains(t, rr.Body.String(), errTooFewActiveRelays.Error())
+	require.InDelta(t, 2, testutil.ToFloat64(relayFloorRefusals.WithLabelValues("getHeader"))-before, 0)
+	require.Equal(t, 1, backend.relays[1].GetRequestCount(path), "relays are not asked for bids")
+
+	rr = backend.request(t, http.MethodPost, params.PathGetPayload, nil)
+	require.Equal(t, http.StatusServiceUnavailable, rr.Code, rr.Body.String())
+	rr = backend.request(t, http.MethodGet, params.PathStatus, nil)
+	require.Equal(t, http.StatusServiceUnavailable, rr.Code, rr.Body.String())
+
+	// The breach is logged once
+	var breaches int
+	for _, entry := range hook.AllEntries() {
+		if entry.Level == logrus.ErrorLevel && entry.Data["minActiveRelays"] == 2 {
+			breaches++
+		}
+	}
+	require.Equal(t, 1, breaches)
+
+	// With the deprioritize policy, relays in cooldown stay active
+	backend.boost.withholdingPenaltyPolicy = WithholdingPenaltyDeprioritize
+	rr = backend.request(t, http.MethodGet, path, nil)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	// Without a minimum, requests are served with any number of active relays
+	backend.boost.withholdingPenaltyPolicy = WithholdingPenaltyExclude
+	backend.boost.withholdingPenalties.penalize([]types.RelayEntry{backend.relays[1].RelayEntry}, time.Now())
+	backend.boost.minActiveRelays = 0
+	rr = backend.request(t, http.MethodGet, path, nil)
+	require.Equal(t, http.StatusNoContent, rr.Code, rr.Body.String())
+}
+
+func TestMinActiveRelaysConfig(t *testing.T) {
+	opts := BoostServiceOpts{
+		Log:                   mock.TestLog,
+		ListenAddr:            "localhost:12345",
+		Relays:                []types.RelayEntry{mock.NewRelay(t).RelayEntry},
+		GenesisForkVersionHex: "0x00000000",
+	}
+	opts.MinActiveRelays = 1
+	_, err := NewBoostService(opts)
+	require.NoError(t, err)
+
+	opts.MinActiveRelays = 2
+	_, err = NewBoostService(opts)
+	require.ErrorIs(t, err, errInvalidMinActiveRelays)
+}
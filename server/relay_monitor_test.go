@@ -0,0 +1,98 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	builderApiV1 "github.com/attestantio/go-builder-client/api/v1"
+	"github.com/flashbots/mev-boost/server/mock"
+	"github.com/flashbots/mev-boost/server/params"
+	"github.com/flashbots/mev-boost/server/types"
+	"github.com/stretchr/testify/require"
+)
+
+// newRelayMonitorBackend creates a backend with one relay and the relay monitors
+func newRelayMonitorBackend(t *testing.T, regValTimeout, monitorTimeout time.Duration, monitors ...string) *testBackend {
+	t.Helper()
+	relay := mock.NewRelay(t)
+	monitorURLs := make([]*url.URL, 0, len(monitors))
+	for _, monitor := range monitors {
+		u, err := url.Parse(monitor)
+		require.NoError(t, err)
+		monitorURLs = append(monitorURLs, u)
+	}
+	service, err := NewBoostService(BoostServiceOpts{
+		Log:                        mock.TestLog,
+		ListenAddr:                 "localhost:12345",
+		Relays:                     []types.RelayEntry{relay.RelayEntry},
+		RelayMonitors:              monitorURLs,
+		GenesisForkVersionHex:      "0x00000000",
+		RelayMinBid:                types.IntToU256(12345),
+		RequestTimeoutGetHeader:    time.Second,
+		RequestTimeoutGetPayload:   time.Second,
+		RequestTimeoutRegVal:       regValTimeout,
+		RequestTimeoutRelayMonitor: monitorTimeout,
+	})
+	require.NoError(t, err)
+	return &testBackend{boost: service, relays: []*mock.Relay{relay}}
+}
+
+func TestRelayMonitorTimeout(t *testing.T) {
+	t.Run("defaults to the registerValidator timeout", func(t *testing.T) {
+		backend := newRelayMonitorBackend(t, 3*time.Second, 0)
+		require.Equal(t, 3*time.Second, backend.boost.httpClientMonitor.Timeout)
+	})
+
+	t.Run("configured independently", func(t *testing.T) {
+		backend := newRelayMonitorBackend(t, 3*time.Second, 500*time.Millisecond)
+		require.Equal(t, 3*time.Second, backend.boost.httpClientRegVal.Timeout)
+		require.Equal(t, 500*time.Millisecond, backend.boost.httpClientMonitor.Timeout)
+	})
+}
+
+func TestHangingRelayMonitor(t *testing.T) {
+	received := make(chan string, 1)
+	canceled := make(chan struct{})
+	release := make(chan struct{})
+	monitor := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, req *http.Request) {
+		received <- req.URL.Path
+		// The connection is only watched for the client going away after the body is read
+		_, _ = io.ReadAll(req.Body)
+		select {
+		case <-req.Context().Done():
+			close(canceled)
+		case <-release:
+		}
+	}))
+	t.Cleanup(func() {
+		close(release)
+		monitor.Close()
+	})
+
+	backend := newRelayMonitorBackend(t, 2*time.Second, 100*time.Millisecond, monitor.URL+"?path-prefix=/monitor/v1")
+	// The relay answers after the monitor timed out, with the whole registerValidator timeout left
+	backend.relays[0].ResponseDelay = 200 * time.Millisecond
+	payload := []builderApiV1.SignedValidatorRegistration{testRegistration(mock.HexToPubkey(feeRecipientTestPubkey))}
+
+	start := time.Now()
+	rr := backend.request(t, http.MethodPost, params.PathRegisterValidator, payload)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.Less(t, time.Since(start), time.Second)
+	require.Equal(t, 1, backend.relays[0].GetRequestCount(params.PathRegisterValidator))
+
+	select {
+	case path := <-received:
+		require.Equal(t, "/monitor/v1/validators", path)
+	case <-time.After(time.Second):
+		t.Fatal("the relay monitor did not receive the registrations")
+	}
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Fatal("the relay monitor request did not time out")
+	}
+}
@@ -0,0 +1,88 @@
+package server
+
+import (
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/flashbots/mev-boost/server/types"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// maxRelayOrderHeaderLen and maxRelayOrderEntries bound the relay order hint of a getHeader request
+	maxRelayOrderHeaderLen = 1024
+	maxRelayOrderEntries   = 32
+)
+
+var (
+	errRelayOrderTooLong       = errors.New("relay order header is too long")
+	errRelayOrderTooManyRelays = errors.New("relay order header lists too many relays")
+)
+
+// parseRelayOrderHeader parses the comma-separated list of relay hostnames of the relay order header.
+// Hostnames are lowercased, and empty and repeated entries are skipped.
+func parseRelayOrderHeader(value string) ([]string, error) {
+	if len(value) > maxRelayOrderHeaderLen {
+		return nil, errRelayOrderTooLong
+	}
+	hosts := []string{}
+	for _, host := range strings.Split(value, ",") {
+		host = strings.ToLower(strings.TrimSpace(host))
+		if host == "" || slices.Contains(hosts, host) {
+			continue
+		}
+		if len(hosts) == maxRelayOrderEntries {
+			return nil, errRelayOrderTooManyRelays
+		}
+		hosts = append(hosts, host)
+	}
+	return hosts, nil
+}
+
+// orderRelays returns the relays in the order of the hostnames hinted by the beacon node, followed by the relays
+// which were not hinted in the configured order, and the hinted hostnames which matched a relay. Hinted relays
+// get a higher priority than all other relays, in the order of the hint, so that they win the priority selection.
+func (m *BoostService) orderRelays(log *logrus.Entry, hint []string) ([]types.RelayEntry, []string) {
+	if len(hint) == 0 {
+		return m.relays, nil
+	}
+
+	basePriority := 0
+	for _, relay := range m.relays {
+		basePriority = max(basePriority, relay.Priority+1)
+	}
+
+	ordered := make([]types.RelayEntry, 0, len(m.relays))
+	honoured := []string{}
+	unknown := []string{}
+	for i, host := range hint {
+		found := false
+		for _, relay := range m.relays {
+			if !strings.EqualFold(relay.URL.Host, host) && !strings.EqualFold(relay.URL.Hostname(), host) {
+				continue
+			}
+			if slices.ContainsFunc(ordered, func(r types.RelayEntry) bool { return r.String() == relay.String() }) {
+				continue
+			}
+			relay.Priority = basePriority + len(hint) - i
+			ordered = append(ordered, relay)
+			found = true
+		}
+		if found {
+			honoured = append(honoured, host)
+		} else {
+			unknown = append(unknown, host)
+		}
+	}
+	if len(unknown) > 0 {
+		log.WithField("unknownRelays", strings.Join(unknown, ", ")).Warn("ignoring unknown relays in the relay order header")
+	}
+
+	for _, relay := range m.relays {
+		if !slices.ContainsFunc(ordered, func(r types.RelayEntry) bool { return r.String() == relay.String() }) {
+			ordered = append(ordered, relay)
+		}
+	}
+	return ordered, honoured
+}
@@ -0,0 +1,162 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	builderSpec "github.com/attestantio/go-builder-client/spec"
+	"github.com/attestantio/go-eth2-client/spec"
+	"github.com/flashbots/mev-boost/server/mock"
+	"github.com/flashbots/mev-boost/server/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRelayOrderHeader(t *testing.T) {
+	tooMany := make([]string, maxRelayOrderEntries+1)
+	for i := range tooMany {
+		tooMany[i] = strings.Repeat("a", i+1) + ".com"
+	}
+
+	testCases := []struct {
+		name     string
+		value    string
+		expected []string
+		err      error
+	}{
+		{name: "single", value: "relay.com", expected: []string{"relay.com"}},
+		{name: "ordered", value: "b.com,a.com:8080", expected: []string{"b.com", "a.com:8080"}},
+		{name: "spaces, case, empty and repeated", value: " B.com ,, a.com,b.COM,", expected: []string{"b.com", "a.com"}},
+		{name: "only separators", value: ", ,", expected: []string{}},
+		{name: "too long", value: strings.Repeat("a", maxRelayOrderHeaderLen+1), err: errRelayOrderTooLong},
+		{name: "too many", value: strings.Join(tooMany, ","), err: errRelayOrderTooManyRelays},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			hosts, err := parseRelayOrderHeader(tc.value)
+			require.ErrorIs(t, err, tc.err)
+			require.Equal(t, tc.expected, hosts)
+		})
+	}
+}
+
+func TestOrderRelays(t *testing.T) {
+	const pubkey = "0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249"
+	relays := []types.RelayEntry{}
+	for _, relayURL := range []string{"https://" + pubkey + "@a.com?priority=3", "https://" + pubkey + "@b.com", "https://" + pubkey + "@c.com:9000"} {
+		relay, err := types.NewRelayEntry(relayURL)
+		require.NoError(t, err)
+		relays = append(relays, relay)
+	}
+	m := &BoostService{relays: relays}
+
+	hosts := func(relays []types.RelayEntry) []string {
+		hosts := []string{}
+		for _, relay := range relays {
+			hosts = append(hosts, relay.URL.Host)
+		}
+		return hosts
+	}
+
+	t.Run("No hint", func(t *testing.T) {
+		ordered, honoured := m.orderRelays(mock.TestLog, nil)
+		require.Equal(t, relays, ordered)
+		require.Empty(t, honoured)
+	})
+
+	t.Run("Partial overlap", func(t *testing.T) {
+		ordered, honoured := m.orderRelays(mock.TestLog, []string{"c.com", "unknown.com", "b.com"})
+		require.Equal(t, []string{"c.com:9000", "b.com", "a.com"}, hosts(ordered))
+		require.Equal(t, []string{"c.com", "b.com"}, honoured)
+		require.Greater(t, ordered[0].Priority, ordered[1].Priority)
+		require.Greater(t, ordered[1].Priority, ordered[2].Priority)
+		require.Equal(t, 3, ordered[2].Priority)
+
+		// The configured relays are unchanged
+		require.Equal(t, 0, relays[1].Priority)
+	})
+
+	t.Run("No overlap", func(t *testing.T) {
+		ordered, honoured := m.orderRelays(mock.TestLog, []string{"unknown.com"})
+		require.Equal(t, []string{"a.com", "b.com", "c.com:9000"}, hosts(ordered))
+		require.Empty(t, honoured)
+	})
+}
+
+func TestGetHeaderRelayOrder(t *testing.T) {
+	hash := mock.HexToHash("0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7")
+	pubkey := mock.HexToPubkey(
+		"0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249")
+	path := getHeaderPath(1, hash, pubkey)
+
+	request := func(t *testing.T, backend *testBackend, relayOrder string) *httptest.ResponseRecorder {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodGet, path, nil)
+		require.NoError(t, err)
+		if relayOrder != "" {
+			req.Header.Set(HeaderKeyRelayOrder, relayOrder)
+		}
+		rr := httptest.NewRecorder()
+		backend.boost.getRouter().ServeHTTP(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		return rr
+	}
+
+	// The second relay bids slightly more, within the priority tolerance
+	newBackend := func(t *testing.T, enabled bool) *testBackend {
+		t.Helper()
+		backend := newTestBackend(t, 2, time.Second)
+		backend.boost.relayOrderHeader = enabled
+		backend.boost.relayPriorityToleranceBps = 100 // 1%
+		for i, value := range []uint64{100000, 100500} {
+			backend.relays[i].GetHeaderResponse = backend.relays[i].MakeGetHeaderResponse(
+				value,
+				"0xa"+strings.Repeat("0", 62)+string(rune('1'+i)),
+				hash.String(),
+				backend.relays[i].RelayEntry.PublicKey.String(),
+				spec.DataVersionDeneb,
+			)
+		}
+		return backend
+	}
+	bidValue := func(t *testing.T, rr *httptest.ResponseRecorder) uint64 {
+		t.Helper()
+		resp := new(builderSpec.VersionedSignedBuilderBid)
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), resp))
+		value, err := resp.Value()
+		require.NoError(t, err)
+		return value.Uint64()
+	}
+
+	t.Run("Hinted relay wins within the tolerance", func(t *testing.T) {
+		backend := newBackend(t, true)
+		first := backend.relays[0].RelayEntry.URL.Host
+		rr := request(t, backend, "unknown.com, "+first)
+		require.Equal(t, first, rr.Header().Get(HeaderKeyRelayOrder))
+		require.Equal(t, uint64(100000), bidValue(t, rr))
+	})
+
+	t.Run("Without a hint the most valuable bid wins", func(t *testing.T) {
+		backend := newBackend(t, true)
+		rr := request(t, backend, "")
+		require.Empty(t, rr.Header().Values(HeaderKeyRelayOrder))
+		require.Equal(t, uint64(100500), bidValue(t, rr))
+	})
+
+	t.Run("Hint is ignored when disabled", func(t *testing.T) {
+		backend := newBackend(t, false)
+		rr := request(t, backend, backend.relays[0].RelayEntry.URL.Host)
+		require.Empty(t, rr.Header().Values(HeaderKeyRelayOrder))
+		require.Equal(t, uint64(100500), bidValue(t, rr))
+	})
+
+	t.Run("Oversized hint is ignored", func(t *testing.T) {
+		backend := newBackend(t, true)
+		rr := request(t, backend, backend.relays[0].RelayEntry.URL.Host+","+strings.Repeat("a", maxRelayOrderHeaderLen))
+		require.Empty(t, rr.Header().Values(HeaderKeyRelayOrder))
+		require.Equal(t, uint64(100500), bidValue(t, rr))
+	})
+}
@@ -0,0 +1,50 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/flashbots/mev-boost/server/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+var errRelayRedirect = errors.New("relay responded with a redirect")
+
+var relayRedirectBlocked = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "relay_redirect_blocked_total",
+	Help: "Number of redirects in relay responses which were not followed",
+}, []string{"relay"})
+
+// newRelayRedirectPolicy returns the CheckRedirect function of the relay clients. Redirects are not followed
+// and logged with their target, unless followSameHost is set and it is a single redirect to the same host
+// and scheme which keeps the request method.
+func newRelayRedirectPolicy(relays []types.RelayEntry, followSameHost bool, log *logrus.Entry) func(*http.Request, []*http.Request) error {
+	return func(req *http.Request, via []*http.Request) error {
+		original := via[0]
+		if followSameHost && len(via) == 1 &&
+			req.URL.Host == original.URL.Host && req.URL.Scheme == original.URL.Scheme && req.Method == original.Method {
+			return nil
+		}
+
+		relay := original.URL.Host
+		for _, entry := range relays {
+			if entry.URL.Host == original.URL.Host {
+				relay = relayLabel(entry)
+				break
+			}
+		}
+		statusCode := 0
+		if req.Response != nil {
+			statusCode = req.Response.StatusCode
+		}
+		relayRedirectBlocked.WithLabelValues(relay).Inc()
+		log.WithFields(logrus.Fields{
+			"relay":      relay,
+			"url":        original.URL.String(),
+			"location":   req.URL.String(),
+			"statusCode": statusCode,
+		}).Warn("relay responded with a redirect, not following it. Check the relay URL")
+		return http.ErrUseLastResponse
+	}
+}
@@ -0,0 +1,76 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/flashbots/mev-boost/server/mock"
+	"github.com/flashbots/mev-boost/server/types"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRelayRedirectPolicy(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer target.Close()
+
+	// The relay moved the status endpoint on the same host, and redirects another path to a different host
+	relayServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/moved":
+			http.Redirect(w, r, "/status", http.StatusPermanentRedirect)
+		case "/moved-twice":
+			http.Redirect(w, r, "/moved", http.StatusPermanentRedirect)
+		case "/moved-get":
+			http.Redirect(w, r, "/status", http.StatusMovedPermanently)
+		case "/migrated":
+			http.Redirect(w, r, target.URL+"/status", http.StatusPermanentRedirect)
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer relayServer.Close()
+
+	relay, err := types.NewRelayEntry(strings.Replace(relayServer.URL, "http://", "http://0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249@", 1) + "?label=redirecting")
+	require.NoError(t, err)
+	relays := []types.RelayEntry{relay}
+
+	send := func(followSameHost bool, method, path string) (int, error) {
+		client := http.Client{CheckRedirect: newRelayRedirectPolicy(relays, followSameHost, mock.TestLog)}
+		return SendHTTPRequest(context.Background(), client, method, relayServer.URL+path, "", nil, nil, nil)
+	}
+
+	testCases := []struct {
+		name           string
+		followSameHost bool
+		method         string
+		path           string
+		expectedCode   int
+	}{
+		{"cross-host redirect is blocked", false, http.MethodGet, "/migrated", http.StatusPermanentRedirect},
+		{"cross-host redirect is blocked when following same-host redirects", true, http.MethodGet, "/migrated", http.StatusPermanentRedirect},
+		{"same-host redirect is blocked by default", false, http.MethodGet, "/moved", http.StatusPermanentRedirect},
+		{"same-host redirect is followed", true, http.MethodPost, "/moved", http.StatusOK},
+		{"only a single same-host redirect is followed", true, http.MethodGet, "/moved-twice", http.StatusPermanentRedirect},
+		{"same-host redirect changing the method is blocked", true, http.MethodPost, "/moved-get", http.StatusMovedPermanently},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			blocked := testutil.ToFloat64(relayRedirectBlocked.WithLabelValues("redirecting"))
+			code, err := send(tc.followSameHost, tc.method, tc.path)
+			require.Equal(t, tc.expectedCode, code)
+			if tc.expectedCode == http.StatusOK {
+				require.NoError(t, err)
+				require.InDelta(t, blocked, testutil.ToFloat64(relayRedirectBlocked.WithLabelValues("redirecting")), 0)
+				return
+			}
+			require.ErrorIs(t, err, errRelayRedirect)
+			require.InDelta(t, blocked+1, testutil.ToFloat64(relayRedirectBlocked.WithLabelValues("redirecting")), 0)
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/flashbots/mev-boost/server/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+// Reasons of TLS errors of relay requests
+const (
+	tlsErrorCertificateExpired     = "certificate_expired"
+	tlsErrorCertificateNotYetValid = "certificate_not_yet_valid"
+	tlsErrorCertificateInvalid     = "certificate_invalid"
+	tlsErrorUnknownAuthority       = "unknown_authority"
+	tlsErrorHostnameMismatch       = "hostname_mismatch"
+	tlsErrorProtocolVersion        = "protocol_version"
+	tlsErrorNotTLS                 = "not_tls"
+	tlsErrorHandshakeFailure       = "handshake_failure"
+)
+
+var relayTLSErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "relay_tls_errors_total",
+	Help: "Number of requests to relays which failed because of TLS, by reason (certificate_expired, certificate_not_yet_valid, certificate_invalid, unknown_authority, hostname_mismatch, protocol_version, not_tls or handshake_failure)",
+}, []string{"relay", "method", "reason"})
+
+// tlsErrorDetail is the reason of a TLS error of a relay request, with the details logged to diagnose it
+type tlsErrorDetail struct {
+	reason string
+	fields logrus.Fields
+}
+
+// classifyTLSError returns the reason of the error of a relay request, if it failed because of TLS
+func classifyTLSError(err error) (tlsErrorDetail, bool) {
+	if err == nil {
+		return tlsErrorDetail{}, false
+	}
+
+	var invalidErr x509.CertificateInvalidError
+	if errors.As(err, &invalidErr) {
+		detail := tlsErrorDetail{reason: tlsErrorCertificateInvalid, fields: certificateFields(invalidErr.Cert)}
+		if invalidErr.Reason == x509.Expired && invalidErr.Cert != nil {
+			detail.reason = tlsErrorCertificateExpired
+			if time.Now().Before(invalidErr.Cert.NotBefore) {
+				detail.reason = tlsErrorCertificateNotYetValid
+			}
+		}
+		return detail, true
+	}
+	var authorityErr x509.UnknownAuthorityError
+	if errors.As(err, &authorityErr) {
+		return tlsErrorDetail{reason: tlsErrorUnknownAuthority, fields: certificateFields(authorityErr.Cert)}, true
+	}
+	var hostnameErr x509.HostnameError
+	if errors.As(err, &hostnameErr) {
+		fields := certificateFields(hostnameErr.Certificate)
+		fields["tlsHost"] = hostnameErr.Host
+		return tlsErrorDetail{reason: tlsErrorHostnameMismatch, fields: fields}, true
+	}
+	var recordErr tls.RecordHeaderError
+	if errors.As(err, &recordErr) {
+		return tlsErrorDetail{reason: tlsErrorNotTLS, fields: logrus.Fields{"tlsError": recordErr.Msg}}, true
+	}
+	// net/http replaces the record header error of a relay answering in plain HTTP
+	if strings.Contains(err.Error(), "server gave HTTP response to HTTPS client") {
+		return tlsErrorDetail{reason: tlsErrorNotTLS, fields: logrus.Fields{"tlsError": "relay answered in plain HTTP"}}, true
+	}
+	if errors.Is(err, errRelayTLSVersion) {
+		return tlsErrorDetail{reason: tlsErrorProtocolVersion, fields: logrus.Fields{"tlsError": err.Error()}}, true
+	}
+	var alertErr tls.AlertError
+	if errors.As(err, &alertErr) {
+		reason := tlsErrorHandshakeFailure
+		if strings.Contains(alertErr.Error(), "protocol version") {
+			reason = tlsErrorProtocolVersion
+		}
+		return tlsErrorDetail{reason: reason, fields: logrus.Fields{"tlsAlert": alertErr.Error()}}, true
+	}
+	// Other handshake errors of crypto/tls are not typed, but are all prefixed
+	if msg := err.Error(); strings.Contains(msg, "tls: ") {
+		return tlsErrorDetail{reason: tlsErrorHandshakeFailure, fields: logrus.Fields{"tlsError": msg[strings.Index(msg, "tls: "):]}}, true
+	}
+	return tlsErrorDetail{}, false
+}
+
+// certificateFields returns the log fields describing the certificate of a relay
+func certificateFields(cert *x509.Certificate) logrus.Fields {
+	fields := logrus.Fields{}
+	if cert == nil {
+		return fields
+	}
+	fields["certSubject"] = cert.Subject.String()
+	fields["certIssuer"] = cert.Issuer.String()
+	fields["certNotBefore"] = cert.NotBefore.UTC().Format(time.RFC3339)
+	fields["certNotAfter"] = cert.NotAfter.UTC().Format(time.RFC3339)
+	return fields
+}
+
+// countRelayTLSError counts the error of a relay request if it failed because of TLS, and returns the log
+// fields detailing it, which are empty otherwise
+func countRelayTLSError(relay types.RelayEntry, method string, err error) logrus.Fields {
+	detail, ok := classifyTLSError(err)
+	if !ok {
+		return logrus.Fields{}
+	}
+	relayTLSErrors.WithLabelValues(relayLabel(relay), method, detail.reason).Inc()
+	detail.fields["tlsErrorReason"] = detail.reason
+	return detail.fields
+}
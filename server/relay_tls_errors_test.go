@@ -0,0 +1,126 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/flashbots/mev-boost/server/mock"
+	"github.com/flashbots/mev-boost/server/params"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// newExpiredTLSServer returns a TLS server whose certificate expired, and the pool trusting the certificate
+func newExpiredTLSServer(t *testing.T) (*httptest.Server, *x509.CertPool) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "expired relay"},
+		NotBefore:             time.Now().Add(-48 * time.Hour),
+		NotAfter:              time.Now().Add(-24 * time.Hour),
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	server.TLS = &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return server, pool
+}
+
+func TestClassifyTLSError(t *testing.T) {
+	request := func(client *http.Client, url string) error {
+		_, err := SendHTTPRequest(context.Background(), *client, http.MethodGet, url, "", nil, nil, nil)
+		require.Error(t, err)
+		return err
+	}
+
+	t.Run("Expired certificate", func(t *testing.T) {
+		server, pool := newExpiredTLSServer(t)
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}}}
+		detail, ok := classifyTLSError(request(client, server.URL))
+		require.True(t, ok)
+		require.Equal(t, tlsErrorCertificateExpired, detail.reason)
+		require.Equal(t, "CN=expired relay", detail.fields["certSubject"])
+		require.Contains(t, detail.fields, "certNotAfter")
+	})
+
+	t.Run("Unknown authority", func(t *testing.T) {
+		server := httptest.NewTLSServer(http.NotFoundHandler())
+		defer server.Close()
+		detail, ok := classifyTLSError(request(&http.Client{}, server.URL))
+		require.True(t, ok)
+		require.Equal(t, tlsErrorUnknownAuthority, detail.reason)
+	})
+
+	t.Run("Hostname mismatch", func(t *testing.T) {
+		server := httptest.NewTLSServer(http.NotFoundHandler())
+		defer server.Close()
+		url := strings.Replace(server.URL, "127.0.0.1", "localhost", 1)
+		detail, ok := classifyTLSError(request(server.Client(), url))
+		require.True(t, ok)
+		require.Equal(t, tlsErrorHostnameMismatch, detail.reason)
+		require.Equal(t, "localhost", detail.fields["tlsHost"])
+	})
+
+	t.Run("Relay does not speak TLS", func(t *testing.T) {
+		server := httptest.NewServer(http.NotFoundHandler())
+		defer server.Close()
+		url := strings.Replace(server.URL, "http://", "https://", 1)
+		detail, ok := classifyTLSError(request(&http.Client{}, url))
+		require.True(t, ok)
+		require.Equal(t, tlsErrorNotTLS, detail.reason)
+	})
+
+	t.Run("Other errors are not TLS errors", func(t *testing.T) {
+		_, ok := classifyTLSError(errHTTPErrorResponse)
+		require.False(t, ok)
+		_, ok = classifyTLSError(nil)
+		require.False(t, ok)
+	})
+}
+
+func TestCountRelayTLSError(t *testing.T) {
+	relay := mock.NewRelay(t)
+	label := relayLabel(relay.RelayEntry)
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	defer server.Close()
+
+	_, err := SendHTTPRequest(context.Background(), http.Client{}, http.MethodGet, server.URL+params.PathStatus, "", nil, nil, nil)
+	require.Error(t, err)
+	fields := countRelayRequestError(relay.RelayEntry, "status", err)
+	require.Equal(t, tlsErrorUnknownAuthority, fields["tlsErrorReason"])
+	require.InDelta(t, 1, testutil.ToFloat64(relayRequestErrors.WithLabelValues(label, "status")), 0)
+	require.InDelta(t, 1, testutil.ToFloat64(relayTLSErrors.WithLabelValues(label, "status", tlsErrorUnknownAuthority)), 0)
+
+	// Relay errors which are not TLS errors are not detailed
+	require.Empty(t, countRelayRequestError(relay.RelayEntry, "status", errHTTPErrorResponse))
+	require.InDelta(t, 1, testutil.ToFloat64(relayTLSErrors.WithLabelValues(label, "status", tlsErrorUnknownAuthority)), 0)
+}
@@ -0,0 +1,148 @@
+package server
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// defaultDialTimeout and defaultDialKeepAlive are the dialer settings of http.DefaultTransport
+const (
+	defaultDialTimeout   = 30 * time.Second
+	defaultDialKeepAlive = 30 * time.Second
+)
+
+// Minimum TLS versions of connections to relays
+const (
+	RelayTLSVersion12 = "1.2"
+	RelayTLSVersion13 = "1.3"
+)
+
+var (
+	errInvalidRelayLocalAddr     = errors.New("relay local address must be an IP address or a network interface")
+	errInvalidRelayTLSMinVersion = errors.New("relay TLS minimum version must be 1.2 or 1.3")
+	errRelayTLSVersion           = errors.New("relay does not support the minimum TLS version")
+)
+
+// parseRelayTLSMinVersion returns the minimum TLS version of connections to relays, TLS 1.2 if empty
+func parseRelayTLSMinVersion(version string) (uint16, error) {
+	switch version {
+	case "", RelayTLSVersion12:
+		return tls.VersionTLS12, nil
+	case RelayTLSVersion13:
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("%w: %s", errInvalidRelayTLSMinVersion, version)
+	}
+}
+
+// resolveRelayLocalAddr returns the source address of connections to relays, from an IP address or the name
+// of a network interface, whose first address is used. It returns nil if the address cannot be bound, in which
+// case connections use the default source address of the route to the relay.
+func resolveRelayLocalAddr(addr string, log *logrus.Entry) (*net.TCPAddr, error) {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		iface, err := net.InterfaceByName(addr)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", errInvalidRelayLocalAddr, addr)
+		}
+		if addrs, err := iface.Addrs(); err == nil && len(addrs) > 0 {
+			if ipNet, ok := addrs[0].(*net.IPNet); ok {
+				ip = ipNet.IP
+			}
+		}
+		if ip == nil {
+			log.WithField("interface", addr).Warn("relay local interface has no address, using the default source address")
+			return nil, nil //nolint:nilnil
+		}
+	}
+
+	// Binding to the address checks that it is assigned to this host
+	listener, err := net.Listen("tcp", net.JoinHostPort(ip.String(), "0"))
+	if err != nil {
+		log.WithError(err).WithField("localAddr", ip.String()).Warn("cannot bind the relay local address, using the default source address")
+		return nil, nil //nolint:nilnil
+	}
+	listener.Close()
+	return &net.TCPAddr{IP: ip}, nil
+}
+
+// newConfiguredRelayTransport resolves the local address and minimum TLS version of relay connections, and returns
+// the transport of newRelayTransport. It is used by the relay clients of the service and by the relay probe, so both
+// connect to relays in the same way.
+func newConfiguredRelayTransport(dialTimeout, tlsHandshakeTimeout, dnsCacheTTL time.Duration, localAddr, tlsMinVersion string, log *logrus.Entry) (http.RoundTripper, error) {
+	var tcpAddr *net.TCPAddr
+	if localAddr != "" {
+		var err error
+		tcpAddr, err = resolveRelayLocalAddr(localAddr, log)
+		if err != nil {
+			return nil, err
+		}
+		if tcpAddr != nil {
+			log.WithField("localAddr", tcpAddr.IP.String()).Info("connecting to relays from local address")
+		}
+	}
+	version, err := parseRelayTLSMinVersion(tlsMinVersion)
+	if err != nil {
+		return nil, err
+	}
+	return newRelayTransport(dialTimeout, tlsHandshakeTimeout, dnsCacheTTL, tcpAddr, version, log), nil
+}
+
+// newRelayTransport returns the transport of the relay clients, or nil to use http.DefaultTransport if none of
+// the transport settings is configured. The dial and TLS handshake timeouts bound connecting to a relay,
+// separately from the request timeouts of the clients, and connections are made from localAddr if not nil.
+// Connections negotiate at least tlsMinVersion, which is never below TLS 1.2.
+func newRelayTransport(dialTimeout, tlsHandshakeTimeout, dnsCacheTTL time.Duration, localAddr *net.TCPAddr, tlsMinVersion uint16, log *logrus.Entry) http.RoundTripper {
+	tlsMinVersion = max(tlsMinVersion, tls.VersionTLS12)
+	if dialTimeout <= 0 && tlsHandshakeTimeout <= 0 && dnsCacheTTL <= 0 && localAddr == nil && tlsMinVersion == tls.VersionTLS12 {
+		return nil
+	}
+
+	dialer := &net.Dialer{Timeout: defaultDialTimeout, KeepAlive: defaultDialKeepAlive}
+	if dialTimeout > 0 {
+		dialer.Timeout = dialTimeout
+	}
+	if localAddr != nil {
+		dialer.LocalAddr = localAddr
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	transport.TLSClientConfig = &tls.Config{MinVersion: tlsMinVersion}
+	if tlsHandshakeTimeout > 0 {
+		transport.TLSHandshakeTimeout = tlsHandshakeTimeout
+	}
+	if dnsCacheTTL > 0 {
+		transport.DialContext = newDNSCache(dnsCacheTTL, dialer, log).DialContext
+	}
+	if tlsMinVersion > tls.VersionTLS12 {
+		return &relayTLSVersionTransport{next: transport, minVersion: tlsMinVersion}
+	}
+	return transport
+}
+
+// relayTLSVersionTransport explains the handshake failures of relays which only support TLS versions below the
+// minimum, which the TLS package reports as an unsupported protocol version
+type relayTLSVersionTransport struct {
+	next       *http.Transport
+	minVersion uint16
+}
+
+// CloseIdleConnections closes the idle connections of the wrapped transport
+func (t *relayTLSVersionTransport) CloseIdleConnections() {
+	t.next.CloseIdleConnections()
+}
+
+func (t *relayTLSVersionTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil && strings.Contains(err.Error(), "protocol version") {
+		return nil, fmt.Errorf("%w %s: %w", errRelayTLSVersion, tls.VersionName(t.minVersion), err)
+	}
+	return resp, err
+}
@@ -0,0 +1,141 @@
+package server
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/flashbots/mev-boost/server/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewRelayTransport(t *testing.T) {
+	require.Nil(t, newRelayTransport(0, 0, 0, nil, 0, mock.TestLog))
+
+	transport, ok := newRelayTransport(time.Second, 2*time.Second, 0, nil, 0, mock.TestLog).(*http.Transport)
+	require.True(t, ok)
+	require.Equal(t, 2*time.Second, transport.TLSHandshakeTimeout)
+	require.NotNil(t, transport.DialContext)
+
+	transport, ok = newRelayTransport(time.Second, 0, 0, nil, 0, mock.TestLog).(*http.Transport)
+	require.True(t, ok)
+	require.Equal(t, http.DefaultTransport.(*http.Transport).TLSHandshakeTimeout, transport.TLSHandshakeTimeout)
+	require.Equal(t, uint16(tls.VersionTLS12), transport.TLSClientConfig.MinVersion)
+
+	// TLS 1.3 is enforced even if no other setting is configured
+	tlsTransport, ok := newRelayTransport(0, 0, 0, nil, tls.VersionTLS13, mock.TestLog).(*relayTLSVersionTransport)
+	require.True(t, ok)
+	require.Equal(t, uint16(tls.VersionTLS13), tlsTransport.next.TLSClientConfig.MinVersion)
+}
+
+func TestParseRelayTLSMinVersion(t *testing.T) {
+	for version, expected := range map[string]uint16{"": tls.VersionTLS12, "1.2": tls.VersionTLS12, "1.3": tls.VersionTLS13} {
+		minVersion, err := parseRelayTLSMinVersion(version)
+		require.NoError(t, err)
+		require.Equal(t, expected, minVersion)
+	}
+	for _, version := range []string{"1.1", "1.0", "tls1.3"} {
+		_, err := parseRelayTLSMinVersion(version)
+		require.ErrorIs(t, err, errInvalidRelayTLSMinVersion)
+	}
+}
+
+func TestRelayTransportTLSMinVersion(t *testing.T) {
+	newRelay := func(t *testing.T, maxVersion uint16) *httptest.Server {
+		t.Helper()
+		relay := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		relay.TLS = &tls.Config{MaxVersion: maxVersion}
+		relay.StartTLS()
+		t.Cleanup(relay.Close)
+		return relay
+	}
+	get := func(t *testing.T, relay *httptest.Server) error {
+		t.Helper()
+		transport := newRelayTransport(0, 0, 0, nil, tls.VersionTLS13, mock.TestLog).(*relayTLSVersionTransport)
+		transport.next.TLSClientConfig.RootCAs = relay.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs
+		resp, err := (&http.Client{Timeout: time.Second, Transport: transport}).Get(relay.URL)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	require.NoError(t, get(t, newRelay(t, tls.VersionTLS13)))
+
+	err := get(t, newRelay(t, tls.VersionTLS12))
+	require.ErrorIs(t, err, errRelayTLSVersion)
+	require.ErrorContains(t, err, "TLS 1.3")
+}
+
+func TestRelayTransportTLSHandshakeTimeout(t *testing.T) {
+	// The relay accepts connections, but never completes the TLS handshake
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		var conns []net.Conn
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				for _, conn := range conns {
+					conn.Close()
+				}
+				return
+			}
+			conns = append(conns, conn)
+		}
+	}()
+
+	client := http.Client{
+		Timeout:   5 * time.Second,
+		Transport: newRelayTransport(0, 100*time.Millisecond, 0, nil, 0, mock.TestLog),
+	}
+	start := time.Now()
+	resp, err := client.Get("https://" + listener.Addr().String())
+	if resp != nil {
+		resp.Body.Close()
+	}
+	require.ErrorContains(t, err, "TLS handshake timeout")
+	require.Less(t, time.Since(start), time.Second)
+}
+
+func TestResolveRelayLocalAddr(t *testing.T) {
+	localAddr, err := resolveRelayLocalAddr("127.0.0.1", mock.TestLog)
+	require.NoError(t, err)
+	require.Equal(t, "127.0.0.1", localAddr.IP.String())
+
+	// An address which is not assigned to this host falls back to the default source address
+	localAddr, err = resolveRelayLocalAddr("192.0.2.1", mock.TestLog)
+	require.NoError(t, err)
+	require.Nil(t, localAddr)
+
+	_, err = resolveRelayLocalAddr("not-an-interface", mock.TestLog)
+	require.ErrorIs(t, err, errInvalidRelayLocalAddr)
+}
+
+func TestRelayTransportLocalAddr(t *testing.T) {
+	remoteAddr := make(chan string, 1)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			remoteAddr <- r.RemoteAddr
+		}),
+		ReadHeaderTimeout: time.Second,
+	}
+	go server.Serve(listener) //nolint:errcheck
+	defer server.Close()
+
+	localAddr := &net.TCPAddr{IP: net.ParseIP("127.0.0.1")}
+	client := http.Client{Timeout: time.Second, Transport: newRelayTransport(0, 0, 0, localAddr, 0, mock.TestLog)}
+	resp, err := client.Get("http://" + listener.Addr().String())
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	host, _, err := net.SplitHostPort(<-remoteAddr)
+	require.NoError(t, err)
+	require.Equal(t, "127.0.0.1", host)
+}
@@ -0,0 +1,163 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/flashbots/mev-boost/server/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// reloadLockTimeout bounds how long a reload waits for the requests in flight to finish
+	reloadLockTimeout = 10 * time.Second
+	// reloadLockPollInterval is how often a reload tries to take the reload lock
+	reloadLockPollInterval = 10 * time.Millisecond
+)
+
+var (
+	errReloadBusy = errors.New("requests in flight did not finish in time, not reloading")
+
+	configReloads = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "config_reloads_total",
+		Help: "Number of reloads of the relays and policies, by kind (relays or policies) and result (ok or error)",
+	}, []string{"kind", "result"})
+)
+
+// holdConfig holds the reload lock for reading while the request is handled, so that the relays and policies
+// do not change in the middle of a request
+func (m *BoostService) holdConfig(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		m.reloadLock.RLock()
+		defer m.reloadLock.RUnlock()
+		next.ServeHTTP(w, req)
+	})
+}
+
+// lockForReload takes the reload lock for writing, once no request is in flight. It polls with TryLock, as a
+// waiting Lock would hold back new getHeader requests until every request in flight has finished. As the lock
+// is never pending, the read lock may also be taken again by a request which already holds it.
+func (m *BoostService) lockForReload() error {
+	deadline := time.Now().Add(reloadLockTimeout)
+	for !m.reloadLock.TryLock() {
+		if time.Now().After(deadline) {
+			return errReloadBusy
+		}
+		time.Sleep(reloadLockPollInterval)
+	}
+	return nil
+}
+
+// ReloadRelays replaces the relays. Top bid streams are only connected at startup, so streams of added relays
+// are not used until the next restart.
+func (m *BoostService) ReloadRelays(relays []types.RelayEntry) error {
+	if len(relays) == 0 {
+		configReloads.WithLabelValues("relays", "error").Inc()
+		return errNoRelays
+	}
+	if err := m.lockForReload(); err != nil {
+		configReloads.WithLabelValues("relays", "error").Inc()
+		return err
+	}
+	previous := m.applyRelaysLocked(relays)
+	m.reloadLock.Unlock()
+
+	m.logReloadedRelays(previous, relays)
+	return nil
+}
+
+// ReloadPolicies applies the minimum bids, relay priority tolerance, conflicting bids and tie-break handling and
+// the failed delivery, relay failure and withholding penalty policies of the options. The options are validated
+// like in NewBoostService, and nothing is changed if they are invalid.
+func (m *BoostService) ReloadPolicies(opts BoostServiceOpts) error {
+	if errs := validateConfig(opts, false); len(errs) > 0 {
+		configReloads.WithLabelValues("policies", "error").Inc()
+		return errors.Join(errs...)
+	}
+	setPolicyDefaults(&opts)
+	if err := m.lockForReload(); err != nil {
+		configReloads.WithLabelValues("policies", "error").Inc()
+		return err
+	}
+	m.applyPoliciesLocked(opts)
+	m.reloadLock.Unlock()
+
+	m.logReloadedPolicies(opts)
+	return nil
+}
+
+// Reload applies the relays and the policies of the options together, like ReloadRelays and ReloadPolicies. Both
+// are validated first and applied under one reload lock, so either both change or neither does.
+func (m *BoostService) Reload(opts BoostServiceOpts) error {
+	fail := func(err error) error {
+		configReloads.WithLabelValues("relays", "error").Inc()
+		configReloads.WithLabelValues("policies", "error").Inc()
+		return err
+	}
+	if len(opts.Relays) == 0 {
+		return fail(errNoRelays)
+	}
+	if errs := validateConfig(opts, false); len(errs) > 0 {
+		return fail(errors.Join(errs...))
+	}
+	setPolicyDefaults(&opts)
+	if err := m.lockForReload(); err != nil {
+		return fail(err)
+	}
+	previous := m.applyRelaysLocked(opts.Relays)
+	m.applyPoliciesLocked(opts)
+	m.reloadLock.Unlock()
+
+	m.logReloadedRelays(previous, opts.Relays)
+	m.logReloadedPolicies(opts)
+	return nil
+}
+
+// applyRelaysLocked replaces the relays and returns the previous ones, the reload lock must be held for writing
+func (m *BoostService) applyRelaysLocked(relays []types.RelayEntry) []types.RelayEntry {
+	previous := m.relays
+	m.relays = slices.Clone(relays)
+	return previous
+}
+
+// applyPoliciesLocked applies the policies of the validated options, the reload lock must be held for writing
+func (m *BoostService) applyPoliciesLocked(opts BoostServiceOpts) {
+	m.relayMinBid = opts.RelayMinBid
+	m.minBidOverLocalPct = opts.MinBidOverLocalPct
+	m.relayPriorityToleranceBps = uint64(opts.RelayPriorityTolerancePct * 100)
+	m.bidTieBreak = opts.BidTieBreak
+	m.conflictingBids = opts.ConflictingBids
+	m.failedDeliveryPolicy = opts.FailedDeliveryPolicy
+	m.relayFailurePolicy = opts.RelayFailurePolicy
+	m.withholdingPenaltyPolicy = opts.WithholdingPenaltyPolicy
+}
+
+func (m *BoostService) logReloadedRelays(previous, relays []types.RelayEntry) {
+	for _, relay := range relays {
+		if relay.Stream && m.topBidStreams[relay.String()] == nil {
+			m.log.WithField("relay", relayLabel(relay)).Warn("the top bid stream of an added relay is only used after a restart")
+		}
+	}
+	configReloads.WithLabelValues("relays", "ok").Inc()
+	m.log.WithFields(logrus.Fields{
+		"previousRelays": types.RelayEntriesToNames(previous),
+		"relays":         types.RelayEntriesToNames(relays),
+	}).Info("reloaded relays")
+}
+
+func (m *BoostService) logReloadedPolicies(opts BoostServiceOpts) {
+	configReloads.WithLabelValues("policies", "ok").Inc()
+	m.log.WithFields(logrus.Fields{
+		"minBid":                   opts.RelayMinBid.String(),
+		"minBidOverLocalPct":       opts.MinBidOverLocalPct,
+		"priorityTolerancePct":     opts.RelayPriorityTolerancePct,
+		"bidTieBreak":              opts.BidTieBreak,
+		"conflictingBids":          opts.ConflictingBids,
+		"failedDeliveryPolicy":     opts.FailedDeliveryPolicy,
+		"relayFailurePolicy":       opts.RelayFailurePolicy,
+		"withholdingPenaltyPolicy": opts.WithholdingPenaltyPolicy,
+	}).Info("reloaded policies")
+}
@@ -0,0 +1,122 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/attestantio/go-eth2-client/spec"
+	"github.com/flashbots/mev-boost/server/mock"
+	"github.com/flashbots/mev-boost/server/params"
+	"github.com/flashbots/mev-boost/server/types"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReloadRelays(t *testing.T) {
+	backend := newTestBackend(t, 2, time.Second)
+	relays := []types.RelayEntry{backend.relays[1].RelayEntry}
+
+	before := testutil.ToFloat64(configReloads.WithLabelValues("relays", "ok"))
+	require.NoError(t, backend.boost.ReloadRelays(relays))
+	require.Equal(t, relays, backend.boost.relays)
+	require.InDelta(t, 1, testutil.ToFloat64(configReloads.WithLabelValues("relays", "ok"))-before, 0)
+
+	require.ErrorIs(t, backend.boost.ReloadRelays(nil), errNoRelays)
+	require.Equal(t, relays, backend.boost.relays)
+
+	t.Run("A reload waits for the requests in flight", func(t *testing.T) {
+		backend.boost.reloadLock.RLock()
+		released := make(chan struct{})
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			close(released)
+			backend.boost.reloadLock.RUnlock()
+		}()
+		require.NoError(t, backend.boost.ReloadRelays([]types.RelayEntry{backend.relays[0].RelayEntry}))
+		select {
+		case <-released:
+		default:
+			t.Fatal("relays were reloaded during a request")
+		}
+	})
+}
+
+func TestReloadPolicies(t *testing.T) {
+	backend := newTestBackend(t, 1, time.Second)
+	opts := BoostServiceOpts{
+		Relays:                    backend.boost.relays,
+		GenesisForkVersionHex:     "0x00000000",
+		RelayPriorityTolerancePct: 1.5,
+		MinBidOverLocalPct:        5,
+		BidTieBreak:               BidTieBreakRandom,
+		RelayFailurePolicy:        RelayFailurePolicyRetry,
+	}
+	require.NoError(t, backend.boost.ReloadPolicies(opts))
+	require.Equal(t, uint64(150), backend.boost.relayPriorityToleranceBps)
+	require.InDelta(t, 5, backend.boost.minBidOverLocalPct, 0)
+	require.Equal(t, BidTieBreakRandom, backend.boost.bidTieBreak)
+	require.Equal(t, RelayFailurePolicyRetry, backend.boost.relayFailurePolicy)
+
+	// Unset policies are reset to their defaults
+	require.Equal(t, ConflictingBidsLowest, backend.boost.conflictingBids)
+	require.Equal(t, FailedDeliveryPolicyDeprioritize, backend.boost.failedDeliveryPolicy)
+
+	// Nothing is changed by invalid options
+	opts.BidTieBreak = "never"
+	opts.MinBidOverLocalPct = 10
+	require.ErrorIs(t, backend.boost.ReloadPolicies(opts), errInvalidBidTieBreak)
+	require.Equal(t, BidTieBreakRandom, backend.boost.bidTieBreak)
+	require.InDelta(t, 5, backend.boost.minBidOverLocalPct, 0)
+}
+
+func TestReload(t *testing.T) {
+	backend := newTestBackend(t, 2, time.Second)
+	initialRelays := backend.boost.relays
+	opts := BoostServiceOpts{
+		Relays:                []types.RelayEntry{backend.relays[1].RelayEntry},
+		GenesisForkVersionHex: "0x00000000",
+		BidTieBreak:           BidTieBreakRandom,
+	}
+
+	// Neither the relays nor the policies change if one of them is invalid
+	invalid := opts
+	invalid.BidTieBreak = "never"
+	require.ErrorIs(t, backend.boost.Reload(invalid), errInvalidBidTieBreak)
+	invalid = opts
+	invalid.Relays = nil
+	require.ErrorIs(t, backend.boost.Reload(invalid), errNoRelays)
+	require.Equal(t, initialRelays, backend.boost.relays)
+	require.Equal(t, BidTieBreakRelayPosition, backend.boost.bidTieBreak)
+
+	require.NoError(t, backend.boost.Reload(opts))
+	require.Equal(t, opts.Relays, backend.boost.relays)
+	require.Equal(t, BidTieBreakRandom, backend.boost.bidTieBreak)
+}
+
+func TestGetPayloadAfterRelayReload(t *testing.T) {
+	block, response := loadDenebBlock(t)
+	header := block.Message.Body.ExecutionPayloadHeader
+	backend := newTestBackend(t, 2, time.Second)
+	backend.relays[0].GetHeaderResponse = backend.relays[0].MakeGetHeaderResponse(
+		12345, header.BlockHash.String(), header.ParentHash.String(), feeRecipientTestPubkey, spec.DataVersionDeneb)
+	backend.relays[0].GetPayloadResponse = response
+	backend.relays[1].OverrideHandleGetHeader(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	rr := backend.request(t, http.MethodGet, getHeaderPath(uint64(block.Message.Slot), header.ParentHash, mock.HexToPubkey(feeRecipientTestPubkey)), nil)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	// The winning relay is not in the reloaded relay list, which is still asked as well
+	reloaded := mock.NewRelay(t)
+	require.NoError(t, backend.boost.ReloadRelays([]types.RelayEntry{reloaded.RelayEntry}))
+
+	rr = backend.request(t, http.MethodPost, params.PathGetPayload, block)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.Equal(t, 1, backend.relays[0].GetRequestCount(params.PathGetPayload))
+	require.Zero(t, backend.relays[1].GetRequestCount(params.PathGetPayload))
+	require.Eventually(t, func() bool {
+		return reloaded.GetRequestCount(params.PathGetPayload) == 1
+	}, time.Second, 10*time.Millisecond)
+}
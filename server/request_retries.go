@@ -0,0 +1,110 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Conditions of failed relay requests, which decide whether a request is retried
+const (
+	RetryOnConnection      = "connection"
+	RetryOnTimeout         = "timeout"
+	RetryOn5xx             = "5xx"
+	RetryOn4xx             = "4xx"
+	RetryOnInvalidResponse = "invalid_response"
+)
+
+// DefaultRetryConditions are the transient errors, a 4xx or an invalid response would fail again
+var DefaultRetryConditions = []string{RetryOnConnection, RetryOnTimeout, RetryOn5xx}
+
+var (
+	errUnknownRetryCondition = errors.New("unknown retry condition, must be connection, timeout, 5xx, 4xx or invalid_response")
+	errInvalidRetryCondition = errors.New("invalid retry condition, must be <condition> or <condition>=<max retries>")
+
+	relayRequestRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "relay_request_retries_total",
+		Help: "Number of failed relay getPayload requests by condition, and whether they were retried",
+	}, []string{"condition", "retried"})
+)
+
+// retryConditions are the maximum number of retries of the conditions which are retried, 0 for the maximum of
+// all requests
+type retryConditions map[string]int
+
+// parseRetryConditions parses entries of <condition> or <condition>=<max retries>, nil entries are the defaults
+func parseRetryConditions(entries []string) (retryConditions, error) {
+	if entries == nil {
+		entries = DefaultRetryConditions
+	}
+	conditions := make(retryConditions, len(entries))
+	for _, entry := range entries {
+		if entry == "" {
+			continue
+		}
+		name, limit, hasLimit := strings.Cut(entry, "=")
+		name = strings.TrimSpace(name)
+		switch name {
+		case RetryOnConnection, RetryOnTimeout, RetryOn5xx, RetryOn4xx, RetryOnInvalidResponse:
+		default:
+			return nil, fmt.Errorf("%w: %s", errUnknownRetryCondition, name)
+		}
+		conditions[name] = 0
+		if hasLimit {
+			n, err := strconv.Atoi(strings.TrimSpace(limit))
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("%w: %s", errInvalidRetryCondition, entry)
+			}
+			conditions[name] = n
+		}
+	}
+	return conditions, nil
+}
+
+// retryConditionsKey is the context key of the retry conditions of SendHTTPRequestWithRetries
+type retryConditionsKey struct{}
+
+// withRetryConditions returns a context in which SendHTTPRequestWithRetries only retries the conditions
+func withRetryConditions(ctx context.Context, conditions retryConditions) context.Context {
+	return context.WithValue(ctx, retryConditionsKey{}, conditions)
+}
+
+// retryLimit returns the maximum number of requests after an error of the condition, and false if the condition
+// is not retried. Without conditions in the context, the default conditions are retried.
+func retryLimit(ctx context.Context, condition string, maxRetries int) (int, bool) {
+	conditions, ok := ctx.Value(retryConditionsKey{}).(retryConditions)
+	if !ok {
+		conditions, _ = parseRetryConditions(nil)
+	}
+	limit, ok := conditions[condition]
+	if !ok {
+		return 0, false
+	}
+	if limit == 0 || limit > maxRetries {
+		limit = maxRetries
+	}
+	return limit, true
+}
+
+// retryCondition returns the condition of a failed relay request
+func retryCondition(code int, err error) string {
+	var urlErr *url.Error
+	switch {
+	case errors.Is(err, context.DeadlineExceeded) || os.IsTimeout(err):
+		return RetryOnTimeout
+	case code >= 500:
+		return RetryOn5xx
+	case code >= 400:
+		return RetryOn4xx
+	case code == 0 && errors.As(err, &urlErr):
+		return RetryOnConnection
+	default:
+		return RetryOnInvalidResponse
+	}
+}
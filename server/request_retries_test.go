@@ -0,0 +1,75 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+
+	"github.com/flashbots/mev-boost/server/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRetryConditions(t *testing.T) {
+	conditions, err := parseRetryConditions(nil)
+	require.NoError(t, err)
+	require.Equal(t, retryConditions{RetryOnConnection: 0, RetryOnTimeout: 0, RetryOn5xx: 0}, conditions)
+
+	conditions, err = parseRetryConditions([]string{"5xx=2", " 4xx ", ""})
+	require.NoError(t, err)
+	require.Equal(t, retryConditions{RetryOn5xx: 2, RetryOn4xx: 0}, conditions)
+
+	conditions, err = parseRetryConditions([]string{""})
+	require.NoError(t, err)
+	require.Empty(t, conditions)
+
+	_, err = parseRetryConditions([]string{"503"})
+	require.ErrorIs(t, err, errUnknownRetryCondition)
+	_, err = parseRetryConditions([]string{"5xx=0"})
+	require.ErrorIs(t, err, errInvalidRetryCondition)
+}
+
+func TestRetryCondition(t *testing.T) {
+	require.Equal(t, RetryOn5xx, retryCondition(http.StatusServiceUnavailable, errHTTPErrorResponse))
+	require.Equal(t, RetryOn4xx, retryCondition(http.StatusBadRequest, errHTTPErrorResponse))
+	require.Equal(t, RetryOnConnection, retryCondition(0, &url.Error{Op: "Post", Err: errors.New("connection reset by peer")}))
+	require.Equal(t, RetryOnTimeout, retryCondition(0, context.DeadlineExceeded))
+	require.Equal(t, RetryOnInvalidResponse, retryCondition(http.StatusOK, errEmptyResponseBody))
+}
+
+func TestSendHTTPRequestWithRetriesConditions(t *testing.T) {
+	var status atomic.Int32
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(int(status.Load()))
+	}))
+	defer server.Close()
+
+	send := func(ctx context.Context, code int) error {
+		status.Store(int32(code))
+		requests.Store(0)
+		_, err := SendHTTPRequestWithRetries(ctx, *http.DefaultClient, http.MethodGet, server.URL, "test", nil, nil, nil, 3, mock.TestLog)
+		return err
+	}
+
+	// A 400 would fail again, and is returned without retrying
+	err := send(context.Background(), http.StatusBadRequest)
+	require.ErrorIs(t, err, errHTTPErrorResponse)
+	require.Equal(t, int32(1), requests.Load())
+
+	// A 503 is retried up to the maximum
+	err = send(context.Background(), http.StatusServiceUnavailable)
+	require.ErrorIs(t, err, errMaxRetriesExceeded)
+	require.Equal(t, int32(3), requests.Load())
+
+	// The conditions may lower the maximum, and retry other conditions
+	ctx := withRetryConditions(context.Background(), retryConditions{RetryOn5xx: 2, RetryOn4xx: 0})
+	require.ErrorIs(t, send(ctx, http.StatusServiceUnavailable), errMaxRetriesExceeded)
+	require.Equal(t, int32(2), requests.Load())
+	require.ErrorIs(t, send(ctx, http.StatusBadRequest), errMaxRetriesExceeded)
+	require.Equal(t, int32(3), requests.Load())
+}
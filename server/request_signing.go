@@ -0,0 +1,137 @@
+package server
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/flashbots/go-boost-utils/bls"
+)
+
+// Built-in schemes for signing relay requests with the operator key
+const (
+	RequestSigningSchemeBLS     = "bls"
+	RequestSigningSchemeEd25519 = "ed25519"
+)
+
+var (
+	errUnknownRequestSigningScheme = errors.New("unknown request signing scheme")
+	errInvalidRequestSigningKey    = errors.New("invalid request signing key")
+)
+
+// RequestSigner signs outgoing relay requests, adding the signature as headers. The body is nil for requests
+// without a body.
+type RequestSigner interface {
+	SignRequest(req *http.Request, body []byte) error
+}
+
+var (
+	requestSigningSchemesLock sync.RWMutex
+	requestSigningSchemes     = map[string]func(key []byte) (RequestSigner, error){
+		RequestSigningSchemeBLS:     newBLSRequestSigner,
+		RequestSigningSchemeEd25519: newEd25519RequestSigner,
+	}
+)
+
+// RegisterRequestSigningScheme adds a scheme for signing relay requests, for relays which expect another
+// scheme than the built-in ones
+func RegisterRequestSigningScheme(name string, newSigner func(key []byte) (RequestSigner, error)) {
+	requestSigningSchemesLock.Lock()
+	defer requestSigningSchemesLock.Unlock()
+	requestSigningSchemes[name] = newSigner
+}
+
+// newRequestSigner returns the request signer of the scheme for the hex encoded operator key
+func newRequestSigner(scheme, keyHex string) (RequestSigner, error) {
+	requestSigningSchemesLock.RLock()
+	newSigner, ok := requestSigningSchemes[scheme]
+	requestSigningSchemesLock.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", errUnknownRequestSigningScheme, scheme)
+	}
+	key, err := hexutil.Decode(keyHex)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errInvalidRequestSigningKey, err)
+	}
+	return newSigner(key)
+}
+
+// requestSignerKey is the context key of the request signer used by SendHTTPRequest
+type requestSignerKey struct{}
+
+// withRequestSigner returns a context in which SendHTTPRequest signs the requests with the signer
+func withRequestSigner(ctx context.Context, signer RequestSigner) context.Context {
+	if signer == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, requestSignerKey{}, signer)
+}
+
+// requestSigningMessage returns the canonical representation of a request which the built-in schemes sign:
+//
+//	<unix timestamp in ms>\n<method>\n<path and query>\n<hex sha256 of the body>
+func requestSigningMessage(timestamp string, req *http.Request, body []byte) []byte {
+	bodyHash := sha256.Sum256(body)
+	return []byte(timestamp + "\n" + req.Method + "\n" + req.URL.RequestURI() + "\n" + hexutil.Encode(bodyHash[:]))
+}
+
+// signRequest adds the signature of the canonical representation of the request, the timestamp and the
+// operator pubkey as headers
+func signRequest(req *http.Request, body, pubkey []byte, sign func(msg []byte) []byte) {
+	timestamp := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	req.Header.Set(HeaderKeyRequestSignatureTimestamp, timestamp)
+	req.Header.Set(HeaderKeyOperatorPubkey, hexutil.Encode(pubkey))
+	req.Header.Set(HeaderKeyRequestSignature, hexutil.Encode(sign(requestSigningMessage(timestamp, req, body))))
+}
+
+// blsRequestSigner signs requests with a BLS secret key
+type blsRequestSigner struct {
+	secretKey *bls.SecretKey
+	pubkey    []byte
+}
+
+func newBLSRequestSigner(key []byte) (RequestSigner, error) {
+	secretKey, err := bls.SecretKeyFromBytes(key)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errInvalidRequestSigningKey, err)
+	}
+	pubkey, err := bls.PublicKeyFromSecretKey(secretKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errInvalidRequestSigningKey, err)
+	}
+	return &blsRequestSigner{secretKey: secretKey, pubkey: bls.PublicKeyToBytes(pubkey)}, nil
+}
+
+func (s *blsRequestSigner) SignRequest(req *http.Request, body []byte) error {
+	signRequest(req, body, s.pubkey, func(msg []byte) []byte {
+		return bls.SignatureToBytes(bls.Sign(s.secretKey, msg))
+	})
+	return nil
+}
+
+// ed25519RequestSigner signs requests with an ed25519 key, given as its 32 byte seed
+type ed25519RequestSigner struct {
+	privateKey ed25519.PrivateKey
+}
+
+func newEd25519RequestSigner(key []byte) (RequestSigner, error) {
+	if len(key) != ed25519.SeedSize {
+		return nil, fmt.Errorf("%w: ed25519 seed must be %d bytes", errInvalidRequestSigningKey, ed25519.SeedSize)
+	}
+	return &ed25519RequestSigner{privateKey: ed25519.NewKeyFromSeed(key)}, nil
+}
+
+func (s *ed25519RequestSigner) SignRequest(req *http.Request, body []byte) error {
+	pubkey, _ := s.privateKey.Public().(ed25519.PublicKey)
+	signRequest(req, body, pubkey, func(msg []byte) []byte {
+		return ed25519.Sign(s.privateKey, msg)
+	})
+	return nil
+}
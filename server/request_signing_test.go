@@ -0,0 +1,140 @@
+package server
+
+import (
+	"context"
+	"crypto/ed25519"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	builderApiV1 "github.com/attestantio/go-builder-client/api/v1"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/flashbots/go-boost-utils/bls"
+	"github.com/flashbots/mev-boost/server/mock"
+	"github.com/flashbots/mev-boost/server/params"
+	"github.com/stretchr/testify/require"
+)
+
+// headerSigner is a custom request signing scheme which adds the key as a header
+type headerSigner struct {
+	key []byte
+}
+
+func (s *headerSigner) SignRequest(req *http.Request, _ []byte) error {
+	req.Header.Set(HeaderKeyRequestSignature, hexutil.Encode(s.key))
+	return nil
+}
+
+// signedRequest sends a POST request with the signer, and returns the request the server received and its body
+func signedRequest(t *testing.T, signer RequestSigner) (*http.Request, []byte) {
+	t.Helper()
+	var received *http.Request
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		received = req
+		var err error
+		body, err = io.ReadAll(req.Body)
+		require.NoError(t, err)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	ctx := withRequestSigner(context.Background(), signer)
+	_, err := SendHTTPRequest(ctx, *http.DefaultClient, http.MethodPost, server.URL+"/path?query=1", "", nil, map[string]string{"a": "b"}, nil)
+	require.NoError(t, err)
+	return received, body
+}
+
+func TestNewRequestSigner(t *testing.T) {
+	_, err := newRequestSigner("rsa", "0x01")
+	require.ErrorIs(t, err, errUnknownRequestSigningScheme)
+
+	_, err = newRequestSigner(RequestSigningSchemeEd25519, "not hex")
+	require.ErrorIs(t, err, errInvalidRequestSigningKey)
+
+	_, err = newRequestSigner(RequestSigningSchemeEd25519, "0x0102")
+	require.ErrorIs(t, err, errInvalidRequestSigningKey)
+
+	_, err = newRequestSigner(RequestSigningSchemeBLS, "0x0102")
+	require.ErrorIs(t, err, errInvalidRequestSigningKey)
+}
+
+func TestRequestSigningBLS(t *testing.T) {
+	secretKey, pubkey, err := bls.GenerateNewKeypair()
+	require.NoError(t, err)
+	signer, err := newRequestSigner(RequestSigningSchemeBLS, hexutil.Encode(bls.SecretKeyToBytes(secretKey)))
+	require.NoError(t, err)
+
+	req, body := signedRequest(t, signer)
+	require.Equal(t, hexutil.Encode(bls.PublicKeyToBytes(pubkey)), req.Header.Get(HeaderKeyOperatorPubkey))
+	signature, err := hexutil.Decode(req.Header.Get(HeaderKeyRequestSignature))
+	require.NoError(t, err)
+
+	msg := requestSigningMessage(req.Header.Get(HeaderKeyRequestSignatureTimestamp), req, body)
+	ok, err := bls.VerifySignatureBytes(msg, signature, bls.PublicKeyToBytes(pubkey))
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// The signature covers the body
+	ok, err = bls.VerifySignatureBytes(requestSigningMessage(req.Header.Get(HeaderKeyRequestSignatureTimestamp), req, []byte(`{"a":"c"}`)), signature, bls.PublicKeyToBytes(pubkey))
+	require.NoError(t, err)
+	require.False(t, ok)
+}
+
+func TestRequestSigningEd25519(t *testing.T) {
+	seed := make([]byte, ed25519.SeedSize)
+	seed[0] = 1
+	signer, err := newRequestSigner(RequestSigningSchemeEd25519, hexutil.Encode(seed))
+	require.NoError(t, err)
+
+	req, body := signedRequest(t, signer)
+	pubkey, err := hexutil.Decode(req.Header.Get(HeaderKeyOperatorPubkey))
+	require.NoError(t, err)
+	require.Equal(t, ed25519.NewKeyFromSeed(seed).Public(), ed25519.PublicKey(pubkey))
+	signature, err := hexutil.Decode(req.Header.Get(HeaderKeyRequestSignature))
+	require.NoError(t, err)
+
+	require.Equal(t, "/path?query=1", req.URL.RequestURI())
+	msg := requestSigningMessage(req.Header.Get(HeaderKeyRequestSignatureTimestamp), req, body)
+	require.True(t, ed25519.Verify(pubkey, msg, signature))
+}
+
+func TestRegisterRequestSigningScheme(t *testing.T) {
+	RegisterRequestSigningScheme("test-header", func(key []byte) (RequestSigner, error) {
+		return &headerSigner{key: key}, nil
+	})
+	signer, err := newRequestSigner("test-header", "0x0102")
+	require.NoError(t, err)
+
+	req, _ := signedRequest(t, signer)
+	require.Equal(t, "0x0102", req.Header.Get(HeaderKeyRequestSignature))
+}
+
+func TestRelayRequestSigning(t *testing.T) {
+	hash := mock.HexToHash("0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7")
+	pubkey := mock.HexToPubkey(
+		"0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249")
+
+	backend := newTestBackend(t, 1, time.Second)
+	backend.boost.requestSigner = &headerSigner{key: []byte{0x01}}
+
+	var getHeaderSignature, registerSignature string
+	backend.relays[0].OverrideHandleGetHeader(func(w http.ResponseWriter, req *http.Request) {
+		getHeaderSignature = req.Header.Get(HeaderKeyRequestSignature)
+		w.WriteHeader(http.StatusNoContent)
+	})
+	backend.relays[0].OverrideHandleRegisterValidator(func(w http.ResponseWriter, req *http.Request) {
+		registerSignature = req.Header.Get(HeaderKeyRequestSignature)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	rr := backend.request(t, http.MethodGet, getHeaderPath(1, hash, pubkey), nil)
+	require.Equal(t, http.StatusNoContent, rr.Code)
+	require.Equal(t, "0x01", getHeaderSignature)
+
+	rr = backend.request(t, http.MethodPost, params.PathRegisterValidator, []builderApiV1.SignedValidatorRegistration{testRegistration(pubkey)})
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, "0x01", registerSignature)
+}
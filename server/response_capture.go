@@ -0,0 +1,121 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/sirupsen/logrus"
+)
+
+var errInvalidPayloadArtifactsDir = errors.New("payload artifacts dir is not a directory")
+
+// writtenResponse identifies the exact bytes of a response written to the beacon node
+type writtenResponse struct {
+	SHA256 string `json:"response_sha256"`
+	Size   int64  `json:"response_size"`
+}
+
+// checkPayloadArtifactsDir returns an error if the payload artifacts can not be written to the directory
+func checkPayloadArtifactsDir(dir string) error {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%w: %s", errInvalidPayloadArtifactsDir, dir)
+	}
+	return nil
+}
+
+// payloadArtifactPath returns the file to which the getPayload response for the block is written
+func payloadArtifactPath(dir string, slot phase0.Slot, blockHash phase0.Hash32) string {
+	return filepath.Join(dir, fmt.Sprintf("%d-%s.json", slot, blockHash.String()))
+}
+
+// countingWriter counts the bytes written to it
+type countingWriter struct {
+	n int64
+}
+
+func (c *countingWriter) Write(b []byte) (int, error) {
+	c.n += int64(len(b))
+	return len(b), nil
+}
+
+// responseCapture hashes and counts the bytes of a response while they are written, and optionally copies
+// them to an artifact file, without holding the whole response in memory
+type responseCapture struct {
+	hash     hash.Hash
+	count    countingWriter
+	artifact *os.File
+	path     string
+}
+
+// newResponseCapture returns a capture of the response. With a path, the response is also written to the file.
+func newResponseCapture(path string, log *logrus.Entry) *responseCapture {
+	c := &responseCapture{hash: sha256.New(), path: path}
+	if path == "" {
+		return c
+	}
+	// Write to a temporary file first, so that a crash does not leave a truncated artifact behind
+	artifact, err := os.OpenFile(path+".tmp", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		log.WithError(err).Error("could not create the payload artifact file")
+		return c
+	}
+	c.artifact = artifact
+	return c
+}
+
+// writer returns a writer which writes to w and captures the written bytes
+func (c *responseCapture) writer(w io.Writer) io.Writer {
+	if c.artifact != nil {
+		return io.MultiWriter(w, c.hash, &c.count, c.artifact)
+	}
+	return io.MultiWriter(w, c.hash, &c.count)
+}
+
+// finish completes the artifact file, and returns the hash and size of the written response
+func (c *responseCapture) finish(log *logrus.Entry) writtenResponse {
+	if c.artifact != nil {
+		err := c.artifact.Close()
+		if err == nil {
+			err = os.Rename(c.path+".tmp", c.path)
+		}
+		if err != nil {
+			log.WithError(err).Error("could not write the payload artifact file")
+		}
+	}
+	return writtenResponse{SHA256: "0x" + hex.EncodeToString(c.hash.Sum(nil)), Size: c.count.n}
+}
+
+// respondPayloadCaptured responds with the payload, and returns the hash and size of the bytes written.
+// With a payload artifacts dir, the response is also persisted there.
+func (m *BoostService) respondPayloadCaptured(w http.ResponseWriter, log *logrus.Entry, payload *relayPayload, slot phase0.Slot, blockHash phase0.Hash32) writtenResponse {
+	path := ""
+	if m.payloadArtifactsDir != "" {
+		path = payloadArtifactPath(m.payloadArtifactsDir, slot, blockHash)
+	}
+	capture := newResponseCapture(path, log)
+	m.writePayload(w, capture.writer(w), log, payload, slot)
+	return capture.finish(log)
+}
+
+// recordWrittenPayload logs the getPayload response written to the beacon node, and stores it with the outcome
+func (m *BoostService) recordWrittenPayload(log *logrus.Entry, slot phase0.Slot, blockHash phase0.Hash32, response writtenResponse) {
+	log.WithFields(logrus.Fields{
+		"responseSHA256": response.SHA256,
+		"responseSize":   response.Size,
+	}).Info("payload written to the beacon node")
+	if err := m.payloadOutcomes.recordResponse(slot, blockHash, response); err != nil {
+		log.WithError(err).Error("could not persist the getPayload response")
+	}
+}
@@ -0,0 +1,53 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/flashbots/mev-boost/server/params"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetPayloadResponseCapture(t *testing.T) {
+	block, response := loadDenebBlock(t)
+	slot, blockHash := blindedBlockID(block)
+	dir := t.TempDir()
+	outcomes, err := newPayloadOutcomes(filepath.Join(t.TempDir(), "outcomes.json"))
+	require.NoError(t, err)
+
+	backend := newTestBackend(t, 1, time.Second)
+	backend.boost.payloadOutcomes = outcomes
+	backend.boost.payloadArtifactsDir = dir
+	backend.relays[0].GetPayloadResponse = response
+
+	rr := backend.request(t, http.MethodPost, params.PathGetPayload, block)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	sum := sha256.Sum256(rr.Body.Bytes())
+
+	// The outcome records the hash and size of the bytes the beacon node received
+	outcome, ok := outcomes.lookup(slot, blockHash)
+	require.True(t, ok)
+	require.Equal(t, &writtenResponse{SHA256: "0x" + hex.EncodeToString(sum[:]), Size: int64(rr.Body.Len())}, outcome.Response)
+
+	// The artifact holds the same bytes
+	artifact, err := os.ReadFile(payloadArtifactPath(dir, slot, blockHash))
+	require.NoError(t, err)
+	require.Equal(t, rr.Body.Bytes(), artifact)
+	_, err = os.Stat(payloadArtifactPath(dir, slot, blockHash) + ".tmp")
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestCheckPayloadArtifactsDir(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, checkPayloadArtifactsDir(dir))
+
+	file := filepath.Join(dir, "file")
+	require.NoError(t, os.WriteFile(file, nil, 0o600))
+	require.ErrorIs(t, checkPayloadArtifactsDir(file), errInvalidPayloadArtifactsDir)
+	require.ErrorIs(t, checkPayloadArtifactsDir(filepath.Join(dir, "missing")), os.ErrNotExist)
+}
@@ -0,0 +1,314 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+// Resources watched by the self monitor, in resource limits like goroutines=5000
+const (
+	ResourceGoroutines = "goroutines"
+	ResourceOpenFDs    = "fds"
+	ResourceHeapMB     = "heap_mb"
+)
+
+// DefaultSelfMonitorInterval is the default interval of the resource usage samples
+const DefaultSelfMonitorInterval = 10 * time.Second
+
+var (
+	errUnknownResource          = errors.New("unknown resource, must be goroutines, fds or heap_mb")
+	errInvalidResourceLimit     = errors.New("invalid resource limit, must be <resource>=<limit> with a positive limit")
+	errHardLimitBelowSoftLimit  = errors.New("hard resource limit must not be below the soft limit")
+	errResourceLimitsNeedSample = errors.New("resource limits need a self monitor interval above 0")
+	errSheddingLoad             = errors.New("mev-boost is over a hard resource limit, only getHeader and getPayload are served")
+
+	selfMonitorGoroutines = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "self_monitor_goroutines",
+		Help: "Number of goroutines at the last self monitor sample",
+	})
+	selfMonitorOpenFDs = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "self_monitor_open_fds",
+		Help: "Number of open file descriptors at the last self monitor sample, -1 if unknown on this platform",
+	})
+	selfMonitorHeapBytes = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "self_monitor_heap_bytes",
+		Help: "Bytes of allocated heap objects at the last self monitor sample",
+	})
+	selfMonitorLimitsExceeded = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "self_monitor_limits_exceeded_total",
+		Help: "Number of self monitor samples over a resource limit, by resource and level (soft or hard)",
+	}, []string{"resource", "level"})
+	selfMonitorActions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "self_monitor_actions_total",
+		Help: "Number of defensive actions over a hard resource limit, by action (close_idle_connections or reject_request)",
+	}, []string{"action"})
+)
+
+// ResourceLimits are limits of the resource usage of the process, zero limits are not checked
+type ResourceLimits struct {
+	Goroutines int
+	OpenFDs    int
+	HeapMB     uint64
+}
+
+// ParseResourceLimits parses entries of <resource>=<limit>, like goroutines=5000 or heap_mb=2048
+func ParseResourceLimits(entries []string) (ResourceLimits, error) {
+	var limits ResourceLimits
+	for _, entry := range entries {
+		if entry == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			return ResourceLimits{}, fmt.Errorf("%w: %s", errInvalidResourceLimit, entry)
+		}
+		limit, err := strconv.ParseUint(strings.TrimSpace(value), 10, 32)
+		if err != nil || limit == 0 {
+			return ResourceLimits{}, fmt.Errorf("%w: %s", errInvalidResourceLimit, entry)
+		}
+		switch strings.TrimSpace(name) {
+		case ResourceGoroutines:
+			limits.Goroutines = int(limit)
+		case ResourceOpenFDs:
+			limits.OpenFDs = int(limit)
+		case ResourceHeapMB:
+			limits.HeapMB = limit
+		default:
+			return ResourceLimits{}, fmt.Errorf("%w: %s", errUnknownResource, name)
+		}
+	}
+	return limits, nil
+}
+
+func (l ResourceLimits) isZero() bool {
+	return l == ResourceLimits{}
+}
+
+// validateResourceLimits checks that no hard limit is below its soft limit
+func validateResourceLimits(soft, hard ResourceLimits) error {
+	below := func(hard, soft uint64) bool { return hard > 0 && hard < soft }
+	if below(uint64(hard.Goroutines), uint64(soft.Goroutines)) || below(uint64(hard.OpenFDs), uint64(soft.OpenFDs)) || below(hard.HeapMB, soft.HeapMB) {
+		return errHardLimitBelowSoftLimit
+	}
+	return nil
+}
+
+// exceeded returns the resources of the sample over the limits
+func (l ResourceLimits) exceeded(sample resourceSample) []string {
+	var resources []string
+	if l.Goroutines > 0 && sample.Goroutines > l.Goroutines {
+		resources = append(resources, ResourceGoroutines)
+	}
+	if l.OpenFDs > 0 && sample.OpenFDs > l.OpenFDs {
+		resources = append(resources, ResourceOpenFDs)
+	}
+	if l.HeapMB > 0 && sample.HeapBytes > l.HeapMB<<20 {
+		resources = append(resources, ResourceHeapMB)
+	}
+	return resources
+}
+
+// resourceSample is the resource usage of the process at a time
+type resourceSample struct {
+	SampledAt  time.Time `json:"sampled_at"`
+	Goroutines int       `json:"goroutines"`
+	OpenFDs    int       `json:"open_fds"` // -1 if unknown on this platform
+	HeapBytes  uint64    `json:"heap_bytes"`
+}
+
+// resourceSampler returns the current resource usage, tests use fake samplers
+type resourceSampler func(now time.Time) resourceSample
+
+// sampleProcessResources samples the resource usage of this process. Open file descriptors are only known
+// where /proc/self/fd exists.
+func sampleProcessResources(now time.Time) resourceSample {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	openFDs := -1
+	if fds, err := os.ReadDir("/proc/self/fd"); err == nil {
+		openFDs = len(fds)
+	}
+	return resourceSample{
+		SampledAt:  now,
+		Goroutines: runtime.NumGoroutine(),
+		OpenFDs:    openFDs,
+		HeapBytes:  mem.HeapAlloc,
+	}
+}
+
+// selfMonitor samples the resource usage of the process, warns over the soft limits, and defends the process
+// over the hard limits by closing idle relay connections and shedding non-critical requests
+type selfMonitor struct {
+	sampler              resourceSampler
+	soft                 ResourceLimits
+	hard                 ResourceLimits
+	closeIdleConnections func()
+	log                  *logrus.Entry
+
+	shedding atomic.Bool
+
+	mu            sync.Mutex
+	last          resourceSample
+	peak          resourceSample
+	softExceeded  map[string]bool // resources over their soft limit at the last sample
+	hardSamples   uint64
+	rejected      uint64
+	idleConnClose uint64
+}
+
+func newSelfMonitor(sampler resourceSampler, soft, hard ResourceLimits, closeIdleConnections func(), log *logrus.Entry) *selfMonitor {
+	return &selfMonitor{
+		sampler:              sampler,
+		soft:                 soft,
+		hard:                 hard,
+		closeIdleConnections: closeIdleConnections,
+		log:                  log,
+		softExceeded:         make(map[string]bool),
+	}
+}
+
+// check takes a sample and acts on the limits it exceeds
+func (s *selfMonitor) check(now time.Time) resourceSample {
+	sample := s.sampler(now)
+	selfMonitorGoroutines.Set(float64(sample.Goroutines))
+	selfMonitorOpenFDs.Set(float64(sample.OpenFDs))
+	selfMonitorHeapBytes.Set(float64(sample.HeapBytes))
+	log := s.log.WithFields(logrus.Fields{
+		"goroutines": sample.Goroutines,
+		"openFDs":    sample.OpenFDs,
+		"heapMB":     sample.HeapBytes >> 20,
+	})
+
+	softExceeded := s.soft.exceeded(sample)
+	hardExceeded := s.hard.exceeded(sample)
+	s.mu.Lock()
+	s.last = sample
+	if sample.Goroutines > s.peak.Goroutines || sample.OpenFDs > s.peak.OpenFDs || sample.HeapBytes > s.peak.HeapBytes {
+		s.peak = resourceSample{
+			SampledAt:  now,
+			Goroutines: max(s.peak.Goroutines, sample.Goroutines),
+			OpenFDs:    max(s.peak.OpenFDs, sample.OpenFDs),
+			HeapBytes:  max(s.peak.HeapBytes, sample.HeapBytes),
+		}
+	}
+	current := make(map[string]bool, len(softExceeded))
+	for _, resource := range softExceeded {
+		selfMonitorLimitsExceeded.WithLabelValues(resource, "soft").Inc()
+		current[resource] = true
+		if !s.softExceeded[resource] {
+			log.WithField("resource", resource).Warn("resource usage is over its soft limit")
+		}
+	}
+	s.softExceeded = current
+	if len(hardExceeded) > 0 {
+		s.hardSamples++
+	}
+	s.mu.Unlock()
+
+	if len(hardExceeded) == 0 {
+		if s.shedding.CompareAndSwap(true, false) {
+			log.Info("resource usage is back under the hard limits, serving all requests again")
+		}
+		return sample
+	}
+	for _, resource := range hardExceeded {
+		selfMonitorLimitsExceeded.WithLabelValues(resource, "hard").Inc()
+	}
+	if !s.shedding.Swap(true) {
+		log.WithField("resources", hardExceeded).Error("resource usage is over a hard limit, closing idle relay connections and rejecting non-critical requests")
+	}
+	s.closeIdle()
+	return sample
+}
+
+// closeIdle closes the idle relay connections, which frees their file descriptors and goroutines
+func (s *selfMonitor) closeIdle() {
+	if s.closeIdleConnections == nil {
+		return
+	}
+	s.closeIdleConnections()
+	selfMonitorActions.WithLabelValues("close_idle_connections").Inc()
+	s.mu.Lock()
+	s.idleConnClose++
+	s.mu.Unlock()
+}
+
+// sheddingLoad returns true while the last sample is over a hard limit
+func (s *selfMonitor) sheddingLoad() bool {
+	return s != nil && s.shedding.Load()
+}
+
+func (s *selfMonitor) recordRejected() {
+	selfMonitorActions.WithLabelValues("reject_request").Inc()
+	s.mu.Lock()
+	s.rejected++
+	s.mu.Unlock()
+}
+
+// run samples the resource usage every interval until the context is done
+func (s *selfMonitor) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		s.check(time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// resourceUsage is the resource usage of the session in the session summary
+type resourceUsage struct {
+	Last                    resourceSample `json:"last"`
+	Peak                    resourceSample `json:"peak"`
+	HardLimitSamples        uint64         `json:"hard_limit_samples"`
+	IdleConnectionsClosed   uint64         `json:"idle_connections_closed"`
+	NonCriticalRequestsShed uint64         `json:"non_critical_requests_shed"`
+}
+
+// usage returns the resource usage of the session, nil without a self monitor
+func (s *selfMonitor) usage() *resourceUsage {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return &resourceUsage{
+		Last:                    s.last,
+		Peak:                    s.peak,
+		HardLimitSamples:        s.hardSamples,
+		IdleConnectionsClosed:   s.idleConnClose,
+		NonCriticalRequestsShed: s.rejected,
+	}
+}
+
+// nonCritical rejects the requests of the handler while the self monitor sheds load, the requests of the
+// proposal (status, getHeader and getPayload) are never rejected
+func (m *BoostService) nonCritical(next http.HandlerFunc) http.HandlerFunc {
+	if m.selfMonitor == nil {
+		return next
+	}
+	return func(w http.ResponseWriter, req *http.Request) {
+		if m.selfMonitor.sheddingLoad() {
+			m.selfMonitor.recordRejected()
+			w.Header().Set("Retry-After", strconv.Itoa(int(m.selfMonitorInterval.Seconds())+1))
+			m.respondError(w, http.StatusServiceUnavailable, errSheddingLoad.Error())
+			return
+		}
+		next(w, req)
+	}
+}
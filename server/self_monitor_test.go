@@ -0,0 +1,140 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	builderApiV1 "github.com/attestantio/go-builder-client/api/v1"
+	"github.com/flashbots/mev-boost/server/mock"
+	"github.com/flashbots/mev-boost/server/params"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSampler returns the sample it is set to
+type fakeSampler struct {
+	sample resourceSample
+}
+
+func (f *fakeSampler) sampleAt(now time.Time) resourceSample {
+	sample := f.sample
+	sample.SampledAt = now
+	return sample
+}
+
+func TestParseResourceLimits(t *testing.T) {
+	limits, err := ParseResourceLimits([]string{"goroutines=5000", " fds = 4096", "heap_mb=1024", ""})
+	require.NoError(t, err)
+	require.Equal(t, ResourceLimits{Goroutines: 5000, OpenFDs: 4096, HeapMB: 1024}, limits)
+
+	_, err = ParseResourceLimits([]string{"threads=10"})
+	require.ErrorIs(t, err, errUnknownResource)
+	_, err = ParseResourceLimits([]string{"fds=0"})
+	require.ErrorIs(t, err, errInvalidResourceLimit)
+	_, err = ParseResourceLimits([]string{"fds"})
+	require.ErrorIs(t, err, errInvalidResourceLimit)
+
+	require.ErrorIs(t, validateResourceLimits(ResourceLimits{OpenFDs: 100}, ResourceLimits{OpenFDs: 50}), errHardLimitBelowSoftLimit)
+	require.NoError(t, validateResourceLimits(ResourceLimits{OpenFDs: 100}, ResourceLimits{Goroutines: 50}))
+}
+
+func TestSelfMonitorSoftLimits(t *testing.T) {
+	sampler := &fakeSampler{sample: resourceSample{Goroutines: 100, OpenFDs: 10, HeapBytes: 1 << 20}}
+	closed := 0
+	monitor := newSelfMonitor(sampler.sampleAt, ResourceLimits{Goroutines: 500, HeapMB: 64}, ResourceLimits{}, func() { closed++ }, mock.TestLog)
+	before := testutil.ToFloat64(selfMonitorLimitsExceeded.WithLabelValues(ResourceGoroutines, "soft"))
+
+	monitor.check(time.Now())
+	require.InDelta(t, 100, testutil.ToFloat64(selfMonitorGoroutines), 0)
+	require.InDelta(t, 10, testutil.ToFloat64(selfMonitorOpenFDs), 0)
+
+	// Over the soft limit, the usage is only reported
+	sampler.sample.Goroutines = 600
+	monitor.check(time.Now())
+	require.InDelta(t, 1, testutil.ToFloat64(selfMonitorLimitsExceeded.WithLabelValues(ResourceGoroutines, "soft"))-before, 0)
+	require.False(t, monitor.sheddingLoad())
+	require.Zero(t, closed)
+
+	sampler.sample.Goroutines = 200
+	monitor.check(time.Now())
+	usage := monitor.usage()
+	require.Equal(t, 200, usage.Last.Goroutines)
+	require.Equal(t, 600, usage.Peak.Goroutines)
+	require.Zero(t, usage.HardLimitSamples)
+}
+
+func TestSelfMonitorHardLimits(t *testing.T) {
+	sampler := &fakeSampler{sample: resourceSample{Goroutines: 100, OpenFDs: 2000}}
+	closed := 0
+	monitor := newSelfMonitor(sampler.sampleAt, ResourceLimits{}, ResourceLimits{OpenFDs: 1000}, func() { closed++ }, mock.TestLog)
+
+	// Idle connections are closed at each sample over the hard limit
+	monitor.check(time.Now())
+	monitor.check(time.Now())
+	require.True(t, monitor.sheddingLoad())
+	require.Equal(t, 2, closed)
+
+	sampler.sample.OpenFDs = 500
+	monitor.check(time.Now())
+	require.False(t, monitor.sheddingLoad())
+	require.Equal(t, 2, closed)
+
+	usage := monitor.usage()
+	require.Equal(t, uint64(2), usage.HardLimitSamples)
+	require.Equal(t, uint64(2), usage.IdleConnectionsClosed)
+	require.Equal(t, 2000, usage.Peak.OpenFDs)
+
+	// A nil monitor never sheds load, and has no usage
+	var none *selfMonitor
+	require.False(t, none.sheddingLoad())
+	require.Nil(t, none.usage())
+}
+
+func TestSelfMonitorShedsNonCriticalRequests(t *testing.T) {
+	pubkey := mock.HexToPubkey(feeRecipientTestPubkey)
+	hash := mock.HexToHash("0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7")
+	backend := newTestBackend(t, 1, time.Second)
+	backend.boost.debugEndpoints = true
+	sampler := &fakeSampler{sample: resourceSample{Goroutines: 20000}}
+	backend.boost.selfMonitor = newSelfMonitor(sampler.sampleAt, ResourceLimits{}, ResourceLimits{Goroutines: 10000}, nil, mock.TestLog)
+	backend.boost.selfMonitorInterval = time.Second
+	backend.boost.selfMonitor.check(time.Now())
+	before := testutil.ToFloat64(selfMonitorActions.WithLabelValues("reject_request"))
+
+	// Registrations and debug requests are rejected
+	rr := backend.request(t, http.MethodPost, params.PathRegisterValidator, []builderApiV1.SignedValidatorRegistration{testRegistration(pubkey)})
+	require.Equal(t, http.StatusServiceUnavailable, rr.Code, rr.Body.String())
+	require.Equal(t, "2", rr.Header().Get("Retry-After"))
+	require.Zero(t, backend.relays[0].GetRequestCount(params.PathRegisterValidator))
+	rr = backend.request(t, http.MethodGet, params.PathDebugRelays, nil)
+	require.Equal(t, http.StatusServiceUnavailable, rr.Code, rr.Body.String())
+	require.InDelta(t, 2, testutil.ToFloat64(selfMonitorActions.WithLabelValues("reject_request"))-before, 0)
+
+	// The requests of the proposal are still served
+	rr = backend.request(t, http.MethodGet, params.PathStatus, nil)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	rr = backend.request(t, http.MethodGet, getHeaderPath(1, hash, pubkey), nil)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	// The session summary has the usage
+	summary := backend.boost.sessionSummary(time.Now())
+	require.NotNil(t, summary.Resources)
+	require.Equal(t, uint64(2), summary.Resources.NonCriticalRequestsShed)
+	require.Equal(t, 20000, summary.Resources.Peak.Goroutines)
+
+	// Under the limit again, registrations are forwarded
+	sampler.sample.Goroutines = 100
+	backend.boost.selfMonitor.check(time.Now())
+	rr = backend.request(t, http.MethodPost, params.PathRegisterValidator, []builderApiV1.SignedValidatorRegistration{testRegistration(pubkey)})
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+}
+
+func TestSampleProcessResources(t *testing.T) {
+	now := time.Now()
+	sample := sampleProcessResources(now)
+	require.Equal(t, now, sample.SampledAt)
+	require.Positive(t, sample.Goroutines)
+	require.Positive(t, sample.HeapBytes)
+	require.NotZero(t, sample.OpenFDs)
+}
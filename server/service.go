@@ -6,39 +6,67 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
+	"math/rand/v2"
 	"net/http"
 	"net/url"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
-	builderApi "github.com/attestantio/go-builder-client/api"
 	builderApiV1 "github.com/attestantio/go-builder-client/api/v1"
 	eth2ApiV1Bellatrix "github.com/attestantio/go-eth2-client/api/v1/bellatrix"
 	eth2ApiV1Capella "github.com/attestantio/go-eth2-client/api/v1/capella"
 	eth2ApiV1Deneb "github.com/attestantio/go-eth2-client/api/v1/deneb"
 	eth2ApiV1Electra "github.com/attestantio/go-eth2-client/api/v1/electra"
+	"github.com/attestantio/go-eth2-client/spec"
+	"github.com/attestantio/go-eth2-client/spec/bellatrix"
 	"github.com/attestantio/go-eth2-client/spec/phase0"
-	"github.com/flashbots/go-boost-utils/ssz"
+	"github.com/flashbots/go-boost-utils/bls"
 	"github.com/flashbots/go-utils/httplogger"
 	"github.com/flashbots/mev-boost/config"
 	"github.com/flashbots/mev-boost/server/params"
 	"github.com/flashbots/mev-boost/server/types"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
+	"github.com/holiman/uint256"
 	"github.com/sirupsen/logrus"
 )
 
 var (
-	errNoRelays                  = errors.New("no relays")
-	errInvalidSlot               = errors.New("invalid slot")
-	errInvalidHash               = errors.New("invalid hash")
-	errInvalidPubkey             = errors.New("invalid pubkey")
-	errNoSuccessfulRelayResponse = errors.New("no successful relay response")
-	errServerAlreadyRunning      = errors.New("server already running")
+	errNoRelays                    = errors.New("no relays")
+	errInvalidSlot                 = errors.New("invalid slot")
+	errInvalidHash                 = errors.New("invalid hash")
+	errInvalidPubkey               = errors.New("invalid pubkey")
+	errNoSuccessfulRelayResponse   = errors.New("no successful relay response")
+	errServerAlreadyRunning        = errors.New("server already running")
+	errWaitingForRelayCheck        = errors.New("waiting for initial relay check")
+	errInvalidPriorityTolerance    = errors.New("relay priority tolerance must be between 0 and 100 percent")
+	errInvalidFailedDeliveryPolicy = errors.New("failed delivery policy must be deprioritize or reject")
+	errRegistrationBatchTooLarge   = errors.New("registration batch too large")
+	errInvalidValidationLevel      = errors.New("validation level must be none, basic or strict")
+	errInvalidBidTieBreak          = errors.New("bid tie-break must be relay-position, reliability or random")
+	errInvalidConflictingBids      = errors.New("conflicting bids policy must be lowest or drop")
+	errInvalidWithholdingPenalty   = errors.New("withholding penalty policy must be deprioritize or exclude")
+	errInvalidMinActiveRelays      = errors.New("minimum active relays must be between 0 and the number of relays")
+	errInvalidRegistrationJitter   = errors.New("registration jitter must be between 0 and half of the registerValidator timeout")
+)
+
+const (
+	// DefaultMaxRegistrationBatchSize is the default maximum number of registrations in a single request
+	DefaultMaxRegistrationBatchSize = 50_000
+
+	// DefaultMaxPayloadResponseSize is the default maximum size of a relay getPayload response in bytes
+	DefaultMaxPayloadResponseSize = 64 << 20
+
+	// DefaultMaxPayloadRequestSize is the default maximum size of a getPayload request body in bytes. The largest
+	// blinded block, an electra block with the maximum number of deposit requests, is about 5 MB of JSON.
+	DefaultMaxPayloadRequestSize = 10 << 20
+
+	// maxRegistrationJSONSize is an upper bound for the JSON size of a single signed registration
+	maxRegistrationJSONSize = 1024
 )
 
 var (
@@ -67,73 +95,736 @@ type BoostServiceOpts struct {
 	RelayCheck            bool
 	RelayMinBid           types.U256Str
 
+	// SecondsPerSlot is the slot time of the network, the SLOT_SEC environment variable or 12 if zero
+	SecondsPerSlot uint64
+	// CheckBidTimestamp rejects bids whose execution payload timestamp is not the start of the requested slot,
+	// unless the genesis time is unknown
+	CheckBidTimestamp bool
+
+	// RelayPriorityTolerancePct is the percentage by which a bid from a higher priority relay
+	// may be lower than the most profitable bid and still win
+	RelayPriorityTolerancePct float64
+	// RetryTimeoutFraction is the fraction of the getHeader budget used for retries of an auction in the same
+	// slot, which are also served the bid of the first auction if it is more valuable. Zero uses the full budget.
+	RetryTimeoutFraction float64
+	// MinBidOverLocalPct is the percentage by which bids must exceed the local block value supplied by the beacon
+	// node, for relays without their own ?min-over-local-pct=N
+	MinBidOverLocalPct float64
+	// RelayOrderHeader lets getHeader requests prioritise relays with the relay order header
+	RelayOrderHeader bool
+	// ForwardHeaders are the names of the beacon node request headers which are copied into
+	// the getHeader and getPayload requests to relays
+	ForwardHeaders []string
+	// BidFilters run after the built-in bid filters for every relay bid in getHeader
+	BidFilters        []BidFilter
+	TimingHeader      bool
+	CompatShims       bool
+	StrictRelaySchema bool
+	MetricsAddr       string
+	DebugEndpoints    bool
+
+	// StatsdAddr additionally sends the key metrics to this StatsD server, prefixed with StatsdPrefix.
+	// StatsdDialect is either statsd (default) or dogstatsd, which adds tags.
+	StatsdAddr    string
+	StatsdPrefix  string
+	StatsdDialect string
+
+	// NextForkVersionHex additionally verifies relay bids signed under the builder domain of the next fork
+	// version, which is preferred from NextForkEpoch. ExtraSigningForkVersions are always accepted as well.
+	NextForkVersionHex       string
+	NextForkEpoch            uint64
+	ExtraSigningForkVersions []string
+
+	// ForkEpochs are the activation epochs of the forks, used to find the fork of a slot
+	ForkEpochs map[spec.DataVersion]uint64
+
+	// BuilderAllowlist restricts bids to the ones signed by these builder pubkeys, if not empty
+	BuilderAllowlist []phase0.BLSPubKey
+
+	// BuilderDenylist ignores bids signed by these builder pubkeys, even if they are on the allowlist
+	BuilderDenylist []phase0.BLSPubKey
+
+	// FeeRecipientAudit keeps the recent fee recipients of each validator, and warns when a validator registers
+	// a different fee recipient
+	FeeRecipientAudit bool
+
+	// ApprovedFeeRecipients stops forwarding registrations whose fee recipient is not one of these, if not empty
+	ApprovedFeeRecipients []bellatrix.ExecutionAddress
+
+	// VerifyRegistrationSignatures checks the signature of each validator registration before forwarding it, and
+	// drops the registrations with an invalid signature. The check is CPU intensive for large batches.
+	VerifyRegistrationSignatures bool
+
+	// StrictPubkeyCheck rejects getHeader requests for pubkeys which are not valid BLS public keys
+	StrictPubkeyCheck bool
+
+	// RequestSigningKey signs the getHeader and registerValidator requests to relays with this hex encoded
+	// operator key, for relays which authenticate operators by request signatures. RequestSigningScheme is
+	// bls (default), ed25519 or a scheme added with RegisterRequestSigningScheme.
+	RequestSigningKey    string
+	RequestSigningScheme string
+
+	// ConsensusVersionShadow compares the Eth-Consensus-Version header of getPayload requests with the fork
+	// the body decodes as, and logs and counts disagreements
+	ConsensusVersionShadow bool
+	// InferConsensusVersion decodes relay responses without a version nor an Eth-Consensus-Version header as the
+	// fork of the slot in ForkEpochs
+	InferConsensusVersion bool
+
+	// AdminEndpoints serves the endpoints which change the state at runtime. AdminToken, if set, is
+	// required in the admin token header for these and the debug endpoints.
+	AdminEndpoints bool
+	AdminToken     string
+
+	// CORSAllowedOrigins allows browsers on these origins to call the status, health and debug endpoints.
+	// Origins are matched exactly or as wildcard subdomain patterns, like https://*.example.com.
+	// CORSAllowedHeaders and CORSMaxAge are returned in preflight responses.
+	CORSAllowedOrigins []string
+	CORSAllowedHeaders []string
+	CORSMaxAge         time.Duration
+
+	// BidTieBreak selects which relay's copy is used when several relays deliver the same bid,
+	// either relay-position (default), reliability or random
+	BidTieBreak string
+
+	// ConflictingBids decides what happens with bids of several relays for the same block hash which do not agree
+	// on the value or the builder pubkey: lowest (default) keeps the bids with the lowest value, drop ignores them all
+	ConflictingBids string
+
+	// ServeCachedBid serves the most recent bid for the same slot, parent hash and proposer when all relays fail
+	// in getHeader, if it is not older than CachedBidMaxAge. Zero allows bids until the end of their slot.
+	ServeCachedBid  bool
+	CachedBidMaxAge time.Duration
+
+	// RelayFailurePolicy decides what happens with getHeader requests for which every relay failed early in
+	// the slot: no-bid (default), retry the auction once within the remaining budget, or retry-after to respond
+	// with 502 and a Retry-After header
+	RelayFailurePolicy string
+
+	// FailedDeliveryPolicy decides what happens with bids for a block hash for which the same
+	// relay previously failed to deliver the payload, either deprioritize or reject
+	FailedDeliveryPolicy string
+
+	// WithholdingPenalty is the cooldown of a relay after it withheld a payload, zero disables it. During the
+	// cooldown WithholdingPenaltyPolicy decides what happens with its bids, either deprioritize (default) or exclude.
+	WithholdingPenalty       time.Duration
+	WithholdingPenaltyPolicy string
+	// MinActiveRelays refuses getHeader and getPayload requests with 503, and reports unhealthy on the status
+	// endpoint, while fewer relays are active. Relays are inactive while their bids are excluded after withholding
+	// a payload. Zero disables the check.
+	MinActiveRelays int
+	// MinBiddingRelays is the number of relays which must send an acceptable bid in an auction, identical bids of
+	// several relays counting once per relay. With fewer bidding relays MinBiddingRelaysPolicy decides what
+	// happens, either warn (default) which serves the bid as low confidence, or strict which responds without a
+	// bid. Zero disables the check.
+	MinBiddingRelays       int
+	MinBiddingRelaysPolicy string
+	// WithholdingEventsRetention is how long withholding events are listed by the withholding debug endpoint
+	WithholdingEventsRetention time.Duration
+
+	// ValidationLevel controls how much of the relay bids and payloads is verified, defaults to strict
+	ValidationLevel ValidationLevel
+	// RelayContentTypeCheck decides what happens with relay responses which are neither JSON nor SSZ, see
+	// ContentTypeCheckLenient (default), ContentTypeCheckStrict and ContentTypeCheckOff
+	RelayContentTypeCheck string
+
+	// SlowRelayThreshold limits logging of getHeader relay responses to errors and responses
+	// slower than the threshold, zero logs all responses
+	SlowRelayThreshold time.Duration
+
+	// APIAuthToken requires requests to authenticate with this token, see apiAuth. APIAuthTokenFile
+	// reads the token from a file instead, which is read again on SIGHUP.
+	APIAuthToken     string
+	APIAuthTokenFile string
+	// AllowedClientCIDRs restricts registerValidator, getHeader and getPayload to clients in these CIDRs or IP
+	// addresses, all clients are allowed if empty. The client of a request from one of the TrustedProxies is the
+	// rightmost address of the X-Forwarded-For header which is not a trusted proxy.
+	AllowedClientCIDRs []string
+	TrustedProxies     []string
+
+	// ChaosConfig injects the faults configured in this JSON file into relay requests, for failure testing.
+	// It is refused on mainnet unless ChaosAllowMainnet is set.
+	ChaosConfig       string
+	ChaosAllowMainnet bool
+	// ChaosDelay delays the getHeader and getPayload responses to the beacon node, for testing the fallback
+	// timing of beacon nodes. It is refused unless ChaosDelayEnabled is set, and on mainnet like ChaosConfig.
+	ChaosDelay        time.Duration
+	ChaosDelayEnabled bool
+
+	// PayloadOutcomesFile persists the outcomes of recent getPayload requests, to recognize duplicate
+	// submissions of a block after a restart
+	PayloadOutcomesFile string
+	// BidMetadataFile persists the relays of recently served bids, to request the payload of a block submitted
+	// after a restart from the relays of its bid first
+	BidMetadataFile string
+	// AlertWebhookURL receives a JSON POST, and AlertCommand is run with the same JSON on stdin, when a payload is
+	// withheld, no relay passes AlertUnhealthyChecks consecutive relay checks, no relay bids in AlertNoBidSlots
+	// consecutive proposer slots, or relay bids do not verify. Each trigger alerts at most once per AlertMinInterval.
+	AlertWebhookURL      string
+	AlertCommand         string
+	AlertMinInterval     time.Duration
+	AlertUnhealthyChecks int
+	AlertNoBidSlots      int
+
+	// PayloadArtifactsDir persists the exact getPayload responses written to the beacon node to this directory,
+	// as <slot>-<block hash>.json
+	PayloadArtifactsDir string
+
+	// TenantsFile is a JSON file mapping validator pubkeys or pubkey prefixes to tenant labels,
+	// which partition the per tenant metrics. It is reloaded when modified.
+	TenantsFile string
+
+	// ExecutionRPCURL enables auditing the payment the proposer received for delivered payloads
+	ExecutionRPCURL string
+
+	// RelayCheckReadiness makes /status report unavailable until the initial relay check
+	// has finished, which is bounded by RelayCheckStartupTimeout
+	RelayCheckReadiness      bool
+	RelayCheckStartupTimeout time.Duration
+	// PrewarmRelayConnections sends a status request to each relay at startup, and then every half of the idle
+	// connection timeout, so that the first getHeader request does not wait for a TLS handshake
+	PrewarmRelayConnections bool
+
+	// SelfMonitorInterval samples the goroutines, open file descriptors and heap of the process, 0 disables it.
+	// Usage over ResourceSoftLimits is logged, usage over ResourceHardLimits also closes idle relay connections
+	// and rejects non-critical requests until it is back under the limits.
+	SelfMonitorInterval time.Duration
+	ResourceSoftLimits  ResourceLimits
+	ResourceHardLimits  ResourceLimits
+
 	RequestTimeoutGetHeader  time.Duration
 	RequestTimeoutGetPayload time.Duration
 	RequestTimeoutRegVal     time.Duration
-	RequestMaxRetries        int
+	// RequestTimeoutRelayMonitor is the timeout of the registrations sent to the relay monitors, 0 for
+	// RequestTimeoutRegVal
+	RequestTimeoutRelayMonitor time.Duration
+	RequestMaxRetries          int
+	// RequestRetryOn are the conditions of failed getPayload requests which are retried, as <condition> or
+	// <condition>=<max retries>, see DefaultRetryConditions for the default
+	RequestRetryOn []string
+
+	// GetHeaderTimeoutByFork overrides RequestTimeoutGetHeader for the slots of a fork, which must have
+	// an activation epoch in ForkEpochs
+	GetHeaderTimeoutByFork map[spec.DataVersion]time.Duration
+
+	// RelayDialTimeout and RelayTLSHandshakeTimeout bound connecting to relays, so unreachable relays fail
+	// fast while the request timeouts leave time to read large responses. Zero uses the Go defaults.
+	RelayDialTimeout         time.Duration
+	RelayTLSHandshakeTimeout time.Duration
+
+	// RelayTLSMinVersion is the minimum TLS version of connections to relays, "1.2" (default) or "1.3"
+	RelayTLSMinVersion string
+
+	// RelayDNSCacheTTL reuses the resolved addresses of relay hosts for this long, zero resolves them
+	// for every new connection
+	RelayDNSCacheTTL time.Duration
+
+	// RelayLocalAddr is the source IP address, or network interface, of connections to relays.
+	// Empty uses the default source address of the route to the relay.
+	RelayLocalAddr string
+
+	// FollowRelayRedirectsSameHost follows a single redirect of a relay to the same host and scheme,
+	// other redirects are never followed
+	FollowRelayRedirectsSameHost bool
+
+	// GetHeaderSlotDeadline is the time into the slot after which getHeader does not wait for relays anymore,
+	// zero only applies RequestTimeoutGetHeader
+	GetHeaderSlotDeadline time.Duration
+
+	// MaxRegistrationBatchSize is the maximum number of registrations accepted in a single request,
+	// zero uses DefaultMaxRegistrationBatchSize
+	MaxRegistrationBatchSize int
+
+	// RegistrationJitter delays each relay registerValidator request by a random duration up to this maximum,
+	// to spread the load of registrations at epoch boundaries. It must be below half of RequestTimeoutRegVal.
+	RegistrationJitter time.Duration
+
+	// MaxPayloadResponseSize is the maximum size of a relay getPayload response in bytes, larger responses are
+	// ignored without reading them completely. Zero uses DefaultMaxPayloadResponseSize.
+	MaxPayloadResponseSize int64
+
+	// MaxPayloadRequestSize is the maximum size of a getPayload request body in bytes, larger requests are
+	// rejected with 413 without reading them completely. Zero uses DefaultMaxPayloadRequestSize.
+	MaxPayloadRequestSize int64
+
+	// SessionSummaryFile additionally writes the session summary logged by Stop to this file as JSON
+	SessionSummaryFile string
+
+	// MaxCachedSlots is the maximum number of distinct slots of which bids are cached, the bids of the
+	// oldest slots are evicted first. Zero only evicts bids by age.
+	MaxCachedSlots int
+
+	// BlockNumberSpread is the spread of the block numbers of the bids of an auction above which the relays
+	// bidding on another block number than most relays are reported, zero uses DefaultBlockNumberSpread
+	BlockNumberSpread uint64
 }
 
 // BoostService - the mev-boost service
 type BoostService struct {
 	listenAddr    string
 	relays        []types.RelayEntry
-	relayMonitors []*url.URL
+	relayMonitors []types.RelayMonitorEntry
 	log           *logrus.Entry
 	srv           *http.Server
+	srvLock       sync.Mutex
 	relayCheck    bool
 	relayMinBid   types.U256Str
 	genesisTime   uint64
+	timingHeader  bool
+	metricsAddr   string
+	metricsSrv    *http.Server
+
+	compatShimsEnabled     bool
+	compatShimLog          compatShimLog
+	consensusVersionShadow bool
+	inferConsensusVersion  bool
+
+	apiAuth         *apiAuth
+	cors            *corsPolicy
+	clientAllowlist *clientAllowlist
+
+	requestSigner     RequestSigner
+	strictPubkeyCheck bool
+
+	session            *sessionStats
+	sessionSummaryFile string
+
+	strictRelaySchema         bool
+	relayPriorityToleranceBps uint64
+	minBidOverLocalPct        float64
+	retryTimeoutFraction      float64
+	relayOrderHeader          bool
+	forwardHeaders            []string
+	debugEndpoints            bool
+	adminEndpoints            bool
+	adminToken                string
+	slowRelayThreshold        time.Duration
+
+	failedDeliveries      *failedDeliveries
+	bidMemo               *bidMemo
+	topBidStreams         map[string]*topBidStream
+	stopTopBidStreams     context.CancelFunc
+	registrationCoverage  *registrationCoverage
+	feeRecipients         *registeredFeeRecipients
+	approvedFeeRecipients map[bellatrix.ExecutionAddress]bool
+	getHeaderCallers      *getHeaderCallers
+	auctionedHeaders      *auctionedHeaders
+	failedDeliveryPolicy  string
+	relayFailurePolicy    string
+	payloadOutcomes       *payloadOutcomes
+	bidMetadata           *bidMetadataStore
+	payloadArtifactsDir   string
+	deliveredPayloads     *deliveredPayloads
+
+	withholdingPenalties     *withholdingPenalties
+	withholdingEvents        *withholdingEvents
+	bidArrivals              *bidArrivals
+	withholdingPenaltyPolicy string
+	relayAdvisories          *relayAdvisories
+	contentTypeCheck         string
 
-	builderSigningDomain phase0.Domain
-	httpClientGetHeader  http.Client
-	httpClientGetPayload http.Client
-	httpClientRegVal     http.Client
-	requestMaxRetries    int
+	validationLevel ValidationLevel
+	serveCachedBid  bool
+	cachedBidMaxAge time.Duration
+	bidTieBreak     string
+	conflictingBids string
 
-	bids     map[string]bidResp // keeping track of bids, to log the originating relay on withholding
-	bidsLock sync.Mutex
+	builderAllowlist map[phase0.BLSPubKey]bool
+	builderDenylist  *builderDenylist
+	bidFilters       []BidFilter
+
+	paymentAuditor *paymentAuditor
+	tenants        *tenantMap
+	statsd         *statsdExporter
+	alerts         *alerter
+	chaos          *chaosConfig
+
+	chaosResponseDelay time.Duration
+
+	relayCheckReadiness      bool
+	relayCheckStartupTimeout time.Duration
+	waitingForRelayCheck     atomic.Bool
+
+	relayConnectionPrewarm    bool
+	connectionPrewarmInterval time.Duration
+	stopConnectionPrewarm     context.CancelFunc
+
+	selfMonitor         *selfMonitor
+	selfMonitorInterval time.Duration
+	stopSelfMonitor     context.CancelFunc
+
+	drain payloadDrain
+
+	minActiveRelays    int
+	relayFloorBreached atomic.Bool
+
+	minBiddingRelays       int
+	minBiddingRelaysPolicy string
+
+	secondsPerSlot    uint64
+	checkBidTimestamp bool
+
+	forkSchedule          forkSchedule
+	getHeaderForkTimeouts map[spec.DataVersion]time.Duration
+
+	signingDomains        *signingDomains
+	httpClientGetHeader   http.Client
+	getHeaderSlotDeadline time.Duration
+	httpClientGetPayload  http.Client
+	httpClientRegVal      http.Client
+	httpClientMonitor     http.Client
+	requestMaxRetries     int
+	requestRetryOn        retryConditions
+
+	maxRegistrationBatchSize int
+	registrationJitter       time.Duration
+	registrationDomain       *phase0.Domain // verifies registration signatures if set
+	maxPayloadResponseSize   int64
+	maxPayloadRequestSize    int64
+
+	bids           bidStore // keeping track of bids, to log the originating relay on withholding
+	maxCachedSlots int
+
+	blockNumberSpread uint64
 
 	slotUID     *slotUID
 	slotUIDLock sync.Mutex
+
+	// reloadLock is held for reading while requests are handled, and for writing while relays and policies are reloaded
+	reloadLock sync.RWMutex
+}
+
+// setPolicyDefaults sets the default of each policy which is not set in the options
+func setPolicyDefaults(opts *BoostServiceOpts) {
+	if opts.FailedDeliveryPolicy == "" {
+		opts.FailedDeliveryPolicy = FailedDeliveryPolicyDeprioritize
+	}
+	if opts.RelayFailurePolicy == "" {
+		opts.RelayFailurePolicy = RelayFailurePolicyNoBid
+	}
+	if opts.WithholdingPenaltyPolicy == "" {
+		opts.WithholdingPenaltyPolicy = WithholdingPenaltyDeprioritize
+	}
+	if opts.BidTieBreak == "" {
+		opts.BidTieBreak = BidTieBreakRelayPosition
+	}
+	if opts.ConflictingBids == "" {
+		opts.ConflictingBids = ConflictingBidsLowest
+	}
+	if opts.RelayContentTypeCheck == "" {
+		opts.RelayContentTypeCheck = ContentTypeCheckLenient
+	}
+	if opts.MinBiddingRelaysPolicy == "" {
+		opts.MinBiddingRelaysPolicy = MinBiddingRelaysWarn
+	}
 }
 
 // NewBoostService created a new BoostService
 func NewBoostService(opts BoostServiceOpts) (*BoostService, error) {
-	if len(opts.Relays) == 0 {
-		return nil, errNoRelays
+	if errs := validateConfig(opts, false); len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	forwardHeaders, err := parseForwardHeaders(opts.ForwardHeaders)
+	if err != nil {
+		return nil, err
+	}
+	retryOn, err := parseRetryConditions(opts.RequestRetryOn)
+	if err != nil {
+		return nil, err
+	}
+	relayMonitors, err := relayMonitorEntries(opts.RelayMonitors)
+	if err != nil {
+		return nil, err
+	}
+	monitorTimeout := opts.RequestTimeoutRelayMonitor
+	if monitorTimeout == 0 {
+		monitorTimeout = opts.RequestTimeoutRegVal
+	}
+	setPolicyDefaults(&opts)
+	if opts.ValidationLevel == "" {
+		opts.ValidationLevel = ValidationLevelStrict
+	}
+
+	signingDomains, err := newSigningDomains(opts.GenesisTime, opts.SecondsPerSlot, opts.GenesisForkVersionHex, opts.NextForkVersionHex, opts.NextForkEpoch, opts.ExtraSigningForkVersions)
+	if err != nil {
+		return nil, err
+	}
+	registrationDomain, err := newRegistrationDomain(opts.VerifyRegistrationSignatures, opts.GenesisForkVersionHex)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.MaxRegistrationBatchSize <= 0 {
+		opts.MaxRegistrationBatchSize = DefaultMaxRegistrationBatchSize
+	}
+	if opts.MaxPayloadResponseSize <= 0 {
+		opts.MaxPayloadResponseSize = DefaultMaxPayloadResponseSize
+	}
+	if opts.MaxPayloadRequestSize <= 0 {
+		opts.MaxPayloadRequestSize = DefaultMaxPayloadRequestSize
+	}
+
+	var auditor *paymentAuditor
+	if opts.ExecutionRPCURL != "" {
+		auditor = newPaymentAuditor(opts.ExecutionRPCURL, secondsPerSlotOrDefault(opts.SecondsPerSlot))
+	}
+
+	var builderAllowlist map[phase0.BLSPubKey]bool
+	if len(opts.BuilderAllowlist) > 0 {
+		builderAllowlist = make(map[phase0.BLSPubKey]bool, len(opts.BuilderAllowlist))
+		for _, pubkey := range opts.BuilderAllowlist {
+			builderAllowlist[pubkey] = true
+		}
+	}
+
+	var approvedFeeRecipients map[bellatrix.ExecutionAddress]bool
+	if len(opts.ApprovedFeeRecipients) > 0 {
+		approvedFeeRecipients = make(map[bellatrix.ExecutionAddress]bool, len(opts.ApprovedFeeRecipients))
+		for _, feeRecipient := range opts.ApprovedFeeRecipients {
+			approvedFeeRecipients[feeRecipient] = true
+		}
+	}
+
+	var auth *apiAuth
+	if opts.APIAuthToken != "" || opts.APIAuthTokenFile != "" {
+		auth, err = newAPIAuth(opts.APIAuthToken, opts.APIAuthTokenFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var clients *clientAllowlist
+	if len(opts.AllowedClientCIDRs) > 0 || len(opts.TrustedProxies) > 0 {
+		clients, err = newClientAllowlist(opts.AllowedClientCIDRs, opts.TrustedProxies)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	builderSigningDomain, err := ComputeDomain(ssz.DomainTypeAppBuilder, opts.GenesisForkVersionHex, phase0.Root{}.String())
+	var requestSigner RequestSigner
+	if opts.RequestSigningKey != "" {
+		if opts.RequestSigningScheme == "" {
+			opts.RequestSigningScheme = RequestSigningSchemeBLS
+		}
+		requestSigner, err = newRequestSigner(opts.RequestSigningScheme, opts.RequestSigningKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var cors *corsPolicy
+	if len(opts.CORSAllowedOrigins) > 0 {
+		cors, err = newCORSPolicy(opts.CORSAllowedOrigins, opts.CORSAllowedHeaders, opts.CORSMaxAge)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var outcomes *payloadOutcomes
+	if opts.PayloadOutcomesFile != "" {
+		outcomes, err = newPayloadOutcomes(opts.PayloadOutcomesFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var bidMetadata *bidMetadataStore
+	if opts.BidMetadataFile != "" {
+		bidMetadata, err = newBidMetadataStore(opts.BidMetadataFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var statsd *statsdExporter
+	if opts.StatsdAddr != "" {
+		dialect := opts.StatsdDialect
+		if dialect == "" {
+			dialect = StatsdDialectStatsd
+		}
+		statsd, err = newStatsdExporter(opts.StatsdAddr, opts.StatsdPrefix, dialect)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var alerts *alerter
+	if opts.AlertWebhookURL != "" || opts.AlertCommand != "" {
+		alerts, err = newAlerter(opts.AlertWebhookURL, opts.AlertCommand, opts.AlertMinInterval, opts.AlertUnhealthyChecks, opts.AlertNoBidSlots, opts.Log)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	checkRedirect := newRelayRedirectPolicy(opts.Relays, opts.FollowRelayRedirectsSameHost, opts.Log)
+
+	transport, err := newConfiguredRelayTransport(opts.RelayDialTimeout, opts.RelayTLSHandshakeTimeout, opts.RelayDNSCacheTTL, opts.RelayLocalAddr, opts.RelayTLSMinVersion, opts.Log)
 	if err != nil {
 		return nil, err
 	}
 
-	return &BoostService{
-		listenAddr:    opts.ListenAddr,
-		relays:        opts.Relays,
-		relayMonitors: opts.RelayMonitors,
-		log:           opts.Log,
-		relayCheck:    opts.RelayCheck,
-		relayMinBid:   opts.RelayMinBid,
-		genesisTime:   opts.GenesisTime,
-		bids:          make(map[string]bidResp),
-		slotUID:       &slotUID{},
+	var chaos *chaosConfig
+	if opts.ChaosConfig != "" {
+		chaos, err = newChaosConfig(opts.ChaosConfig)
+		if err != nil {
+			return nil, err
+		}
+		next := transport
+		if next == nil {
+			next = http.DefaultTransport
+		}
+		transport = &chaosTransport{next: next, chaos: chaos, log: opts.Log}
+		opts.Log.WithField("path", opts.ChaosConfig).Warn("CHAOS: injecting faults into relay requests")
+	}
+	var monitor *selfMonitor
+	if opts.SelfMonitorInterval > 0 {
+		relayClient := http.Client{Transport: transport}
+		monitor = newSelfMonitor(sampleProcessResources, opts.ResourceSoftLimits, opts.ResourceHardLimits, relayClient.CloseIdleConnections, opts.Log)
+	}
+	if opts.ChaosDelay > 0 {
+		opts.Log.WithField("delay", opts.ChaosDelay.String()).Warn("CHAOS: delaying getHeader and getPayload responses")
+	}
+
+	var tenants *tenantMap
+	if opts.TenantsFile != "" {
+		tenants, err = newTenantMap(opts.TenantsFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	m := &BoostService{
+		listenAddr:     opts.ListenAddr,
+		relays:         opts.Relays,
+		relayMonitors:  relayMonitors,
+		log:            opts.Log,
+		relayCheck:     opts.RelayCheck,
+		relayMinBid:    opts.RelayMinBid,
+		genesisTime:    opts.GenesisTime,
+		timingHeader:   opts.TimingHeader,
+		metricsAddr:    opts.MetricsAddr,
+		bids:           newShardedBidStore(),
+		maxCachedSlots: opts.MaxCachedSlots,
+		slotUID:        &slotUID{},
+
+		blockNumberSpread: opts.BlockNumberSpread,
+
+		compatShimsEnabled:     opts.CompatShims,
+		consensusVersionShadow: opts.ConsensusVersionShadow,
+		inferConsensusVersion:  opts.InferConsensusVersion,
 
-		builderSigningDomain: builderSigningDomain,
+		apiAuth:         auth,
+		cors:            cors,
+		clientAllowlist: clients,
+
+		requestSigner:     requestSigner,
+		strictPubkeyCheck: opts.StrictPubkeyCheck,
+
+		session:            newSessionStats(time.Now()),
+		sessionSummaryFile: opts.SessionSummaryFile,
+
+		strictRelaySchema:         opts.StrictRelaySchema,
+		relayPriorityToleranceBps: uint64(opts.RelayPriorityTolerancePct * 100),
+		minBidOverLocalPct:        opts.MinBidOverLocalPct,
+		retryTimeoutFraction:      opts.RetryTimeoutFraction,
+		relayOrderHeader:          opts.RelayOrderHeader,
+		forwardHeaders:            forwardHeaders,
+		debugEndpoints:            opts.DebugEndpoints,
+		adminEndpoints:            opts.AdminEndpoints,
+		adminToken:                opts.AdminToken,
+		slowRelayThreshold:        opts.SlowRelayThreshold,
+
+		failedDeliveries:      newFailedDeliveries(),
+		bidMemo:               newBidMemo(),
+		topBidStreams:         newTopBidStreams(opts.Relays, transport, opts.Log),
+		registrationCoverage:  newRegistrationCoverage(),
+		feeRecipients:         newRegisteredFeeRecipients(opts.FeeRecipientAudit),
+		approvedFeeRecipients: approvedFeeRecipients,
+		getHeaderCallers:      newGetHeaderCallers(),
+		auctionedHeaders:      newAuctionedHeaders(),
+		failedDeliveryPolicy:  opts.FailedDeliveryPolicy,
+		relayFailurePolicy:    opts.RelayFailurePolicy,
+		payloadOutcomes:       outcomes,
+		bidMetadata:           bidMetadata,
+		payloadArtifactsDir:   opts.PayloadArtifactsDir,
+		deliveredPayloads:     newDeliveredPayloads(),
+
+		withholdingPenalties:     newWithholdingPenalties(opts.WithholdingPenalty),
+		withholdingEvents:        newWithholdingEvents(opts.WithholdingEventsRetention),
+		bidArrivals:              newBidArrivals(),
+		withholdingPenaltyPolicy: opts.WithholdingPenaltyPolicy,
+		relayAdvisories:          newRelayAdvisories(opts.Log),
+		contentTypeCheck:         opts.RelayContentTypeCheck,
+
+		validationLevel: opts.ValidationLevel,
+		serveCachedBid:  opts.ServeCachedBid,
+		cachedBidMaxAge: opts.CachedBidMaxAge,
+		bidTieBreak:     opts.BidTieBreak,
+		conflictingBids: opts.ConflictingBids,
+
+		builderAllowlist: builderAllowlist,
+		builderDenylist:  newBuilderDenylist(opts.BuilderDenylist),
+
+		paymentAuditor: auditor,
+		tenants:        tenants,
+		statsd:         statsd,
+		alerts:         alerts,
+		chaos:          chaos,
+
+		chaosResponseDelay: opts.ChaosDelay,
+
+		relayCheckReadiness:      opts.RelayCheckReadiness,
+		relayCheckStartupTimeout: opts.RelayCheckStartupTimeout,
+
+		relayConnectionPrewarm:    opts.PrewarmRelayConnections,
+		connectionPrewarmInterval: connectionPrewarmInterval(transport),
+		selfMonitor:               monitor,
+		selfMonitorInterval:       opts.SelfMonitorInterval,
+
+		minActiveRelays: opts.MinActiveRelays,
+
+		minBiddingRelays:       opts.MinBiddingRelays,
+		minBiddingRelaysPolicy: opts.MinBiddingRelaysPolicy,
+
+		secondsPerSlot:    secondsPerSlotOrDefault(opts.SecondsPerSlot),
+		checkBidTimestamp: opts.CheckBidTimestamp && opts.GenesisTime > 0,
+
+		forkSchedule:          newForkSchedule(opts.ForkEpochs),
+		getHeaderForkTimeouts: opts.GetHeaderTimeoutByFork,
+
+		signingDomains:        signingDomains,
+		getHeaderSlotDeadline: opts.GetHeaderSlotDeadline,
 		httpClientGetHeader: http.Client{
 			Timeout:       opts.RequestTimeoutGetHeader,
-			CheckRedirect: httpClientDisallowRedirects,
+			CheckRedirect: checkRedirect,
+			Transport:     transport,
 		},
 		httpClientGetPayload: http.Client{
 			Timeout:       opts.RequestTimeoutGetPayload,
-			CheckRedirect: httpClientDisallowRedirects,
+			CheckRedirect: checkRedirect,
+			Transport:     transport,
 		},
 		httpClientRegVal: http.Client{
 			Timeout:       opts.RequestTimeoutRegVal,
-			CheckRedirect: httpClientDisallowRedirects,
+			CheckRedirect: checkRedirect,
+			Transport:     transport,
+		},
+		httpClientMonitor: http.Client{
+			Timeout:       monitorTimeout,
+			CheckRedirect: checkRedirect,
+			Transport:     transport,
 		},
 		requestMaxRetries: opts.RequestMaxRetries,
-	}, nil
+		requestRetryOn:    retryOn,
+
+		maxRegistrationBatchSize: opts.MaxRegistrationBatchSize,
+		registrationJitter:       opts.RegistrationJitter,
+		registrationDomain:       registrationDomain,
+		maxPayloadResponseSize:   opts.MaxPayloadResponseSize,
+		maxPayloadRequestSize:    opts.MaxPayloadRequestSize,
+	}
+	m.bidFilters = append(m.defaultBidFilters(), opts.BidFilters...)
+	return m, nil
 }
 
 func (m *BoostService) respondError(w http.ResponseWriter, code int, message string) {
@@ -141,7 +832,7 @@ func (m *BoostService) respondError(w http.ResponseWriter, code int, message str
 	w.WriteHeader(code)
 	resp := httpErrorResp{code, message}
 	if err := json.NewEncoder(w).Encode(resp); err != nil {
-		m.log.WithField("response", resp).WithError(err).Error("Couldn't write error response")
+		m.log.WithField("response", redactJSON(resp)).WithError(err).Error("Couldn't write error response")
 		http.Error(w, "", http.StatusInternalServerError)
 	}
 }
@@ -150,7 +841,7 @@ func (m *BoostService) respondOK(w http.ResponseWriter, response any) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	if err := json.NewEncoder(w).Encode(response); err != nil {
-		m.log.WithField("response", response).WithError(err).Error("Couldn't write OK response")
+		m.log.WithField("response", redactJSON(response)).WithError(err).Error("Couldn't write OK response")
 		http.Error(w, "", http.StatusInternalServerError)
 	}
 }
@@ -158,26 +849,75 @@ func (m *BoostService) respondOK(w http.ResponseWriter, response any) {
 func (m *BoostService) getRouter() http.Handler {
 	r := mux.NewRouter()
 	r.HandleFunc("/", m.handleRoot)
+	r.HandleFunc(params.PathHealthz, m.handleHealthz).Methods(http.MethodGet)
 
 	r.HandleFunc(params.PathStatus, m.handleStatus).Methods(http.MethodGet)
-	r.HandleFunc(params.PathRegisterValidator, m.handleRegisterValidator).Methods(http.MethodPost)
-	r.HandleFunc(params.PathGetHeader, m.handleGetHeader).Methods(http.MethodGet)
-	r.HandleFunc(params.PathGetPayload, m.handleGetPayload).Methods(http.MethodPost)
+	r.HandleFunc(params.PathRegisterValidator, m.nonCritical(m.restrictClients("registerValidator", m.handleRegisterValidator))).Methods(http.MethodPost)
+	r.HandleFunc(params.PathGetHeader, m.refuseWhileDraining(m.restrictClients("getHeader", m.chaosDelay(m.handleGetHeader)))).Methods(http.MethodGet)
+	r.HandleFunc(params.PathGetPayload, m.trackPayload(m.restrictClients("getPayload", m.chaosDelay(m.handleGetPayload)))).Methods(http.MethodPost)
+
+	if m.debugEndpoints {
+		r.HandleFunc(params.PathDebugFailedDeliveries, m.nonCritical(m.adminAuth(m.handleDebugFailedDeliveries))).Methods(http.MethodGet)
+		r.HandleFunc(params.PathDebugRegistrations, m.nonCritical(m.adminAuth(m.handleDebugRegistrations))).Methods(http.MethodGet)
+		r.HandleFunc(params.PathDebugRelays, m.nonCritical(m.adminAuth(m.handleDebugRelays))).Methods(http.MethodGet)
+		r.HandleFunc(params.PathDebugWithholding, m.nonCritical(m.adminAuth(m.handleDebugWithholding))).Methods(http.MethodGet)
+		r.HandleFunc(params.PathDebugConfig, m.nonCritical(m.adminAuth(m.handleDebugConfig))).Methods(http.MethodGet)
+		r.HandleFunc(params.PathDebugFeeRecipients, m.nonCritical(m.adminAuth(m.handleDebugFeeRecipients))).Methods(http.MethodGet)
+		r.HandleFunc(params.PathDebugBidArrivals, m.nonCritical(m.adminAuth(m.handleDebugBidArrivals))).Methods(http.MethodGet)
+	}
+	if m.adminEndpoints {
+		r.HandleFunc(params.PathAdminBuilderDenylist, m.adminAuth(m.handleAdminBuilderDenylist)).Methods(http.MethodGet, http.MethodPost, http.MethodDelete)
+		r.HandleFunc(params.PathDebugBidsFlush, m.adminAuth(m.handleDebugBidsFlush)).Methods(http.MethodPost)
+		r.HandleFunc(params.PathAdminDrain, m.adminAuth(m.handleAdminDrain)).Methods(http.MethodPost)
+	}
 
 	r.Use(mux.CORSMethodMiddleware(r))
-	loggedRouter := httplogger.LoggingMiddlewareLogrus(m.log, r)
+	loggedRouter := httplogger.LoggingMiddlewareLogrus(m.log, m.corsMiddleware(m.authMiddleware(m.compatMiddleware(m.holdConfig(r)))))
 	return loggedRouter
 }
 
 // StartHTTPServer starts the HTTP server for this boost service instance
 func (m *BoostService) StartHTTPServer() error {
+	m.srvLock.Lock()
 	if m.srv != nil {
+		m.srvLock.Unlock()
 		return errServerAlreadyRunning
 	}
 
 	go m.startBidCacheCleanupTask()
+	if m.tenants != nil {
+		go m.watchTenantsFile()
+	}
+	if m.chaos != nil {
+		go m.watchChaosConfig()
+	}
+	if m.apiAuth != nil && m.apiAuth.tokenFile != "" {
+		go m.watchAuthTokenFile()
+	}
+
+	if m.relayCheckReadiness {
+		m.waitingForRelayCheck.Store(true)
+		go m.runStartupRelayCheck()
+	}
+	if m.relayConnectionPrewarm {
+		ctx, cancel := context.WithCancel(context.Background())
+		m.stopConnectionPrewarm = cancel
+		go m.runConnectionPrewarm(ctx)
+	}
+	if m.selfMonitor != nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		m.stopSelfMonitor = cancel
+		go m.selfMonitor.run(ctx, m.selfMonitorInterval)
+	}
+	if len(m.topBidStreams) > 0 {
+		ctx, cancel := context.WithCancel(context.Background())
+		m.stopTopBidStreams = cancel
+		for _, stream := range m.topBidStreams {
+			go stream.run(ctx)
+		}
+	}
 
-	m.srv = &http.Server{
+	srv := &http.Server{
 		Addr:    m.listenAddr,
 		Handler: m.getRouter(),
 
@@ -188,34 +928,120 @@ func (m *BoostService) StartHTTPServer() error {
 
 		MaxHeaderBytes: config.ServerMaxHeaderBytes,
 	}
+	m.srv = srv
+	m.srvLock.Unlock()
 
-	err := m.srv.ListenAndServe()
+	err := srv.ListenAndServe()
 	if errors.Is(err, http.ErrServerClosed) {
 		return nil
 	}
 	return err
 }
 
+// runStartupRelayCheck checks the relays once, giving up after the startup timeout, and then marks the service as ready
+func (m *BoostService) runStartupRelayCheck() {
+	defer m.waitingForRelayCheck.Store(false)
+	m.reloadLock.RLock()
+	defer m.reloadLock.RUnlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), m.relayCheckStartupTimeout)
+	defer cancel()
+
+	numHealthyRelays := m.checkRelays(ctx)
+	log := m.log.WithFields(logrus.Fields{
+		"healthyRelays": numHealthyRelays,
+		"totalRelays":   len(m.relays),
+	})
+	switch {
+	case ctx.Err() != nil:
+		log.Warn("initial relay check timed out, reporting ready anyway")
+	case numHealthyRelays == 0:
+		log.Error("no relay passed the initial health-check!")
+	default:
+		log.Info("initial relay check finished")
+	}
+}
+
 func (m *BoostService) startBidCacheCleanupTask() {
 	for {
 		time.Sleep(1 * time.Minute)
-		m.bidsLock.Lock()
-		for k, bidResp := range m.bids {
-			if time.Since(bidResp.t) > 3*time.Minute {
-				delete(m.bids, k)
-			}
+		m.evictExpiredBids(time.Now())
+		m.evictOldestBidSlots()
+	}
+}
+
+// storeBid caches the bid served for the block hash, for getPayload and for the logging of withholding
+func (m *BoostService) storeBid(slot phase0.Slot, blockHash phase0.Hash32, bid bidResp) {
+	m.bids.put(slot, blockHash, bid)
+	bidCacheEntries.Set(float64(m.bids.len()))
+	m.evictOldestBidSlots()
+}
+
+// removeBids removes the bids for which del returns true, and counts them as evicted for the reason
+func (m *BoostService) removeBids(reason string, del func(bidResp) bool) int {
+	removed := m.bids.deleteFunc(del)
+	bidCacheEvictions.WithLabelValues(reason).Add(float64(removed))
+	bidCacheEntries.Set(float64(m.bids.len()))
+	return removed
+}
+
+// evictExpiredBids removes the bids older than three minutes
+func (m *BoostService) evictExpiredBids(now time.Time) {
+	m.removeBids("expired", func(bid bidResp) bool {
+		return now.Sub(bid.t) > 3*time.Minute
+	})
+}
+
+// evictOldestBidSlots removes the bids of the oldest slots while bids of more than the maximum number of
+// slots are cached
+func (m *BoostService) evictOldestBidSlots() {
+	if m.maxCachedSlots <= 0 {
+		return
+	}
+	slots := m.bids.slots()
+	if len(slots) <= m.maxCachedSlots {
+		return
+	}
+	slices.Sort(slots)
+	evicted := make(map[phase0.Slot]bool)
+	for _, slot := range slots[:len(slots)-m.maxCachedSlots] {
+		evicted[slot] = true
+	}
+	m.removeBids("max_slots", func(bid bidResp) bool {
+		return evicted[bid.slot]
+	})
+}
+
+// handleDebugBidsFlush removes all bids from the bid cache, and returns the number of removed bids
+func (m *BoostService) handleDebugBidsFlush(w http.ResponseWriter, _ *http.Request) {
+	removed := m.removeBids("flush", func(bidResp) bool { return true })
+
+	m.log.WithField("removed", removed).Warn("flushed the bid cache")
+	m.respondOK(w, map[string]int{"removed": removed})
+}
+
+// relayMonitorEntries parses the options in the URLs of the relay monitors
+func relayMonitorEntries(monitors []*url.URL) ([]types.RelayMonitorEntry, error) {
+	entries := make([]types.RelayMonitorEntry, 0, len(monitors))
+	for _, monitor := range monitors {
+		entry, err := types.NewRelayMonitorEntry(monitor)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", err, redactURL(monitor))
 		}
-		m.bidsLock.Unlock()
+		entries = append(entries, entry)
 	}
+	return entries, nil
 }
 
+// sendValidatorRegistrationsToRelayMonitors sends the registrations to the relay monitors with their own
+// client, so slow monitors never delay the registerValidator response
 func (m *BoostService) sendValidatorRegistrationsToRelayMonitors(payload []builderApiV1.SignedValidatorRegistration) {
 	log := m.log.WithField("method", "sendValidatorRegistrationsToRelayMonitors").WithField("numRegistrations", len(payload))
 	for _, relayMonitor := range m.relayMonitors {
-		go func(relayMonitor *url.URL) {
-			url := types.GetURI(relayMonitor, params.PathRegisterValidator)
-			log = log.WithField("url", url)
-			_, err := SendHTTPRequest(context.Background(), m.httpClientRegVal, http.MethodPost, url, "", nil, payload, nil)
+		go func(relayMonitor types.RelayMonitorEntry) {
+			url := relayMonitor.GetURI(params.PathRegisterValidator)
+			log := log.WithField("url", url)
+			_, err := SendHTTPRequest(context.Background(), m.httpClientMonitor, http.MethodPost, url, "", nil, payload, nil)
 			if err != nil {
 				log.WithError(err).Warn("error calling registerValidator on relay monitor")
 				return
@@ -233,6 +1059,14 @@ func (m *BoostService) handleRoot(w http.ResponseWriter, _ *http.Request) {
 // It returns OK if at least one returned OK, and returns error otherwise.
 func (m *BoostService) handleStatus(w http.ResponseWriter, _ *http.Request) {
 	w.Header().Set(HeaderKeyVersion, config.Version)
+	if m.waitingForRelayCheck.Load() {
+		m.respondError(w, http.StatusServiceUnavailable, errWaitingForRelayCheck.Error())
+		return
+	}
+	if err := m.checkMinActiveRelays(m.log.WithField("method", "status"), "status"); err != nil {
+		m.respondError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
 	if !m.relayCheck || m.CheckRelays() > 0 {
 		m.respondOK(w, nilResponse)
 	} else {
@@ -242,14 +1076,51 @@ func (m *BoostService) handleStatus(w http.ResponseWriter, _ *http.Request) {
 
 // handleRegisterValidator returns StatusOK if at least one relay returns StatusOK, else StatusBadGateway
 func (m *BoostService) handleRegisterValidator(w http.ResponseWriter, req *http.Request) {
-	log := m.log.WithField("method", "registerValidator")
+	log := m.log.WithFields(logrus.Fields{
+		"method":   "registerValidator",
+		"clientIP": m.clientAllowlist.clientLogField(req),
+	})
 	log.Debug("registerValidator")
 
+	// Reject oversized batches before reading the full body
+	maxBodySize := int64(m.maxRegistrationBatchSize) * maxRegistrationJSONSize
+	if req.ContentLength > maxBodySize {
+		log.WithField("contentLength", req.ContentLength).Warn("rejecting oversized registration batch")
+		m.respondError(w, http.StatusRequestEntityTooLarge, errRegistrationBatchTooLarge.Error())
+		return
+	}
+
 	payload := []builderApiV1.SignedValidatorRegistration{}
-	if err := DecodeJSON(req.Body, &payload); err != nil {
+	if err := DecodeJSON(http.MaxBytesReader(w, req.Body, maxBodySize), &payload); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			log.Warn("rejecting oversized registration batch")
+			m.respondError(w, http.StatusRequestEntityTooLarge, errRegistrationBatchTooLarge.Error())
+			return
+		}
 		m.respondError(w, http.StatusBadRequest, err.Error())
 		return
 	}
+	if len(payload) > m.maxRegistrationBatchSize {
+		log.WithField("numRegistrations", len(payload)).Warn("rejecting oversized registration batch")
+		m.respondError(w, http.StatusRequestEntityTooLarge, errRegistrationBatchTooLarge.Error())
+		return
+	}
+
+	// Only reject batches whose every registration was dropped, empty batches are forwarded as before
+	valid := m.filterValidRegistrationSignatures(log, payload)
+	if len(payload) > 0 && len(valid) == 0 {
+		m.respondError(w, http.StatusBadRequest, errInvalidRegistrationSignatures.Error())
+		return
+	}
+	payload = valid
+	approved := m.filterApprovedFeeRecipients(log, payload)
+	if len(payload) > 0 && len(approved) == 0 {
+		m.respondError(w, http.StatusBadRequest, errFeeRecipientNotApproved.Error())
+		return
+	}
+	payload = approved
+	m.recordFeeRecipients(log, payload)
 
 	ua := UserAgent(req.Header.Get("User-Agent"))
 	log = log.WithFields(logrus.Fields{
@@ -262,16 +1133,23 @@ func (m *BoostService) handleRegisterValidator(w http.ResponseWriter, req *http.
 		HeaderStartTimeUnixMS: fmt.Sprintf("%d", time.Now().UTC().UnixMilli()),
 	}
 
-	relayRespCh := make(chan error, len(m.relays))
+	relays := m.relayAdvisories.available(log, m.relays, time.Now())
+	relayRespCh := make(chan error, len(relays))
 
-	for _, relay := range m.relays {
+	for _, relay := range relays {
 		go func(relay types.RelayEntry) {
 			url := relay.GetURI(params.PathRegisterValidator)
-			log := log.WithField("url", url)
+			log := log.WithFields(logrus.Fields{"relay": relayLabel(relay), "url": url})
 
-			_, err := SendHTTPRequest(context.Background(), m.httpClientRegVal, http.MethodPost, url, ua, headers, payload, nil)
+			// Relays are not all called at once, the response is still sent on the first success
+			if m.registrationJitter > 0 {
+				time.Sleep(rand.N(m.registrationJitter)) //nolint:gosec // not used for security
+			}
+			_, err := SendHTTPRequest(m.relayAdvisories.observing(withRequestSigner(context.Background(), m.requestSigner), relay), m.httpClientRegVal, http.MethodPost, url, ua, withRelayHeaders(headers, relay), payload, nil)
 			if err != nil {
-				log.WithError(err).Warn("error calling registerValidator on relay")
+				log.WithFields(m.session.recordRequestError(relay, "registerValidator", err)).WithError(err).Warn("error calling registerValidator on relay")
+			} else {
+				m.registrationCoverage.record(relay, payload, time.Now())
 			}
 			relayRespCh <- err
 		}(relay)
@@ -279,9 +1157,11 @@ func (m *BoostService) handleRegisterValidator(w http.ResponseWriter, req *http.
 
 	go m.sendValidatorRegistrationsToRelayMonitors(payload)
 
-	for i := 0; i < len(m.relays); i++ {
+	for i := 0; i < len(relays); i++ {
 		respErr := <-relayRespCh
 		if respErr == nil {
+			m.countForwardedRegistrations(payload)
+			m.session.recordRegistrationBatch(len(payload))
 			m.respondOK(w, nilResponse)
 			return
 		}
@@ -290,13 +1170,26 @@ func (m *BoostService) handleRegisterValidator(w http.ResponseWriter, req *http.
 	m.respondError(w, http.StatusBadGateway, errNoSuccessfulRelayResponse.Error())
 }
 
+// countForwardedRegistrations increments the forwarded registrations metric of each tenant
+func (m *BoostService) countForwardedRegistrations(payload []builderApiV1.SignedValidatorRegistration) {
+	counts := make(map[string]int)
+	for _, registration := range payload {
+		if registration.Message == nil {
+			continue
+		}
+		counts[m.tenants.tenant(registration.Message.Pubkey.String())]++
+	}
+	for tenant, count := range counts {
+		tenantRegistrationsForwarded.WithLabelValues(tenant).Add(float64(count))
+	}
+}
+
 // handleGetHeader requests bids from the relays
 func (m *BoostService) handleGetHeader(w http.ResponseWriter, req *http.Request) {
 	var (
-		vars          = mux.Vars(req)
-		parentHashHex = vars["parent_hash"]
-		pubkey        = vars["pubkey"]
-		ua            = UserAgent(req.Header.Get("User-Agent"))
+		vars  = mux.Vars(req)
+		ua    = UserAgent(req.Header.Get("User-Agent"))
+		timer = newRequestTimer()
 	)
 
 	slotValue, err := strconv.ParseUint(vars["slot"], 10, 64)
@@ -306,66 +1199,259 @@ func (m *BoostService) handleGetHeader(w http.ResponseWriter, req *http.Request)
 	}
 	slot := phase0.Slot(slotValue)
 
+	// Relays differ in which spellings of hex parameters they accept, so only the normalized form is forwarded
+	parentHashHex, _, ok := normalizeHexParam(vars["parent_hash"], len(phase0.Hash32{}))
+	if !ok {
+		m.respondError(w, http.StatusBadRequest, fmt.Sprintf("%s: %s", errInvalidHash, vars["parent_hash"]))
+		return
+	}
+	pubkey, pubkeyBytes, ok := normalizeHexParam(vars["pubkey"], len(phase0.BLSPubKey{}))
+	if ok && m.strictPubkeyCheck {
+		_, err = bls.PublicKeyFromBytes(pubkeyBytes)
+		ok = err == nil
+	}
+	if !ok {
+		m.respondError(w, http.StatusBadRequest, fmt.Sprintf("%s: %s", errInvalidPubkey, vars["pubkey"]))
+		return
+	}
+	tenant := m.tenants.tenant(pubkey)
+
+	// The arrival time in the slot shows whether the beacon node calls getHeader early or late
 	log := m.log.WithFields(logrus.Fields{
-		"method":     "getHeader",
-		"slot":       slot,
-		"parentHash": parentHashHex,
-		"pubkey":     pubkey,
-		"ua":         ua,
+		"method":            "getHeader",
+		"slot":              slot,
+		"parentHash":        parentHashHex,
+		"pubkey":            pubkey,
+		"ua":                ua,
+		"tenant":            tenant,
+		"clientIP":          m.clientAllowlist.clientLogField(req),
+		"arrivalMsIntoSlot": m.msIntoSlot(slot, timer.start),
 	})
 	log.Debug("getHeader")
 
-	// Query the relays for the header
-	result, err := m.getHeader(log, ua, slot, pubkey, parentHashHex)
-	if err != nil {
+	if err := m.checkMinActiveRelays(log, "getHeader"); err != nil {
+		m.respondError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+
+	// Several beacon nodes requesting a header for the same validator may propose twice
+	if others := m.getHeaderCallers.record(slot, pubkey, string(ua)); len(others) > 0 {
+		duplicateGetHeaderRequests.Inc()
+		log.WithField("otherUserAgents", strings.Join(others, ", ")).Warn("getHeader for the same slot and validator from another client, check for a redundant beacon node setup")
+	}
+
+	// Beacon nodes may hint which relays to prioritise, the honoured part of the hint is echoed back
+	relays := m.relays
+	if value := req.Header.Get(HeaderKeyRelayOrder); m.relayOrderHeader && value != "" {
+		hint, err := parseRelayOrderHeader(value)
+		if err != nil {
+			log.WithError(err).Warn("ignoring relay order header")
+		} else {
+			var honoured []string
+			relays, honoured = m.orderRelays(log, hint)
+			w.Header().Set(HeaderKeyRelayOrder, strings.Join(honoured, ","))
+		}
+	}
+
+	// Beacon nodes may supply the value of their local block, which bids must beat by a percentage
+	var localValue *uint256.Int
+	if value := req.Header.Get(HeaderKeyLocalBlockValue); value != "" {
+		localValue, err = uint256.FromDecimal(value)
+		if err != nil {
+			log.WithError(err).WithField("localValue", value).Warn("ignoring invalid local block value header")
+			localValue = nil
+		}
+	}
+
+	// Query the relays for the header. If configured, a retry of an auction gets a reduced budget, as the bid
+	// of the first auction can be served again.
+	now := time.Now()
+	deadline := m.getHeaderDeadline(slot, now)
+	retry := m.retryTimeoutFraction > 0 && m.auctionedHeaders.record(slot, parentHashHex, pubkey)
+	if retry {
+		deadline = m.retryDeadline(deadline, now)
+		log = log.WithField("retry", true)
+		log.WithField("retryBudgetMs", deadline.Sub(now).Milliseconds()).Info("getHeader retry for an auction of this slot")
+	}
+	forwarded := m.forwardedHeaders(req)
+	result, err := m.getHeader(log, timer, ua, forwarded, relays, slot, pubkey, parentHashHex, localValue, deadline)
+	if retry {
+		result, err = m.preferCachedBid(log, result, err, slot, parentHashHex, pubkey)
+	}
+	if errors.Is(err, errAllRelaysFailed) {
+		var retryAfter time.Duration
+		result, retryAfter = m.afterAllRelaysFailed(log, timer, ua, forwarded, relays, slot, pubkey, parentHashHex, localValue, deadline)
+		if retryAfter > 0 {
+			tenantAuctions.WithLabelValues(tenant).Inc()
+			m.session.recordAuction(nil)
+			m.statsd.count("auctions", 1, statsdTags{"outcome": "retry_after", "tenant": tenant})
+			m.setTimingHeader(w, timer)
+			w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds())))
+			m.respondError(w, http.StatusBadGateway, errNoSuccessfulRelayResponse.Error())
+			return
+		}
+	} else if err != nil {
 		m.respondError(w, http.StatusBadRequest, err.Error())
 		return
 	}
+	log, serve := m.checkBiddingRelays(log, result)
+	if !serve {
+		result = bidResp{}
+	}
+	tenantAuctions.WithLabelValues(tenant).Inc()
+	m.session.recordAuction(result.relays)
 
 	if result.response.IsEmpty() {
+		m.statsd.count("auctions", 1, statsdTags{"outcome": "no_bid", "tenant": tenant})
 		log.Info("no bid received")
+		m.alerts.auction(slot, false)
+		m.setTimingHeader(w, timer)
 		w.WriteHeader(http.StatusNoContent)
 		return
 	}
 
+	// Served bids keep a drain open until their getPayload request, so the bid is refused if draining started
+	// during the auction
+	if !m.drain.served(slot, m.slotEnd(slot)) {
+		log.Warn("draining started during the auction, not serving the bid")
+		m.respondDraining(w)
+		return
+	}
+
 	// Remember the bid, for future logging in case of withholding
-	m.bidsLock.Lock()
-	m.bids[bidKey(slot, result.bidInfo.blockHash)] = result
-	m.bidsLock.Unlock()
+	result.slot = slot
+	result.tenant = tenant
+	result.proposerPubkey = pubkey
+	m.storeBid(slot, result.bidInfo.blockHash, result)
+	if m.bidMetadata != nil {
+		go func() {
+			if err := m.bidMetadata.record(slot, result.bidInfo.blockHash, result.relays); err != nil {
+				log.WithError(err).Error("could not persist the bid metadata")
+			}
+		}()
+	}
 
 	// Log result
+	m.setTimingHeader(w, timer)
 	valueEth := weiBigIntToEthBigFloat(result.bidInfo.value.ToBig())
-	log.WithFields(logrus.Fields{
-		"blockHash":   result.bidInfo.blockHash.String(),
-		"blockNumber": result.bidInfo.blockNumber,
-		"txRoot":      result.bidInfo.txRoot.String(),
-		"value":       valueEth.Text('f', 18),
-		"relays":      strings.Join(types.RelayEntriesToStrings(result.relays), ", "),
+	if localValue != nil && len(result.relays) > 0 {
+		log = log.WithFields(m.localBlockThreshold(result.relays[0], localValue).logFields())
+	}
+	log.WithFields(timer.logFields()).WithFields(logrus.Fields{
+		"blockHash":     result.bidInfo.blockHash.String(),
+		"blockNumber":   result.bidInfo.blockNumber,
+		"txRoot":        result.bidInfo.txRoot.String(),
+		"builderPubkey": result.bidInfo.pubkey.String(),
+		"value":         valueEth.Text('f', 18),
+		"relays":        strings.Join(types.RelayEntriesToNames(result.relays), ", "),
+		"tieBreak":      result.tieBreak,
+		"fromCache":     result.servedFromCache,
 	}).Info("best bid")
+	m.alerts.auction(slot, true)
+	if !result.servedFromCache {
+		m.checkFeeRecipient(log, phase0.BLSPubKey(pubkeyBytes), result)
+	}
 
 	// Return the bid
+	tenantBidsWon.WithLabelValues(tenant).Inc()
+	builderBidsWon.WithLabelValues(result.bidInfo.pubkey.String()).Inc()
+	outcome := "bid"
+	if result.servedFromCache {
+		outcome = "served_from_cache"
+	}
+	m.statsd.count("auctions", 1, statsdTags{"outcome": outcome, "tenant": tenant})
 	m.respondOK(w, &result.response)
 }
 
-// respondPayload responds to the proposer with the payload
-func (m *BoostService) respondPayload(w http.ResponseWriter, log *logrus.Entry, result *builderApi.VersionedSubmitBlindedBlockResponse, originalBid bidResp) {
+// respondPayload responds to the proposer with the payload. previous is the outcome of an earlier submission of the
+// same block, for which the failure to deliver the payload is not reported again.
+func (m *BoostService) respondPayload(w http.ResponseWriter, log *logrus.Entry, timer *requestTimer, slot phase0.Slot, blockHash phase0.Hash32, result *relayPayload, originalBid bidResp, previous *payloadOutcome) {
+	m.setTimingHeader(w, timer)
+	log = log.WithFields(timer.logFields())
+	tenant := originalBid.tenant
+	if tenant == "" {
+		tenant = tenantUnknown
+	}
+	log = log.WithField("tenant", tenant)
+	if originalBid.coldStartRecovery != "" {
+		log = log.WithField("coldStartRecovery", originalBid.coldStartRecovery)
+		delivered := result != nil && !getPayloadResponseIsEmpty(result.response)
+		coldStartRecoveries.WithLabelValues(originalBid.coldStartRecovery, strconv.FormatBool(delivered)).Inc()
+	}
+
+	if previous != nil && previous.Delivered && result != nil {
+		log.Info("payload for this block was already delivered in this slot, responding with the same payload")
+		w.Header().Set(HeaderEthConsensusVersion, result.response.Version.String())
+		m.recordWrittenPayload(log, slot, blockHash, m.respondPayloadCaptured(w, log, result, slot, blockHash))
+		return
+	}
+	if previous != nil && previous.Delivered {
+		log.Warn("payload for this block was already delivered, not requesting it again")
+		m.respondError(w, http.StatusConflict, errPayloadAlreadyDelivered.Error())
+		return
+	}
+
 	// If no payload has been received from relay, log loudly about withholding!
-	if result == nil || getPayloadResponseIsEmpty(result) {
-		originRelays := types.RelayEntriesToStrings(originalBid.relays)
-		log.WithField("relaysWithBid", strings.Join(originRelays, ", ")).Error("no payload received from relay!")
+	if result == nil || getPayloadResponseIsEmpty(result.response) {
+		originRelays := types.RelayEntriesToNames(originalBid.relays)
+		log := log.WithField("relaysWithBid", strings.Join(originRelays, ", "))
+		if previous != nil {
+			log.Warn("no payload received from relay, again")
+		} else {
+			log.Error("no payload received from relay!")
+			m.session.recordPayload(originalBid.relays, false)
+			m.withholdingPenalties.penalize(originalBid.relays, time.Now())
+			m.withholdingEvents.record(slot, blockHash, originalBid.relays, time.Now())
+			for _, relay := range originalBid.relays {
+				m.statsd.count("payloads.withheld", 1, statsdTags{"relay": relayLabel(relay)})
+			}
+			m.alerts.alert(AlertPayloadWithheld, "no payload received from relay", logrus.Fields{
+				"slot":          slot,
+				"blockHash":     blockHash.String(),
+				"relaysWithBid": strings.Join(originRelays, ", "),
+			})
+		}
 		m.respondError(w, http.StatusBadGateway, errNoSuccessfulRelayResponse.Error())
 		return
 	}
-	m.respondOK(w, result)
+	w.Header().Set(HeaderEthConsensusVersion, result.response.Version.String())
+	m.recordWrittenPayload(log, slot, blockHash, m.respondPayloadCaptured(w, log, result, slot, blockHash))
+	tenantPayloadsDelivered.WithLabelValues(tenant).Inc()
+	m.session.recordPayload(originalBid.relays, true)
+	m.statsd.count("payloads.delivered", 1, statsdTags{"tenant": tenant})
+
+	// Audit the proposer payment in the background, without delaying the response
+	if m.paymentAuditor != nil && !originalBid.response.IsEmpty() {
+		go m.paymentAuditor.audit(log, result.response, originalBid)
+	}
 }
 
 // handleGetPayload requests the payload from the relays
 func (m *BoostService) handleGetPayload(w http.ResponseWriter, req *http.Request) {
-	log := m.log.WithField("method", "getPayload")
+	log := m.log.WithFields(logrus.Fields{
+		"method":   "getPayload",
+		"clientIP": m.clientAllowlist.clientLogField(req),
+	})
 	log.Debug("getPayload request starts")
+	timer := newRequestTimer()
 
-	// Read the body first, so we can log it later on error
-	body, err := io.ReadAll(req.Body)
+	if err := m.checkMinActiveRelays(log, "getPayload"); err != nil {
+		m.respondError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+
+	// Read the body first, so we can log it later on error. Oversized bodies are rejected before reading them completely.
+	if req.ContentLength > m.maxPayloadRequestSize {
+		log.WithField("contentLength", req.ContentLength).Warn("rejecting oversized getPayload request")
+		m.respondError(w, http.StatusRequestEntityTooLarge, errPayloadRequestTooLarge.Error())
+		return
+	}
+	body, err := readLimitedBody(req.Body, m.maxPayloadRequestSize)
+	if errors.Is(err, errPayloadRequestTooLarge) {
+		log.WithField("maxRequestSize", m.maxPayloadRequestSize).Warn("rejecting oversized getPayload request")
+		m.respondError(w, http.StatusRequestEntityTooLarge, err.Error())
+		return
+	}
 	if err != nil {
 		log.WithError(err).Error("could not read body of request from the beacon node")
 		m.respondError(w, http.StatusBadRequest, err.Error())
@@ -374,70 +1460,75 @@ func (m *BoostService) handleGetPayload(w http.ResponseWriter, req *http.Request
 
 	// Read user agent for logging
 	userAgent := UserAgent(req.Header.Get("User-Agent"))
+	forwarded := m.forwardedHeaders(req)
 
-	// New forks need to be added at the front of this array.
-	// The ordering of the array conveys precedence of the decoders.
-	decoders := []struct {
-		fork      string
+	// New forks need to be added to detectBlindedBlockFork as well
+	decoders := map[string]struct {
 		payload   any
-		processor func(payload any) (*builderApi.VersionedSubmitBlindedBlockResponse, bidResp)
+		processor func(payload any) (*relayPayload, bidResp, *payloadOutcome)
 	}{
-		{
-			fork:    "electra",
+		"electra": {
 			payload: new(eth2ApiV1Electra.SignedBlindedBeaconBlock),
-			processor: func(payload any) (*builderApi.VersionedSubmitBlindedBlockResponse, bidResp) {
+			processor: func(payload any) (*relayPayload, bidResp, *payloadOutcome) {
 				//nolint: forcetypeassert
-				return processPayload(m, log, userAgent, payload.(*eth2ApiV1Electra.SignedBlindedBeaconBlock))
+				return processPayload(m, log, timer, userAgent, forwarded, payload.(*eth2ApiV1Electra.SignedBlindedBeaconBlock))
 			},
 		},
-		{
-			fork:    "deneb",
+		"deneb": {
 			payload: new(eth2ApiV1Deneb.SignedBlindedBeaconBlock),
-			processor: func(payload any) (*builderApi.VersionedSubmitBlindedBlockResponse, bidResp) {
+			processor: func(payload any) (*relayPayload, bidResp, *payloadOutcome) {
 				//nolint: forcetypeassert
-				return processPayload(m, log, userAgent, payload.(*eth2ApiV1Deneb.SignedBlindedBeaconBlock))
+				return processPayload(m, log, timer, userAgent, forwarded, payload.(*eth2ApiV1Deneb.SignedBlindedBeaconBlock))
 			},
 		},
-		{
-			fork:    "capella",
+		"capella": {
 			payload: new(eth2ApiV1Capella.SignedBlindedBeaconBlock),
-			processor: func(payload any) (*builderApi.VersionedSubmitBlindedBlockResponse, bidResp) {
+			processor: func(payload any) (*relayPayload, bidResp, *payloadOutcome) {
 				//nolint: forcetypeassert
-				return processPayload(m, log, userAgent, payload.(*eth2ApiV1Capella.SignedBlindedBeaconBlock))
+				return processPayload(m, log, timer, userAgent, forwarded, payload.(*eth2ApiV1Capella.SignedBlindedBeaconBlock))
 			},
 		},
-		{
-			fork:    "bellatrix",
+		"bellatrix": {
 			payload: new(eth2ApiV1Bellatrix.SignedBlindedBeaconBlock),
-			processor: func(payload any) (*builderApi.VersionedSubmitBlindedBlockResponse, bidResp) {
+			processor: func(payload any) (*relayPayload, bidResp, *payloadOutcome) {
 				//nolint: forcetypeassert
-				return processPayload(m, log, userAgent, payload.(*eth2ApiV1Bellatrix.SignedBlindedBeaconBlock))
+				return processPayload(m, log, timer, userAgent, forwarded, payload.(*eth2ApiV1Bellatrix.SignedBlindedBeaconBlock))
 			},
 		},
 	}
 
-	// Decode the body now
-	for _, decoder := range decoders {
-		payload := decoder.payload
-		// Try to decode the payload
-		log.Debugf("attempting to decode body into %v payload", decoder.fork)
-		if err := DecodeJSON(bytes.NewReader(body), payload); err != nil {
-			log.Debugf("could not decode %v request payload", decoder.fork)
-			continue
-		}
-		// Decoding was successful, process the payload
-		result, originalBid := decoder.processor(payload)
-		m.respondPayload(w, log, result, originalBid)
+	// Determine the fork from the body, and decode it once into the block of that fork
+	fork, err := detectBlindedBlockFork(body)
+	if err != nil {
+		log.WithError(err).WithField("body", redactBody(body)).Error("could not decode request payload from the beacon-node (signed blinded beacon block)")
+		m.respondError(w, http.StatusBadRequest, "could not decode body")
+		return
+	}
+	log = log.WithField("fork", fork)
+	decoder := decoders[fork]
+	if err = DecodeJSON(bytes.NewReader(body), decoder.payload); err != nil {
+		log.WithError(err).WithField("body", redactBody(body)).Error("could not decode request payload from the beacon-node (signed blinded beacon block)")
+		m.respondError(w, http.StatusBadRequest, "could not decode body")
 		return
 	}
 
-	// No decoder was able to decode the body, log error
-	log.WithError(err).WithField("body", string(body)).Error("could not decode request payload from the beacon-node (signed blinded beacon block)")
-	m.respondError(w, http.StatusBadRequest, "could not decode body")
+	if m.consensusVersionShadow {
+		m.compareConsensusVersion(log, req, fork)
+	}
+	result, originalBid, previous := decoder.processor(decoder.payload)
+	slot, blockHash := blindedBlockID(decoder.payload)
+	m.respondPayload(w, log, timer, slot, blockHash, result, originalBid, previous)
 }
 
 // CheckRelays sends a request to each one of the relays previously registered to get their status
 func (m *BoostService) CheckRelays() int {
+	m.reloadLock.RLock()
+	defer m.reloadLock.RUnlock()
+	return m.checkRelays(context.Background())
+}
+
+// checkRelays returns the number of relays which returned OK on the status endpoint, before the context is done
+func (m *BoostService) checkRelays(ctx context.Context) int {
 	var wg sync.WaitGroup
 	var numSuccessRequestsToRelay uint32
 
@@ -447,12 +1538,12 @@ func (m *BoostService) CheckRelays() int {
 		go func(relay types.RelayEntry) {
 			defer wg.Done()
 			url := relay.GetURI(params.PathStatus)
-			log := m.log.WithField("url", url)
+			log := m.log.WithFields(logrus.Fields{"relay": relayLabel(relay), "url": url})
 			log.Debug("checking relay status")
 
-			code, err := SendHTTPRequest(context.Background(), m.httpClientGetHeader, http.MethodGet, url, "", nil, nil, nil)
+			code, err := SendHTTPRequest(m.relayAdvisories.observing(ctx, relay), m.httpClientGetHeader, http.MethodGet, url, "", withRelayHeaders(nil, relay), nil, nil)
 			if err != nil {
-				log.WithError(err).Error("relay status error - request failed")
+				log.WithFields(m.session.recordRequestError(relay, "status", err)).WithError(err).Error("relay status error - request failed")
 				return
 			}
 			if code == http.StatusOK {
@@ -469,5 +1560,6 @@ func (m *BoostService) CheckRelays() int {
 
 	// At the end, wait for every routine and return status according to relay's ones.
 	wg.Wait()
+	m.alerts.relayCheck(int(numSuccessRequestsToRelay), len(m.relays))
 	return int(numSuccessRequestsToRelay)
 }
@@ -10,12 +10,14 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"os"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
 
 	builderApi "github.com/attestantio/go-builder-client/api"
 	builderApiDeneb "github.com/attestantio/go-builder-client/api/deneb"
+	builderApiElectra "github.com/attestantio/go-builder-client/api/electra"
 	builderApiV1 "github.com/attestantio/go-builder-client/api/v1"
 	builderSpec "github.com/attestantio/go-builder-client/spec"
 	eth2ApiV1Bellatrix "github.com/attestantio/go-eth2-client/api/v1/bellatrix"
@@ -27,13 +29,18 @@ import (
 	"github.com/attestantio/go-eth2-client/spec/bellatrix"
 	"github.com/attestantio/go-eth2-client/spec/capella"
 	"github.com/attestantio/go-eth2-client/spec/deneb"
+	"github.com/attestantio/go-eth2-client/spec/electra"
 	"github.com/attestantio/go-eth2-client/spec/phase0"
 	eth2UtilBellatrix "github.com/attestantio/go-eth2-client/util/bellatrix"
+	"github.com/flashbots/mev-boost/config"
 	"github.com/flashbots/mev-boost/server/mock"
 	"github.com/flashbots/mev-boost/server/params"
 	"github.com/flashbots/mev-boost/server/types"
 	"github.com/holiman/uint256"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/prysmaticlabs/go-bitfield"
+	"github.com/sirupsen/logrus"
+	logrusTest "github.com/sirupsen/logrus/hooks/test"
 	"github.com/stretchr/testify/require"
 )
 
@@ -192,6 +199,46 @@ func TestStatus(t *testing.T) {
 	})
 }
 
+func TestStatusReadinessGate(t *testing.T) {
+	path := "/eth/v1/builder/status"
+
+	t.Run("Not ready until the initial relay check finished", func(t *testing.T) {
+		backend := newTestBackend(t, 1, time.Second)
+		backend.boost.relayCheckStartupTimeout = time.Second
+		backend.relays[0].ResponseDelay = 100 * time.Millisecond
+
+		backend.boost.waitingForRelayCheck.Store(true)
+		done := make(chan struct{})
+		go func() {
+			backend.boost.runStartupRelayCheck()
+			close(done)
+		}()
+
+		rr := backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, http.StatusServiceUnavailable, rr.Code)
+		require.JSONEq(t, `{"code":503,"message":"waiting for initial relay check"}`, rr.Body.String())
+
+		<-done
+		rr = backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("Initial relay check is bounded by the startup timeout", func(t *testing.T) {
+		backend := newTestBackend(t, 1, time.Second)
+		backend.boost.relayCheck = false
+		backend.boost.relayCheckStartupTimeout = 50 * time.Millisecond
+		backend.relays[0].ResponseDelay = 500 * time.Millisecond
+
+		backend.boost.waitingForRelayCheck.Store(true)
+		start := time.Now()
+		backend.boost.runStartupRelayCheck()
+		require.Less(t, time.Since(start), 400*time.Millisecond)
+
+		rr := backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, http.StatusOK, rr.Code)
+	})
+}
+
 func TestRegisterValidator(t *testing.T) {
 	path := "/eth/v1/builder/validators"
 	reg := builderApiV1.SignedValidatorRegistration{
@@ -256,6 +303,49 @@ func TestRegisterValidator(t *testing.T) {
 		require.Equal(t, http.StatusBadGateway, rr.Code)
 		require.Equal(t, 2, backend.relays[0].GetRequestCount(path))
 	})
+
+	t.Run("Relay calls are spread by the jitter", func(t *testing.T) {
+		backend := newTestBackend(t, 2, time.Second)
+		backend.boost.registrationJitter = 100 * time.Millisecond
+
+		start := time.Now()
+		rr := backend.request(t, http.MethodPost, path, payload)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		require.Less(t, time.Since(start), 500*time.Millisecond)
+		require.Eventually(t, func() bool {
+			return backend.relays[0].GetRequestCount(path) == 1 && backend.relays[1].GetRequestCount(path) == 1
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("Oversized batches are rejected", func(t *testing.T) {
+		backend := newTestBackend(t, 1, time.Second)
+		backend.boost.maxRegistrationBatchSize = 2
+
+		// Too many registrations
+		rr := backend.request(t, http.MethodPost, path, []builderApiV1.SignedValidatorRegistration{reg, reg, reg})
+		require.Equal(t, http.StatusRequestEntityTooLarge, rr.Code, rr.Body.String())
+
+		// Content-Length above the limit
+		body := bytes.Repeat([]byte(" "), 2*maxRegistrationJSONSize+1)
+		req, err := http.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+		require.NoError(t, err)
+		rr = httptest.NewRecorder()
+		backend.boost.getRouter().ServeHTTP(rr, req)
+		require.Equal(t, http.StatusRequestEntityTooLarge, rr.Code, rr.Body.String())
+
+		// Body above the limit without Content-Length
+		req, err = http.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+		require.NoError(t, err)
+		req.ContentLength = -1
+		rr = httptest.NewRecorder()
+		backend.boost.getRouter().ServeHTTP(rr, req)
+		require.Equal(t, http.StatusRequestEntityTooLarge, rr.Code, rr.Body.String())
+
+		require.Equal(t, 0, backend.relays[0].GetRequestCount(path))
+
+		rr = backend.request(t, http.MethodPost, path, []builderApiV1.SignedValidatorRegistration{reg, reg})
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	})
 }
 
 func getHeaderPath(slot uint64, parentHash phase0.Hash32, pubkey phase0.BLSPubKey) string {
@@ -291,6 +381,26 @@ func TestGetHeader(t *testing.T) {
 		require.Equal(t, 1, backend.relays[0].GetRequestCount(path))
 	})
 
+	t.Run("Empty 200 response from relay is no bid", func(t *testing.T) {
+		for _, body := range []string{"", "null", " null\n"} {
+			backend := newTestBackend(t, 1, time.Second)
+			backend.relays[0].OverrideHandleGetHeader(func(w http.ResponseWriter, _ *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusOK)
+				_, _ = w.Write([]byte(body))
+			})
+			label := relayLabel(backend.relays[0].RelayEntry)
+			errorsBefore := testutil.ToFloat64(relayRequestErrors.WithLabelValues(label, "getHeader"))
+			violationsBefore := testutil.ToFloat64(relayBidSchemaViolations.WithLabelValues(label, schemaViolationZeroValue))
+
+			rr := backend.request(t, http.MethodGet, path, nil)
+			require.Equal(t, http.StatusNoContent, rr.Code, "body %q: %s", body, rr.Body.String())
+			require.Equal(t, 1, backend.relays[0].GetRequestCount(path))
+			require.InDelta(t, errorsBefore, testutil.ToFloat64(relayRequestErrors.WithLabelValues(label, "getHeader")), 0)
+			require.InDelta(t, violationsBefore, testutil.ToFloat64(relayBidSchemaViolations.WithLabelValues(label, schemaViolationZeroValue)), 0)
+		}
+	})
+
 	t.Run("Bad response from relays", func(t *testing.T) {
 		backend := newTestBackend(t, 2, time.Second)
 		resp := backend.relays[0].MakeGetHeaderResponse(
@@ -377,7 +487,7 @@ func TestGetHeader(t *testing.T) {
 
 		backend := newTestBackend(t, 1, time.Second)
 		rr := backend.request(t, http.MethodGet, invalidPubkeyPath, nil)
-		require.JSONEq(t, `{"code":400,"message":"invalid pubkey"}`+"\n", rr.Body.String())
+		require.JSONEq(t, `{"code":400,"message":"invalid pubkey: 0x1"}`+"\n", rr.Body.String())
 		require.Equal(t, http.StatusBadRequest, rr.Code, rr.Body.String())
 		require.Equal(t, 0, backend.relays[0].GetRequestCount(path))
 	})
@@ -387,7 +497,7 @@ func TestGetHeader(t *testing.T) {
 
 		backend := newTestBackend(t, 1, time.Second)
 		rr := backend.request(t, http.MethodGet, invalidSlotPath, nil)
-		require.JSONEq(t, `{"code":400,"message":"invalid hash"}`+"\n", rr.Body.String())
+		require.JSONEq(t, `{"code":400,"message":"invalid hash: 0x1"}`+"\n", rr.Body.String())
 		require.Equal(t, http.StatusBadRequest, rr.Code, rr.Body.String())
 		require.Equal(t, 0, backend.relays[0].GetRequestCount(path))
 	})
@@ -413,6 +523,56 @@ func TestGetHeaderBids(t *testing.T) {
 		// Create backend and register 3 relays.
 		backend := newTestBackend(t, 3, time.Second)
 
+		// First relay will return signed response with value 12345.
+		backend.relays[0].GetHeaderResponse = backend.relays[0].MakeGetHeaderResponse(
+			12345,
+			"0xa18385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7",
+			"0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7",
+			"0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249",
+			spec.DataVersionDeneb,
+		)
+
+		// First relay will return signed response with value 12347.
+		backend.relays[1].GetHeaderResponse = backend.relays[1].MakeGetHeaderResponse(
+			12347,
+			"0xb28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7",
+			"0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7",
+			"0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249",
+			spec.DataVersionDeneb,
+		)
+
+		// First relay will return signed response with value 12346.
+		backend.relays[2].GetHeaderResponse = backend.relays[2].MakeGetHeaderResponse(
+			12346,
+			"0xc38385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7",
+			"0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7",
+			"0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249",
+			spec.DataVersionDeneb,
+		)
+
+		// Run the request.
+		rr := backend.request(t, http.MethodGet, path, nil)
+
+		// Each relay must have received the request.
+		require.Equal(t, 1, backend.relays[0].GetRequestCount(path))
+		require.Equal(t, 1, backend.relays[1].GetRequestCount(path))
+		require.Equal(t, 1, backend.relays[2].GetRequestCount(path))
+
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+		// Highest value should be 12347, i.e. second relay.
+		resp := new(builderSpec.VersionedSignedBuilderBid)
+		err := json.Unmarshal(rr.Body.Bytes(), resp)
+		require.NoError(t, err)
+		value, err := resp.Value()
+		require.NoError(t, err)
+		require.Equal(t, uint256.NewInt(12347), value)
+	})
+
+	t.Run("Use the lowest value of conflicting bids for the same block hash", func(t *testing.T) {
+		// Create backend and register 3 relays.
+		backend := newTestBackend(t, 3, time.Second)
+
 		// First relay will return signed response with value 12345.
 		backend.relays[0].GetHeaderResponse = backend.relays[0].MakeGetHeaderResponse(
 			12345,
@@ -450,13 +610,13 @@ func TestGetHeaderBids(t *testing.T) {
 
 		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
 
-		// Highest value should be 12347, i.e. second relay.
+		// The relays bid different values for the same block hash, the lowest one is used.
 		resp := new(builderSpec.VersionedSignedBuilderBid)
 		err := json.Unmarshal(rr.Body.Bytes(), resp)
 		require.NoError(t, err)
 		value, err := resp.Value()
 		require.NoError(t, err)
-		require.Equal(t, uint256.NewInt(12347), value)
+		require.Equal(t, uint256.NewInt(12345), value)
 	})
 
 	t.Run("Use header with lowest blockhash if same value", func(t *testing.T) {
@@ -593,6 +753,8 @@ func TestGetPayload(t *testing.T) {
 					BlockNumber:   12345,
 					FeeRecipient:  mock.HexToAddress("0xdb65fEd33dc262Fe09D9a2Ba8F80b329BA25f941"),
 					BaseFeePerGas: uint256.NewInt(100),
+					// Root of the empty transactions list of the mock relay payload
+					TransactionsRoot: phase0.Root(mock.HexToHash("0x7ffe241ea60187fdb0187bfa22de35d1f9bed7ab061d9401fd47e34a54fbede1")),
 				},
 			},
 		},
@@ -720,6 +882,241 @@ func TestCheckRelays(t *testing.T) {
 	})
 }
 
+func TestGetHeaderSlowRelayLogging(t *testing.T) {
+	hash := mock.HexToHash("0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7")
+	pubkey := mock.HexToPubkey(
+		"0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249")
+	path := getHeaderPath(1, hash, pubkey)
+
+	// messages returns the messages logged during a getHeader request
+	messages := func(t *testing.T, threshold time.Duration) []string {
+		t.Helper()
+		backend := newTestBackend(t, 1, time.Second)
+		backend.boost.slowRelayThreshold = threshold
+		logger, hook := logrusTest.NewNullLogger()
+		logger.SetLevel(logrus.DebugLevel)
+		backend.boost.log = logrus.NewEntry(logger)
+
+		rr := backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		messages := []string{}
+		for _, entry := range hook.AllEntries() {
+			messages = append(messages, entry.Message)
+		}
+		return messages
+	}
+
+	t.Run("All responses are logged by default", func(t *testing.T) {
+		logged := messages(t, 0)
+		require.Contains(t, logged, "bid received")
+		require.NotContains(t, logged, "slow relay response")
+	})
+
+	t.Run("Fast responses are not logged", func(t *testing.T) {
+		logged := messages(t, time.Hour)
+		require.NotContains(t, logged, "bid received")
+		require.NotContains(t, logged, "slow relay response")
+	})
+
+	t.Run("Slow responses are logged", func(t *testing.T) {
+		logged := messages(t, time.Nanosecond)
+		require.Contains(t, logged, "bid received")
+		require.Contains(t, logged, "slow relay response")
+	})
+}
+
+func TestGetHeaderBuilderAllowlist(t *testing.T) {
+	hash := mock.HexToHash("0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7")
+	pubkey := mock.HexToPubkey(
+		"0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249")
+	path := getHeaderPath(1, hash, pubkey)
+
+	t.Run("Bid of an allowed builder", func(t *testing.T) {
+		backend := newTestBackend(t, 1, time.Second)
+		backend.boost.builderAllowlist = map[phase0.BLSPubKey]bool{backend.relays[0].RelayEntry.PublicKey: true}
+		rr := backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	})
+
+	t.Run("Bid of another builder", func(t *testing.T) {
+		backend := newTestBackend(t, 1, time.Second)
+		backend.boost.builderAllowlist = map[phase0.BLSPubKey]bool{{0xb1, 0x9c, 0x3f}: true}
+		label := relayLabel(backend.relays[0].RelayEntry)
+		rr := backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, http.StatusNoContent, rr.Code, rr.Body.String())
+		require.InDelta(t, 1, testutil.ToFloat64(relayBidsRejected.WithLabelValues(label, "builder_not_allowed")), 0)
+	})
+}
+
+func TestGetHeaderBuilderPubkey(t *testing.T) {
+	hash := mock.HexToHash("0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7")
+	pubkey := mock.HexToPubkey(
+		"0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249")
+
+	logger, hook := logrusTest.NewNullLogger()
+	backend := newTestBackend(t, 1, time.Second)
+	backend.boost.log = logrus.NewEntry(logger)
+	builder := backend.relays[0].RelayEntry.PublicKey.String()
+	before := testutil.ToFloat64(builderBidsWon.WithLabelValues(builder))
+
+	rr := backend.request(t, http.MethodGet, getHeaderPath(1, hash, pubkey), nil)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.InDelta(t, 1, testutil.ToFloat64(builderBidsWon.WithLabelValues(builder))-before, 0)
+
+	var logged any
+	for _, entry := range hook.AllEntries() {
+		if entry.Message == "best bid" {
+			logged = entry.Data["builderPubkey"]
+		}
+	}
+	require.Equal(t, builder, logged)
+}
+
+func TestGetHeaderSharedDeadline(t *testing.T) {
+	hash := mock.HexToHash("0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7")
+	pubkey := mock.HexToPubkey(
+		"0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249")
+
+	t.Run("Deadline is the earlier of the timeout and the slot deadline", func(t *testing.T) {
+		backend := newTestBackend(t, 1, time.Second)
+		now := time.Unix(1000, 0)
+		require.Equal(t, now.Add(time.Second), backend.boost.getHeaderDeadline(1, now))
+
+		backend.boost.genesisTime = 1000 - config.SlotTimeSec
+		backend.boost.getHeaderSlotDeadline = 500 * time.Millisecond
+		require.Equal(t, now.Add(500*time.Millisecond), backend.boost.getHeaderDeadline(1, now))
+		require.Equal(t, now.Add(time.Second), backend.boost.getHeaderDeadline(2, now))
+	})
+
+	t.Run("Slow relays are cut off at the slot deadline", func(t *testing.T) {
+		backend := newTestBackend(t, 3, time.Second)
+		// Slot 1 started less than a second ago, and relays are cut off 200ms from now
+		backend.boost.genesisTime = uint64(time.Now().Unix()) - config.SlotTimeSec
+		backend.boost.getHeaderSlotDeadline = time.Since(time.Unix(int64(backend.boost.genesisTime+config.SlotTimeSec), 0)) + 200*time.Millisecond
+		backend.relays[1].ResponseDelay = 500 * time.Millisecond
+		backend.relays[2].ResponseDelay = 500 * time.Millisecond
+
+		start := time.Now()
+		rr := backend.request(t, http.MethodGet, getHeaderPath(1, hash, pubkey), nil)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		require.Less(t, time.Since(start), 450*time.Millisecond)
+	})
+
+	t.Run("Relays are sent the budget left until the deadline", func(t *testing.T) {
+		backend := newTestBackend(t, 1, 2*time.Second)
+		backend.boost.genesisTime = uint64(time.Now().Unix()) - config.SlotTimeSec
+		slotStart := time.Unix(int64(backend.boost.genesisTime+config.SlotTimeSec), 0)
+		backend.boost.getHeaderSlotDeadline = time.Since(slotStart) + 900*time.Millisecond
+		cutoff := slotStart.Add(backend.boost.getHeaderSlotDeadline).UnixMilli()
+
+		headers := make(chan http.Header, 2)
+		backend.relays[0].OverrideHandleGetHeader(func(w http.ResponseWriter, req *http.Request) {
+			headers <- req.Header.Clone()
+			w.WriteHeader(http.StatusNoContent)
+		})
+		budget := func(parentHash phase0.Hash32) int64 {
+			t.Helper()
+			rr := backend.request(t, http.MethodGet, getHeaderPath(1, parentHash, pubkey), nil)
+			require.Equal(t, http.StatusNoContent, rr.Code, rr.Body.String())
+			header := <-headers
+			require.Equal(t, strconv.FormatInt(cutoff, 10), header.Get(HeaderKeyCutoffUnixMS))
+			budget, err := strconv.ParseInt(header.Get(HeaderKeyBudgetMS), 10, 64)
+			require.NoError(t, err)
+			return budget
+		}
+
+		early := budget(hash)
+		require.LessOrEqual(t, early, int64(900))
+		require.Greater(t, early, int64(600))
+
+		// A request later in the slot has less time left
+		time.Sleep(300 * time.Millisecond)
+		late := budget(mock.HexToHash("0x1f8b6ba5b6b3f4b2d4b9fb6a1b0d4c7d8e3a6f5e2c1b0a9f8e7d6c5b4a392817"))
+		require.LessOrEqual(t, late, early-300)
+	})
+
+	t.Run("Budget is never negative", func(t *testing.T) {
+		now := time.Unix(1000, 0)
+		headers := map[string]string{HeaderKeySlotUID: "uid"}
+		require.Equal(t, "250", withBudget(headers, now.Add(250*time.Millisecond), now)[HeaderKeyBudgetMS])
+		require.Equal(t, "0", withBudget(headers, now.Add(-time.Second), now)[HeaderKeyBudgetMS])
+		require.Equal(t, map[string]string{HeaderKeySlotUID: "uid"}, headers, "the shared headers are not modified")
+	})
+}
+
+func TestGetHeaderServeCachedBid(t *testing.T) {
+	hash := mock.HexToHash("0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7")
+	pubkey := mock.HexToPubkey(
+		"0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249")
+	path := getHeaderPath(1, hash, pubkey)
+
+	// requestAgain requests a bid, and then requests the second path after the relay started failing
+	requestAgain := func(t *testing.T, serveCachedBid bool, genesisTime uint64, maxAge time.Duration, secondPath string) *httptest.ResponseRecorder {
+		t.Helper()
+		backend := newTestBackend(t, 1, time.Second)
+		backend.boost.serveCachedBid = serveCachedBid
+		backend.boost.cachedBidMaxAge = maxAge
+		backend.boost.genesisTime = genesisTime
+
+		rr := backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+		backend.relays[0].OverrideHandleGetHeader(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		})
+		time.Sleep(10 * time.Millisecond)
+		return backend.request(t, http.MethodGet, secondPath, nil)
+	}
+	requestTwice := func(t *testing.T, serveCachedBid bool, genesisTime uint64) *httptest.ResponseRecorder {
+		t.Helper()
+		return requestAgain(t, serveCachedBid, genesisTime, 0, path)
+	}
+
+	// Slot 1 started a second ago
+	currentGenesisTime := uint64(time.Now().Unix()) - config.SlotTimeSec - 1
+
+	t.Run("Disabled by default", func(t *testing.T) {
+		rr := requestTwice(t, false, currentGenesisTime)
+		require.Equal(t, http.StatusNoContent, rr.Code)
+	})
+
+	t.Run("Cached bid is served when all relays fail", func(t *testing.T) {
+		servedFromCache := testutil.ToFloat64(getHeaderRelayFailureResponses.WithLabelValues("served_from_cache"))
+		rr := requestTwice(t, true, currentGenesisTime)
+		require.InDelta(t, servedFromCache+1, testutil.ToFloat64(getHeaderRelayFailureResponses.WithLabelValues("served_from_cache")), 0)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		resp := new(builderSpec.VersionedSignedBuilderBid)
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), resp))
+		blockHash, err := resp.BlockHash()
+		require.NoError(t, err)
+		require.Equal(t, hash, blockHash)
+	})
+
+	t.Run("Cached bid is not served after the slot", func(t *testing.T) {
+		rr := requestTwice(t, true, 0)
+		require.Equal(t, http.StatusNoContent, rr.Code)
+	})
+
+	t.Run("Cached bid is not served after the maximum age", func(t *testing.T) {
+		rr := requestAgain(t, true, currentGenesisTime, time.Millisecond, path)
+		require.Equal(t, http.StatusNoContent, rr.Code)
+
+		rr = requestAgain(t, true, currentGenesisTime, time.Minute, path)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	})
+
+	t.Run("Cached bid is not served for another parent hash or proposer", func(t *testing.T) {
+		otherHash := mock.HexToHash("0xcf8e0d4e9587369b2301d0790347320302cc0943d5a1884560367e8208d920f2")
+		rr := requestAgain(t, true, currentGenesisTime, 0, getHeaderPath(1, otherHash, pubkey))
+		require.Equal(t, http.StatusNoContent, rr.Code)
+
+		otherPubkey := mock.HexToPubkey(
+			"0xb5246e299aeb782fbc7c91b41b3284245b1ed5206134b0028b81dfb974e5900616c67847c2354479934fc4bb75519ee1")
+		rr = requestAgain(t, true, currentGenesisTime, 0, getHeaderPath(1, hash, otherPubkey))
+		require.Equal(t, http.StatusNoContent, rr.Code)
+	})
+}
+
 func TestEmptyTxRoot(t *testing.T) {
 	transactions := eth2UtilBellatrix.ExecutionPayloadTransactions{Transactions: []bellatrix.Transaction{}}
 	txroot, _ := transactions.HashTreeRoot()
@@ -727,6 +1124,25 @@ func TestEmptyTxRoot(t *testing.T) {
 	require.Equal(t, "0x7ffe241ea60187fdb0187bfa22de35d1f9bed7ab061d9401fd47e34a54fbede1", txRootHex)
 }
 
+// setTransactionsRoot sets the transactions root of the block to the one of the payload response
+func setTransactionsRoot(t *testing.T, signedBlock any, response *builderApi.VersionedSubmitBlindedBlockResponse) {
+	t.Helper()
+	delivered, err := getDeliveredPayload(response)
+	require.NoError(t, err)
+	root, err := (&eth2UtilBellatrix.ExecutionPayloadTransactions{Transactions: delivered.transactions}).HashTreeRoot()
+	require.NoError(t, err)
+	switch block := signedBlock.(type) {
+	case *eth2ApiV1Bellatrix.SignedBlindedBeaconBlock:
+		block.Message.Body.ExecutionPayloadHeader.TransactionsRoot = root
+	case *eth2ApiV1Capella.SignedBlindedBeaconBlock:
+		block.Message.Body.ExecutionPayloadHeader.TransactionsRoot = root
+	case *eth2ApiV1Deneb.SignedBlindedBeaconBlock:
+		block.Message.Body.ExecutionPayloadHeader.TransactionsRoot = root
+	case *eth2ApiV1Electra.SignedBlindedBeaconBlock:
+		block.Message.Body.ExecutionPayloadHeader.TransactionsRoot = root
+	}
+}
+
 func blindedBlockToBlockResponse(signedBlock any) *builderApi.VersionedSubmitBlindedBlockResponse {
 	switch block := signedBlock.(type) {
 	case *eth2ApiV1Bellatrix.SignedBlindedBeaconBlock:
@@ -878,6 +1294,8 @@ func TestGetPayloadForks(t *testing.T) {
 			backend := newTestBackend(t, 1, time.Second)
 			// Prepare getPayload response
 			backend.relays[0].GetPayloadResponse = blindedBlockToBlockResponse(signedBlindedBeaconBlock)
+			setTransactionsRoot(t, signedBlindedBeaconBlock, backend.relays[0].GetPayloadResponse)
+			setTransactionsRoot(t, signedBlindedBeaconBlock, backend.relays[0].GetPayloadResponse)
 			// call getPayload, ensure it's only called on relay 0 (origin of the bid)
 			rr := backend.request(t, http.MethodPost, params.PathGetPayload, signedBlindedBeaconBlock)
 			require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
@@ -891,6 +1309,158 @@ func TestGetPayloadForks(t *testing.T) {
 	}
 }
 
+// electraExecutionRequests returns execution requests with one request of each type
+func electraExecutionRequests() *electra.ExecutionRequests {
+	withdrawalCredentials := mock.HexToHash("0x010000000000000000000000db65fed33dc262fe09d9a2ba8f80b329ba25f941")
+	return &electra.ExecutionRequests{
+		Deposits: []*electra.DepositRequest{{
+			Pubkey:                mock.HexToPubkey("0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249"),
+			WithdrawalCredentials: withdrawalCredentials[:],
+			Amount:                32_000_000_000,
+			Signature:             phase0.BLSSignature{0x01, 0x02},
+			Index:                 1024,
+		}},
+		Withdrawals: []*electra.WithdrawalRequest{{
+			SourceAddress:   mock.HexToAddress("0xdb65fEd33dc262Fe09D9a2Ba8F80b329BA25f941"),
+			ValidatorPubkey: phase0.BLSPubKey{0xb1, 0x9c, 0x3f},
+			Amount:          1_000_000_000,
+		}},
+		Consolidations: []*electra.ConsolidationRequest{{
+			SourceAddress: mock.HexToAddress("0xdb65fEd33dc262Fe09D9a2Ba8F80b329BA25f941"),
+			SourcePubkey:  phase0.BLSPubKey{0xb1, 0x9c, 0x3f},
+			TargetPubkey:  mock.HexToPubkey("0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249"),
+		}},
+	}
+}
+
+func TestGetPayloadElectra(t *testing.T) {
+	// loadBlock returns the electra testdata block, with execution requests and a blob commitment
+	loadBlock := func(t *testing.T) *eth2ApiV1Electra.SignedBlindedBeaconBlock {
+		t.Helper()
+		jsonFile, err := os.Open("../testdata/signed-blinded-beacon-block-electra.json")
+		require.NoError(t, err)
+		defer jsonFile.Close()
+		block := new(eth2ApiV1Electra.SignedBlindedBeaconBlock)
+		require.NoError(t, DecodeJSON(jsonFile, block))
+		block.Message.Body.ExecutionRequests = electraExecutionRequests()
+		block.Message.Body.BlobKZGCommitments = []deneb.KZGCommitment{{0xa1, 0xb2}}
+		return block
+	}
+
+	// payloadResponse returns a getPayload response with transactions, withdrawals and a blob
+	payloadResponse := func(t *testing.T, block *eth2ApiV1Electra.SignedBlindedBeaconBlock) *builderApi.VersionedSubmitBlindedBlockResponse {
+		t.Helper()
+		response := blindedBlockToBlockResponse(block)
+		response.Electra.ExecutionPayload.Transactions = []bellatrix.Transaction{{0x02, 0xf8, 0x72}, {0x03, 0xf9, 0x01}}
+		response.Electra.ExecutionPayload.Withdrawals = []*capella.Withdrawal{{
+			Index:          7,
+			ValidatorIndex: 42,
+			Address:        mock.HexToAddress("0xdb65fEd33dc262Fe09D9a2Ba8F80b329BA25f941"),
+			Amount:         12_345,
+		}}
+		response.Electra.BlobsBundle.Blobs[0][0] = 0xff
+		response.Electra.BlobsBundle.Proofs[0] = deneb.KZGProof{0xc3}
+		setTransactionsRoot(t, block, response)
+		return response
+	}
+
+	t.Run("All fields of the payload are forwarded", func(t *testing.T) {
+		block := loadBlock(t)
+		backend := newTestBackend(t, 1, time.Second)
+		response := payloadResponse(t, block)
+		backend.relays[0].GetPayloadResponse = response
+
+		rr := backend.request(t, http.MethodPost, params.PathGetPayload, block)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		require.Equal(t, "electra", rr.Header().Get(HeaderEthConsensusVersion))
+
+		expected, err := json.Marshal(response)
+		require.NoError(t, err)
+		require.JSONEq(t, string(expected), rr.Body.String())
+	})
+
+	t.Run("Execution requests are verified against the bid", func(t *testing.T) {
+		block := loadBlock(t)
+		bid := bidResp{response: builderSpec.VersionedSignedBuilderBid{
+			Version: spec.DataVersionElectra,
+			Electra: &builderApiElectra.SignedBuilderBid{
+				Message: &builderApiElectra.BuilderBid{ExecutionRequests: electraExecutionRequests()},
+			},
+		}}
+		require.NoError(t, verifyExecutionRequests(block, bid))
+		require.NoError(t, verifyExecutionRequests(block, bidResp{}))
+
+		bid.response.Electra.Message.ExecutionRequests.Withdrawals[0].Amount++
+		require.ErrorIs(t, verifyExecutionRequests(block, bid), errInvalidExecutionRequests)
+
+		block.Message.Body.ExecutionRequests = nil
+		require.ErrorIs(t, verifyExecutionRequests(block, bid), errMissingExecutionRequests)
+	})
+}
+
+// loadDenebBlock returns the deneb testdata block, and a matching payload response
+func loadDenebBlock(t *testing.T) (*eth2ApiV1Deneb.SignedBlindedBeaconBlock, *builderApi.VersionedSubmitBlindedBlockResponse) {
+	t.Helper()
+	jsonFile, err := os.Open("../testdata/signed-blinded-beacon-block-deneb.json")
+	require.NoError(t, err)
+	defer jsonFile.Close()
+	block := new(eth2ApiV1Deneb.SignedBlindedBeaconBlock)
+	require.NoError(t, DecodeJSON(jsonFile, block))
+	response := blindedBlockToBlockResponse(block)
+	setTransactionsRoot(t, block, response)
+	return block, response
+}
+
+func TestValidationLevel(t *testing.T) {
+	testCases := []struct {
+		name   string
+		level  ValidationLevel
+		modify func(response *builderApi.VersionedSubmitBlindedBlockResponse)
+		code   int
+	}{
+		{name: "Strict accepts a valid payload", level: ValidationLevelStrict, modify: func(*builderApi.VersionedSubmitBlindedBlockResponse) {}, code: http.StatusOK},
+		{name: "Strict rejects a transactions root mismatch", level: ValidationLevelStrict, modify: modifyTransactions, code: http.StatusBadGateway},
+		{name: "Basic ignores a transactions root mismatch", level: ValidationLevelBasic, modify: modifyTransactions, code: http.StatusOK},
+		{name: "Basic rejects a block hash mismatch", level: ValidationLevelBasic, modify: modifyBlockHash, code: http.StatusBadGateway},
+		{name: "Basic rejects a KZG commitment mismatch", level: ValidationLevelBasic, modify: modifyKZGCommitment, code: http.StatusBadGateway},
+		{name: "None ignores a block hash mismatch", level: ValidationLevelNone, modify: modifyBlockHash, code: http.StatusOK},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			block, response := loadDenebBlock(t)
+			tt.modify(response)
+			backend := newTestBackend(t, 1, time.Second)
+			backend.boost.validationLevel = tt.level
+			backend.relays[0].GetPayloadResponse = response
+
+			rr := backend.request(t, http.MethodPost, params.PathGetPayload, block)
+			require.Equal(t, tt.code, rr.Code, rr.Body.String())
+		})
+	}
+
+	t.Run("Invalid level", func(t *testing.T) {
+		_, err := NewBoostService(BoostServiceOpts{
+			Log:                   mock.TestLog,
+			Relays:                []types.RelayEntry{mock.NewRelay(t).RelayEntry},
+			GenesisForkVersionHex: "0x00000000",
+			ValidationLevel:       "paranoid",
+		})
+		require.ErrorIs(t, err, errInvalidValidationLevel)
+	})
+}
+
+func modifyTransactions(response *builderApi.VersionedSubmitBlindedBlockResponse) {
+	response.Deneb.ExecutionPayload.Transactions = []bellatrix.Transaction{{0x02, 0xf8, 0x72}}
+}
+
+func modifyBlockHash(response *builderApi.VersionedSubmitBlindedBlockResponse) {
+	response.Deneb.ExecutionPayload.BlockHash = phase0.Hash32{0x99}
+}
+
+func modifyKZGCommitment(response *builderApi.VersionedSubmitBlindedBlockResponse) {
+	response.Deneb.BlobsBundle.Commitments[0] = deneb.KZGCommitment{0x99}
+}
+
 func TestGetPayloadToAllRelays(t *testing.T) {
 	// Load the signed blinded beacon block used for getPayload
 	jsonFile, err := os.Open("../testdata/signed-blinded-beacon-block-deneb.json")
@@ -918,6 +1488,7 @@ func TestGetPayloadToAllRelays(t *testing.T) {
 
 	// Prepare getPayload response
 	backend.relays[0].GetPayloadResponse = blindedBlockToBlockResponse(signedBlindedBeaconBlock)
+	setTransactionsRoot(t, signedBlindedBeaconBlock, backend.relays[0].GetPayloadResponse)
 
 	// call getPayload, ensure it's called to all relays
 	rr = backend.request(t, http.MethodPost, params.PathGetPayload, signedBlindedBeaconBlock)
@@ -925,3 +1496,76 @@ func TestGetPayloadToAllRelays(t *testing.T) {
 	require.Equal(t, 1, backend.relays[0].GetRequestCount(params.PathGetPayload))
 	require.Equal(t, 1, backend.relays[1].GetRequestCount(params.PathGetPayload))
 }
+
+func TestDebugBidsFlush(t *testing.T) {
+	hash := mock.HexToHash("0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7")
+	pubkey := mock.HexToPubkey(
+		"0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249")
+
+	backend := newTestBackend(t, 1, time.Second)
+	backend.boost.adminEndpoints = true
+	backend.boost.adminToken = "secret"
+	rr := backend.request(t, http.MethodGet, getHeaderPath(1, hash, pubkey), nil)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.Equal(t, 1, backend.boost.bids.len())
+
+	// flush requests the flush with the admin token
+	flush := func(token string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, params.PathDebugBidsFlush, nil)
+		req.Header.Set(HeaderKeyAdminToken, token)
+		rr := httptest.NewRecorder()
+		backend.boost.getRouter().ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr = flush("wrong")
+	require.Equal(t, http.StatusUnauthorized, rr.Code, rr.Body.String())
+	require.Equal(t, 1, backend.boost.bids.len())
+
+	rr = flush("secret")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.JSONEq(t, `{"removed":1}`, rr.Body.String())
+	require.Zero(t, backend.boost.bids.len())
+}
+
+func TestMaxCachedSlots(t *testing.T) {
+	hash := mock.HexToHash("0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7")
+	pubkey := mock.HexToPubkey(
+		"0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249")
+
+	backend := newTestBackend(t, 1, time.Second)
+	backend.boost.maxCachedSlots = 2
+	backend.boost.bids.put(1, phase0.Hash32{0x01}, bidResp{slot: 1})
+	backend.boost.bids.put(1, phase0.Hash32{0x02}, bidResp{slot: 1})
+	backend.boost.bids.put(2, phase0.Hash32{0x03}, bidResp{slot: 2})
+	evictions := testutil.ToFloat64(bidCacheEvictions.WithLabelValues("max_slots"))
+
+	// Storing the bid of slot 3 evicts the bids of slot 1
+	rr := backend.request(t, http.MethodGet, getHeaderPath(3, hash, pubkey), nil)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.Equal(t, 2, backend.boost.bids.len())
+	require.ElementsMatch(t, []phase0.Slot{2, 3}, backend.boost.bids.slots())
+	require.InDelta(t, 2, testutil.ToFloat64(bidCacheEvictions.WithLabelValues("max_slots"))-evictions, 0)
+	require.InDelta(t, 2, testutil.ToFloat64(bidCacheEntries), 0)
+
+	// Without a maximum, only the age evicts bids
+	backend.boost.maxCachedSlots = 0
+	backend.boost.bids.put(1, phase0.Hash32{0x01}, bidResp{slot: 1})
+	backend.boost.evictOldestBidSlots()
+	require.Equal(t, 3, backend.boost.bids.len())
+}
+
+func TestEvictExpiredBids(t *testing.T) {
+	backend := newTestBackend(t, 1, time.Second)
+	now := time.Now()
+	backend.boost.storeBid(1, phase0.Hash32{0x01}, bidResp{slot: 1, t: now.Add(-4 * time.Minute)})
+	backend.boost.storeBid(2, phase0.Hash32{0x02}, bidResp{slot: 2, t: now.Add(-time.Minute)})
+	require.InDelta(t, 2, testutil.ToFloat64(bidCacheEntries), 0)
+	evictions := testutil.ToFloat64(bidCacheEvictions.WithLabelValues("expired"))
+
+	backend.boost.evictExpiredBids(now)
+	require.Equal(t, []phase0.Slot{2}, backend.boost.bids.slots())
+	require.InDelta(t, 1, testutil.ToFloat64(bidCacheEvictions.WithLabelValues("expired"))-evictions, 0)
+	require.InDelta(t, 1, testutil.ToFloat64(bidCacheEntries), 0)
+}
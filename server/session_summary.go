@@ -0,0 +1,239 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/flashbots/mev-boost/config"
+	"github.com/flashbots/mev-boost/server/types"
+	"github.com/sirupsen/logrus"
+)
+
+// serverShutdownTimeout is how long the HTTP servers may take to finish their requests on Stop, after the drain
+const serverShutdownTimeout = 5 * time.Second
+
+// sessionStats accumulates what mev-boost did since it started, for the summary logged on shutdown and for
+// Stats. The same methods update the prometheus metrics, so both always agree.
+type sessionStats struct {
+	start time.Time
+
+	mu                  sync.Mutex
+	auctions            uint64
+	bidsServed          uint64
+	registrationBatches uint64
+	registrations       uint64
+	payloadsDelivered   uint64
+	payloadsWithheld    uint64
+	relays              map[string]*RelayStats
+}
+
+// relaySessionStats are the session counters of a relay in the session summary. Payloads are attributed to all
+// relays which delivered the bid.
+type relaySessionStats struct {
+	BidsWon           uint64 `json:"bids_won"`
+	PayloadsDelivered uint64 `json:"payloads_delivered"`
+	PayloadsWithheld  uint64 `json:"payloads_withheld"`
+}
+
+func newSessionStats(start time.Time) *sessionStats {
+	return &sessionStats{
+		start:  start,
+		relays: make(map[string]*RelayStats),
+	}
+}
+
+// relay returns the counters of the relay, s.mu must be held
+func (s *sessionStats) relay(relay types.RelayEntry) *RelayStats {
+	stats, ok := s.relays[relayLabel(relay)]
+	if !ok {
+		stats = &RelayStats{}
+		s.relays[relayLabel(relay)] = stats
+	}
+	return stats
+}
+
+// recordAuction counts a getHeader request, won by the bid of the relays if any
+func (s *sessionStats) recordAuction(winners []types.RelayEntry) {
+	if s == nil {
+		return
+	}
+	auctionsTotal.Inc()
+	if len(winners) > 0 {
+		auctionBidsServed.Inc()
+	}
+	for _, relay := range winners {
+		relayBidsWon.WithLabelValues(relayLabel(relay)).Inc()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.auctions++
+	if len(winners) > 0 {
+		s.bidsServed++
+	}
+	for _, relay := range winners {
+		s.relay(relay).BidsWon++
+	}
+}
+
+// recordRegistrationBatch counts a batch of registrations which was forwarded to at least one relay
+func (s *sessionStats) recordRegistrationBatch(registrations int) {
+	if s == nil {
+		return
+	}
+	registrationBatchesForwarded.Inc()
+	registrationsForwarded.Add(float64(registrations))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.registrationBatches++
+	s.registrations += uint64(registrations)
+}
+
+// recordPayload counts a payload delivered or withheld for a bid of the relays
+func (s *sessionStats) recordPayload(relays []types.RelayEntry, delivered bool) {
+	if s == nil {
+		return
+	}
+	outcome := payloadOutcomeLabel(delivered)
+	payloadsTotal.WithLabelValues(outcome).Inc()
+	for _, relay := range relays {
+		relayPayloads.WithLabelValues(relayLabel(relay), outcome).Inc()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if delivered {
+		s.payloadsDelivered++
+	} else {
+		s.payloadsWithheld++
+	}
+	for _, relay := range relays {
+		if delivered {
+			s.relay(relay).PayloadsDelivered++
+		} else {
+			s.relay(relay).PayloadsWithheld++
+		}
+	}
+}
+
+// sessionSummary is the final accounting of a mev-boost run
+type sessionSummary struct {
+	Version                      string                       `json:"version"`
+	StartedAt                    time.Time                    `json:"started_at"`
+	StoppedAt                    time.Time                    `json:"stopped_at"`
+	UptimeSeconds                int64                        `json:"uptime_seconds"`
+	Auctions                     uint64                       `json:"auctions"`
+	BidsServed                   uint64                       `json:"bids_served"`
+	RegistrationBatchesForwarded uint64                       `json:"registration_batches_forwarded"`
+	PayloadsDelivered            uint64                       `json:"payloads_delivered"`
+	PayloadsWithheld             uint64                       `json:"payloads_withheld"`
+	Relays                       map[string]relaySessionStats `json:"relays"`
+	UnresolvedFailedDeliveries   []failedDelivery             `json:"unresolved_failed_deliveries"`
+	Resources                    *resourceUsage               `json:"resources,omitempty"`
+}
+
+// sessionSummary returns the summary of the session until now, it only includes the subsystems which are set up
+func (m *BoostService) sessionSummary(now time.Time) sessionSummary {
+	summary := sessionSummary{
+		Version:                    config.Version,
+		StoppedAt:                  now,
+		Relays:                     make(map[string]relaySessionStats),
+		UnresolvedFailedDeliveries: []failedDelivery{},
+		Resources:                  m.selfMonitor.usage(),
+	}
+	if m.failedDeliveries != nil {
+		summary.UnresolvedFailedDeliveries = m.failedDeliveries.list()
+	}
+	s := m.session
+	if s == nil {
+		return summary
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	summary.StartedAt = s.start
+	summary.UptimeSeconds = int64(now.Sub(s.start).Seconds())
+	summary.Auctions = s.auctions
+	summary.BidsServed = s.bidsServed
+	summary.RegistrationBatchesForwarded = s.registrationBatches
+	summary.PayloadsDelivered = s.payloadsDelivered
+	summary.PayloadsWithheld = s.payloadsWithheld
+	for relay, stats := range s.relays {
+		relayStats := relaySessionStats{
+			BidsWon:           stats.BidsWon,
+			PayloadsDelivered: stats.PayloadsDelivered,
+			PayloadsWithheld:  stats.PayloadsWithheld,
+		}
+		if relayStats != (relaySessionStats{}) {
+			summary.Relays[relay] = relayStats
+		}
+	}
+	return summary
+}
+
+// Stop drains until the context is done, then shuts down the HTTP servers, waiting up to serverShutdownTimeout for their
+// requests, and logs the session summary and writes it to the session summary file, if configured
+func (m *BoostService) Stop(ctx context.Context) error {
+	// Proposals which already got a header are served before the server shuts down
+	if inFlight, pendingSlots := m.drain.start(); inFlight > 0 || pendingSlots > 0 {
+		m.log.WithFields(logrus.Fields{
+			"inFlightPayloads": inFlight,
+			"pendingSlots":     pendingSlots,
+		}).Info("waiting for in-flight and expected getPayload requests before shutting down")
+	}
+	if err := m.drain.wait(ctx); err != nil {
+		m.log.WithError(err).Warn("shutting down with in-flight getPayload requests")
+	}
+
+	m.srvLock.Lock()
+	srv, metricsSrv := m.srv, m.metricsSrv
+	if m.stopTopBidStreams != nil {
+		m.stopTopBidStreams()
+	}
+	if m.stopConnectionPrewarm != nil {
+		m.stopConnectionPrewarm()
+	}
+	if m.stopSelfMonitor != nil {
+		m.stopSelfMonitor()
+	}
+	m.srvLock.Unlock()
+
+	// The servers get their own budget, which a long drain does not use up
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), serverShutdownTimeout)
+	defer cancel()
+	var err error
+	if srv != nil {
+		err = srv.Shutdown(shutdownCtx)
+	}
+	if metricsSrv != nil {
+		err = errors.Join(err, metricsSrv.Shutdown(shutdownCtx))
+	}
+
+	summary := m.sessionSummary(time.Now())
+	m.log.WithFields(logrus.Fields{
+		"startedAt":                    summary.StartedAt,
+		"uptimeSeconds":                summary.UptimeSeconds,
+		"auctions":                     summary.Auctions,
+		"bidsServed":                   summary.BidsServed,
+		"registrationBatchesForwarded": summary.RegistrationBatchesForwarded,
+		"payloadsDelivered":            summary.PayloadsDelivered,
+		"payloadsWithheld":             summary.PayloadsWithheld,
+		"relays":                       summary.Relays,
+		"unresolvedFailedDeliveries":   len(summary.UnresolvedFailedDeliveries),
+		"resources":                    summary.Resources,
+	}).Info("session summary")
+
+	if m.sessionSummaryFile != "" {
+		data, jsonErr := json.MarshalIndent(summary, "", "  ")
+		if jsonErr == nil {
+			jsonErr = os.WriteFile(m.sessionSummaryFile, data, 0o600)
+		}
+		err = errors.Join(err, jsonErr)
+	}
+	return err
+}
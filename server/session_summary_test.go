@@ -0,0 +1,106 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	builderApiV1 "github.com/attestantio/go-builder-client/api/v1"
+	"github.com/flashbots/mev-boost/server/mock"
+	"github.com/flashbots/mev-boost/server/params"
+	"github.com/flashbots/mev-boost/server/types"
+	"github.com/sirupsen/logrus"
+	logrusTest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionSummary(t *testing.T) {
+	hash := mock.HexToHash("0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7")
+	pubkey := mock.HexToPubkey(
+		"0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249")
+	path := filepath.Join(t.TempDir(), "summary.json")
+
+	backend := newTestBackend(t, 2, time.Second)
+	logger, hook := logrusTest.NewNullLogger()
+	backend.boost.log = logrus.NewEntry(logger)
+	backend.boost.sessionSummaryFile = path
+	relay0, relay1 := backend.relays[0].RelayEntry.Name(), backend.relays[1].RelayEntry.Name()
+
+	// An auction won by the same bid of both relays
+	rr := backend.request(t, http.MethodGet, getHeaderPath(1, hash, pubkey), nil)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	// An auction without bids
+	noBid := func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}
+	backend.relays[0].OverrideHandleGetHeader(noBid)
+	backend.relays[1].OverrideHandleGetHeader(noBid)
+	rr = backend.request(t, http.MethodGet, getHeaderPath(2, hash, pubkey), nil)
+	require.Equal(t, http.StatusNoContent, rr.Code, rr.Body.String())
+
+	// A registration batch
+	rr = backend.request(t, http.MethodPost, params.PathRegisterValidator, []builderApiV1.SignedValidatorRegistration{testRegistration(pubkey)})
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	// A delivered payload and a withheld one, for a bid of the first relay
+	block, response := loadDenebBlock(t)
+	blockHash := block.Message.Body.ExecutionPayloadHeader.BlockHash
+	backend.boost.bids.put(block.Message.Slot, blockHash, bidResp{
+		response: *mock.NewRelay(t).MakeGetHeaderResponse(12345, blockHash.String(), hash.String(), pubkey.String(), 4),
+		relays:   []types.RelayEntry{backend.relays[0].RelayEntry},
+	})
+	backend.relays[0].GetPayloadResponse = response
+	backend.relays[1].GetPayloadResponse = response
+	rr = backend.request(t, http.MethodPost, params.PathGetPayload, block)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	failing := func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+	backend.relays[0].OverrideHandleGetPayload(failing)
+	backend.relays[1].OverrideHandleGetPayload(failing)
+	rr = backend.request(t, http.MethodPost, params.PathGetPayload, block)
+	require.Equal(t, http.StatusBadGateway, rr.Code, rr.Body.String())
+
+	// The HTTP server was never started
+	require.NoError(t, backend.boost.Stop(context.Background()))
+
+	entry := hook.LastEntry()
+	require.Equal(t, "session summary", entry.Message)
+	require.Equal(t, uint64(2), entry.Data["auctions"])
+	require.Equal(t, uint64(1), entry.Data["bidsServed"])
+	require.Equal(t, uint64(1), entry.Data["registrationBatchesForwarded"])
+	require.Equal(t, uint64(1), entry.Data["payloadsDelivered"])
+	require.Equal(t, uint64(1), entry.Data["payloadsWithheld"])
+	require.Equal(t, 1, entry.Data["unresolvedFailedDeliveries"])
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var summary sessionSummary
+	require.NoError(t, json.Unmarshal(data, &summary))
+	require.Equal(t, map[string]relaySessionStats{
+		relay0: {BidsWon: 1, PayloadsDelivered: 1, PayloadsWithheld: 1},
+		relay1: {BidsWon: 1},
+	}, summary.Relays)
+	require.Equal(t, []failedDelivery{{Relay: relay0, Slot: block.Message.Slot, BlockHash: blockHash}}, summary.UnresolvedFailedDeliveries)
+}
+
+func TestSessionSummaryWithoutSubsystems(t *testing.T) {
+	m := &BoostService{log: mock.TestLog}
+	summary := m.sessionSummary(time.Now())
+	require.Zero(t, summary.Auctions)
+	require.Empty(t, summary.Relays)
+	require.Empty(t, summary.UnresolvedFailedDeliveries)
+	require.NoError(t, m.Stop(context.Background()))
+
+	var stats *sessionStats
+	stats.recordAuction([]types.RelayEntry{})
+	stats.recordPayload(nil, true)
+	stats.recordRegistrationBatch(1)
+	require.Nil(t, stats)
+}
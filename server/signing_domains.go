@@ -0,0 +1,138 @@
+package server
+
+import (
+	"math"
+	"time"
+
+	builderSpec "github.com/attestantio/go-builder-client/spec"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/flashbots/go-boost-utils/ssz"
+	"github.com/flashbots/mev-boost/server/types"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// slotsPerEpoch is the number of slots in an epoch
+	slotsPerEpoch = 32
+
+	// signingDomainTransitionEpochs is the number of epochs after the next fork during which bids signed
+	// under the domain of the previous fork version are still accepted
+	signingDomainTransitionEpochs = 2
+)
+
+var relayBidSigningDomains = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "relay_bid_signing_domains_total",
+	Help: "Number of relay bids with a valid signature, by the fork version of the builder domain they were signed under",
+}, []string{"relay", "fork_version"})
+
+var relayBidPubkeys = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "relay_bid_pubkeys_total",
+	Help: "Number of relay bids with a valid signature, by the relay public key which signed them",
+}, []string{"relay", "pubkey"})
+
+// signingDomain is a builder signing domain, and the fork version it was computed from
+type signingDomain struct {
+	forkVersion string
+	domain      phase0.Domain
+}
+
+// signingDomains are the builder signing domains relay bids are verified against. Around a fork relays may sign
+// under the domain of the current or of the next fork version, so both are tried, the more likely one first.
+// All domains are computed once, and the order of the candidates follows the wallclock.
+type signingDomains struct {
+	genesisTime    uint64
+	secondsPerSlot uint64
+	nextForkEpoch  uint64
+
+	beforeFork []signingDomain // current fork version first
+	transition []signingDomain // next fork version first, the current fork version still accepted
+	afterFork  []signingDomain // next fork version only
+}
+
+// newSigningDomains computes the builder signing domains of the current fork version, of the next fork version
+// which activates at nextForkEpoch if set, and of any extra fork versions, which are always accepted last
+func newSigningDomains(genesisTime, secondsPerSlot uint64, currentForkVersionHex, nextForkVersionHex string, nextForkEpoch uint64, extraForkVersionHexes []string) (*signingDomains, error) {
+	compute := func(forkVersionHex string) (signingDomain, error) {
+		domain, err := ComputeDomain(ssz.DomainTypeAppBuilder, forkVersionHex, phase0.Root{}.String())
+		return signingDomain{forkVersion: forkVersionHex, domain: domain}, err
+	}
+
+	current, err := compute(currentForkVersionHex)
+	if err != nil {
+		return nil, err
+	}
+	extra := make([]signingDomain, 0, len(extraForkVersionHexes))
+	for _, forkVersionHex := range extraForkVersionHexes {
+		domain, err := compute(forkVersionHex)
+		if err != nil {
+			return nil, err
+		}
+		extra = append(extra, domain)
+	}
+
+	s := &signingDomains{
+		genesisTime:    genesisTime,
+		secondsPerSlot: secondsPerSlotOrDefault(secondsPerSlot),
+		nextForkEpoch:  nextForkEpoch,
+	}
+	if nextForkVersionHex == "" {
+		s.nextForkEpoch = math.MaxUint64
+		s.beforeFork = append([]signingDomain{current}, extra...)
+		return s, nil
+	}
+	next, err := compute(nextForkVersionHex)
+	if err != nil {
+		return nil, err
+	}
+	s.beforeFork = append([]signingDomain{current, next}, extra...)
+	s.transition = append([]signingDomain{next, current}, extra...)
+	s.afterFork = append([]signingDomain{next}, extra...)
+	return s, nil
+}
+
+// forkVersions returns the current fork version, the next fork version if any, and the extra fork versions
+func (s *signingDomains) forkVersions() (current, next string, extra []string) {
+	current = s.beforeFork[0].forkVersion
+	others := s.beforeFork[1:]
+	if len(s.afterFork) > 0 {
+		next = s.afterFork[0].forkVersion
+		others = s.afterFork[1:]
+	}
+	for _, domain := range others {
+		extra = append(extra, domain.forkVersion)
+	}
+	return current, next, extra
+}
+
+// candidates returns the domains to verify bids against at the time, in order
+func (s *signingDomains) candidates(now time.Time) []signingDomain {
+	var epoch uint64
+	if unix := uint64(now.Unix()); unix > s.genesisTime {
+		epoch = (unix - s.genesisTime) / s.secondsPerSlot / slotsPerEpoch
+	}
+	switch {
+	case epoch < s.nextForkEpoch:
+		return s.beforeFork
+	case epoch < s.nextForkEpoch+signingDomainTransitionEpochs:
+		return s.transition
+	default:
+		return s.afterFork
+	}
+}
+
+// verifyRelaySignature checks the signature of the bid by one of the relay's public keys against each candidate
+// signing domain, and counts the domain and key which matched
+func (m *BoostService) verifyRelaySignature(bid *builderSpec.VersionedSignedBuilderBid, relay types.RelayEntry, pubkey phase0.BLSPubKey) (bool, error) {
+	for _, candidate := range m.signingDomains.candidates(time.Now()) {
+		ok, err := checkRelaySignature(bid, candidate.domain, pubkey)
+		if err != nil {
+			return false, err
+		}
+		if ok {
+			relayBidSigningDomains.WithLabelValues(relayLabel(relay), candidate.forkVersion).Inc()
+			relayBidPubkeys.WithLabelValues(relayLabel(relay), pubkey.String()).Inc()
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,133 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/flashbots/mev-boost/config"
+	"github.com/flashbots/mev-boost/server/mock"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testCurrentForkVersion = "0x00000000"
+	testNextForkVersion    = "0x01000000"
+	testExtraForkVersion   = "0x02000000"
+)
+
+// genesisTimeForEpoch returns a genesis time for which the current wallclock is early in the epoch
+func genesisTimeForEpoch(epoch uint64) uint64 {
+	return uint64(time.Now().Unix()) - epoch*slotsPerEpoch*config.SlotTimeSec - 1
+}
+
+func TestSigningDomainCandidates(t *testing.T) {
+	forkVersions := func(domains []signingDomain) []string {
+		versions := make([]string, len(domains))
+		for i, domain := range domains {
+			versions[i] = domain.forkVersion
+		}
+		return versions
+	}
+
+	t.Run("Without a next fork", func(t *testing.T) {
+		domains, err := newSigningDomains(0, config.SlotTimeSec, testCurrentForkVersion, "", 0, []string{testExtraForkVersion})
+		require.NoError(t, err)
+		require.Equal(t, []string{testCurrentForkVersion, testExtraForkVersion}, forkVersions(domains.candidates(time.Now())))
+	})
+
+	testCases := []struct {
+		name     string
+		epoch    uint64
+		expected []string
+	}{
+		{name: "Before the fork", epoch: 9, expected: []string{testCurrentForkVersion, testNextForkVersion}},
+		{name: "Transition", epoch: 10, expected: []string{testNextForkVersion, testCurrentForkVersion}},
+		{name: "End of the transition", epoch: 10 + signingDomainTransitionEpochs - 1, expected: []string{testNextForkVersion, testCurrentForkVersion}},
+		{name: "After the transition", epoch: 10 + signingDomainTransitionEpochs, expected: []string{testNextForkVersion}},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			domains, err := newSigningDomains(genesisTimeForEpoch(tt.epoch), config.SlotTimeSec, testCurrentForkVersion, testNextForkVersion, 10, nil)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, forkVersions(domains.candidates(time.Now())))
+		})
+	}
+
+	t.Run("Invalid fork version", func(t *testing.T) {
+		_, err := newSigningDomains(0, config.SlotTimeSec, testCurrentForkVersion, "0x0100", 10, nil)
+		require.Error(t, err)
+	})
+}
+
+func TestGetHeaderSigningDomainTransition(t *testing.T) {
+	hash := mock.HexToHash("0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7")
+	pubkey := mock.HexToPubkey(
+		"0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249")
+
+	// The mock relay signs bids under the domain of the current fork version
+	testCases := []struct {
+		name  string
+		epoch uint64
+		code  int
+	}{
+		{name: "Bid signed under the old domain during the transition", epoch: 10, code: http.StatusOK},
+		{name: "Bid signed under the old domain after the transition", epoch: 10 + signingDomainTransitionEpochs, code: http.StatusNoContent},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := newTestBackend(t, 1, time.Second)
+			genesisTime := genesisTimeForEpoch(tt.epoch)
+			domains, err := newSigningDomains(genesisTime, config.SlotTimeSec, testCurrentForkVersion, testNextForkVersion, 10, nil)
+			require.NoError(t, err)
+			backend.boost.signingDomains = domains
+			label := relayLabel(backend.relays[0].RelayEntry)
+			before := testutil.ToFloat64(relayBidSigningDomains.WithLabelValues(label, testCurrentForkVersion))
+
+			slot := genesisTimeForEpoch(0) - genesisTime
+			rr := backend.request(t, http.MethodGet, getHeaderPath(slot/config.SlotTimeSec, hash, pubkey), nil)
+			require.Equal(t, tt.code, rr.Code, rr.Body.String())
+			if tt.code == http.StatusOK {
+				require.InDelta(t, 1, testutil.ToFloat64(relayBidSigningDomains.WithLabelValues(label, testCurrentForkVersion))-before, 0)
+			}
+		})
+	}
+}
+
+func TestGetHeaderRotatedRelayKey(t *testing.T) {
+	hash := mock.HexToHash("0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7")
+	pubkey := mock.HexToPubkey(
+		"0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249")
+	oldKey := mock.HexToPubkey(
+		"0x82f6e7cc57a2ce68ec41321bebc55bcb31945fe66a8e67eb8251425fab4c6a38c10c53210aea9796dd0ba0441b46762a")
+
+	// The relay is configured with an old key, while the mock relay signs bids with its current key
+	testCases := []struct {
+		name           string
+		additionalKeys bool
+		code           int
+	}{
+		{name: "Bid signed by the additional key", additionalKeys: true, code: http.StatusOK},
+		{name: "Bid signed by an unknown key", additionalKeys: false, code: http.StatusNoContent},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := newTestBackend(t, 1, time.Second)
+			signingKey := backend.boost.relays[0].PublicKey
+			backend.boost.relays[0].PublicKey = oldKey
+			if tt.additionalKeys {
+				backend.boost.relays[0].AdditionalPublicKeys = []phase0.BLSPubKey{signingKey}
+			}
+			label := relayLabel(backend.relays[0].RelayEntry)
+			before := testutil.ToFloat64(relayBidPubkeys.WithLabelValues(label, signingKey.String()))
+
+			rr := backend.request(t, http.MethodGet, getHeaderPath(1, hash, pubkey), nil)
+			require.Equal(t, tt.code, rr.Code, rr.Body.String())
+			if tt.code == http.StatusOK {
+				require.InDelta(t, 1, testutil.ToFloat64(relayBidPubkeys.WithLabelValues(label, signingKey.String()))-before, 0)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package server
+
+import (
+	"time"
+
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/flashbots/mev-boost/config"
+)
+
+// secondsPerSlotOrDefault returns the seconds per slot, or the SLOT_SEC environment variable and then the mainnet
+// slot time if zero
+func secondsPerSlotOrDefault(secondsPerSlot uint64) uint64 {
+	if secondsPerSlot == 0 {
+		return config.SlotTimeSec
+	}
+	return secondsPerSlot
+}
+
+// slotTimestamp returns the unix timestamp at which the slot starts, which is also the timestamp of its block
+func (m *BoostService) slotTimestamp(slot phase0.Slot) uint64 {
+	return m.genesisTime + uint64(slot)*m.secondsPerSlot
+}
+
+// slotStart returns the time at which the slot starts
+func (m *BoostService) slotStart(slot phase0.Slot) time.Time {
+	return time.Unix(int64(m.slotTimestamp(slot)), 0)
+}
+
+// msIntoSlot returns how many milliseconds into the slot the time is, negative before the slot starts
+func (m *BoostService) msIntoSlot(slot phase0.Slot, t time.Time) int64 {
+	return t.Sub(m.slotStart(slot)).Milliseconds()
+}
+
+// slotEnd returns the time at which the slot ends
+func (m *BoostService) slotEnd(slot phase0.Slot) time.Time {
+	return time.Unix(int64(m.slotTimestamp(slot+1)), 0)
+}
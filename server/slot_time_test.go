@@ -0,0 +1,47 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/attestantio/go-eth2-client/spec"
+	"github.com/flashbots/mev-boost/server/mock"
+	"github.com/sirupsen/logrus"
+	logrusTest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMsIntoSlot(t *testing.T) {
+	m := &BoostService{genesisTime: 1000, secondsPerSlot: 12}
+	require.Equal(t, int64(1500), m.msIntoSlot(2, time.UnixMilli(1_025_500)))
+	require.Equal(t, int64(-250), m.msIntoSlot(2, time.UnixMilli(1_023_750)), "requests before the slot starts are early")
+}
+
+func TestGetHeaderLogsArrivalInSlot(t *testing.T) {
+	parentHash := mock.HexToHash("0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7")
+	logger, hook := logrusTest.NewNullLogger()
+	backend := newTestBackend(t, 1, time.Second)
+	backend.boost.log = logrus.NewEntry(logger)
+	backend.relays[0].GetHeaderResponse = backend.relays[0].MakeGetHeaderResponse(
+		12345, parentHash.String(), parentHash.String(), feeRecipientTestPubkey, spec.DataVersionDeneb)
+
+	// Slot 10 started two seconds ago
+	const slot = 10
+	backend.boost.genesisTime = uint64(time.Now().Unix()) - slot*backend.boost.secondsPerSlot - 2
+	rr := backend.request(t, http.MethodGet, getHeaderPath(slot, parentHash, mock.HexToPubkey(feeRecipientTestPubkey)), nil)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	entries := 0
+	for _, entry := range hook.AllEntries() {
+		if entry.Data["method"] != "getHeader" {
+			continue
+		}
+		entries++
+		arrival, ok := entry.Data["arrivalMsIntoSlot"].(int64)
+		require.True(t, ok, entry.Message)
+		require.GreaterOrEqual(t, arrival, int64(2000), entry.Message)
+		require.Less(t, arrival, int64(3000), entry.Message)
+	}
+	require.NotZero(t, entries)
+}
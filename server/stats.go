@@ -0,0 +1,188 @@
+package server
+
+import (
+	"time"
+
+	"github.com/flashbots/mev-boost/server/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+// Service counters, updated by the sessionStats methods together with the counters of Stats
+var (
+	auctionsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "auctions_total",
+		Help: "Number of getHeader requests",
+	})
+	auctionBidsServed = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "auction_bids_served_total",
+		Help: "Number of getHeader requests answered with a bid",
+	})
+	registrationBatchesForwarded = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "registration_batches_forwarded_total",
+		Help: "Number of registerValidator requests accepted by at least one relay",
+	})
+	registrationsForwarded = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "registrations_forwarded_total",
+		Help: "Number of validator registrations in the registerValidator requests accepted by at least one relay",
+	})
+	payloadsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "payloads_total",
+		Help: "Number of payloads of known bids, by outcome (delivered or withheld)",
+	}, []string{"outcome"})
+	relayBidsReceived = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "relay_bids_received_total",
+		Help: "Number of relay bids with a valid signature",
+	}, []string{"relay"})
+	relayBidsWon = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "relay_bids_won_total",
+		Help: "Number of getHeader requests won by a bid of the relay",
+	}, []string{"relay"})
+	relayPayloads = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "relay_payloads_total",
+		Help: "Number of payloads for bids of the relay, by outcome (delivered or withheld)",
+	}, []string{"relay", "outcome"})
+	relayRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "relay_request_duration_seconds",
+		Help:    "Duration of the getHeader and getPayload requests to relays which got a response",
+		Buckets: []float64{0.05, 0.1, 0.2, 0.3, 0.5, 0.75, 1, 1.5, 2, 3, 5},
+	}, []string{"relay", "method"})
+)
+
+// ServiceStats is a snapshot of the counters of a BoostService since it started, for programs which embed it
+// without scraping the prometheus metrics. It is a copy, which later requests do not change.
+type ServiceStats struct {
+	StartedAt time.Time
+
+	// Auctions is the number of getHeader requests, BidsServed the number of them answered with a bid
+	Auctions   uint64
+	BidsServed uint64
+
+	// RegistrationBatches is the number of registerValidator requests accepted by at least one relay, and
+	// Registrations the number of validator registrations in them
+	RegistrationBatches uint64
+	Registrations       uint64
+
+	PayloadsDelivered uint64
+	PayloadsWithheld  uint64
+
+	// BidCacheSize is the number of bids kept for getPayload, and PayloadCacheSize the number of delivered
+	// payloads kept for getPayload retries
+	BidCacheSize     int
+	PayloadCacheSize int
+
+	// Relays are the counters by relay label or host
+	Relays map[string]RelayStats
+}
+
+// RelayStats are the counters of a relay. Bids and payloads are attributed to all relays which sent the bid.
+type RelayStats struct {
+	// BidsReceived is the number of bids of the relay with a valid signature
+	BidsReceived      uint64
+	BidsWon           uint64
+	PayloadsDelivered uint64
+	PayloadsWithheld  uint64
+	// RequestFailures is the number of failed requests to the relay, excluding requests aborted by mev-boost
+	RequestFailures uint64
+
+	// Latencies of the getHeader and getPayload requests which got a response
+	GetHeaderLatency  LatencyStats
+	GetPayloadLatency LatencyStats
+}
+
+// LatencyStats summarizes the durations of requests
+type LatencyStats struct {
+	Count uint64
+	Total time.Duration
+	Max   time.Duration
+}
+
+// Mean returns the mean duration, zero without requests
+func (l LatencyStats) Mean() time.Duration {
+	if l.Count == 0 {
+		return 0
+	}
+	return l.Total / time.Duration(l.Count)
+}
+
+func (l *LatencyStats) observe(d time.Duration) {
+	l.Count++
+	l.Total += d
+	l.Max = max(l.Max, d)
+}
+
+func payloadOutcomeLabel(delivered bool) string {
+	if delivered {
+		return "delivered"
+	}
+	return "withheld"
+}
+
+// recordBid counts a bid of the relay with a valid signature
+func (s *sessionStats) recordBid(relay types.RelayEntry) {
+	if s == nil {
+		return
+	}
+	relayBidsReceived.WithLabelValues(relayLabel(relay)).Inc()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.relay(relay).BidsReceived++
+}
+
+// recordLatency records the duration of a getHeader or getPayload request to the relay which got a response
+func (s *sessionStats) recordLatency(relay types.RelayEntry, method string, latency time.Duration) {
+	if s == nil {
+		return
+	}
+	relayRequestDuration.WithLabelValues(relayLabel(relay), method).Observe(latency.Seconds())
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch method {
+	case "getHeader":
+		s.relay(relay).GetHeaderLatency.observe(latency)
+	case "getPayload":
+		s.relay(relay).GetPayloadLatency.observe(latency)
+	}
+}
+
+// recordRequestError counts a failed relay request, see countRelayRequestError, and returns the log fields
+// detailing TLS errors
+func (s *sessionStats) recordRequestError(relay types.RelayEntry, method string, err error) logrus.Fields {
+	fields := countRelayRequestError(relay, method, err)
+	if s == nil || relayRequestAborted(err) {
+		return fields
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.relay(relay).RequestFailures++
+	return fields
+}
+
+// Stats returns a snapshot of the counters of the service. It is safe to call while requests are handled.
+func (m *BoostService) Stats() ServiceStats {
+	stats := ServiceStats{Relays: make(map[string]RelayStats)}
+	if m.bids != nil {
+		stats.BidCacheSize = m.bids.len()
+	}
+	stats.PayloadCacheSize = m.deliveredPayloads.len()
+	s := m.session
+	if s == nil {
+		return stats
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats.StartedAt = s.start
+	stats.Auctions = s.auctions
+	stats.BidsServed = s.bidsServed
+	stats.RegistrationBatches = s.registrationBatches
+	stats.Registrations = s.registrations
+	stats.PayloadsDelivered = s.payloadsDelivered
+	stats.PayloadsWithheld = s.payloadsWithheld
+	for relay, relayStats := range s.relays {
+		stats.Relays[relay] = *relayStats
+	}
+	return stats
+}
@@ -0,0 +1,159 @@
+package server
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	builderApiV1 "github.com/attestantio/go-builder-client/api/v1"
+	"github.com/flashbots/mev-boost/server/mock"
+	"github.com/flashbots/mev-boost/server/params"
+	"github.com/flashbots/mev-boost/server/types"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/stretchr/testify/require"
+)
+
+// scrapeMetrics scrapes the metrics server handler, and returns the values by series, like name{label="value"}
+func scrapeMetrics(t *testing.T) map[string]float64 {
+	t.Helper()
+	rr := httptest.NewRecorder()
+	promhttp.HandlerFor(prometheusRegistry, promhttp.HandlerOpts{}).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	values := make(map[string]float64)
+	scanner := bufio.NewScanner(rr.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		i := strings.LastIndex(line, " ")
+		value, err := strconv.ParseFloat(line[i+1:], 64)
+		require.NoError(t, err, line)
+		values[line[:i]] = value
+	}
+	return values
+}
+
+func TestStatsMatchMetrics(t *testing.T) {
+	hash := mock.HexToHash("0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7")
+	pubkey := mock.HexToPubkey(
+		"0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249")
+	backend := newTestBackend(t, 2, time.Second)
+	backend.boost.requestMaxRetries = 1
+	before := scrapeMetrics(t)
+
+	// An auction won by the same bid of both relays
+	rr := backend.request(t, http.MethodGet, getHeaderPath(1, hash, pubkey), nil)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	// An auction without bids, in which the second relay fails
+	backend.relays[0].OverrideHandleGetHeader(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	backend.relays[1].OverrideHandleGetHeader(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	rr = backend.request(t, http.MethodGet, getHeaderPath(2, hash, pubkey), nil)
+	require.Equal(t, http.StatusNoContent, rr.Code, rr.Body.String())
+
+	// A batch of two registrations
+	registrations := []builderApiV1.SignedValidatorRegistration{testRegistration(pubkey), testRegistration(mock.HexToPubkey(feeRecipientTestPubkey))}
+	rr = backend.request(t, http.MethodPost, params.PathRegisterValidator, registrations)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	// A delivered payload and a withheld one, for a bid of the first relay
+	block, response := loadDenebBlock(t)
+	blockHash := block.Message.Body.ExecutionPayloadHeader.BlockHash
+	backend.boost.bids.put(block.Message.Slot, blockHash, bidResp{
+		response: *mock.NewRelay(t).MakeGetHeaderResponse(12345, blockHash.String(), hash.String(), pubkey.String(), 4),
+		relays:   []types.RelayEntry{backend.relays[0].RelayEntry},
+	})
+	backend.relays[0].GetPayloadResponse = response
+	// The second relay hangs until the payload of the first relay cancels its request, which is not a failure
+	backend.relays[1].OverrideHandleGetPayload(func(_ http.ResponseWriter, req *http.Request) {
+		_, _ = io.ReadAll(req.Body)
+		<-req.Context().Done()
+	})
+	rr = backend.request(t, http.MethodPost, params.PathGetPayload, block)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	failing := func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+	backend.relays[0].OverrideHandleGetPayload(failing)
+	backend.relays[1].OverrideHandleGetPayload(failing)
+	rr = backend.request(t, http.MethodPost, params.PathGetPayload, block)
+	require.Equal(t, http.StatusBadGateway, rr.Code, rr.Body.String())
+
+	stats := backend.boost.Stats()
+	after := scrapeMetrics(t)
+	delta := func(series string) float64 {
+		return after[series] - before[series]
+	}
+
+	require.Equal(t, uint64(2), stats.Auctions)
+	require.Equal(t, uint64(1), stats.BidsServed)
+	require.Equal(t, uint64(1), stats.RegistrationBatches)
+	require.Equal(t, uint64(2), stats.Registrations)
+	require.Equal(t, uint64(1), stats.PayloadsDelivered)
+	require.Equal(t, uint64(1), stats.PayloadsWithheld)
+	// The bid of the first auction and the bid of the payloads
+	require.Equal(t, 2, stats.BidCacheSize)
+	// The slot of the test block ended long ago, so its payload is not kept for retries
+	require.Zero(t, stats.PayloadCacheSize)
+
+	require.InDelta(t, float64(stats.Auctions), delta("auctions_total"), 0)
+	require.InDelta(t, float64(stats.BidsServed), delta("auction_bids_served_total"), 0)
+	require.InDelta(t, float64(stats.RegistrationBatches), delta("registration_batches_forwarded_total"), 0)
+	require.InDelta(t, float64(stats.Registrations), delta("registrations_forwarded_total"), 0)
+	require.InDelta(t, float64(stats.PayloadsDelivered), delta(`payloads_total{outcome="delivered"}`), 0)
+	require.InDelta(t, float64(stats.PayloadsWithheld), delta(`payloads_total{outcome="withheld"}`), 0)
+
+	require.Len(t, stats.Relays, 2)
+	for _, relay := range backend.relays {
+		label := relayLabel(relay.RelayEntry)
+		relayStats := stats.Relays[label]
+		series := func(name string, labels ...string) string {
+			return fmt.Sprintf(`%s{%srelay=%q}`, name, strings.Join(labels, ""), label)
+		}
+		require.Equal(t, uint64(1), relayStats.BidsReceived, label)
+		require.Equal(t, uint64(1), relayStats.BidsWon, label)
+		require.InDelta(t, float64(relayStats.BidsReceived), delta(series("relay_bids_received_total")), 0, label)
+		require.InDelta(t, float64(relayStats.BidsWon), delta(series("relay_bids_won_total")), 0, label)
+		require.InDelta(t, float64(relayStats.PayloadsDelivered), delta(fmt.Sprintf(`relay_payloads_total{outcome="delivered",relay=%q}`, label)), 0, label)
+		require.InDelta(t, float64(relayStats.PayloadsWithheld), delta(fmt.Sprintf(`relay_payloads_total{outcome="withheld",relay=%q}`, label)), 0, label)
+
+		var failures float64
+		for _, method := range []string{"getHeader", "getPayload", "registerValidator", "status"} {
+			failures += delta(series("relay_request_errors_total", fmt.Sprintf("method=%q,", method)))
+		}
+		require.Positive(t, relayStats.RequestFailures, label)
+		require.InDelta(t, float64(relayStats.RequestFailures), failures, 0, label)
+
+		for method, latency := range map[string]LatencyStats{"getHeader": relayStats.GetHeaderLatency, "getPayload": relayStats.GetPayloadLatency} {
+			method := fmt.Sprintf("method=%q,", method)
+			require.InDelta(t, float64(latency.Count), delta(series("relay_request_duration_seconds_count", method)), 0, label)
+			require.InDelta(t, latency.Total.Seconds(), delta(series("relay_request_duration_seconds_sum", method)), 1e-6, label)
+			require.LessOrEqual(t, latency.Mean(), latency.Max)
+		}
+	}
+	require.Equal(t, uint64(1), stats.Relays[relayLabel(backend.relays[0].RelayEntry)].GetPayloadLatency.Count)
+	require.Zero(t, stats.Relays[relayLabel(backend.relays[1].RelayEntry)].GetPayloadLatency.Count)
+	require.Equal(t, uint64(1), stats.Relays[relayLabel(backend.relays[0].RelayEntry)].PayloadsDelivered)
+	require.Equal(t, uint64(1), stats.Relays[relayLabel(backend.relays[0].RelayEntry)].PayloadsWithheld)
+}
+
+func TestStatsWithoutSubsystems(t *testing.T) {
+	m := &BoostService{log: mock.TestLog}
+	stats := m.Stats()
+	require.Zero(t, stats.Auctions)
+	require.Empty(t, stats.Relays)
+	require.Zero(t, LatencyStats{}.Mean())
+}
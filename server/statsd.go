@@ -0,0 +1,105 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// StatsD dialects, dogstatsd adds tags to the metrics
+const (
+	StatsdDialectStatsd    = "statsd"
+	StatsdDialectDogstatsd = "dogstatsd"
+)
+
+// statsdBufferSize is the number of metrics waiting to be sent, further metrics are dropped
+const statsdBufferSize = 1024
+
+var errInvalidStatsdDialect = errors.New("invalid StatsD dialect")
+
+var statsdMetricsDropped = prometheus.NewCounter(prometheus.CounterOpts{
+	Name: "statsd_metrics_dropped_total",
+	Help: "Number of StatsD metrics dropped because the send buffer was full",
+})
+
+// statsdTags are the tags of a StatsD metric, only sent with the dogstatsd dialect
+type statsdTags map[string]string
+
+// statsdExporter sends metrics as StatsD UDP packets. Metrics are queued in a buffered channel and sent in
+// the background, so the request handlers never wait for the network. All methods are no-ops on a nil value.
+type statsdExporter struct {
+	conn      net.Conn
+	prefix    string
+	dogstatsd bool
+	lines     chan string
+}
+
+// newStatsdExporter starts sending metrics to the StatsD server at addr
+func newStatsdExporter(addr, prefix, dialect string) (*statsdExporter, error) {
+	if dialect != StatsdDialectStatsd && dialect != StatsdDialectDogstatsd {
+		return nil, fmt.Errorf("%w: %s", errInvalidStatsdDialect, dialect)
+	}
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	s := &statsdExporter{
+		conn:      conn,
+		prefix:    prefix,
+		dogstatsd: dialect == StatsdDialectDogstatsd,
+		lines:     make(chan string, statsdBufferSize),
+	}
+	go s.run()
+	return s, nil
+}
+
+// run sends the queued metrics, one per packet
+func (s *statsdExporter) run() {
+	for line := range s.lines {
+		// UDP writes only fail locally, e.g. without a route, and there is nobody to report to
+		_, _ = s.conn.Write([]byte(line))
+	}
+}
+
+// count sends a counter metric
+func (s *statsdExporter) count(name string, value int64, tags statsdTags) {
+	if s == nil {
+		return
+	}
+	s.send(name, fmt.Sprintf("%d|c", value), tags)
+}
+
+// timing sends a timer metric in milliseconds
+func (s *statsdExporter) timing(name string, d time.Duration, tags statsdTags) {
+	if s == nil {
+		return
+	}
+	s.send(name, fmt.Sprintf("%d|ms", d.Milliseconds()), tags)
+}
+
+// send formats the metric, and queues it unless the buffer is full
+func (s *statsdExporter) send(name, value string, tags statsdTags) {
+	if s.prefix != "" {
+		name = s.prefix + "." + name
+	}
+	line := name + ":" + value
+	if s.dogstatsd && len(tags) > 0 {
+		pairs := make([]string, 0, len(tags))
+		for key, tag := range tags {
+			pairs = append(pairs, key+":"+tag)
+		}
+		slices.Sort(pairs)
+		line += "|#" + strings.Join(pairs, ",")
+	}
+
+	select {
+	case s.lines <- line:
+	default:
+		statsdMetricsDropped.Inc()
+	}
+}
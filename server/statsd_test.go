@@ -0,0 +1,92 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/flashbots/mev-boost/server/mock"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// listenStatsd returns a local UDP listener, and a function returning the next received metric line
+func listenStatsd(t *testing.T) (net.PacketConn, func() string) {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	next := func() string {
+		t.Helper()
+		buf := make([]byte, 1024)
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+		n, _, err := conn.ReadFrom(buf)
+		require.NoError(t, err)
+		return string(buf[:n])
+	}
+	return conn, next
+}
+
+func TestStatsdExporter(t *testing.T) {
+	t.Run("StatsD dialect", func(t *testing.T) {
+		conn, next := listenStatsd(t)
+		s, err := newStatsdExporter(conn.LocalAddr().String(), "mev_boost", StatsdDialectStatsd)
+		require.NoError(t, err)
+
+		s.count("auctions", 1, statsdTags{"outcome": "bid"})
+		s.timing("relay.latency", 150*time.Millisecond, statsdTags{"relay": "relay.example.com"})
+		require.Equal(t, "mev_boost.auctions:1|c", next())
+		require.Equal(t, "mev_boost.relay.latency:150|ms", next())
+	})
+
+	t.Run("Dogstatsd dialect", func(t *testing.T) {
+		conn, next := listenStatsd(t)
+		s, err := newStatsdExporter(conn.LocalAddr().String(), "", StatsdDialectDogstatsd)
+		require.NoError(t, err)
+
+		s.count("auctions", 1, statsdTags{"outcome": "bid", "tenant": "staking-pool"})
+		require.Equal(t, "auctions:1|c|#outcome:bid,tenant:staking-pool", next())
+	})
+
+	t.Run("Metrics are dropped when the buffer is full", func(t *testing.T) {
+		// Without a running sender, the buffer fills up
+		s := &statsdExporter{lines: make(chan string, 1)}
+		before := testutil.ToFloat64(statsdMetricsDropped)
+		s.count("auctions", 1, nil)
+		s.count("auctions", 1, nil)
+		require.InDelta(t, 1, testutil.ToFloat64(statsdMetricsDropped)-before, 0)
+	})
+
+	t.Run("Invalid dialect", func(t *testing.T) {
+		_, err := newStatsdExporter("127.0.0.1:8125", "", "graphite")
+		require.ErrorIs(t, err, errInvalidStatsdDialect)
+	})
+
+	t.Run("Nil exporter", func(t *testing.T) {
+		var s *statsdExporter
+		s.count("auctions", 1, nil)
+		s.timing("relay.latency", time.Second, nil)
+	})
+}
+
+func TestGetHeaderStatsd(t *testing.T) {
+	conn, next := listenStatsd(t)
+	backend := newTestBackend(t, 1, time.Second)
+	s, err := newStatsdExporter(conn.LocalAddr().String(), "mev_boost", StatsdDialectDogstatsd)
+	require.NoError(t, err)
+	backend.boost.statsd = s
+
+	hash := mock.HexToHash("0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7")
+	pubkey := mock.HexToPubkey(
+		"0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249")
+	rr := backend.request(t, http.MethodGet, getHeaderPath(1, hash, pubkey), nil)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	latency := next()
+	require.True(t, strings.HasPrefix(latency, "mev_boost.relay.latency:"), latency)
+	require.True(t, strings.HasSuffix(latency, "|ms|#method:getHeader,relay:"+relayLabel(backend.relays[0].RelayEntry)), latency)
+	require.Equal(t, "mev_boost.auctions:1|c|#outcome:bid,tenant:unknown", next())
+}
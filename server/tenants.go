@@ -0,0 +1,131 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var errInvalidTenantLabel = errors.New("invalid tenant label")
+
+// tenantUnknown is the tenant label of validators which are not in the tenants file
+const tenantUnknown = "unknown"
+
+// tenantsReloadInterval is how often the tenants file is checked for changes
+const tenantsReloadInterval = 10 * time.Second
+
+// tenantLabelRegex restricts tenant labels to values which are safe to use as metric labels
+var tenantLabelRegex = regexp.MustCompile(`^[a-zA-Z0-9_.-]{1,64}$`)
+
+// tenantPrefix maps validator pubkeys starting with prefix to a tenant
+type tenantPrefix struct {
+	prefix string
+	tenant string
+}
+
+// tenantMap resolves validator pubkeys to tenant labels, as configured in a JSON file which maps full
+// pubkeys or pubkey prefixes to labels. The longest matching prefix wins.
+type tenantMap struct {
+	path string
+
+	mu       sync.RWMutex
+	modTime  time.Time
+	exact    map[string]string
+	prefixes []tenantPrefix // sorted by descending prefix length
+}
+
+// newTenantMap loads the tenants file
+func newTenantMap(path string) (*tenantMap, error) {
+	t := &tenantMap{path: path}
+	if _, err := t.reload(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// reload reads the tenants file again if it was modified, and returns whether it was reloaded.
+// On error the previous mapping is kept.
+func (t *tenantMap) reload() (bool, error) {
+	info, err := os.Stat(t.path)
+	if err != nil {
+		return false, err
+	}
+	t.mu.RLock()
+	unchanged := info.ModTime().Equal(t.modTime)
+	t.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	data, err := os.ReadFile(t.path)
+	if err != nil {
+		return false, err
+	}
+	entries := make(map[string]string)
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return false, fmt.Errorf("could not parse tenants file: %w", err)
+	}
+
+	exact := make(map[string]string)
+	prefixes := []tenantPrefix{}
+	for pubkey, tenant := range entries {
+		if !tenantLabelRegex.MatchString(tenant) {
+			return false, fmt.Errorf("%w: %q", errInvalidTenantLabel, tenant)
+		}
+		pubkey = strings.ToLower(pubkey)
+		if len(pubkey) == 98 {
+			exact[pubkey] = tenant
+		} else {
+			prefixes = append(prefixes, tenantPrefix{pubkey, tenant})
+		}
+	}
+	sort.Slice(prefixes, func(i, j int) bool {
+		return len(prefixes[i].prefix) > len(prefixes[j].prefix)
+	})
+
+	t.mu.Lock()
+	t.modTime = info.ModTime()
+	t.exact = exact
+	t.prefixes = prefixes
+	t.mu.Unlock()
+	return true, nil
+}
+
+// tenant returns the tenant label of the validator pubkey, or tenantUnknown
+func (t *tenantMap) tenant(pubkey string) string {
+	if t == nil {
+		return tenantUnknown
+	}
+	pubkey = strings.ToLower(pubkey)
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if tenant, ok := t.exact[pubkey]; ok {
+		return tenant
+	}
+	for _, p := range t.prefixes {
+		if strings.HasPrefix(pubkey, p.prefix) {
+			return p.tenant
+		}
+	}
+	return tenantUnknown
+}
+
+// watchTenantsFile reloads the tenants file when it changes
+func (m *BoostService) watchTenantsFile() {
+	for {
+		time.Sleep(tenantsReloadInterval)
+		reloaded, err := m.tenants.reload()
+		if err != nil {
+			m.log.WithError(err).Error("could not reload tenants file, keeping the previous mapping")
+		} else if reloaded {
+			m.log.WithField("path", m.tenants.path).Info("reloaded tenants file")
+		}
+	}
+}
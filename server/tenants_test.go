@@ -0,0 +1,100 @@
+package server
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/flashbots/mev-boost/server/mock"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const tenantTestPubkey = "0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249"
+
+func writeTenantsFile(t *testing.T, path, content string, modTime time.Time) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+}
+
+func TestTenantMap(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tenants.json")
+	writeTenantsFile(t, path, `{
+		"0x8A1D7B8DD64E0AAFE7EA7B6C95065C9364CF99D38470C12EE807D55F7DE1529AD29CE2C422E0B65E3D5A05C02CACA249": "exact",
+		"0x8a": "short-prefix",
+		"0x8a1d": "long-prefix"
+	}`, time.Unix(1000, 0))
+
+	tenants, err := newTenantMap(path)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name   string
+		pubkey string
+		tenant string
+	}{
+		{name: "Exact match ignores case", pubkey: tenantTestPubkey, tenant: "exact"},
+		{name: "Longest prefix wins", pubkey: "0x8a1d00", tenant: "long-prefix"},
+		{name: "Shorter prefix", pubkey: "0x8a0000", tenant: "short-prefix"},
+		{name: "Unmapped pubkey", pubkey: "0x9900", tenant: tenantUnknown},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.tenant, tenants.tenant(tt.pubkey))
+		})
+	}
+
+	t.Run("No tenants file", func(t *testing.T) {
+		var tenants *tenantMap
+		require.Equal(t, tenantUnknown, tenants.tenant(tenantTestPubkey))
+	})
+
+	t.Run("Unchanged file is not reloaded", func(t *testing.T) {
+		reloaded, err := tenants.reload()
+		require.NoError(t, err)
+		require.False(t, reloaded)
+	})
+
+	t.Run("Invalid file keeps the previous mapping", func(t *testing.T) {
+		writeTenantsFile(t, path, `{"0x8a": "not a valid label"}`, time.Unix(2000, 0))
+		_, err := tenants.reload()
+		require.ErrorIs(t, err, errInvalidTenantLabel)
+		require.Equal(t, "exact", tenants.tenant(tenantTestPubkey))
+	})
+
+	t.Run("Modified file is reloaded", func(t *testing.T) {
+		writeTenantsFile(t, path, `{"0x8a": "reloaded"}`, time.Unix(3000, 0))
+		reloaded, err := tenants.reload()
+		require.NoError(t, err)
+		require.True(t, reloaded)
+		require.Equal(t, "reloaded", tenants.tenant(tenantTestPubkey))
+	})
+
+	t.Run("Missing file is an error", func(t *testing.T) {
+		_, err := newTenantMap(filepath.Join(t.TempDir(), "missing.json"))
+		require.Error(t, err)
+	})
+}
+
+func TestTenantMetrics(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tenants.json")
+	writeTenantsFile(t, path, `{"0x8a1d": "tenant-metrics-test"}`, time.Unix(1000, 0))
+	tenants, err := newTenantMap(path)
+	require.NoError(t, err)
+
+	backend := newTestBackend(t, 1, time.Second)
+	backend.boost.tenants = tenants
+
+	auctions := testutil.ToFloat64(tenantAuctions.WithLabelValues("tenant-metrics-test"))
+	bidsWon := testutil.ToFloat64(tenantBidsWon.WithLabelValues("tenant-metrics-test"))
+
+	hash := mock.HexToHash("0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7")
+	rr := backend.request(t, http.MethodGet, getHeaderPath(1, hash, mock.HexToPubkey(tenantTestPubkey)), nil)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	require.InDelta(t, auctions+1, testutil.ToFloat64(tenantAuctions.WithLabelValues("tenant-metrics-test")), 0)
+	require.InDelta(t, bidsWon+1, testutil.ToFloat64(tenantBidsWon.WithLabelValues("tenant-metrics-test")), 0)
+}
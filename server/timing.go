@@ -0,0 +1,83 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	timingStageFanout   = "fanout"
+	timingStageFirstBid = "firstbid"
+	timingStageSelected = "selected"
+	timingStagePayload  = "payload"
+	timingStageTotal    = "total"
+)
+
+// timingStage is a named point in time, in milliseconds since the request was received
+type timingStage struct {
+	name string
+	ms   int64
+}
+
+// requestTimer records how long it took a request to reach each stage of processing.
+// It is used for logging, and optionally returned to the beacon node in the timing header.
+type requestTimer struct {
+	start time.Time
+
+	mu     sync.Mutex
+	stages []timingStage
+}
+
+func newRequestTimer() *requestTimer {
+	return &requestTimer{start: time.Now()}
+}
+
+// mark records the stage, unless it has been recorded already
+func (t *requestTimer) mark(name string) {
+	ms := time.Since(t.start).Milliseconds()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, stage := range t.stages {
+		if stage.name == name {
+			return
+		}
+	}
+	t.stages = append(t.stages, timingStage{name: name, ms: ms})
+}
+
+// String returns the stages in the order they were reached, eg. "fanout=2;firstbid=213;selected=655;total=702"
+func (t *requestTimer) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	parts := make([]string, len(t.stages))
+	for i, stage := range t.stages {
+		parts[i] = fmt.Sprintf("%s=%d", stage.name, stage.ms)
+	}
+	return strings.Join(parts, ";")
+}
+
+// logFields returns the stages as log fields
+func (t *requestTimer) logFields() logrus.Fields {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	fields := make(logrus.Fields, len(t.stages))
+	for _, stage := range t.stages {
+		fields["ms_"+stage.name] = stage.ms
+	}
+	return fields
+}
+
+// setTimingHeader marks the end of the request and adds the timing header to the response, if enabled.
+// It must be called before the response status code is written.
+func (m *BoostService) setTimingHeader(w http.ResponseWriter, timer *requestTimer) {
+	timer.mark(timingStageTotal)
+	if m.timingHeader {
+		w.Header().Set(HeaderKeyTiming, timer.String())
+	}
+}
@@ -0,0 +1,91 @@
+package server
+
+import (
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	eth2ApiV1Deneb "github.com/attestantio/go-eth2-client/api/v1/deneb"
+	"github.com/attestantio/go-eth2-client/spec"
+	"github.com/flashbots/mev-boost/server/params"
+	"github.com/stretchr/testify/require"
+)
+
+// parseTimingHeader returns the stage names and values of a timing header, in order
+func parseTimingHeader(t *testing.T, header string) ([]string, []int64) {
+	t.Helper()
+	names := []string{}
+	values := []int64{}
+	for _, part := range strings.Split(header, ";") {
+		kv := strings.Split(part, "=")
+		require.Len(t, kv, 2, header)
+		ms, err := strconv.ParseInt(kv[1], 10, 64)
+		require.NoError(t, err)
+		names = append(names, kv[0])
+		values = append(values, ms)
+	}
+	return names, values
+}
+
+func TestRequestTimer(t *testing.T) {
+	timer := newRequestTimer()
+	timer.mark(timingStageFanout)
+	time.Sleep(5 * time.Millisecond)
+	timer.mark(timingStageFirstBid)
+	timer.mark(timingStageFanout) // already marked, ignored
+	timer.mark(timingStageTotal)
+
+	names, values := parseTimingHeader(t, timer.String())
+	require.Equal(t, []string{timingStageFanout, timingStageFirstBid, timingStageTotal}, names)
+	require.GreaterOrEqual(t, values[1], int64(5))
+	require.Len(t, timer.logFields(), 3)
+}
+
+func TestTimingHeader(t *testing.T) {
+	jsonFile, err := os.Open("../testdata/signed-blinded-beacon-block-deneb.json")
+	require.NoError(t, err)
+	defer jsonFile.Close()
+	signedBlindedBeaconBlock := new(eth2ApiV1Deneb.SignedBlindedBeaconBlock)
+	require.NoError(t, DecodeJSON(jsonFile, &signedBlindedBeaconBlock))
+
+	getHeaderPath := "/eth/v1/builder/header/12345/0xcf8e0d4e9587369b2301d0790347320302cc0943d5a1884560367e8208d920f2/0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249"
+
+	t.Run("Disabled by default", func(t *testing.T) {
+		backend := newTestBackend(t, 1, time.Second)
+		rr := backend.request(t, http.MethodGet, getHeaderPath, nil)
+		require.Empty(t, rr.Header().Get(HeaderKeyTiming))
+	})
+
+	t.Run("Present and monotonic when enabled", func(t *testing.T) {
+		backend := newTestBackend(t, 1, time.Second)
+		backend.boost.timingHeader = true
+		backend.relays[0].ResponseDelay = 10 * time.Millisecond
+		backend.relays[0].GetHeaderResponse = backend.relays[0].MakeGetHeaderResponse(
+			12345,
+			"0xcf8e0d4e9587369b2301d0790347320302cc0943d5a1884560367e8208d920f2",
+			"0xcf8e0d4e9587369b2301d0790347320302cc0943d5a1884560367e8208d920f2",
+			"0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249",
+			spec.DataVersionDeneb,
+		)
+
+		// getHeader
+		rr := backend.request(t, http.MethodGet, getHeaderPath, nil)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		names, values := parseTimingHeader(t, rr.Header().Get(HeaderKeyTiming))
+		require.Equal(t, []string{timingStageFanout, timingStageFirstBid, timingStageSelected, timingStageTotal}, names)
+		require.IsNonDecreasing(t, values)
+		require.GreaterOrEqual(t, values[1], int64(10))
+
+		// getPayload
+		backend.relays[0].GetPayloadResponse = blindedBlockToBlockResponse(signedBlindedBeaconBlock)
+		setTransactionsRoot(t, signedBlindedBeaconBlock, backend.relays[0].GetPayloadResponse)
+		rr = backend.request(t, http.MethodPost, params.PathGetPayload, signedBlindedBeaconBlock)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		names, values = parseTimingHeader(t, rr.Header().Get(HeaderKeyTiming))
+		require.Equal(t, []string{timingStageFanout, timingStagePayload, timingStageTotal}, names)
+		require.IsNonDecreasing(t, values)
+	})
+}
@@ -0,0 +1,232 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/flashbots/mev-boost/config"
+	"github.com/flashbots/mev-boost/server/params"
+	"github.com/flashbots/mev-boost/server/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// Reconnection backoff of top bid streams, reset after a connection lasted for the maximum backoff
+	topBidStreamMinBackoff = time.Second
+	topBidStreamMaxBackoff = 30 * time.Second
+
+	// topBidStreamMaxEventSize is the maximum size of a line of a top bid stream
+	topBidStreamMaxEventSize = 1 << 20
+)
+
+var (
+	errTopBidStreamClosed = errors.New("top bid stream closed by the relay")
+
+	relayTopBidStreamConnected = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "relay_top_bid_stream_connected",
+		Help: "Whether the top bid stream of the relay is connected",
+	}, []string{"relay"})
+	relayTopBidStreamDisconnects = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "relay_top_bid_stream_disconnects_total",
+		Help: "Number of times the top bid stream of the relay disconnected or failed to connect",
+	}, []string{"relay"})
+	relayTopBidStreamBids = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "relay_top_bid_stream_bids_total",
+		Help: "Number of getHeader bids of relays with a top bid stream, by whether the bid came from the stream or a request",
+	}, []string{"relay", "source"})
+)
+
+// topBidEvent is an event of a relay top bid stream, the relay's current top bid for a proposer
+type topBidEvent struct {
+	Slot       phase0.Slot     `json:"slot,string"`
+	ParentHash string          `json:"parent_hash"`
+	Pubkey     string          `json:"pubkey"`
+	Bid        json.RawMessage `json:"bid"`
+}
+
+type topBidKey struct {
+	slot       phase0.Slot
+	parentHash string
+	pubkey     string
+}
+
+// topBidStream consumes the server-sent events top bid stream of a relay, and keeps the top bids of the
+// current and the previous slot while connected. Without a connection, getHeader requests the relay instead.
+type topBidStream struct {
+	relay  types.RelayEntry
+	client http.Client
+	log    *logrus.Entry
+
+	mu        sync.Mutex
+	connected bool
+	slot      phase0.Slot
+	bids      map[topBidKey]json.RawMessage
+}
+
+func newTopBidStream(relay types.RelayEntry, transport http.RoundTripper, log *logrus.Entry) *topBidStream {
+	return &topBidStream{
+		relay: relay,
+		client: http.Client{
+			CheckRedirect: httpClientDisallowRedirects,
+			Transport:     transport,
+		},
+		log:  log.WithField("relay", relayLabel(relay)),
+		bids: make(map[topBidKey]json.RawMessage),
+	}
+}
+
+// bid returns the relay's top bid for the slot, parent hash and proposer, if the stream is connected
+func (s *topBidStream) bid(slot phase0.Slot, parentHash, pubkey string) (json.RawMessage, bool) {
+	if s == nil {
+		return nil, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.connected {
+		return nil, false
+	}
+	bid, ok := s.bids[topBidKey{slot, parentHash, pubkey}]
+	return bid, ok
+}
+
+// run keeps the stream connected until the context is cancelled
+func (s *topBidStream) run(ctx context.Context) {
+	backoff := topBidStreamMinBackoff
+	for {
+		connectedAt := time.Now()
+		err := s.consume(ctx)
+		s.setConnected(false)
+		if ctx.Err() != nil {
+			return
+		}
+		relayTopBidStreamDisconnects.WithLabelValues(relayLabel(s.relay)).Inc()
+		s.log.WithError(err).Warn("top bid stream disconnected, using getHeader requests")
+
+		if time.Since(connectedAt) > topBidStreamMaxBackoff {
+			backoff = topBidStreamMinBackoff
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, topBidStreamMaxBackoff)
+	}
+}
+
+// consume connects to the stream and handles its events until it disconnects
+func (s *topBidStream) consume(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.relay.GetURI(params.PathTopBidStream), nil)
+	if err != nil {
+		return err
+	}
+	for key, value := range s.relay.Headers {
+		req.Header.Set(key, value)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("User-Agent", "mev-boost/"+config.Version)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: %d", errHTTPErrorResponse, resp.StatusCode)
+	}
+	s.setConnected(true)
+	s.log.Info("top bid stream connected")
+
+	// Events are separated by empty lines, other fields than data and comments are ignored
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), topBidStreamMaxEventSize)
+	var data []byte
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		switch {
+		case len(line) == 0 && len(data) > 0:
+			s.handleEvent(data)
+			data = nil
+		case bytes.HasPrefix(line, []byte("data:")):
+			if len(data) > 0 {
+				data = append(data, '\n')
+			}
+			data = append(data, bytes.TrimPrefix(line[len("data:"):], []byte(" "))...)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return errTopBidStreamClosed
+}
+
+// handleEvent stores the top bid of an event, and forgets the bids of slots before the previous slot
+func (s *topBidStream) handleEvent(data []byte) {
+	var event topBidEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		s.log.WithError(err).Warn("could not decode top bid stream event")
+		return
+	}
+	parentHash, _, ok := normalizeHexParam(event.ParentHash, len(phase0.Hash32{}))
+	if !ok {
+		s.log.WithField("parentHash", event.ParentHash).Warn("invalid parent hash in top bid stream event")
+		return
+	}
+	pubkey, _, ok := normalizeHexParam(event.Pubkey, len(phase0.BLSPubKey{}))
+	if !ok {
+		s.log.WithField("pubkey", event.Pubkey).Warn("invalid pubkey in top bid stream event")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if event.Slot+1 < s.slot {
+		return
+	}
+	if event.Slot > s.slot {
+		s.slot = event.Slot
+		for key := range s.bids {
+			if key.slot+1 < event.Slot {
+				delete(s.bids, key)
+			}
+		}
+	}
+	s.bids[topBidKey{event.Slot, parentHash, pubkey}] = event.Bid
+}
+
+// setConnected updates the connection state, the bids of a previous connection are not used again
+func (s *topBidStream) setConnected(connected bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.connected = connected
+	if !connected {
+		clear(s.bids)
+	}
+	value := 0.0
+	if connected {
+		value = 1
+	}
+	relayTopBidStreamConnected.WithLabelValues(relayLabel(s.relay)).Set(value)
+}
+
+// newTopBidStreams creates the top bid streams of the relays which enable them, by relay
+func newTopBidStreams(relays []types.RelayEntry, transport http.RoundTripper, log *logrus.Entry) map[string]*topBidStream {
+	streams := make(map[string]*topBidStream)
+	for _, relay := range relays {
+		if relay.Stream {
+			streams[relay.String()] = newTopBidStream(relay, transport, log)
+		}
+	}
+	return streams
+}
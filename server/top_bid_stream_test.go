@@ -0,0 +1,161 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/attestantio/go-eth2-client/spec"
+	"github.com/flashbots/mev-boost/server/mock"
+	"github.com/flashbots/mev-boost/server/params"
+	"github.com/flashbots/mev-boost/server/types"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testStreamParentHash = "0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7"
+	testStreamPubkey     = "0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249"
+)
+
+func testTopBidEvent(t *testing.T, slot uint64, parentHash, pubkey, bid string) string {
+	t.Helper()
+	event, err := json.Marshal(map[string]any{
+		"slot":        fmt.Sprint(slot),
+		"parent_hash": parentHash,
+		"pubkey":      pubkey,
+		"bid":         json.RawMessage(bid),
+	})
+	require.NoError(t, err)
+	return string(event)
+}
+
+func TestTopBidStream(t *testing.T) {
+	disconnect := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != params.PathTopBidStream || req.Header.Get("Accept") != "text/event-stream" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, ": keep-alive\n\n")
+		fmt.Fprintf(w, "event: top_bid\ndata: %s\n\n", testTopBidEvent(t, 1, strings.ToUpper(testStreamParentHash[2:]), testStreamPubkey, `{"version":"deneb"}`))
+		fmt.Fprintf(w, "data: {invalid\n\n")
+		w.(http.Flusher).Flush()
+		select {
+		case <-disconnect:
+		case <-req.Context().Done():
+		}
+	}))
+	defer server.Close()
+
+	relay, err := types.NewRelayEntry(strings.Replace(server.URL, "http://", "http://"+testStreamPubkey+"@", 1) + "?stream=true")
+	require.NoError(t, err)
+	stream := newTopBidStream(relay, nil, mock.TestLog)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go stream.run(ctx)
+
+	require.Eventually(t, func() bool {
+		_, ok := stream.bid(1, testStreamParentHash, testStreamPubkey)
+		return ok
+	}, time.Second, 10*time.Millisecond)
+	bid, _ := stream.bid(1, testStreamParentHash, testStreamPubkey)
+	require.JSONEq(t, `{"version":"deneb"}`, string(bid))
+	_, ok := stream.bid(2, testStreamParentHash, testStreamPubkey)
+	require.False(t, ok)
+
+	// Without a connection, the bids are not used
+	disconnects := testutil.ToFloat64(relayTopBidStreamDisconnects.WithLabelValues(relayLabel(relay)))
+	close(disconnect)
+	require.Eventually(t, func() bool {
+		_, ok := stream.bid(1, testStreamParentHash, testStreamPubkey)
+		return !ok
+	}, time.Second, 10*time.Millisecond)
+	require.Eventually(t, func() bool {
+		return testutil.ToFloat64(relayTopBidStreamDisconnects.WithLabelValues(relayLabel(relay))) == disconnects+1
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestTopBidStreamSlots(t *testing.T) {
+	relay, err := types.NewRelayEntry("http://" + testStreamPubkey + "@relay.com")
+	require.NoError(t, err)
+	stream := newTopBidStream(relay, nil, mock.TestLog)
+	stream.setConnected(true)
+
+	stream.handleEvent([]byte(testTopBidEvent(t, 1, testStreamParentHash, testStreamPubkey, `1`)))
+	stream.handleEvent([]byte(testTopBidEvent(t, 2, testStreamParentHash, testStreamPubkey, `2`)))
+	stream.handleEvent([]byte(testTopBidEvent(t, 2, testStreamParentHash, testStreamPubkey, `3`)))
+	_, ok := stream.bid(1, testStreamParentHash, testStreamPubkey)
+	require.True(t, ok)
+	bid, ok := stream.bid(2, testStreamParentHash, testStreamPubkey)
+	require.True(t, ok)
+	require.Equal(t, "3", string(bid))
+
+	// Only the bids of the current and the previous slot are kept
+	stream.handleEvent([]byte(testTopBidEvent(t, 3, testStreamParentHash, testStreamPubkey, `4`)))
+	stream.handleEvent([]byte(testTopBidEvent(t, 1, testStreamParentHash, testStreamPubkey, `5`)))
+	_, ok = stream.bid(1, testStreamParentHash, testStreamPubkey)
+	require.False(t, ok)
+	_, ok = stream.bid(2, testStreamParentHash, testStreamPubkey)
+	require.True(t, ok)
+
+	// Events with invalid parameters are ignored
+	stream.handleEvent([]byte(testTopBidEvent(t, 3, "0x1", testStreamPubkey, `6`)))
+	stream.handleEvent([]byte(testTopBidEvent(t, 3, testStreamParentHash, "0x1", `6`)))
+	require.Len(t, stream.bids, 2)
+
+	var nilStream *topBidStream
+	_, ok = nilStream.bid(3, testStreamParentHash, testStreamPubkey)
+	require.False(t, ok)
+}
+
+func TestGetHeaderTopBidStream(t *testing.T) {
+	backend := newTestBackend(t, 1, time.Second)
+	relay := backend.relays[0].RelayEntry
+	relay.Stream = true
+	backend.boost.relays = []types.RelayEntry{relay}
+	stream := newTopBidStream(relay, nil, mock.TestLog)
+	backend.boost.topBidStreams = map[string]*topBidStream{relay.String(): stream}
+
+	bid, err := json.Marshal(backend.relays[0].MakeGetHeaderResponse(
+		12345,
+		"0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7",
+		testStreamParentHash,
+		relay.PublicKey.String(),
+		spec.DataVersionDeneb,
+	))
+	require.NoError(t, err)
+	stream.setConnected(true)
+	stream.handleEvent([]byte(testTopBidEvent(t, 1, testStreamParentHash, testStreamPubkey, string(bid))))
+
+	path := getHeaderPath(1, mock.HexToHash(testStreamParentHash), mock.HexToPubkey(testStreamPubkey))
+	streamBids := relayTopBidStreamBids.WithLabelValues(relayLabel(relay), "stream")
+	requestBids := relayTopBidStreamBids.WithLabelValues(relayLabel(relay), "request")
+	streamBefore, requestBefore := testutil.ToFloat64(streamBids), testutil.ToFloat64(requestBids)
+
+	// The streamed bid is used without a request
+	rr := backend.request(t, http.MethodGet, path, nil)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.JSONEq(t, string(bid), rr.Body.String())
+	require.Equal(t, 0, backend.relays[0].GetRequestCount(path))
+	require.InDelta(t, streamBefore+1, testutil.ToFloat64(streamBids), 0)
+
+	// Without a bid for the slot, the relay is requested
+	rr = backend.request(t, http.MethodGet, getHeaderPath(2, mock.HexToHash(testStreamParentHash), mock.HexToPubkey(testStreamPubkey)), nil)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.InDelta(t, requestBefore+1, testutil.ToFloat64(requestBids), 0)
+
+	// After the stream disconnected, the relay is requested
+	stream.setConnected(false)
+	rr = backend.request(t, http.MethodGet, path, nil)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.Equal(t, 1, backend.relays[0].GetRequestCount(path))
+	require.InDelta(t, streamBefore+1, testutil.ToFloat64(streamBids), 0)
+	require.InDelta(t, requestBefore+2, testutil.ToFloat64(requestBids), 0)
+}
@@ -7,3 +7,36 @@ var ErrMissingRelayPubkey = errors.New("missing relay public key")
 
 // ErrPointAtInfinityPubkey is returned if a new RelayEntry URL has point-at-infinity public key.
 var ErrPointAtInfinityPubkey = errors.New("relay public key cannot be the point-at-infinity")
+
+// ErrDuplicateRelayPubkey is returned if a new RelayEntry URL has the same public key more than once.
+var ErrDuplicateRelayPubkey = errors.New("duplicate relay public key")
+
+// ErrInvalidRelayPriority is returned if a new RelayEntry URL has a priority which is not an integer.
+var ErrInvalidRelayPriority = errors.New("relay priority must be an integer")
+
+// ErrInvalidRelayStream is returned if a new RelayEntry URL has a stream option which is not a boolean.
+var ErrInvalidRelayStream = errors.New("relay stream option must be true or false")
+
+// ErrInvalidRelaySniffGzip is returned if a new RelayEntry URL has a sniff-gzip option which is not a boolean.
+var ErrInvalidRelaySniffGzip = errors.New("relay sniff-gzip option must be true or false")
+
+// ErrInvalidRelayLabel is returned if a new RelayEntry URL has a label which is empty, too long or has invalid characters.
+var ErrInvalidRelayLabel = errors.New("relay label must be 1 to 32 lowercase letters, digits, '.', '_' or '-'")
+
+// ErrInvalidRelayMinOverLocal is returned if a new RelayEntry URL has a min-over-local-pct option which is not a non-negative number.
+var ErrInvalidRelayMinOverLocal = errors.New("relay min-over-local-pct must be a non-negative number")
+
+// ErrInvalidRelayFork is returned if a new RelayEntry URL has a fork option which is not a fork with a builder API.
+var ErrInvalidRelayFork = errors.New("relay fork must be a fork with a builder API, like deneb or electra")
+
+// ErrInvalidRelayWeight is returned if a new RelayEntry URL has a weight which is not a positive integer.
+var ErrInvalidRelayWeight = errors.New("relay weight must be a positive integer")
+
+// ErrInvalidRelayMinBid is returned if a new RelayEntry URL has a min-bid option which is not a number of ETH between 0 and 1000000.
+var ErrInvalidRelayMinBid = errors.New("relay min-bid must be a number of ETH between 0 and 1000000")
+
+// ErrInvalidRelayHeader is returned if a new RelayEntry URL has a header option which is not Name:Value, or a header set by mev-boost.
+var ErrInvalidRelayHeader = errors.New("relay header must be Name:Value, and not a header set by mev-boost")
+
+// ErrInvalidRelayMonitorPathPrefix is returned if a relay monitor URL has a path-prefix option which is not an absolute path.
+var ErrInvalidRelayMonitorPathPrefix = errors.New("relay monitor path-prefix must be an absolute path")
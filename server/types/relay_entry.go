@@ -1,31 +1,137 @@
 package types
 
 import (
+	"maps"
+	"math"
+	"net/http"
 	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 
+	"github.com/attestantio/go-eth2-client/spec"
 	"github.com/attestantio/go-eth2-client/spec/phase0"
 	"github.com/flashbots/go-boost-utils/utils"
+	"github.com/flashbots/mev-boost/common"
 )
 
 // RelayEntry represents a relay that mev-boost connects to.
 type RelayEntry struct {
 	PublicKey phase0.BLSPubKey
 	URL       *url.URL
+
+	// AdditionalPublicKeys are also accepted for the bids of the relay, e.g. while it rotates its key
+	AdditionalPublicKeys []phase0.BLSPubKey
+
+	// Priority makes bids from this relay win over slightly more valuable bids from relays with a lower priority
+	Priority int
+
+	// Stream consumes the top bid stream of the relay, and uses its bids instead of getHeader requests
+	Stream bool
+
+	// SniffGzip decompresses responses of the relay which are gzip compressed without a Content-Encoding header
+	SniffGzip bool
+
+	// Label is the operator's name for the relay, used instead of the host to identify it in logs and metrics
+	Label string
+
+	// MinOverLocalPct is the percentage by which bids from this relay must exceed the local block value,
+	// instead of the global percentage. Nil if the relay has no override.
+	MinOverLocalPct *float64
+
+	// Forks restricts the getHeader requests to the relay to the slots of these forks, if not empty
+	Forks []spec.DataVersion
+
+	// Weight makes the random bid tie-break select this relay proportionally more often, 1 if zero
+	Weight int
+
+	// MinBid is the minimum value of bids from this relay in wei, instead of the global minimum bid.
+	// Nil if the relay has no override.
+	MinBid *U256Str
+
+	// Headers are added to every request to the relay, e.g. for authentication
+	Headers map[string]string
 }
 
+// maxRelayLabelLength is the maximum length of a relay label
+const maxRelayLabelLength = 32
+
+// maxRelayMinBid is the maximum minimum bid of a relay in ETH, like the global minimum bid
+const maxRelayMinBid = 1000000.0
+
+// headerNameRegex matches the token characters allowed in header names
+var headerNameRegex = regexp.MustCompile("^[A-Za-z0-9!#$%&'*+.^_`|~-]+$")
+
+// reservedRelayHeaders are set by mev-boost or the HTTP client, and cannot be configured for a relay
+var reservedRelayHeaders = []string{"Connection", "Content-Length", "Content-Type", "Host", "Transfer-Encoding", "User-Agent"}
+
 func (r *RelayEntry) String() string {
 	return r.URL.String()
 }
 
-// GetURI returns the full request URI with scheme, host, path and args.
+// Name identifies the relay in logs and metrics: the label if set, otherwise the host
+func (r *RelayEntry) Name() string {
+	if r.Label != "" {
+		return r.Label
+	}
+	return r.URL.Host
+}
+
+// SupportsFork returns true if the relay has no fork allowlist, or if the fork is on it
+func (r *RelayEntry) SupportsFork(fork spec.DataVersion) bool {
+	return len(r.Forks) == 0 || slices.Contains(r.Forks, fork)
+}
+
+// TieBreakWeight returns the weight of the relay in the random bid tie-break
+func (r *RelayEntry) TieBreakWeight() int {
+	return max(r.Weight, 1)
+}
+
+// HasPublicKey returns true if the key is the public key of the relay, or one of its additional public keys
+func (r *RelayEntry) HasPublicKey(pubkey phase0.BLSPubKey) bool {
+	return r.PublicKey == pubkey || slices.Contains(r.AdditionalPublicKeys, pubkey)
+}
+
+// Clone returns a deep copy of the relay entry, which stays usable if the relay list is reloaded
+func (r *RelayEntry) Clone() RelayEntry {
+	clone := *r
+	if r.URL != nil {
+		u := *r.URL // the user info is immutable, and may be shared
+		clone.URL = &u
+	}
+	clone.AdditionalPublicKeys = slices.Clone(r.AdditionalPublicKeys)
+	clone.Forks = slices.Clone(r.Forks)
+	clone.Headers = maps.Clone(r.Headers)
+	if r.MinBid != nil {
+		minBid := *r.MinBid
+		clone.MinBid = &minBid
+	}
+	if r.MinOverLocalPct != nil {
+		pct := *r.MinOverLocalPct
+		clone.MinOverLocalPct = &pct
+	}
+	return clone
+}
+
+// GetURI returns the full request URI with scheme, host, path and args. The path is appended to the
+// path of the URL, so relays mounted under a path prefix work.
 func GetURI(url *url.URL, path string) string {
 	u2 := *url
 	u2.User = nil
-	u2.Path = path
+	u2.Path = joinURLPath(url.Path, path)
+	u2.RawPath = ""
 	return u2.String()
 }
 
+// joinURLPath joins a path prefix and a path with a single slash
+func joinURLPath(prefix, path string) string {
+	if path == "" {
+		return prefix
+	}
+	return strings.TrimSuffix(prefix, "/") + "/" + strings.TrimPrefix(path, "/")
+}
+
 // GetURI returns the full request URI with scheme, host, path and args for the relay.
 func (r *RelayEntry) GetURI(path string) string {
 	return GetURI(r.URL, path)
@@ -61,9 +167,156 @@ func NewRelayEntry(relayURL string) (entry RelayEntry, err error) {
 		return entry, ErrPointAtInfinityPubkey
 	}
 
+	// Extract the mev-boost options from the query, they are not sent to the relay.
+	for _, value := range popQueryParamValues(entry.URL, "pubkey") {
+		pubkey, err := utils.HexToPubkey(value)
+		if err != nil {
+			return entry, err
+		}
+		if pubkey.IsInfinity() {
+			return entry, ErrPointAtInfinityPubkey
+		}
+		if entry.HasPublicKey(pubkey) {
+			return entry, ErrDuplicateRelayPubkey
+		}
+		entry.AdditionalPublicKeys = append(entry.AdditionalPublicKeys, pubkey)
+	}
+	if priority, ok := popQueryParam(entry.URL, "priority"); ok {
+		entry.Priority, err = strconv.Atoi(priority)
+		if err != nil {
+			return entry, ErrInvalidRelayPriority
+		}
+	}
+	if label, ok := popQueryParam(entry.URL, "label"); ok {
+		if !validRelayLabel(label) {
+			return entry, ErrInvalidRelayLabel
+		}
+		entry.Label = label
+	}
+	if stream, ok := popQueryParam(entry.URL, "stream"); ok {
+		entry.Stream, err = strconv.ParseBool(stream)
+		if err != nil {
+			return entry, ErrInvalidRelayStream
+		}
+	}
+	if sniff, ok := popQueryParam(entry.URL, "sniff-gzip"); ok {
+		entry.SniffGzip, err = strconv.ParseBool(sniff)
+		if err != nil {
+			return entry, ErrInvalidRelaySniffGzip
+		}
+	}
+	if pct, ok := popQueryParam(entry.URL, "min-over-local-pct"); ok {
+		minOverLocalPct, err := strconv.ParseFloat(pct, 64)
+		if err != nil || math.IsNaN(minOverLocalPct) || math.IsInf(minOverLocalPct, 0) || minOverLocalPct < 0 {
+			return entry, ErrInvalidRelayMinOverLocal
+		}
+		entry.MinOverLocalPct = &minOverLocalPct
+	}
+	for _, value := range popQueryParamValues(entry.URL, "fork") {
+		fork, err := parseBuilderFork(value)
+		if err != nil {
+			return entry, err
+		}
+		if !slices.Contains(entry.Forks, fork) {
+			entry.Forks = append(entry.Forks, fork)
+		}
+	}
+	if weight, ok := popQueryParam(entry.URL, "weight"); ok {
+		entry.Weight, err = strconv.Atoi(weight)
+		if err != nil || entry.Weight < 1 {
+			return entry, ErrInvalidRelayWeight
+		}
+	}
+	if value, ok := popQueryParam(entry.URL, "min-bid"); ok {
+		minBid, err := strconv.ParseFloat(value, 64)
+		if err != nil || math.IsNaN(minBid) || minBid < 0 || minBid > maxRelayMinBid {
+			return entry, ErrInvalidRelayMinBid
+		}
+		entry.MinBid, err = common.FloatEthTo256Wei(minBid)
+		if err != nil {
+			return entry, ErrInvalidRelayMinBid
+		}
+	}
+	for _, value := range popQueryParamValues(entry.URL, "header") {
+		name, headerValue, err := parseRelayHeader(value)
+		if err != nil {
+			return entry, err
+		}
+		if entry.Headers == nil {
+			entry.Headers = make(map[string]string)
+		}
+		entry.Headers[name] = headerValue
+	}
+
 	return entry, nil
 }
 
+// parseRelayHeader returns the canonical name and the value of a header given as Name:Value
+func parseRelayHeader(header string) (name, value string, err error) {
+	name, value, ok := strings.Cut(header, ":")
+	name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+	value = strings.TrimSpace(value)
+	if !ok || !headerNameRegex.MatchString(name) || slices.Contains(reservedRelayHeaders, name) ||
+		strings.HasPrefix(name, "X-Mevboost-") || strings.ContainsAny(value, "\r\n") {
+		return "", "", ErrInvalidRelayHeader
+	}
+	return name, value, nil
+}
+
+// parseBuilderFork returns the fork of a name, like "electra", which must be a fork with a builder API
+func parseBuilderFork(name string) (spec.DataVersion, error) {
+	var fork spec.DataVersion
+	if err := fork.UnmarshalJSON([]byte(strconv.Quote(strings.ToLower(name)))); err != nil || fork < spec.DataVersionBellatrix {
+		return spec.DataVersionUnknown, ErrInvalidRelayFork
+	}
+	return fork, nil
+}
+
+// validRelayLabel returns true if the label is short and only has characters which are safe in logs and metric labels
+func validRelayLabel(label string) bool {
+	if label == "" || len(label) > maxRelayLabelLength {
+		return false
+	}
+	for _, c := range label {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '.' && c != '_' && c != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+// popQueryParam removes a parameter from the URL query and returns its last value,
+// leaving the order of the remaining parameters untouched.
+func popQueryParam(u *url.URL, key string) (value string, ok bool) {
+	values := popQueryParamValues(u, key)
+	if len(values) == 0 {
+		return "", false
+	}
+	return values[len(values)-1], true
+}
+
+// popQueryParamValues removes a parameter from the URL query and returns all its values in order,
+// leaving the order of the remaining parameters untouched.
+func popQueryParamValues(u *url.URL, key string) []string {
+	if u.RawQuery == "" {
+		return nil
+	}
+	var values []string
+	params := strings.Split(u.RawQuery, "&")
+	remaining := make([]string, 0, len(params))
+	for _, param := range params {
+		k, v, _ := strings.Cut(param, "=")
+		if unescaped, err := url.QueryUnescape(k); err == nil && unescaped == key {
+			value, _ := url.QueryUnescape(v)
+			values = append(values, value)
+			continue
+		}
+		remaining = append(remaining, param)
+	}
+	u.RawQuery = strings.Join(remaining, "&")
+	return values
+}
+
 // RelayEntriesToStrings returns the string representation of a list of relay entries
 func RelayEntriesToStrings(relays []RelayEntry) []string {
 	ret := make([]string, len(relays))
@@ -72,3 +325,12 @@ func RelayEntriesToStrings(relays []RelayEntry) []string {
 	}
 	return ret
 }
+
+// RelayEntriesToNames returns the names of a list of relay entries, as used in logs
+func RelayEntriesToNames(relays []RelayEntry) []string {
+	ret := make([]string, len(relays))
+	for i, entry := range relays {
+		ret[i] = entry.Name()
+	}
+	return ret
+}
@@ -2,8 +2,12 @@ package types
 
 import (
 	"fmt"
+	"net/url"
+	"strings"
 	"testing"
 
+	"github.com/attestantio/go-eth2-client/spec"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
 	"github.com/flashbots/go-boost-utils/types"
 	"github.com/flashbots/go-boost-utils/utils"
 	"github.com/stretchr/testify/require"
@@ -13,6 +17,8 @@ func TestParseRelaysURLs(t *testing.T) {
 	// Used to fake a relay's public key.
 	publicKey, err := utils.HexToPubkey("0x82f6e7cc57a2ce68ec41321bebc55bcb31945fe66a8e67eb8251425fab4c6a38c10c53210aea9796dd0ba0441b46762a")
 	require.NoError(t, err)
+	rotatedKey, err := utils.HexToPubkey("0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249")
+	require.NoError(t, err)
 
 	testCases := []struct {
 		name     string
@@ -23,6 +29,16 @@ func TestParseRelaysURLs(t *testing.T) {
 		expectedURI       string // full URI with scheme, host, path and args
 		expectedPublicKey string
 		expectedURL       string
+		expectedPriority  int
+		expectedStream    bool
+		expectedSniffGzip bool
+		expectedName      string
+		expectedMinOver   *float64
+		expectedAddlKeys  []phase0.BLSPubKey
+		expectedForks     []spec.DataVersion
+		expectedWeight    int
+		expectedMinBid    string // in wei, empty without an override
+		expectedHeaders   map[string]string
 	}{
 		{
 			name:              "Relay URL with protocol scheme",
@@ -91,6 +107,205 @@ func TestParseRelaysURLs(t *testing.T) {
 			expectedPublicKey: publicKey.String(),
 			expectedURL:       fmt.Sprintf("http://%s@foo.com?id=foo&bar=1", publicKey.String()),
 		},
+		{
+			name:              "Relay URL with priority",
+			relayURL:          fmt.Sprintf("http://%s@foo.com?priority=10", publicKey.String()),
+			expectedURI:       "http://foo.com",
+			expectedPublicKey: publicKey.String(),
+			expectedURL:       fmt.Sprintf("http://%s@foo.com", publicKey.String()),
+			expectedPriority:  10,
+		},
+		{
+			name:              "Relay URL with priority and query args",
+			relayURL:          fmt.Sprintf("http://%s@foo.com?id=foo&priority=-1&bar=1", publicKey.String()),
+			expectedURI:       "http://foo.com?id=foo&bar=1",
+			expectedPublicKey: publicKey.String(),
+			expectedURL:       fmt.Sprintf("http://%s@foo.com?id=foo&bar=1", publicKey.String()),
+			expectedPriority:  -1,
+		},
+		{
+			name:              "Relay URL with path prefix",
+			relayURL:          fmt.Sprintf("https://%s@proxy.example.com/relay-a", publicKey.String()),
+			path:              "/eth/v1/builder/status",
+			expectedURI:       "https://proxy.example.com/relay-a/eth/v1/builder/status",
+			expectedPublicKey: publicKey.String(),
+			expectedURL:       fmt.Sprintf("https://%s@proxy.example.com/relay-a", publicKey.String()),
+		},
+		{
+			name:              "Relay URL with path prefix and trailing slash",
+			relayURL:          fmt.Sprintf("https://%s@proxy.example.com/relay-a/", publicKey.String()),
+			path:              "/eth/v1/builder/status",
+			expectedURI:       "https://proxy.example.com/relay-a/eth/v1/builder/status",
+			expectedPublicKey: publicKey.String(),
+			expectedURL:       fmt.Sprintf("https://%s@proxy.example.com/relay-a/", publicKey.String()),
+		},
+		{
+			name:              "Relay URL with nested path prefix, priority and query args",
+			relayURL:          fmt.Sprintf("https://%s@proxy.example.com/relays/a?id=foo&priority=2", publicKey.String()),
+			path:              "/eth/v1/builder/validators",
+			expectedURI:       "https://proxy.example.com/relays/a/eth/v1/builder/validators?id=foo",
+			expectedPublicKey: publicKey.String(),
+			expectedURL:       fmt.Sprintf("https://%s@proxy.example.com/relays/a?id=foo", publicKey.String()),
+			expectedPriority:  2,
+		},
+		{
+			name:              "Relay URL with root path",
+			relayURL:          fmt.Sprintf("http://%s@foo.com/", publicKey.String()),
+			path:              "/eth/v1/builder/status",
+			expectedURI:       "http://foo.com/eth/v1/builder/status",
+			expectedPublicKey: publicKey.String(),
+			expectedURL:       fmt.Sprintf("http://%s@foo.com/", publicKey.String()),
+		},
+		{
+			name:        "Relay URL with invalid priority",
+			relayURL:    fmt.Sprintf("http://%s@foo.com?priority=high", publicKey.String()),
+			expectedErr: ErrInvalidRelayPriority,
+		},
+		{
+			name:              "Relay URL with stream and priority",
+			relayURL:          fmt.Sprintf("https://%s@foo.com?stream=true&id=foo&priority=1", publicKey.String()),
+			expectedURI:       "https://foo.com?id=foo",
+			expectedPublicKey: publicKey.String(),
+			expectedURL:       fmt.Sprintf("https://%s@foo.com?id=foo", publicKey.String()),
+			expectedPriority:  1,
+			expectedStream:    true,
+		},
+		{
+			name:              "Relay URL with label",
+			relayURL:          fmt.Sprintf("https://%s@foo.com:9000/relay-a?label=agnostic-eu.1&priority=1", publicKey.String()),
+			expectedURI:       "https://foo.com:9000/relay-a",
+			expectedPublicKey: publicKey.String(),
+			expectedURL:       fmt.Sprintf("https://%s@foo.com:9000/relay-a", publicKey.String()),
+			expectedPriority:  1,
+			expectedName:      "agnostic-eu.1",
+		},
+		{
+			name:        "Relay URL with empty label",
+			relayURL:    fmt.Sprintf("http://%s@foo.com?label=", publicKey.String()),
+			expectedErr: ErrInvalidRelayLabel,
+		},
+		{
+			name:        "Relay URL with invalid label characters",
+			relayURL:    fmt.Sprintf("http://%s@foo.com?label=My%%20Relay", publicKey.String()),
+			expectedErr: ErrInvalidRelayLabel,
+		},
+		{
+			name:        "Relay URL with too long label",
+			relayURL:    fmt.Sprintf("http://%s@foo.com?label=%s", publicKey.String(), strings.Repeat("a", 33)),
+			expectedErr: ErrInvalidRelayLabel,
+		},
+		{
+			name:        "Relay URL with invalid stream option",
+			relayURL:    fmt.Sprintf("http://%s@foo.com?stream=yes", publicKey.String()),
+			expectedErr: ErrInvalidRelayStream,
+		},
+		{
+			name:              "Relay URL with sniff-gzip",
+			relayURL:          fmt.Sprintf("https://%s@foo.com?sniff-gzip=true&id=foo", publicKey.String()),
+			expectedURI:       "https://foo.com?id=foo",
+			expectedPublicKey: publicKey.String(),
+			expectedURL:       fmt.Sprintf("https://%s@foo.com?id=foo", publicKey.String()),
+			expectedSniffGzip: true,
+		},
+		{
+			name:        "Relay URL with invalid sniff-gzip option",
+			relayURL:    fmt.Sprintf("http://%s@foo.com?sniff-gzip=yes", publicKey.String()),
+			expectedErr: ErrInvalidRelaySniffGzip,
+		},
+		{
+			name:              "Relay URL with min-over-local-pct",
+			relayURL:          fmt.Sprintf("https://%s@foo.com?min-over-local-pct=2.5&id=foo", publicKey.String()),
+			expectedURI:       "https://foo.com?id=foo",
+			expectedPublicKey: publicKey.String(),
+			expectedURL:       fmt.Sprintf("https://%s@foo.com?id=foo", publicKey.String()),
+			expectedMinOver:   func() *float64 { pct := 2.5; return &pct }(),
+		},
+		{
+			name:              "Relay URL with an additional pubkey",
+			relayURL:          fmt.Sprintf("https://%s@foo.com?id=foo&pubkey=%s", publicKey.String(), rotatedKey.String()),
+			expectedURI:       "https://foo.com?id=foo",
+			expectedPublicKey: publicKey.String(),
+			expectedURL:       fmt.Sprintf("https://%s@foo.com?id=foo", publicKey.String()),
+			expectedAddlKeys:  []phase0.BLSPubKey{rotatedKey},
+		},
+		{
+			name:        "Relay URL with a duplicate pubkey",
+			relayURL:    fmt.Sprintf("http://%s@foo.com?pubkey=%s", publicKey.String(), publicKey.String()),
+			expectedErr: ErrDuplicateRelayPubkey,
+		},
+		{
+			name:        "Relay URL with an additional pubkey twice",
+			relayURL:    fmt.Sprintf("http://%s@foo.com?pubkey=%s&pubkey=%s", publicKey.String(), rotatedKey.String(), rotatedKey.String()),
+			expectedErr: ErrDuplicateRelayPubkey,
+		},
+		{
+			name:        "Relay URL with an additional point-at-infinity pubkey",
+			relayURL:    fmt.Sprintf("http://%s@foo.com?pubkey=0xc00000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000", publicKey.String()),
+			expectedErr: ErrPointAtInfinityPubkey,
+		},
+		{
+			name:        "Relay URL with negative min-over-local-pct",
+			relayURL:    fmt.Sprintf("http://%s@foo.com?min-over-local-pct=-1", publicKey.String()),
+			expectedErr: ErrInvalidRelayMinOverLocal,
+		},
+		{
+			name:        "Relay URL with invalid min-over-local-pct",
+			relayURL:    fmt.Sprintf("http://%s@foo.com?min-over-local-pct=NaN", publicKey.String()),
+			expectedErr: ErrInvalidRelayMinOverLocal,
+		},
+		{
+			name:              "Relay URL with forks",
+			relayURL:          fmt.Sprintf("https://%s@foo.com?fork=deneb&id=foo&fork=Electra&fork=deneb", publicKey.String()),
+			expectedURI:       "https://foo.com?id=foo",
+			expectedPublicKey: publicKey.String(),
+			expectedURL:       fmt.Sprintf("https://%s@foo.com?id=foo", publicKey.String()),
+			expectedForks:     []spec.DataVersion{spec.DataVersionDeneb, spec.DataVersionElectra},
+		},
+		{
+			name:        "Relay URL with a fork without a builder API",
+			relayURL:    fmt.Sprintf("http://%s@foo.com?fork=altair", publicKey.String()),
+			expectedErr: ErrInvalidRelayFork,
+		},
+		{
+			name:        "Relay URL with an unknown fork",
+			relayURL:    fmt.Sprintf("http://%s@foo.com?fork=fulu", publicKey.String()),
+			expectedErr: ErrInvalidRelayFork,
+		},
+		{
+			name:              "Relay URL with weight, min-bid and headers",
+			relayURL:          fmt.Sprintf("https://%s@foo.com?weight=3&min-bid=0.05&header=authorization:Bearer%%20abc&id=foo&header=X-Api-Key:%%20k", publicKey.String()),
+			expectedURI:       "https://foo.com?id=foo",
+			expectedPublicKey: publicKey.String(),
+			expectedURL:       fmt.Sprintf("https://%s@foo.com?id=foo", publicKey.String()),
+			expectedWeight:    3,
+			expectedMinBid:    "50000000000000000",
+			expectedHeaders:   map[string]string{"Authorization": "Bearer abc", "X-Api-Key": "k"},
+		},
+		{
+			name:        "Relay URL with a zero weight",
+			relayURL:    fmt.Sprintf("http://%s@foo.com?weight=0", publicKey.String()),
+			expectedErr: ErrInvalidRelayWeight,
+		},
+		{
+			name:        "Relay URL with a negative min-bid",
+			relayURL:    fmt.Sprintf("http://%s@foo.com?min-bid=-0.1", publicKey.String()),
+			expectedErr: ErrInvalidRelayMinBid,
+		},
+		{
+			name:        "Relay URL with a header without value separator",
+			relayURL:    fmt.Sprintf("http://%s@foo.com?header=Authorization", publicKey.String()),
+			expectedErr: ErrInvalidRelayHeader,
+		},
+		{
+			name:        "Relay URL with a header set by mev-boost",
+			relayURL:    fmt.Sprintf("http://%s@foo.com?header=User-Agent:other", publicKey.String()),
+			expectedErr: ErrInvalidRelayHeader,
+		},
+		{
+			name:        "Relay URL with a header value on several lines",
+			relayURL:    fmt.Sprintf("http://%s@foo.com?header=X-Api-Key:a%%0D%%0AHost:b", publicKey.String()),
+			expectedErr: ErrInvalidRelayHeader,
+		},
 	}
 
 	for _, tt := range testCases {
@@ -105,7 +320,72 @@ func TestParseRelaysURLs(t *testing.T) {
 				require.Equal(t, tt.expectedURI, relayEntry.GetURI(tt.path))
 				require.Equal(t, tt.expectedPublicKey, relayEntry.PublicKey.String())
 				require.Equal(t, tt.expectedURL, relayEntry.String())
+				require.Equal(t, tt.expectedPriority, relayEntry.Priority)
+				require.Equal(t, tt.expectedStream, relayEntry.Stream)
+				require.Equal(t, tt.expectedSniffGzip, relayEntry.SniffGzip)
+				if tt.expectedName == "" {
+					tt.expectedName = relayEntry.URL.Host
+				}
+				require.Equal(t, tt.expectedName, relayEntry.Name())
+				require.Equal(t, tt.expectedMinOver, relayEntry.MinOverLocalPct)
+				require.Equal(t, tt.expectedAddlKeys, relayEntry.AdditionalPublicKeys)
+				require.Equal(t, tt.expectedForks, relayEntry.Forks)
+				require.Equal(t, tt.expectedWeight, relayEntry.Weight)
+				require.Equal(t, max(tt.expectedWeight, 1), relayEntry.TieBreakWeight())
+				if tt.expectedMinBid == "" {
+					require.Nil(t, relayEntry.MinBid)
+				} else {
+					require.Equal(t, tt.expectedMinBid, relayEntry.MinBid.String())
+				}
+				require.Equal(t, tt.expectedHeaders, relayEntry.Headers)
+				require.True(t, relayEntry.SupportsFork(spec.DataVersionElectra))
 			}
 		})
 	}
 }
+
+func TestGetURI(t *testing.T) {
+	testCases := []struct {
+		name     string
+		url      string
+		path     string
+		expected string
+	}{
+		{name: "No prefix", url: "http://foo.com", path: "/eth/v1/builder/status", expected: "http://foo.com/eth/v1/builder/status"},
+		{name: "Prefix", url: "http://foo.com/relay", path: "/eth/v1/builder/status", expected: "http://foo.com/relay/eth/v1/builder/status"},
+		{name: "Prefix with trailing slash", url: "http://foo.com/relay/", path: "/eth/v1/builder/status", expected: "http://foo.com/relay/eth/v1/builder/status"},
+		{name: "Path without leading slash", url: "http://foo.com/relay", path: "eth/v1/builder/status", expected: "http://foo.com/relay/eth/v1/builder/status"},
+		{name: "Empty path", url: "http://foo.com/relay", path: "", expected: "http://foo.com/relay"},
+		{name: "Query args", url: "http://foo.com/relay?id=1", path: "/eth/v1/builder/status", expected: "http://foo.com/relay/eth/v1/builder/status?id=1"},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := url.Parse(tt.url)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, GetURI(u, tt.path))
+		})
+	}
+}
+
+func TestRelayEntryClone(t *testing.T) {
+	relay, err := NewRelayEntry("https://0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249@relay.example.com/api?label=relay&pubkey=0xb8a0bad3f3a4f0b35418c03357c6d42017582437924a1e1ca6aee2072d5c38d321d1f8b22cd36c50b0c29187b6543b6e&min-over-local-pct=5&fork=electra&min-bid=0.1&header=X-Api-Key:abc")
+	require.NoError(t, err)
+	clone := relay.Clone()
+	require.Equal(t, relay, clone)
+
+	// Changes of the clone do not reach the relay entry
+	clone.URL.Host = "other.example.com"
+	clone.URL.User = url.User("other")
+	clone.AdditionalPublicKeys[0] = phase0.BLSPubKey{}
+	*clone.MinOverLocalPct = 10
+	clone.Forks[0] = spec.DataVersionDeneb
+	clone.Headers["X-Api-Key"] = "other"
+	*clone.MinBid = IntToU256(1)
+	require.Equal(t, "relay.example.com", relay.URL.Host)
+	require.Equal(t, "0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249", relay.URL.User.Username())
+	require.NotEqual(t, phase0.BLSPubKey{}, relay.AdditionalPublicKeys[0])
+	require.InDelta(t, 5, *relay.MinOverLocalPct, 0)
+	require.Equal(t, []spec.DataVersion{spec.DataVersionElectra}, relay.Forks)
+	require.Equal(t, "abc", relay.Headers["X-Api-Key"])
+	require.Equal(t, "100000000000000000", relay.MinBid.String())
+}
@@ -0,0 +1,40 @@
+package types
+
+import (
+	"net/url"
+	"strings"
+)
+
+// BuilderAPIPathPrefix is the common prefix of the builder API paths
+const BuilderAPIPathPrefix = "/eth/v1/builder"
+
+// RelayMonitorEntry represents a relay monitor that mev-boost sends validator registrations to.
+type RelayMonitorEntry struct {
+	URL *url.URL
+
+	// PathPrefix replaces the builder API path prefix of the requests to the monitor, for monitors which
+	// serve the registrations under another path. Empty for the builder API path prefix.
+	PathPrefix string
+}
+
+// NewRelayMonitorEntry creates a relay monitor entry from its URL, and removes the mev-boost options
+// from the query of the URL
+func NewRelayMonitorEntry(monitorURL *url.URL) (entry RelayMonitorEntry, err error) {
+	u := *monitorURL
+	entry.URL = &u
+	if prefix, ok := popQueryParam(entry.URL, "path-prefix"); ok {
+		if !strings.HasPrefix(prefix, "/") || strings.ContainsAny(prefix, "?#") {
+			return entry, ErrInvalidRelayMonitorPathPrefix
+		}
+		entry.PathPrefix = strings.TrimSuffix(prefix, "/")
+	}
+	return entry, nil
+}
+
+// GetURI returns the full request URI for a builder API path, with the path prefix of the monitor
+func (e RelayMonitorEntry) GetURI(path string) string {
+	if e.PathPrefix != "" {
+		path = e.PathPrefix + strings.TrimPrefix(path, BuilderAPIPathPrefix)
+	}
+	return GetURI(e.URL, path)
+}
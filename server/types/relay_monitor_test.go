@@ -0,0 +1,36 @@
+package types
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRelayMonitorEntryGetURI(t *testing.T) {
+	testCases := []struct {
+		name     string
+		url      string
+		expected string
+		err      error
+	}{
+		{name: "Builder API prefix", url: "http://monitor.com", expected: "http://monitor.com/eth/v1/builder/validators"},
+		{name: "Subpath", url: "http://monitor.com/mainnet", expected: "http://monitor.com/mainnet/eth/v1/builder/validators"},
+		{name: "Path prefix", url: "http://monitor.com?path-prefix=/api/v2", expected: "http://monitor.com/api/v2/validators"},
+		{name: "Subpath and path prefix", url: "http://monitor.com/mainnet?path-prefix=/api/&id=1", expected: "http://monitor.com/mainnet/api/validators?id=1"},
+		{name: "Relative path prefix", url: "http://monitor.com?path-prefix=api", err: ErrInvalidRelayMonitorPathPrefix},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := url.Parse(tt.url)
+			require.NoError(t, err)
+			entry, err := NewRelayMonitorEntry(u)
+			require.ErrorIs(t, err, tt.err)
+			if tt.err != nil {
+				return
+			}
+			require.Equal(t, tt.expected, entry.GetURI("/eth/v1/builder/validators"))
+			require.Equal(t, tt.url, u.String(), "the monitor URL is not modified")
+		})
+	}
+}
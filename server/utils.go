@@ -9,6 +9,7 @@ import (
 	"io"
 	"math/big"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -22,7 +23,6 @@ import (
 	"github.com/flashbots/go-boost-utils/ssz"
 	"github.com/flashbots/mev-boost/config"
 	"github.com/flashbots/mev-boost/server/types"
-	"github.com/holiman/uint256"
 	"github.com/sirupsen/logrus"
 )
 
@@ -30,10 +30,32 @@ const (
 	HeaderKeySlotUID      = "X-MEVBoost-SlotID"
 	HeaderKeyVersion      = "X-MEVBoost-Version"
 	HeaderStartTimeUnixMS = "X-MEVBoost-StartTimeUnixMS"
+	HeaderKeyTiming       = "X-MEVBoost-Timing"
+	HeaderKeyAdminToken   = "X-MEVBoost-Admin-Token"
+
+	// HeaderKeyLocalBlockValue carries the value of the locally built block in wei, which bids must beat
+	HeaderKeyLocalBlockValue = "X-MEVBoost-Local-Block-Value"
+
+	// Headers of getHeader requests with the time the relay has left to respond: the milliseconds until the
+	// deadline when the request is sent, and the deadline as a unix timestamp in milliseconds
+	HeaderKeyBudgetMS     = "X-MEVBoost-BudgetMS"
+	HeaderKeyCutoffUnixMS = "X-MEVBoost-CutoffUnixMS"
+
+	// HeaderKeyRelayOrder carries the relay hostnames a beacon node prefers for a getHeader request, in order
+	HeaderKeyRelayOrder = "X-MEVBoost-Relay-Order"
+
+	// Headers of relay requests signed with the operator key
+	HeaderKeyRequestSignature          = "X-MEVBoost-Signature"
+	HeaderKeyRequestSignatureTimestamp = "X-MEVBoost-Signature-Timestamp"
+	HeaderKeyOperatorPubkey            = "X-MEVBoost-Operator-Pubkey"
+
+	// HeaderEthConsensusVersion is the builder spec header carrying the fork of a response
+	HeaderEthConsensusVersion = "Eth-Consensus-Version"
 )
 
 var (
 	errHTTPErrorResponse  = errors.New("HTTP error response")
+	errEmptyResponseBody  = errors.New("empty response body")
 	errInvalidForkVersion = errors.New("invalid fork version")
 	errMaxRetriesExceeded = errors.New("max retries exceeded")
 )
@@ -47,13 +69,14 @@ type BlockHashHex string
 // SendHTTPRequest - prepare and send HTTP request, marshaling the payload if any, and decoding the response if dst is set
 func SendHTTPRequest(ctx context.Context, client http.Client, method, url string, userAgent UserAgent, headers map[string]string, payload, dst any) (code int, err error) {
 	var req *http.Request
+	var payloadBytes []byte
 
 	if payload == nil {
 		req, err = http.NewRequestWithContext(ctx, method, url, nil)
 	} else {
-		payloadBytes, err2 := json.Marshal(payload)
-		if err2 != nil {
-			return 0, fmt.Errorf("could not marshal request: %w", err2)
+		payloadBytes, err = json.Marshal(payload)
+		if err != nil {
+			return 0, fmt.Errorf("could not marshal request: %w", err)
 		}
 		req, err = http.NewRequestWithContext(ctx, method, url, bytes.NewReader(payloadBytes))
 
@@ -72,40 +95,86 @@ func SendHTTPRequest(ctx context.Context, client http.Client, method, url string
 		req.Header.Set(key, value)
 	}
 
+	// Sign the request last, if signing is enabled for it
+	if signer, ok := ctx.Value(requestSignerKey{}).(RequestSigner); ok {
+		if err := signer.SignRequest(req, payloadBytes); err != nil {
+			return 0, fmt.Errorf("could not sign request: %w", err)
+		}
+	}
+
 	// Execute request
 	resp, err := client.Do(req)
 	if err != nil {
 		return 0, err
 	}
 	defer resp.Body.Close()
+	observeRelayResponse(ctx, resp)
 
 	if resp.StatusCode == http.StatusNoContent {
 		return resp.StatusCode, nil
 	}
 
+	// Stop reading responses which are too large before reading them
+	maxSize, _ := ctx.Value(maxResponseSizeKey{}).(int64)
+	if maxSize > 0 && resp.ContentLength > maxSize {
+		return resp.StatusCode, fmt.Errorf("%w: %d bytes", errResponseTooLarge, resp.ContentLength)
+	}
+	body := io.Reader(resp.Body)
+	if maxSize > 0 {
+		body = io.LimitReader(resp.Body, maxSize+1)
+	}
+
+	if resp.StatusCode >= 300 && resp.StatusCode < 400 {
+		return resp.StatusCode, fmt.Errorf("%w: %d to %s", errRelayRedirect, resp.StatusCode, resp.Header.Get("Location"))
+	}
 	if resp.StatusCode > 299 {
-		bodyBytes, err := io.ReadAll(resp.Body)
+		bodyBytes, err := io.ReadAll(body)
 		if err != nil {
 			return resp.StatusCode, fmt.Errorf("could not read error response body for status code %d: %w", resp.StatusCode, err)
 		}
-		return resp.StatusCode, fmt.Errorf("%w: %d / %s", errHTTPErrorResponse, resp.StatusCode, string(bodyBytes))
+		return resp.StatusCode, fmt.Errorf("%w: %d / %s", errHTTPErrorResponse, resp.StatusCode, redactBody(bodyBytes))
 	}
 
 	if dst != nil {
-		bodyBytes, err := io.ReadAll(resp.Body)
+		bodyBytes, err := io.ReadAll(body)
 		if err != nil {
 			return resp.StatusCode, fmt.Errorf("could not read response body: %w", err)
 		}
+		if maxSize > 0 && int64(len(bodyBytes)) > maxSize {
+			return resp.StatusCode, fmt.Errorf("%w: more than %d bytes", errResponseTooLarge, maxSize)
+		}
+		bodyBytes, err = sniffGzip(ctx, resp.Header, bodyBytes, maxSize)
+		if err != nil {
+			return resp.StatusCode, err
+		}
 
+		if len(bytes.TrimSpace(bodyBytes)) == 0 {
+			return resp.StatusCode, errEmptyResponseBody
+		}
+		if err := unexpectedContentType(ctx, resp.Header, bodyBytes, nil); err != nil {
+			return resp.StatusCode, err
+		}
+		bodyBytes = fillConsensusVersion(ctx, resp.Header, bodyBytes)
 		if err := json.Unmarshal(bodyBytes, dst); err != nil {
-			return resp.StatusCode, fmt.Errorf("could not unmarshal response %s: %w", string(bodyBytes), err)
+			if ctErr := unexpectedContentType(ctx, resp.Header, bodyBytes, err); ctErr != nil {
+				return resp.StatusCode, ctErr
+			}
+			return resp.StatusCode, fmt.Errorf("could not unmarshal response %s: %w", redactBody(bodyBytes), err)
 		}
 	}
 
 	return resp.StatusCode, nil
 }
 
-// SendHTTPRequestWithRetries - prepare and send HTTP request, retrying the request if within the client timeout
+// isNoBidBody returns true if a successful getHeader response body has no bid, which some relays send
+// instead of a 204 response
+func isNoBidBody(body []byte) bool {
+	body = bytes.TrimSpace(body)
+	return len(body) == 0 || bytes.Equal(body, []byte("null"))
+}
+
+// SendHTTPRequestWithRetries - prepare and send HTTP request, retrying the request if within the client timeout and
+// if the error is of one of the retry conditions
 func SendHTTPRequestWithRetries(ctx context.Context, client http.Client, method, url string, userAgent UserAgent, headers map[string]string, payload, dst any, maxRetries int, log *logrus.Entry) (code int, err error) {
 	var requestCtx context.Context
 	var cancel context.CancelFunc
@@ -128,8 +197,25 @@ func SendHTTPRequestWithRetries(ctx context.Context, client http.Client, method,
 		}
 
 		code, err = SendHTTPRequest(ctx, client, method, url, userAgent, headers, payload, dst)
+		if errors.Is(err, errResponseTooLarge) || errors.Is(err, errRelayRedirect) || errors.Is(err, context.Canceled) {
+			// The relay would send the same response again, or the request is no longer needed
+			return code, err
+		}
 		if err != nil {
-			log.WithError(err).Warn("error making request to relay, retrying")
+			// Only retry the conditions which may succeed on retry, like a 503 but unlike a 400
+			condition := retryCondition(code, err)
+			limit, retryable := retryLimit(ctx, condition, maxRetries)
+			retry := retryable && attempts < limit
+			relayRequestRetries.WithLabelValues(condition, strconv.FormatBool(retry)).Inc()
+			log := log.WithError(err).WithField("condition", condition)
+			if !retryable {
+				log.Warn("error making request to relay, not retrying")
+				return code, err
+			}
+			if !retry {
+				return code, fmt.Errorf("%w: %w", errMaxRetriesExceeded, err)
+			}
+			log.Warn("error making request to relay, retrying")
 			time.Sleep(100 * time.Millisecond) // note: this timeout is only applied between retries, it does not delay the initial request!
 			continue
 		}
@@ -162,16 +248,18 @@ type bidResp struct {
 	response builderSpec.VersionedSignedBuilderBid
 	bidInfo  bidInfo
 	relays   []types.RelayEntry
-}
+	slot     phase0.Slot
+	tenant   string
+	tieBreak string
 
-// bidInfo is used to store bid response fields for logging and validation
-type bidInfo struct {
-	blockHash   phase0.Hash32
-	parentHash  phase0.Hash32
-	pubkey      phase0.BLSPubKey
-	blockNumber uint64
-	txRoot      phase0.Root
-	value       *uint256.Int
+	// proposerPubkey is the validator pubkey of the getHeader request
+	proposerPubkey string
+	// servedFromCache is set if the bid was served again because all relays failed
+	servedFromCache bool
+	// biddingRelays is the number of relays with an acceptable bid in the auction
+	biddingRelays int
+	// coldStartRecovery is set for getPayload requests of blocks whose bid was not known, see coldStartPersisted
+	coldStartRecovery string
 }
 
 func httpClientDisallowRedirects(_ *http.Request, _ []*http.Request) error {
@@ -186,41 +274,6 @@ func weiBigIntToEthBigFloat(wei *big.Int) (ethValue *big.Float) {
 	return
 }
 
-func parseBidInfo(bid *builderSpec.VersionedSignedBuilderBid) (bidInfo, error) {
-	blockHash, err := bid.BlockHash()
-	if err != nil {
-		return bidInfo{}, err
-	}
-	parentHash, err := bid.ParentHash()
-	if err != nil {
-		return bidInfo{}, err
-	}
-	pubkey, err := bid.Builder()
-	if err != nil {
-		return bidInfo{}, err
-	}
-	blockNumber, err := bid.BlockNumber()
-	if err != nil {
-		return bidInfo{}, err
-	}
-	txRoot, err := bid.TransactionsRoot()
-	if err != nil {
-		return bidInfo{}, err
-	}
-	value, err := bid.Value()
-	if err != nil {
-		return bidInfo{}, err
-	}
-	return bidInfo{
-		blockHash:   blockHash,
-		parentHash:  parentHash,
-		pubkey:      pubkey,
-		blockNumber: blockNumber,
-		txRoot:      txRoot,
-		value:       value,
-	}, nil
-}
-
 func checkRelaySignature(bid *builderSpec.VersionedSignedBuilderBid, domain phase0.Domain, pubKey phase0.BLSPubKey) (bool, error) {
 	root, err := bid.MessageHashTreeRoot()
 	if err != nil {
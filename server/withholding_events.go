@@ -0,0 +1,83 @@
+package server
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/flashbots/mev-boost/server/types"
+)
+
+// maxWithholdingEvents is the number of withholding events kept, older events are overwritten
+const maxWithholdingEvents = 256
+
+// defaultWithholdingEventsRetention is how long withholding events are kept if no retention is configured
+const defaultWithholdingEventsRetention = 24 * time.Hour
+
+// withholdingEvent is a getPayload request for which no relay delivered the payload
+type withholdingEvent struct {
+	Slot      phase0.Slot   `json:"slot,string"`
+	BlockHash phase0.Hash32 `json:"block_hash"`
+	Relays    []string      `json:"relays"`
+	Time      time.Time     `json:"time"`
+}
+
+// withholdingEvents is a ring buffer of the most recent withholding events
+type withholdingEvents struct {
+	mu        sync.Mutex
+	retention time.Duration
+	events    []withholdingEvent
+	next      int // index the next event is written to, once the buffer is full
+}
+
+func newWithholdingEvents(retention time.Duration) *withholdingEvents {
+	if retention <= 0 {
+		retention = defaultWithholdingEventsRetention
+	}
+	return &withholdingEvents{
+		retention: retention,
+		events:    make([]withholdingEvent, 0, maxWithholdingEvents),
+	}
+}
+
+// record adds a withholding event by the relays, overwriting the oldest event if the buffer is full
+func (e *withholdingEvents) record(slot phase0.Slot, blockHash phase0.Hash32, relays []types.RelayEntry, now time.Time) {
+	event := withholdingEvent{
+		Slot:      slot,
+		BlockHash: blockHash,
+		Relays:    types.RelayEntriesToNames(relays),
+		Time:      now,
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if len(e.events) < maxWithholdingEvents {
+		e.events = append(e.events, event)
+		return
+	}
+	e.events[e.next] = event
+	e.next = (e.next + 1) % maxWithholdingEvents
+}
+
+// list returns the events within the retention, most recent first
+func (e *withholdingEvents) list(now time.Time) []withholdingEvent {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	events := make([]withholdingEvent, 0, len(e.events))
+	for i := range e.events {
+		// Walk backwards from the most recently written event
+		event := e.events[(e.next-1-i+2*len(e.events))%len(e.events)]
+		if now.Sub(event.Time) > e.retention {
+			break
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+// handleDebugWithholding returns the recent withholding events
+func (m *BoostService) handleDebugWithholding(w http.ResponseWriter, _ *http.Request) {
+	m.respondOK(w, m.withholdingEvents.list(time.Now()))
+}
@@ -0,0 +1,38 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithholdingEvents(t *testing.T) {
+	now := time.Now()
+	e := newWithholdingEvents(time.Hour)
+
+	// slots returns the slots of the listed events
+	slots := func(events []withholdingEvent) []phase0.Slot {
+		listed := make([]phase0.Slot, 0, len(events))
+		for _, event := range events {
+			listed = append(listed, event.Slot)
+		}
+		return listed
+	}
+
+	e.record(1, phase0.Hash32{0x01}, nil, now.Add(-2*time.Hour))
+	e.record(2, phase0.Hash32{0x02}, nil, now.Add(-time.Minute))
+	e.record(3, phase0.Hash32{0x03}, nil, now)
+	require.Equal(t, []phase0.Slot{3, 2}, slots(e.list(now)), "most recent first, without events beyond the retention")
+
+	// The buffer is bounded, the oldest events are overwritten
+	for slot := phase0.Slot(4); slot < 4+maxWithholdingEvents; slot++ {
+		e.record(slot, phase0.Hash32{}, nil, now)
+	}
+	listed := e.list(now)
+	require.Len(t, listed, maxWithholdingEvents)
+	require.Equal(t, phase0.Slot(3+maxWithholdingEvents), listed[0].Slot)
+	require.Equal(t, phase0.Slot(4), listed[maxWithholdingEvents-1].Slot)
+	require.Len(t, e.events, maxWithholdingEvents)
+}
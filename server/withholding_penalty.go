@@ -0,0 +1,96 @@
+package server
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/flashbots/mev-boost/server/types"
+)
+
+// Penalties for bids of relays which recently withheld a payload
+const (
+	WithholdingPenaltyDeprioritize = "deprioritize"
+	WithholdingPenaltyExclude      = "exclude"
+)
+
+// withholdingPenalties keeps track of the relays in cooldown after withholding a payload
+type withholdingPenalties struct {
+	mu       sync.Mutex
+	duration time.Duration
+	until    map[string]time.Time // relay -> end of the cooldown
+}
+
+func newWithholdingPenalties(duration time.Duration) *withholdingPenalties {
+	return &withholdingPenalties{
+		duration: duration,
+		until:    make(map[string]time.Time),
+	}
+}
+
+// penalize starts the cooldown of the relays, if penalties are enabled
+func (p *withholdingPenalties) penalize(relays []types.RelayEntry, now time.Time) {
+	if p.duration <= 0 {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, relay := range relays {
+		p.until[relay.String()] = now.Add(p.duration)
+	}
+}
+
+// penalized returns the end of the cooldown of the relay, and whether it is still in cooldown
+func (p *withholdingPenalties) penalized(relay types.RelayEntry, now time.Time) (time.Time, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	until, ok := p.until[relay.String()]
+	if !ok {
+		return time.Time{}, false
+	}
+	if !now.Before(until) {
+		delete(p.until, relay.String())
+		return time.Time{}, false
+	}
+	return until, true
+}
+
+// relayState is the state of a relay, returned by the relays debug endpoint
+type relayState struct {
+	Relay                  string     `json:"relay"`
+	URL                    string     `json:"url"`
+	Priority               int        `json:"priority"`
+	Penalized              bool       `json:"penalized"`
+	PenalizedUntil         *time.Time `json:"penalized_until,omitempty"`
+	RecentFailedDeliveries int        `json:"recent_failed_deliveries"`
+
+	// BackoffUntil is the end of the backoff the relay asked for with Retry-After, and Warnings are the
+	// deprecation and upgrade warnings it announced in response headers
+	BackoffUntil *time.Time     `json:"backoff_until,omitempty"`
+	Warnings     []relayWarning `json:"warnings,omitempty"`
+}
+
+// handleDebugRelays returns the configured relays, whether they are in cooldown after withholding a payload or
+// backing off after a Retry-After, and their active warnings
+func (m *BoostService) handleDebugRelays(w http.ResponseWriter, _ *http.Request) {
+	now := time.Now()
+	relays := make([]relayState, 0, len(m.relays))
+	for _, relay := range m.relays {
+		state := relayState{
+			Relay:                  relayLabel(relay),
+			URL:                    relay.String(),
+			Priority:               relay.Priority,
+			RecentFailedDeliveries: m.failedDeliveries.count(relay),
+		}
+		if until, ok := m.withholdingPenalties.penalized(relay, now); ok {
+			state.Penalized = true
+			state.PenalizedUntil = &until
+		}
+		if until, ok := m.relayAdvisories.backingOff(relay, now); ok {
+			state.BackoffUntil = &until
+		}
+		state.Warnings = m.relayAdvisories.activeWarnings(relay, now)
+		relays = append(relays, state)
+	}
+	m.respondOK(w, relays)
+}
@@ -0,0 +1,144 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	eth2ApiV1Deneb "github.com/attestantio/go-eth2-client/api/v1/deneb"
+	"github.com/attestantio/go-eth2-client/spec"
+	"github.com/flashbots/mev-boost/server/mock"
+	"github.com/flashbots/mev-boost/server/params"
+	"github.com/flashbots/mev-boost/server/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithholdingPenalties(t *testing.T) {
+	relayA, err := types.NewRelayEntry("http://0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249@a.com")
+	require.NoError(t, err)
+	relayB, err := types.NewRelayEntry("http://0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249@b.com")
+	require.NoError(t, err)
+	now := time.Now()
+
+	p := newWithholdingPenalties(time.Minute)
+	p.penalize([]types.RelayEntry{relayA}, now)
+	until, ok := p.penalized(relayA, now.Add(59*time.Second))
+	require.True(t, ok)
+	require.Equal(t, now.Add(time.Minute), until)
+	_, ok = p.penalized(relayB, now)
+	require.False(t, ok)
+
+	// The cooldown ends after the penalty duration
+	_, ok = p.penalized(relayA, now.Add(time.Minute))
+	require.False(t, ok)
+
+	// Without a penalty duration, relays are not penalized
+	p = newWithholdingPenalties(0)
+	p.penalize([]types.RelayEntry{relayA}, now)
+	_, ok = p.penalized(relayA, now)
+	require.False(t, ok)
+}
+
+func TestGetHeaderAfterWithholding(t *testing.T) {
+	const (
+		pubkey    = "0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249"
+		otherHash = "0xcf8e0d4e9587369b2301d0790347320302cc0943d5a1884560367e8208d920f2"
+		nextHash  = "0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7"
+	)
+
+	jsonFile, err := os.Open("../testdata/signed-blinded-beacon-block-deneb.json")
+	require.NoError(t, err)
+	defer jsonFile.Close()
+	block := new(eth2ApiV1Deneb.SignedBlindedBeaconBlock)
+	require.NoError(t, DecodeJSON(jsonFile, block))
+	header := block.Message.Body.ExecutionPayloadHeader
+	path := getHeaderPath(uint64(block.Message.Slot), header.ParentHash, mock.HexToPubkey(pubkey))
+
+	// withhold makes relay 0 win the auction and withhold the payload, after which it bids the
+	// highest value for another block
+	withhold := func(t *testing.T, backend *testBackend) {
+		t.Helper()
+		backend.boost.withholdingPenalties = newWithholdingPenalties(time.Minute)
+		backend.relays[0].GetHeaderResponse = backend.relays[0].MakeGetHeaderResponse(
+			20000, header.BlockHash.String(), header.ParentHash.String(), pubkey, spec.DataVersionDeneb)
+		backend.relays[0].OverrideHandleGetPayload(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		})
+		for _, relay := range backend.relays[1:] {
+			relay.GetHeaderResponse = relay.MakeGetHeaderResponse(
+				15000, otherHash, header.ParentHash.String(), pubkey, spec.DataVersionDeneb)
+		}
+
+		rr := backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		require.Contains(t, rr.Body.String(), header.BlockHash.String())
+
+		rr = backend.request(t, http.MethodPost, params.PathGetPayload, block)
+		require.Equal(t, http.StatusBadGateway, rr.Code, rr.Body.String())
+
+		backend.relays[0].GetHeaderResponse = backend.relays[0].MakeGetHeaderResponse(
+			30000, nextHash, header.ParentHash.String(), pubkey, spec.DataVersionDeneb)
+	}
+
+	t.Run("Bids are excluded", func(t *testing.T) {
+		backend := newTestBackend(t, 1, 250*time.Millisecond)
+		backend.boost.withholdingPenaltyPolicy = WithholdingPenaltyExclude
+		withhold(t, backend)
+
+		rr := backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, http.StatusNoContent, rr.Code, rr.Body.String())
+	})
+
+	t.Run("Bids are deprioritized", func(t *testing.T) {
+		backend := newTestBackend(t, 2, 250*time.Millisecond)
+		withhold(t, backend)
+
+		rr := backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		require.Contains(t, rr.Body.String(), otherHash)
+
+		// Without other bids, the deprioritized bid is still used
+		backend.relays[1].GetHeaderResponse = nil
+		backend.relays[1].OverrideHandleGetHeader(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		})
+		rr = backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		require.Contains(t, rr.Body.String(), nextHash)
+	})
+
+	t.Run("Penalized relays are shown on the debug endpoint", func(t *testing.T) {
+		backend := newTestBackend(t, 2, 250*time.Millisecond)
+		backend.boost.debugEndpoints = true
+		withhold(t, backend)
+
+		rr := backend.request(t, http.MethodGet, params.PathDebugRelays, nil)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		var relays []relayState
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &relays))
+		require.Len(t, relays, 2)
+		require.Equal(t, backend.relays[0].RelayEntry.Name(), relays[0].Relay)
+		require.True(t, relays[0].Penalized)
+		require.NotNil(t, relays[0].PenalizedUntil)
+		require.Equal(t, 1, relays[0].RecentFailedDeliveries)
+		require.False(t, relays[1].Penalized)
+		require.Nil(t, relays[1].PenalizedUntil)
+	})
+
+	t.Run("Withholding events are shown on the debug endpoint", func(t *testing.T) {
+		backend := newTestBackend(t, 2, 250*time.Millisecond)
+		backend.boost.debugEndpoints = true
+		withhold(t, backend)
+
+		rr := backend.request(t, http.MethodGet, params.PathDebugWithholding, nil)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		var events []withholdingEvent
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &events))
+		require.Len(t, events, 1)
+		require.Equal(t, block.Message.Slot, events[0].Slot)
+		require.Equal(t, header.BlockHash, events[0].BlockHash)
+		require.Equal(t, []string{backend.relays[0].RelayEntry.Name()}, events[0].Relays)
+	})
+}